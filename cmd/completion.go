@@ -0,0 +1,174 @@
+package cmd
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/cuongtl1992/grp-cli/internal/config"
+	"github.com/cuongtl1992/grp-cli/internal/models"
+)
+
+// completionCmd represents the completion command
+var completionCmd = &cobra.Command{
+	Use:   "completion [bash|zsh]",
+	Short: "Generate the shell completion script",
+	Long: `Generate the completion script of grp-cli for bash or zsh.
+
+Besides commands and flags, the script completes plan files, the stages of the
+plan given on the command line for --from-stage, --to-stage and --stage, its tags
+for --tags and --exclude-tags, its environments for --environment, and plugin
+names for plugins describe.
+
+Bash (requires the bash-completion package):
+
+  source <(grp-cli completion bash)
+  # or, for every session:
+  grp-cli completion bash > /etc/bash_completion.d/grp-cli
+
+Zsh (with compinit enabled):
+
+  grp-cli completion zsh > "${fpath[1]}/_grp-cli"`,
+	Args:                  cobra.MatchAll(cobra.ExactArgs(1), cobra.OnlyValidArgs),
+	ValidArgs:             []string{"bash", "zsh"},
+	DisableFlagsInUseLine: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		switch args[0] {
+		case "bash":
+			return cmd.Root().GenBashCompletionV2(cmd.OutOrStdout(), true)
+		case "zsh":
+			return cmd.Root().GenZshCompletion(cmd.OutOrStdout())
+		}
+		return fmt.Errorf("unsupported shell: %s (expected bash or zsh)", args[0])
+	},
+}
+
+// completePlanFile completes the plan file argument of a command with YAML files
+func completePlanFile(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	return []string{"yaml", "yml"}, cobra.ShellCompDirectiveFilterFileExt
+}
+
+// completePluginFile completes the plugin file argument of a command with .so files
+func completePluginFile(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	return []string{"so"}, cobra.ShellCompDirectiveFilterFileExt
+}
+
+// completeStages completes a flag with the names of the stages of the plan given
+// as the command's argument
+func completeStages(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	plan := completionPlan(cmd, args)
+	if plan == nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	var names []string
+	for _, stage := range plan.Stages {
+		names = append(names, stage.Name)
+	}
+	return names, cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeTags completes a flag with the tags of the stages and jobs of the plan
+// given as the command's argument. The flags take comma-separated lists, so the
+// tags already listed are kept as a prefix and not suggested again.
+func completeTags(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	plan := completionPlan(cmd, args)
+	if plan == nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	listed := make(map[string]bool)
+	prefix := ""
+	if i := strings.LastIndex(toComplete, ","); i >= 0 {
+		prefix = toComplete[:i+1]
+		for _, tag := range strings.Split(toComplete[:i], ",") {
+			listed[tag] = true
+		}
+	}
+
+	tags := make(map[string]bool)
+	for _, stage := range plan.Stages {
+		for _, tag := range stage.Tags {
+			tags[tag] = true
+		}
+		for _, job := range stage.Jobs {
+			for _, tag := range job.Tags {
+				tags[tag] = true
+			}
+		}
+	}
+
+	var suggestions []string
+	for tag := range tags {
+		if !listed[tag] {
+			suggestions = append(suggestions, prefix+tag)
+		}
+	}
+	sort.Strings(suggestions)
+	return suggestions, cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeEnvironments completes a flag with the environments defined by the plan
+// given as the command's argument
+func completeEnvironments(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) == 0 || args[0] == config.StdinPlan || strings.Contains(args[0], "://") {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	loader, err := newPlanLoader(cmd)
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	loader.SetEnvironment("")
+	if _, err := loader.LoadPlan(args[0]); err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	return loader.Environments(), cobra.ShellCompDirectiveNoFileComp
+}
+
+// completePluginNames completes the plugin name argument of a command with the
+// built-in plugins and those of the plugin directories
+func completePluginNames(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	var names []string
+	for _, plg := range loadPluginManager(cmd).ListPlugins() {
+		names = append(names, plg.Name()+"\t"+plg.Description())
+	}
+	sort.Strings(names)
+	return names, cobra.ShellCompDirectiveNoFileComp
+}
+
+// completionPlan loads the plan given as the command's argument for completion,
+// or returns nil if there is none or it cannot be loaded. Plans read from stdin
+// or fetched from a URL are not loaded, to keep completion instant.
+func completionPlan(cmd *cobra.Command, args []string) *models.Plan {
+	if len(args) == 0 || args[0] == config.StdinPlan || strings.Contains(args[0], "://") {
+		return nil
+	}
+
+	loader, err := newPlanLoader(cmd)
+	if err != nil {
+		return nil
+	}
+	plan, err := loader.LoadPlan(args[0])
+	if err != nil {
+		return nil
+	}
+	return plan
+}
+
+func init() {
+	rootCmd.CompletionOptions.DisableDefaultCmd = true
+	rootCmd.AddCommand(completionCmd)
+}
@@ -0,0 +1,81 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func TestCompletePlanValues(t *testing.T) {
+	plan := `
+apiVersion: v1
+kind: ReleasePlan
+metadata:
+  name: test-plan
+stages:
+  - name: build
+    tags: [ci]
+    jobs:
+      - name: image
+        type: docker
+  - name: deploy
+    jobs:
+      - name: web
+        type: kubernetes
+        tags: [web, frontend]
+      - name: migrate
+        type: shell
+        tags: [db]
+environments:
+  staging:
+  prod:
+    variables: {replicas: 3}
+`
+	planPath := filepath.Join(t.TempDir(), "plan.yaml")
+	if err := os.WriteFile(planPath, []byte(plan), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		complete   func(*cobra.Command, []string, string) ([]string, cobra.ShellCompDirective)
+		args       []string
+		toComplete string
+		expected   []string
+	}{
+		{name: "stages", complete: completeStages, args: []string{planPath}, expected: []string{"build", "deploy"}},
+		{name: "tags", complete: completeTags, args: []string{planPath}, expected: []string{"ci", "db", "frontend", "web"}},
+		{name: "more tags", complete: completeTags, args: []string{planPath}, toComplete: "web,c", expected: []string{"web,ci", "web,db", "web,frontend"}},
+		{name: "environments", complete: completeEnvironments, args: []string{planPath}, expected: []string{"prod", "staging"}},
+		{name: "no plan", complete: completeStages},
+		{name: "missing plan", complete: completeStages, args: []string{"missing.yaml"}},
+		{name: "plan from stdin", complete: completeTags, args: []string{"-"}},
+		{name: "environments of a plan from stdin", complete: completeEnvironments, args: []string{"-"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			suggestions, directive := tt.complete(runCmd, tt.args, tt.toComplete)
+			if !reflect.DeepEqual(suggestions, tt.expected) {
+				t.Errorf("Expected suggestions %q, got %q", tt.expected, suggestions)
+			}
+			if directive != cobra.ShellCompDirectiveNoFileComp {
+				t.Errorf("Expected no file completion, got directive %d", directive)
+			}
+		})
+	}
+}
+
+func TestCompletePlanFile(t *testing.T) {
+	extensions, directive := completePlanFile(runCmd, nil, "")
+	if !reflect.DeepEqual(extensions, []string{"yaml", "yml"}) || directive != cobra.ShellCompDirectiveFilterFileExt {
+		t.Errorf("Expected YAML files for the plan argument, got %q (directive %d)", extensions, directive)
+	}
+
+	if suggestions, directive := completePlanFile(runCmd, []string{"plan.yaml"}, ""); suggestions != nil || directive != cobra.ShellCompDirectiveNoFileComp {
+		t.Errorf("Expected nothing after the plan argument, got %q (directive %d)", suggestions, directive)
+	}
+}
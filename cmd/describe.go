@@ -0,0 +1,302 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/cuongtl1992/grp-cli/internal/config"
+	"github.com/cuongtl1992/grp-cli/internal/engine"
+	"github.com/cuongtl1992/grp-cli/internal/models"
+	"github.com/cuongtl1992/grp-cli/internal/secrets"
+)
+
+// planOutline is the resolved overview of a plan printed by the describe command
+type planOutline struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description,omitempty"`
+	Owner       string                 `json:"owner,omitempty"`
+	Version     string                 `json:"version,omitempty"`
+	Variables   map[string]interface{} `json:"variables,omitempty"`
+	Stages      []stageOutline         `json:"stages"`
+	Rollback    []stageOutline         `json:"rollback,omitempty"`
+}
+
+// stageOutline describes a stage, its approval requirements, hooks, jobs and job dependencies
+type stageOutline struct {
+	Name            string        `json:"name"`
+	Description     string        `json:"description,omitempty"`
+	DependsOn       []string      `json:"dependsOn,omitempty"`
+	RequireApproval bool          `json:"requireApproval"`
+	Approvers       []string      `json:"approvers,omitempty"`
+	Tags            []string      `json:"tags,omitempty"`
+	PreJobs         []jobOutline  `json:"preJobs,omitempty"`
+	Jobs            []jobOutline  `json:"jobs"`
+	PostJobs        []jobOutline  `json:"postJobs,omitempty"`
+	RollbackJobs    []jobOutline  `json:"rollbackJobs,omitempty"`
+	Edges           []edgeOutline `json:"edges,omitempty"`
+}
+
+// jobOutline describes a job with its resolved config
+type jobOutline struct {
+	Name                string                 `json:"name"`
+	Type                string                 `json:"type"`
+	DependsOn           []string               `json:"dependsOn,omitempty"`
+	DependsOnConditions map[string]string      `json:"dependsOnConditions,omitempty"`
+	OptionalDependsOn   []string               `json:"optionalDependsOn,omitempty"`
+	Tags                []string               `json:"tags,omitempty"`
+	Priority            int                    `json:"priority,omitempty"`
+	Config              map[string]interface{} `json:"config,omitempty"`
+}
+
+// edgeOutline is a dependency between two jobs: To runs after From. An optional
+// dependency only orders the jobs; On is the outcome of From To requires, if not success.
+type edgeOutline struct {
+	From     string `json:"from"`
+	To       string `json:"to"`
+	Optional bool   `json:"optional,omitempty"`
+	On       string `json:"on,omitempty"`
+}
+
+// describeCmd represents the describe command
+var describeCmd = &cobra.Command{
+	Use:   "describe [plan file]",
+	Short: "Show a resolved overview of a release plan",
+	Long: `Load and resolve a release plan and print an outline of what it will do: its
+metadata, resolved variables, each stage with its approval requirements, jobs and
+job dependencies, and the rollback stages. Config values are shown resolved, with
+secrets masked. Nothing is executed.`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completePlanFile,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		outputFormat, _ := cmd.Flags().GetString("output")
+		if outputFormat != "text" && outputFormat != "json" {
+			return fmt.Errorf("unsupported output format: %s (expected text or json)", outputFormat)
+		}
+
+		// Load and validate the plan
+		loader, err := newPlanLoader(cmd)
+		if err != nil {
+			return err
+		}
+		plan, err := loader.LoadPlan(args[0])
+		if err != nil {
+			return fmt.Errorf("failed to load plan: %w", err)
+		}
+		if err := config.NewValidator().ValidatePlan(plan); err != nil {
+			return fmt.Errorf("validation failed: %w", err)
+		}
+
+		outline := outlinePlan(plan, secrets.NewMasker(loader.Secrets()...))
+
+		if outputFormat == "json" {
+			encoder := json.NewEncoder(cmd.OutOrStdout())
+			encoder.SetIndent("", "  ")
+			return encoder.Encode(outline)
+		}
+
+		writePlanOutline(cmd.OutOrStdout(), outline)
+		return nil
+	},
+}
+
+// outlinePlan builds the overview of a plan, masking secret values
+func outlinePlan(plan *models.Plan, masker *secrets.Masker) planOutline {
+	outline := planOutline{
+		Name:        plan.Metadata.Name,
+		Description: plan.Metadata.Description,
+		Owner:       plan.Metadata.Owner,
+		Version:     plan.Metadata.Version,
+		Variables:   masker.MaskMap(plan.Variables),
+		Stages:      outlineStages(plan.Stages, masker),
+	}
+	if plan.Rollback != nil {
+		outline.Rollback = outlineStages(plan.Rollback.Stages, masker)
+	}
+	return outline
+}
+
+// outlineStages builds the overview of each stage
+func outlineStages(stages []models.Stage, masker *secrets.Masker) []stageOutline {
+	outlines := make([]stageOutline, 0, len(stages))
+	for _, stage := range stages {
+		outline := stageOutline{
+			Name:            stage.Name,
+			Description:     stage.Description,
+			DependsOn:       stage.DependsOn,
+			RequireApproval: stage.RequireApproval,
+			Approvers:       stage.Approvers,
+			Tags:            stage.Tags,
+			PreJobs:         outlineJobs(stage.PreJobs, masker),
+			Jobs:            outlineJobs(stage.Jobs, masker),
+			PostJobs:        outlineJobs(stage.PostJobs, masker),
+			RollbackJobs:    outlineJobs(stage.Rollback, masker),
+		}
+		for _, edge := range engine.BuildDependencyGraph(stage.Jobs).Edges() {
+			outline.Edges = append(outline.Edges, edgeOutline{From: edge.From, To: edge.To, Optional: edge.Optional, On: edge.On})
+		}
+		outlines = append(outlines, outline)
+	}
+	return outlines
+}
+
+// outlineJobs builds the overview of each job, masking secret values
+func outlineJobs(jobs []models.Job, masker *secrets.Masker) []jobOutline {
+	outlines := make([]jobOutline, 0, len(jobs))
+	for _, job := range jobs {
+		outlines = append(outlines, jobOutline{
+			Name:                job.Name,
+			Type:                job.Type,
+			DependsOn:           job.DependsOn,
+			DependsOnConditions: job.DependsOnConditions,
+			OptionalDependsOn:   job.OptionalDependsOn,
+			Tags:                job.Tags,
+			Priority:            job.Priority,
+			Config:              masker.MaskMap(job.Config),
+		})
+	}
+	return outlines
+}
+
+// writePlanOutline writes a human-readable overview of a plan
+func writePlanOutline(w io.Writer, outline planOutline) {
+	fmt.Fprintf(w, "Plan:        %s\n", outline.Name)
+	if outline.Description != "" {
+		fmt.Fprintf(w, "Description: %s\n", outline.Description)
+	}
+	if outline.Owner != "" {
+		fmt.Fprintf(w, "Owner:       %s\n", outline.Owner)
+	}
+	if outline.Version != "" {
+		fmt.Fprintf(w, "Version:     %s\n", outline.Version)
+	}
+
+	if len(outline.Variables) > 0 {
+		fmt.Fprintln(w, "\nVariables:")
+		writeValues(w, outline.Variables, 1)
+	}
+
+	fmt.Fprintln(w, "\nStages:")
+	writeStageOutlines(w, outline.Stages)
+
+	if len(outline.Rollback) == 0 {
+		fmt.Fprintln(w, "\nRollback: none")
+		return
+	}
+	fmt.Fprintln(w, "\nRollback stages:")
+	writeStageOutlines(w, outline.Rollback)
+}
+
+// writeStageOutlines writes each stage with its jobs and job dependencies
+func writeStageOutlines(w io.Writer, stages []stageOutline) {
+	for _, stage := range stages {
+		fmt.Fprintf(w, "  %s\n", stage.Name)
+		if stage.Description != "" {
+			fmt.Fprintf(w, "    Description: %s\n", stage.Description)
+		}
+		if len(stage.DependsOn) > 0 {
+			fmt.Fprintf(w, "    Depends on: %s\n", strings.Join(stage.DependsOn, ", "))
+		}
+		if stage.RequireApproval {
+			approvers := "any approver"
+			if len(stage.Approvers) > 0 {
+				approvers = strings.Join(stage.Approvers, ", ")
+			}
+			fmt.Fprintf(w, "    Approval: required (%s)\n", approvers)
+		}
+		if len(stage.Tags) > 0 {
+			fmt.Fprintf(w, "    Tags: %s\n", strings.Join(stage.Tags, ", "))
+		}
+
+		if len(stage.PreJobs) > 0 {
+			fmt.Fprintln(w, "    Pre jobs:")
+			writeJobs(w, stage.PreJobs)
+		}
+		fmt.Fprintln(w, "    Jobs:")
+		writeJobs(w, stage.Jobs)
+		if len(stage.PostJobs) > 0 {
+			fmt.Fprintln(w, "    Post jobs:")
+			writeJobs(w, stage.PostJobs)
+		}
+		if len(stage.RollbackJobs) > 0 {
+			fmt.Fprintln(w, "    Rollback jobs:")
+			writeJobs(w, stage.RollbackJobs)
+		}
+
+		if len(stage.Edges) > 0 {
+			fmt.Fprintln(w, "    Dependencies:")
+			for _, edge := range stage.Edges {
+				switch {
+				case edge.Optional:
+					fmt.Fprintf(w, "      %s -> %s (optional)\n", edge.From, edge.To)
+				case edge.On != "":
+					fmt.Fprintf(w, "      %s -> %s (on %s)\n", edge.From, edge.To, edge.On)
+				default:
+					fmt.Fprintf(w, "      %s -> %s\n", edge.From, edge.To)
+				}
+			}
+		}
+	}
+}
+
+// writeJobs writes the jobs of a stage outline with their tags, priority and config
+func writeJobs(w io.Writer, jobs []jobOutline) {
+	for _, job := range jobs {
+		fmt.Fprintf(w, "      %s (%s)\n", job.Name, job.Type)
+		if len(job.Tags) > 0 {
+			fmt.Fprintf(w, "        tags: %s\n", strings.Join(job.Tags, ", "))
+		}
+		if job.Priority != 0 {
+			fmt.Fprintf(w, "        priority: %d\n", job.Priority)
+		}
+		if len(job.OptionalDependsOn) > 0 {
+			fmt.Fprintf(w, "        optional dependencies: %s\n", strings.Join(job.OptionalDependsOn, ", "))
+		}
+		writeValues(w, job.Config, 4)
+	}
+}
+
+// writeValues writes a map of values as an indented tree, in key order
+func writeValues(w io.Writer, values map[string]interface{}, depth int) {
+	indent := strings.Repeat("  ", depth)
+
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		switch value := values[key].(type) {
+		case map[string]interface{}:
+			fmt.Fprintf(w, "%s%s:\n", indent, key)
+			writeValues(w, value, depth+1)
+		case string:
+			// Indent multi-line values such as inline manifests under their key
+			if strings.Contains(value, "\n") {
+				fmt.Fprintf(w, "%s%s: |\n", indent, key)
+				for _, line := range strings.Split(strings.TrimRight(value, "\n"), "\n") {
+					fmt.Fprintf(w, "%s  %s\n", indent, line)
+				}
+				continue
+			}
+			fmt.Fprintf(w, "%s%s: %s\n", indent, key, value)
+		default:
+			fmt.Fprintf(w, "%s%s: %v\n", indent, key, value)
+		}
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(describeCmd)
+	describeCmd.Flags().StringP("output", "o", "text", "Output format: text or json")
+	describeCmd.Flags().Bool("strict", false, "Reject fields that are not part of the plan schema")
+	describeCmd.Flags().StringArray("values", nil, "YAML file of variables overriding the plan's; repeat to merge several in order")
+	describeCmd.Flags().StringArray("set", nil, "Override a variable as key.path=value, after the --values files; repeatable")
+	describeCmd.Flags().String("environment", "", "Apply this environment from the plan's environments block")
+	describeCmd.RegisterFlagCompletionFunc("environment", completeEnvironments)
+}
@@ -0,0 +1,57 @@
+package cmd
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/cuongtl1992/grp-cli/internal/models"
+	"github.com/cuongtl1992/grp-cli/internal/secrets"
+)
+
+func TestDescribePlan(t *testing.T) {
+	plan := &models.Plan{
+		Metadata:  models.Metadata{Name: "release", Owner: "DevOps"},
+		Variables: map[string]interface{}{"app": map[string]interface{}{"name": "web"}, "token": "s3cr3t"},
+		Stages: []models.Stage{{
+			Name:            "deploy",
+			RequireApproval: true,
+			Approvers:       []string{"ops@example.com"},
+			Jobs: []models.Job{
+				{Name: "build", Type: "docker", Config: map[string]interface{}{"image": "web"}},
+				{Name: "release", Type: "http", DependsOn: []string{"build"}, Config: map[string]interface{}{
+					"headers": map[string]interface{}{"Authorization": "Bearer s3cr3t"},
+				}},
+			},
+		}},
+	}
+
+	outline := outlinePlan(plan, secrets.NewMasker("s3cr3t"))
+	if edges := outline.Stages[0].Edges; len(edges) != 1 || edges[0] != (edgeOutline{From: "build", To: "release"}) {
+		t.Errorf("Expected edge build -> release, got %v", edges)
+	}
+
+	buf := new(bytes.Buffer)
+	writePlanOutline(buf, outline)
+	output := buf.String()
+
+	expected := []string{
+		"Plan:        release\n",
+		"Owner:       DevOps\n",
+		"  app:\n    name: web\n",
+		"  token: ***\n",
+		"    Approval: required (ops@example.com)\n",
+		"      release (http)\n",
+		"          Authorization: Bearer ***\n",
+		"    Dependencies:\n      build -> release\n",
+		"Rollback: none\n",
+	}
+	for _, line := range expected {
+		if !strings.Contains(output, line) {
+			t.Errorf("Expected output to contain %q, got:\n%s", line, output)
+		}
+	}
+	if strings.Contains(output, "s3cr3t") {
+		t.Errorf("Expected secrets to be masked, got:\n%s", output)
+	}
+}
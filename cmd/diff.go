@@ -0,0 +1,367 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+
+	"github.com/spf13/cobra"
+
+	"github.com/cuongtl1992/grp-cli/internal/config"
+	"github.com/cuongtl1992/grp-cli/internal/secrets"
+)
+
+// Kinds of change reported by the diff command
+const (
+	changeAdded    = "added"
+	changeRemoved  = "removed"
+	changeModified = "modified"
+)
+
+// planDiff is the semantic difference between two resolved plans printed by the
+// diff command. Stages and jobs are matched by name.
+type planDiff struct {
+	Old      string        `json:"old"`
+	New      string        `json:"new"`
+	Changed  bool          `json:"changed"`
+	Fields   []valueChange `json:"fields,omitempty"`
+	Stages   []stageDiff   `json:"stages,omitempty"`
+	Rollback []stageDiff   `json:"rollback,omitempty"`
+}
+
+// stageDiff is an added, removed or modified stage
+type stageDiff struct {
+	Name   string        `json:"name"`
+	Change string        `json:"change"`
+	Fields []valueChange `json:"fields,omitempty"`
+	Jobs   []jobDiff     `json:"jobs,omitempty"`
+}
+
+// jobDiff is an added, removed or modified job. Kind tells the list of the stage
+// the job belongs to: job, preJob, postJob or rollbackJob.
+type jobDiff struct {
+	Name    string        `json:"name"`
+	Kind    string        `json:"kind"`
+	Type    string        `json:"type"`
+	Change  string        `json:"change"`
+	Changes []valueChange `json:"changes,omitempty"`
+}
+
+// valueChange is a field or config key whose value was added, removed or changed.
+// Nested config keys are joined with dots, e.g. config.image.tag.
+type valueChange struct {
+	Path   string      `json:"path"`
+	Change string      `json:"change"`
+	Old    interface{} `json:"old,omitempty"`
+	New    interface{} `json:"new,omitempty"`
+}
+
+// diffCmd represents the diff command
+var diffCmd = &cobra.Command{
+	Use:   "diff [old plan] [new plan]",
+	Short: "Show the semantic differences between two release plans",
+	Long: `Load and resolve two release plans and show how the second one differs from the
+first: added, removed and modified stages and jobs, changed dependencies and the
+config keys whose resolved values changed. Stages and jobs are matched by name,
+and secrets are masked. Nothing is executed.`,
+	Args:              cobra.ExactArgs(2),
+	ValidArgsFunction: completePlanFiles,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		outputFormat, _ := cmd.Flags().GetString("output")
+		if outputFormat != "text" && outputFormat != "json" {
+			return fmt.Errorf("unsupported output format: %s (expected text or json)", outputFormat)
+		}
+
+		var outlines [2]planOutline
+		for i, file := range args {
+			loader, err := newPlanLoader(cmd)
+			if err != nil {
+				return err
+			}
+			plan, err := loader.LoadPlan(file)
+			if err != nil {
+				return fmt.Errorf("failed to load plan %s: %w", file, err)
+			}
+			if err := config.NewValidator().ValidatePlan(plan); err != nil {
+				return fmt.Errorf("validation of %s failed: %w", file, err)
+			}
+			outlines[i] = outlinePlan(plan, secrets.NewMasker(loader.Secrets()...))
+		}
+
+		diff := diffPlans(outlines[0], outlines[1])
+		diff.Old, diff.New = args[0], args[1]
+
+		if outputFormat == "json" {
+			encoder := json.NewEncoder(cmd.OutOrStdout())
+			encoder.SetIndent("", "  ")
+			return encoder.Encode(diff)
+		}
+
+		writePlanDiff(cmd.OutOrStdout(), diff)
+		return nil
+	},
+}
+
+// completePlanFiles completes both plan file arguments of the diff command
+func completePlanFiles(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) > 1 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	return []string{"yaml", "yml"}, cobra.ShellCompDirectiveFilterFileExt
+}
+
+// diffPlans returns the differences between the outlines of two plans
+func diffPlans(old, new planOutline) planDiff {
+	diff := planDiff{
+		Stages:   diffStages(old.Stages, new.Stages),
+		Rollback: diffStages(old.Rollback, new.Rollback),
+	}
+	diff.Fields = append(diff.Fields, diffField("metadata.name", old.Name, new.Name)...)
+	diff.Fields = append(diff.Fields, diffField("metadata.description", old.Description, new.Description)...)
+	diff.Fields = append(diff.Fields, diffField("metadata.owner", old.Owner, new.Owner)...)
+	diff.Fields = append(diff.Fields, diffField("metadata.version", old.Version, new.Version)...)
+	diff.Fields = append(diff.Fields, diffValues("variables", old.Variables, new.Variables)...)
+	diff.Changed = len(diff.Fields) > 0 || len(diff.Stages) > 0 || len(diff.Rollback) > 0
+	return diff
+}
+
+// diffStages matches stages by name and returns those added, removed or
+// modified, in the order of the new plan followed by the removed stages
+func diffStages(old, new []stageOutline) []stageDiff {
+	oldStages := make(map[string]stageOutline, len(old))
+	for _, stage := range old {
+		oldStages[stage.Name] = stage
+	}
+	newStages := make(map[string]bool, len(new))
+
+	var diffs []stageDiff
+	for _, stage := range new {
+		newStages[stage.Name] = true
+		previous, found := oldStages[stage.Name]
+		if !found {
+			diffs = append(diffs, stageDiff{Name: stage.Name, Change: changeAdded, Jobs: diffStageJobs(stageOutline{}, stage)})
+			continue
+		}
+
+		diff := stageDiff{Name: stage.Name, Change: changeModified}
+		diff.Fields = append(diff.Fields, diffField("description", previous.Description, stage.Description)...)
+		diff.Fields = append(diff.Fields, diffField("dependsOn", previous.DependsOn, stage.DependsOn)...)
+		diff.Fields = append(diff.Fields, diffField("requireApproval", previous.RequireApproval, stage.RequireApproval)...)
+		diff.Fields = append(diff.Fields, diffField("approvers", previous.Approvers, stage.Approvers)...)
+		diff.Fields = append(diff.Fields, diffField("tags", previous.Tags, stage.Tags)...)
+		diff.Jobs = diffStageJobs(previous, stage)
+		if len(diff.Fields) > 0 || len(diff.Jobs) > 0 {
+			diffs = append(diffs, diff)
+		}
+	}
+	for _, stage := range old {
+		if !newStages[stage.Name] {
+			diffs = append(diffs, stageDiff{Name: stage.Name, Change: changeRemoved, Jobs: diffStageJobs(stage, stageOutline{})})
+		}
+	}
+	return diffs
+}
+
+// diffStageJobs returns the differences between the hooks, jobs and rollback jobs
+// of two versions of a stage
+func diffStageJobs(old, new stageOutline) []jobDiff {
+	var diffs []jobDiff
+	diffs = append(diffs, diffJobs("preJob", old.PreJobs, new.PreJobs)...)
+	diffs = append(diffs, diffJobs("job", old.Jobs, new.Jobs)...)
+	diffs = append(diffs, diffJobs("postJob", old.PostJobs, new.PostJobs)...)
+	diffs = append(diffs, diffJobs("rollbackJob", old.RollbackJobs, new.RollbackJobs)...)
+	return diffs
+}
+
+// diffJobs matches jobs by name and returns those added, removed or modified, in
+// the order of the new list followed by the removed jobs
+func diffJobs(kind string, old, new []jobOutline) []jobDiff {
+	oldJobs := make(map[string]jobOutline, len(old))
+	for _, job := range old {
+		oldJobs[job.Name] = job
+	}
+	newJobs := make(map[string]bool, len(new))
+
+	var diffs []jobDiff
+	for _, job := range new {
+		newJobs[job.Name] = true
+		previous, found := oldJobs[job.Name]
+		if !found {
+			diffs = append(diffs, jobDiff{Name: job.Name, Kind: kind, Type: job.Type, Change: changeAdded})
+			continue
+		}
+
+		diff := jobDiff{Name: job.Name, Kind: kind, Type: job.Type, Change: changeModified}
+		diff.Changes = append(diff.Changes, diffField("type", previous.Type, job.Type)...)
+		diff.Changes = append(diff.Changes, diffField("dependsOn", previous.DependsOn, job.DependsOn)...)
+		diff.Changes = append(diff.Changes, diffField("dependsOnConditions", previous.DependsOnConditions, job.DependsOnConditions)...)
+		diff.Changes = append(diff.Changes, diffField("optionalDependsOn", previous.OptionalDependsOn, job.OptionalDependsOn)...)
+		diff.Changes = append(diff.Changes, diffField("tags", previous.Tags, job.Tags)...)
+		diff.Changes = append(diff.Changes, diffField("priority", previous.Priority, job.Priority)...)
+		diff.Changes = append(diff.Changes, diffValues("config", previous.Config, job.Config)...)
+		if len(diff.Changes) > 0 {
+			diffs = append(diffs, diff)
+		}
+	}
+	for _, job := range old {
+		if !newJobs[job.Name] {
+			diffs = append(diffs, jobDiff{Name: job.Name, Kind: kind, Type: job.Type, Change: changeRemoved})
+		}
+	}
+	return diffs
+}
+
+// diffField returns the change of a field, or nothing if its value did not
+// change. Empty values count as unset.
+func diffField(path string, old, new interface{}) []valueChange {
+	return diffValue(path, emptyToNil(old), emptyToNil(new))
+}
+
+// diffValue returns the change of a value, or nothing if it did not change. A nil
+// value is unset.
+func diffValue(path string, old, new interface{}) []valueChange {
+	switch {
+	case reflect.DeepEqual(old, new):
+		return nil
+	case old == nil:
+		return []valueChange{{Path: path, Change: changeAdded, New: new}}
+	case new == nil:
+		return []valueChange{{Path: path, Change: changeRemoved, Old: old}}
+	}
+	return []valueChange{{Path: path, Change: changeModified, Old: old, New: new}}
+}
+
+// diffValues returns the changes between two maps of values, such as job configs,
+// descending into nested maps. Keys are reported in order under path.
+func diffValues(path string, old, new map[string]interface{}) []valueChange {
+	keys := make(map[string]bool)
+	for key := range old {
+		keys[key] = true
+	}
+	for key := range new {
+		keys[key] = true
+	}
+	sorted := make([]string, 0, len(keys))
+	for key := range keys {
+		sorted = append(sorted, key)
+	}
+	sort.Strings(sorted)
+
+	var changes []valueChange
+	for _, key := range sorted {
+		oldMap, oldIsMap := old[key].(map[string]interface{})
+		newMap, newIsMap := new[key].(map[string]interface{})
+		if oldIsMap && newIsMap {
+			changes = append(changes, diffValues(path+"."+key, oldMap, newMap)...)
+			continue
+		}
+		changes = append(changes, diffValue(path+"."+key, old[key], new[key])...)
+	}
+	return changes
+}
+
+// emptyToNil returns nil for zero values and empty lists and maps, so that an
+// unset field and an empty one compare equal
+func emptyToNil(value interface{}) interface{} {
+	if value == nil {
+		return nil
+	}
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.Slice, reflect.Map:
+		if v.Len() == 0 {
+			return nil
+		}
+	case reflect.String, reflect.Bool, reflect.Int:
+		if v.IsZero() {
+			return nil
+		}
+	}
+	return value
+}
+
+// writePlanDiff writes a human-readable summary of the differences between two
+// plans: + marks added stages, jobs and keys, - removed ones and ~ modified ones
+func writePlanDiff(w io.Writer, diff planDiff) {
+	fmt.Fprintf(w, "--- %s\n+++ %s\n", diff.Old, diff.New)
+	if !diff.Changed {
+		fmt.Fprintln(w, "No differences")
+		return
+	}
+
+	writeValueChanges(w, diff.Fields, "")
+	writeStageDiffs(w, "stage", diff.Stages)
+	writeStageDiffs(w, "rollback stage", diff.Rollback)
+}
+
+// writeStageDiffs writes each changed stage with its changed fields and jobs
+func writeStageDiffs(w io.Writer, label string, stages []stageDiff) {
+	for _, stage := range stages {
+		fmt.Fprintf(w, "%s %s %s\n", changeMarker(stage.Change), label, stage.Name)
+		writeValueChanges(w, stage.Fields, "    ")
+		for _, job := range stage.Jobs {
+			fmt.Fprintf(w, "  %s %s %s (%s)\n", changeMarker(job.Change), jobKindLabel(job.Kind), job.Name, job.Type)
+			writeValueChanges(w, job.Changes, "      ")
+		}
+	}
+}
+
+// writeValueChanges writes each changed field or config key
+func writeValueChanges(w io.Writer, changes []valueChange, indent string) {
+	for _, change := range changes {
+		switch change.Change {
+		case changeAdded:
+			fmt.Fprintf(w, "%s+ %s: %s\n", indent, change.Path, formatDiffValue(change.New))
+		case changeRemoved:
+			fmt.Fprintf(w, "%s- %s: %s\n", indent, change.Path, formatDiffValue(change.Old))
+		default:
+			fmt.Fprintf(w, "%s~ %s: %s -> %s\n", indent, change.Path, formatDiffValue(change.Old), formatDiffValue(change.New))
+		}
+	}
+}
+
+// changeMarker returns the marker of a kind of change in the text output
+func changeMarker(change string) string {
+	switch change {
+	case changeAdded:
+		return "+"
+	case changeRemoved:
+		return "-"
+	}
+	return "~"
+}
+
+// jobKindLabel returns how a kind of job is named in the text output
+func jobKindLabel(kind string) string {
+	switch kind {
+	case "preJob":
+		return "pre job"
+	case "postJob":
+		return "post job"
+	case "rollbackJob":
+		return "rollback job"
+	}
+	return "job"
+}
+
+// formatDiffValue renders a value on a single line as JSON, so that strings are
+// quoted and lists and maps stay readable
+func formatDiffValue(value interface{}) string {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(data)
+}
+
+func init() {
+	rootCmd.AddCommand(diffCmd)
+	diffCmd.Flags().StringP("output", "o", "text", "Output format: text or json")
+	diffCmd.Flags().Bool("strict", false, "Reject fields that are not part of the plan schema")
+	diffCmd.Flags().StringArray("values", nil, "YAML file of variables overriding the plans'; repeat to merge several in order")
+	diffCmd.Flags().StringArray("set", nil, "Override a variable as key.path=value, after the --values files; repeatable")
+	diffCmd.Flags().String("environment", "", "Apply this environment from the plans' environments block")
+	diffCmd.RegisterFlagCompletionFunc("environment", completeEnvironments)
+}
@@ -0,0 +1,205 @@
+package cmd
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/cuongtl1992/grp-cli/internal/models"
+	"github.com/cuongtl1992/grp-cli/internal/secrets"
+)
+
+func TestDiffPlans(t *testing.T) {
+	base := func() *models.Plan {
+		return &models.Plan{
+			Metadata:  models.Metadata{Name: "release", Version: "1.0"},
+			Variables: map[string]interface{}{"tag": "1.0"},
+			Stages: []models.Stage{
+				{Name: "build", Jobs: []models.Job{{Name: "image", Type: "docker", Config: map[string]interface{}{"tag": "1.0"}}}},
+				{Name: "deploy", DependsOn: []string{"build"}, Jobs: []models.Job{
+					{Name: "web", Type: "kubernetes", Config: map[string]interface{}{
+						"replicas": 0,
+						"image":    map[string]interface{}{"name": "web", "tag": "1.0"},
+					}},
+					{Name: "legacy", Type: "shell"},
+				}},
+			},
+		}
+	}
+
+	tests := []struct {
+		name     string
+		change   func(plan *models.Plan)
+		expected planDiff
+	}{
+		{
+			name:     "no changes",
+			change:   func(plan *models.Plan) {},
+			expected: planDiff{},
+		},
+		{
+			name: "metadata and variables",
+			change: func(plan *models.Plan) {
+				plan.Metadata.Version = "1.1"
+				plan.Variables["tag"] = "1.1"
+				plan.Variables["region"] = "eu"
+			},
+			expected: planDiff{Changed: true, Fields: []valueChange{
+				{Path: "metadata.version", Change: changeModified, Old: "1.0", New: "1.1"},
+				{Path: "variables.region", Change: changeAdded, New: "eu"},
+				{Path: "variables.tag", Change: changeModified, Old: "1.0", New: "1.1"},
+			}},
+		},
+		{
+			name: "modified jobs and config keys",
+			change: func(plan *models.Plan) {
+				web := &plan.Stages[1].Jobs[0]
+				web.DependsOn = []string{"legacy"}
+				web.Config = map[string]interface{}{
+					"image":   map[string]interface{}{"name": "web", "tag": "1.1"},
+					"timeout": "5m",
+				}
+			},
+			expected: planDiff{Changed: true, Stages: []stageDiff{{
+				Name:   "deploy",
+				Change: changeModified,
+				Jobs: []jobDiff{{Name: "web", Kind: "job", Type: "kubernetes", Change: changeModified, Changes: []valueChange{
+					{Path: "dependsOn", Change: changeAdded, New: []string{"legacy"}},
+					{Path: "config.image.tag", Change: changeModified, Old: "1.0", New: "1.1"},
+					{Path: "config.replicas", Change: changeRemoved, Old: 0},
+					{Path: "config.timeout", Change: changeAdded, New: "5m"},
+				}}},
+			}}},
+		},
+		{
+			name: "added and removed stages and jobs",
+			change: func(plan *models.Plan) {
+				plan.Stages[1].Jobs = append(plan.Stages[1].Jobs[:1], models.Job{Name: "smoke", Type: "http"})
+				plan.Stages[1].RequireApproval = true
+				plan.Stages = []models.Stage{plan.Stages[1], {Name: "verify", Jobs: []models.Job{{Name: "check", Type: "http"}}}}
+			},
+			expected: planDiff{Changed: true, Stages: []stageDiff{
+				{
+					Name:   "deploy",
+					Change: changeModified,
+					Fields: []valueChange{{Path: "requireApproval", Change: changeAdded, New: true}},
+					Jobs: []jobDiff{
+						{Name: "smoke", Kind: "job", Type: "http", Change: changeAdded},
+						{Name: "legacy", Kind: "job", Type: "shell", Change: changeRemoved},
+					},
+				},
+				{Name: "verify", Change: changeAdded, Jobs: []jobDiff{{Name: "check", Kind: "job", Type: "http", Change: changeAdded}}},
+				{Name: "build", Change: changeRemoved, Jobs: []jobDiff{{Name: "image", Kind: "job", Type: "docker", Change: changeRemoved}}},
+			}},
+		},
+		{
+			name: "rollback stages and hooks",
+			change: func(plan *models.Plan) {
+				plan.Stages[0].PostJobs = []models.Job{{Name: "notify", Type: "http"}}
+				plan.Rollback = &models.Rollback{Stages: []models.Stage{{Name: "restore", Jobs: []models.Job{{Name: "db", Type: "shell"}}}}}
+			},
+			expected: planDiff{
+				Changed: true,
+				Stages: []stageDiff{{Name: "build", Change: changeModified, Jobs: []jobDiff{
+					{Name: "notify", Kind: "postJob", Type: "http", Change: changeAdded},
+				}}},
+				Rollback: []stageDiff{{Name: "restore", Change: changeAdded, Jobs: []jobDiff{
+					{Name: "db", Kind: "job", Type: "shell", Change: changeAdded},
+				}}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			masker := secrets.NewMasker()
+			plan := base()
+			tt.change(plan)
+
+			diff := diffPlans(outlinePlan(base(), masker), outlinePlan(plan, masker))
+			if !reflect.DeepEqual(diff, tt.expected) {
+				t.Errorf("Expected %+v, got %+v", tt.expected, diff)
+			}
+		})
+	}
+}
+
+func TestWritePlanDiff(t *testing.T) {
+	diff := planDiff{
+		Old:     "old.yaml",
+		New:     "new.yaml",
+		Changed: true,
+		Fields:  []valueChange{{Path: "variables.tag", Change: changeModified, Old: "1.0", New: "1.1"}},
+		Stages: []stageDiff{
+			{
+				Name:   "deploy",
+				Change: changeModified,
+				Fields: []valueChange{{Path: "approvers", Change: changeAdded, New: []string{"ops"}}},
+				Jobs: []jobDiff{
+					{Name: "web", Kind: "job", Type: "kubernetes", Change: changeModified, Changes: []valueChange{
+						{Path: "config.replicas", Change: changeModified, Old: 2, New: 3},
+						{Path: "config.timeout", Change: changeRemoved, Old: "5m"},
+					}},
+					{Name: "drain", Kind: "preJob", Type: "shell", Change: changeAdded},
+				},
+			},
+			{Name: "build", Change: changeRemoved},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		diff     planDiff
+		expected string
+	}{
+		{
+			name: "changes",
+			diff: diff,
+			expected: `--- old.yaml
++++ new.yaml
+~ variables.tag: "1.0" -> "1.1"
+~ stage deploy
+    + approvers: ["ops"]
+  ~ job web (kubernetes)
+      ~ config.replicas: 2 -> 3
+      - config.timeout: "5m"
+  + pre job drain (shell)
+- stage build
+`,
+		},
+		{
+			name:     "no changes",
+			diff:     planDiff{Old: "a.yaml", New: "b.yaml"},
+			expected: "--- a.yaml\n+++ b.yaml\nNo differences\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := new(bytes.Buffer)
+			writePlanDiff(buf, tt.diff)
+			if buf.String() != tt.expected {
+				t.Errorf("Expected:\n%s\ngot:\n%s", tt.expected, buf.String())
+			}
+		})
+	}
+}
+
+func TestDiffPlansMasksSecrets(t *testing.T) {
+	plan := func(token string) *models.Plan {
+		return &models.Plan{
+			Metadata: models.Metadata{Name: "release"},
+			Stages: []models.Stage{{Name: "deploy", Jobs: []models.Job{
+				{Name: "notify", Type: "http", Config: map[string]interface{}{"url": "https://example.com?token=" + token}},
+			}}},
+		}
+	}
+
+	diff := diffPlans(outlinePlan(plan("old-s3cr3t"), secrets.NewMasker("old-s3cr3t")), outlinePlan(plan("new-s3cr3t"), secrets.NewMasker("new-s3cr3t")))
+	buf := new(bytes.Buffer)
+	writePlanDiff(buf, diff)
+	if strings.Contains(buf.String(), "s3cr3t") {
+		t.Errorf("Expected secrets to be masked, got:\n%s", buf.String())
+	}
+}
@@ -0,0 +1,85 @@
+package cmd
+
+import (
+	"errors"
+
+	"github.com/cuongtl1992/grp-cli/internal/models"
+)
+
+// Exit codes of grp-cli, so that CI pipelines can tell why a release failed
+const (
+	// ExitOK means the command succeeded
+	ExitOK = 0
+	// ExitError is used for usage errors and failures not covered below
+	ExitError = 1
+	// ExitValidation means the plan could not be loaded or is invalid
+	ExitValidation = 2
+	// ExitExecutionFailed means a stage of the plan failed
+	ExitExecutionFailed = 3
+	// ExitRollbackFailed means the execution failed and so did its rollback
+	ExitRollbackFailed = 4
+	// ExitApprovalDenied means a stage approval was rejected or expired
+	ExitApprovalDenied = 5
+	// ExitTimeout means the execution exceeded its timeout
+	ExitTimeout = 6
+	// ExitCanceled means the execution was interrupted, as shells report SIGINT
+	ExitCanceled = 130
+)
+
+// exitError is an error carrying the exit code the CLI ends with
+type exitError struct {
+	code int
+	err  error
+}
+
+func (e *exitError) Error() string {
+	return e.err.Error()
+}
+
+func (e *exitError) Unwrap() error {
+	return e.err
+}
+
+// withExitCode attaches an exit code to err; a nil err stays nil
+func withExitCode(code int, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &exitError{code: code, err: err}
+}
+
+// exitCode returns the exit code for an error returned by a command
+func exitCode(err error) int {
+	if err == nil {
+		return ExitOK
+	}
+
+	var exitErr *exitError
+	if errors.As(err, &exitErr) {
+		return exitErr.code
+	}
+	return ExitError
+}
+
+// executionExitCode classifies a failed execution by its result. A timeout or an
+// interrupt takes precedence over the failures it caused, and a failed rollback
+// over the failure that triggered it.
+func executionExitCode(result *models.ExecutionResult) int {
+	switch {
+	case result == nil:
+		return ExitExecutionFailed
+	case result.TimedOut:
+		return ExitTimeout
+	case result.Canceled:
+		return ExitCanceled
+	case result.Rollback != nil && !result.Rollback.Success:
+		return ExitRollbackFailed
+	}
+
+	for _, stage := range result.Stages {
+		if stage.Approval == models.ApprovalStatusRejected || stage.Approval == models.ApprovalStatusExpired {
+			return ExitApprovalDenied
+		}
+	}
+	return ExitExecutionFailed
+}
@@ -0,0 +1,58 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/cuongtl1992/grp-cli/internal/models"
+)
+
+func TestExitCode(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		expected int
+	}{
+		{name: "success", err: nil, expected: ExitOK},
+		{name: "plain error", err: errors.New("unknown flag: --nope"), expected: ExitError},
+		{name: "validation error", err: withExitCode(ExitValidation, errors.New("validation failed")), expected: ExitValidation},
+		{name: "wrapped", err: fmt.Errorf("run: %w", withExitCode(ExitTimeout, errors.New("plan timed out"))), expected: ExitTimeout},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if code := exitCode(tt.err); code != tt.expected {
+				t.Errorf("Expected exit code %d, got %d", tt.expected, code)
+			}
+		})
+	}
+}
+
+func TestExecutionExitCode(t *testing.T) {
+	failedRollback := &models.RollbackResult{Success: false}
+	rejected := []models.StageResult{{Name: "prod", Approval: models.ApprovalStatusRejected}}
+
+	tests := []struct {
+		name     string
+		result   *models.ExecutionResult
+		expected int
+	}{
+		{name: "no result", result: nil, expected: ExitExecutionFailed},
+		{name: "failed stage", result: &models.ExecutionResult{Stages: []models.StageResult{{Name: "deploy"}}}, expected: ExitExecutionFailed},
+		{name: "rejected approval", result: &models.ExecutionResult{Stages: rejected}, expected: ExitApprovalDenied},
+		{name: "expired approval", result: &models.ExecutionResult{Stages: []models.StageResult{{Name: "prod", Approval: models.ApprovalStatusExpired}}}, expected: ExitApprovalDenied},
+		{name: "rollback failed after rejection", result: &models.ExecutionResult{Stages: rejected, Rollback: failedRollback}, expected: ExitRollbackFailed},
+		{name: "rollback succeeded", result: &models.ExecutionResult{Rollback: &models.RollbackResult{Success: true}}, expected: ExitExecutionFailed},
+		{name: "timed out", result: &models.ExecutionResult{TimedOut: true, Rollback: failedRollback}, expected: ExitTimeout},
+		{name: "canceled", result: &models.ExecutionResult{Canceled: true}, expected: ExitCanceled},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if code := executionExitCode(tt.result); code != tt.expected {
+				t.Errorf("Expected exit code %d, got %d", tt.expected, code)
+			}
+		})
+	}
+}
@@ -0,0 +1,159 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/cuongtl1992/grp-cli/internal/config"
+	"github.com/cuongtl1992/grp-cli/internal/engine"
+	"github.com/cuongtl1992/grp-cli/internal/models"
+)
+
+// Supported graph output formats
+const (
+	graphFormatDOT     = "dot"
+	graphFormatMermaid = "mermaid"
+)
+
+// graphCmd represents the graph command
+var graphCmd = &cobra.Command{
+	Use:   "graph [plan file]",
+	Short: "Visualize the job dependencies of a release plan",
+	Long: `Print the job dependency graph of each stage in a release plan, in Graphviz DOT
+or Mermaid format. Nodes are jobs and edges point from a job to the jobs that depend on it.
+
+Render a DOT graph with: grp-cli graph plan.yaml | dot -Tpng -o plan.png`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completePlanFile,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		format, _ := cmd.Flags().GetString("format")
+		if format != graphFormatDOT && format != graphFormatMermaid {
+			return fmt.Errorf("unsupported graph format %q (expected dot or mermaid)", format)
+		}
+		stageName, _ := cmd.Flags().GetString("stage")
+
+		// Load and validate the plan
+		loader, err := newPlanLoader(cmd)
+		if err != nil {
+			return err
+		}
+		plan, err := loader.LoadPlan(args[0])
+		if err != nil {
+			return fmt.Errorf("failed to load plan: %w", err)
+		}
+		if err := config.NewValidator().ValidatePlan(plan); err != nil {
+			return fmt.Errorf("validation failed: %w", err)
+		}
+
+		stages, err := selectStages(plan, stageName)
+		if err != nil {
+			return err
+		}
+
+		if format == graphFormatMermaid {
+			writeMermaidGraph(cmd.OutOrStdout(), stages)
+		} else {
+			writeDOTGraph(cmd.OutOrStdout(), plan.Metadata.Name, stages)
+		}
+		return nil
+	},
+}
+
+// selectStages returns all stages of the plan, or only the named one
+func selectStages(plan *models.Plan, stageName string) ([]models.Stage, error) {
+	if stageName == "" {
+		return plan.Stages, nil
+	}
+
+	for _, stage := range plan.Stages {
+		if stage.Name == stageName {
+			return []models.Stage{stage}, nil
+		}
+	}
+	return nil, fmt.Errorf("stage %s not found in plan", stageName)
+}
+
+// writeDOTGraph writes the job graphs as a Graphviz digraph with one cluster per stage
+func writeDOTGraph(w io.Writer, name string, stages []models.Stage) {
+	fmt.Fprintf(w, "digraph %s {\n", dotQuote(name))
+	fmt.Fprintln(w, "  rankdir=LR;")
+	for i, stage := range stages {
+		graph := engine.BuildDependencyGraph(stage.Jobs)
+
+		fmt.Fprintf(w, "  subgraph cluster_%d {\n", i)
+		fmt.Fprintf(w, "    label=%s;\n", dotQuote(stage.Name))
+		for _, job := range graph.Jobs() {
+			fmt.Fprintf(w, "    %s [label=%s];\n", dotQuote(nodeID(stage.Name, job.Name)), dotQuote(job.Name+"\n("+job.Type+")"))
+		}
+		for _, edge := range graph.Edges() {
+			style := ""
+			switch {
+			case edge.Optional:
+				style = " [style=dashed]"
+			case edge.On != "":
+				style = fmt.Sprintf(" [label=%s]", dotQuote(edge.On))
+			}
+			fmt.Fprintf(w, "    %s -> %s%s;\n", dotQuote(nodeID(stage.Name, edge.From)), dotQuote(nodeID(stage.Name, edge.To)), style)
+		}
+		fmt.Fprintln(w, "  }")
+	}
+	fmt.Fprintln(w, "}")
+}
+
+// writeMermaidGraph writes the job graphs as a Mermaid flowchart with one subgraph per stage
+func writeMermaidGraph(w io.Writer, stages []models.Stage) {
+	fmt.Fprintln(w, "flowchart LR")
+	for i, stage := range stages {
+		graph := engine.BuildDependencyGraph(stage.Jobs)
+
+		// Mermaid IDs must be plain identifiers, so jobs are numbered within their stage
+		ids := make(map[string]string)
+		fmt.Fprintf(w, "  subgraph s%d [%s]\n", i, mermaidQuote(stage.Name))
+		for j, job := range graph.Jobs() {
+			ids[job.Name] = fmt.Sprintf("s%d_j%d", i, j)
+			fmt.Fprintf(w, "    %s[%s]\n", ids[job.Name], mermaidQuote(job.Name+" ("+job.Type+")"))
+		}
+		for _, edge := range graph.Edges() {
+			arrow := "-->"
+			switch {
+			case edge.Optional:
+				arrow = "-.->"
+			case edge.On != "":
+				arrow = "-->|" + edge.On + "|"
+			}
+			fmt.Fprintf(w, "    %s %s %s\n", ids[edge.From], arrow, ids[edge.To])
+		}
+		fmt.Fprintln(w, "  end")
+	}
+}
+
+// nodeID identifies a job across stages, since job names are only unique within a stage
+func nodeID(stageName, jobName string) string {
+	return stageName + "/" + jobName
+}
+
+// dotQuote quotes a string as a DOT identifier
+func dotQuote(value string) string {
+	value = strings.ReplaceAll(value, `\`, `\\`)
+	value = strings.ReplaceAll(value, `"`, `\"`)
+	return `"` + strings.ReplaceAll(value, "\n", `\n`) + `"`
+}
+
+// mermaidQuote quotes a string as a Mermaid label
+func mermaidQuote(value string) string {
+	return `"` + strings.ReplaceAll(value, `"`, "#quot;") + `"`
+}
+
+func init() {
+	rootCmd.AddCommand(graphCmd)
+
+	graphCmd.Flags().String("format", graphFormatDOT, "Output format: dot or mermaid")
+	graphCmd.Flags().String("stage", "", "Only show the jobs of this stage")
+	graphCmd.Flags().Bool("strict", false, "Reject fields that are not part of the plan schema")
+	graphCmd.Flags().String("environment", "", "Apply this environment from the plan's environments block")
+	graphCmd.RegisterFlagCompletionFunc("stage", completeStages)
+	graphCmd.RegisterFlagCompletionFunc("environment", completeEnvironments)
+}
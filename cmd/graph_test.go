@@ -0,0 +1,83 @@
+package cmd
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/cuongtl1992/grp-cli/internal/models"
+)
+
+// graphTestStages returns a stage where deploy depends on build and test, and
+// notify optionally on deploy, and undo runs when deploy fails
+func graphTestStages() []models.Stage {
+	return []models.Stage{{
+		Name: "release",
+		Jobs: []models.Job{
+			{Name: "build", Type: "shell"},
+			{Name: "test", Type: "shell"},
+			{Name: "deploy", Type: "kubernetes", DependsOn: []string{"build", "test"}},
+			{Name: "notify", Type: "http", OptionalDependsOn: []string{"deploy"}},
+			{Name: "undo", Type: "kubernetes", DependsOn: []string{"deploy"}, DependsOnConditions: map[string]string{"deploy": models.DependencyOnFailure}},
+		},
+	}}
+}
+
+func TestWriteGraph(t *testing.T) {
+	tests := []struct {
+		name     string
+		write    func(buf *bytes.Buffer)
+		expected []string
+	}{
+		{
+			name:  "dot",
+			write: func(buf *bytes.Buffer) { writeDOTGraph(buf, "my plan", graphTestStages()) },
+			expected: []string{
+				`digraph "my plan" {`,
+				`label="release";`,
+				`"release/deploy" [label="deploy\n(kubernetes)"];`,
+				`"release/build" -> "release/deploy";`,
+				`"release/test" -> "release/deploy";`,
+				`"release/deploy" -> "release/notify" [style=dashed];`,
+				`"release/deploy" -> "release/undo" [label="failure"];`,
+			},
+		},
+		{
+			name:  "mermaid",
+			write: func(buf *bytes.Buffer) { writeMermaidGraph(buf, graphTestStages()) },
+			expected: []string{
+				"flowchart LR",
+				`subgraph s0 ["release"]`,
+				`s0_j2["deploy (kubernetes)"]`,
+				"s0_j0 --> s0_j2",
+				"s0_j1 --> s0_j2",
+				"s0_j2 -.-> s0_j3",
+				"s0_j2 -->|failure| s0_j4",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := new(bytes.Buffer)
+			tt.write(buf)
+			for _, line := range tt.expected {
+				if !strings.Contains(buf.String(), line) {
+					t.Errorf("Expected output to contain %q, got:\n%s", line, buf.String())
+				}
+			}
+		})
+	}
+}
+
+func TestSelectStages(t *testing.T) {
+	plan := &models.Plan{Stages: []models.Stage{{Name: "build"}, {Name: "deploy"}}}
+
+	stages, err := selectStages(plan, "deploy")
+	if err != nil || len(stages) != 1 || stages[0].Name != "deploy" {
+		t.Errorf("Expected only the deploy stage, got %v (err %v)", stages, err)
+	}
+	if _, err := selectStages(plan, "missing"); err == nil {
+		t.Error("Expected an error for an unknown stage")
+	}
+}
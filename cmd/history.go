@@ -0,0 +1,138 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/cuongtl1992/grp-cli/internal/models"
+	"github.com/cuongtl1992/grp-cli/internal/state"
+)
+
+// historyEntry is a past execution as listed by the history command
+type historyEntry struct {
+	ID        string        `json:"id"`
+	Plan      string        `json:"plan"`
+	StartTime time.Time     `json:"startTime"`
+	Duration  time.Duration `json:"duration"`
+	Success   bool          `json:"success"`
+	Status    string        `json:"status"`
+}
+
+// historyCmd represents the history command
+var historyCmd = &cobra.Command{
+	Use:   "history",
+	Short: "List past executions",
+	Long: `List the executions recorded in the state directory, most recent first, with
+their ID, plan name, start time, duration and outcome. Executions are recorded by
+"grp-cli run" when a state directory is set with --state-dir or state.dir in the
+config file. Records that cannot be read are skipped with a warning.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		outputFormat, _ := cmd.Flags().GetString("output")
+		if outputFormat != "text" && outputFormat != "json" {
+			return fmt.Errorf("unsupported output format: %s (expected text or json)", outputFormat)
+		}
+		last, _ := cmd.Flags().GetInt("last")
+		if last < 0 {
+			return fmt.Errorf("--last must not be negative, got %d", last)
+		}
+		planName, _ := cmd.Flags().GetString("plan")
+
+		dir := stateDir(cmd)
+		if dir == "" {
+			return errors.New("no state directory: set --state-dir or state.dir in the config file")
+		}
+
+		records, errs := state.NewStore(dir).List()
+		logger := newLogger()
+		for _, err := range errs {
+			logger.Warn("Skipping execution record", "error", err)
+		}
+
+		entries := historyEntries(records, planName, last)
+		if outputFormat == "json" {
+			encoder := json.NewEncoder(cmd.OutOrStdout())
+			encoder.SetIndent("", "  ")
+			return encoder.Encode(entries)
+		}
+
+		writeHistory(cmd.OutOrStdout(), entries)
+		return nil
+	},
+}
+
+// stateDir returns the directory executions are recorded in: --state-dir if
+// set, else state.dir from the config file
+func stateDir(cmd *cobra.Command) string {
+	if dir, _ := cmd.Flags().GetString("state-dir"); dir != "" {
+		return dir
+	}
+	return viper.GetString("state.dir")
+}
+
+// historyEntries lists the records of the plan, or of all plans if planName is
+// empty, keeping the last most recent ones if last is set
+func historyEntries(records []state.Record, planName string, last int) []historyEntry {
+	entries := make([]historyEntry, 0, len(records))
+	for _, record := range records {
+		if planName != "" && record.Plan != planName {
+			continue
+		}
+		if last > 0 && len(entries) == last {
+			break
+		}
+		entries = append(entries, historyEntry{
+			ID:        record.Result.ID,
+			Plan:      record.Plan,
+			StartTime: record.Result.StartTime,
+			Duration:  record.Result.Duration,
+			Success:   record.Result.Success,
+			Status:    executionStatus(record.Result),
+		})
+	}
+	return entries
+}
+
+// executionStatus describes the outcome of an execution in a word
+func executionStatus(result *models.ExecutionResult) string {
+	switch {
+	case result.Success:
+		return "succeeded"
+	case result.TimedOut:
+		return "timed out"
+	case result.Canceled:
+		return "canceled"
+	}
+	return "failed"
+}
+
+// writeHistory writes the executions as a table
+func writeHistory(w io.Writer, entries []historyEntry) {
+	if len(entries) == 0 {
+		fmt.Fprintln(w, "No executions recorded")
+		return
+	}
+
+	table := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(table, "ID\tPLAN\tSTARTED\tDURATION\tSTATUS")
+	for _, entry := range entries {
+		fmt.Fprintf(table, "%s\t%s\t%s\t%s\t%s\n",
+			entry.ID, entry.Plan, entry.StartTime.Local().Format("2006-01-02 15:04:05"), entry.Duration.Round(time.Millisecond), entry.Status)
+	}
+	table.Flush()
+}
+
+func init() {
+	rootCmd.AddCommand(historyCmd)
+	historyCmd.Flags().String("state-dir", "", "Directory executions are recorded in (default: state.dir from the config file)")
+	historyCmd.Flags().Int("last", 0, "Only list the last N executions (0 = all)")
+	historyCmd.Flags().String("plan", "", "Only list the executions of this plan")
+	historyCmd.Flags().StringP("output", "o", "text", "Output format: text or json")
+}
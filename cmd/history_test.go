@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cuongtl1992/grp-cli/internal/models"
+	"github.com/cuongtl1992/grp-cli/internal/state"
+)
+
+func TestHistoryEntries(t *testing.T) {
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	records := []state.Record{
+		{Plan: "api", Result: &models.ExecutionResult{ID: "4", Success: true, StartTime: start.Add(3 * time.Hour)}},
+		{Plan: "web", Result: &models.ExecutionResult{ID: "3", TimedOut: true, StartTime: start.Add(2 * time.Hour)}},
+		{Plan: "api", Result: &models.ExecutionResult{ID: "2", Canceled: true, StartTime: start.Add(time.Hour)}},
+		{Plan: "api", Result: &models.ExecutionResult{ID: "1", StartTime: start}},
+	}
+
+	tests := []struct {
+		name     string
+		plan     string
+		last     int
+		expected []string
+	}{
+		{name: "all", expected: []string{"4 succeeded", "3 timed out", "2 canceled", "1 failed"}},
+		{name: "last", last: 2, expected: []string{"4 succeeded", "3 timed out"}},
+		{name: "plan", plan: "api", expected: []string{"4 succeeded", "2 canceled", "1 failed"}},
+		{name: "last of plan", plan: "api", last: 2, expected: []string{"4 succeeded", "2 canceled"}},
+		{name: "unknown plan", plan: "db", expected: []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entries := historyEntries(records, tt.plan, tt.last)
+			listed := make([]string, len(entries))
+			for i, entry := range entries {
+				listed[i] = entry.ID + " " + entry.Status
+			}
+			if strings.Join(listed, ", ") != strings.Join(tt.expected, ", ") {
+				t.Errorf("Expected %v, got %v", tt.expected, listed)
+			}
+		})
+	}
+}
+
+func TestWriteHistory(t *testing.T) {
+	var out bytes.Buffer
+	writeHistory(&out, []historyEntry{{ID: "abc", Plan: "api", Duration: 1500 * time.Millisecond, Status: "succeeded"}})
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 2 || !strings.HasPrefix(lines[0], "ID") {
+		t.Fatalf("Expected a header and one row, got %q", out.String())
+	}
+	for _, field := range []string{"abc", "api", "1.5s", "succeeded"} {
+		if !strings.Contains(lines[1], field) {
+			t.Errorf("Expected row to contain %q, got %q", field, lines[1])
+		}
+	}
+}
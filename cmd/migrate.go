@@ -0,0 +1,68 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/cuongtl1992/grp-cli/internal/config"
+)
+
+// migrateCmd represents the migrate command
+var migrateCmd = &cobra.Command{
+	Use:   "migrate [plan file]",
+	Short: "Upgrade a release plan to the current apiVersion",
+	Long: `Convert a release plan written for an older apiVersion to the current one,
+keeping its comments and field order. The migrated plan is printed to stdout,
+or written back to the plan file with --write. Included files are not changed.`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completePlanFile,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		planFile := args[0]
+		write, _ := cmd.Flags().GetBool("write")
+		if write && planFile == config.StdinPlan {
+			return fmt.Errorf("--write cannot be used with a plan read from stdin")
+		}
+
+		var data []byte
+		var err error
+		if planFile == config.StdinPlan {
+			data, err = io.ReadAll(cmd.InOrStdin())
+		} else {
+			data, err = os.ReadFile(planFile)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read plan: %w", err)
+		}
+
+		migrated, version, err := config.MigratePlan(data)
+		if err != nil {
+			return withExitCode(ExitValidation, fmt.Errorf("failed to migrate plan: %w", err))
+		}
+		if version == "" {
+			return withExitCode(ExitValidation, fmt.Errorf("failed to migrate plan: missing required field: apiVersion"))
+		}
+
+		if !write {
+			_, err := cmd.OutOrStdout().Write(migrated)
+			return err
+		}
+		if version == config.CurrentAPIVersion {
+			fmt.Fprintf(cmd.ErrOrStderr(), "%s is already at apiVersion %s\n", planFile, config.CurrentAPIVersion)
+			return nil
+		}
+		if err := os.WriteFile(planFile, migrated, 0644); err != nil {
+			return fmt.Errorf("failed to write plan: %w", err)
+		}
+		fmt.Fprintf(cmd.ErrOrStderr(), "Migrated %s from apiVersion %s to %s\n", planFile, version, config.CurrentAPIVersion)
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(migrateCmd)
+
+	migrateCmd.Flags().BoolP("write", "w", false, "Write the migrated plan back to the plan file instead of stdout")
+}
@@ -0,0 +1,379 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/cuongtl1992/grp-cli/internal/config"
+	"github.com/cuongtl1992/grp-cli/internal/models"
+	"github.com/cuongtl1992/grp-cli/internal/plugins"
+	"github.com/cuongtl1992/grp-cli/pkg/plugin"
+)
+
+// defaultPluginDir is used when --plugin-dir is not set
+const defaultPluginDir = "./plugins"
+
+// pluginsCmd represents the plugins command
+var pluginsCmd = &cobra.Command{
+	Use:   "plugins",
+	Short: "Inspect and install plugins",
+	Long:  `Inspect the plugins available to release plans and install external plugins.`,
+}
+
+// pluginSummary describes a plugin in the output of plugins list
+type pluginSummary struct {
+	Name         string              `json:"name"`
+	Version      string              `json:"version"`
+	Description  string              `json:"description"`
+	Capabilities plugin.Capabilities `json:"capabilities"`
+}
+
+// pluginsListCmd represents the plugins list command
+var pluginsListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the available plugins",
+	Long: `List the built-in plugins and the plugins loaded from the plugin directories,
+with their version and the optional features they support: rollback, preview of
+the changes a job would make, and streaming of job output.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		outputFormat, _ := cmd.Flags().GetString("output")
+		if outputFormat != "text" && outputFormat != "json" {
+			return fmt.Errorf("unsupported output format: %s (expected text or json)", outputFormat)
+		}
+
+		summaries := summarizePlugins(loadPluginManager(cmd).ListPlugins())
+		if outputFormat == "json" {
+			encoder := json.NewEncoder(cmd.OutOrStdout())
+			encoder.SetIndent("", "  ")
+			return encoder.Encode(summaries)
+		}
+
+		writePluginList(cmd.OutOrStdout(), summaries)
+		return nil
+	},
+}
+
+// pluginsDescribeCmd represents the plugins describe command
+var pluginsDescribeCmd = &cobra.Command{
+	Use:   "describe [plugin name]",
+	Short: "Show a plugin's configuration schema",
+	Long: `Show a plugin's description, version, capabilities and configuration schema,
+including property types, required fields and nested properties and items. The
+json output is the configuration schema.`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completePluginNames,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		outputFormat, _ := cmd.Flags().GetString("output")
+		if outputFormat != "text" && outputFormat != "json" {
+			return fmt.Errorf("unsupported output format: %s (expected text or json)", outputFormat)
+		}
+
+		pluginManager := loadPluginManager(cmd)
+
+		plg, err := pluginManager.GetPlugin(args[0])
+		if err != nil {
+			return err
+		}
+
+		if outputFormat == "json" {
+			encoder := json.NewEncoder(cmd.OutOrStdout())
+			encoder.SetIndent("", "  ")
+			return encoder.Encode(plg.ConfigSchema())
+		}
+
+		describePlugin(cmd.OutOrStdout(), plg)
+		return nil
+	},
+}
+
+// pluginsInspectCmd represents the plugins inspect command
+var pluginsInspectCmd = &cobra.Command{
+	Use:   "inspect [plugin file]",
+	Short: "Show the plugin provided by a plugin file",
+	Long: `Load a plugin file (.so) and show the name and version of its plugin, failing
+if it is not a plugin compatible with this grp-cli.`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completePluginFile,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		outputFormat, _ := cmd.Flags().GetString("output")
+		if outputFormat != "text" && outputFormat != "json" {
+			return fmt.Errorf("unsupported output format: %s (expected text or json)", outputFormat)
+		}
+
+		info, err := plugins.InspectFile(args[0])
+		if err != nil {
+			return fmt.Errorf("failed to load plugin %s: %w", args[0], err)
+		}
+
+		if outputFormat == "json" {
+			return json.NewEncoder(cmd.OutOrStdout()).Encode(info)
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "Plugin:  %s\nVersion: %s\n", info.Name, info.Version)
+		return nil
+	},
+}
+
+// pluginsInstallCmd represents the plugins install command
+var pluginsInstallCmd = &cobra.Command{
+	Use:   "install [path or URL]",
+	Short: "Install a plugin file into the plugin directory",
+	Long: `Copy a plugin file (.so), or download it from an http(s) URL, into the plugin
+directory, which is created if needed. The file must load as a plugin compatible
+with this grp-cli. It is installed as NAME.so after the name of its plugin, or
+replaces the file already providing that plugin; replacing a plugin of a different
+version requires --force.
+
+The plugin is installed into the first --plugin-dir, or ./plugins by default.`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completePluginFile,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		dir := defaultPluginDir
+		if dirs := pluginDirsFlag(cmd); len(dirs) > 0 {
+			dir = dirs[0]
+		}
+		force, _ := cmd.Flags().GetBool("force")
+
+		installer := plugins.NewInstaller(dir, inspectPlugin)
+		installer.SetForce(force)
+		result, err := installer.Install(cmd.Context(), args[0])
+		if err != nil {
+			return err
+		}
+
+		installed := result.Installed
+		fmt.Fprintf(cmd.OutOrStdout(), "Installed plugin %s %s to %s", installed.Name, installed.Version, installed.Path)
+		if result.Replaced != nil {
+			fmt.Fprintf(cmd.OutOrStdout(), " (replaced version %s)", result.Replaced.Version)
+		}
+		fmt.Fprintln(cmd.OutOrStdout())
+		return nil
+	},
+}
+
+// inspectPlugin inspects a plugin file with plugins inspect in a separate grp-cli
+// process, since two builds of the same plugin cannot be loaded in one process
+var inspectPlugin plugins.Inspector = func(path string) (plugins.Info, error) {
+	executable, err := os.Executable()
+	if err != nil {
+		return plugins.Info{}, err
+	}
+
+	var stderr bytes.Buffer
+	inspect := exec.Command(executable, "plugins", "inspect", "--output", "json", path)
+	inspect.Stderr = &stderr
+	output, err := inspect.Output()
+	if err != nil {
+		// Report the error line, without the usage that follows it
+		if message, _, _ := strings.Cut(stderr.String(), "\n"); message != "" {
+			return plugins.Info{}, errors.New(strings.TrimPrefix(message, "Error: "))
+		}
+		return plugins.Info{}, err
+	}
+
+	var info plugins.Info
+	if err := json.Unmarshal(output, &info); err != nil {
+		return plugins.Info{}, fmt.Errorf("failed to inspect plugin: %w", err)
+	}
+	return info, nil
+}
+
+// loadPluginManager creates a plugin manager with the built-in plugins and loads the
+// plugins from the --plugin-dir directories on top, warning instead of failing when
+// they cannot be loaded
+func loadPluginManager(cmd *cobra.Command) *plugins.Manager {
+	pluginDirs := pluginDirsFlag(cmd)
+	explicit := len(pluginDirs) > 0
+	if !explicit {
+		pluginDirs = []string{defaultPluginDir}
+	}
+
+	logger := newLogger()
+	pluginManager := plugins.NewManager(pluginDirs...)
+	pluginManager.SetLogger(logger)
+	recursive, _ := cmd.Flags().GetBool("plugin-recursive")
+	pluginManager.SetRecursive(recursive)
+
+	// The default plugin directory is optional now that plugins are built in
+	if _, err := os.Stat(defaultPluginDir); !explicit && os.IsNotExist(err) {
+		return pluginManager
+	}
+	if err := pluginManager.LoadPlugins(); err != nil {
+		logger.Warn("Failed to load plugins", "error", err)
+	}
+	return pluginManager
+}
+
+// applyRateLimits caps the executions of the plugins with the rateLimits of the
+// config file, overridden plugin by plugin by those of the plan
+func applyRateLimits(pluginManager *plugins.Manager, plan *models.Plan) error {
+	var limits map[string]models.RateLimit
+	if err := viper.UnmarshalKey("rateLimits", &limits); err != nil {
+		return fmt.Errorf("invalid rateLimits in the config file: %w", err)
+	}
+	for name, limit := range limits {
+		if err := config.ValidateRateLimit(limit); err != nil {
+			return fmt.Errorf("invalid rateLimits.%s in the config file: %w", name, err)
+		}
+	}
+	if limits == nil {
+		limits = make(map[string]models.RateLimit)
+	}
+	for name, limit := range plan.RateLimits {
+		limits[name] = limit
+	}
+
+	for name, limit := range limits {
+		pluginManager.SetRateLimit(name, limit.RequestsPerSecond, limit.Burst)
+	}
+	return nil
+}
+
+// pluginDirsFlag returns the directories given with --plugin-dir, which may be
+// repeated and may each hold a list of directories separated like PATH
+func pluginDirsFlag(cmd *cobra.Command) []string {
+	values, _ := cmd.Flags().GetStringArray("plugin-dir")
+
+	var dirs []string
+	for _, value := range values {
+		for _, dir := range filepath.SplitList(value) {
+			if dir != "" {
+				dirs = append(dirs, dir)
+			}
+		}
+	}
+	return dirs
+}
+
+// describePlugin writes a human-readable description of a plugin and its schema
+func describePlugin(w io.Writer, plg plugin.Plugin) {
+	fmt.Fprintf(w, "Plugin:       %s\n", plg.Name())
+	fmt.Fprintf(w, "Version:      %s\n", plg.Version())
+	fmt.Fprintf(w, "Description:  %s\n", plg.Description())
+	fmt.Fprintf(w, "Capabilities: %s\n", formatCapabilities(plugin.CapabilitiesOf(plg)))
+
+	schema := plg.ConfigSchema()
+	if schema == nil {
+		fmt.Fprintln(w, "\nConfig schema: none")
+		return
+	}
+
+	fmt.Fprintf(w, "\nConfig schema (%s):\n", schemaType(schema))
+	writeSchemaTree(w, schema, 1)
+}
+
+// summarizePlugins describes plugins, sorted by name
+func summarizePlugins(plgs []plugin.Plugin) []pluginSummary {
+	summaries := make([]pluginSummary, 0, len(plgs))
+	for _, plg := range plgs {
+		summaries = append(summaries, pluginSummary{
+			Name:         plg.Name(),
+			Version:      plg.Version(),
+			Description:  plg.Description(),
+			Capabilities: plugin.CapabilitiesOf(plg),
+		})
+	}
+	sort.Slice(summaries, func(i, j int) bool { return summaries[i].Name < summaries[j].Name })
+	return summaries
+}
+
+// writePluginList writes the plugins as a table
+func writePluginList(w io.Writer, summaries []pluginSummary) {
+	table := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(table, "NAME\tVERSION\tCAPABILITIES\tDESCRIPTION")
+	for _, summary := range summaries {
+		fmt.Fprintf(table, "%s\t%s\t%s\t%s\n", summary.Name, summary.Version, formatCapabilities(summary.Capabilities), summary.Description)
+	}
+	table.Flush()
+}
+
+// formatCapabilities lists the supported capabilities, or "none"
+func formatCapabilities(capabilities plugin.Capabilities) string {
+	var names []string
+	if capabilities.Rollback {
+		names = append(names, "rollback")
+	}
+	if capabilities.Preview {
+		names = append(names, "preview")
+	}
+	if capabilities.Streaming {
+		names = append(names, "streaming")
+	}
+	if len(names) == 0 {
+		return "none"
+	}
+	return strings.Join(names, ", ")
+}
+
+// writeSchemaTree writes the properties and items of a schema as an indented tree
+func writeSchemaTree(w io.Writer, schema *plugin.JSONSchema, depth int) {
+	indent := strings.Repeat("  ", depth)
+
+	required := make(map[string]bool)
+	for _, name := range schema.Required {
+		required[name] = true
+	}
+
+	names := make([]string, 0, len(schema.Properties))
+	for name := range schema.Properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		property := schema.Properties[name]
+		line := fmt.Sprintf("%s%s: %s", indent, name, schemaType(property))
+		if required[name] {
+			line += " (required)"
+		}
+		if property.Default != nil {
+			line += fmt.Sprintf(" (default: %v)", property.Default)
+		}
+		fmt.Fprintln(w, line)
+		writeSchemaTree(w, property, depth+1)
+	}
+
+	if schema.Items != nil && (len(schema.Items.Properties) > 0 || schema.Items.Items != nil) {
+		fmt.Fprintf(w, "%sitems: %s\n", indent, schemaType(schema.Items))
+		writeSchemaTree(w, schema.Items, depth+1)
+	}
+}
+
+// schemaType describes the type of a schema, including the item type of arrays
+func schemaType(schema *plugin.JSONSchema) string {
+	if schema == nil || schema.Type == "" {
+		return "any"
+	}
+	if schema.Type == "array" && schema.Items != nil {
+		return "array of " + schemaType(schema.Items)
+	}
+	return schema.Type
+}
+
+func init() {
+	rootCmd.AddCommand(pluginsCmd)
+	pluginsCmd.AddCommand(pluginsListCmd)
+	pluginsCmd.AddCommand(pluginsDescribeCmd)
+	pluginsCmd.AddCommand(pluginsInspectCmd)
+	pluginsCmd.AddCommand(pluginsInstallCmd)
+
+	pluginsCmd.PersistentFlags().StringArray("plugin-dir", nil, "Directory containing plugins; repeat or separate with ':' for several (default: ./plugins)")
+	pluginsCmd.PersistentFlags().Bool("plugin-recursive", false, "Also search the subdirectories of the plugin directories")
+	pluginsListCmd.Flags().StringP("output", "o", "text", "Output format: text or json")
+	pluginsDescribeCmd.Flags().StringP("output", "o", "text", "Output format: text or json")
+	pluginsInspectCmd.Flags().StringP("output", "o", "text", "Output format: text or json")
+	pluginsInstallCmd.Flags().Bool("force", false, "Replace an installed plugin of a different version")
+	pluginsCmd.MarkPersistentFlagDirname("plugin-dir")
+}
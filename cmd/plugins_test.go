@@ -0,0 +1,117 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+
+	"github.com/cuongtl1992/grp-cli/pkg/plugin"
+)
+
+// describedPlugin is a minimal plugin used to test schema rendering
+type describedPlugin struct{}
+
+func (describedPlugin) Name() string        { return "example" }
+func (describedPlugin) Description() string { return "Example plugin" }
+func (describedPlugin) Version() string     { return "1.2.3" }
+func (describedPlugin) ConfigSchema() *plugin.JSONSchema {
+	return &plugin.JSONSchema{
+		Type: "object",
+		Properties: map[string]*plugin.JSONSchema{
+			"name":  {Type: "string"},
+			"mode":  {Type: "string", Default: "fast"},
+			"ports": {Type: "array", Items: &plugin.JSONSchema{Type: "integer"}},
+			"target": {
+				Type:       "object",
+				Properties: map[string]*plugin.JSONSchema{"host": {Type: "string"}},
+				Required:   []string{"host"},
+			},
+		},
+		Required: []string{"name"},
+	}
+}
+func (describedPlugin) Validate(ctx context.Context, config map[string]interface{}) error {
+	return nil
+}
+func (describedPlugin) Execute(ctx context.Context, config map[string]interface{}) (*plugin.Result, error) {
+	return &plugin.Result{Success: true}, nil
+}
+func (describedPlugin) Rollback(ctx context.Context, executionID string) error { return nil }
+
+func TestDescribePlugin(t *testing.T) {
+	buf := new(bytes.Buffer)
+	describePlugin(buf, describedPlugin{})
+
+	expected := []string{
+		"Plugin:       example",
+		"Version:      1.2.3",
+		"Capabilities: rollback",
+		"Config schema (object):",
+		"  mode: string (default: fast)",
+		"  name: string (required)",
+		"  ports: array of integer",
+		"  target: object",
+		"    host: string (required)",
+	}
+	for _, line := range expected {
+		if !strings.Contains(buf.String(), line+"\n") {
+			t.Errorf("Expected output to contain %q, got:\n%s", line, buf.String())
+		}
+	}
+}
+
+// previewPlugin is a plugin declaring its capabilities and implementing plugin.Previewer
+type previewPlugin struct {
+	describedPlugin
+}
+
+func (previewPlugin) Name() string { return "preview" }
+func (previewPlugin) Capabilities() plugin.Capabilities {
+	return plugin.Capabilities{Streaming: true}
+}
+func (previewPlugin) Preview(ctx context.Context, config map[string]interface{}) (string, error) {
+	return "", nil
+}
+
+func TestWritePluginList(t *testing.T) {
+	buf := new(bytes.Buffer)
+	writePluginList(buf, summarizePlugins([]plugin.Plugin{previewPlugin{}, describedPlugin{}}))
+
+	expected := "NAME     VERSION  CAPABILITIES        DESCRIPTION\n" +
+		"example  1.2.3    rollback            Example plugin\n" +
+		"preview  1.2.3    preview, streaming  Example plugin\n"
+	if buf.String() != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, buf.String())
+	}
+}
+
+func TestPluginDirsFlag(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		expected []string
+	}{
+		{name: "not set"},
+		{name: "single", args: []string{"--plugin-dir", "./plugins"}, expected: []string{"./plugins"}},
+		{name: "repeated", args: []string{"--plugin-dir", "/org/plugins", "--plugin-dir", "./plugins"}, expected: []string{"/org/plugins", "./plugins"}},
+		{name: "path list", args: []string{"--plugin-dir", "/org/plugins:./plugins:"}, expected: []string{"/org/plugins", "./plugins"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := &cobra.Command{}
+			cmd.Flags().StringArray("plugin-dir", nil, "")
+			if err := cmd.Flags().Parse(tt.args); err != nil {
+				t.Fatal(err)
+			}
+
+			dirs := pluginDirsFlag(cmd)
+			if strings.Join(dirs, ",") != strings.Join(tt.expected, ",") {
+				t.Errorf("Expected %v, got %v", tt.expected, dirs)
+			}
+		})
+	}
+}
@@ -0,0 +1,87 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/cuongtl1992/grp-cli/internal/engine"
+)
+
+// progressPrinter renders execution events as progress output. On a terminal it
+// keeps a single live status line; otherwise it prints one line per finished job.
+type progressPrinter struct {
+	out       io.Writer
+	live      bool
+	mask      func(string) string
+	totalJobs int
+	finished  int
+	failed    int
+}
+
+// newProgressPrinter creates a progress printer writing to out; mask redacts
+// secrets from job messages
+func newProgressPrinter(out *os.File, totalJobs int, mask func(string) string) *progressPrinter {
+	return &progressPrinter{
+		out:       out,
+		live:      isTerminal(out),
+		mask:      mask,
+		totalJobs: totalJobs,
+	}
+}
+
+// Render consumes events until the channel is closed
+func (p *progressPrinter) Render(events <-chan engine.Event) {
+	for event := range events {
+		p.handle(event)
+	}
+	if p.live {
+		fmt.Fprintln(p.out)
+	}
+}
+
+// handle updates the progress output for a single event
+func (p *progressPrinter) handle(event engine.Event) {
+	var status string
+	switch event.Type {
+	case engine.EventStageStarted:
+		status = fmt.Sprintf("stage %s started", event.Stage)
+	case engine.EventJobStarted:
+		status = fmt.Sprintf("%s/%s running", event.Stage, event.Job)
+	case engine.EventJobFinished:
+		p.finished++
+		status = fmt.Sprintf("%s/%s succeeded in %s", event.Stage, event.Job, event.Duration)
+		if event.Canceled {
+			status = fmt.Sprintf("%s/%s canceled after %s", event.Stage, event.Job, event.Duration)
+		} else if !event.Success {
+			p.failed++
+			status = fmt.Sprintf("%s/%s failed in %s: %s", event.Stage, event.Job, event.Duration, p.mask(event.Message))
+		}
+	case engine.EventStageFinished:
+		status = fmt.Sprintf("stage %s finished in %s", event.Stage, event.Duration)
+		if !event.Success {
+			status = fmt.Sprintf("stage %s failed in %s", event.Stage, event.Duration)
+		}
+	default:
+		return
+	}
+
+	line := fmt.Sprintf("[%d/%d jobs, %d failed] %s", p.finished, p.totalJobs, p.failed, status)
+	if p.live {
+		// Overwrite the previous status line
+		fmt.Fprintf(p.out, "\r\033[K%s", line)
+		return
+	}
+	if event.Type == engine.EventJobFinished || event.Type == engine.EventStageFinished {
+		fmt.Fprintln(p.out, line)
+	}
+}
+
+// isTerminal returns true if the file is attached to a terminal
+func isTerminal(file *os.File) bool {
+	info, err := file.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,139 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+
+	"github.com/cuongtl1992/grp-cli/internal/config"
+	"github.com/cuongtl1992/grp-cli/internal/models"
+	"github.com/cuongtl1992/grp-cli/internal/secrets"
+)
+
+// renderCmd represents the render command
+var renderCmd = &cobra.Command{
+	Use:   "render [plan file]",
+	Short: "Print a release plan as it will be run, fully resolved",
+	Long: `Load a release plan through the same steps as run and print the resulting plan
+as YAML: base plans, includes and values files merged, the environment applied,
+job templates expanded and variable references substituted. Secrets are masked
+unless --unmask is given, which asks for confirmation first. Nothing is executed
+and the plan is not validated; use validate for that.`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completePlanFile,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		out, _ := cmd.Flags().GetString("out")
+		unmask, _ := cmd.Flags().GetBool("unmask")
+		yes, _ := cmd.Flags().GetBool("yes")
+
+		loader, err := newPlanLoader(cmd)
+		if err != nil {
+			return err
+		}
+		plan, err := loader.LoadPlan(args[0])
+		if err != nil {
+			return fmt.Errorf("failed to load plan: %w", err)
+		}
+
+		masker := secrets.NewMasker(loader.Secrets()...)
+		if unmask {
+			if !yes {
+				// The plan takes stdin, so there is nothing left to read an answer from
+				if args[0] == config.StdinPlan {
+					return fmt.Errorf("--unmask with a plan read from stdin requires --yes")
+				}
+				if !confirmUnmask(cmd, out) {
+					return fmt.Errorf("rendering with secrets unmasked was not confirmed")
+				}
+			}
+			masker = nil
+		}
+
+		rendered, err := renderPlan(plan, masker)
+		if err != nil {
+			return fmt.Errorf("failed to render plan: %w", err)
+		}
+		if out == "" {
+			_, err := cmd.OutOrStdout().Write(rendered)
+			return err
+		}
+
+		// Keep files holding secrets readable by their owner only
+		mode := os.FileMode(0644)
+		if unmask {
+			mode = 0600
+		}
+		if err := os.WriteFile(out, rendered, mode); err != nil {
+			return fmt.Errorf("failed to write rendered plan: %w", err)
+		}
+		fmt.Fprintf(cmd.ErrOrStderr(), "Rendered plan written to %s\n", out)
+		return nil
+	},
+}
+
+// confirmUnmask asks on stderr whether to render secrets in clear text, reading
+// the answer from stdin. Anything but yes, including no answer, declines.
+func confirmUnmask(cmd *cobra.Command, out string) bool {
+	destination := "stdout"
+	if out != "" {
+		destination = out
+	}
+	fmt.Fprintf(cmd.ErrOrStderr(), "The rendered plan will show secrets in clear text on %s. Continue? [y/N]: ", destination)
+	answer, _ := bufio.NewReader(cmd.InOrStdin()).ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
+
+// renderPlan encodes a loaded plan as YAML, in the field order of the plan
+// structure, masking secret values unless masker is nil. The includes are left
+// out, as the loader has merged them.
+func renderPlan(plan *models.Plan, masker *secrets.Masker) ([]byte, error) {
+	rendered := *plan
+	rendered.Includes = nil
+
+	var node yaml.Node
+	if err := node.Encode(&rendered); err != nil {
+		return nil, err
+	}
+	if masker != nil {
+		maskNode(&node, masker)
+	}
+
+	buf := new(bytes.Buffer)
+	encoder := yaml.NewEncoder(buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&node); err != nil {
+		return nil, err
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// maskNode masks secret values in the string scalars of a YAML node and its children
+func maskNode(node *yaml.Node, masker *secrets.Masker) {
+	if node.Kind == yaml.ScalarNode && node.Tag == "!!str" {
+		node.Value = masker.Mask(node.Value)
+	}
+	for _, child := range node.Content {
+		maskNode(child, masker)
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(renderCmd)
+	renderCmd.Flags().String("out", "", "Write the rendered plan to this file instead of stdout")
+	renderCmd.Flags().Bool("unmask", false, "Show secret values in clear text, after confirmation (for local debugging only)")
+	renderCmd.Flags().BoolP("yes", "y", false, "Do not ask for confirmation with --unmask")
+	renderCmd.Flags().Bool("strict", false, "Reject fields that are not part of the plan schema")
+	renderCmd.Flags().StringArray("values", nil, "YAML file of variables overriding the plan's; repeat to merge several in order")
+	renderCmd.Flags().StringArray("set", nil, "Override a variable as key.path=value, after the --values files; repeatable")
+	renderCmd.Flags().String("environment", "", "Apply this environment from the plan's environments block")
+	renderCmd.RegisterFlagCompletionFunc("environment", completeEnvironments)
+}
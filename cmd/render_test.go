@@ -0,0 +1,105 @@
+package cmd
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+
+	"github.com/cuongtl1992/grp-cli/internal/models"
+	"github.com/cuongtl1992/grp-cli/internal/secrets"
+)
+
+func TestRenderPlan(t *testing.T) {
+	plan := &models.Plan{
+		APIVersion: "v1",
+		Kind:       "ReleasePlan",
+		Metadata:   models.Metadata{Name: "release"},
+		Includes:   []models.Include{{Path: "common.yaml"}},
+		Variables:  map[string]interface{}{"token": "s3cr3t"},
+		Stages: []models.Stage{{
+			Name: "deploy",
+			Jobs: []models.Job{{Name: "release", Type: "http", Config: map[string]interface{}{
+				"headers": map[string]interface{}{"Authorization": "Bearer s3cr3t"},
+			}}},
+		}},
+	}
+
+	tests := []struct {
+		name       string
+		masker     *secrets.Masker
+		expected   []string
+		unexpected []string
+	}{
+		{
+			name:   "masked",
+			masker: secrets.NewMasker("s3cr3t"),
+			expected: []string{
+				"apiVersion: v1\nkind: ReleasePlan\nmetadata:\n  name: release\n",
+				"  token: '***'\n",
+				"  - name: deploy\n    jobs:\n      - name: release\n        type: http\n",
+				"            Authorization: Bearer ***\n",
+			},
+			unexpected: []string{"s3cr3t", "includes:"},
+		},
+		{
+			name:       "unmasked",
+			expected:   []string{"  token: s3cr3t\n", "            Authorization: Bearer s3cr3t\n"},
+			unexpected: []string{"***"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rendered, err := renderPlan(plan, tt.masker)
+			if err != nil {
+				t.Fatalf("renderPlan() error = %v", err)
+			}
+			output := string(rendered)
+			for _, want := range tt.expected {
+				if !strings.Contains(output, want) {
+					t.Errorf("Expected output to contain %q, got:\n%s", want, output)
+				}
+			}
+			for _, unwanted := range tt.unexpected {
+				if strings.Contains(output, unwanted) {
+					t.Errorf("Expected output not to contain %q, got:\n%s", unwanted, output)
+				}
+			}
+		})
+	}
+
+	if plan.Includes == nil {
+		t.Errorf("Expected the plan's includes to be left unchanged")
+	}
+}
+
+func TestConfirmUnmask(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected bool
+	}{
+		{input: "y\n", expected: true},
+		{input: "YES\n", expected: true},
+		{input: "n\n", expected: false},
+		{input: "\n", expected: false},
+		{input: "", expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			cmd := &cobra.Command{}
+			cmd.SetIn(strings.NewReader(tt.input))
+			prompt := new(bytes.Buffer)
+			cmd.SetErr(prompt)
+
+			if confirmed := confirmUnmask(cmd, "plan.out.yaml"); confirmed != tt.expected {
+				t.Errorf("Expected confirmation %v, got %v", tt.expected, confirmed)
+			}
+			if !strings.Contains(prompt.String(), "in clear text on plan.out.yaml") {
+				t.Errorf("Expected the prompt to name the destination, got %q", prompt.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,120 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/spf13/cobra"
+
+	"github.com/cuongtl1992/grp-cli/internal/config"
+	"github.com/cuongtl1992/grp-cli/internal/engine"
+	"github.com/cuongtl1992/grp-cli/internal/logging"
+	"github.com/cuongtl1992/grp-cli/internal/secrets"
+)
+
+// rollbackCmd represents the rollback command
+var rollbackCmd = &cobra.Command{
+	Use:   "rollback [plan file]",
+	Short: "Execute the rollback stages of a release plan",
+	Long: `Execute only the rollback section of a release plan, for manual recovery
+after a release failed without rolling back. The stages of the plan are not
+run, and no plugin rollback is called since this execution changed nothing.
+
+With --dry-run the rollback stages are validated without being executed.`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completePlanFile,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		sigs := make(chan os.Signal, 1)
+		signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
+		go func() {
+			<-sigs
+			fmt.Println("Received signal, stopping the rollback...")
+			cancel()
+		}()
+
+		loader, err := newPlanLoader(cmd)
+		if err != nil {
+			return err
+		}
+		plan, err := loader.LoadPlan(args[0])
+		if err != nil {
+			return withExitCode(ExitValidation, fmt.Errorf("failed to load plan: %w", err))
+		}
+		if err := config.NewValidator().ValidatePlan(plan); err != nil {
+			return withExitCode(ExitValidation, fmt.Errorf("validation failed: %w", err))
+		}
+		if kind, _ := config.LookupKind(plan.Kind); !kind.Rollbackable {
+			return withExitCode(ExitValidation, fmt.Errorf("plan kind %s has no rollback stages to execute", plan.Kind))
+		}
+		if plan.Rollback == nil {
+			return withExitCode(ExitValidation, fmt.Errorf("plan %s has no rollback stages", plan.Metadata.Name))
+		}
+
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+
+		masker := secrets.NewMasker(loader.Secrets()...)
+		logger := logging.WithRedaction(newLogger(), masker.Mask)
+
+		pluginManager := loadPluginManager(cmd)
+		pluginManager.SetLogger(logger)
+		pluginTimeout, _ := cmd.Flags().GetDuration("plugin-timeout")
+		pluginManager.SetExecutionTimeout(pluginTimeout)
+		if err := applyRateLimits(pluginManager, plan); err != nil {
+			return err
+		}
+
+		orchestrator := engine.NewOrchestrator(pluginManager)
+		orchestrator.SetLogger(logger)
+
+		fmt.Printf("Starting rollback of plan: %s\n", plan.Metadata.Name)
+		options := engine.ExecuteOptions{
+			DryRun:     dryRun,
+			LogMask:    masker.Mask,
+			WorkingDir: executionWorkingDir(cmd, args[0], plan),
+		}
+		result, err := orchestrator.ExecuteRollback(ctx, plan, options)
+		if err != nil {
+			err = &maskedError{message: masker.Mask(err.Error()), err: err}
+		}
+
+		if dryRun && result != nil {
+			fmt.Println("\nDry run rollback plan:")
+			printDryRunStages("Rollback stage", result.Stages)
+		}
+
+		if err != nil {
+			// The error itself is printed by Execute
+			if result != nil && !dryRun {
+				printRollbackSummary(os.Stdout, result)
+			}
+			return withExitCode(ExitRollbackFailed, err)
+		}
+
+		if !dryRun {
+			printRollbackSummary(os.Stdout, result)
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(rollbackCmd)
+
+	rollbackCmd.Flags().Bool("dry-run", false, "Validate the rollback stages without executing them")
+	rollbackCmd.Flags().Bool("strict", false, "Reject fields that are not part of the plan schema")
+	rollbackCmd.Flags().StringArray("values", nil, "YAML file of variables overriding the plan's; repeat to merge several in order")
+	rollbackCmd.Flags().StringArray("set", nil, "Override a variable as key.path=value, after the --values files; repeatable")
+	rollbackCmd.Flags().String("environment", "", "Apply this environment from the plan's environments block")
+	rollbackCmd.Flags().StringArray("plugin-dir", nil, "Directory containing plugins; repeat or separate with ':' for several (default: ./plugins)")
+	rollbackCmd.Flags().Bool("plugin-recursive", false, "Also search the subdirectories of the plugin directories")
+	rollbackCmd.Flags().Duration("plugin-timeout", 0, "Abandon any single plugin execution running longer than this (0 = no limit)")
+	rollbackCmd.Flags().String("working-dir", "", "Directory relative job config paths are rooted at, overriding the plan's workingDir (default: the plan file's directory)")
+	rollbackCmd.MarkFlagDirname("working-dir")
+	rollbackCmd.RegisterFlagCompletionFunc("environment", completeEnvironments)
+}
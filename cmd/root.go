@@ -3,9 +3,15 @@ package cmd
 import (
 	"fmt"
 	"os"
+	"path/filepath"
+	"strings"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
+
+	"github.com/cuongtl1992/grp-cli/internal/config"
+	"github.com/cuongtl1992/grp-cli/internal/logging"
+	"github.com/cuongtl1992/grp-cli/internal/models"
 )
 
 var cfgFile string
@@ -17,13 +23,18 @@ var rootCmd = &cobra.Command{
 	Long: `A comprehensive CLI tool for DevOps to automate and manage complex release workflows
 across multiple environments and deployment targets including VMs, Docker containers, and Kubernetes.
 It supports various release strategies like Canary, Blue/Green, Shadow, and A/B testing.`,
+	// Execute prints the error of a failed command once; the usage is shown with --help
+	SilenceUsage:  true,
+	SilenceErrors: true,
 }
 
 // Execute adds all child commands to the root command and sets flags appropriately.
+// It prints the error to stderr and exits with one of the Exit codes when a
+// command fails.
 func Execute() {
 	if err := rootCmd.Execute(); err != nil {
-		fmt.Println(err)
-		os.Exit(1)
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		os.Exit(exitCode(err))
 	}
 }
 
@@ -34,12 +45,81 @@ func init() {
 	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.grp-cli.yaml)")
 	rootCmd.PersistentFlags().BoolP("verbose", "v", false, "enable verbose output")
 	rootCmd.PersistentFlags().Bool("debug", false, "enable debug mode")
+	rootCmd.PersistentFlags().StringArray("plan-header", nil, "header sent when fetching a plan from a URL, as 'Name: value' (repeatable)")
+	rootCmd.PersistentFlags().Duration("fetch-timeout", config.DefaultFetchTimeout, "timeout for fetching a plan and each of its includes from a URL")
 	
 	// Bind flags to viper
 	viper.BindPFlag("verbose", rootCmd.PersistentFlags().Lookup("verbose"))
 	viper.BindPFlag("debug", rootCmd.PersistentFlags().Lookup("debug"))
 }
 
+// newLogger creates a logger writing to stderr at the level selected by the
+// --verbose and --debug flags
+func newLogger() logging.Logger {
+	level := logging.LevelFromFlags(viper.GetBool("verbose"), viper.GetBool("debug"))
+	return logging.New(os.Stderr, level)
+}
+
+// newPlanLoader creates a plan loader configured by the --strict, --plan-header,
+// --fetch-timeout, --values, --set and --environment flags
+func newPlanLoader(cmd *cobra.Command) (*config.Loader, error) {
+	loader := config.NewLoader()
+	strict, _ := cmd.Flags().GetBool("strict")
+	loader.SetStrict(strict)
+	environment, _ := cmd.Flags().GetString("environment")
+	loader.SetEnvironment(environment)
+
+	if timeout, err := cmd.Flags().GetDuration("fetch-timeout"); err == nil {
+		loader.SetFetchTimeout(timeout)
+	}
+	headers, _ := cmd.Flags().GetStringArray("plan-header")
+	for _, header := range headers {
+		name, value, found := strings.Cut(header, ":")
+		if !found || strings.TrimSpace(name) == "" {
+			return nil, fmt.Errorf("invalid --plan-header %q (expected 'Name: value')", header)
+		}
+		loader.SetHeader(strings.TrimSpace(name), strings.TrimSpace(value))
+	}
+
+	// Values files are layered over the plan's variables in the order given
+	if files, err := cmd.Flags().GetStringArray("values"); err == nil {
+		for _, file := range files {
+			if err := loader.AddValuesFile(file); err != nil {
+				return nil, withExitCode(ExitValidation, err)
+			}
+		}
+	}
+	if expressions, err := cmd.Flags().GetStringArray("set"); err == nil {
+		for _, expression := range expressions {
+			values, err := config.ParseSetValue(expression)
+			if err != nil {
+				return nil, fmt.Errorf("invalid --set: %w", err)
+			}
+			loader.AddValues(values)
+		}
+	}
+	return loader, nil
+}
+
+// executionWorkingDir returns the working directory of an execution of the plan
+// loaded from planFile: the --working-dir flag, else the plan's workingDir relative
+// to the plan file's directory, which is also the default. Plans read from stdin
+// or fetched from a URL are rooted at the current directory.
+func executionWorkingDir(cmd *cobra.Command, planFile string, plan *models.Plan) string {
+	if dir, _ := cmd.Flags().GetString("working-dir"); dir != "" {
+		return dir
+	}
+	if filepath.IsAbs(plan.WorkingDir) {
+		return plan.WorkingDir
+	}
+
+	base := "."
+	if planFile != config.StdinPlan && !strings.Contains(planFile, "://") {
+		base = filepath.Dir(planFile)
+	}
+	return filepath.Join(base, plan.WorkingDir)
+}
+
 // initConfig reads in config file and ENV variables if set
 func initConfig() {
 	if cfgFile != "" {
@@ -63,6 +143,6 @@ func initConfig() {
 
 	// If a config file is found, read it in
 	if err := viper.ReadInConfig(); err == nil {
-		fmt.Println("Using config file:", viper.ConfigFileUsed())
+		fmt.Fprintln(os.Stderr, "Using config file:", viper.ConfigFileUsed())
 	}
 } 
\ No newline at end of file
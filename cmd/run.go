@@ -2,17 +2,28 @@ package cmd
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
 	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
 
+	"github.com/cuongtl1992/grp-cli/internal/approval"
 	"github.com/cuongtl1992/grp-cli/internal/config"
 	"github.com/cuongtl1992/grp-cli/internal/engine"
-	"github.com/cuongtl1992/grp-cli/internal/plugins"
+	"github.com/cuongtl1992/grp-cli/internal/logging"
+	"github.com/cuongtl1992/grp-cli/internal/metrics"
+	"github.com/cuongtl1992/grp-cli/internal/models"
+	"github.com/cuongtl1992/grp-cli/internal/report"
+	"github.com/cuongtl1992/grp-cli/internal/secrets"
+	"github.com/cuongtl1992/grp-cli/internal/state"
+	"github.com/cuongtl1992/grp-cli/internal/upload"
 )
 
 // runCmd represents the run command
@@ -24,7 +35,8 @@ var runCmd = &cobra.Command{
 2. Process required approvals
 3. Execute all stages and jobs
 4. Generate a report of the results`,
-	Args: cobra.ExactArgs(1),
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completePlanFile,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		planFile := args[0]
 		
@@ -37,59 +49,240 @@ var runCmd = &cobra.Command{
 		signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
 		go func() {
 			<-sigs
-			fmt.Println("Received signal, attempting graceful shutdown...")
+			// stderr, as stdout may be reserved for the report
+			fmt.Fprintln(os.Stderr, "Received signal, attempting graceful shutdown...")
 			cancel()
 		}()
 		
 		// Load the plan
-		loader := config.NewLoader()
+		loader, err := newPlanLoader(cmd)
+		if err != nil {
+			return err
+		}
 		plan, err := loader.LoadPlan(planFile)
 		if err != nil {
-			return fmt.Errorf("failed to load plan: %w", err)
+			return withExitCode(ExitValidation, fmt.Errorf("failed to load plan: %w", err))
 		}
 		
 		// Validate the plan
 		validator := config.NewValidator()
 		if err := validator.ValidatePlan(plan); err != nil {
-			return fmt.Errorf("validation failed: %w", err)
+			return withExitCode(ExitValidation, fmt.Errorf("validation failed: %w", err))
+		}
+		if kind, _ := config.LookupKind(plan.Kind); !kind.Runnable {
+			err := fmt.Errorf("plan kind %s cannot be run", plan.Kind)
+			if kind.Rollbackable {
+				err = fmt.Errorf("%w; execute its rollback stages with grp-cli rollback", err)
+			}
+			return withExitCode(ExitValidation, err)
+		}
+		
+		// Limit the run to the selected range of stages
+		fromStage, _ := cmd.Flags().GetString("from-stage")
+		toStage, _ := cmd.Flags().GetString("to-stage")
+		plan, err = engine.SelectStages(plan, fromStage, toStage)
+		if err != nil {
+			return withExitCode(ExitValidation, fmt.Errorf("failed to select stages: %w", err))
+		}
+		
+		// Limit the run to the selected jobs and their dependencies
+		tags, _ := cmd.Flags().GetStringSlice("tags")
+		excludeTags, _ := cmd.Flags().GetStringSlice("exclude-tags")
+		plan, err = engine.SelectJobs(plan, tags, excludeTags)
+		if err != nil {
+			return withExitCode(ExitValidation, fmt.Errorf("failed to select jobs: %w", err))
 		}
 		
 		// Get execution options from flags
+		outputFormat, _ := cmd.Flags().GetString("output")
+		if err := report.ValidateFormat(outputFormat); err != nil {
+			return err
+		}
+		reportFile, _ := cmd.Flags().GetString("report-file")
+		
+		// A structured report written to stdout must be all that is written there,
+		// so that it can be piped: every other line goes to stderr
+		out := io.Writer(os.Stdout)
+		if report.IsStructured(outputFormat) && reportFile == "" {
+			out = os.Stderr
+		}
+		
 		autoRollback, _ := cmd.Flags().GetBool("auto-rollback")
 		skipApproval, _ := cmd.Flags().GetBool("skip-approval")
 		dryRun, _ := cmd.Flags().GetBool("dry-run")
-		
-		// Initialize plugin manager
-		pluginDir, _ := cmd.Flags().GetString("plugin-dir")
-		if pluginDir == "" {
-			// Default to plugins directory in current working directory
-			pluginDir = "./plugins"
+		maxParallelStages, _ := cmd.Flags().GetInt("max-parallel-stages")
+		rollbackOnCancel, _ := cmd.Flags().GetBool("rollback-on-cancel")
+		cancelGracePeriod, _ := cmd.Flags().GetDuration("cancel-grace-period")
+		timeout, _ := cmd.Flags().GetDuration("timeout")
+		onStageFailure, _ := cmd.Flags().GetString("on-stage-failure")
+		if err := config.ValidateStageFailure(onStageFailure); err != nil {
+			return fmt.Errorf("invalid --on-stage-failure: %w", err)
 		}
 		
-		pluginManager := plugins.NewManager(pluginDir)
+		// Mask secret values in logs, progress output and reports
+		masker := secrets.NewMasker(loader.Secrets()...)
+		logger := logging.WithRedaction(newLogger(), masker.Mask)
 		
-		// Load plugins
-		if err := pluginManager.LoadPlugins(); err != nil {
-			fmt.Printf("Warning: Failed to load plugins: %v\n", err)
+		// Initialize plugin manager and load plugins
+		pluginManager := loadPluginManager(cmd)
+		pluginManager.SetLogger(logger)
+		pluginTimeout, _ := cmd.Flags().GetDuration("plugin-timeout")
+		pluginManager.SetExecutionTimeout(pluginTimeout)
+		if err := applyRateLimits(pluginManager, plan); err != nil {
+			return err
 		}
 		
 		// Create orchestrator
 		orchestrator := engine.NewOrchestrator(pluginManager)
+		orchestrator.SetLogger(logger)
+		prefixOutput, _ := cmd.Flags().GetBool("prefix-output")
+		orchestrator.SetConsole(os.Stderr, prefixOutput, masker.Mask)
+		approvalDir, _ := cmd.Flags().GetString("approval-dir")
+		approvalURL := viper.GetString("approval.url")
+		pollInterval, _ := cmd.Flags().GetDuration("approval-poll-interval")
+		switch {
+		case approvalDir != "" && approvalURL != "":
+			return fmt.Errorf("--approval-dir and --approval-url cannot be used together")
+		case approvalDir != "":
+			orchestrator.SetApprovalService(approval.NewFileService(approvalDir, pollInterval))
+		case approvalURL != "":
+			service, err := approval.NewWebhookService(approval.WebhookConfig{
+				URL:          approvalURL,
+				StatusURL:    viper.GetString("approval.statusUrl"),
+				Token:        approvalToken(),
+				PollInterval: pollInterval,
+			})
+			if err != nil {
+				return err
+			}
+			orchestrator.SetApprovalService(service)
+		}
+		if pushgateway := viper.GetString("metrics.pushgateway"); pushgateway != "" && !dryRun {
+			orchestrator.SetMetricsSink(metrics.NewPushgateway(pushgateway))
+		}
+		
+		// Check the upload destination before anything runs
+		var destination *upload.Destination
+		if uploadURL := viper.GetString("upload.url"); uploadURL != "" && !dryRun {
+			if destination, err = upload.New(uploadURL); err != nil {
+				return err
+			}
+		}
+		requireUpload, _ := cmd.Flags().GetBool("require-upload")
+		if requireUpload && destination == nil && !dryRun {
+			return fmt.Errorf("--require-upload requires --upload-url or upload.url in the config file")
+		}
 		
 		// Execute the plan
 		options := engine.ExecuteOptions{
-			AutoRollback: autoRollback,
-			SkipApproval: skipApproval,
-			DryRun:       dryRun,
+			AutoRollback:      autoRollback,
+			SkipApproval:      skipApproval,
+			DryRun:            dryRun,
+			MaxParallelStages: maxParallelStages,
+			RollbackOnCancel:  rollbackOnCancel,
+			CancelGracePeriod: cancelGracePeriod,
+			Timeout:           timeout,
+			LogMask:           masker.Mask,
 		}
+		options.LogDir, _ = cmd.Flags().GetString("log-dir")
+		options.ArtifactDir, _ = cmd.Flags().GetString("artifact-dir")
+		options.StageRollbackOnly, _ = cmd.Flags().GetBool("stage-rollback-only")
+		options.RequireRollback, _ = cmd.Flags().GetBool("require-rollback")
+		failFast, _ := cmd.Flags().GetBool("fail-fast")
+		options.KeepGoing = !failFast
+		options.OnStageFailure = onStageFailure
+		options.WorkingDir = executionWorkingDir(cmd, planFile, plan)
 		
-		fmt.Printf("Starting execution of plan: %s\n", plan.Metadata.Name)
+		fmt.Fprintf(out, "Starting execution of plan: %s\n", plan.Metadata.Name)
 		startTime := time.Now()
 		
+		// Render live progress unless stdout is reserved for the report
+		var events chan engine.Event
+		progressDone := make(chan struct{})
+		if !report.IsStructured(outputFormat) || reportFile != "" {
+			events = make(chan engine.Event, 64)
+			options.Events = engine.ChannelSink(events)
+			go func() {
+				newProgressPrinter(os.Stdout, countJobs(plan), masker.Mask).Render(events)
+				close(progressDone)
+			}()
+		}
+		
 		result, err := orchestrator.ExecutePlan(ctx, plan, options)
+		if events != nil {
+			// Flush the progress output before printing the summary
+			close(events)
+			<-progressDone
+		}
+		
+		masker.MaskResult(result)
 		if err != nil {
-			fmt.Printf("Execution failed: %v\n", err)
-			return err
+			err = &maskedError{message: masker.Mask(err.Error()), err: err}
+		}
+		
+		// Record the execution for the history command; dry runs change nothing
+		if dir := stateDir(cmd); dir != "" && result != nil && !dryRun {
+			if saveErr := state.NewStore(dir).Save(plan.Metadata.Name, result); saveErr != nil {
+				logger.Warn("Failed to record execution", "error", saveErr)
+			}
+		}
+		
+		// Write the structured report, even for failed executions
+		if result != nil && report.IsStructured(outputFormat) {
+			if reportErr := writeReport(result, outputFormat, reportFile); reportErr != nil {
+				return reportErr
+			}
+		}
+		
+		// Upload the report and job logs for the audit trail; a failed upload only
+		// fails a successful release if it is required
+		if destination != nil && result != nil {
+			uploaded, uploadErr := destination.UploadExecution(context.Background(), plan.Metadata.Name, result, options.LogDir)
+			if uploadErr != nil {
+				uploadErr = fmt.Errorf("failed to upload execution results: %w", uploadErr)
+				if requireUpload && err == nil {
+					return uploadErr
+				}
+				logger.Warn("Failed to upload execution results", "error", uploadErr)
+			} else {
+				logger.Info("Uploaded execution results", "objects", len(uploaded))
+			}
+		}
+		
+		// Show what a real run would execute
+		if dryRun && result != nil && !(report.IsStructured(outputFormat) && reportFile == "") {
+			printDryRunPlan(result)
+		}
+		
+		// Show the outcome of each stage and job once the progress output is done
+		if events != nil && result != nil && !dryRun && len(result.Stages) > 0 {
+			fmt.Println()
+			writeResultTable(os.Stdout, withPlanHook(result), useColor(cmd, os.Stdout))
+		}
+		
+		if err != nil {
+			// The error itself is printed by Execute
+			if result != nil && result.Canceled {
+				fmt.Fprintf(out, "Completed jobs: %d, Failed jobs: %d, Canceled jobs: %d\n", result.CompletedJobs, result.FailedJobs, result.CanceledJobs)
+			}
+			if result != nil && len(result.SkippedStages) > 0 {
+				fmt.Fprintf(out, "Skipped stages (onStageFailure: %s): %s\n", result.OnStageFailure, strings.Join(result.SkippedStages, ", "))
+			}
+			if result != nil && result.Rollback != nil {
+				printRollbackSummary(out, result.Rollback)
+			}
+			if result != nil && result.Hooks != nil {
+				printPlanHookSummary(out, result.Hooks)
+			}
+			if errors.Is(err, engine.ErrRollbackRequired) {
+				// Nothing ran: the plan is rejected like an invalid one
+				return withExitCode(ExitValidation, err)
+			}
+			return withExitCode(executionExitCode(result), err)
+		}
+		
+		if report.IsStructured(outputFormat) && reportFile == "" {
+			return nil
 		}
 		
 		// Display result summary
@@ -97,17 +290,197 @@ var runCmd = &cobra.Command{
 		fmt.Printf("ID: %s\n", result.ID)
 		fmt.Printf("Total stages: %d, Jobs: %d\n", result.TotalStages, result.TotalJobs)
 		fmt.Printf("Completed jobs: %d, Failed jobs: %d\n", result.CompletedJobs, result.FailedJobs)
+		if result.Hooks != nil {
+			printPlanHookSummary(out, result.Hooks)
+		}
 		
 		return nil
 	},
 }
 
+// maskedError shows an error with its secrets masked while keeping the error
+// chain for errors.Is and errors.As
+type maskedError struct {
+	message string
+	err     error
+}
+
+func (e *maskedError) Error() string {
+	return e.message
+}
+
+func (e *maskedError) Unwrap() error {
+	return e.err
+}
+
+// printRollbackSummary reports whether the rollback succeeded or partially failed
+func printRollbackSummary(out io.Writer, rollback *models.RollbackResult) {
+	if rollback.Success {
+		skipped := rollback.SkippedJobs()
+		fmt.Fprintf(out, "Rollback completed successfully in %s (%d jobs compensated, %d not rolled back, %d stages)\n", rollback.Duration, len(rollback.Jobs)-skipped, skipped, len(rollback.Stages))
+		return
+	}
+	
+	fmt.Fprintf(out, "Rollback partially failed: %d of %d job rollbacks and %d of %d stages failed\n",
+		rollback.FailedJobs(), len(rollback.Jobs), rollback.FailedStages(), len(rollback.Stages))
+	for _, job := range rollback.Jobs {
+		if !job.Success {
+			fmt.Fprintf(out, "  %s: %s\n", job.Name, job.Message)
+		}
+	}
+	for _, stage := range rollback.Stages {
+		if stage.Success {
+			continue
+		}
+		for _, job := range stage.Jobs {
+			if !job.Success {
+				fmt.Fprintf(out, "  %s/%s: %s\n", stage.Name, job.Name, job.Message)
+			}
+		}
+	}
+}
+
+// printPlanHookSummary reports the outcome of the plan's onSuccess or onFailure hook
+func printPlanHookSummary(out io.Writer, hook *models.StageResult) {
+	if hook.Success {
+		fmt.Fprintf(out, "%s hook completed successfully in %s (%d jobs)\n", hook.Name, hook.Duration, len(hook.Jobs))
+		return
+	}
+	
+	fmt.Fprintf(out, "%s hook failed:\n", hook.Name)
+	for _, job := range hook.Jobs {
+		if !job.Success {
+			fmt.Fprintf(out, "  %s: %s\n", job.Name, job.Message)
+		}
+	}
+}
+
+// withPlanHook returns the results of the stages followed by those of the plan's
+// hook, if one ran
+func withPlanHook(result *models.ExecutionResult) []models.StageResult {
+	if result.Hooks == nil {
+		return result.Stages
+	}
+	return append(result.Stages[:len(result.Stages):len(result.Stages)], *result.Hooks)
+}
+
+// printDryRunPlan lists the batches of jobs each stage, rollback stage and plan
+// hook would run, and any job that failed validation
+func printDryRunPlan(result *models.ExecutionResult) {
+	fmt.Println("\nDry run execution plan:")
+	printDryRunStages("Stage", result.Stages)
+	if result.Rollback != nil {
+		printDryRunStages("Rollback stage", result.Rollback.Stages)
+	}
+	if result.Hooks != nil {
+		printDryRunStages("Hook", []models.StageResult{*result.Hooks})
+	}
+}
+
+// printDryRunStages lists the batches of jobs of the simulated stages
+func printDryRunStages(kind string, stages []models.StageResult) {
+	for _, stage := range stages {
+		fmt.Printf("  %s %s\n", kind, stage.Name)
+		for i, batch := range stage.Batches {
+			fmt.Printf("    Batch %d: %s\n", i+1, strings.Join(batch, ", "))
+		}
+		for _, job := range stage.AllJobs() {
+			if !job.Success {
+				fmt.Printf("    %s: %s\n", job.Name, job.Message)
+			}
+		}
+	}
+}
+
+// countJobs returns the number of jobs and hooks across all stages of the plan
+func countJobs(plan *models.Plan) int {
+	total := 0
+	for _, stage := range plan.Stages {
+		total += len(stage.AllJobs())
+	}
+	return total
+}
+
+// writeReport writes the execution result to the report file, or stdout if none is set
+func writeReport(result *models.ExecutionResult, format, reportFile string) error {
+	if reportFile == "" {
+		return report.Write(os.Stdout, result, format)
+	}
+	
+	file, err := os.Create(reportFile)
+	if err != nil {
+		return fmt.Errorf("failed to create report file: %w", err)
+	}
+	defer file.Close()
+	
+	if err := report.Write(file, result, format); err != nil {
+		return err
+	}
+	return file.Close()
+}
+
+// approvalToken returns the bearer token sent to the approval service: the
+// GRP_APPROVAL_TOKEN environment variable, else approval.token from the config file
+func approvalToken() string {
+	if token := os.Getenv("GRP_APPROVAL_TOKEN"); token != "" {
+		return token
+	}
+	return viper.GetString("approval.token")
+}
+
 func init() {
 	rootCmd.AddCommand(runCmd)
 	
 	// Local flags
 	runCmd.Flags().Bool("auto-rollback", false, "Automatically rollback on failure")
 	runCmd.Flags().Bool("skip-approval", false, "Skip approval steps")
+	runCmd.Flags().String("approval-dir", "", "Exchange approval requests and responses as files in this directory instead of prompting")
+	runCmd.Flags().String("approval-url", "", "POST approval requests to this URL and poll the approval service for decisions instead of prompting")
+	viper.BindPFlag("approval.url", runCmd.Flags().Lookup("approval-url"))
+	runCmd.Flags().String("approval-status-url", "", "URL polled for approval decisions, with {id} replaced by the request ID (default: <approval-url>/{id})")
+	viper.BindPFlag("approval.statusUrl", runCmd.Flags().Lookup("approval-status-url"))
+	runCmd.Flags().Duration("approval-poll-interval", approval.DefaultPollInterval, "How often to check --approval-dir or --approval-url for a response")
+	runCmd.Flags().Bool("rollback-on-cancel", false, "Rollback the completed work when the execution is interrupted")
+	runCmd.Flags().Bool("fail-fast", true, "Stop a stage at its first failed job; with --fail-fast=false, run every job whose dependencies succeeded and report all failures")
+	runCmd.Flags().String("on-stage-failure", "", "What happens to the remaining stages once a stage fails: abort, continue (run the stages not depending on it) or rollback-and-abort, overriding the plan's onStageFailure (default: abort)")
+	runCmd.Flags().Bool("require-rollback", false, "With --auto-rollback or rollback-and-abort, refuse to start if a rollback could not undo some stages (no rollback jobs and no plugin supporting rollback)")
+	runCmd.Flags().Bool("stage-rollback-only", false, "Only run the rollback jobs of the failed stages when rolling back, not plugin rollbacks or the plan's rollback stages")
+	runCmd.Flags().Duration("cancel-grace-period", engine.DefaultCancelGracePeriod, "How long running jobs may clean up after an interrupt")
+	runCmd.Flags().Duration("timeout", 0, "Fail the execution if it runs longer than this, overriding the plan's timeout (0 = the plan's)")
+	runCmd.Flags().Bool("strict", false, "Reject fields that are not part of the plan schema")
+	runCmd.Flags().StringArray("values", nil, "YAML file of variables overriding the plan's; repeat to merge several in order")
+	runCmd.Flags().StringArray("set", nil, "Override a variable as key.path=value, after the --values files; repeatable")
+	runCmd.Flags().String("environment", "", "Apply this environment from the plan's environments block")
 	runCmd.Flags().Bool("dry-run", false, "Validate and simulate execution without making changes")
-	runCmd.Flags().String("plugin-dir", "", "Directory containing plugins (default: ./plugins)")
+	runCmd.Flags().StringArray("plugin-dir", nil, "Directory containing plugins; repeat or separate with ':' for several (default: ./plugins)")
+	runCmd.Flags().Bool("plugin-recursive", false, "Also search the subdirectories of the plugin directories")
+	runCmd.Flags().String("log-dir", "", "Capture the output of each job to <dir>/<execution id>/<stage>/<job>.log")
+	runCmd.Flags().Bool("prefix-output", true, "Prefix each line of job output on stderr with [stage/job]; --prefix-output=false prints the lines as the jobs wrote them")
+	runCmd.Flags().String("artifact-dir", "", "Store the artifacts of the jobs and their manifest in <dir>/<execution id>/")
+	runCmd.Flags().String("working-dir", "", "Directory relative job config paths are rooted at, overriding the plan's workingDir (default: the plan file's directory)")
+	runCmd.Flags().String("state-dir", "", "Record the execution in this directory for the history command (default: state.dir from the config file)")
+	runCmd.Flags().Duration("plugin-timeout", 0, "Abandon any single plugin execution running longer than this (0 = no limit)")
+	runCmd.Flags().String("from-stage", "", "Start at this stage, skipping the stages before it")
+	runCmd.Flags().String("to-stage", "", "Stop after this stage, skipping the stages after it")
+	runCmd.Flags().StringSlice("tags", nil, "Only run jobs with any of these tags, and the jobs they depend on")
+	runCmd.Flags().StringSlice("exclude-tags", nil, "Skip jobs with any of these tags")
+	runCmd.Flags().Int("max-parallel-stages", 0, "Maximum number of independent stages to run at once (0 = unlimited)")
+	runCmd.Flags().StringP("output", "o", report.FormatText, "Output format: text, json, yaml or junit")
+	runCmd.Flags().String("metrics-pushgateway", "", "Push execution metrics to this Prometheus Pushgateway URL")
+	viper.BindPFlag("metrics.pushgateway", runCmd.Flags().Lookup("metrics-pushgateway"))
+	runCmd.Flags().String("upload-url", "", "Upload the JSON report and job logs to s3://bucket/prefix or gs://bucket/prefix after the run")
+	viper.BindPFlag("upload.url", runCmd.Flags().Lookup("upload-url"))
+	runCmd.Flags().Bool("require-upload", false, "Fail the run if the results cannot be uploaded")
+	runCmd.Flags().String("report-file", "", "Write the json/yaml/junit report to this file instead of stdout")
+	runCmd.Flags().Bool("no-color", false, "Do not color the result table, even when stdout is a terminal")
+	
+	// Complete stage names and tags from the plan given as argument
+	runCmd.RegisterFlagCompletionFunc("from-stage", completeStages)
+	runCmd.RegisterFlagCompletionFunc("to-stage", completeStages)
+	runCmd.RegisterFlagCompletionFunc("tags", completeTags)
+	runCmd.RegisterFlagCompletionFunc("exclude-tags", completeTags)
+	runCmd.RegisterFlagCompletionFunc("environment", completeEnvironments)
+	for _, flag := range []string{"approval-dir", "plugin-dir", "log-dir", "artifact-dir", "state-dir", "working-dir"} {
+		runCmd.MarkFlagDirname(flag)
+	}
 } 
\ No newline at end of file
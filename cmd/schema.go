@@ -0,0 +1,54 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/cuongtl1992/grp-cli/internal/config"
+)
+
+// schemaCmd represents the schema command
+var schemaCmd = &cobra.Command{
+	Use:   "schema",
+	Short: "Print the JSON Schema of the release plan format",
+	Long: `Print the JSON Schema describing release plans, so that editors can validate
+and complete plans as they are written. With the YAML language server, save it
+with --out and reference it from the first line of a plan:
+
+  # yaml-language-server: $schema=./releaseplan.schema.json
+
+The schema matches the plan format of this grp-cli version, including templates,
+environments and the other fields resolved when a plan is loaded.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		out, _ := cmd.Flags().GetString("out")
+
+		schema, err := config.PlanJSONSchema()
+		if err != nil {
+			return err
+		}
+		data, err := json.MarshalIndent(schema, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to encode schema: %w", err)
+		}
+		data = append(data, '\n')
+
+		if out == "" {
+			_, err := cmd.OutOrStdout().Write(data)
+			return err
+		}
+		if err := os.WriteFile(out, data, 0644); err != nil {
+			return fmt.Errorf("failed to write schema: %w", err)
+		}
+		fmt.Fprintf(cmd.ErrOrStderr(), "Schema written to %s\n", out)
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(schemaCmd)
+	schemaCmd.Flags().String("out", "", "Write the schema to this file instead of stdout")
+}
@@ -0,0 +1,110 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/cuongtl1992/grp-cli/internal/models"
+)
+
+// ANSI colors of the statuses in result tables. They all have two digits, so
+// every colored cell of a column grows by the same number of bytes and tabwriter
+// keeps the columns aligned; the header uses the default color for the same reason.
+const (
+	colorDefault = "39"
+	colorRed     = "31"
+	colorGreen   = "32"
+	colorYellow  = "33"
+)
+
+// useColor reports whether tables written to out are colored: out must be a
+// terminal, and neither --no-color nor the NO_COLOR environment variable set
+func useColor(cmd *cobra.Command, out *os.File) bool {
+	if noColor, _ := cmd.Flags().GetBool("no-color"); noColor {
+		return false
+	}
+	if os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+	return isTerminal(out)
+}
+
+// writeResultTable writes the status, duration, attempts and message of each
+// stage and of its hooks and jobs as an aligned table
+func writeResultTable(w io.Writer, stages []models.StageResult, color bool) {
+	paint := func(text, code string) string {
+		if !color {
+			return text
+		}
+		return "\033[" + code + "m" + text + "\033[0m"
+	}
+
+	table := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(table, "STAGE\tJOB\t%s\tDURATION\tATTEMPTS\tMESSAGE\n", paint("STATUS", colorDefault))
+	for _, stage := range stages {
+		status, code := stageStatus(stage)
+		fmt.Fprintf(table, "%s\t\t%s\t%s\t\t%s\n", stage.Name, paint(status, code), formatDuration(stage.Duration), firstLine(stage.AbortReason))
+
+		for _, hooks := range []struct {
+			suffix string
+			jobs   []models.JobResult
+		}{{" (pre)", stage.PreJobs}, {"", stage.Jobs}, {" (post)", stage.PostJobs}} {
+			for _, job := range hooks.jobs {
+				status, code := jobStatus(job)
+				attempts := "-"
+				if job.Attempts > 0 {
+					attempts = strconv.Itoa(job.Attempts)
+				}
+				fmt.Fprintf(table, "\t%s\t%s\t%s\t%s\t%s\n", job.Name+hooks.suffix, paint(status, code), formatDuration(job.Duration), attempts, firstLine(job.Message))
+			}
+		}
+	}
+	table.Flush()
+}
+
+// stageStatus describes the outcome of a stage in a word, with its color
+func stageStatus(stage models.StageResult) (string, string) {
+	switch {
+	case stage.Canceled:
+		return "canceled", colorYellow
+	case stage.Success:
+		return "succeeded", colorGreen
+	}
+	return "failed", colorRed
+}
+
+// jobStatus describes the outcome of a job, with its color
+func jobStatus(job models.JobResult) (string, string) {
+	switch {
+	case job.Skipped:
+		return "skipped", colorYellow
+	case job.Canceled:
+		return "canceled", colorYellow
+	case job.ContinuedOnError:
+		return "failed (continued)", colorYellow
+	case job.Success:
+		return "succeeded", colorGreen
+	}
+	return "failed", colorRed
+}
+
+// formatDuration rounds a duration for display, or returns "-" if it is unknown
+func formatDuration(duration time.Duration) string {
+	if duration <= 0 {
+		return "-"
+	}
+	return duration.Round(time.Millisecond).String()
+}
+
+// firstLine returns the first line of a message, so that the table stays one row per entry
+func firstLine(message string) string {
+	line, _, _ := strings.Cut(strings.TrimSpace(message), "\n")
+	return line
+}
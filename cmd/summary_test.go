@@ -0,0 +1,67 @@
+package cmd
+
+import (
+	"bytes"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/cuongtl1992/grp-cli/internal/models"
+)
+
+func TestWriteResultTable(t *testing.T) {
+	stages := []models.StageResult{
+		{
+			Name:     "build",
+			Success:  true,
+			Duration: 1204 * time.Millisecond,
+			Jobs: []models.JobResult{
+				{Name: "compile", Success: true, Attempts: 1, Duration: 1202 * time.Millisecond, Message: "Build completed\nwith 2 warnings"},
+				{Name: "lint", ContinuedOnError: true, Attempts: 1, Duration: 300 * time.Millisecond, Message: "3 issues"},
+			},
+		},
+		{
+			Name:        "deploy",
+			Duration:    3510 * time.Millisecond,
+			AbortReason: "job migrate failed",
+			PreJobs:     []models.JobResult{{Name: "lock", Success: true, Attempts: 1, Duration: 8 * time.Millisecond}},
+			Jobs: []models.JobResult{
+				{Name: "migrate", Attempts: 3, Duration: 3500 * time.Millisecond, Message: "migration timed out"},
+				{Name: "app", Skipped: true, Message: "not run: blocked by failed job migrate"},
+			},
+			PostJobs: []models.JobResult{{Name: "unlock", Canceled: true, Attempts: 1, Duration: time.Millisecond, Message: "canceled"}},
+		},
+	}
+	expected := "STAGE   JOB            STATUS              DURATION  ATTEMPTS  MESSAGE\n" +
+		"build                  succeeded           1.204s              \n" +
+		"        compile        succeeded           1.202s    1         Build completed\n" +
+		"        lint           failed (continued)  300ms     1         3 issues\n" +
+		"deploy                 failed              3.51s               job migrate failed\n" +
+		"        lock (pre)     succeeded           8ms       1         \n" +
+		"        migrate        failed              3.5s      3         migration timed out\n" +
+		"        app            skipped             -         -         not run: blocked by failed job migrate\n" +
+		"        unlock (post)  canceled            1ms       1         canceled\n"
+
+	tests := []struct {
+		name  string
+		color bool
+	}{
+		{"plain", false},
+		{"colored", true},
+	}
+	ansi := regexp.MustCompile("\033\\[[0-9]+m")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := new(bytes.Buffer)
+			writeResultTable(buf, stages, tt.color)
+
+			if colored := ansi.MatchString(buf.String()); colored != tt.color {
+				t.Errorf("Expected colored output %v, got %v", tt.color, colored)
+			}
+			// Colors must not break the alignment of the columns
+			if got := ansi.ReplaceAllString(buf.String(), ""); got != expected {
+				t.Errorf("Expected:\n%s\ngot:\n%s", expected, got)
+			}
+		})
+	}
+}
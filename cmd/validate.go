@@ -2,47 +2,43 @@ package cmd
 
 import (
 	"fmt"
-	"os"
 
 	"github.com/spf13/cobra"
-
-	"github.com/cuongtl1992/grp-cli/internal/config"
 )
 
 // validateCmd represents the validate command
 var validateCmd = &cobra.Command{
-	Use:   "validate [plan file]",
+	Use:   "validate [plan file, directory or glob]",
 	Short: "Validate a release plan",
 	Long: `Validate a release plan defined in YAML format. This command will:
 1. Check the syntax of the plan file
 2. Validate the structure against the schema
 3. Verify that all references are valid
-4. Check for circular dependencies`,
-	Args: cobra.ExactArgs(1),
+4. Check for circular dependencies
+
+Given a directory or a glob pattern, or with --all, every *.yaml and *.yml plan
+it names is validated and a pass/fail summary of each file is printed. YAML files
+without apiVersion and kind are skipped as not being plans.`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completePlanFile,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		planFile := args[0]
 		
-		 // Check if file exists before proceeding
-		if _, err := os.Stat(planFile); err != nil {
-			if os.IsNotExist(err) {
-				return fmt.Errorf("plan file not found: %s", planFile)
-			}
-			return fmt.Errorf("error accessing plan file: %w", err)
+		outputFormat, _ := cmd.Flags().GetString("output")
+		if outputFormat != "" && outputFormat != "text" && outputFormat != "json" {
+			return fmt.Errorf("unsupported output format: %s (expected text or json)", outputFormat)
 		}
 		
-		// Create loader and validator
-		loader := config.NewLoader()
-		validator := config.NewValidator()
-		
-		// Load the plan
-		plan, err := loader.LoadPlan(planFile)
-		if err != nil {
-			return fmt.Errorf("failed to load plan: %w", err)
+		// Validate each plan of a directory or glob and report them all
+		all, _ := cmd.Flags().GetBool("all")
+		if all || outputFormat == "json" || isPlanSet(planFile) {
+			return validatePlanSet(cmd, planFile, outputFormat)
 		}
 		
-		// Validate the plan
-		if err := validator.ValidatePlan(plan); err != nil {
-			return fmt.Errorf("validation failed: %w", err)
+		// Load and validate the plan
+		plan, err := loadAndValidatePlan(cmd, planFile)
+		if err != nil {
+			return err
 		}
 		
 		fmt.Println("Plan validation successful!")
@@ -76,4 +72,11 @@ var validateCmd = &cobra.Command{
 func init() {
 	rootCmd.AddCommand(validateCmd)
 	validateCmd.Flags().BoolP("verbose", "v", false, "Show detailed validation information")
+	validateCmd.Flags().Bool("all", false, "Report the result of each plan file, even for a single file; implied by a directory or glob")
+	validateCmd.Flags().StringP("output", "o", "text", "Output format: text or json")
+	validateCmd.Flags().Bool("strict", false, "Reject fields that are not part of the plan schema")
+	validateCmd.Flags().StringArray("values", nil, "YAML file of variables overriding the plan's; repeat to merge several in order")
+	validateCmd.Flags().StringArray("set", nil, "Override a variable as key.path=value, after the --values files; repeatable")
+	validateCmd.Flags().String("environment", "", "Apply this environment from the plan's environments block")
+	validateCmd.RegisterFlagCompletionFunc("environment", completeEnvironments)
 }
\ No newline at end of file
@@ -168,3 +168,78 @@ stages:
 		})
 	}
 }
+
+func TestValidatePlanSet(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"api.yaml":    "apiVersion: v1\nkind: ReleasePlan\nmetadata:\n  name: api\nstages:\n  - name: deploy\n    jobs:\n      - name: app\n        type: test\n",
+		"web.yml":     "apiVersion: v1\nkind: ReleasePlan\nmetadata:\n  name: web\nstages: []\n",
+		"values.yaml": "replicas: 3\n",
+		"notes.txt":   "not yaml",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name    string
+		target  string
+		output  string
+		wantErr string
+		wantOut []string
+	}{
+		{
+			name:    "directory",
+			target:  dir,
+			output:  "text",
+			wantErr: "1 of 2 plans failed validation",
+			wantOut: []string{
+				"PASS  " + filepath.Join(dir, "api.yaml") + " (api)\n",
+				"SKIP  " + filepath.Join(dir, "values.yaml") + ": not a release plan: missing apiVersion, kind\n",
+				"FAIL  " + filepath.Join(dir, "web.yml") + ": validation failed: ",
+				"3 files: 1 passed, 1 failed, 1 skipped\n",
+			},
+		},
+		{
+			name:    "glob",
+			target:  filepath.Join(dir, "a*.yaml"),
+			output:  "text",
+			wantOut: []string{"1 files: 1 passed, 0 failed, 0 skipped\n"},
+		},
+		{
+			name:    "json",
+			target:  filepath.Join(dir, "*.yaml"),
+			output:  "json",
+			wantOut: []string{`"status": "passed"`, `"status": "skipped"`, `"passed": 1`, `"skipped": 1`},
+		},
+		{
+			name:    "no plans",
+			target:  filepath.Join(dir, "*.json"),
+			output:  "text",
+			wantErr: "no plan files found",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := new(bytes.Buffer)
+			cmd := &cobra.Command{}
+			cmd.SetOut(buf)
+
+			err := validatePlanSet(cmd, tt.target, tt.output)
+			if tt.wantErr == "" && err != nil {
+				t.Errorf("Expected no error, got %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Errorf("Expected error containing %q, got %v", tt.wantErr, err)
+			}
+			for _, want := range tt.wantOut {
+				if !strings.Contains(buf.String(), want) {
+					t.Errorf("Expected output containing %q, got:\n%s", want, buf.String())
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,182 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+
+	"github.com/cuongtl1992/grp-cli/internal/config"
+	"github.com/cuongtl1992/grp-cli/internal/models"
+)
+
+// Statuses of the files validated by validate --all
+const (
+	validationPassed  = "passed"
+	validationFailed  = "failed"
+	validationSkipped = "skipped"
+)
+
+// planValidation is the outcome of validating one file of a set of plans
+type planValidation struct {
+	File   string `json:"file"`
+	Status string `json:"status"`
+	Plan   string `json:"plan,omitempty"`
+	Error  string `json:"error,omitempty"`
+	Note   string `json:"note,omitempty"`
+}
+
+// validationReport is the outcome of validating a set of plans
+type validationReport struct {
+	Files   []planValidation `json:"files"`
+	Passed  int              `json:"passed"`
+	Failed  int              `json:"failed"`
+	Skipped int              `json:"skipped"`
+}
+
+// isPlanSet reports whether a validate argument names several plans: a directory
+// or a glob pattern
+func isPlanSet(target string) bool {
+	if strings.ContainsAny(target, "*?[") {
+		return true
+	}
+	info, err := os.Stat(target)
+	return err == nil && info.IsDir()
+}
+
+// planSetFiles returns the files a validate argument names, sorted: the *.yaml and
+// *.yml files of a directory, the files matching a glob pattern, or a single file
+func planSetFiles(target string) ([]string, error) {
+	info, err := os.Stat(target)
+	if err == nil && info.IsDir() {
+		var files []string
+		for _, pattern := range []string{"*.yaml", "*.yml"} {
+			matches, err := filepath.Glob(filepath.Join(target, pattern))
+			if err != nil {
+				return nil, err
+			}
+			files = append(files, matches...)
+		}
+		sort.Strings(files)
+		return files, nil
+	}
+	if !strings.ContainsAny(target, "*?[") {
+		return []string{target}, nil
+	}
+
+	matches, err := filepath.Glob(target)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pattern %s: %w", target, err)
+	}
+	var files []string
+	for _, match := range matches {
+		if info, err := os.Stat(match); err == nil && !info.IsDir() {
+			files = append(files, match)
+		}
+	}
+	return files, nil
+}
+
+// loadAndValidatePlan loads a plan with the loader settings of cmd and validates it
+func loadAndValidatePlan(cmd *cobra.Command, planFile string) (*models.Plan, error) {
+	loader, err := newPlanLoader(cmd)
+	if err != nil {
+		return nil, err
+	}
+	plan, err := loader.LoadPlan(planFile)
+	if err != nil {
+		return nil, withExitCode(ExitValidation, fmt.Errorf("failed to load plan: %w", err))
+	}
+	if err := config.NewValidator().ValidatePlan(plan); err != nil {
+		return nil, withExitCode(ExitValidation, fmt.Errorf("validation failed: %w", err))
+	}
+	return plan, nil
+}
+
+// missingPlanFields returns the apiVersion and kind fields a YAML file lacks to be a
+// plan. Files that cannot be parsed are left to the loader to report.
+func missingPlanFields(file string) []string {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil
+	}
+	var document map[string]interface{}
+	if err := yaml.Unmarshal(data, &document); err != nil {
+		return nil
+	}
+
+	var missing []string
+	for _, field := range []string{"apiVersion", "kind"} {
+		if _, exists := document[field]; !exists {
+			missing = append(missing, field)
+		}
+	}
+	return missing
+}
+
+// validatePlanSet validates every plan named by target, skipping the YAML files
+// that are not plans, and writes a report of each file. It fails if any plan does.
+func validatePlanSet(cmd *cobra.Command, target, outputFormat string) error {
+	files, err := planSetFiles(target)
+	if err != nil {
+		return withExitCode(ExitValidation, err)
+	}
+	if len(files) == 0 {
+		return withExitCode(ExitValidation, fmt.Errorf("no plan files found in %s", target))
+	}
+
+	var report validationReport
+	for _, file := range files {
+		validation := planValidation{File: file}
+		if missing := missingPlanFields(file); len(missing) > 0 {
+			validation.Status = validationSkipped
+			validation.Note = "not a release plan: missing " + strings.Join(missing, ", ")
+			report.Skipped++
+		} else if plan, err := loadAndValidatePlan(cmd, file); err != nil {
+			validation.Status = validationFailed
+			validation.Error = err.Error()
+			report.Failed++
+		} else {
+			validation.Status = validationPassed
+			validation.Plan = plan.Metadata.Name
+			report.Passed++
+		}
+		report.Files = append(report.Files, validation)
+	}
+
+	if outputFormat == "json" {
+		encoder := json.NewEncoder(cmd.OutOrStdout())
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(report); err != nil {
+			return err
+		}
+	} else {
+		writeValidationReport(cmd.OutOrStdout(), report)
+	}
+
+	if report.Failed > 0 {
+		return withExitCode(ExitValidation, fmt.Errorf("%d of %d plans failed validation", report.Failed, report.Passed+report.Failed))
+	}
+	return nil
+}
+
+// writeValidationReport writes the outcome of each file and the totals
+func writeValidationReport(w io.Writer, report validationReport) {
+	for _, file := range report.Files {
+		switch file.Status {
+		case validationPassed:
+			fmt.Fprintf(w, "PASS  %s (%s)\n", file.File, file.Plan)
+		case validationFailed:
+			fmt.Fprintf(w, "FAIL  %s: %s\n", file.File, file.Error)
+		default:
+			fmt.Fprintf(w, "SKIP  %s: %s\n", file.File, file.Note)
+		}
+	}
+	fmt.Fprintf(w, "\n%d files: %d passed, %d failed, %d skipped\n", len(report.Files), report.Passed, report.Failed, report.Skipped)
+}
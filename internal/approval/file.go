@@ -0,0 +1,179 @@
+package approval
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/cuongtl1992/grp-cli/internal/models"
+)
+
+// DefaultPollInterval is how often the file service checks for a response
+const DefaultPollInterval = 5 * time.Second
+
+// FileService exchanges approval requests and responses through files in a
+// directory, so that CI systems or other tools can approve out of band.
+// Each request is written to <id>.request.json and the decision is read from
+// <id>.response.json, which holds a JSON ApprovalResponse.
+type FileService struct {
+	dir          string
+	pollInterval time.Duration
+}
+
+// pendingRequest is the JSON form of a request written for external approvers
+type pendingRequest struct {
+	ID           string     `json:"id"`
+	ExecutionID  string     `json:"executionId"`
+	Stage        string     `json:"stage"`
+	Approvers    []string   `json:"approvers"`
+	RequestedAt  time.Time  `json:"requestedAt"`
+	ExpiresAt    *time.Time `json:"expiresAt,omitempty"`
+	ResponseFile string     `json:"responseFile,omitempty"`
+	StatusURL    string     `json:"statusUrl,omitempty"`
+	// Jobs describes what the stage will do
+	Jobs []models.ApprovalJob `json:"jobs,omitempty"`
+}
+
+// newPendingRequest returns the JSON form of request for external approvers
+func newPendingRequest(request *models.ApprovalRequest) pendingRequest {
+	pending := pendingRequest{
+		ID:          request.ID,
+		ExecutionID: request.ExecutionID,
+		Stage:       request.StageName,
+		Approvers:   request.Approvers,
+		RequestedAt: request.RequestedAt,
+		Jobs:        request.Jobs,
+	}
+	if pending.Approvers == nil {
+		pending.Approvers = []string{}
+	}
+	if !request.ExpiresAt.IsZero() {
+		expiresAt := request.ExpiresAt
+		pending.ExpiresAt = &expiresAt
+	}
+	return pending
+}
+
+// NewFileService creates an approval service using dir for the request and
+// response files. A non-positive pollInterval uses DefaultPollInterval.
+func NewFileService(dir string, pollInterval time.Duration) *FileService {
+	if pollInterval <= 0 {
+		pollInterval = DefaultPollInterval
+	}
+	return &FileService{
+		dir:          dir,
+		pollInterval: pollInterval,
+	}
+}
+
+// RequestPath returns the file the request with the given ID is written to
+func (s *FileService) RequestPath(id string) string {
+	return filepath.Join(s.dir, id+".request.json")
+}
+
+// ResponsePath returns the file the response to the request with the given ID is read from
+func (s *FileService) ResponsePath(id string) string {
+	return filepath.Join(s.dir, id+".response.json")
+}
+
+// RequestApproval writes the pending request and polls for its response file
+func (s *FileService) RequestApproval(ctx context.Context, request *models.ApprovalRequest) (*models.ApprovalResponse, error) {
+	// Honor the expiry of the request, if any
+	if !request.ExpiresAt.IsZero() {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithDeadline(ctx, request.ExpiresAt)
+		defer cancel()
+	}
+
+	if err := s.writeRequest(request); err != nil {
+		return nil, err
+	}
+
+	ticker := time.NewTicker(s.pollInterval)
+	defer ticker.Stop()
+
+	var lastErr error
+	for {
+		response, err := s.readResponse(request)
+		if err == nil {
+			return response, nil
+		}
+		if !errors.Is(err, os.ErrNotExist) {
+			// The approver may still be writing the file; retry until the request ends
+			lastErr = err
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			if ctx.Err() == context.DeadlineExceeded {
+				if lastErr != nil {
+					return nil, fmt.Errorf("%w (%v)", ErrExpired, lastErr)
+				}
+				return nil, ErrExpired
+			}
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// writeRequest atomically writes the request file for external approvers
+func (s *FileService) writeRequest(request *models.ApprovalRequest) error {
+	if err := os.MkdirAll(s.dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create approval directory: %w", err)
+	}
+
+	pending := newPendingRequest(request)
+	pending.ResponseFile = s.ResponsePath(request.ID)
+
+	data, err := json.MarshalIndent(pending, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode approval request: %w", err)
+	}
+
+	// Write to a temporary file first so readers never see a partial request
+	tmp, err := os.CreateTemp(s.dir, request.ID+".request.*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to write approval request: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write approval request: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write approval request: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), s.RequestPath(request.ID)); err != nil {
+		return fmt.Errorf("failed to write approval request: %w", err)
+	}
+	return nil
+}
+
+// readResponse reads and validates the response file of the request
+func (s *FileService) readResponse(request *models.ApprovalRequest) (*models.ApprovalResponse, error) {
+	path := s.ResponsePath(request.ID)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var response models.ApprovalResponse
+	if err := json.Unmarshal(data, &response); err != nil {
+		return nil, fmt.Errorf("invalid approval response %s: %w", path, err)
+	}
+	if response.RequestID == "" {
+		response.RequestID = request.ID
+	} else if response.RequestID != request.ID {
+		return nil, fmt.Errorf("approval response %s is for request %s, not %s", path, response.RequestID, request.ID)
+	}
+	if response.RespondedAt.IsZero() {
+		response.RespondedAt = time.Now()
+	}
+	return &response, nil
+}
@@ -0,0 +1,132 @@
+package approval
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/cuongtl1992/grp-cli/internal/models"
+)
+
+func TestFileServiceRequestApproval(t *testing.T) {
+	tests := []struct {
+		name             string
+		response         string
+		expectedErr      error
+		expectedApproved bool
+		expectedComment  string
+	}{
+		{
+			name:             "approved",
+			response:         `{"requestId": "req-1", "approved": true, "responderId": "ci", "comment": "pipeline green"}`,
+			expectedApproved: true,
+			expectedComment:  "pipeline green",
+		},
+		{
+			name:     "rejected without request id",
+			response: `{"approved": false, "responderId": "alice"}`,
+		},
+		{
+			name:        "no response expires",
+			expectedErr: ErrExpired,
+		},
+		{
+			name:        "response for another request is ignored",
+			response:    `{"requestId": "req-2", "approved": true}`,
+			expectedErr: ErrExpired,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			service := NewFileService(dir, 10*time.Millisecond)
+			request := &models.ApprovalRequest{
+				ID:          "req-1",
+				ExecutionID: "exec-1",
+				StageName:   "deploy",
+				Approvers:   []string{"alice@example.com"},
+				RequestedAt: time.Now(),
+				ExpiresAt:   time.Now().Add(200 * time.Millisecond),
+			}
+
+			if tt.response != "" {
+				// Answer once the request file shows up, like an external approver would
+				go func() {
+					for i := 0; i < 100; i++ {
+						if _, err := os.Stat(service.RequestPath(request.ID)); err == nil {
+							os.WriteFile(service.ResponsePath(request.ID), []byte(tt.response), 0o644)
+							return
+						}
+						time.Sleep(5 * time.Millisecond)
+					}
+				}()
+			}
+
+			response, err := service.RequestApproval(context.Background(), request)
+			if tt.expectedErr != nil {
+				if !errors.Is(err, tt.expectedErr) {
+					t.Fatalf("Expected %v, got %v", tt.expectedErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("RequestApproval() error = %v", err)
+			}
+			if response.Approved != tt.expectedApproved {
+				t.Errorf("Expected approved=%v, got %v", tt.expectedApproved, response.Approved)
+			}
+			if response.Comment != tt.expectedComment {
+				t.Errorf("Expected comment %q, got %q", tt.expectedComment, response.Comment)
+			}
+			if response.RequestID != request.ID || response.RespondedAt.IsZero() {
+				t.Errorf("Expected response for %s with a response time, got %+v", request.ID, response)
+			}
+		})
+	}
+}
+
+func TestFileServiceWritesRequest(t *testing.T) {
+	dir := t.TempDir()
+	service := NewFileService(dir, time.Millisecond)
+	request := &models.ApprovalRequest{
+		ID:          "req-1",
+		ExecutionID: "exec-1",
+		StageName:   "deploy",
+		Approvers:   []string{"alice@example.com", "bob@example.com"},
+		ExpiresAt:   time.Now().Add(20 * time.Millisecond),
+	}
+
+	if _, err := service.RequestApproval(context.Background(), request); !errors.Is(err, ErrExpired) {
+		t.Fatalf("Expected ErrExpired, got %v", err)
+	}
+
+	data, err := os.ReadFile(service.RequestPath(request.ID))
+	if err != nil {
+		t.Fatalf("Expected request file: %v", err)
+	}
+	var pending pendingRequest
+	if err := json.Unmarshal(data, &pending); err != nil {
+		t.Fatalf("Invalid request file: %v", err)
+	}
+	if pending.ID != "req-1" || pending.Stage != "deploy" || len(pending.Approvers) != 2 || pending.ExpiresAt == nil {
+		t.Errorf("Unexpected request file contents: %s", data)
+	}
+	if pending.ResponseFile != service.ResponsePath(request.ID) {
+		t.Errorf("Expected response file %s, got %s", service.ResponsePath(request.ID), pending.ResponseFile)
+	}
+}
+
+func TestFileServiceCanceled(t *testing.T) {
+	service := NewFileService(t.TempDir(), time.Millisecond)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := service.RequestApproval(ctx, &models.ApprovalRequest{ID: "req-1", StageName: "deploy"})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+}
@@ -0,0 +1,147 @@
+package approval
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/user"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/cuongtl1992/grp-cli/internal/models"
+)
+
+// InteractiveService prompts for approval decisions on a terminal
+type InteractiveService struct {
+	in  *bufio.Reader
+	out io.Writer
+}
+
+// NewInteractiveService creates an approval service reading answers from in
+// and writing prompts to out
+func NewInteractiveService(in io.Reader, out io.Writer) *InteractiveService {
+	return &InteractiveService{
+		in:  bufio.NewReader(in),
+		out: out,
+	}
+}
+
+// RequestApproval prompts for an approve/reject decision and an optional comment
+func (s *InteractiveService) RequestApproval(ctx context.Context, request *models.ApprovalRequest) (*models.ApprovalResponse, error) {
+	// Honor the expiry of the request, if any
+	if !request.ExpiresAt.IsZero() {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithDeadline(ctx, request.ExpiresAt)
+		defer cancel()
+	}
+
+	fmt.Fprintf(s.out, "\nStage %s requires approval.\n", request.StageName)
+	if len(request.Approvers) > 0 {
+		fmt.Fprintf(s.out, "Approvers: %s\n", strings.Join(request.Approvers, ", "))
+	}
+	if !request.ExpiresAt.IsZero() {
+		fmt.Fprintf(s.out, "Request expires at %s\n", request.ExpiresAt.Format(time.RFC3339))
+	}
+	writeJobs(s.out, request.Jobs)
+
+	answer, err := s.prompt(ctx, "Approve? [y/N]: ")
+	if err != nil {
+		return nil, err
+	}
+	comment, err := s.prompt(ctx, "Comment (optional): ")
+	if err != nil {
+		return nil, err
+	}
+
+	answer = strings.ToLower(answer)
+	return &models.ApprovalResponse{
+		RequestID:     request.ID,
+		Approved:      answer == "y" || answer == "yes",
+		ResponderID:   currentUserID(),
+		ResponderName: currentUserName(),
+		Comment:       comment,
+		RespondedAt:   time.Now(),
+	}, nil
+}
+
+// writeJobs describes the jobs of the stage: their config and preview
+func writeJobs(w io.Writer, jobs []models.ApprovalJob) {
+	if len(jobs) == 0 {
+		return
+	}
+
+	fmt.Fprintln(w, "\nJobs:")
+	for _, job := range jobs {
+		fmt.Fprintf(w, "  %s (%s)\n", job.Name, job.Type)
+		if len(job.Config) > 0 {
+			fmt.Fprintln(w, "    config:")
+			if data, err := yaml.Marshal(job.Config); err == nil {
+				writeIndented(w, string(data), "      ")
+			}
+		}
+		if job.Preview != "" {
+			fmt.Fprintln(w, "    preview:")
+			writeIndented(w, job.Preview, "      ")
+		}
+		if job.PreviewError != "" {
+			fmt.Fprintf(w, "    preview failed: %s\n", job.PreviewError)
+		}
+	}
+	fmt.Fprintln(w)
+}
+
+// writeIndented writes each line of text with the given indent
+func writeIndented(w io.Writer, text, indent string) {
+	for _, line := range strings.Split(strings.TrimRight(text, "\n"), "\n") {
+		fmt.Fprintf(w, "%s%s\n", indent, line)
+	}
+}
+
+// prompt writes a question and waits for a line of input or the context to end
+func (s *InteractiveService) prompt(ctx context.Context, question string) (string, error) {
+	fmt.Fprint(s.out, question)
+
+	lines := make(chan string, 1)
+	errs := make(chan error, 1)
+	go func() {
+		line, err := s.in.ReadString('\n')
+		if err != nil && (err != io.EOF || line == "") {
+			errs <- fmt.Errorf("failed to read approval input: %w", err)
+			return
+		}
+		lines <- strings.TrimSpace(line)
+	}()
+
+	select {
+	case line := <-lines:
+		return line, nil
+	case err := <-errs:
+		return "", err
+	case <-ctx.Done():
+		fmt.Fprintln(s.out)
+		if ctx.Err() == context.DeadlineExceeded {
+			return "", ErrExpired
+		}
+		return "", ctx.Err()
+	}
+}
+
+// currentUserID returns the login name of the user running the CLI
+func currentUserID() string {
+	if u, err := user.Current(); err == nil {
+		return u.Username
+	}
+	return os.Getenv("USER")
+}
+
+// currentUserName returns the display name of the user running the CLI
+func currentUserName() string {
+	if u, err := user.Current(); err == nil && u.Name != "" {
+		return u.Name
+	}
+	return currentUserID()
+}
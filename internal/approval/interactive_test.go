@@ -0,0 +1,101 @@
+package approval
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cuongtl1992/grp-cli/internal/models"
+)
+
+func TestInteractiveServiceRequestApproval(t *testing.T) {
+	tests := []struct {
+		name             string
+		input            string
+		expectedApproved bool
+		expectedComment  string
+	}{
+		{name: "approved", input: "y\nlooks good\n", expectedApproved: true, expectedComment: "looks good"},
+		{name: "approved long form", input: "YES\n\n", expectedApproved: true},
+		{name: "rejected", input: "n\nnot today\n", expectedApproved: false, expectedComment: "not today"},
+		{name: "empty answer rejects", input: "\n\n", expectedApproved: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := new(bytes.Buffer)
+			service := NewInteractiveService(strings.NewReader(tt.input), out)
+			request := &models.ApprovalRequest{ID: "req-1", StageName: "deploy", Approvers: []string{"alice@example.com"}}
+
+			response, err := service.RequestApproval(context.Background(), request)
+			if err != nil {
+				t.Fatalf("RequestApproval() error = %v", err)
+			}
+			if response.Approved != tt.expectedApproved {
+				t.Errorf("Expected approved=%v, got %v", tt.expectedApproved, response.Approved)
+			}
+			if response.Comment != tt.expectedComment {
+				t.Errorf("Expected comment %q, got %q", tt.expectedComment, response.Comment)
+			}
+			if !strings.Contains(out.String(), "alice@example.com") {
+				t.Errorf("Expected prompt to list approvers, got %q", out.String())
+			}
+		})
+	}
+}
+
+func TestInteractiveServiceShowsJobs(t *testing.T) {
+	out := new(bytes.Buffer)
+	service := NewInteractiveService(strings.NewReader("y\n\n"), out)
+	request := &models.ApprovalRequest{ID: "req-1", StageName: "deploy", Jobs: []models.ApprovalJob{
+		{Name: "infra", Type: "terraform", Config: map[string]interface{}{"dir": "infra"}, Preview: "Plan: 1 to add\n"},
+		{Name: "web", Type: "kubernetes", PreviewError: "kubectl diff: exit status 2"},
+	}}
+
+	if _, err := service.RequestApproval(context.Background(), request); err != nil {
+		t.Fatalf("RequestApproval() error = %v", err)
+	}
+	expected := `
+Jobs:
+  infra (terraform)
+    config:
+      dir: infra
+    preview:
+      Plan: 1 to add
+  web (kubernetes)
+    preview failed: kubectl diff: exit status 2
+
+Approve? [y/N]: `
+	if !strings.Contains(out.String(), expected) {
+		t.Errorf("Expected prompt to describe the jobs as %q, got %q", expected, out.String())
+	}
+}
+
+func TestInteractiveServiceExpires(t *testing.T) {
+	reader, writer := io.Pipe()
+	defer writer.Close()
+
+	service := NewInteractiveService(reader, io.Discard)
+	request := &models.ApprovalRequest{ID: "req-1", StageName: "deploy", ExpiresAt: time.Now().Add(50 * time.Millisecond)}
+
+	_, err := service.RequestApproval(context.Background(), request)
+	if !errors.Is(err, ErrExpired) {
+		t.Errorf("Expected ErrExpired, got %v", err)
+	}
+}
+
+func TestApply(t *testing.T) {
+	request := &models.ApprovalRequest{ID: "req-1", Status: models.ApprovalStatusPending}
+
+	err := Apply(request, &models.ApprovalResponse{Approved: false, ResponderID: "bob"})
+	if !errors.Is(err, ErrRejected) {
+		t.Errorf("Expected ErrRejected, got %v", err)
+	}
+	if request.Status != models.ApprovalStatusRejected || request.ResponderID != "bob" {
+		t.Errorf("Expected rejected request responded by bob, got %+v", request)
+	}
+}
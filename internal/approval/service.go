@@ -0,0 +1,39 @@
+package approval
+
+import (
+	"context"
+	"errors"
+
+	"github.com/cuongtl1992/grp-cli/internal/models"
+)
+
+var (
+	// ErrRejected is returned when an approver denies the request
+	ErrRejected = errors.New("approval rejected")
+	// ErrExpired is returned when no decision is made before the request expires
+	ErrExpired = errors.New("approval request expired")
+)
+
+// Service obtains approval decisions for stages that require them
+type Service interface {
+	// RequestApproval blocks until a response is received, the request expires
+	// (when ExpiresAt is set) or the context is canceled
+	RequestApproval(ctx context.Context, request *models.ApprovalRequest) (*models.ApprovalResponse, error)
+}
+
+// Apply records the response on the request and returns an error if the
+// request was not approved
+func Apply(request *models.ApprovalRequest, response *models.ApprovalResponse) error {
+	request.RespondedAt = response.RespondedAt
+	request.ResponderID = response.ResponderID
+	request.ResponderName = response.ResponderName
+	request.Comment = response.Comment
+
+	if !response.Approved {
+		request.Status = models.ApprovalStatusRejected
+		return ErrRejected
+	}
+
+	request.Status = models.ApprovalStatusApproved
+	return nil
+}
@@ -0,0 +1,263 @@
+package approval
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/cuongtl1992/grp-cli/internal/models"
+)
+
+// ErrWebhook is returned when the approval service behind a webhook cannot be
+// reached or rejects the requests of grp-cli
+var ErrWebhook = errors.New("approval webhook failed")
+
+// webhookRequestTimeout bounds a single request to the approval service
+const webhookRequestTimeout = 30 * time.Second
+
+// WebhookConfig configures the HTTP approval backend
+type WebhookConfig struct {
+	// URL is the endpoint pending requests are POSTed to
+	URL string
+	// StatusURL is polled for the decision, with {id} replaced by the request ID.
+	// It defaults to URL/{id}.
+	StatusURL string
+	// Token, if set, is sent as a bearer token with every request
+	Token string
+	// PollInterval is how often StatusURL is polled; non-positive values use
+	// DefaultPollInterval
+	PollInterval time.Duration
+}
+
+// WebhookService submits approval requests to an external approval service over
+// HTTP. Each request is POSTed as JSON to the configured URL, then its status URL
+// is polled until it returns a decision: a 200 response holding a JSON
+// ApprovalResponse. 202, 204 and 404 responses, and responses whose status is
+// "pending", mean no decision was made yet. The POST response may name another
+// status URL to poll in its statusUrl field.
+type WebhookService struct {
+	config WebhookConfig
+	client *http.Client
+}
+
+// webhookStatus is the JSON body of the responses of the approval service
+type webhookStatus struct {
+	models.ApprovalResponse
+	// Status is pending, approved or rejected; when set, it takes precedence over approved
+	Status string `json:"status"`
+	// StatusURL replaces the status URL to poll, in the response to the POST
+	StatusURL string `json:"statusUrl"`
+}
+
+// NewWebhookService creates an approval service using the approval service
+// described by config
+func NewWebhookService(config WebhookConfig) (*WebhookService, error) {
+	if err := checkWebhookURL(config.URL); err != nil {
+		return nil, fmt.Errorf("invalid approval URL: %w", err)
+	}
+	if config.StatusURL != "" {
+		if err := checkWebhookURL(strings.ReplaceAll(config.StatusURL, "{id}", "id")); err != nil {
+			return nil, fmt.Errorf("invalid approval status URL: %w", err)
+		}
+	}
+	if config.PollInterval <= 0 {
+		config.PollInterval = DefaultPollInterval
+	}
+	return &WebhookService{
+		config: config,
+		client: &http.Client{Timeout: webhookRequestTimeout},
+	}, nil
+}
+
+// checkWebhookURL returns an error if rawURL is not an absolute HTTP(S) URL
+func checkWebhookURL(rawURL string) error {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return err
+	}
+	if (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return fmt.Errorf("%q is not an http or https URL", rawURL)
+	}
+	return nil
+}
+
+// StatusURL returns the URL polled for the decision on the request with the given ID
+func (s *WebhookService) StatusURL(id string) string {
+	if s.config.StatusURL != "" {
+		return strings.ReplaceAll(s.config.StatusURL, "{id}", url.PathEscape(id))
+	}
+	return strings.TrimSuffix(s.config.URL, "/") + "/" + url.PathEscape(id)
+}
+
+// RequestApproval submits the request and polls its status URL for the decision
+func (s *WebhookService) RequestApproval(ctx context.Context, request *models.ApprovalRequest) (*models.ApprovalResponse, error) {
+	// Honor the expiry of the request, if any
+	if !request.ExpiresAt.IsZero() {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithDeadline(ctx, request.ExpiresAt)
+		defer cancel()
+	}
+
+	statusURL, err := s.submit(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+
+	ticker := time.NewTicker(s.config.PollInterval)
+	defer ticker.Stop()
+
+	var lastErr error
+	for {
+		response, err := s.poll(ctx, request, statusURL)
+		if err == nil && response != nil {
+			return response, nil
+		}
+		if err != nil {
+			if !isTransient(err) {
+				return nil, err
+			}
+			// The approval service may be briefly unavailable; retry until the request ends
+			lastErr = err
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			if ctx.Err() == context.DeadlineExceeded {
+				if lastErr != nil {
+					return nil, fmt.Errorf("%w (%v)", ErrExpired, lastErr)
+				}
+				return nil, ErrExpired
+			}
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// submit POSTs the request to the approval service and returns the URL to poll
+// for its decision
+func (s *WebhookService) submit(ctx context.Context, request *models.ApprovalRequest) (string, error) {
+	statusURL := s.StatusURL(request.ID)
+	pending := newPendingRequest(request)
+	pending.StatusURL = statusURL
+
+	data, err := json.Marshal(pending)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode approval request: %w", err)
+	}
+	resp, err := s.do(ctx, http.MethodPost, s.config.URL, data)
+	if err != nil {
+		return "", fmt.Errorf("%w: POST %s: %v", ErrWebhook, s.config.URL, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return "", fmt.Errorf("%w: POST %s returned %s%s", ErrWebhook, s.config.URL, resp.Status, responseDetail(resp.Body))
+	}
+
+	// The approval service may tell where to poll for the decision
+	var status webhookStatus
+	if body, err := io.ReadAll(resp.Body); err == nil && json.Unmarshal(body, &status) == nil && status.StatusURL != "" {
+		if err := checkWebhookURL(status.StatusURL); err != nil {
+			return "", fmt.Errorf("%w: invalid statusUrl in the response to POST %s: %v", ErrWebhook, s.config.URL, err)
+		}
+		statusURL = status.StatusURL
+	}
+	return statusURL, nil
+}
+
+// poll fetches the status of the request and returns its response, or nil if no
+// decision was made yet. Errors worth retrying are wrapped in transientError.
+func (s *WebhookService) poll(ctx context.Context, request *models.ApprovalRequest, statusURL string) (*models.ApprovalResponse, error) {
+	resp, err := s.do(ctx, http.MethodGet, statusURL, nil)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, nil
+		}
+		return nil, transientError{fmt.Errorf("%w: GET %s: %v", ErrWebhook, statusURL, err)}
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusAccepted || resp.StatusCode == http.StatusNoContent || resp.StatusCode == http.StatusNotFound:
+		return nil, nil
+	case resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests:
+		return nil, transientError{fmt.Errorf("%w: GET %s returned %s%s", ErrWebhook, statusURL, resp.Status, responseDetail(resp.Body))}
+	case resp.StatusCode != http.StatusOK:
+		return nil, fmt.Errorf("%w: GET %s returned %s%s", ErrWebhook, statusURL, resp.Status, responseDetail(resp.Body))
+	}
+
+	var status webhookStatus
+	if err := json.NewDecoder(resp.Body).Decode(&status); err != nil {
+		return nil, fmt.Errorf("%w: invalid approval response from %s: %v", ErrWebhook, statusURL, err)
+	}
+	switch status.Status {
+	case "", string(models.ApprovalStatusApproved), string(models.ApprovalStatusRejected):
+		if status.Status != "" {
+			status.Approved = status.Status == string(models.ApprovalStatusApproved)
+		}
+	case string(models.ApprovalStatusPending):
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("%w: unknown approval status %q from %s", ErrWebhook, status.Status, statusURL)
+	}
+
+	response := status.ApprovalResponse
+	if response.RequestID == "" {
+		response.RequestID = request.ID
+	} else if response.RequestID != request.ID {
+		return nil, fmt.Errorf("%w: approval response from %s is for request %s, not %s", ErrWebhook, statusURL, response.RequestID, request.ID)
+	}
+	if response.RespondedAt.IsZero() {
+		response.RespondedAt = time.Now()
+	}
+	return &response, nil
+}
+
+// do sends a request to the approval service, with the configured token
+func (s *WebhookService) do(ctx context.Context, method, rawURL string, body []byte) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, rawURL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if s.config.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+s.config.Token)
+	}
+	return s.client.Do(req)
+}
+
+// responseDetail returns the start of an error response body, to explain the failure
+func responseDetail(body io.Reader) string {
+	data, _ := io.ReadAll(io.LimitReader(body, 512))
+	detail := strings.TrimSpace(string(data))
+	if detail == "" {
+		return ""
+	}
+	return ": " + detail
+}
+
+// transientError marks a failure to get the status of a request that is retried
+// until the request expires
+type transientError struct {
+	err error
+}
+
+func (e transientError) Error() string { return e.err.Error() }
+func (e transientError) Unwrap() error { return e.err }
+
+// isTransient reports whether err is worth retrying
+func isTransient(err error) bool {
+	var transient transientError
+	return errors.As(err, &transient)
+}
@@ -0,0 +1,238 @@
+package approval
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cuongtl1992/grp-cli/internal/models"
+)
+
+// approvalServer is a fake approval service answering status polls in turn from
+// its statuses, repeating the last one
+type approvalServer struct {
+	mutex      sync.Mutex
+	postStatus int
+	postBody   string
+	statuses   []fakeStatus
+	polls      int
+	submitted  pendingRequest
+	auth       string
+	pollPaths  []string
+}
+
+// fakeStatus is the response to a status poll
+type fakeStatus struct {
+	code int
+	body string
+}
+
+func (s *approvalServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.auth = r.Header.Get("Authorization")
+
+	if r.Method == http.MethodPost {
+		json.NewDecoder(r.Body).Decode(&s.submitted)
+		if s.postStatus != 0 {
+			w.WriteHeader(s.postStatus)
+		}
+		w.Write([]byte(s.postBody))
+		return
+	}
+
+	s.pollPaths = append(s.pollPaths, r.URL.Path)
+	status := s.statuses[len(s.statuses)-1]
+	if s.polls < len(s.statuses) {
+		status = s.statuses[s.polls]
+	}
+	s.polls++
+	w.WriteHeader(status.code)
+	w.Write([]byte(status.body))
+}
+
+func TestWebhookServiceRequestApproval(t *testing.T) {
+	tests := []struct {
+		name             string
+		server           *approvalServer
+		expectedErr      error
+		expectedMessage  string
+		expectedApproved bool
+		expectedComment  string
+	}{
+		{
+			name: "approved after pending polls",
+			server: &approvalServer{statuses: []fakeStatus{
+				{code: http.StatusNotFound},
+				{code: http.StatusOK, body: `{"status": "pending"}`},
+				{code: http.StatusOK, body: `{"requestId": "req-1", "approved": true, "responderId": "alice", "comment": "ship it"}`},
+			}},
+			expectedApproved: true,
+			expectedComment:  "ship it",
+		},
+		{
+			name: "status field takes precedence",
+			server: &approvalServer{statuses: []fakeStatus{
+				{code: http.StatusAccepted},
+				{code: http.StatusOK, body: `{"status": "rejected", "approved": true, "responderId": "bob"}`},
+			}},
+		},
+		{
+			name: "transient failures are retried",
+			server: &approvalServer{statuses: []fakeStatus{
+				{code: http.StatusBadGateway},
+				{code: http.StatusOK, body: `{"approved": true, "responderId": "alice"}`},
+			}},
+			expectedApproved: true,
+		},
+		{
+			name:            "failed submission",
+			server:          &approvalServer{postStatus: http.StatusUnauthorized, postBody: "bad token", statuses: []fakeStatus{{code: http.StatusOK}}},
+			expectedErr:     ErrWebhook,
+			expectedMessage: "401 Unauthorized: bad token",
+		},
+		{
+			name:            "client error while polling",
+			server:          &approvalServer{statuses: []fakeStatus{{code: http.StatusForbidden}}},
+			expectedErr:     ErrWebhook,
+			expectedMessage: "403 Forbidden",
+		},
+		{
+			name:            "server errors until expiry",
+			server:          &approvalServer{statuses: []fakeStatus{{code: http.StatusServiceUnavailable}}},
+			expectedErr:     ErrExpired,
+			expectedMessage: "503 Service Unavailable",
+		},
+		{
+			name:        "no decision expires",
+			server:      &approvalServer{statuses: []fakeStatus{{code: http.StatusNoContent}}},
+			expectedErr: ErrExpired,
+		},
+		{
+			name:            "response for another request",
+			server:          &approvalServer{statuses: []fakeStatus{{code: http.StatusOK, body: `{"requestId": "req-2", "approved": true}`}}},
+			expectedErr:     ErrWebhook,
+			expectedMessage: "is for request req-2, not req-1",
+		},
+		{
+			name:            "unknown status",
+			server:          &approvalServer{statuses: []fakeStatus{{code: http.StatusOK, body: `{"status": "maybe"}`}}},
+			expectedErr:     ErrWebhook,
+			expectedMessage: `unknown approval status "maybe"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(tt.server)
+			defer server.Close()
+
+			service, err := NewWebhookService(WebhookConfig{
+				URL:          server.URL + "/approvals",
+				Token:        "s3cr3t",
+				PollInterval: 5 * time.Millisecond,
+			})
+			if err != nil {
+				t.Fatalf("NewWebhookService() error = %v", err)
+			}
+			request := &models.ApprovalRequest{
+				ID:          "req-1",
+				ExecutionID: "exec-1",
+				StageName:   "deploy",
+				RequestedAt: time.Now(),
+				ExpiresAt:   time.Now().Add(200 * time.Millisecond),
+			}
+
+			response, err := service.RequestApproval(context.Background(), request)
+			if tt.expectedErr != nil {
+				if !errors.Is(err, tt.expectedErr) || !strings.Contains(err.Error(), tt.expectedMessage) {
+					t.Fatalf("Expected %v containing %q, got %v", tt.expectedErr, tt.expectedMessage, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("RequestApproval() error = %v", err)
+			}
+			if response.Approved != tt.expectedApproved || response.Comment != tt.expectedComment {
+				t.Errorf("Unexpected response %+v", response)
+			}
+			if response.RequestID != "req-1" || response.RespondedAt.IsZero() {
+				t.Errorf("Expected the request ID and response time to be set, got %+v", response)
+			}
+
+			tt.server.mutex.Lock()
+			defer tt.server.mutex.Unlock()
+			if tt.server.auth != "Bearer s3cr3t" {
+				t.Errorf("Expected the bearer token, got %q", tt.server.auth)
+			}
+			if tt.server.submitted.ID != "req-1" || tt.server.submitted.Stage != "deploy" || tt.server.submitted.StatusURL != server.URL+"/approvals/req-1" {
+				t.Errorf("Unexpected submitted request %+v", tt.server.submitted)
+			}
+			if tt.server.pollPaths[0] != "/approvals/req-1" {
+				t.Errorf("Expected polls of /approvals/req-1, got %v", tt.server.pollPaths)
+			}
+		})
+	}
+}
+
+func TestWebhookServiceStatusURL(t *testing.T) {
+	tests := []struct {
+		name        string
+		config      WebhookConfig
+		expected    string
+		expectedErr string
+	}{
+		{name: "default", config: WebhookConfig{URL: "https://approvals.example.com/requests/"}, expected: "https://approvals.example.com/requests/a%2Fb"},
+		{name: "template", config: WebhookConfig{URL: "https://approvals.example.com/requests", StatusURL: "https://approvals.example.com/status?id={id}"}, expected: "https://approvals.example.com/status?id=a%2Fb"},
+		{name: "invalid URL", config: WebhookConfig{URL: "approvals.example.com"}, expectedErr: "invalid approval URL"},
+		{name: "invalid status URL", config: WebhookConfig{URL: "https://approvals.example.com", StatusURL: "ftp://example.com/{id}"}, expectedErr: "invalid approval status URL"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service, err := NewWebhookService(tt.config)
+			if tt.expectedErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.expectedErr) {
+					t.Fatalf("Expected error containing %q, got %v", tt.expectedErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("NewWebhookService() error = %v", err)
+			}
+			if got := service.StatusURL("a/b"); got != tt.expected {
+				t.Errorf("Expected %s, got %s", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestWebhookServiceStatusURLFromResponse(t *testing.T) {
+	statuses := &approvalServer{statuses: []fakeStatus{{code: http.StatusOK, body: `{"approved": true, "responderId": "alice"}`}}}
+	statusServer := httptest.NewServer(statuses)
+	defer statusServer.Close()
+	server := httptest.NewServer(&approvalServer{postStatus: http.StatusCreated, postBody: `{"statusUrl": "` + statusServer.URL + `/tickets/42"}`})
+	defer server.Close()
+
+	service, err := NewWebhookService(WebhookConfig{URL: server.URL, PollInterval: 5 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("NewWebhookService() error = %v", err)
+	}
+	request := &models.ApprovalRequest{ID: "req-1", ExpiresAt: time.Now().Add(time.Second)}
+	response, err := service.RequestApproval(context.Background(), request)
+	if err != nil || !response.Approved {
+		t.Fatalf("Expected an approval, got %+v, %v", response, err)
+	}
+
+	statuses.mutex.Lock()
+	defer statuses.mutex.Unlock()
+	if len(statuses.pollPaths) == 0 || statuses.pollPaths[0] != "/tickets/42" {
+		t.Errorf("Expected polls of the returned status URL, got %v", statuses.pollPaths)
+	}
+}
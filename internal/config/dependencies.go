@@ -0,0 +1,111 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/cuongtl1992/grp-cli/internal/models"
+)
+
+// Fields of a dependsOn entry written as a map, e.g. {job: deploy, on: failure}
+const (
+	dependencyJobField       = "job"
+	dependencyConditionField = "on"
+	dependsOnConditionsField = "dependsOnConditions"
+)
+
+// splitDependencyConditions replaces the dependsOn entries of every job, stage or
+// plan hook and stage or plan rollback job written as {job, on} with the name of
+// the job, and records the condition in the job's dependsOnConditions. Conditions
+// are checked by the validator.
+func splitDependencyConditions(raw map[string]interface{}) error {
+	if err := splitStageDependencyConditions(raw["stages"]); err != nil {
+		return err
+	}
+	for _, hook := range []string{models.PlanHookOnSuccess, models.PlanHookOnFailure} {
+		if err := splitJobListDependencyConditions(hook, raw[hook]); err != nil {
+			return err
+		}
+	}
+	if rollback, ok := raw["rollback"].(map[string]interface{}); ok {
+		return splitStageDependencyConditions(rollback["stages"])
+	}
+	return nil
+}
+
+// splitStageDependencyConditions splits the conditions off the dependencies of
+// the jobs and hooks of a list of stages
+func splitStageDependencyConditions(stages interface{}) error {
+	list, _ := stages.([]interface{})
+	for _, item := range list {
+		stage, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		for _, key := range []string{"preJobs", "jobs", "postJobs", "rollback"} {
+			if err := splitJobListDependencyConditions("stage "+itemName(stage), stage[key]); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// splitJobListDependencyConditions splits the conditions off the dependencies of
+// a list of jobs; owner names the stage or hook of the list in errors
+func splitJobListDependencyConditions(owner string, list interface{}) error {
+	jobs, _ := list.([]interface{})
+	for _, item := range jobs {
+		job, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if err := splitJobDependencyConditions(job); err != nil {
+			return fmt.Errorf("%s: job %s: %w", owner, itemName(job), err)
+		}
+	}
+	return nil
+}
+
+// splitJobDependencyConditions splits the conditions off the dependencies of a job
+func splitJobDependencyConditions(job map[string]interface{}) error {
+	dependsOn, _ := job["dependsOn"].([]interface{})
+	conditions, _ := job[dependsOnConditionsField].(map[string]interface{})
+	for i, entry := range dependsOn {
+		dependency, ok := entry.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		var unknown []string
+		for key := range dependency {
+			if key != dependencyJobField && key != dependencyConditionField {
+				unknown = append(unknown, key)
+			}
+		}
+		if len(unknown) > 0 {
+			sort.Strings(unknown)
+			return fmt.Errorf("dependsOn entry has unknown fields %s; expected job and on", strings.Join(unknown, ", "))
+		}
+		name, ok := dependency[dependencyJobField].(string)
+		if !ok || name == "" {
+			return fmt.Errorf("dependsOn entry must name its job, e.g. {job: deploy, on: failure}")
+		}
+		condition, ok := dependency[dependencyConditionField].(string)
+		if dependency[dependencyConditionField] != nil && !ok {
+			return fmt.Errorf("dependsOn entry for job %s: on must be a string", name)
+		}
+
+		dependsOn[i] = name
+		if condition == "" {
+			continue
+		}
+		if conditions == nil {
+			conditions = make(map[string]interface{})
+			job[dependsOnConditionsField] = conditions
+		}
+		conditions[name] = condition
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+package config
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLoadPlanDependencyConditions(t *testing.T) {
+	tests := []struct {
+		name               string
+		dependsOn          string
+		expectedDependsOn  []string
+		expectedConditions map[string]string
+		errMsg             string
+	}{
+		{
+			name:              "names only",
+			dependsOn:         "[build, deploy]",
+			expectedDependsOn: []string{"build", "deploy"},
+		},
+		{
+			name:               "conditions",
+			dependsOn:          "[build, {job: deploy, on: failure}, {job: smoke}]",
+			expectedDependsOn:  []string{"build", "deploy", "smoke"},
+			expectedConditions: map[string]string{"deploy": "failure"},
+		},
+		{
+			name:      "unknown field",
+			dependsOn: "[{job: deploy, when: failure}]",
+			errMsg:    "stage deploy: job cleanup: dependsOn entry has unknown fields when; expected job and on",
+		},
+		{
+			name:      "missing job",
+			dependsOn: "[{on: failure}]",
+			errMsg:    "dependsOn entry must name its job",
+		},
+		{
+			name:      "condition is not a string",
+			dependsOn: "[{job: deploy, on: [failure]}]",
+			errMsg:    "dependsOn entry for job deploy: on must be a string",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan := "apiVersion: v1\nkind: ReleasePlan\nmetadata:\n  name: test\n" +
+				"stages:\n  - name: deploy\n    jobs:\n" +
+				"      - {name: build, type: shell, config: {command: make}}\n" +
+				"      - {name: deploy, type: shell, config: {command: make deploy}}\n" +
+				"      - {name: smoke, type: shell, config: {command: make smoke}}\n" +
+				"      - {name: cleanup, type: shell, config: {command: make clean}, dependsOn: " + tt.dependsOn + "}\n"
+			dir := writeFiles(t, map[string]string{"plan.yaml": plan})
+
+			loaded, err := NewLoader().LoadPlan(filepath.Join(dir, "plan.yaml"))
+			if tt.errMsg != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+					t.Fatalf("Expected error containing %q, got %v", tt.errMsg, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoadPlan() error = %v", err)
+			}
+
+			job := loaded.Stages[0].Jobs[3]
+			if !reflect.DeepEqual(job.DependsOn, tt.expectedDependsOn) {
+				t.Errorf("Expected dependsOn %v, got %v", tt.expectedDependsOn, job.DependsOn)
+			}
+			if !reflect.DeepEqual(job.DependsOnConditions, tt.expectedConditions) {
+				t.Errorf("Expected conditions %v, got %v", tt.expectedConditions, job.DependsOnConditions)
+			}
+		})
+	}
+}
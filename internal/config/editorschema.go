@@ -0,0 +1,161 @@
+package config
+
+import (
+	"github.com/cuongtl1992/grp-cli/internal/models"
+	"github.com/cuongtl1992/grp-cli/pkg/plugin"
+)
+
+// jsonSchemaDraft is the JSON Schema dialect of the schema returned by PlanJSONSchema
+const jsonSchemaDraft = "http://json-schema.org/draft-07/schema#"
+
+// referencePattern matches strings holding a ${...} reference, which the loader
+// may resolve to a number or a boolean
+const referencePattern = `\$\{`
+
+// schemaEnums lists the values of the plan fields that take one of a fixed set,
+// by field name, so that editors can complete them
+var schemaEnums = map[string][]string{
+	"onStageFailure":    {models.StageFailureAbort, models.StageFailureContinue, models.StageFailureRollbackAndAbort},
+	"onApprovalTimeout": {models.ApprovalTimeoutExpire, models.ApprovalTimeoutReject},
+	"retryStrategy":     {models.RetryStrategyExponential, models.RetryStrategyFixed},
+}
+
+// PlanJSONSchema returns the JSON Schema of the plan format, for editors such as
+// the YAML language server to validate and complete plans as they are written.
+// It is derived from the schema loaded plans are checked against in strict mode,
+// so it follows the models, and also describes what the loader consumes before
+// that check: extends, removeStages, templates, environments, variableSchema and
+// x- extension fields, jobs using a template, {job, on} dependsOn entries, and
+// ${...} references where numbers and booleans are expected.
+func PlanJSONSchema() (map[string]interface{}, error) {
+	planSchema, err := releasePlanSchema(true)
+	if err != nil {
+		return nil, err
+	}
+	schema := editorSchema("", planSchema)
+	schema["$schema"] = jsonSchemaDraft
+	schema["title"] = "grp-cli release plan"
+	schema["patternProperties"] = map[string]interface{}{"^x-": map[string]interface{}{}}
+
+	properties := schema["properties"].(map[string]interface{})
+	properties["kind"] = map[string]interface{}{"type": "string", "enum": KindNames()}
+	properties[extendsField] = map[string]interface{}{"type": "string"}
+	properties[removeStagesField] = map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "string"}}
+	properties[environmentsField] = map[string]interface{}{"type": "object", "additionalProperties": map[string]interface{}{"type": "object"}}
+	properties[variableSchemaField] = map[string]interface{}{"type": "object"}
+
+	// Jobs can use a template and name the outcome of their dependencies
+	var job map[string]interface{}
+	stages := []interface{}{properties["stages"]}
+	if rollback, ok := properties["rollback"].(map[string]interface{}); ok {
+		stages = append(stages, rollback["properties"].(map[string]interface{})["stages"])
+	}
+	for _, list := range stages {
+		stage := list.(map[string]interface{})["items"].(map[string]interface{})
+		for key, value := range stage["properties"].(map[string]interface{}) {
+			if key == "preJobs" || key == "jobs" || key == "postJobs" || key == "rollback" {
+				job = value.(map[string]interface{})["items"].(map[string]interface{})
+				editorJobSchema(job)
+			}
+		}
+	}
+	for _, hook := range []string{models.PlanHookOnSuccess, models.PlanHookOnFailure} {
+		editorJobSchema(properties[hook].(map[string]interface{})["items"].(map[string]interface{}))
+	}
+	properties[templatesField] = map[string]interface{}{"type": "object", "additionalProperties": templateSchema(job)}
+
+	// A plan extending a base plan gets the fields its kind requires from the base
+	schema["required"] = []string{"apiVersion", "kind", "metadata"}
+	var kindRequirements []interface{}
+	for _, name := range KindNames() {
+		kindRequirements = append(kindRequirements, map[string]interface{}{
+			"if": map[string]interface{}{
+				"required":   []string{"kind"},
+				"properties": map[string]interface{}{"kind": map[string]interface{}{"const": name}},
+				"not":        map[string]interface{}{"required": []string{extendsField}},
+			},
+			"then": map[string]interface{}{"required": kinds[name].Required},
+		})
+	}
+	schema["allOf"] = kindRequirements
+	return schema, nil
+}
+
+// editorSchema converts the schema of the plan field name into a JSON Schema
+// document, accepting ${...} references for numbers and booleans
+func editorSchema(name string, schema *plugin.JSONSchema) map[string]interface{} {
+	switch schema.Type {
+	case "integer", "number", "boolean":
+		return map[string]interface{}{"anyOf": []interface{}{
+			map[string]interface{}{"type": schema.Type},
+			map[string]interface{}{"type": "string", "pattern": referencePattern},
+		}}
+	}
+
+	converted := map[string]interface{}{"type": schema.Type}
+	if values, ok := schemaEnums[name]; ok {
+		converted["enum"] = values
+	}
+	if len(schema.Required) > 0 {
+		converted["required"] = schema.Required
+	}
+	if len(schema.Properties) > 0 {
+		properties := make(map[string]interface{}, len(schema.Properties))
+		for property, propertySchema := range schema.Properties {
+			properties[property] = editorSchema(property, propertySchema)
+		}
+		converted["properties"] = properties
+	}
+	if schema.AdditionalProperties != nil {
+		converted["additionalProperties"] = *schema.AdditionalProperties
+	}
+	if schema.Items != nil {
+		converted["items"] = editorSchema("", schema.Items)
+	}
+	return converted
+}
+
+// editorJobSchema lets a job schema use a template, in which case the template
+// may provide its type, and write a dependency as {job, on}. dependsOnConditions,
+// which the loader fills from such entries, is left out.
+func editorJobSchema(job map[string]interface{}) {
+	properties := job["properties"].(map[string]interface{})
+	properties[usesField] = map[string]interface{}{"type": "string"}
+	properties[withField] = map[string]interface{}{"type": "object"}
+	delete(properties, dependsOnConditionsField)
+
+	conditions := []string{models.DependencyOnSuccess, models.DependencyOnFailure, models.DependencyOnAlways}
+	properties["dependsOn"] = map[string]interface{}{
+		"type": "array",
+		"items": map[string]interface{}{"anyOf": []interface{}{
+			map[string]interface{}{"type": "string"},
+			map[string]interface{}{
+				"type":     "object",
+				"required": []string{dependencyJobField},
+				"properties": map[string]interface{}{
+					dependencyJobField:       map[string]interface{}{"type": "string"},
+					dependencyConditionField: map[string]interface{}{"type": "string", "enum": conditions},
+				},
+				"additionalProperties": false,
+			},
+		}},
+	}
+
+	job["required"] = []string{"name"}
+	job["anyOf"] = []interface{}{
+		map[string]interface{}{"required": []string{"type"}},
+		map[string]interface{}{"required": []string{usesField}},
+	}
+}
+
+// templateSchema returns the schema of a job template: the fields of a job other
+// than its name and template
+func templateSchema(job map[string]interface{}) map[string]interface{} {
+	properties := make(map[string]interface{})
+	for key, value := range job["properties"].(map[string]interface{}) {
+		if key != "name" && key != usesField && key != withField {
+			properties[key] = value
+		}
+	}
+	return map[string]interface{}{"type": "object", "properties": properties, "additionalProperties": false}
+}
@@ -0,0 +1,116 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/cuongtl1992/grp-cli/pkg/plugin"
+)
+
+// schemaAt returns the value at path in a schema, with array indexes as numbers
+func schemaAt(t *testing.T, schema map[string]interface{}, path string) interface{} {
+	t.Helper()
+	var value interface{} = schema
+	for _, key := range strings.Split(path, "/") {
+		switch v := value.(type) {
+		case map[string]interface{}:
+			value = v[key]
+		case []interface{}:
+			var i int
+			fmt.Sscan(key, &i)
+			value = v[i]
+		default:
+			t.Fatalf("No %s in the schema at %s", key, path)
+		}
+	}
+	return value
+}
+
+func TestPlanJSONSchema(t *testing.T) {
+	schema, err := PlanJSONSchema()
+	if err != nil {
+		t.Fatalf("PlanJSONSchema() error = %v", err)
+	}
+	// Round trip through JSON, as editors read it
+	data, err := json.Marshal(schema)
+	if err != nil {
+		t.Fatalf("Failed to encode the schema: %v", err)
+	}
+	schema = nil
+	if err := json.Unmarshal(data, &schema); err != nil {
+		t.Fatalf("Failed to decode the schema: %v", err)
+	}
+
+	job := "properties/stages/items/properties/jobs/items"
+	tests := []struct {
+		path     string
+		expected string
+	}{
+		{path: "$schema", expected: `"http://json-schema.org/draft-07/schema#"`},
+		{path: "properties/kind/enum", expected: `["Library","ReleasePlan","RollbackPlan"]`},
+		{path: "required", expected: `["apiVersion","kind","metadata"]`},
+		{path: "allOf/1/then/required", expected: `["apiVersion","kind","metadata","stages"]`},
+		{path: "properties/extends/type", expected: `"string"`},
+		{path: "properties/templates/additionalProperties/properties/name", expected: "null"},
+		{path: "properties/templates/additionalProperties/properties/retries/anyOf/0/type", expected: `"integer"`},
+		{path: "properties/onStageFailure/enum", expected: `["abort","continue","rollback-and-abort"]`},
+		{path: "properties/metadata/additionalProperties", expected: "false"},
+		{path: "properties/stages/items/properties/maxParallel/anyOf/1/pattern", expected: `"\\$\\{"`},
+		{path: job + "/required", expected: `["name"]`},
+		{path: job + "/anyOf/1/required", expected: `["uses"]`},
+		{path: job + "/properties/with/type", expected: `"object"`},
+		{path: job + "/properties/dependsOnConditions", expected: "null"},
+		{path: job + "/properties/dependsOn/items/anyOf/0/type", expected: `"string"`},
+		{path: job + "/properties/dependsOn/items/anyOf/1/properties/on/enum", expected: `["success","failure","always"]`},
+		{path: job + "/properties/retryStrategy/enum", expected: `["exponential","fixed"]`},
+		{path: job + "/properties/config/additionalProperties", expected: "null"},
+		{path: "properties/stages/items/properties/rollback/items/properties/uses/type", expected: `"string"`},
+		{path: "properties/rollback/properties/stages/items/properties/jobs/items/properties/uses/type", expected: `"string"`},
+		{path: "properties/onSuccess/items/properties/uses/type", expected: `"string"`},
+		{path: "properties/onFailure/items/anyOf/0/required", expected: `["type"]`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			got, _ := json.Marshal(schemaAt(t, schema, tt.path))
+			if string(got) != tt.expected {
+				t.Errorf("Expected %s at %s, got %s", tt.expected, tt.path, got)
+			}
+		})
+	}
+}
+
+// TestPlanJSONSchemaCoversValidation checks that the editor schema describes every
+// field plans are validated with, so that it stays in sync with the models
+func TestPlanJSONSchemaCoversValidation(t *testing.T) {
+	editor, err := PlanJSONSchema()
+	if err != nil {
+		t.Fatalf("PlanJSONSchema() error = %v", err)
+	}
+	validation, err := releasePlanSchema(true)
+	if err != nil {
+		t.Fatalf("releasePlanSchema() error = %v", err)
+	}
+
+	var check func(path string, validation *plugin.JSONSchema, editor map[string]interface{})
+	check = func(path string, validation *plugin.JSONSchema, editor map[string]interface{}) {
+		properties, _ := editor["properties"].(map[string]interface{})
+		for name, property := range validation.Properties {
+			if name == dependsOnConditionsField {
+				continue
+			}
+			editorProperty, ok := properties[name].(map[string]interface{})
+			if !ok {
+				t.Errorf("Expected the editor schema to describe %s%s", path, name)
+				continue
+			}
+			check(path+name+".", property, editorProperty)
+		}
+		if items, ok := editor["items"].(map[string]interface{}); ok && validation.Items != nil {
+			check(path, validation.Items, items)
+		}
+	}
+	check("", validation, editor)
+}
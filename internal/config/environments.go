@@ -0,0 +1,55 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// environmentsField is the top-level plan field defining the environments a plan
+// can be run in; it is removed once the selected environment is applied
+const environmentsField = "environments"
+
+// applyEnvironment removes the environments block from raw and merges the named
+// environment over the rest of the plan like a plan over its base: variables are
+// deep-merged, stages are merged by name and other settings are replaced. An
+// empty name leaves the plan as written. The names of the defined environments
+// are returned, sorted.
+func applyEnvironment(raw map[string]interface{}, name string) (map[string]interface{}, []string, error) {
+	value, exists := raw[environmentsField]
+	delete(raw, environmentsField)
+
+	environments, ok := value.(map[string]interface{})
+	if exists && !ok {
+		return nil, nil, fmt.Errorf("environments must be a map of environment names to plan settings")
+	}
+	names := make([]string, 0, len(environments))
+	for environment := range environments {
+		names = append(names, environment)
+	}
+	sort.Strings(names)
+
+	if name == "" {
+		return raw, names, nil
+	}
+	if len(names) == 0 {
+		return nil, names, fmt.Errorf("unknown environment %s: the plan defines no environments", name)
+	}
+	settings, exists := environments[name]
+	if !exists {
+		return nil, names, fmt.Errorf("unknown environment %s (available: %s)", name, strings.Join(names, ", "))
+	}
+	if settings == nil {
+		return raw, names, nil
+	}
+	overrides, ok := settings.(map[string]interface{})
+	if !ok {
+		return nil, names, fmt.Errorf("environment %s must be a map of plan settings", name)
+	}
+	for _, field := range []string{"apiVersion", "kind", environmentsField, extendsField, removeStagesField} {
+		if _, exists := overrides[field]; exists {
+			return nil, names, fmt.Errorf("environment %s cannot set %s", name, field)
+		}
+	}
+	return mergePlans(raw, overrides), names, nil
+}
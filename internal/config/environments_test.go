@@ -0,0 +1,154 @@
+package config
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// environmentsPlan defines dev and prod environments over a two-stage plan
+const environmentsPlan = `
+apiVersion: v1
+kind: ReleasePlan
+metadata:
+  name: web
+variables:
+  replicas: 1
+  image:
+    name: web
+    tag: latest
+stages:
+  - name: build
+    jobs:
+      - name: image
+        type: shell
+        config:
+          command: build ${variables.image.name}:${variables.image.tag}
+  - name: deploy
+    dependsOn: [build]
+    jobs:
+      - name: apply
+        type: shell
+        config:
+          command: apply ${variables.replicas}
+environments:
+  dev:
+  staging:
+    variables:
+      image:
+        tag: rc
+  prod:
+    maxParallel: 2
+    variables:
+      replicas: 5
+      image:
+        tag: stable
+    stages:
+      - name: deploy
+        requireApproval: true
+        approvers: [ops]
+`
+
+func TestLoadPlanEnvironments(t *testing.T) {
+	tests := []struct {
+		name        string
+		plan        string
+		environment string
+		values      map[string]interface{}
+		stages      string
+		approvers   []string
+		maxParallel int
+		errMsg      string
+	}{
+		{
+			name:   "no environment selected",
+			plan:   environmentsPlan,
+			stages: "build[image(build web:latest)] deploy[apply(apply 1)]",
+		},
+		{
+			name:        "empty environment",
+			plan:        environmentsPlan,
+			environment: "dev",
+			stages:      "build[image(build web:latest)] deploy[apply(apply 1)]",
+		},
+		{
+			name:        "variable overrides",
+			plan:        environmentsPlan,
+			environment: "staging",
+			stages:      "build[image(build web:rc)] deploy[apply(apply 1)]",
+		},
+		{
+			name:        "variables, settings and approvals",
+			plan:        environmentsPlan,
+			environment: "prod",
+			stages:      "build[image(build web:stable)] deploy[apply(apply 5)]",
+			approvers:   []string{"ops"},
+			maxParallel: 2,
+		},
+		{
+			name:        "values override the environment",
+			plan:        environmentsPlan,
+			environment: "prod",
+			values:      map[string]interface{}{"replicas": 3},
+			stages:      "build[image(build web:stable)] deploy[apply(apply 3)]",
+			approvers:   []string{"ops"},
+			maxParallel: 2,
+		},
+		{
+			name:        "unknown environment",
+			plan:        environmentsPlan,
+			environment: "qa",
+			errMsg:      "unknown environment qa (available: dev, prod, staging)",
+		},
+		{
+			name:        "plan without environments",
+			plan:        "apiVersion: v1\nkind: ReleasePlan\nmetadata:\n  name: web\nstages:\n  - name: build\n    jobs:\n      - name: image\n        type: shell\n        config: {command: build}\n",
+			environment: "prod",
+			errMsg:      "unknown environment prod: the plan defines no environments",
+		},
+		{
+			name:        "environment cannot change the kind",
+			plan:        environmentsPlan + "  broken:\n    kind: RollbackPlan\n",
+			environment: "broken",
+			errMsg:      "environment broken cannot set kind",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := writeFiles(t, map[string]string{"plan.yaml": tt.plan})
+			loader := NewLoader()
+			loader.SetStrict(true)
+			loader.SetEnvironment(tt.environment)
+			if tt.values != nil {
+				loader.AddValues(tt.values)
+			}
+
+			plan, err := loader.LoadPlan(filepath.Join(dir, "plan.yaml"))
+			if tt.errMsg != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+					t.Fatalf("Expected error containing %q, got %v", tt.errMsg, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Failed to load plan: %v", err)
+			}
+
+			if got := summarizeStages(plan.Stages); got != tt.stages {
+				t.Errorf("Expected stages %s, got %s", tt.stages, got)
+			}
+			deploy := plan.Stages[1]
+			if deploy.RequireApproval != (tt.approvers != nil) || !reflect.DeepEqual(deploy.Approvers, tt.approvers) {
+				t.Errorf("Expected deploy approvers %v, got requireApproval=%v approvers=%v", tt.approvers, deploy.RequireApproval, deploy.Approvers)
+			}
+			if plan.MaxParallel != tt.maxParallel {
+				t.Errorf("Expected maxParallel %d, got %d", tt.maxParallel, plan.MaxParallel)
+			}
+			if environments := loader.Environments(); strings.Join(environments, ",") != "dev,prod,staging" {
+				t.Errorf("Expected environments dev, prod and staging, got %v", environments)
+			}
+		})
+	}
+}
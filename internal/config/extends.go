@@ -0,0 +1,243 @@
+package config
+
+import (
+	"fmt"
+
+	"github.com/cuongtl1992/grp-cli/internal/models"
+)
+
+// Plan fields that make a plan inherit from a base plan; they are removed once the
+// base is merged in
+const (
+	extendsField      = "extends"
+	removeStagesField = "removeStages"
+)
+
+// isExtendsField reports whether a top-level plan field configures inheritance
+func isExtendsField(key string) bool {
+	return key == extendsField || key == removeStagesField
+}
+
+// extendPlan merges the base plan raw extends, itself extending its own base if
+// any, under raw, which was loaded from location. Includes of the base are merged
+// into its variables first, so the plan's variables override them. chain holds the
+// absolute paths or URLs of the plans being loaded, outermost first, and is used
+// to reject extends cycles.
+func (l *Loader) extendPlan(raw map[string]interface{}, location string, chain []string) (map[string]interface{}, error) {
+	extends, exists := raw[extendsField]
+	removed, err := stageNames(raw[removeStagesField])
+	if err != nil {
+		return nil, err
+	}
+	delete(raw, extendsField)
+	delete(raw, removeStagesField)
+	if !exists {
+		if len(removed) > 0 {
+			return nil, fmt.Errorf("removeStages requires extends")
+		}
+		return raw, nil
+	}
+
+	path, ok := extends.(string)
+	if !ok || path == "" {
+		return nil, fmt.Errorf("extends must be the path or URL of a base plan")
+	}
+	basePath, err := includeLocation(location, path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve base plan %s: %w", path, err)
+	}
+
+	// Reject plans that extend themselves, directly or transitively
+	for i, loading := range chain {
+		if loading == basePath {
+			return nil, fmt.Errorf("extends cycle detected: %s", formatIncludeChain(chain[0], append(chain[i:len(chain):len(chain)], basePath)))
+		}
+	}
+	chain = append(chain[:len(chain):len(chain)], basePath)
+
+	base, err := l.loadBasePlan(basePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load base plan %s: %w", path, err)
+	}
+	if err := l.inlineFiles(base, basePath); err != nil {
+		return nil, fmt.Errorf("failed to load base plan %s: %w", path, err)
+	}
+	if base, err = l.extendPlan(base, basePath, chain); err != nil {
+		return nil, err
+	}
+
+	variables, ok := base["variables"].(map[string]interface{})
+	if !ok {
+		if _, exists := base["variables"]; exists {
+			return nil, fmt.Errorf("failed to merge variables of base plan %s: variables must be a map", path)
+		}
+		variables = make(map[string]interface{})
+	}
+	if err := l.processIncludes(base, basePath, variables, chain); err != nil {
+		return nil, err
+	}
+	base["variables"] = variables
+	delete(base, "includes")
+
+	merged := mergePlans(base, raw)
+	if err := removeStages(merged, removed); err != nil {
+		return nil, err
+	}
+	return merged, nil
+}
+
+// loadBasePlan reads a base plan from a file or URL, converting it to the current
+// apiVersion. A base plan is a single YAML document.
+func (l *Loader) loadBasePlan(location string) (map[string]interface{}, error) {
+	data, err := l.readPlan(location)
+	if err != nil {
+		return nil, err
+	}
+	if data, _, err = MigratePlan(data); err != nil {
+		return nil, err
+	}
+	documents, err := parseDocuments(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse YAML: %w", err)
+	}
+	if len(documents) > 1 {
+		return nil, fmt.Errorf("a base plan must be a single YAML document")
+	}
+
+	base := documents[0]
+	if base == nil {
+		base = make(map[string]interface{})
+	}
+	for key := range base {
+		if isExtensionField(key) {
+			delete(base, key)
+		}
+	}
+	return base, nil
+}
+
+// mergePlans deep-merges plan over base: nested maps are merged key by key and
+// plan values win. Stages, including rollback stages, the jobs and hooks of
+// stages and the plan's hooks are merged by name; items with a new name are
+// appended. Other lists are replaced.
+func mergePlans(base, plan map[string]interface{}) map[string]interface{} {
+	merged := mergeMaps(base, plan)
+	mergeNamedList(merged, base, plan, "stages", mergeStages)
+	for _, hook := range []string{models.PlanHookOnSuccess, models.PlanHookOnFailure} {
+		mergeNamedList(merged, base, plan, hook, mergeMaps)
+	}
+
+	baseRollback, baseOK := base["rollback"].(map[string]interface{})
+	rollback, ok := plan["rollback"].(map[string]interface{})
+	if baseOK && ok {
+		mergedRollback := merged["rollback"].(map[string]interface{})
+		mergeNamedList(mergedRollback, baseRollback, rollback, "stages", mergeStages)
+	}
+	return merged
+}
+
+// mergeStages deep-merges a stage over the base stage of the same name, merging
+// their pre hooks, jobs, post hooks and rollback jobs by name
+func mergeStages(base, stage map[string]interface{}) map[string]interface{} {
+	merged := mergeMaps(base, stage)
+	for _, key := range []string{"preJobs", "jobs", "postJobs", "rollback"} {
+		mergeNamedList(merged, base, stage, key, mergeMaps)
+	}
+	return merged
+}
+
+// mergeMaps returns a copy of base with values deep-merged over it
+func mergeMaps(base, values map[string]interface{}) map[string]interface{} {
+	merged := make(map[string]interface{}, len(base))
+	for key, value := range base {
+		merged[key] = value
+	}
+	mergeValues(merged, values)
+	return merged
+}
+
+// mergeNamedList sets merged[key] to the items of base[key] with those of
+// values[key] merged in by name, when both are lists
+func mergeNamedList(merged, base, values map[string]interface{}, key string, merge func(base, item map[string]interface{}) map[string]interface{}) {
+	baseItems, baseOK := base[key].([]interface{})
+	items, ok := values[key].([]interface{})
+	if !baseOK || !ok {
+		return
+	}
+
+	result := append([]interface{}(nil), baseItems...)
+	index := make(map[string]int, len(baseItems))
+	for i, item := range baseItems {
+		if name := itemName(item); name != "" {
+			index[name] = i
+		}
+	}
+	for _, item := range items {
+		i, exists := index[itemName(item)]
+		if !exists {
+			result = append(result, item)
+			continue
+		}
+		baseItem, _ := result[i].(map[string]interface{})
+		result[i] = merge(baseItem, item.(map[string]interface{}))
+	}
+	merged[key] = result
+}
+
+// itemName returns the name of a stage or job, or "" if it has none
+func itemName(item interface{}) string {
+	fields, ok := item.(map[string]interface{})
+	if !ok {
+		return ""
+	}
+	name, _ := fields["name"].(string)
+	return name
+}
+
+// removeStages removes the named inherited stages from a merged plan
+func removeStages(plan map[string]interface{}, names []string) error {
+	if len(names) == 0 {
+		return nil
+	}
+	stages, _ := plan["stages"].([]interface{})
+	remove := make(map[string]bool, len(names))
+	for _, name := range names {
+		remove[name] = true
+	}
+
+	var kept []interface{}
+	for _, stage := range stages {
+		if name := itemName(stage); remove[name] {
+			delete(remove, name)
+			continue
+		}
+		kept = append(kept, stage)
+	}
+	for _, name := range names {
+		if remove[name] {
+			return fmt.Errorf("removeStages: stage %s is not defined by the base plan", name)
+		}
+	}
+	plan["stages"] = kept
+	return nil
+}
+
+// stageNames parses the removeStages field: a list of stage names
+func stageNames(value interface{}) ([]string, error) {
+	if value == nil {
+		return nil, nil
+	}
+	items, ok := value.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("removeStages must be a list of stage names")
+	}
+	names := make([]string, len(items))
+	for i, item := range items {
+		name, ok := item.(string)
+		if !ok || name == "" {
+			return nil, fmt.Errorf("removeStages must be a list of stage names")
+		}
+		names[i] = name
+	}
+	return names, nil
+}
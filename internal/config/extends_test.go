@@ -0,0 +1,238 @@
+package config
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/cuongtl1992/grp-cli/internal/models"
+)
+
+// basePlan is the base of the plans of TestLoadPlanExtends
+const basePlan = `
+apiVersion: v1
+kind: ReleasePlan
+metadata:
+  name: base-pipeline
+  owner: platform
+includes:
+  - path: shared/registry.yaml
+variables:
+  replicas: 2
+  image:
+    name: web
+    tag: latest
+stages:
+  - name: build
+    jobs:
+      - name: image
+        type: shell
+        config:
+          command: build ${variables.registry}/${variables.image.name}:${variables.image.tag}
+  - name: deploy
+    dependsOn: [build]
+    jobs:
+      - name: apply
+        type: shell
+        config:
+          command: apply ${variables.replicas}
+  - name: smoke-test
+    dependsOn: [deploy]
+    jobs:
+      - name: curl
+        type: shell
+        config:
+          command: curl
+rollback:
+  stages:
+    - name: undo
+      jobs:
+        - name: revert
+          type: shell
+          config:
+            command: revert
+onFailure:
+  - name: page
+    type: shell
+    config:
+      command: page
+`
+
+// summarizeStages renders the stages of a plan and the commands of their jobs
+func summarizeStages(stages []models.Stage) string {
+	var parts []string
+	for _, stage := range stages {
+		var jobs []string
+		for _, job := range stage.Jobs {
+			jobs = append(jobs, fmt.Sprintf("%s(%v)", job.Name, job.Config["command"]))
+		}
+		parts = append(parts, fmt.Sprintf("%s[%s]", stage.Name, strings.Join(jobs, ", ")))
+	}
+	return strings.Join(parts, " ")
+}
+
+func TestLoadPlanExtends(t *testing.T) {
+	tests := []struct {
+		name      string
+		plan      string
+		files     map[string]string
+		stages    string
+		rollback  string
+		onFailure string
+		errMsg    string
+	}{
+		{
+			name:      "inherits the base plan",
+			plan:      "extends: base.yaml\nmetadata:\n  name: web-release\n",
+			stages:    "build[image(build registry.example.com/web:latest)] deploy[apply(apply 2)] smoke-test[curl(curl)]",
+			rollback:  "undo[revert(revert)]",
+			onFailure: "onFailure[page(page)]",
+		},
+		{
+			name: "overrides variables and appends stages",
+			plan: `extends: base.yaml
+metadata:
+  name: web-release
+variables:
+  registry: ghcr.io/acme
+  image:
+    tag: v2
+stages:
+  - name: notify
+    dependsOn: [smoke-test]
+    jobs:
+      - name: slack
+        type: shell
+        config:
+          command: notify
+`,
+			stages:   "build[image(build ghcr.io/acme/web:v2)] deploy[apply(apply 2)] smoke-test[curl(curl)] notify[slack(notify)]",
+			rollback: "undo[revert(revert)]",
+		},
+		{
+			name: "merges stages and jobs by name",
+			plan: `extends: base.yaml
+metadata:
+  name: web-release
+stages:
+  - name: deploy
+    jobs:
+      - name: apply
+        config:
+          command: apply 5
+      - name: migrate
+        type: shell
+        config:
+          command: migrate
+rollback:
+  stages:
+    - name: undo
+      jobs:
+        - name: page
+          type: shell
+          config:
+            command: page
+onFailure:
+  - name: page
+    config:
+      command: page on-call
+  - name: notify
+    type: shell
+    config:
+      command: notify
+`,
+			stages:    "build[image(build registry.example.com/web:latest)] deploy[apply(apply 5), migrate(migrate)] smoke-test[curl(curl)]",
+			rollback:  "undo[revert(revert), page(page)]",
+			onFailure: "onFailure[page(page on-call), notify(notify)]",
+		},
+		{
+			name:     "removes inherited stages",
+			plan:     "extends: base.yaml\nmetadata:\n  name: web-release\nremoveStages: [smoke-test]\n",
+			stages:   "build[image(build registry.example.com/web:latest)] deploy[apply(apply 2)]",
+			rollback: "undo[revert(revert)]",
+		},
+		{
+			name: "extends a plan that extends another",
+			plan: "extends: lib/service.yaml\nmetadata:\n  name: web-release\n",
+			files: map[string]string{
+				"lib/service.yaml": "extends: ../base.yaml\nvariables:\n  replicas: 3\nremoveStages: [smoke-test]\n",
+			},
+			stages:   "build[image(build registry.example.com/web:latest)] deploy[apply(apply 3)]",
+			rollback: "undo[revert(revert)]",
+		},
+		{
+			name:   "removed stage is a dependency",
+			plan:   "extends: base.yaml\nmetadata:\n  name: web-release\nremoveStages: [build]\n",
+			errMsg: "depends on unknown stage: build",
+		},
+		{
+			name:   "unknown removed stage",
+			plan:   "extends: base.yaml\nmetadata:\n  name: web-release\nremoveStages: [lint]\n",
+			errMsg: "removeStages: stage lint is not defined by the base plan",
+		},
+		{
+			name:   "removeStages without extends",
+			plan:   basePlan + "removeStages: [smoke-test]\n",
+			errMsg: "removeStages requires extends",
+		},
+		{
+			name:   "missing base plan",
+			plan:   "extends: missing.yaml\nmetadata:\n  name: web-release\n",
+			errMsg: "failed to load base plan missing.yaml",
+		},
+		{
+			name: "extends cycle",
+			plan: "extends: lib/service.yaml\nmetadata:\n  name: web-release\n",
+			files: map[string]string{
+				"lib/service.yaml": "extends: ../plan.yaml\n",
+			},
+			errMsg: "extends cycle detected: plan.yaml -> lib/service.yaml -> plan.yaml",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			files := map[string]string{
+				"plan.yaml":            tt.plan,
+				"base.yaml":            basePlan,
+				"shared/registry.yaml": "variables:\n  registry: registry.example.com\n",
+			}
+			for name, content := range tt.files {
+				files[name] = content
+			}
+			dir := writeFiles(t, files)
+
+			loaded, err := NewLoader().LoadPlan(filepath.Join(dir, "plan.yaml"))
+			if err == nil && tt.errMsg != "" {
+				err = NewValidator().ValidatePlan(loaded)
+			}
+			if tt.errMsg != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+					t.Fatalf("Expected error containing %q, got %v", tt.errMsg, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoadPlan() error = %v", err)
+			}
+			if err := NewValidator().ValidatePlan(loaded); err != nil {
+				t.Fatalf("ValidatePlan() error = %v", err)
+			}
+
+			if loaded.Metadata.Name != "web-release" || loaded.Metadata.Owner != "platform" {
+				t.Errorf("Expected metadata merged over the base's, got %+v", loaded.Metadata)
+			}
+			if stages := summarizeStages(loaded.Stages); stages != tt.stages {
+				t.Errorf("Expected stages %s, got %s", tt.stages, stages)
+			}
+			if rollback := summarizeStages(loaded.Rollback.Stages); rollback != tt.rollback {
+				t.Errorf("Expected rollback stages %s, got %s", tt.rollback, rollback)
+			}
+			hook := []models.Stage{{Name: models.PlanHookOnFailure, Jobs: loaded.OnFailure}}
+			if onFailure := summarizeStages(hook); tt.onFailure != "" && onFailure != tt.onFailure {
+				t.Errorf("Expected onFailure jobs %s, got %s", tt.onFailure, onFailure)
+			}
+		})
+	}
+}
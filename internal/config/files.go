@@ -0,0 +1,98 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// fileField makes a plan value a reference to a file whose content replaces it,
+// as in manifest: {$file: ./deploy.yaml}
+const fileField = "$file"
+
+// inlineFiles replaces the {$file: path} values of raw, which was loaded from
+// location, with the content of the referenced files. Paths are relative to
+// location like includes: its directory for a local plan, its URL for a remote one.
+func (l *Loader) inlineFiles(raw map[string]interface{}, location string) error {
+	return l.inlineMap(raw, location, "")
+}
+
+// inlineMap replaces the file references in the values of raw; path locates raw
+// in the plan for error messages
+func (l *Loader) inlineMap(raw map[string]interface{}, location, path string) error {
+	// Visit the keys in a stable order so the first error is reported consistently
+	keys := make([]string, 0, len(raw))
+	for key := range raw {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		keyPath := key
+		if path != "" {
+			keyPath = path + "." + key
+		}
+		inlined, err := l.inlineValue(raw[key], location, keyPath)
+		if err != nil {
+			return err
+		}
+		raw[key] = inlined
+	}
+	return nil
+}
+
+// inlineValue returns value with its file references replaced by the content of
+// the files
+func (l *Loader) inlineValue(value interface{}, location, path string) (interface{}, error) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		if reference, exists := v[fileField]; exists {
+			return l.readReferencedFile(v, reference, location, path)
+		}
+		if err := l.inlineMap(v, location, path); err != nil {
+			return nil, err
+		}
+	case []interface{}:
+		for i, item := range v {
+			inlined, err := l.inlineValue(item, location, fmt.Sprintf("%s[%d]", path, i))
+			if err != nil {
+				return nil, err
+			}
+			v[i] = inlined
+		}
+	}
+	return value, nil
+}
+
+// readReferencedFile returns the content of the file referenced by the $file
+// field of value
+func (l *Loader) readReferencedFile(value map[string]interface{}, reference interface{}, location, path string) (string, error) {
+	if len(value) > 1 {
+		return "", fmt.Errorf("%s: %s cannot be combined with other fields", path, fileField)
+	}
+	file, ok := reference.(string)
+	if !ok || strings.TrimSpace(file) == "" {
+		return "", fmt.Errorf("%s: %s must be the path of a file", path, fileField)
+	}
+
+	resolved, err := includeLocation(location, file)
+	if err != nil {
+		return "", fmt.Errorf("%s: failed to resolve file %s: %w", path, file, err)
+	}
+	if isRemote(resolved) {
+		data, err := l.fetch(resolved)
+		if err != nil {
+			return "", fmt.Errorf("%s: %w", path, err)
+		}
+		return string(data), nil
+	}
+	data, err := os.ReadFile(resolved)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", fmt.Errorf("%s: file %s does not exist (resolved to %s)", path, file, resolved)
+		}
+		return "", fmt.Errorf("%s: failed to read file %s: %w", path, file, err)
+	}
+	return string(data), nil
+}
@@ -0,0 +1,95 @@
+package config
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadPlanFileReferences(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"plans/plan.yaml": `
+apiVersion: v1
+kind: ReleasePlan
+extends: ../base/base.yaml
+metadata:
+  name: web
+variables:
+  namespace: production
+stages:
+  - name: deploy
+    jobs:
+      - name: apply
+        type: kubernetes
+        config:
+          action: apply
+          manifest: {$file: ../manifests/deploy.yaml}
+`,
+		"manifests/deploy.yaml": "kind: Deployment\nnamespace: ${variables.namespace}\n",
+		"base/base.yaml": `
+apiVersion: v1
+kind: ReleasePlan
+stages:
+  - name: build
+    jobs:
+      - name: script
+        type: shell
+        config:
+          commands:
+            - {$file: scripts/build.sh}
+`,
+		"base/scripts/build.sh": "make build\n",
+	})
+
+	plan, err := NewLoader().LoadPlan(filepath.Join(dir, "plans", "plan.yaml"))
+	if err != nil {
+		t.Fatalf("Failed to load plan: %v", err)
+	}
+
+	build := plan.Stages[0].Jobs[0]
+	if commands, _ := build.Config["commands"].([]interface{}); len(commands) != 1 || commands[0] != "make build\n" {
+		t.Errorf("Expected the base plan's file to be read relative to the base plan, got %v", build.Config["commands"])
+	}
+	deploy := plan.Stages[1].Jobs[0]
+	if manifest := deploy.Config["manifest"]; manifest != "kind: Deployment\nnamespace: production\n" {
+		t.Errorf("Expected the manifest to be inlined with its references resolved, got %q", manifest)
+	}
+}
+
+func TestLoadPlanFileReferenceErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		value  string
+		errMsg string
+	}{
+		{
+			name:   "missing file",
+			value:  "{$file: ./missing.yaml}",
+			errMsg: "stages[0].jobs[0].config.manifest: file ./missing.yaml does not exist (resolved to ",
+		},
+		{
+			name:   "not a path",
+			value:  "{$file: [a]}",
+			errMsg: "stages[0].jobs[0].config.manifest: $file must be the path of a file",
+		},
+		{
+			name:   "other fields",
+			value:  "{$file: ./deploy.yaml, format: yaml}",
+			errMsg: "stages[0].jobs[0].config.manifest: $file cannot be combined with other fields",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan := "apiVersion: v1\nkind: ReleasePlan\nmetadata:\n  name: test\n" +
+				"stages:\n  - name: deploy\n    jobs:\n      - name: apply\n        type: kubernetes\n" +
+				"        config:\n          action: apply\n          manifest: " + tt.value + "\n"
+			dir := writeFiles(t, map[string]string{"plan.yaml": plan, "deploy.yaml": "kind: Deployment\n"})
+
+			_, err := NewLoader().LoadPlan(filepath.Join(dir, "plan.yaml"))
+			if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+				t.Errorf("Expected error containing %q, got %v", tt.errMsg, err)
+			}
+		})
+	}
+}
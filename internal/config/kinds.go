@@ -0,0 +1,80 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/cuongtl1992/grp-cli/internal/models"
+)
+
+// Plan kinds supported by grp-cli
+const (
+	// KindReleasePlan is a release: stages to run, and optionally how to roll them back
+	KindReleasePlan = "ReleasePlan"
+	// KindRollbackPlan only has rollback stages, run with the rollback command
+	KindRollbackPlan = "RollbackPlan"
+	// KindLibrary only shares variables with the plans that include it
+	KindLibrary = "Library"
+)
+
+// Kind describes how plans of a kind are loaded, validated and used
+type Kind struct {
+	Name string
+	// Required lists the top-level fields a plan of the kind must have
+	Required []string
+	// Runnable is set if plans of the kind can be executed with run
+	Runnable bool
+	// Rollbackable is set if the rollback stages of the plans can be executed with rollback
+	Rollbackable bool
+	// Validate checks a plan of the kind after the fields common to all kinds
+	Validate func(v *Validator, plan *models.Plan) error
+}
+
+// kinds is the registry of supported kinds, by name
+var kinds = map[string]Kind{}
+
+// RegisterKind adds a kind to the registry, replacing any kind with the same name
+func RegisterKind(kind Kind) {
+	kinds[kind.Name] = kind
+}
+
+// LookupKind returns the registered kind with the given name
+func LookupKind(name string) (Kind, error) {
+	kind, ok := kinds[name]
+	if !ok {
+		return Kind{}, fmt.Errorf("unsupported kind %q (supported: %s)", name, strings.Join(KindNames(), ", "))
+	}
+	return kind, nil
+}
+
+// KindNames returns the names of the registered kinds, sorted
+func KindNames() []string {
+	names := make([]string, 0, len(kinds))
+	for name := range kinds {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func init() {
+	RegisterKind(Kind{
+		Name:         KindReleasePlan,
+		Required:     []string{"apiVersion", "kind", "metadata", "stages"},
+		Runnable:     true,
+		Rollbackable: true,
+		Validate:     validateReleasePlan,
+	})
+	RegisterKind(Kind{
+		Name:         KindRollbackPlan,
+		Required:     []string{"apiVersion", "kind", "metadata", "rollback"},
+		Rollbackable: true,
+		Validate:     validateRollbackPlan,
+	})
+	RegisterKind(Kind{
+		Name:     KindLibrary,
+		Required: []string{"apiVersion", "kind", "metadata"},
+		Validate: validateLibrary,
+	})
+}
@@ -1,19 +1,50 @@
 package config
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
+	"io"
+	"net/http"
+	"net/url"
 	"os"
 	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
 
 	"gopkg.in/yaml.v3"
 
 	"github.com/cuongtl1992/grp-cli/internal/models"
+	"github.com/cuongtl1992/grp-cli/internal/secrets"
+	"github.com/cuongtl1992/grp-cli/pkg/plugin"
 )
 
+// StdinPlan is the plan path that reads the plan from standard input
+const StdinPlan = "-"
+
+// DefaultFetchTimeout limits how long fetching a remote plan or include may take
+const DefaultFetchTimeout = 30 * time.Second
+
 // Loader handles loading and parsing configuration files
 type Loader struct {
 	resolver *Resolver
 	cache    map[string]interface{}
+	secrets  []string
+	strict   bool
+	// Absolute paths or URLs of the files included by the plan being loaded
+	loaded map[string]bool
+	// Source of plans read from standard input
+	stdin io.Reader
+	// Client and headers used to fetch remote plans and includes
+	client  *http.Client
+	headers http.Header
+	// Variable overrides layered over the plan's variables, in order
+	values []map[string]interface{}
+	// Environment whose settings are merged over the plan, and the environments
+	// defined by the last plan loaded
+	environment  string
+	environments []string
 }
 
 // NewLoader creates a new configuration loader
@@ -21,61 +52,235 @@ func NewLoader() *Loader {
 	return &Loader{
 		resolver: NewResolver(),
 		cache:    make(map[string]interface{}),
+		stdin:    os.Stdin,
+		client:   &http.Client{Timeout: DefaultFetchTimeout},
+		headers:  make(http.Header),
+	}
+}
+
+// SetStrict makes plan loading reject fields that are not part of the plan schema
+func (l *Loader) SetStrict(strict bool) {
+	l.strict = strict
+}
+
+// SetStdin replaces the reader a plan is read from when its path is StdinPlan
+func (l *Loader) SetStdin(stdin io.Reader) {
+	l.stdin = stdin
+}
+
+// SetFetchTimeout limits how long fetching a remote plan or include may take
+func (l *Loader) SetFetchTimeout(timeout time.Duration) {
+	l.client.Timeout = timeout
+}
+
+// SetHeader adds a header, such as Authorization, to the requests fetching remote
+// plans and includes. The value is treated as a secret and masked in output.
+func (l *Loader) SetHeader(name, value string) {
+	l.headers.Add(name, value)
+	l.secrets = append(l.secrets, value)
+}
+
+// AddValues layers variables over those of the plan and its includes before they
+// are resolved. Nested maps are merged key by key; other values replace the plan's.
+// Values added later take precedence.
+func (l *Loader) AddValues(values map[string]interface{}) {
+	l.values = append(l.values, values)
+}
+
+// AddValuesFile reads a YAML map of variables from a file or http(s) URL and
+// layers it over the plan's variables like AddValues
+func (l *Loader) AddValuesFile(path string) error {
+	var data []byte
+	var err error
+	if isRemote(path) {
+		data, err = l.fetch(path)
+	} else {
+		data, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read values file: %w", err)
+	}
+	
+	var values map[string]interface{}
+	if err := yaml.Unmarshal(data, &values); err != nil {
+		return fmt.Errorf("failed to parse values file %s: %w", path, err)
 	}
+	l.AddValues(values)
+	return nil
+}
+
+// SetEnvironment selects the environment of the plan's environments block whose
+// variables and settings are merged over the plan. An empty name uses the plan as
+// written.
+func (l *Loader) SetEnvironment(name string) {
+	l.environment = name
 }
 
-// LoadPlan loads a release plan from a file
+// Environments returns the names of the environments defined by the last plan
+// loaded, sorted
+func (l *Loader) Environments() []string {
+	return l.environments
+}
+
+// LoadPlan loads a release plan from a file, from standard input if filePath is
+// StdinPlan, or from an http(s) URL. Base plans and includes are resolved relative
+// to the plan: to its directory, the working directory for standard input, or its URL.
 func (l *Loader) LoadPlan(filePath string) (*models.Plan, error) {
 	if filePath == "" {
 		return nil, fmt.Errorf("file path cannot be empty")
 	}
 
-	// Verify file exists and is readable
-	if _, err := os.Stat(filePath); err != nil {
-		if os.IsNotExist(err) {
-			return nil, fmt.Errorf("plan file does not exist: %s", filePath)
-		}
-		return nil, fmt.Errorf("error accessing plan file: %w", err)
-	}
-
-	// Read the file
-	data, err := os.ReadFile(filePath)
+	// Read the plan from its source
+	data, err := l.readPlan(filePath)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read file: %w", err)
+		return nil, err
 	}
 	
-	// Parse as YAML
-	var rawPlan map[string]interface{}
-	if err := yaml.Unmarshal(data, &rawPlan); err != nil {
+	// Parse as YAML: the first document is the plan, later ones are merged like includes
+	documents, err := parseDocuments(data)
+	if err != nil {
 		return nil, fmt.Errorf("failed to parse YAML: %w", err)
 	}
+	
+	// Convert a plan written for an older apiVersion to the current one
+	migrated, version, err := MigratePlan(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid plan structure: %w", err)
+	}
+	if version != "" && version != CurrentAPIVersion {
+		data = migrated
+		if documents, err = parseDocuments(data); err != nil {
+			return nil, fmt.Errorf("failed to parse migrated plan: %w", err)
+		}
+	}
+	rawPlan := documents[0]
+	
+	// Extension fields only hold anchors for the rest of the plan
+	for key := range rawPlan {
+		if isExtensionField(key) {
+			delete(rawPlan, key)
+		}
+	}
 
+	// Resolve base plans and includes relative to the plan file
+	planPath := filePath
+	if !isRemote(filePath) {
+		// A plan read from stdin includes files relative to the working directory
+		if planPath, err = filepath.Abs(filePath); err != nil {
+			return nil, fmt.Errorf("failed to resolve plan path: %w", err)
+		}
+	}
+	l.loaded = make(map[string]bool)
+	
+	// Replace file references with the content of the files, relative to the plan
+	if err := l.inlineFiles(rawPlan, planPath); err != nil {
+		return nil, fmt.Errorf("invalid plan structure: %w", err)
+	}
+	
+	// Inherit the stages and settings of the base plan the plan extends
+	if rawPlan, err = l.extendPlan(rawPlan, planPath, []string{planPath}); err != nil {
+		return nil, err
+	}
+	
+	// Merge the settings of the selected environment over the plan
+	if rawPlan, l.environments, err = applyEnvironment(rawPlan, l.environment); err != nil {
+		return nil, err
+	}
+	
+	// Replace the jobs using a template with the template merged under them
+	if err := expandTemplates(rawPlan); err != nil {
+		return nil, fmt.Errorf("invalid plan structure: %w", err)
+	}
+	
+	// Record the conditions of the dependencies written as {job, on} apart from their names
+	if err := splitDependencyConditions(rawPlan); err != nil {
+		return nil, fmt.Errorf("invalid plan structure: %w", err)
+	}
+	
+	// Take out the declarations of the variables, checked once they are resolved
+	variableSchema, err := parseVariableSchema(rawPlan)
+	if err != nil {
+		return nil, fmt.Errorf("invalid plan structure: %w", err)
+	}
+	
 	// Validate the raw plan structure before processing
 	if err := l.validateRawPlan(rawPlan); err != nil {
 		return nil, fmt.Errorf("invalid plan structure: %w", err)
 	}
 	
-	// Process includes
-	baseDir := filepath.Dir(filePath)
-	if includes, ok := rawPlan["includes"].([]interface{}); ok {
-		for _, include := range includes {
-			if includeMap, ok := include.(map[string]interface{}); ok {
-				if path, ok := includeMap["path"].(string); ok {
-					// Resolve path relative to the plan file
-					includePath := filepath.Join(baseDir, path)
-					if err := l.loadInclude(includePath); err != nil {
-						return nil, fmt.Errorf("failed to load include %s: %w", path, err)
-					}
-				}
-			}
+	// Reject kinds grp-cli does not know how to handle
+	kindName, _ := rawPlan["kind"].(string)
+	kind, err := LookupKind(kindName)
+	if err != nil {
+		return nil, fmt.Errorf("invalid plan structure: %w", err)
+	}
+	
+	// Report misspelled fields with their line before they are silently dropped
+	if l.strict {
+		if err := checkKnownFields(data); err != nil {
+			return nil, fmt.Errorf("invalid plan structure: %w", err)
+		}
+	}
+	
+	// Start from the plan's own variables; included files merge theirs in
+	variables, _ := rawPlan["variables"].(map[string]interface{})
+	if variables == nil {
+		variables = make(map[string]interface{})
+	}
+	
+	// Process includes, starting the include chain at the plan file
+	if err := l.processIncludes(rawPlan, planPath, variables, []string{planPath}); err != nil {
+		return nil, err
+	}
+	for i, document := range documents[1:] {
+		name := fmt.Sprintf("document %d", i+2)
+		if err := l.mergeIncluded(document, planPath, name, variables, []string{planPath}, "", false); err != nil {
+			return nil, err
+		}
+		if kind, ok := document["kind"].(string); ok {
+			l.cache[kind] = document
 		}
 	}
 	
-	// Resolve variable references
-	resolvedPlan, err := l.resolver.ResolveValues(rawPlan, l.cache)
+	// Layer the values files and overrides on top
+	for _, values := range l.values {
+		mergeValues(variables, values)
+	}
+	
+	// Give the declared variables nobody set their default
+	variables = plugin.ApplyDefaults(variableSchema, variables)
+	
+	// Resolve variable references against the merged variables and the included files
+	context := make(map[string]interface{}, len(l.cache)+1)
+	for key, value := range l.cache {
+		context[key] = value
+	}
+	context["variables"] = variables
+	
+	// Resolve the variables first so the plan references their resolved values
+	resolvedVariables, err := l.resolver.ResolveValues(variables, context)
 	if err != nil {
 		return nil, fmt.Errorf("failed to resolve variables: %w", err)
 	}
+	if variableSchema != nil {
+		if resolvedVariables, err = checkVariables(variableSchema, resolvedVariables); err != nil {
+			return nil, fmt.Errorf("invalid variables: %w", err)
+		}
+	}
+	context["variables"] = resolvedVariables
+	if len(resolvedVariables) > 0 {
+		rawPlan["variables"] = resolvedVariables
+	}
+	
+	resolvedPlan, err := l.resolver.ResolveValues(rawPlan, context)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve variables: %w", err)
+	}
+	
+	// Check the structure of the plan, with the values it will actually have
+	if err := validatePlanSchema(resolvedPlan, kind, l.strict); err != nil {
+		return nil, fmt.Errorf("invalid plan structure: %w", err)
+	}
 	
 	// Convert to structured plan
 	var plan models.Plan
@@ -88,21 +293,250 @@ func (l *Loader) LoadPlan(filePath string) (*models.Plan, error) {
 		return nil, fmt.Errorf("failed to parse plan structure: %w", err)
 	}
 	
+	// Collect secret config values so they can be masked in output
+	l.secrets = append(l.secrets, planSecrets(&plan)...)
+	
 	return &plan, nil
 }
 
-// loadInclude loads an included configuration file
-func (l *Loader) loadInclude(filePath string) error {
+// Secrets returns the secret values of the loaded plans: values resolved from
+// ${secret.*} references and job config values stored under a key listed in
+// the plan's secrets
+func (l *Loader) Secrets() []string {
+	return append(append([]string(nil), l.resolver.Secrets()...), l.secrets...)
+}
+
+// planSecrets returns the job config values stored under the plan's secret keys
+func planSecrets(plan *models.Plan) []string {
+	stages := plan.Stages
+	if plan.Rollback != nil {
+		stages = append(stages[:len(stages):len(stages)], plan.Rollback.Stages...)
+	}
+	
+	var values []string
+	for _, stage := range stages {
+		for _, job := range append(stage.AllJobs(), stage.Rollback...) {
+			values = append(values, secrets.ConfigValues(job.Config, plan.Secrets)...)
+		}
+	}
+	return values
+}
+
+// readPlan reads a plan from standard input, a URL or a file
+func (l *Loader) readPlan(filePath string) ([]byte, error) {
+	if filePath == StdinPlan {
+		data, err := io.ReadAll(l.stdin)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read plan from stdin: %w", err)
+		}
+		return data, nil
+	}
+	if isRemote(filePath) {
+		return l.fetch(filePath)
+	}
+	
+	// Verify file exists and is readable
+	if _, err := os.Stat(filePath); err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("plan file does not exist: %s", filePath)
+		}
+		return nil, fmt.Errorf("error accessing plan file: %w", err)
+	}
+	
 	// Read the file
 	data, err := os.ReadFile(filePath)
 	if err != nil {
-		return fmt.Errorf("failed to read include file: %w", err)
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+	return data, nil
+}
+
+// fetch downloads a remote plan or include file
+func (l *Loader) fetch(location string) ([]byte, error) {
+	request, err := http.NewRequest(http.MethodGet, location, nil)
+	if err != nil {
+		return nil, fmt.Errorf("invalid URL %s: %w", location, err)
+	}
+	for name, values := range l.headers {
+		request.Header[name] = values
+	}
+	
+	response, err := l.client.Do(request)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch %s: %w", location, err)
+	}
+	defer response.Body.Close()
+	
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		return nil, fmt.Errorf("failed to fetch %s: %s", location, response.Status)
+	}
+	data, err := io.ReadAll(response.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch %s: %w", location, err)
+	}
+	return data, nil
+}
+
+// isRemote returns true if the location is an http(s) URL
+func isRemote(location string) bool {
+	return strings.HasPrefix(location, "http://") || strings.HasPrefix(location, "https://")
+}
+
+// includeLocation resolves an include path relative to the file including it: a
+// URL relative to a remote file, or an absolute path relative to a local one
+func includeLocation(including, path string) (string, error) {
+	if isRemote(path) {
+		return path, nil
+	}
+	if isRemote(including) {
+		base, err := url.Parse(including)
+		if err != nil {
+			return "", err
+		}
+		reference, err := url.Parse(path)
+		if err != nil {
+			return "", err
+		}
+		return base.ResolveReference(reference).String(), nil
+	}
+	return filepath.Abs(filepath.Join(filepath.Dir(including), path))
+}
+
+// processIncludes loads the files included by raw, which was loaded from including,
+// and merges their variables into variables. chain holds the absolute paths or URLs
+// of the files being loaded, outermost first, and is used to reject include cycles.
+func (l *Loader) processIncludes(raw map[string]interface{}, including string, variables map[string]interface{}, chain []string) error {
+	includes, _ := raw["includes"].([]interface{})
+	for _, include := range includes {
+		includeMap, ok := include.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		path, ok := includeMap["path"].(string)
+		if !ok {
+			continue
+		}
+		
+		// Resolve path relative to the including file
+		includePath, err := includeLocation(including, path)
+		if err != nil {
+			return fmt.Errorf("failed to resolve include %s: %w", path, err)
+		}
+		
+		// Reject files that include themselves, directly or transitively
+		for i, loading := range chain {
+			if loading == includePath {
+				return fmt.Errorf("include cycle detected: %s", formatIncludeChain(chain[0], append(chain[i:len(chain):len(chain)], includePath)))
+			}
+		}
+		
+		// Load each file once, even if it is included several times
+		if l.loaded[includePath] {
+			continue
+		}
+		l.loaded[includePath] = true
+		
+		rawConfig, err := l.loadInclude(includePath)
+		if err != nil {
+			return fmt.Errorf("failed to load include %s: %w", path, err)
+		}
+		
+		alias, _ := includeMap["as"].(string)
+		override, _ := includeMap["override"].(bool)
+		if err := l.mergeIncluded(rawConfig, includePath, "include "+path, variables, append(chain[:len(chain):len(chain)], includePath), alias, override); err != nil {
+			return err
+		}
+	}
+	
+	return nil
+}
+
+// mergeIncluded merges the variables of an included file or plan document, named
+// name in errors, into variables after processing its own includes
+func (l *Loader) mergeIncluded(rawConfig map[string]interface{}, location, name string, variables map[string]interface{}, chain []string, alias string, override bool) error {
+	// Nested includes merge into the variables of the file including them
+	nested, ok := rawConfig["variables"].(map[string]interface{})
+	if !ok {
+		if _, exists := rawConfig["variables"]; exists {
+			return fmt.Errorf("failed to merge variables of %s: variables must be a map", name)
+		}
+		nested = make(map[string]interface{})
+	}
+	if err := l.processIncludes(rawConfig, location, nested, chain); err != nil {
+		return err
+	}
+	
+	if err := mergeVariables(variables, nested, alias, override); err != nil {
+		return fmt.Errorf("failed to merge variables of %s: %w", name, err)
+	}
+	return nil
+}
+
+// parseDocuments parses the documents of a YAML stream, skipping empty ones
+func parseDocuments(data []byte) ([]map[string]interface{}, error) {
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	var documents []map[string]interface{}
+	for i := 1; ; i++ {
+		var document map[string]interface{}
+		err := decoder.Decode(&document)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			if i > 1 {
+				return nil, fmt.Errorf("document %d: %w", i, err)
+			}
+			return nil, err
+		}
+		if document != nil {
+			documents = append(documents, document)
+		}
+	}
+	if len(documents) == 0 {
+		return []map[string]interface{}{nil}, nil
+	}
+	return documents, nil
+}
+
+// isExtensionField reports whether a top-level plan field is an x- extension
+// field, which is ignored so it can hold the anchors of templates
+func isExtensionField(key string) bool {
+	return strings.HasPrefix(key, "x-")
+}
+
+// formatIncludeChain renders a chain of included files relative to the plan file
+func formatIncludeChain(planPath string, chain []string) string {
+	names := make([]string, len(chain))
+	for i, path := range chain {
+		names[i] = path
+		if isRemote(path) || isRemote(planPath) {
+			continue
+		}
+		if rel, err := filepath.Rel(filepath.Dir(planPath), path); err == nil {
+			names[i] = rel
+		}
+	}
+	return strings.Join(names, " -> ")
+}
+
+// loadInclude loads an included configuration file or URL and caches it under its kind
+func (l *Loader) loadInclude(filePath string) (map[string]interface{}, error) {
+	// Read the file
+	var data []byte
+	var err error
+	if isRemote(filePath) {
+		data, err = l.fetch(filePath)
+	} else if data, err = os.ReadFile(filePath); err != nil {
+		err = fmt.Errorf("failed to read include file: %w", err)
+	}
+	if err != nil {
+		return nil, err
 	}
 	
 	// Parse as YAML
 	var rawConfig map[string]interface{}
 	if err := yaml.Unmarshal(data, &rawConfig); err != nil {
-		return fmt.Errorf("failed to parse include YAML: %w", err)
+		return nil, fmt.Errorf("failed to parse include YAML: %w", err)
 	}
 	
 	// Get include key based on kind
@@ -117,6 +551,76 @@ func (l *Loader) loadInclude(filePath string) error {
 	// Store in cache
 	l.cache[key] = rawConfig
 	
+	return rawConfig, nil
+}
+
+// mergeVariables merges the variables of an included file into variables, under
+// alias if set. Keys that already exist are an error unless override is set.
+func mergeVariables(variables, included map[string]interface{}, alias string, override bool) error {
+	// Namespace the included variables under the alias
+	if alias != "" {
+		included = map[string]interface{}{alias: included}
+	}
+	
+	for key, value := range included {
+		if _, exists := variables[key]; exists && !override {
+			return fmt.Errorf("variable %s is already defined (set override: true to replace it)", key)
+		}
+		variables[key] = value
+	}
+	return nil
+}
+
+// mergeValues deep-merges values into variables: nested maps are merged key by
+// key, and any other value replaces the existing one. Nested maps are copied
+// rather than modified, as they may be shared with included files.
+func mergeValues(variables, values map[string]interface{}) {
+	for key, value := range values {
+		existing, isMap := variables[key].(map[string]interface{})
+		nested, mergesMap := value.(map[string]interface{})
+		if isMap && mergesMap {
+			merged := make(map[string]interface{}, len(existing))
+			for name, existingValue := range existing {
+				merged[name] = existingValue
+			}
+			mergeValues(merged, nested)
+			value = merged
+		}
+		variables[key] = value
+	}
+}
+
+// unknownFieldRegex matches the yaml.v3 error for a field missing from the target struct
+var unknownFieldRegex = regexp.MustCompile(`^line (\d+): field (\S+) not found in type models\.(\w+)$`)
+
+// checkKnownFields decodes the plan file with unknown fields disallowed and
+// returns an error naming every unknown field and its line. Other decoding
+// errors, such as ${...} references in typed fields, are left to the regular parse.
+func checkKnownFields(data []byte) error {
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	
+	var plan models.Plan
+	var typeErr *yaml.TypeError
+	if err := decoder.Decode(&plan); !errors.As(err, &typeErr) {
+		return nil
+	}
+	
+	var unknown []string
+	for _, message := range typeErr.Errors {
+		if match := unknownFieldRegex.FindStringSubmatch(message); match != nil {
+			if match[3] == "Plan" && (isExtensionField(match[2]) || isExtendsField(match[2]) || match[2] == environmentsField || match[2] == templatesField || match[2] == variableSchemaField) {
+				continue
+			}
+			if match[3] == "Job" && isTemplateJobField(match[2]) {
+				continue
+			}
+			unknown = append(unknown, fmt.Sprintf("line %s: unknown field %s in %s", match[1], match[2], strings.ToLower(match[3])))
+		}
+	}
+	if len(unknown) > 0 {
+		return errors.New(strings.Join(unknown, "; "))
+	}
 	return nil
 }
 
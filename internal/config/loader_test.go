@@ -0,0 +1,604 @@
+package config
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeFiles writes the given files to a temporary directory and returns its path
+func writeFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestLoadPlanIncludeVariables(t *testing.T) {
+	shared := `
+kind: SharedVariables
+variables:
+  registry: registry.example.com
+  region: eu-west-1
+`
+	tests := []struct {
+		name     string
+		includes string
+		image    string
+		expected string
+		errMsg   string
+	}{
+		{
+			name:     "merged variables",
+			includes: "- path: shared.yaml",
+			image:    "${variables.registry}/app",
+			expected: "registry.example.com/app",
+		},
+		{
+			name:     "aliased variables",
+			includes: "- path: shared.yaml\n    as: shared",
+			image:    "${variables.shared.registry}/${variables.app}",
+			expected: "registry.example.com/web",
+		},
+		{
+			name:     "conflicting variable",
+			includes: "- path: shared.yaml\n  - path: other.yaml",
+			errMsg:   "variable region is already defined",
+		},
+		{
+			name:     "override",
+			includes: "- path: shared.yaml\n  - path: other.yaml\n    override: true",
+			image:    "${variables.registry}:${variables.region}",
+			expected: "registry.example.com:us-east-1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan := `
+apiVersion: v1
+kind: ReleasePlan
+metadata:
+  name: test-plan
+includes:
+  ` + tt.includes + `
+variables:
+  app: web
+stages:
+  - name: deploy
+    jobs:
+      - name: deploy
+        type: kubernetes
+        config:
+          image: "` + tt.image + `"
+`
+			dir := writeFiles(t, map[string]string{
+				"plan.yaml":   plan,
+				"shared.yaml": shared,
+				"other.yaml":  "variables:\n  region: us-east-1\n",
+			})
+
+			loaded, err := NewLoader().LoadPlan(filepath.Join(dir, "plan.yaml"))
+			if tt.errMsg != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+					t.Fatalf("Expected error containing %q, got %v", tt.errMsg, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoadPlan() error = %v", err)
+			}
+			if image := loaded.Stages[0].Jobs[0].Config["image"]; image != tt.expected {
+				t.Errorf("Expected image %q, got %v", tt.expected, image)
+			}
+		})
+	}
+}
+
+func TestLoadPlanIncludeGraph(t *testing.T) {
+	plan := `
+apiVersion: v1
+kind: ReleasePlan
+metadata:
+  name: test-plan
+includes:
+  - path: a.yaml
+stages:
+  - name: deploy
+    jobs:
+      - name: deploy
+        type: kubernetes
+        config:
+          image: "${variables.registry}"
+`
+	tests := []struct {
+		name   string
+		files  map[string]string
+		errMsg string
+	}{
+		{
+			name: "nested includes",
+			files: map[string]string{
+				"a.yaml":     "includes:\n  - path: lib/b.yaml\n",
+				"lib/b.yaml": "variables:\n  registry: registry.example.com\n",
+			},
+		},
+		{
+			name: "shared include loaded once",
+			files: map[string]string{
+				"a.yaml":      "includes:\n  - path: b.yaml\n  - path: c.yaml\n",
+				"b.yaml":      "includes:\n  - path: common.yaml\n",
+				"c.yaml":      "includes:\n  - path: common.yaml\n",
+				"common.yaml": "variables:\n  registry: registry.example.com\n",
+			},
+		},
+		{
+			name:   "self include",
+			files:  map[string]string{"a.yaml": "includes:\n  - path: a.yaml\n"},
+			errMsg: "include cycle detected: a.yaml -> a.yaml",
+		},
+		{
+			name: "transitive cycle",
+			files: map[string]string{
+				"a.yaml":     "includes:\n  - path: lib/b.yaml\n",
+				"lib/b.yaml": "includes:\n  - path: ../plan.yaml\n",
+			},
+			errMsg: "include cycle detected: plan.yaml -> a.yaml -> lib/b.yaml -> plan.yaml",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			files := map[string]string{"plan.yaml": plan}
+			for name, content := range tt.files {
+				files[name] = content
+			}
+			dir := writeFiles(t, files)
+
+			loaded, err := NewLoader().LoadPlan(filepath.Join(dir, "plan.yaml"))
+			if tt.errMsg != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+					t.Fatalf("Expected error containing %q, got %v", tt.errMsg, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoadPlan() error = %v", err)
+			}
+			if image := loaded.Stages[0].Jobs[0].Config["image"]; image != "registry.example.com" {
+				t.Errorf("Expected image from included variables, got %v", image)
+			}
+		})
+	}
+}
+
+func TestLoadPlanStrict(t *testing.T) {
+	plan := `apiVersion: v1
+kind: ReleasePlan
+metadata:
+  name: test-plan
+variables:
+  retries: 2
+stages:
+  - name: deploy
+    jobs:
+      - name: deploy
+        type: kubernetes
+        retries: ${variables.retries}
+        timout: 5m
+`
+	dir := writeFiles(t, map[string]string{"plan.yaml": plan})
+
+	if _, err := NewLoader().LoadPlan(filepath.Join(dir, "plan.yaml")); err != nil {
+		t.Fatalf("Expected lenient mode to ignore unknown fields, got %v", err)
+	}
+
+	loader := NewLoader()
+	loader.SetStrict(true)
+	_, err := loader.LoadPlan(filepath.Join(dir, "plan.yaml"))
+	if err == nil || !strings.Contains(err.Error(), "line 13: unknown field timout in job") {
+		t.Errorf("Expected unknown field error with its line, got %v", err)
+	}
+}
+
+func TestLoadPlanKinds(t *testing.T) {
+	tests := []struct {
+		name    string
+		plan    string
+		wantErr string
+	}{
+		{
+			name: "rollback plan without stages",
+			plan: `apiVersion: v1
+kind: RollbackPlan
+metadata:
+  name: recover
+rollback:
+  stages:
+    - name: undo
+      jobs:
+        - name: revert
+          type: shell
+`,
+		},
+		{
+			name: "library",
+			plan: `apiVersion: v1
+kind: Library
+metadata:
+  name: shared
+variables:
+  registry: registry.example.com
+`,
+		},
+		{
+			name: "release plan without stages",
+			plan: `apiVersion: v1
+kind: ReleasePlan
+metadata:
+  name: release
+`,
+			wantErr: `missing required field "stages"`,
+		},
+		{
+			name: "rollback plan without rollback",
+			plan: `apiVersion: v1
+kind: RollbackPlan
+metadata:
+  name: recover
+`,
+			wantErr: `missing required field "rollback"`,
+		},
+		{
+			name: "unsupported kind",
+			plan: `apiVersion: v1
+kind: Deployment
+metadata:
+  name: web
+`,
+			wantErr: `unsupported kind "Deployment" (supported: Library, ReleasePlan, RollbackPlan)`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := writeFiles(t, map[string]string{"plan.yaml": tt.plan})
+			_, err := NewLoader().LoadPlan(filepath.Join(dir, "plan.yaml"))
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("LoadPlan() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestLoadPlanAnchors(t *testing.T) {
+	plan := `apiVersion: v1
+kind: ReleasePlan
+metadata:
+  name: test-plan
+x-templates:
+  deploy: &deploy
+    type: kubernetes
+    timeout: 5m
+    retries: 2
+    config: &deployConfig
+      namespace: staging
+      resource: deployment/web
+      image: ${variables.image}
+  unused:
+    config: ${variables.undefined}
+variables:
+  image: web:v2
+stages:
+  - name: staging
+    jobs:
+      - <<: *deploy
+        name: deploy
+  - name: production
+    jobs:
+      - <<: *deploy
+        name: deploy
+        config:
+          <<: *deployConfig
+          namespace: prod
+  - name: verify
+    jobs:
+      - &check
+        name: check
+        type: http
+        config:
+          url: https://example.com/health
+  - name: verify-again
+    jobs:
+      - *check
+`
+	dir := writeFiles(t, map[string]string{"plan.yaml": plan})
+
+	for _, strict := range []bool{false, true} {
+		t.Run(fmt.Sprintf("strict=%v", strict), func(t *testing.T) {
+			loader := NewLoader()
+			loader.SetStrict(strict)
+			loaded, err := loader.LoadPlan(filepath.Join(dir, "plan.yaml"))
+			if err != nil {
+				t.Fatalf("LoadPlan() error = %v", err)
+			}
+
+			tests := []struct {
+				stage     int
+				namespace string
+			}{
+				{stage: 0, namespace: "staging"},
+				{stage: 1, namespace: "prod"},
+			}
+			for _, tt := range tests {
+				job := loaded.Stages[tt.stage].Jobs[0]
+				if job.Name != "deploy" || job.Type != "kubernetes" || job.Timeout != "5m" || job.Retries != 2 {
+					t.Errorf("Expected stage %d to run the deploy template, got %+v", tt.stage, job)
+				}
+				if job.Config["namespace"] != tt.namespace || job.Config["image"] != "web:v2" || job.Config["resource"] != "deployment/web" {
+					t.Errorf("Expected stage %d config in namespace %s, got %v", tt.stage, tt.namespace, job.Config)
+				}
+			}
+
+			// Each alias is a copy: changing one job leaves the others alone
+			loaded.Stages[2].Jobs[0].Config["url"] = "changed"
+			if url := loaded.Stages[3].Jobs[0].Config["url"]; url != "https://example.com/health" {
+				t.Errorf("Expected the aliased job to keep its config, got %v", url)
+			}
+		})
+	}
+}
+
+func TestLoadPlanDocuments(t *testing.T) {
+	tests := []struct {
+		name      string
+		documents string
+		expected  string
+		errMsg    string
+	}{
+		{
+			name:      "variable set",
+			documents: "---\nvariables:\n  region: eu-west-1\n",
+			expected:  "web:v2 eu-west-1",
+		},
+		{
+			name:      "kind and includes",
+			documents: "---\nkind: Regions\nincludes:\n  - path: region.yaml\n---\n",
+			expected:  "web:v2 us-east-1",
+		},
+		{
+			name:      "conflicting variable",
+			documents: "---\nvariables:\n  image: web:v3\n",
+			errMsg:    "failed to merge variables of document 2: variable image is already defined",
+		},
+		{
+			name:      "not a map",
+			documents: "---\n- region\n",
+			errMsg:    "document 2",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan := `apiVersion: v1
+kind: ReleasePlan
+metadata:
+  name: test-plan
+variables:
+  image: web:v2
+stages:
+  - name: deploy
+    jobs:
+      - name: deploy
+        type: shell
+        config:
+          command: echo ${variables.image} ${variables.region}
+` + tt.documents
+			dir := writeFiles(t, map[string]string{
+				"plan.yaml":   plan,
+				"region.yaml": "variables:\n  region: us-east-1\n",
+			})
+
+			loaded, err := NewLoader().LoadPlan(filepath.Join(dir, "plan.yaml"))
+			if tt.errMsg != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+					t.Fatalf("Expected error containing %q, got %v", tt.errMsg, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoadPlan() error = %v", err)
+			}
+			if command := loaded.Stages[0].Jobs[0].Config["command"]; command != "echo "+tt.expected {
+				t.Errorf("Expected command %q, got %v", "echo "+tt.expected, command)
+			}
+		})
+	}
+}
+
+// remotePlan includes a file relative to its own URL
+const remotePlan = `
+apiVersion: v1
+kind: ReleasePlan
+metadata:
+  name: remote
+includes:
+  - path: shared/vars.yaml
+stages:
+  - name: deploy
+    jobs:
+      - name: app
+        type: shell
+        config:
+          command: echo ${variables.registry}
+`
+
+func TestLoadPlanRemote(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer t0ken" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/plans/release.yaml":
+			fmt.Fprint(w, remotePlan)
+		case "/plans/shared/vars.yaml":
+			fmt.Fprint(w, "variables:\n  registry: registry.example.com\n")
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name   string
+		path   string
+		token  string
+		errMsg string
+	}{
+		{name: "plan and includes fetched", path: "/plans/release.yaml", token: "Bearer t0ken"},
+		{name: "missing auth header", path: "/plans/release.yaml", errMsg: "401 Unauthorized"},
+		{name: "not found", path: "/plans/missing.yaml", token: "Bearer t0ken", errMsg: "404 Not Found"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			loader := NewLoader()
+			if tt.token != "" {
+				loader.SetHeader("Authorization", tt.token)
+			}
+
+			plan, err := loader.LoadPlan(server.URL + tt.path)
+			if tt.errMsg != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+					t.Fatalf("Expected error containing %q, got %v", tt.errMsg, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoadPlan() error = %v", err)
+			}
+
+			if command := plan.Stages[0].Jobs[0].Config["command"]; command != "echo registry.example.com" {
+				t.Errorf("Expected include resolved relative to the plan URL, got %v", command)
+			}
+			if secrets := loader.Secrets(); len(secrets) != 1 || secrets[0] != tt.token {
+				t.Errorf("Expected the header value to be masked, got %v", secrets)
+			}
+		})
+	}
+}
+
+func TestLoadPlanStdin(t *testing.T) {
+	dir := writeFiles(t, map[string]string{"shared/vars.yaml": "variables:\n  registry: registry.example.com\n"})
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+
+	loader := NewLoader()
+	loader.SetStdin(strings.NewReader(remotePlan))
+	plan, err := loader.LoadPlan(StdinPlan)
+	if err != nil {
+		t.Fatalf("LoadPlan() error = %v", err)
+	}
+	if command := plan.Stages[0].Jobs[0].Config["command"]; command != "echo registry.example.com" {
+		t.Errorf("Expected include resolved relative to the working directory, got %v", command)
+	}
+}
+
+func TestLoadPlanValues(t *testing.T) {
+	plan := `apiVersion: v1
+kind: ReleasePlan
+metadata:
+  name: values
+variables:
+  replicas: 1
+  image:
+    name: app
+    tag: latest
+stages:
+  - name: deploy
+    jobs:
+      - name: deploy
+        type: shell
+        config:
+          command: "deploy ${variables.image.name}:${variables.image.tag} x${variables.replicas} ${variables.region}"
+`
+	dir := writeFiles(t, map[string]string{
+		"plan.yaml":    plan,
+		"staging.yaml": "region: eu-west-1\nimage:\n  tag: v1\n",
+		"prod.yaml":    "replicas: 3\nimage:\n  tag: v2\n",
+		"list.yaml":    "- not a map\n",
+	})
+
+	tests := []struct {
+		name        string
+		files       []string
+		values      map[string]interface{}
+		expected    string
+		expectedErr string
+	}{
+		{name: "no values", files: nil, expected: "deploy app:latest x1 ${variables.region}"},
+		{name: "single file", files: []string{"staging.yaml"}, expected: "deploy app:v1 x1 eu-west-1"},
+		{name: "files merge in order", files: []string{"staging.yaml", "prod.yaml"}, expected: "deploy app:v2 x3 eu-west-1"},
+		{
+			name:     "values override files",
+			files:    []string{"staging.yaml"},
+			values:   map[string]interface{}{"image": map[string]interface{}{"name": "api"}},
+			expected: "deploy api:v1 x1 eu-west-1",
+		},
+		{name: "missing file", files: []string{"missing.yaml"}, expectedErr: "failed to read values file"},
+		{name: "not a map", files: []string{"list.yaml"}, expectedErr: "failed to parse values file"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			loader := NewLoader()
+			for _, file := range tt.files {
+				if err := loader.AddValuesFile(filepath.Join(dir, file)); err != nil {
+					if tt.expectedErr == "" || !strings.Contains(err.Error(), tt.expectedErr) {
+						t.Fatalf("AddValuesFile() error = %v", err)
+					}
+					return
+				}
+			}
+			if tt.expectedErr != "" {
+				t.Fatalf("Expected error containing %q", tt.expectedErr)
+			}
+			if tt.values != nil {
+				loader.AddValues(tt.values)
+			}
+
+			loaded, err := loader.LoadPlan(filepath.Join(dir, "plan.yaml"))
+			if err != nil {
+				t.Fatalf("LoadPlan() error = %v", err)
+			}
+			if command := loaded.Stages[0].Jobs[0].Config["command"]; command != tt.expected {
+				t.Errorf("Expected command %q, got %q", tt.expected, command)
+			}
+		})
+	}
+}
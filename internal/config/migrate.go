@@ -0,0 +1,148 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Plan API versions
+const (
+	// APIVersionV1 is the current plan format
+	APIVersionV1 = "v1"
+	// APIVersionDevopsReleaseV1 is the format of the original design of grp-cli,
+	// which v1 evolved from
+	APIVersionDevopsReleaseV1 = "devops.release/v1"
+	// CurrentAPIVersion is the apiVersion of the plans this version of grp-cli runs
+	CurrentAPIVersion = APIVersionV1
+)
+
+// migration converts a plan from one apiVersion to the next one
+type migration struct {
+	from string
+	to   string
+	// convert transforms the fields of the plan's mapping node, if they changed
+	// between the versions; the apiVersion itself is updated afterwards
+	convert func(plan *yaml.Node) error
+}
+
+// migrations lists the conversions between versions, oldest first
+var migrations = []migration{
+	// v1 kept the fields of the original design, only its apiVersion changed
+	{from: APIVersionDevopsReleaseV1, to: APIVersionV1},
+}
+
+// SupportedAPIVersions returns the current apiVersion followed by the older
+// versions that are migrated to it, newest first
+func SupportedAPIVersions() []string {
+	versions := []string{CurrentAPIVersion}
+	for i := len(migrations) - 1; i >= 0; i-- {
+		versions = append(versions, migrations[i].from)
+	}
+	return versions
+}
+
+// unsupportedAPIVersion is the error for a version grp-cli cannot read
+func unsupportedAPIVersion(version string) error {
+	older := SupportedAPIVersions()[1:]
+	return fmt.Errorf("unsupported apiVersion %q: this version of grp-cli runs %s plans and migrates %s ones; "+
+		"upgrade grp-cli to use plans written for a newer version", version, CurrentAPIVersion, strings.Join(older, ", "))
+}
+
+// MigratePlan converts the plan document of a plan file (the first YAML document)
+// to the current apiVersion, keeping its comments and field order. It returns the
+// apiVersion the plan had, and data unchanged if it is already current or has no
+// apiVersion.
+func MigratePlan(data []byte) ([]byte, string, error) {
+	var documents []*yaml.Node
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	for {
+		var document yaml.Node
+		err := decoder.Decode(&document)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, "", err
+		}
+		documents = append(documents, &document)
+	}
+	if len(documents) == 0 {
+		return data, "", nil
+	}
+
+	plan := documents[0]
+	if plan.Kind == yaml.DocumentNode && len(plan.Content) > 0 {
+		plan = plan.Content[0]
+	}
+	version := apiVersionNode(plan)
+	if version == nil {
+		return data, "", nil
+	}
+	if version.Value == CurrentAPIVersion {
+		return data, version.Value, nil
+	}
+
+	from := version.Value
+	if err := migrateNode(plan, version); err != nil {
+		return nil, from, err
+	}
+
+	var buffer bytes.Buffer
+	encoder := yaml.NewEncoder(&buffer)
+	encoder.SetIndent(2)
+	for _, document := range documents {
+		if err := encoder.Encode(document); err != nil {
+			return nil, from, fmt.Errorf("failed to write migrated plan: %w", err)
+		}
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, from, fmt.Errorf("failed to write migrated plan: %w", err)
+	}
+	return buffer.Bytes(), from, nil
+}
+
+// migrateNode applies the migrations from the plan's apiVersion to the current one
+func migrateNode(plan, version *yaml.Node) error {
+	start := -1
+	for i, m := range migrations {
+		if m.from == version.Value {
+			start = i
+			break
+		}
+	}
+	if start < 0 {
+		return unsupportedAPIVersion(version.Value)
+	}
+
+	for _, m := range migrations[start:] {
+		if m.convert != nil {
+			if err := m.convert(plan); err != nil {
+				return fmt.Errorf("failed to migrate plan from %s to %s: %w", m.from, m.to, err)
+			}
+		}
+		version.Value = m.to
+	}
+	// The current version needs no quotes, whatever the old one used
+	version.Style = 0
+	version.Tag = "!!str"
+	return nil
+}
+
+// apiVersionNode returns the scalar value node of a mapping's apiVersion, if any
+func apiVersionNode(mapping *yaml.Node) *yaml.Node {
+	if mapping.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		key, value := mapping.Content[i], mapping.Content[i+1]
+		if key.Value == "apiVersion" && value.Kind == yaml.ScalarNode {
+			return value
+		}
+	}
+	return nil
+}
@@ -0,0 +1,119 @@
+package config
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMigratePlan(t *testing.T) {
+	tests := []struct {
+		name        string
+		plan        string
+		wantVersion string
+		wantPlan    string
+		wantErr     string
+	}{
+		{
+			name:        "current version is unchanged",
+			plan:        "apiVersion: v1\nkind: ReleasePlan   # comment kept as is\n",
+			wantVersion: "v1",
+			wantPlan:    "apiVersion: v1\nkind: ReleasePlan   # comment kept as is\n",
+		},
+		{
+			name: "design spec version",
+			plan: `# Release of service X
+apiVersion: "devops.release/v1"
+kind: "ReleasePlan"
+metadata:
+  name: service-x # the service
+---
+kind: Regions
+`,
+			wantVersion: "devops.release/v1",
+			wantPlan: `# Release of service X
+apiVersion: v1
+kind: "ReleasePlan"
+metadata:
+  name: service-x # the service
+---
+kind: Regions
+`,
+		},
+		{
+			name:     "no apiVersion",
+			plan:     "kind: ReleasePlan\n",
+			wantPlan: "kind: ReleasePlan\n",
+		},
+		{
+			name:    "unknown version",
+			plan:    "apiVersion: v2\nkind: ReleasePlan\n",
+			wantErr: `unsupported apiVersion "v2": this version of grp-cli runs v1 plans and migrates devops.release/v1 ones`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			migrated, version, err := MigratePlan([]byte(tt.plan))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("MigratePlan() error = %v", err)
+			}
+			if version != tt.wantVersion {
+				t.Errorf("Expected version %q, got %q", tt.wantVersion, version)
+			}
+			if string(migrated) != tt.wantPlan {
+				t.Errorf("Expected migrated plan:\n%s\ngot:\n%s", tt.wantPlan, migrated)
+			}
+		})
+	}
+}
+
+func TestLoadPlanAPIVersions(t *testing.T) {
+	tests := []struct {
+		name       string
+		apiVersion string
+		wantErr    string
+	}{
+		{name: "current", apiVersion: "v1"},
+		{name: "migrated", apiVersion: `"devops.release/v1"`},
+		{name: "unknown", apiVersion: "v2", wantErr: `unsupported apiVersion "v2"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan := "apiVersion: " + tt.apiVersion + `
+kind: ReleasePlan
+metadata:
+  name: test-plan
+stages:
+  - name: deploy
+    jobs:
+      - name: deploy
+        type: kubernetes
+`
+			dir := writeFiles(t, map[string]string{"plan.yaml": plan})
+			loaded, err := NewLoader().LoadPlan(filepath.Join(dir, "plan.yaml"))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("Expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoadPlan() error = %v", err)
+			}
+			if loaded.APIVersion != CurrentAPIVersion {
+				t.Errorf("Expected the plan loaded as %s, got %s", CurrentAPIVersion, loaded.APIVersion)
+			}
+			if err := NewValidator().ValidatePlan(loaded); err != nil {
+				t.Errorf("ValidatePlan() error = %v", err)
+			}
+		})
+	}
+}
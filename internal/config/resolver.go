@@ -1,28 +1,76 @@
 package config
 
 import (
+	"encoding/base64"
+	"errors"
 	"fmt"
+	"os"
 	"regexp"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
 )
 
+const (
+	// envPrefix marks references that are read from environment variables, e.g. ${env.HOME}
+	envPrefix = "env."
+	// secretPrefix marks references to environment variables holding secrets, e.g. ${secret.DB_PASSWORD};
+	// their values are masked in output
+	secretPrefix = "secret."
+	// outputsPrefix marks references to the data returned by an earlier job, e.g. ${outputs.build.tag}
+	outputsPrefix = "outputs."
+	// runPrefix marks references to values of the running execution, e.g. ${run.workingDir}
+	runPrefix = "run."
+)
+
+var (
+	// errNotFound and errNotSet mark references to missing values, which functions
+	// such as default receive as nil
+	errNotFound = errors.New("not found")
+	errNotSet   = errors.New("not set")
+
+	// funcCallRegex matches a function call reference, e.g. upper(variables.env)
+	funcCallRegex = regexp.MustCompile(`^([A-Za-z_][A-Za-z0-9_]*)\((.*)\)$`)
+	// outputsRefRegex matches the job name of an outputs reference, also inside function calls
+	outputsRefRegex = regexp.MustCompile(`(?:^|[^\w.])outputs\.([\w-]+)`)
+	// runRefRegex matches a run reference, also inside function calls
+	runRefRegex = regexp.MustCompile(`(?:^|[^\w.])run\.`)
+)
+
+// Func is a function usable in references, e.g. ${upper(variables.env)}. Its
+// arguments are string, integer or resolved reference values; references to
+// missing values are passed as nil.
+type Func func(args ...interface{}) (interface{}, error)
+
 // Resolver handles variable and reference resolution
 type Resolver struct {
 	// Regular expression for variable references ${...}
 	refRegex *regexp.Regexp
+	// Values resolved from ${secret.*} references
+	secrets []string
+	// Functions usable in references, by name
+	funcs map[string]Func
 }
 
-// NewResolver creates a new resolver
+// NewResolver creates a new resolver with the built-in functions
 func NewResolver() *Resolver {
 	return &Resolver{
 		refRegex: regexp.MustCompile(`\${([^}]+)}`),
+		funcs:    builtinFuncs(),
 	}
 }
 
+// RegisterFunc makes a function usable in references under the given name,
+// replacing any function of the same name
+func (r *Resolver) RegisterFunc(name string, fn Func) {
+	r.funcs[name] = fn
+}
+
 // ResolveValues processes all variable references in a configuration
 func (r *Resolver) ResolveValues(config map[string]interface{}, context map[string]interface{}) (map[string]interface{}, error) {
 	result := make(map[string]interface{})
-	
+
 	// Process each key-value pair
 	for key, value := range config {
 		resolvedValue, err := r.resolveValue(value, context)
@@ -31,7 +79,7 @@ func (r *Resolver) ResolveValues(config map[string]interface{}, context map[stri
 		}
 		result[key] = resolvedValue
 	}
-	
+
 	return result, nil
 }
 
@@ -53,43 +101,118 @@ func (r *Resolver) resolveValue(value interface{}, context map[string]interface{
 // resolveString handles variable substitution in strings
 func (r *Resolver) resolveString(value string, context map[string]interface{}) (interface{}, error) {
 	// Check if the entire string is a reference
-	if r.refRegex.MatchString(value) && strings.HasPrefix(value, "${") && strings.HasSuffix(value, "}") {
+	if loc := r.refRegex.FindStringIndex(value); loc != nil && loc[0] == 0 && loc[1] == len(value) {
 		// Extract reference path
 		path := value[2 : len(value)-1]
-		
+
 		// Resolve the reference
 		resolvedValue, err := r.resolvePath(path, context)
 		if err != nil {
 			return nil, err
 		}
-		
+
 		// Return the resolved value with its original type
 		return resolvedValue, nil
 	}
-	
+
 	// Handle partial substitutions
+	var envErr error
 	result := r.refRegex.ReplaceAllStringFunc(value, func(match string) string {
 		// Extract reference path
 		path := match[2 : len(match)-1]
-		
+
 		// Resolve the reference
 		resolvedValue, err := r.resolvePath(path, context)
 		if err != nil {
+			// Unset environment variables and failed function calls are always an error
+			if (isEnvReference(path) || funcCallRegex.MatchString(path)) && envErr == nil {
+				envErr = err
+			}
 			// Just return the original reference if resolution fails
 			return match
 		}
-		
+
 		// Convert to string for interpolation
 		return fmt.Sprintf("%v", resolvedValue)
 	})
-	
+	if envErr != nil {
+		return nil, envErr
+	}
+
 	return result, nil
 }
 
+// Secrets returns the values resolved from ${secret.*} references so far
+func (r *Resolver) Secrets() []string {
+	return r.secrets
+}
+
+// OutputReferences returns the names of the jobs whose outputs are referenced in
+// a configuration, e.g. "build" for ${outputs.build.tag}, in name order
+func (r *Resolver) OutputReferences(config map[string]interface{}) []string {
+	var jobs []string
+	seen := make(map[string]bool)
+
+	var walk func(value interface{})
+	walk = func(value interface{}) {
+		switch v := value.(type) {
+		case string:
+			for _, match := range r.refRegex.FindAllStringSubmatch(v, -1) {
+				for _, ref := range outputsRefRegex.FindAllStringSubmatch(match[1], -1) {
+					if !seen[ref[1]] {
+						seen[ref[1]] = true
+						jobs = append(jobs, ref[1])
+					}
+				}
+			}
+		case map[string]interface{}:
+			for _, item := range v {
+				walk(item)
+			}
+		case []interface{}:
+			for _, item := range v {
+				walk(item)
+			}
+		}
+	}
+	walk(config)
+
+	sort.Strings(jobs)
+	return jobs
+}
+
+// isEnvReference returns true if the path references an environment variable
+func isEnvReference(path string) bool {
+	return strings.HasPrefix(path, envPrefix) || strings.HasPrefix(path, secretPrefix)
+}
+
+// resolveEnv reads an environment variable referenced as env.NAME or secret.NAME,
+// recording the value of secret references
+func (r *Resolver) resolveEnv(path string) (interface{}, error) {
+	prefix := envPrefix
+	if strings.HasPrefix(path, secretPrefix) {
+		prefix = secretPrefix
+	}
+
+	name := strings.TrimPrefix(path, prefix)
+	if name == "" {
+		return nil, fmt.Errorf("invalid environment reference: %s", path)
+	}
+
+	value, ok := os.LookupEnv(name)
+	if !ok {
+		return nil, fmt.Errorf("environment variable %s is %w", name, errNotSet)
+	}
+	if prefix == secretPrefix {
+		r.secrets = append(r.secrets, value)
+	}
+	return value, nil
+}
+
 // resolveSlice handles variable substitution in slices
 func (r *Resolver) resolveSlice(slice []interface{}, context map[string]interface{}) ([]interface{}, error) {
 	result := make([]interface{}, len(slice))
-	
+
 	for i, item := range slice {
 		resolvedItem, err := r.resolveValue(item, context)
 		if err != nil {
@@ -97,17 +220,37 @@ func (r *Resolver) resolveSlice(slice []interface{}, context map[string]interfac
 		}
 		result[i] = resolvedItem
 	}
-	
+
 	return result, nil
 }
 
 // resolvePath handles dot-notation path resolution (e.g., "variables.service.port")
 func (r *Resolver) resolvePath(path string, context map[string]interface{}) (interface{}, error) {
+	// Function calls resolve their arguments first
+	if call := funcCallRegex.FindStringSubmatch(path); call != nil {
+		return r.callFunc(path, call[1], call[2], context)
+	}
+
+	// Environment references are read from the process environment
+	if isEnvReference(path) {
+		return r.resolveEnv(path)
+	}
+
+	// Job outputs only exist at execution time; keep the reference until then
+	if _, ok := context["outputs"]; !ok && strings.HasPrefix(path, outputsPrefix) {
+		return "${" + path + "}", nil
+	}
+
+	// Run values are only known once the execution starts
+	if _, ok := context["run"]; !ok && strings.HasPrefix(path, runPrefix) {
+		return "${" + path + "}", nil
+	}
+
 	parts := strings.Split(path, ".")
-	
+
 	// Start with the top-level context
 	var current interface{} = context
-	
+
 	// Navigate through the path
 	for _, part := range parts {
 		// Handle map access
@@ -115,14 +258,177 @@ func (r *Resolver) resolvePath(path string, context map[string]interface{}) (int
 			var exists bool
 			current, exists = currentMap[part]
 			if !exists {
-				return nil, fmt.Errorf("reference path not found: %s", path)
+				return nil, fmt.Errorf("reference path %w: %s", errNotFound, path)
 			}
 			continue
 		}
-		
+
 		// Can't navigate further
 		return nil, fmt.Errorf("invalid reference path: %s", path)
 	}
-	
+
 	return current, nil
-} 
\ No newline at end of file
+}
+
+// callFunc resolves the arguments of a function call reference and calls the function
+func (r *Resolver) callFunc(path, name, argList string, context map[string]interface{}) (interface{}, error) {
+	fn, ok := r.funcs[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown function %q in reference ${%s}", name, path)
+	}
+
+	// Job outputs only exist at execution time; keep the call until then
+	if _, ok := context["outputs"]; !ok && outputsRefRegex.MatchString(argList) {
+		return "${" + path + "}", nil
+	}
+	if _, ok := context["run"]; !ok && runRefRegex.MatchString(argList) {
+		return "${" + path + "}", nil
+	}
+
+	args, err := splitArgs(argList)
+	if err != nil {
+		return nil, fmt.Errorf("invalid arguments in reference ${%s}: %w", path, err)
+	}
+
+	values := make([]interface{}, len(args))
+	for i, arg := range args {
+		values[i], err = r.resolveArg(arg, context)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+	}
+
+	value, err := fn(values...)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return value, nil
+}
+
+// resolveArg resolves a function argument: a quoted string, an integer, or a
+// reference, which is nil if the referenced value is missing
+func (r *Resolver) resolveArg(arg string, context map[string]interface{}) (interface{}, error) {
+	if strings.HasPrefix(arg, `"`) {
+		value, err := strconv.Unquote(arg)
+		if err != nil {
+			return nil, fmt.Errorf("invalid string argument %s", arg)
+		}
+		return value, nil
+	}
+	if number, err := strconv.Atoi(arg); err == nil {
+		return number, nil
+	}
+
+	value, err := r.resolvePath(arg, context)
+	if errors.Is(err, errNotFound) || errors.Is(err, errNotSet) {
+		return nil, nil
+	}
+	return value, err
+}
+
+// splitArgs splits a function's argument list on the commas outside of quoted
+// strings and nested calls
+func splitArgs(argList string) ([]string, error) {
+	if strings.TrimSpace(argList) == "" {
+		return nil, nil
+	}
+
+	var args []string
+	depth, start := 0, 0
+	inString := false
+	for i := 0; i < len(argList); i++ {
+		switch c := argList[i]; {
+		case inString && c == '\\':
+			i++
+		case c == '"':
+			inString = !inString
+		case inString:
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+		case c == ',' && depth == 0:
+			args = append(args, strings.TrimSpace(argList[start:i]))
+			start = i + 1
+		}
+	}
+	if inString || depth != 0 {
+		return nil, fmt.Errorf("unbalanced quotes or parentheses")
+	}
+	args = append(args, strings.TrimSpace(argList[start:]))
+
+	for _, arg := range args {
+		if arg == "" {
+			return nil, fmt.Errorf("empty argument")
+		}
+	}
+	return args, nil
+}
+
+// builtinFuncs returns the functions available in every resolver
+func builtinFuncs() map[string]Func {
+	return map[string]Func{
+		"upper": stringFunc(strings.ToUpper),
+		"lower": stringFunc(strings.ToLower),
+		"trim":  stringFunc(strings.TrimSpace),
+		"b64enc": stringFunc(func(s string) string {
+			return base64.StdEncoding.EncodeToString([]byte(s))
+		}),
+		"b64dec": func(args ...interface{}) (interface{}, error) {
+			s, err := stringArg(args)
+			if err != nil {
+				return nil, err
+			}
+			decoded, err := base64.StdEncoding.DecodeString(s)
+			if err != nil {
+				return nil, fmt.Errorf("invalid base64 value: %w", err)
+			}
+			return string(decoded), nil
+		},
+		// default returns the first argument that is set and not empty
+		"default": func(args ...interface{}) (interface{}, error) {
+			if len(args) < 2 {
+				return nil, fmt.Errorf("expected at least 2 arguments, got %d", len(args))
+			}
+			for _, arg := range args[:len(args)-1] {
+				if arg != nil && arg != "" {
+					return arg, nil
+				}
+			}
+			return args[len(args)-1], nil
+		},
+		// now formats the current time with a Go time layout, RFC 3339 by default
+		"now": func(args ...interface{}) (interface{}, error) {
+			if len(args) == 0 {
+				return time.Now().Format(time.RFC3339), nil
+			}
+			layout, err := stringArg(args)
+			if err != nil {
+				return nil, err
+			}
+			return time.Now().Format(layout), nil
+		},
+	}
+}
+
+// stringFunc adapts a string transformation to a Func taking one argument
+func stringFunc(transform func(string) string) Func {
+	return func(args ...interface{}) (interface{}, error) {
+		s, err := stringArg(args)
+		if err != nil {
+			return nil, err
+		}
+		return transform(s), nil
+	}
+}
+
+// stringArg returns the single argument of a function as a string
+func stringArg(args []interface{}) (string, error) {
+	if len(args) != 1 {
+		return "", fmt.Errorf("expected 1 argument, got %d", len(args))
+	}
+	if args[0] == nil {
+		return "", fmt.Errorf("argument is not set")
+	}
+	return fmt.Sprintf("%v", args[0]), nil
+}
@@ -0,0 +1,144 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestResolveValues(t *testing.T) {
+	t.Setenv("GRP_TEST_REGION", "eu-west-1")
+	t.Setenv("GRP_TEST_TOKEN", "s3cr3t")
+
+	context := map[string]interface{}{
+		"variables": map[string]interface{}{
+			"app": map[string]interface{}{
+				"name": "example",
+				"port": 8080,
+			},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		value    interface{}
+		expected interface{}
+		wantErr  bool
+	}{
+		{name: "variable reference keeps type", value: "${variables.app.port}", expected: 8080},
+		{name: "env reference", value: "${env.GRP_TEST_TOKEN}", expected: "s3cr3t"},
+		{name: "mixed env and variables", value: "${variables.app.name}-${env.GRP_TEST_REGION}:${variables.app.port}", expected: "example-eu-west-1:8080"},
+		{name: "unknown partial variable is left as is", value: "prefix-${variables.missing}", expected: "prefix-${variables.missing}"},
+		{name: "unset env variable", value: "${env.GRP_TEST_UNSET}", wantErr: true},
+		{name: "unset env variable in partial", value: "${variables.app.name}-${env.GRP_TEST_UNSET}", wantErr: true},
+		{name: "secret reference", value: "token-${secret.GRP_TEST_TOKEN}", expected: "token-s3cr3t"},
+		{name: "job outputs are kept until execution", value: "app:${outputs.build.tag}", expected: "app:${outputs.build.tag}"},
+		{name: "run values are kept until execution", value: "${run.workingDir}/infra", expected: "${run.workingDir}/infra"},
+		{name: "function call with run values is kept until execution", value: "${default(run.workingDir, \".\")}", expected: "${default(run.workingDir, \".\")}"},
+		{name: "function call", value: "${upper(variables.app.name)}", expected: "EXAMPLE"},
+		{name: "function call keeps type", value: "${default(variables.app.port, 80)}", expected: 8080},
+		{name: "default for missing variable", value: "${default(variables.missing, \"v1\")}", expected: "v1"},
+		{name: "default for unset env variable", value: "${default(env.GRP_TEST_UNSET, \"eu\")}", expected: "eu"},
+		{name: "nested function calls", value: "app-${lower(default(variables.missing, \"WEB\"))}", expected: "app-web"},
+		{name: "b64enc", value: "${b64enc(variables.app.name)}", expected: "ZXhhbXBsZQ=="},
+		{name: "function call with outputs is kept until execution", value: "${upper(outputs.build.tag)}", expected: "${upper(outputs.build.tag)}"},
+		{name: "unknown function", value: "${nope(variables.app.name)}", wantErr: true},
+		{name: "unknown function in partial", value: "app-${nope(variables.app.name)}", wantErr: true},
+		{name: "function of missing variable", value: "${upper(variables.missing)}", wantErr: true},
+		{name: "unbalanced arguments", value: "${upper(\"x)}", wantErr: true},
+		{name: "nested values", value: []interface{}{map[string]interface{}{"region": "${env.GRP_TEST_REGION}"}}, expected: []interface{}{map[string]interface{}{"region": "eu-west-1"}}},
+	}
+
+	resolver := NewResolver()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resolved, err := resolver.ResolveValues(map[string]interface{}{"value": tt.value}, context)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ResolveValues() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if !equalValues(resolved["value"], tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, resolved["value"])
+			}
+		})
+	}
+
+	if secrets := resolver.Secrets(); len(secrets) != 1 || secrets[0] != "s3cr3t" {
+		t.Errorf("Expected only the secret reference to be recorded, got %v", secrets)
+	}
+}
+
+func TestOutputReferences(t *testing.T) {
+	config := map[string]interface{}{
+		"image":   "app:${outputs.build.tag}",
+		"digest":  "${outputs.build.digest}",
+		"targets": []interface{}{map[string]interface{}{"url": "${outputs.deploy.url}/health"}},
+		"release": "${lower(outputs.release.tag)}",
+		"region":  "${variables.region}",
+		"port":    8080,
+	}
+
+	references := NewResolver().OutputReferences(config)
+	if expected := []string{"build", "deploy", "release"}; !reflect.DeepEqual(references, expected) {
+		t.Errorf("Expected references to %v, got %v", expected, references)
+	}
+}
+
+func TestResolverFuncs(t *testing.T) {
+	resolver := NewResolver()
+	resolver.RegisterFunc("repeat", func(args ...interface{}) (interface{}, error) {
+		return strings.Repeat(args[0].(string), args[1].(int)), nil
+	})
+
+	resolved, err := resolver.ResolveValues(map[string]interface{}{
+		"repeated": "${repeat(\"ab\", 3)}",
+		"date":     "${now(\"2006\")}",
+	}, map[string]interface{}{})
+	if err != nil {
+		t.Fatalf("ResolveValues() error = %v", err)
+	}
+	if resolved["repeated"] != "ababab" {
+		t.Errorf("Expected registered function to be called, got %v", resolved["repeated"])
+	}
+	if year := time.Now().Format("2006"); resolved["date"] != year {
+		t.Errorf("Expected now to format the current time as %s, got %v", year, resolved["date"])
+	}
+
+	_, err = resolver.ResolveValues(map[string]interface{}{"value": "${nope()}"}, map[string]interface{}{})
+	if err == nil || !strings.Contains(err.Error(), `unknown function "nope"`) {
+		t.Errorf("Expected unknown function error, got %v", err)
+	}
+}
+
+// equalValues compares resolved values, including nested maps and slices
+func equalValues(actual, expected interface{}) bool {
+	switch e := expected.(type) {
+	case map[string]interface{}:
+		a, ok := actual.(map[string]interface{})
+		if !ok || len(a) != len(e) {
+			return false
+		}
+		for key, value := range e {
+			if !equalValues(a[key], value) {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		a, ok := actual.([]interface{})
+		if !ok || len(a) != len(e) {
+			return false
+		}
+		for i := range e {
+			if !equalValues(a[i], e[i]) {
+				return false
+			}
+		}
+		return true
+	default:
+		return actual == expected
+	}
+}
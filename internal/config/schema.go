@@ -0,0 +1,63 @@
+package config
+
+import (
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/cuongtl1992/grp-cli/pkg/plugin"
+)
+
+// releasePlanSchemaJSON is the JSON schema of the ReleasePlan kind
+//
+//go:embed schema/releaseplan.json
+var releasePlanSchemaJSON []byte
+
+// releasePlanSchema parses the embedded ReleasePlan schema. In strict mode every
+// object with declared properties rejects undeclared ones, catching typos such as
+// `depnedsOn` that YAML unmarshaling would otherwise drop silently.
+func releasePlanSchema(strict bool) (*plugin.JSONSchema, error) {
+	var schema plugin.JSONSchema
+	if err := json.Unmarshal(releasePlanSchemaJSON, &schema); err != nil {
+		return nil, fmt.Errorf("failed to parse plan schema: %w", err)
+	}
+	if strict {
+		closeSchema(&schema)
+	}
+	return &schema, nil
+}
+
+// closeSchema forbids undeclared properties on every object that declares properties
+func closeSchema(schema *plugin.JSONSchema) {
+	if schema == nil {
+		return
+	}
+	if len(schema.Properties) > 0 && schema.AdditionalProperties == nil {
+		closed := false
+		schema.AdditionalProperties = &closed
+	}
+	for _, property := range schema.Properties {
+		closeSchema(property)
+	}
+	closeSchema(schema.Items)
+}
+
+// validatePlanSchema checks a parsed plan of the given kind against the plan
+// schema, with the top-level fields the kind requires, and reports every problem
+// with the path of the offending field
+func validatePlanSchema(rawPlan map[string]interface{}, kind Kind, strict bool) error {
+	schema, err := releasePlanSchema(strict)
+	if err != nil {
+		return err
+	}
+	schema.Required = kind.Required
+
+	err = plugin.ValidateConfig(schema, rawPlan)
+	var configErr *plugin.ConfigError
+	if errors.As(err, &configErr) {
+		return fmt.Errorf("plan does not match schema: %s", strings.Join(configErr.Problems, "; "))
+	}
+	return err
+}
@@ -0,0 +1,144 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/cuongtl1992/grp-cli/internal/models"
+	"github.com/cuongtl1992/grp-cli/pkg/plugin"
+)
+
+func TestValidatePlanSchema(t *testing.T) {
+	tests := []struct {
+		name   string
+		plan   string
+		strict bool
+		errMsg string
+	}{
+		{
+			name: "valid plan",
+			plan: `
+apiVersion: v1
+kind: ReleasePlan
+metadata: {name: test}
+stages:
+  - name: build
+    jobs: [{name: compile, type: shell, retries: 2}]
+`,
+			strict: true,
+		},
+		{
+			name: "missing job type",
+			plan: `
+apiVersion: v1
+kind: ReleasePlan
+metadata: {name: test}
+stages:
+  - name: build
+    jobs: [{name: compile}]
+`,
+			errMsg: `missing required field "stages[0].jobs[0].type"`,
+		},
+		{
+			name: "wrong type",
+			plan: `
+apiVersion: v1
+kind: ReleasePlan
+metadata: {name: test}
+stages:
+  - name: build
+    jobs: [{name: compile, type: shell, retries: many}]
+`,
+			errMsg: `"stages[0].jobs[0].retries" must be integer, got string`,
+		},
+		{
+			name: "unknown field is ignored by default",
+			plan: `
+apiVersion: v1
+kind: ReleasePlan
+metadata: {name: test}
+stages:
+  - name: build
+    jobs: [{name: compile, type: shell, depnedsOn: [lint]}]
+`,
+		},
+		{
+			name: "unknown field in strict mode",
+			plan: `
+apiVersion: v1
+kind: ReleasePlan
+metadata: {name: test}
+stages:
+  - name: build
+    jobs: [{name: compile, type: shell, depnedsOn: [lint], config: {anything: goes}}]
+`,
+			strict: true,
+			errMsg: `unknown field "stages[0].jobs[0].depnedsOn"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var rawPlan map[string]interface{}
+			if err := yaml.Unmarshal([]byte(tt.plan), &rawPlan); err != nil {
+				t.Fatal(err)
+			}
+
+			kind, err := LookupKind(rawPlan["kind"].(string))
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = validatePlanSchema(rawPlan, kind, tt.strict)
+			if tt.errMsg == "" {
+				if err != nil {
+					t.Errorf("Expected no error, got %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+				t.Errorf("Expected error containing %q, got %v", tt.errMsg, err)
+			}
+		})
+	}
+}
+
+// TestReleasePlanSchemaCoversModel guards against plan fields being added to the
+// models without updating the embedded schema, which strict mode would reject
+func TestReleasePlanSchemaCoversModel(t *testing.T) {
+	schema, err := releasePlanSchema(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkSchemaFields(t, "plan", reflect.TypeOf(models.Plan{}), schema)
+}
+
+// checkSchemaFields reports every yaml field of a struct type missing from the schema
+func checkSchemaFields(t *testing.T, path string, typ reflect.Type, schema *plugin.JSONSchema) {
+	t.Helper()
+	switch typ.Kind() {
+	case reflect.Ptr:
+		checkSchemaFields(t, path, typ.Elem(), schema)
+	case reflect.Slice:
+		if schema.Items == nil {
+			t.Errorf("Schema for %s has no items", path)
+			return
+		}
+		checkSchemaFields(t, path+"[]", typ.Elem(), schema.Items)
+	case reflect.Struct:
+		for i := 0; i < typ.NumField(); i++ {
+			name := strings.Split(typ.Field(i).Tag.Get("yaml"), ",")[0]
+			if name == "" || name == "-" {
+				continue
+			}
+			property, ok := schema.Properties[name]
+			if !ok {
+				t.Errorf("Schema is missing field %s.%s", path, name)
+				continue
+			}
+			checkSchemaFields(t, path+"."+name, typ.Field(i).Type, property)
+		}
+	}
+}
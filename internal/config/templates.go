@@ -0,0 +1,148 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/cuongtl1992/grp-cli/internal/models"
+)
+
+// Plan and job fields of job templates; they are removed once the jobs using a
+// template are expanded
+const (
+	templatesField = "templates"
+	usesField      = "uses"
+	withField      = "with"
+)
+
+// isTemplateJobField reports whether a job field references a template
+func isTemplateJobField(key string) bool {
+	return key == usesField || key == withField
+}
+
+// expandTemplates removes the templates block from raw and replaces every job,
+// stage or plan hook and stage or plan rollback job that uses a template with the
+// template merged under the job. The parameters given with with are deep-merged into the template's config,
+// and the job's other fields, including its own config, override the template's.
+func expandTemplates(raw map[string]interface{}) error {
+	value := raw[templatesField]
+	delete(raw, templatesField)
+
+	templates, ok := value.(map[string]interface{})
+	if value != nil && !ok {
+		return fmt.Errorf("templates must be a map of template names to jobs")
+	}
+
+	if err := expandStageTemplates(raw["stages"], templates); err != nil {
+		return err
+	}
+	for _, hook := range []string{models.PlanHookOnSuccess, models.PlanHookOnFailure} {
+		if err := expandJobTemplates(hook, raw[hook], templates); err != nil {
+			return err
+		}
+	}
+	if rollback, ok := raw["rollback"].(map[string]interface{}); ok {
+		return expandStageTemplates(rollback["stages"], templates)
+	}
+	return nil
+}
+
+// expandStageTemplates expands the jobs and hooks of a list of stages that use a template
+func expandStageTemplates(stages interface{}, templates map[string]interface{}) error {
+	list, _ := stages.([]interface{})
+	for _, item := range list {
+		stage, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		for _, key := range []string{"preJobs", "jobs", "postJobs", "rollback"} {
+			if err := expandJobTemplates("stage "+itemName(stage), stage[key], templates); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// expandJobTemplates expands the jobs of a list that use a template; owner names
+// the stage or hook of the list in errors
+func expandJobTemplates(owner string, list interface{}, templates map[string]interface{}) error {
+	jobs, _ := list.([]interface{})
+	for i, item := range jobs {
+		job, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if _, uses := job[usesField]; !uses {
+			continue
+		}
+		expanded, err := expandJob(job, templates)
+		if err != nil {
+			return fmt.Errorf("%s: job %s: %w", owner, itemName(job), err)
+		}
+		jobs[i] = expanded
+	}
+	return nil
+}
+
+// expandJob merges a job over the template it uses
+func expandJob(job, templates map[string]interface{}) (map[string]interface{}, error) {
+	name, ok := job[usesField].(string)
+	if !ok || name == "" {
+		return nil, fmt.Errorf("uses must be the name of a template")
+	}
+	value, exists := templates[name]
+	if !exists {
+		return nil, fmt.Errorf("uses undefined template %s%s", name, definedTemplates(templates))
+	}
+	template, ok := value.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("template %s must be a job", name)
+	}
+	for _, field := range []string{"name", usesField, withField} {
+		if _, exists := template[field]; exists {
+			return nil, fmt.Errorf("template %s cannot set %s", name, field)
+		}
+	}
+
+	params, ok := job[withField].(map[string]interface{})
+	if job[withField] != nil && !ok {
+		return nil, fmt.Errorf("with must be a map of parameters")
+	}
+	config, _ := job["config"].(map[string]interface{})
+	var collisions []string
+	for param := range params {
+		if _, exists := config[param]; exists {
+			collisions = append(collisions, param)
+		}
+	}
+	if len(collisions) > 0 {
+		sort.Strings(collisions)
+		return nil, fmt.Errorf("parameters %s are also set in config", strings.Join(collisions, ", "))
+	}
+
+	fields := make(map[string]interface{}, len(job))
+	for key, value := range job {
+		if !isTemplateJobField(key) {
+			fields[key] = value
+		}
+	}
+	if len(params) > 0 {
+		fields["config"] = mergeMaps(params, config)
+	}
+	return mergeMaps(template, fields), nil
+}
+
+// definedTemplates lists the names of the templates for an error message
+func definedTemplates(templates map[string]interface{}) string {
+	if len(templates) == 0 {
+		return " (no templates are defined)"
+	}
+	names := make([]string, 0, len(templates))
+	for name := range templates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return fmt.Sprintf(" (defined: %s)", strings.Join(names, ", "))
+}
@@ -0,0 +1,153 @@
+package config
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// templatesPlan defines a deploy template used by jobs of two stages, a rollback
+// stage and the onFailure hook
+const templatesPlan = `
+apiVersion: v1
+kind: ReleasePlan
+metadata:
+  name: web
+variables:
+  namespace: production
+templates:
+  deploy:
+    type: kubernetes
+    timeout: 5m
+    retries: 2
+    config:
+      action: apply
+      namespace: ${variables.namespace}
+      resource:
+        kind: deployment
+stages:
+  - name: api
+    jobs:
+      - name: deploy-api
+        uses: deploy
+        with:
+          resource:
+            name: api
+          replicas: 3
+  - name: web
+    dependsOn: [api]
+    jobs:
+      - name: deploy-web
+        uses: deploy
+        timeout: 10m
+        with:
+          resource:
+            name: web
+        config:
+          namespace: edge
+rollback:
+  stages:
+    - name: undo
+      jobs:
+        - name: undo-api
+          uses: deploy
+          with:
+            action: rollback
+onFailure:
+  - name: rollout-status
+    uses: deploy
+    with:
+      action: status
+  - name: page
+    type: http
+    dependsOn: [{job: rollout-status, on: always}]
+    config:
+      url: https://pager.example.com
+`
+
+func TestLoadPlanTemplates(t *testing.T) {
+	loader := NewLoader()
+	loader.SetStrict(true)
+	dir := writeFiles(t, map[string]string{"plan.yaml": templatesPlan})
+
+	plan, err := loader.LoadPlan(filepath.Join(dir, "plan.yaml"))
+	if err != nil {
+		t.Fatalf("Failed to load plan: %v", err)
+	}
+
+	api := plan.Stages[0].Jobs[0]
+	if api.Type != "kubernetes" || api.Timeout != "5m" || api.Retries != 2 {
+		t.Errorf("Expected the template's type, timeout and retries, got %+v", api)
+	}
+	resource, _ := api.Config["resource"].(map[string]interface{})
+	if resource["kind"] != "deployment" || resource["name"] != "api" || api.Config["replicas"] != 3 || api.Config["namespace"] != "production" {
+		t.Errorf("Expected parameters merged into the template's resolved config, got %v", api.Config)
+	}
+
+	web := plan.Stages[1].Jobs[0]
+	if web.Timeout != "10m" || web.Config["namespace"] != "edge" || web.Config["action"] != "apply" {
+		t.Errorf("Expected job fields and config to override the template, got %+v", web)
+	}
+	if _, exists := web.Config["replicas"]; exists {
+		t.Errorf("Expected parameters of other jobs not to leak, got %v", web.Config)
+	}
+
+	undo := plan.Rollback.Stages[0].Jobs[0]
+	if undo.Type != "kubernetes" || undo.Config["action"] != "rollback" {
+		t.Errorf("Expected the rollback job to be expanded, got %+v", undo)
+	}
+
+	if len(plan.OnFailure) != 2 {
+		t.Fatalf("Expected 2 onFailure jobs, got %+v", plan.OnFailure)
+	}
+	status, page := plan.OnFailure[0], plan.OnFailure[1]
+	if status.Type != "kubernetes" || status.Config["action"] != "status" {
+		t.Errorf("Expected the onFailure job to be expanded, got %+v", status)
+	}
+	if len(page.DependsOn) != 1 || page.DependsOn[0] != "rollout-status" || page.DependsOnConditions["rollout-status"] != "always" {
+		t.Errorf("Expected the condition of the onFailure dependency to be split off, got %+v", page)
+	}
+}
+
+func TestLoadPlanTemplateErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		job    string
+		errMsg string
+	}{
+		{
+			name:   "undefined template",
+			job:    "{name: job, uses: deploi}",
+			errMsg: "stage build: job job: uses undefined template deploi (defined: shell)",
+		},
+		{
+			name:   "parameter collision",
+			job:    "{name: job, uses: shell, with: {command: a, env: {A: b}}, config: {env: {}, command: b}}",
+			errMsg: "stage build: job job: parameters command, env are also set in config",
+		},
+		{
+			name:   "with is not a map",
+			job:    "{name: job, uses: shell, with: [a]}",
+			errMsg: "with must be a map of parameters",
+		},
+		{
+			name:   "uses is not a name",
+			job:    "{name: job, uses: {template: shell}}",
+			errMsg: "uses must be the name of a template",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan := "apiVersion: v1\nkind: ReleasePlan\nmetadata:\n  name: test\n" +
+				"templates:\n  shell:\n    type: shell\n    config: {command: echo}\n" +
+				"stages:\n  - name: build\n    jobs:\n      - " + tt.job + "\n"
+			dir := writeFiles(t, map[string]string{"plan.yaml": plan})
+
+			_, err := NewLoader().LoadPlan(filepath.Join(dir, "plan.yaml"))
+			if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+				t.Errorf("Expected error containing %q, got %v", tt.errMsg, err)
+			}
+		})
+	}
+}
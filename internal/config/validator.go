@@ -2,20 +2,60 @@ package config
 
 import (
 	"fmt"
+	"sort"
+	"strings"
+	"time"
 
 	"github.com/cuongtl1992/grp-cli/internal/models"
+	"github.com/cuongtl1992/grp-cli/internal/notify"
 )
 
 // Validator handles validation of release plans
 type Validator struct{}
 
+// ValidationError describes why a plan is invalid. Field is the path of the
+// offending field, e.g. "stage[deploy].job[build].timeout", and Stage and Job
+// name the stage and job it belongs to, if any. Err is the underlying cause, if any.
+type ValidationError struct {
+	Field  string
+	Stage  string
+	Job    string
+	Reason string
+	Err    error
+}
+
+// Error returns the field followed by the reason it is invalid
+func (e *ValidationError) Error() string {
+	message := e.Reason
+	if e.Field != "" {
+		message = e.Field + " " + message
+	}
+	if e.Err != nil {
+		message += ": " + e.Err.Error()
+	}
+	return message
+}
+
+func (e *ValidationError) Unwrap() error {
+	return e.Err
+}
+
+// within nests the field of the error under path, in the given stage and job
+func (e *ValidationError) within(path, stage, job string) *ValidationError {
+	e.Field = path + "." + e.Field
+	e.Stage = stage
+	e.Job = job
+	return e
+}
+
 // NewValidator creates a new validator
 func NewValidator() *Validator {
 	return &Validator{}
 }
 
-// checkCircularDependencies checks for circular dependencies in job dependencies
-func (v *Validator) checkCircularDependencies(jobs []models.Job) error {
+// checkCircularDependencies checks for circular dependencies between the jobs of a
+// stage; path is the field path of the stage and element that of its jobs
+func (v *Validator) checkCircularDependencies(path, element, stageName string, jobs []models.Job) error {
 	visited := make(map[string]bool)
 	stack := make(map[string]bool)
 
@@ -24,17 +64,28 @@ func (v *Validator) checkCircularDependencies(jobs []models.Job) error {
 		visited[job.Name] = true
 		stack[job.Name] = true
 
-		for _, depName := range job.DependsOn {
-			if !visited[depName] {
-				for _, j := range jobs {
-					if j.Name == depName {
-						if err := checkDeps(j); err != nil {
-							return err
+		// Optional dependencies order the jobs too, so they may not form a cycle either
+		for _, deps := range []struct {
+			field string
+			names []string
+		}{{"dependsOn", job.DependsOn}, {"optionalDependsOn", job.OptionalDependsOn}} {
+			for _, depName := range deps.names {
+				if !visited[depName] {
+					for _, j := range jobs {
+						if j.Name == depName {
+							if err := checkDeps(j); err != nil {
+								return err
+							}
 						}
 					}
+				} else if stack[depName] {
+					return &ValidationError{
+						Field:  fmt.Sprintf("%s.%s[%s].%s", path, element, job.Name, deps.field),
+						Stage:  stageName,
+						Job:    job.Name,
+						Reason: fmt.Sprintf("has a circular dependency: %s -> %s", job.Name, depName),
+					}
 				}
-			} else if stack[depName] {
-				return fmt.Errorf("circular dependency detected: %s -> %s", job.Name, depName)
 			}
 		}
 
@@ -53,116 +104,493 @@ func (v *Validator) checkCircularDependencies(jobs []models.Job) error {
 	return nil
 }
 
-// ValidatePlan checks if a plan is valid
+// validateStageJobs checks a list of jobs of a stage, e.g. its jobs or its pre
+// hooks; element names an entry of the list in field paths, e.g. "job". Jobs may
+// only depend on jobs of the same list, and names must be unique across the
+// lists of the stage, which share jobNames.
+func (v *Validator) validateStageJobs(path, stageName, element string, jobs []models.Job, jobNames map[string]bool) error {
+	listed := make(map[string]bool)
+	for j, job := range jobs {
+		if job.Name == "" {
+			return &ValidationError{Field: fmt.Sprintf("%s.%s[%d].name", path, element, j), Stage: stageName, Reason: "is required"}
+		}
+		
+		jobPath := fmt.Sprintf("%s.%s[%s]", path, element, job.Name)
+		if jobNames[job.Name] {
+			return &ValidationError{Field: jobPath + ".name", Stage: stageName, Job: job.Name, Reason: "is used by more than one job in the stage"}
+		}
+		jobNames[job.Name] = true
+		listed[job.Name] = true
+		
+		if job.Type == "" {
+			return &ValidationError{Field: jobPath + ".type", Stage: stageName, Job: job.Name, Reason: "is required"}
+		}
+		
+		if err := validateJobSettings(job); err != nil {
+			return err.within(jobPath, stageName, job.Name)
+		}
+		
+		// Validate job dependencies
+		for _, depName := range job.DependsOn {
+			if !listed[depName] {
+				return &ValidationError{Field: jobPath, Stage: stageName, Job: job.Name, Reason: "depends on unknown job: " + depName}
+			}
+		}
+		if err := validateDependencyConditions(job); err != nil {
+			return err.within(jobPath, stageName, job.Name)
+		}
+		
+		// Optional dependencies may name jobs that are not in the plan
+		for _, depName := range job.OptionalDependsOn {
+			if depName == job.Name {
+				return &ValidationError{Field: jobPath + ".optionalDependsOn", Stage: stageName, Job: job.Name, Reason: "cannot depend on itself"}
+			}
+		}
+	}
+	
+	// Check for circular dependencies between the jobs
+	return v.checkCircularDependencies(path, element, stageName, jobs)
+}
+
+// checkStageDependencies checks that stage dependencies reference known stages
+// and do not form a cycle
+func (v *Validator) checkStageDependencies(stages []models.Stage) error {
+	dependencies := make(map[string][]string)
+	for _, stage := range stages {
+		dependencies[stage.Name] = stage.DependsOn
+	}
+
+	for _, stage := range stages {
+		for _, depName := range stage.DependsOn {
+			if _, ok := dependencies[depName]; !ok {
+				return &ValidationError{Field: fmt.Sprintf("stage[%s]", stage.Name), Stage: stage.Name, Reason: "depends on unknown stage: " + depName}
+			}
+			if depName == stage.Name {
+				return &ValidationError{Field: fmt.Sprintf("stage[%s]", stage.Name), Stage: stage.Name, Reason: "cannot depend on itself"}
+			}
+		}
+	}
+
+	visited := make(map[string]bool)
+	stack := make(map[string]bool)
+
+	var checkDeps func(name string) error
+	checkDeps = func(name string) error {
+		visited[name] = true
+		stack[name] = true
+
+		for _, depName := range dependencies[name] {
+			if !visited[depName] {
+				if err := checkDeps(depName); err != nil {
+					return err
+				}
+			} else if stack[depName] {
+				return &ValidationError{
+					Field:  fmt.Sprintf("stage[%s].dependsOn", name),
+					Stage:  name,
+					Reason: fmt.Sprintf("has a circular stage dependency: %s -> %s", name, depName),
+				}
+			}
+		}
+
+		stack[name] = false
+		return nil
+	}
+
+	for _, stage := range stages {
+		if !visited[stage.Name] {
+			if err := checkDeps(stage.Name); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// ValidatePlan checks if a plan is valid, with the checks of its kind. The
+// returned error is a *ValidationError.
 func (v *Validator) ValidatePlan(plan *models.Plan) error {
 	if plan == nil {
-		return fmt.Errorf("plan cannot be nil")
+		return &ValidationError{Reason: "plan cannot be nil"}
 	}
 
 	// Check required fields
 	if plan.APIVersion == "" {
-		return fmt.Errorf("apiVersion is required")
+		return &ValidationError{Field: "apiVersion", Reason: "is required"}
+	}
+	
+	if plan.APIVersion != CurrentAPIVersion {
+		return &ValidationError{Field: "apiVersion", Reason: fmt.Sprintf("%q is not supported (expected %s; migrate older plans with grp-cli migrate)", plan.APIVersion, CurrentAPIVersion)}
 	}
 	
 	if plan.Kind == "" {
-		return fmt.Errorf("kind is required")
+		return &ValidationError{Field: "kind", Reason: "is required"}
+	}
+	
+	kind, err := LookupKind(plan.Kind)
+	if err != nil {
+		return &ValidationError{Field: "kind", Reason: fmt.Sprintf("%q is not supported (supported: %s)", plan.Kind, strings.Join(KindNames(), ", "))}
 	}
 	
 	if plan.Metadata.Name == "" {
-		return fmt.Errorf("metadata.name is required")
+		return &ValidationError{Field: "metadata.name", Reason: "is required"}
 	}
 	
+	return kind.Validate(v, plan)
+}
+
+// validateReleasePlan checks the stages, rollback, hooks and notifications of a ReleasePlan
+func validateReleasePlan(v *Validator, plan *models.Plan) error {
 	if len(plan.Stages) == 0 {
-		return fmt.Errorf("at least one stage is required")
+		return &ValidationError{Field: "stages", Reason: "must have at least one stage"}
 	}
 	
-	// Validate stages
+	if err := validateExecution(plan); err != nil {
+		return err
+	}
+	if err := v.validateStages(plan.Stages); err != nil {
+		return err
+	}
+	if err := v.validateRollback(plan.Rollback); err != nil {
+		return err
+	}
+	if err := v.validatePlanHooks(plan); err != nil {
+		return err
+	}
+	return validateNotifications(plan.Notifications)
+}
+
+// validatePlanHooks checks the onSuccess and onFailure jobs of a plan like the
+// jobs of a stage. Each hook runs on its own, so their job names are separate.
+func (v *Validator) validatePlanHooks(plan *models.Plan) error {
+	for _, hook := range []struct {
+		name string
+		jobs []models.Job
+	}{{models.PlanHookOnSuccess, plan.OnSuccess}, {models.PlanHookOnFailure, plan.OnFailure}} {
+		if err := v.validateStageJobs(hook.name, "", "job", hook.jobs, make(map[string]bool)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// definedPlanHook returns the name of the first hook the plan defines, if any
+func definedPlanHook(plan *models.Plan) string {
+	switch {
+	case len(plan.OnSuccess) > 0:
+		return models.PlanHookOnSuccess
+	case len(plan.OnFailure) > 0:
+		return models.PlanHookOnFailure
+	}
+	return ""
+}
+
+// validateRollbackPlan checks a RollbackPlan, whose only steps are its rollback stages
+func validateRollbackPlan(v *Validator, plan *models.Plan) error {
+	if len(plan.Stages) > 0 {
+		return &ValidationError{Field: "stages", Reason: "are not allowed in a RollbackPlan; put its steps under rollback.stages"}
+	}
+	
+	if plan.Rollback == nil {
+		return &ValidationError{Field: "rollback", Reason: "is required"}
+	}
+	
+	if hook := definedPlanHook(plan); hook != "" {
+		return &ValidationError{Field: hook, Reason: "is not allowed in a RollbackPlan"}
+	}
+	
+	if err := validateExecution(plan); err != nil {
+		return err
+	}
+	if err := v.validateRollback(plan.Rollback); err != nil {
+		return err
+	}
+	return validateNotifications(plan.Notifications)
+}
+
+// validateLibrary checks a Library, which only shares variables with the plans including it
+func validateLibrary(v *Validator, plan *models.Plan) error {
+	if len(plan.Stages) > 0 {
+		return &ValidationError{Field: "stages", Reason: "are not allowed in a Library"}
+	}
+	
+	if plan.Rollback != nil {
+		return &ValidationError{Field: "rollback", Reason: "is not allowed in a Library"}
+	}
+	
+	if hook := definedPlanHook(plan); hook != "" {
+		return &ValidationError{Field: hook, Reason: "is not allowed in a Library"}
+	}
+	return nil
+}
+
+// validateExecution checks the plan-level execution settings
+func validateExecution(plan *models.Plan) error {
+	if plan.MaxParallel < 0 {
+		return &ValidationError{Field: "maxParallel", Reason: fmt.Sprintf("must not be negative, got %d", plan.MaxParallel)}
+	}
+	
+	if err := validateDuration(plan.Timeout); err != nil {
+		return &ValidationError{Field: "timeout", Reason: "is invalid", Err: err}
+	}
+	
+	names := make([]string, 0, len(plan.RateLimits))
+	for name := range plan.RateLimits {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if err := ValidateRateLimit(plan.RateLimits[name]); err != nil {
+			return &ValidationError{Field: fmt.Sprintf("rateLimits[%s]", name), Reason: "is invalid", Err: err}
+		}
+	}
+	
+	if err := ValidateStageFailure(plan.OnStageFailure); err != nil {
+		return &ValidationError{Field: "onStageFailure", Reason: err.Error()}
+	}
+	return nil
+}
+
+// ValidateStageFailure checks a behavior on stage failure; empty means the default
+func ValidateStageFailure(onStageFailure string) error {
+	switch onStageFailure {
+	case "", models.StageFailureAbort, models.StageFailureContinue, models.StageFailureRollbackAndAbort:
+		return nil
+	}
+	return fmt.Errorf("must be %q, %q or %q, got %q", models.StageFailureAbort, models.StageFailureContinue, models.StageFailureRollbackAndAbort, onStageFailure)
+}
+
+// ValidateRateLimit checks the rate limit of a plugin
+func ValidateRateLimit(limit models.RateLimit) error {
+	if limit.RequestsPerSecond <= 0 {
+		return fmt.Errorf("requestsPerSecond must be positive, got %g", limit.RequestsPerSecond)
+	}
+	if limit.Burst < 0 {
+		return fmt.Errorf("burst must not be negative, got %d", limit.Burst)
+	}
+	return nil
+}
+
+// validateStages checks the stages of a plan and the dependencies between them
+func (v *Validator) validateStages(stages []models.Stage) error {
 	stageNames := make(map[string]bool)
-	for i, stage := range plan.Stages {
+	for i, stage := range stages {
 		if stage.Name == "" {
-			return fmt.Errorf("stage[%d].name is required", i)
+			return &ValidationError{Field: fmt.Sprintf("stage[%d].name", i), Reason: "is required"}
 		}
 		
 		if stageNames[stage.Name] {
-			return fmt.Errorf("duplicate stage name: %s", stage.Name)
+			return &ValidationError{Field: fmt.Sprintf("stage[%s].name", stage.Name), Stage: stage.Name, Reason: "is used by more than one stage"}
 		}
 		stageNames[stage.Name] = true
 		
 		if len(stage.Jobs) == 0 {
-			return fmt.Errorf("stage[%s] must have at least one job", stage.Name)
+			return &ValidationError{Field: fmt.Sprintf("stage[%s].jobs", stage.Name), Stage: stage.Name, Reason: "must have at least one job"}
 		}
 		
-		// Validate jobs
-		jobNames := make(map[string]bool)
-		for j, job := range stage.Jobs {
-			if job.Name == "" {
-				return fmt.Errorf("stage[%s].job[%d].name is required", stage.Name, j)
-			}
-			
-			if jobNames[job.Name] {
-				return fmt.Errorf("duplicate job name in stage %s: %s", stage.Name, job.Name)
-			}
-			jobNames[job.Name] = true
-			
-			if job.Type == "" {
-				return fmt.Errorf("stage[%s].job[%s].type is required", stage.Name, job.Name)
+		if stage.MaxParallel < 0 {
+			return &ValidationError{Field: fmt.Sprintf("stage[%s].maxParallel", stage.Name), Stage: stage.Name, Reason: fmt.Sprintf("must not be negative, got %d", stage.MaxParallel)}
+		}
+		
+		if err := validateDuration(stage.ApprovalTimeout); err != nil {
+			return &ValidationError{Field: fmt.Sprintf("stage[%s].approvalTimeout", stage.Name), Stage: stage.Name, Reason: "is invalid", Err: err}
+		}
+		
+		switch stage.OnApprovalTimeout {
+		case "", models.ApprovalTimeoutExpire, models.ApprovalTimeoutReject:
+		default:
+			return &ValidationError{
+				Field:  fmt.Sprintf("stage[%s].onApprovalTimeout", stage.Name),
+				Stage:  stage.Name,
+				Reason: fmt.Sprintf("must be %q or %q, got %q", models.ApprovalTimeoutExpire, models.ApprovalTimeoutReject, stage.OnApprovalTimeout),
 			}
-			
-			// Validate job dependencies
-			for _, depName := range job.DependsOn {
-				if !jobNames[depName] {
-					return fmt.Errorf("stage[%s].job[%s] depends on unknown job: %s", stage.Name, job.Name, depName)
-				}
+		}
+		
+		if stage.MaxFailures < 0 {
+			return &ValidationError{Field: fmt.Sprintf("stage[%s].maxFailures", stage.Name), Stage: stage.Name, Reason: fmt.Sprintf("must not be negative, got %d", stage.MaxFailures)}
+		}
+		
+		if stage.MaxFailurePercent < 0 || stage.MaxFailurePercent > 100 {
+			return &ValidationError{Field: fmt.Sprintf("stage[%s].maxFailurePercent", stage.Name), Stage: stage.Name, Reason: fmt.Sprintf("must be between 0 and 100, got %d", stage.MaxFailurePercent)}
+		}
+		
+		// Validate the jobs and hooks, whose names share the stage's job outputs
+		jobNames := make(map[string]bool)
+		stagePath := fmt.Sprintf("stage[%s]", stage.Name)
+		for _, list := range []struct {
+			element string
+			jobs    []models.Job
+		}{{"preJob", stage.PreJobs}, {"job", stage.Jobs}, {"postJob", stage.PostJobs}} {
+			if err := v.validateStageJobs(stagePath, stage.Name, list.element, list.jobs, jobNames); err != nil {
+				return err
 			}
 		}
-
-		// Check for circular dependencies in each stage
-		if err := v.checkCircularDependencies(stage.Jobs); err != nil {
-			return fmt.Errorf("in stage %s: %w", stage.Name, err)
+		
+		// Rollback jobs run on their own, after the stage failed
+		if err := v.validateStageJobs(stagePath, stage.Name, "rollback", stage.Rollback, make(map[string]bool)); err != nil {
+			return err
 		}
 	}
 	
-	// Validate rollback if present
-	if plan.Rollback != nil {
-		if len(plan.Rollback.Stages) == 0 {
-			return fmt.Errorf("rollback must have at least one stage")
+	// Validate stage dependencies
+	return v.checkStageDependencies(stages)
+}
+
+// validateRollback checks the rollback section of a plan, if present
+func (v *Validator) validateRollback(rollback *models.Rollback) error {
+	if rollback != nil {
+		if len(rollback.Stages) == 0 {
+			return &ValidationError{Field: "rollback.stages", Reason: "must have at least one stage"}
 		}
 		
 		// Validate rollback stages
-		for i, stage := range plan.Rollback.Stages {
+		rollbackNames := make(map[string]bool)
+		for i, stage := range rollback.Stages {
 			if stage.Name == "" {
-				return fmt.Errorf("rollback.stage[%d].name is required", i)
+				return &ValidationError{Field: fmt.Sprintf("rollback.stage[%d].name", i), Reason: "is required"}
+			}
+			
+			if rollbackNames[stage.Name] {
+				return &ValidationError{Field: fmt.Sprintf("rollback.stage[%s].name", stage.Name), Stage: stage.Name, Reason: "is used by more than one stage"}
+			}
+			rollbackNames[stage.Name] = true
+			
+			if stage.MaxParallel < 0 {
+				return &ValidationError{Field: fmt.Sprintf("rollback.stage[%s].maxParallel", stage.Name), Stage: stage.Name, Reason: fmt.Sprintf("must not be negative, got %d", stage.MaxParallel)}
 			}
 			
 			if len(stage.Jobs) == 0 {
-				return fmt.Errorf("rollback.stage[%s] must have at least one job", stage.Name)
+				return &ValidationError{Field: fmt.Sprintf("rollback.stage[%s].jobs", stage.Name), Stage: stage.Name, Reason: "must have at least one job"}
 			}
 			
-			// Validate rollback jobs
-			jobNames := make(map[string]bool)
-			for j, job := range stage.Jobs {
-				if job.Name == "" {
-					return fmt.Errorf("rollback.stage[%s].job[%d].name is required", stage.Name, j)
-				}
-				
-				if jobNames[job.Name] {
-					return fmt.Errorf("duplicate job name in rollback stage %s: %s", stage.Name, job.Name)
-				}
-				jobNames[job.Name] = true
-				
-				if job.Type == "" {
-					return fmt.Errorf("rollback.stage[%s].job[%s].type is required", stage.Name, job.Name)
-				}
-				
-				// Validate job dependencies
-				for _, depName := range job.DependsOn {
-					if !jobNames[depName] {
-						return fmt.Errorf("rollback.stage[%s].job[%s] depends on unknown job: %s", stage.Name, job.Name, depName)
-					}
-				}
+			if len(stage.PreJobs) > 0 || len(stage.PostJobs) > 0 || len(stage.Rollback) > 0 {
+				return &ValidationError{Field: fmt.Sprintf("rollback.stage[%s]", stage.Name), Stage: stage.Name, Reason: "cannot have preJobs, postJobs or rollback"}
+			}
+			
+			// Validate rollback jobs like the jobs of a stage
+			if err := v.validateStageJobs(fmt.Sprintf("rollback.stage[%s]", stage.Name), stage.Name, "job", stage.Jobs, make(map[string]bool)); err != nil {
+				return err
 			}
 		}
+		
+		// Rollback stages depend on each other like the plan's stages
+		if err, ok := v.checkStageDependencies(rollback.Stages).(*ValidationError); ok {
+			return err.within("rollback", err.Stage, "")
+		}
+	}
+	
+	return nil
+}
+
+// validateNotifications checks the notification channels of a plan, if any
+func validateNotifications(notifications *models.Notifications) error {
+	if notifications != nil && notifications.Slack != nil {
+		if err := validateSlackNotification(notifications.Slack); err != nil {
+			return err.within("notifications.slack", "", "")
+		}
+	}
+	
+	return nil
+}
+
+// validateSlackNotification checks the webhook, events and template of a Slack notification.
+// The field of the returned error is relative to the notification.
+func validateSlackNotification(slack *models.SlackNotification) *ValidationError {
+	if slack.WebhookURL == "" {
+		return &ValidationError{Field: "webhookUrl", Reason: "is required"}
+	}
+	
+	for _, event := range slack.Events {
+		switch event {
+		case models.NotifyOnStart, models.NotifyOnSuccess, models.NotifyOnFailure, models.NotifyOnApproval:
+		default:
+			return &ValidationError{Field: "events", Reason: fmt.Sprintf("contains unknown event %q (expected start, success, failure or approval)", event)}
+		}
+	}
+	
+	if _, err := notify.ParseTemplate(slack.Template); err != nil {
+		return &ValidationError{Field: "template", Reason: "is invalid", Err: err}
+	}
+	return nil
+}
+
+// validateJobSettings checks the execution settings of a job (timeout and retries).
+// The field of the returned error is relative to the job.
+func validateJobSettings(job models.Job) *ValidationError {
+	if err := validateDuration(job.Timeout); err != nil {
+		return &ValidationError{Field: "timeout", Reason: "is invalid", Err: err}
+	}
+
+	if job.Retries < 0 {
+		return &ValidationError{Field: "retries", Reason: fmt.Sprintf("must not be negative, got %d", job.Retries)}
+	}
+
+	switch job.RetryStrategy {
+	case "", models.RetryStrategyExponential, models.RetryStrategyFixed:
+	default:
+		return &ValidationError{Field: "retryStrategy", Reason: fmt.Sprintf("must be %q or %q, got %q", models.RetryStrategyExponential, models.RetryStrategyFixed, job.RetryStrategy)}
+	}
+
+	if err := validateDuration(job.RetryDelay); err != nil {
+		return &ValidationError{Field: "retryDelay", Reason: "is invalid", Err: err}
+	}
+
+	if job.RetryBackoff != 0 && job.RetryBackoff < 1 {
+		return &ValidationError{Field: "retryBackoff", Reason: fmt.Sprintf("must be at least 1, got %g", job.RetryBackoff)}
+	}
+
+	if job.RetryJitter < 0 || job.RetryJitter > 1 {
+		return &ValidationError{Field: "retryJitter", Reason: fmt.Sprintf("must be between 0 and 1, got %g", job.RetryJitter)}
+	}
+
+	if err := validateDuration(job.RetryMaxElapsed); err != nil {
+		return &ValidationError{Field: "retryMaxElapsed", Reason: "is invalid", Err: err}
 	}
+
+	return nil
+}
+
+// validateDependencyConditions checks the conditions of the dependencies of a job,
+// in the order of their names
+func validateDependencyConditions(job models.Job) *ValidationError {
+	names := make([]string, 0, len(job.DependsOnConditions))
+	for name := range job.DependsOnConditions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
 	
+	dependsOn := make(map[string]bool, len(job.DependsOn))
+	for _, name := range job.DependsOn {
+		dependsOn[name] = true
+	}
+	for _, name := range names {
+		field := fmt.Sprintf("dependsOn[%s].on", name)
+		if !dependsOn[name] {
+			return &ValidationError{Field: field, Reason: "is set for a job that is not in dependsOn"}
+		}
+		switch condition := job.DependsOnConditions[name]; condition {
+		case models.DependencyOnSuccess, models.DependencyOnFailure, models.DependencyOnAlways:
+		default:
+			return &ValidationError{Field: field, Reason: fmt.Sprintf("must be %q, %q or %q, got %q", models.DependencyOnSuccess, models.DependencyOnFailure, models.DependencyOnAlways, condition)}
+		}
+	}
 	return nil
-}
\ No newline at end of file
+}
+
+// validateDuration checks that a duration string, if set, is a positive duration
+func validateDuration(value string) error {
+	if value == "" {
+		return nil
+	}
+
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		return err
+	}
+	if duration <= 0 {
+		return fmt.Errorf("must be greater than zero, got %s", value)
+	}
+
+	return nil
+}
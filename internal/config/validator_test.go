@@ -1,6 +1,8 @@
 package config
 
 import (
+	"errors"
+	"strings"
 	"testing"
 
 	"github.com/cuongtl1992/grp-cli/internal/models"
@@ -36,6 +38,19 @@ func TestValidatePlan(t *testing.T) {
 			},
 			wantErr: false,
 		},
+		{
+			name: "optional dependency on a job not in the plan",
+			plan: &models.Plan{
+				APIVersion: "v1",
+				Kind:       "ReleasePlan",
+				Metadata:   models.Metadata{Name: "test-plan"},
+				Stages: []models.Stage{{
+					Name: "test-stage",
+					Jobs: []models.Job{{Name: "deploy", Type: "shell", OptionalDependsOn: []string{"migrate"}}},
+				}},
+			},
+			wantErr: false,
+		},
 		{
 			name: "missing apiVersion",
 			plan: &models.Plan{
@@ -153,6 +168,231 @@ func TestValidatePlan(t *testing.T) {
 			},
 			wantErr: true,
 		},
+		{
+			name: "invalid job timeout",
+			plan: &models.Plan{
+				APIVersion: "v1",
+				Kind:       "ReleasePlan",
+				Metadata: models.Metadata{
+					Name: "test-plan",
+				},
+				Stages: []models.Stage{
+					{
+						Name: "test-stage",
+						Jobs: []models.Job{
+							{
+								Name:    "test-job",
+								Type:    "test-type",
+								Timeout: "thirty seconds",
+							},
+						},
+					},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid retry strategy",
+			plan: &models.Plan{
+				APIVersion: "v1",
+				Kind:       "ReleasePlan",
+				Metadata: models.Metadata{
+					Name: "test-plan",
+				},
+				Stages: []models.Stage{
+					{
+						Name: "test-stage",
+						Jobs: []models.Job{
+							{
+								Name:          "test-job",
+								Type:          "test-type",
+								Retries:       2,
+								RetryStrategy: "linear",
+							},
+						},
+					},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "retry jitter out of range",
+			plan: &models.Plan{
+				APIVersion: "v1",
+				Kind:       "ReleasePlan",
+				Metadata: models.Metadata{
+					Name: "test-plan",
+				},
+				Stages: []models.Stage{
+					{
+						Name: "test-stage",
+						Jobs: []models.Job{
+							{
+								Name:        "test-job",
+								Type:        "test-type",
+								Retries:     2,
+								RetryJitter: 1.5,
+							},
+						},
+					},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "unknown stage dependency",
+			plan: &models.Plan{
+				APIVersion: "v1",
+				Kind:       "ReleasePlan",
+				Metadata: models.Metadata{
+					Name: "test-plan",
+				},
+				Stages: []models.Stage{
+					{
+						Name:      "deploy",
+						DependsOn: []string{"build"},
+						Jobs:      []models.Job{{Name: "job1", Type: "type1"}},
+					},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "circular stage dependencies",
+			plan: &models.Plan{
+				APIVersion: "v1",
+				Kind:       "ReleasePlan",
+				Metadata: models.Metadata{
+					Name: "test-plan",
+				},
+				Stages: []models.Stage{
+					{
+						Name:      "build",
+						DependsOn: []string{"deploy"},
+						Jobs:      []models.Job{{Name: "job1", Type: "type1"}},
+					},
+					{
+						Name:      "deploy",
+						DependsOn: []string{"build"},
+						Jobs:      []models.Job{{Name: "job2", Type: "type2"}},
+					},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "parallel stages",
+			plan: &models.Plan{
+				APIVersion: "v1",
+				Kind:       "ReleasePlan",
+				Metadata: models.Metadata{
+					Name: "test-plan",
+				},
+				Stages: []models.Stage{
+					{Name: "build-api", Jobs: []models.Job{{Name: "job1", Type: "type1"}}},
+					{Name: "build-web", Jobs: []models.Job{{Name: "job2", Type: "type2"}}},
+					{
+						Name:      "deploy",
+						DependsOn: []string{"build-api", "build-web"},
+						Jobs:      []models.Job{{Name: "job3", Type: "type3"}},
+					},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "invalid failure budget",
+			plan: &models.Plan{
+				APIVersion: "v1",
+				Kind:       "ReleasePlan",
+				Metadata: models.Metadata{
+					Name: "test-plan",
+				},
+				Stages: []models.Stage{
+					{Name: "deploy", MaxFailurePercent: 150, Jobs: []models.Job{{Name: "job1", Type: "type1"}}},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid plan timeout",
+			plan: &models.Plan{
+				APIVersion: "v1",
+				Kind:       "ReleasePlan",
+				Metadata: models.Metadata{
+					Name: "test-plan",
+				},
+				Timeout: "-5m",
+				Stages: []models.Stage{
+					{Name: "deploy", Jobs: []models.Job{{Name: "job1", Type: "type1"}}},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid approval timeout",
+			plan: &models.Plan{
+				APIVersion: "v1",
+				Kind:       "ReleasePlan",
+				Metadata: models.Metadata{
+					Name: "test-plan",
+				},
+				Stages: []models.Stage{
+					{Name: "deploy", RequireApproval: true, ApprovalTimeout: "soon", Jobs: []models.Job{{Name: "job1", Type: "type1"}}},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "unknown approval timeout action",
+			plan: &models.Plan{
+				APIVersion: "v1",
+				Kind:       "ReleasePlan",
+				Metadata: models.Metadata{
+					Name: "test-plan",
+				},
+				Stages: []models.Stage{
+					{Name: "deploy", RequireApproval: true, ApprovalTimeout: "1h", OnApprovalTimeout: "approve", Jobs: []models.Job{{Name: "job1", Type: "type1"}}},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "unknown notification event",
+			plan: &models.Plan{
+				APIVersion: "v1",
+				Kind:       "ReleasePlan",
+				Metadata: models.Metadata{
+					Name: "test-plan",
+				},
+				Stages: []models.Stage{
+					{Name: "deploy", Jobs: []models.Job{{Name: "job1", Type: "type1"}}},
+				},
+				Notifications: &models.Notifications{Slack: &models.SlackNotification{
+					WebhookURL: "https://hooks.slack.com/services/T000/B000/XXX",
+					Events:     []string{"failure", "finished"},
+				}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid notification template",
+			plan: &models.Plan{
+				APIVersion: "v1",
+				Kind:       "ReleasePlan",
+				Metadata: models.Metadata{
+					Name: "test-plan",
+				},
+				Stages: []models.Stage{
+					{Name: "deploy", Jobs: []models.Job{{Name: "job1", Type: "type1"}}},
+				},
+				Notifications: &models.Notifications{Slack: &models.SlackNotification{
+					WebhookURL: "https://hooks.slack.com/services/T000/B000/XXX",
+					Template:   "{{.Plan",
+				}},
+			},
+			wantErr: true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -164,3 +404,344 @@ func TestValidatePlan(t *testing.T) {
 		})
 	}
 }
+
+func TestValidatePlanKinds(t *testing.T) {
+	stages := []models.Stage{{Name: "deploy", Jobs: []models.Job{{Name: "build", Type: "shell"}}}}
+	rollback := &models.Rollback{Stages: []models.Stage{{Name: "undo", Jobs: []models.Job{{Name: "revert", Type: "shell"}}}}}
+	hook := []models.Job{{Name: "page", Type: "http"}}
+
+	tests := []struct {
+		name      string
+		kind      string
+		stages    []models.Stage
+		rollback  *models.Rollback
+		onFailure []models.Job
+		message   string
+	}{
+		{name: "release plan", kind: "ReleasePlan", stages: stages, rollback: rollback},
+		{name: "release plan with hooks", kind: "ReleasePlan", stages: stages, onFailure: hook},
+		{name: "release plan without stages", kind: "ReleasePlan", rollback: rollback, message: "stages must have at least one stage"},
+		{name: "rollback plan", kind: "RollbackPlan", rollback: rollback},
+		{name: "rollback plan with stages", kind: "RollbackPlan", stages: stages, rollback: rollback, message: "stages are not allowed in a RollbackPlan; put its steps under rollback.stages"},
+		{name: "rollback plan without rollback", kind: "RollbackPlan", message: "rollback is required"},
+		{name: "rollback plan with hooks", kind: "RollbackPlan", rollback: rollback, onFailure: hook, message: "onFailure is not allowed in a RollbackPlan"},
+		{name: "library", kind: "Library"},
+		{name: "library with stages", kind: "Library", stages: stages, message: "stages are not allowed in a Library"},
+		{name: "library with rollback", kind: "Library", rollback: rollback, message: "rollback is not allowed in a Library"},
+		{name: "library with hooks", kind: "Library", onFailure: hook, message: "onFailure is not allowed in a Library"},
+		{name: "unsupported kind", kind: "Deployment", stages: stages, message: `kind "Deployment" is not supported (supported: Library, ReleasePlan, RollbackPlan)`},
+	}
+
+	validator := NewValidator()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan := &models.Plan{
+				APIVersion: "v1",
+				Kind:       tt.kind,
+				Metadata:   models.Metadata{Name: "test-plan"},
+				Stages:     tt.stages,
+				Rollback:   tt.rollback,
+				OnFailure:  tt.onFailure,
+			}
+
+			err := validator.ValidatePlan(plan)
+			if tt.message == "" {
+				if err != nil {
+					t.Errorf("ValidatePlan() error = %v", err)
+				}
+				return
+			}
+			if err == nil || err.Error() != tt.message {
+				t.Errorf("Expected error %q, got %v", tt.message, err)
+			}
+		})
+	}
+}
+
+func TestValidatePlanErrorFields(t *testing.T) {
+	newPlan := func(jobs ...models.Job) *models.Plan {
+		return &models.Plan{
+			APIVersion: "v1",
+			Kind:       "ReleasePlan",
+			Metadata:   models.Metadata{Name: "test-plan"},
+			Stages:     []models.Stage{{Name: "deploy", Jobs: jobs}},
+		}
+	}
+
+	tests := []struct {
+		name     string
+		plan     *models.Plan
+		expected ValidationError
+		message  string
+	}{
+		{
+			name:     "plan field",
+			plan:     &models.Plan{Kind: "ReleasePlan"},
+			expected: ValidationError{Field: "apiVersion", Reason: "is required"},
+			message:  "apiVersion is required",
+		},
+		{
+			name:     "older apiVersion",
+			plan:     &models.Plan{APIVersion: "devops.release/v1", Kind: "ReleasePlan"},
+			expected: ValidationError{Field: "apiVersion", Reason: `"devops.release/v1" is not supported (expected v1; migrate older plans with grp-cli migrate)`},
+			message:  `apiVersion "devops.release/v1" is not supported (expected v1; migrate older plans with grp-cli migrate)`,
+		},
+		{
+			name:     "job setting",
+			plan:     newPlan(models.Job{Name: "build", Type: "shell", Retries: -1}),
+			expected: ValidationError{Field: "stage[deploy].job[build].retries", Stage: "deploy", Job: "build", Reason: "must not be negative, got -1"},
+			message:  "stage[deploy].job[build].retries must not be negative, got -1",
+		},
+		{
+			name:     "unknown job dependency",
+			plan:     newPlan(models.Job{Name: "build", Type: "shell", DependsOn: []string{"test"}}),
+			expected: ValidationError{Field: "stage[deploy].job[build]", Stage: "deploy", Job: "build", Reason: "depends on unknown job: test"},
+			message:  "stage[deploy].job[build] depends on unknown job: test",
+		},
+		{
+			name: "unknown dependency condition",
+			plan: newPlan(
+				models.Job{Name: "build", Type: "shell"},
+				models.Job{Name: "cleanup", Type: "shell", DependsOn: []string{"build"}, DependsOnConditions: map[string]string{"build": "failed"}},
+			),
+			expected: ValidationError{Field: "stage[deploy].job[cleanup].dependsOn[build].on", Stage: "deploy", Job: "cleanup", Reason: `must be "success", "failure" or "always", got "failed"`},
+			message:  `stage[deploy].job[cleanup].dependsOn[build].on must be "success", "failure" or "always", got "failed"`,
+		},
+		{
+			name: "condition on a job not depended on",
+			plan: newPlan(
+				models.Job{Name: "build", Type: "shell"},
+				models.Job{Name: "cleanup", Type: "shell", DependsOnConditions: map[string]string{"build": "failure"}},
+			),
+			expected: ValidationError{Field: "stage[deploy].job[cleanup].dependsOn[build].on", Stage: "deploy", Job: "cleanup", Reason: "is set for a job that is not in dependsOn"},
+			message:  "stage[deploy].job[cleanup].dependsOn[build].on is set for a job that is not in dependsOn",
+		},
+		{
+			name:     "optional dependency on itself",
+			plan:     newPlan(models.Job{Name: "build", Type: "shell", OptionalDependsOn: []string{"build"}}),
+			expected: ValidationError{Field: "stage[deploy].job[build].optionalDependsOn", Stage: "deploy", Job: "build", Reason: "cannot depend on itself"},
+			message:  "stage[deploy].job[build].optionalDependsOn cannot depend on itself",
+		},
+		{
+			name: "circular optional dependency",
+			plan: newPlan(
+				models.Job{Name: "build", Type: "shell", OptionalDependsOn: []string{"test"}},
+				models.Job{Name: "test", Type: "shell", OptionalDependsOn: []string{"build"}},
+			),
+			expected: ValidationError{Field: "stage[deploy].job[test].optionalDependsOn", Stage: "deploy", Job: "test", Reason: "has a circular dependency: test -> build"},
+			message:  "stage[deploy].job[test].optionalDependsOn has a circular dependency: test -> build",
+		},
+		{
+			name: "hook named like a job",
+			plan: &models.Plan{
+				APIVersion: "v1",
+				Kind:       "ReleasePlan",
+				Metadata:   models.Metadata{Name: "test-plan"},
+				Stages: []models.Stage{{
+					Name:     "deploy",
+					Jobs:     []models.Job{{Name: "build", Type: "shell"}},
+					PostJobs: []models.Job{{Name: "build", Type: "shell"}},
+				}},
+			},
+			expected: ValidationError{Field: "stage[deploy].postJob[build].name", Stage: "deploy", Job: "build", Reason: "is used by more than one job in the stage"},
+			message:  "stage[deploy].postJob[build].name is used by more than one job in the stage",
+		},
+		{
+			name: "hook depending on a job",
+			plan: &models.Plan{
+				APIVersion: "v1",
+				Kind:       "ReleasePlan",
+				Metadata:   models.Metadata{Name: "test-plan"},
+				Stages: []models.Stage{{
+					Name:    "deploy",
+					PreJobs: []models.Job{{Name: "maintenance-on", Type: "http", DependsOn: []string{"build"}}},
+					Jobs:    []models.Job{{Name: "build", Type: "shell"}},
+				}},
+			},
+			expected: ValidationError{Field: "stage[deploy].preJob[maintenance-on]", Stage: "deploy", Job: "maintenance-on", Reason: "depends on unknown job: build"},
+			message:  "stage[deploy].preJob[maintenance-on] depends on unknown job: build",
+		},
+		{
+			name: "plan hook job without type",
+			plan: &models.Plan{
+				APIVersion: "v1",
+				Kind:       "ReleasePlan",
+				Metadata:   models.Metadata{Name: "test-plan"},
+				Stages:     []models.Stage{{Name: "deploy", Jobs: []models.Job{{Name: "build", Type: "shell"}}}},
+				OnSuccess:  []models.Job{{Name: "tag"}},
+			},
+			expected: ValidationError{Field: "onSuccess.job[tag].type", Job: "tag", Reason: "is required"},
+			message:  "onSuccess.job[tag].type is required",
+		},
+		{
+			name: "plan hook job depending on a stage job",
+			plan: &models.Plan{
+				APIVersion: "v1",
+				Kind:       "ReleasePlan",
+				Metadata:   models.Metadata{Name: "test-plan"},
+				Stages:     []models.Stage{{Name: "deploy", Jobs: []models.Job{{Name: "build", Type: "shell"}}}},
+				OnFailure:  []models.Job{{Name: "page", Type: "http", DependsOn: []string{"build"}}},
+			},
+			expected: ValidationError{Field: "onFailure.job[page]", Job: "page", Reason: "depends on unknown job: build"},
+			message:  "onFailure.job[page] depends on unknown job: build",
+		},
+		{
+			name: "hook in a rollback stage",
+			plan: &models.Plan{
+				APIVersion: "v1",
+				Kind:       "ReleasePlan",
+				Metadata:   models.Metadata{Name: "test-plan"},
+				Stages:     []models.Stage{{Name: "deploy", Jobs: []models.Job{{Name: "build", Type: "shell"}}}},
+				Rollback: &models.Rollback{Stages: []models.Stage{{
+					Name:    "undo",
+					PreJobs: []models.Job{{Name: "notify", Type: "http"}},
+					Jobs:    []models.Job{{Name: "revert", Type: "shell"}},
+				}}},
+			},
+			expected: ValidationError{Field: "rollback.stage[undo]", Stage: "undo", Reason: "cannot have preJobs, postJobs or rollback"},
+			message:  "rollback.stage[undo] cannot have preJobs, postJobs or rollback",
+		},
+		{
+			name: "stage rollback job without type",
+			plan: &models.Plan{
+				APIVersion: "v1",
+				Kind:       "ReleasePlan",
+				Metadata:   models.Metadata{Name: "test-plan"},
+				Stages: []models.Stage{{
+					Name:     "deploy",
+					Jobs:     []models.Job{{Name: "apply", Type: "shell"}},
+					Rollback: []models.Job{{Name: "revert"}},
+				}},
+			},
+			expected: ValidationError{Field: "stage[deploy].rollback[revert].type", Stage: "deploy", Job: "revert", Reason: "is required"},
+			message:  "stage[deploy].rollback[revert].type is required",
+		},
+		{
+			name: "duplicate rollback stage",
+			plan: &models.Plan{
+				APIVersion: "v1",
+				Kind:       "ReleasePlan",
+				Metadata:   models.Metadata{Name: "test-plan"},
+				Stages:     []models.Stage{{Name: "deploy", Jobs: []models.Job{{Name: "build", Type: "shell"}}}},
+				Rollback: &models.Rollback{Stages: []models.Stage{
+					{Name: "undo", Jobs: []models.Job{{Name: "revert", Type: "shell"}}},
+					{Name: "undo", Jobs: []models.Job{{Name: "notify", Type: "http"}}},
+				}},
+			},
+			expected: ValidationError{Field: "rollback.stage[undo].name", Stage: "undo", Reason: "is used by more than one stage"},
+			message:  "rollback.stage[undo].name is used by more than one stage",
+		},
+		{
+			name: "rollback stage depends on unknown stage",
+			plan: &models.Plan{
+				APIVersion: "v1",
+				Kind:       "ReleasePlan",
+				Metadata:   models.Metadata{Name: "test-plan"},
+				Stages:     []models.Stage{{Name: "deploy", Jobs: []models.Job{{Name: "build", Type: "shell"}}}},
+				Rollback: &models.Rollback{Stages: []models.Stage{
+					{Name: "undo", DependsOn: []string{"deploy"}, Jobs: []models.Job{{Name: "revert", Type: "shell"}}},
+				}},
+			},
+			expected: ValidationError{Field: "rollback.stage[undo]", Stage: "undo", Reason: "depends on unknown stage: deploy"},
+			message:  "rollback.stage[undo] depends on unknown stage: deploy",
+		},
+		{
+			name: "invalid rate limit",
+			plan: func() *models.Plan {
+				plan := newPlan(models.Job{Name: "build", Type: "shell"})
+				plan.RateLimits = map[string]models.RateLimit{"kubernetes": {RequestsPerSecond: 5}, "http": {RequestsPerSecond: 0, Burst: 3}}
+				return plan
+			}(),
+			expected: ValidationError{Field: "rateLimits[http]", Reason: "is invalid"},
+			message:  "rateLimits[http] is invalid: requestsPerSecond must be positive, got 0",
+		},
+		{
+			name: "negative rate limit burst",
+			plan: func() *models.Plan {
+				plan := newPlan(models.Job{Name: "build", Type: "shell"})
+				plan.RateLimits = map[string]models.RateLimit{"kubernetes": {RequestsPerSecond: 0.5, Burst: -1}}
+				return plan
+			}(),
+			expected: ValidationError{Field: "rateLimits[kubernetes]", Reason: "is invalid"},
+			message:  "rateLimits[kubernetes] is invalid: burst must not be negative, got -1",
+		},
+		{
+			name: "unknown behavior on stage failure",
+			plan: func() *models.Plan {
+				plan := newPlan(models.Job{Name: "build", Type: "shell"})
+				plan.OnStageFailure = "retry"
+				return plan
+			}(),
+			expected: ValidationError{Field: "onStageFailure", Reason: `must be "abort", "continue" or "rollback-and-abort", got "retry"`},
+			message:  `onStageFailure must be "abort", "continue" or "rollback-and-abort", got "retry"`,
+		},
+		{
+			name: "circular rollback stage dependency",
+			plan: &models.Plan{
+				APIVersion: "v1",
+				Kind:       "ReleasePlan",
+				Metadata:   models.Metadata{Name: "test-plan"},
+				Stages:     []models.Stage{{Name: "deploy", Jobs: []models.Job{{Name: "build", Type: "shell"}}}},
+				Rollback: &models.Rollback{Stages: []models.Stage{
+					{Name: "undo-app", DependsOn: []string{"undo-db"}, Jobs: []models.Job{{Name: "revert", Type: "shell"}}},
+					{Name: "undo-db", DependsOn: []string{"undo-app"}, Jobs: []models.Job{{Name: "restore", Type: "shell"}}},
+				}},
+			},
+			expected: ValidationError{Field: "rollback.stage[undo-db].dependsOn", Stage: "undo-db", Reason: "has a circular stage dependency: undo-db -> undo-app"},
+			message:  "rollback.stage[undo-db].dependsOn has a circular stage dependency: undo-db -> undo-app",
+		},
+		{
+			name: "invalid rollback job settings",
+			plan: &models.Plan{
+				APIVersion: "v1",
+				Kind:       "ReleasePlan",
+				Metadata:   models.Metadata{Name: "test-plan"},
+				Stages:     []models.Stage{{Name: "deploy", Jobs: []models.Job{{Name: "build", Type: "shell"}}}},
+				Rollback: &models.Rollback{Stages: []models.Stage{{
+					Name: "undo",
+					Jobs: []models.Job{{Name: "revert", Type: "shell", Retries: -1}},
+				}}},
+			},
+			expected: ValidationError{Field: "rollback.stage[undo].job[revert].retries", Stage: "undo", Job: "revert", Reason: "must not be negative, got -1"},
+			message:  "rollback.stage[undo].job[revert].retries must not be negative, got -1",
+		},
+	}
+
+	validator := NewValidator()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validator.ValidatePlan(tt.plan)
+
+			var validationErr *ValidationError
+			if !errors.As(err, &validationErr) {
+				t.Fatalf("Expected a *ValidationError, got %T: %v", err, err)
+			}
+			if validationErr.Field != tt.expected.Field || validationErr.Stage != tt.expected.Stage ||
+				validationErr.Job != tt.expected.Job || validationErr.Reason != tt.expected.Reason {
+				t.Errorf("Expected %+v, got %+v", tt.expected, *validationErr)
+			}
+			if err.Error() != tt.message {
+				t.Errorf("Expected message %q, got %q", tt.message, err.Error())
+			}
+		})
+	}
+}
+
+func TestValidatePlanErrorCause(t *testing.T) {
+	plan := &models.Plan{
+		APIVersion: "v1",
+		Kind:       "ReleasePlan",
+		Metadata:   models.Metadata{Name: "test-plan"},
+		Timeout:    "soon",
+		Stages:     []models.Stage{{Name: "deploy", Jobs: []models.Job{{Name: "build", Type: "shell"}}}},
+	}
+
+	err := NewValidator().ValidatePlan(plan)
+	var validationErr *ValidationError
+	if !errors.As(err, &validationErr) || validationErr.Field != "timeout" || validationErr.Err == nil {
+		t.Fatalf("Expected a timeout error with its cause, got %v", err)
+	}
+	if !strings.HasPrefix(err.Error(), "timeout is invalid: time: invalid duration") {
+		t.Errorf("Expected the cause in the message, got %q", err.Error())
+	}
+}
@@ -0,0 +1,49 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ParseSetValue parses a "key.path=value" override into the nested variables it
+// sets, e.g. "image.tag=v2" into {"image": {"tag": "v2"}}. The value is a bool
+// for true or false, an int if it is one, and otherwise a string; quote it to
+// keep a number or bool as a string.
+func ParseSetValue(expression string) (map[string]interface{}, error) {
+	path, raw, found := strings.Cut(expression, "=")
+	if !found {
+		return nil, fmt.Errorf("invalid value %q (expected key.path=value)", expression)
+	}
+
+	keys := strings.Split(strings.TrimSpace(path), ".")
+	for _, key := range keys {
+		if key == "" {
+			return nil, fmt.Errorf("invalid key %q in %q", path, expression)
+		}
+	}
+
+	// Build the nested maps from the innermost key outwards
+	var value interface{} = parseScalar(raw)
+	for i := len(keys) - 1; i >= 0; i-- {
+		value = map[string]interface{}{keys[i]: value}
+	}
+	return value.(map[string]interface{}), nil
+}
+
+// parseScalar infers the type of a command-line value
+func parseScalar(raw string) interface{} {
+	if len(raw) >= 2 && (raw[0] == '"' || raw[0] == '\'') && raw[len(raw)-1] == raw[0] {
+		return raw[1 : len(raw)-1]
+	}
+	switch raw {
+	case "true":
+		return true
+	case "false":
+		return false
+	}
+	if number, err := strconv.Atoi(raw); err == nil {
+		return number
+	}
+	return raw
+}
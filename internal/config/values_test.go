@@ -0,0 +1,39 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseSetValue(t *testing.T) {
+	tests := []struct {
+		name       string
+		expression string
+		expected   map[string]interface{}
+		wantErr    bool
+	}{
+		{name: "string", expression: "region=eu-west-1", expected: map[string]interface{}{"region": "eu-west-1"}},
+		{name: "nested key", expression: "image.tag=abc123", expected: map[string]interface{}{"image": map[string]interface{}{"tag": "abc123"}}},
+		{name: "int", expression: "replicas=3", expected: map[string]interface{}{"replicas": 3}},
+		{name: "bool", expression: "canary.enabled=false", expected: map[string]interface{}{"canary": map[string]interface{}{"enabled": false}}},
+		{name: "quoted number stays a string", expression: `image.tag="123"`, expected: map[string]interface{}{"image": map[string]interface{}{"tag": "123"}}},
+		{name: "single quotes", expression: "flag='true'", expected: map[string]interface{}{"flag": "true"}},
+		{name: "value with equals sign", expression: "args=--level=debug", expected: map[string]interface{}{"args": "--level=debug"}},
+		{name: "empty value", expression: "suffix=", expected: map[string]interface{}{"suffix": ""}},
+		{name: "missing equals sign", expression: "replicas", wantErr: true},
+		{name: "empty key", expression: "image..tag=v1", wantErr: true},
+		{name: "no key", expression: "=v1", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			values, err := ParseSetValue(tt.expression)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseSetValue() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(values, tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, values)
+			}
+		})
+	}
+}
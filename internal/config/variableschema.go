@@ -0,0 +1,124 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/cuongtl1992/grp-cli/pkg/plugin"
+)
+
+// variableSchemaField is the top-level plan field declaring the type, default and
+// whether it is required of each variable; it is removed once the variables are checked
+const variableSchemaField = "variableSchema"
+
+// parseVariableSchema removes the variableSchema block from raw and decodes it as
+// the schema of the variables object. It returns nil if the plan has none.
+func parseVariableSchema(raw map[string]interface{}) (*plugin.JSONSchema, error) {
+	value, exists := raw[variableSchemaField]
+	delete(raw, variableSchemaField)
+	if !exists {
+		return nil, nil
+	}
+	if _, ok := value.(map[string]interface{}); !ok {
+		return nil, fmt.Errorf("variableSchema must be a map")
+	}
+
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid variableSchema: %w", err)
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	var schema plugin.JSONSchema
+	if err := decoder.Decode(&schema); err != nil {
+		return nil, fmt.Errorf("invalid variableSchema: %w", err)
+	}
+	if schema.Type == "" {
+		schema.Type = "object"
+	}
+	return &schema, nil
+}
+
+// checkVariables converts the resolved variables to the types of the schema where
+// that loses nothing, e.g. "3" from an environment variable to 3 for an integer,
+// and validates them against it
+func checkVariables(schema *plugin.JSONSchema, variables map[string]interface{}) (map[string]interface{}, error) {
+	coerced, _ := coerceValue(schema, variables).(map[string]interface{})
+
+	err := plugin.ValidateConfig(schema, coerced)
+	var configErr *plugin.ConfigError
+	if !errors.As(err, &configErr) {
+		return coerced, err
+	}
+
+	problems := make([]string, len(configErr.Problems))
+	missing := false
+	for i, problem := range configErr.Problems {
+		if name, found := strings.CutPrefix(problem, "missing required field "); found {
+			problem = "missing required variable " + name
+			missing = true
+		}
+		problems[i] = problem
+	}
+	message := "variables do not match variableSchema: " + strings.Join(problems, "; ")
+	if missing {
+		message += " (set them in the plan's variables, a --values file or with --set)"
+	}
+	return nil, errors.New(message)
+}
+
+// coerceValue returns value with the scalars whose type differs from their schema
+// converted to it when they can be, copying the objects and arrays it changes
+func coerceValue(schema *plugin.JSONSchema, value interface{}) interface{} {
+	if schema == nil {
+		return value
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		result := make(map[string]interface{}, len(v))
+		for name, propValue := range v {
+			result[name] = coerceValue(schema.Properties[name], propValue)
+		}
+		return result
+	case []interface{}:
+		result := make([]interface{}, len(v))
+		for i, item := range v {
+			result[i] = coerceValue(schema.Items, item)
+		}
+		return result
+	case string:
+		switch schema.Type {
+		case "integer":
+			if n, err := strconv.Atoi(strings.TrimSpace(v)); err == nil {
+				return n
+			}
+		case "number":
+			if n, err := strconv.Atoi(strings.TrimSpace(v)); err == nil {
+				return n
+			}
+			if f, err := strconv.ParseFloat(strings.TrimSpace(v), 64); err == nil {
+				return f
+			}
+		case "boolean":
+			switch strings.TrimSpace(v) {
+			case "true":
+				return true
+			case "false":
+				return false
+			}
+		}
+	case int, int64, float64, bool:
+		if schema.Type == "string" {
+			if f, ok := v.(float64); ok {
+				return strconv.FormatFloat(f, 'f', -1, 64)
+			}
+			return fmt.Sprint(v)
+		}
+	}
+	return value
+}
@@ -0,0 +1,109 @@
+package config
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// variableSchemaPlan declares an image to be set by the caller, replicas and a
+// region with defaults, and a debug flag
+const variableSchemaPlan = `
+apiVersion: v1
+kind: ReleasePlan
+metadata:
+  name: web
+variableSchema:
+  required: [image]
+  properties:
+    image:
+      type: string
+    replicas:
+      type: integer
+      default: 2
+    region:
+      type: string
+      default: eu-west-1
+    debug:
+      type: boolean
+variables:
+  debug: ${env.GRP_TEST_DEBUG}
+stages:
+  - name: deploy
+    jobs:
+      - name: app
+        type: kubernetes
+        config:
+          action: apply
+          replicas: ${variables.replicas}
+          region: ${variables.region}
+`
+
+func TestLoadPlanVariableSchema(t *testing.T) {
+	tests := []struct {
+		name      string
+		schema    string
+		values    map[string]interface{}
+		expected  map[string]interface{}
+		errMsg    string
+		errSuffix string
+	}{
+		{
+			name:     "defaults and coercion",
+			values:   map[string]interface{}{"image": 1.25, "replicas": "3"},
+			expected: map[string]interface{}{"image": "1.25", "replicas": 3, "region": "eu-west-1", "debug": true},
+		},
+		{
+			name:      "missing required variable",
+			values:    map[string]interface{}{"replicas": 3},
+			errMsg:    `invalid variables: variables do not match variableSchema: missing required variable "image"`,
+			errSuffix: "(set them in the plan's variables, a --values file or with --set)",
+		},
+		{
+			name:   "wrong type",
+			values: map[string]interface{}{"image": "web:1.0", "replicas": "three"},
+			errMsg: `invalid variables: variables do not match variableSchema: "replicas" must be integer, got string`,
+		},
+		{
+			name:   "misspelled schema field",
+			schema: "variableSchema:\n  requried: [image]\n",
+			errMsg: `invalid variableSchema: json: unknown field "requried"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("GRP_TEST_DEBUG", "true")
+			plan := variableSchemaPlan
+			if tt.schema != "" {
+				start := strings.Index(plan, "variableSchema:")
+				end := strings.Index(plan, "variables:")
+				plan = plan[:start] + tt.schema + plan[end:]
+			}
+			dir := writeFiles(t, map[string]string{"plan.yaml": plan})
+
+			loader := NewLoader()
+			loader.SetStrict(true)
+			loader.AddValues(tt.values)
+			loaded, err := loader.LoadPlan(filepath.Join(dir, "plan.yaml"))
+			if tt.errMsg != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errMsg) || !strings.HasSuffix(err.Error(), tt.errSuffix) {
+					t.Fatalf("Expected error containing %q, got %v", tt.errMsg, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Failed to load plan: %v", err)
+			}
+
+			if !reflect.DeepEqual(loaded.Variables, tt.expected) {
+				t.Errorf("Expected variables %v, got %v", tt.expected, loaded.Variables)
+			}
+			config := loaded.Stages[0].Jobs[0].Config
+			if config["replicas"] != 3 || config["region"] != "eu-west-1" {
+				t.Errorf("Expected jobs to reference the checked variables, got %v", config)
+			}
+		})
+	}
+}
@@ -0,0 +1,145 @@
+package engine
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/cuongtl1992/grp-cli/internal/models"
+	"github.com/cuongtl1992/grp-cli/pkg/plugin"
+)
+
+// ArtifactManifest is the name of the manifest written to the artifact directory
+// of an execution
+const ArtifactManifest = "artifacts.json"
+
+// artifactCollector gathers the artifacts returned by the jobs of an execution,
+// to be stored in dir if set. Jobs run in parallel, so access is guarded by a mutex.
+type artifactCollector struct {
+	mu        sync.Mutex
+	dir       string
+	artifacts []models.Artifact
+}
+
+// newArtifactCollector creates an empty artifact collector storing into dir
+func newArtifactCollector(dir string) *artifactCollector {
+	return &artifactCollector{dir: dir}
+}
+
+// Add records the artifacts returned by a job
+func (c *artifactCollector) Add(stage, job string, artifacts []plugin.Artifact) {
+	if c == nil || len(artifacts) == 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, artifact := range artifacts {
+		c.artifacts = append(c.artifacts, models.Artifact{
+			Stage:       stage,
+			Job:         job,
+			Name:        artifact.Name,
+			Type:        artifact.Type,
+			ContentType: artifact.ContentType,
+			Path:        artifact.Path,
+			Data:        artifact.Data,
+		})
+	}
+}
+
+// Artifacts returns a copy of the artifacts recorded so far
+func (c *artifactCollector) Artifacts() []models.Artifact {
+	if c == nil {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]models.Artifact(nil), c.artifacts...)
+}
+
+// storeArtifacts copies the artifacts into <dir>/<stage>/<job>/ and writes the
+// manifest to <dir>/artifacts.json. Files are copied and inline data is written
+// to a file named after the artifact. An artifact that cannot be stored keeps
+// its error; only failing to write the manifest is returned.
+func storeArtifacts(dir, executionID string, artifacts []models.Artifact) error {
+	manifest := struct {
+		ExecutionID string                       `json:"executionId"`
+		Jobs        map[string][]models.Artifact `json:"jobs"`
+	}{ExecutionID: executionID, Jobs: make(map[string][]models.Artifact)}
+
+	for i := range artifacts {
+		artifact := &artifacts[i]
+		if err := storeArtifact(dir, artifact); err != nil {
+			artifact.Error = err.Error()
+		}
+		key := artifact.Stage + "/" + artifact.Job
+		manifest.Jobs[key] = append(manifest.Jobs[key], *artifact)
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create artifact directory: %w", err)
+	}
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode artifact manifest: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, ArtifactManifest), data, 0o644); err != nil {
+		return fmt.Errorf("failed to write artifact manifest: %w", err)
+	}
+	return nil
+}
+
+// storeArtifact copies a file artifact or writes an inline one into the job's
+// directory, pointing the artifact at the stored file
+func storeArtifact(dir string, artifact *models.Artifact) error {
+	jobDir := filepath.Join(dir, pathSegment(artifact.Stage), pathSegment(artifact.Job))
+	if err := os.MkdirAll(jobDir, 0o755); err != nil {
+		return fmt.Errorf("failed to create artifact directory: %w", err)
+	}
+
+	if artifact.Path == "" {
+		name := artifact.Name
+		if name == "" {
+			name = "artifact"
+		}
+		target := filepath.Join(jobDir, pathSegment(name))
+		if err := os.WriteFile(target, artifact.Data, 0o644); err != nil {
+			return fmt.Errorf("failed to write artifact %s: %w", artifact.Name, err)
+		}
+		artifact.Path = target
+		artifact.Size = int64(len(artifact.Data))
+		artifact.Data = nil
+		return nil
+	}
+
+	target := filepath.Join(jobDir, filepath.Base(artifact.Path))
+	size, err := copyFile(artifact.Path, target)
+	if err != nil {
+		return fmt.Errorf("failed to copy artifact %s: %w", artifact.Name, err)
+	}
+	artifact.Source = artifact.Path
+	artifact.Path = target
+	artifact.Size = size
+	return nil
+}
+
+// copyFile copies the file at source to target and returns its size
+func copyFile(source, target string) (int64, error) {
+	in, err := os.Open(source)
+	if err != nil {
+		return 0, err
+	}
+	defer in.Close()
+
+	out, err := os.Create(target)
+	if err != nil {
+		return 0, err
+	}
+	size, err := io.Copy(out, in)
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	return size, err
+}
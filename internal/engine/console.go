@@ -0,0 +1,39 @@
+package engine
+
+import (
+	"fmt"
+	"io"
+	"sync"
+)
+
+// Console merges the output of jobs running in parallel into a single stream,
+// writing it a whole line at a time so that the lines of different jobs do not
+// interleave. It is safe for concurrent use.
+type Console struct {
+	mutex sync.Mutex
+	out   io.Writer
+	mask  func(string) string
+}
+
+// NewConsole creates a console writing to out. mask, if set, is applied to every
+// line, e.g. to hide secrets.
+func NewConsole(out io.Writer, mask func(string) string) *Console {
+	if mask == nil {
+		mask = func(s string) string { return s }
+	}
+	return &Console{out: out, mask: mask}
+}
+
+// WriteLine writes a masked line, preceded by [prefix] unless prefix is empty
+func (c *Console) WriteLine(prefix, line string) {
+	line = c.mask(line)
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if prefix == "" {
+		fmt.Fprintln(c.out, line)
+		return
+	}
+	fmt.Fprintf(c.out, "[%s] %s\n", prefix, line)
+}
@@ -0,0 +1,71 @@
+package engine
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestConsoleWriteLine(t *testing.T) {
+	tests := []struct {
+		name     string
+		prefix   string
+		line     string
+		mask     func(string) string
+		expected string
+	}{
+		{name: "prefixed", prefix: "deploy/api", line: "ready", expected: "[deploy/api] ready\n"},
+		{name: "unprefixed", line: "ready", expected: "ready\n"},
+		{name: "empty line", prefix: "deploy/api", expected: "[deploy/api] \n"},
+		{
+			name:     "masked",
+			prefix:   "deploy/api",
+			line:     "token s3cr3t",
+			mask:     func(s string) string { return strings.ReplaceAll(s, "s3cr3t", "***") },
+			expected: "[deploy/api] token ***\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out strings.Builder
+			NewConsole(&out, tt.mask).WriteLine(tt.prefix, tt.line)
+			if out.String() != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, out.String())
+			}
+		})
+	}
+}
+
+func TestConsoleParallelJobs(t *testing.T) {
+	var out strings.Builder
+	console := NewConsole(&out, nil)
+
+	// Jobs writing partial lines at the same time must not interleave them
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(job string) {
+			defer wg.Done()
+			output := newJobOutput(console, job, nil)
+			for n := 0; n < 50; n++ {
+				fmt.Fprintf(output, "line %d ", n)
+				fmt.Fprintf(output, "of %s\n", job)
+			}
+		}(fmt.Sprintf("job%d", i))
+	}
+	wg.Wait()
+
+	lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+	if len(lines) != 8*50 {
+		t.Fatalf("Expected %d lines, got %d", 8*50, len(lines))
+	}
+	for _, line := range lines {
+		var job, of string
+		var n int
+		if _, err := fmt.Sscanf(line, "[%s line %d of %s", &job, &n, &of); err != nil || job != of+"]" {
+			t.Errorf("Expected a whole line of a single job, got %q", line)
+		}
+	}
+}
@@ -0,0 +1,15 @@
+package engine
+
+// contextKey is the type of the keys of the values the engine keeps in the
+// context of an execution for its own use. The values passed to plugins are set
+// with the helpers of pkg/plugin.
+type contextKey string
+
+const (
+	// outputsKey holds the *jobOutputs of the execution
+	outputsKey contextKey = "outputs"
+	// artifactsKey holds the *artifactCollector of the execution
+	artifactsKey contextKey = "artifacts"
+	// notificationsKey holds the *notify.Dispatcher of the execution
+	notificationsKey contextKey = "notifications"
+)
@@ -0,0 +1,60 @@
+package engine
+
+import (
+	"fmt"
+	"strings"
+)
+
+// StageError is the failure or cancellation of a stage. Err is the cause, e.g. a
+// *JobError, a rejected approval or an exceeded failure budget.
+type StageError struct {
+	Stage    string
+	Canceled bool
+	Err      error
+}
+
+func (e *StageError) Error() string {
+	if e.Canceled {
+		return fmt.Sprintf("Stage %s canceled: %v", e.Stage, e.Err)
+	}
+	return fmt.Sprintf("Stage %s failed: %v", e.Stage, e.Err)
+}
+
+func (e *StageError) Unwrap() error {
+	return e.Err
+}
+
+// JobError is the failure or cancellation of a job that stopped its stage
+type JobError struct {
+	Stage    string
+	Job      string
+	Canceled bool
+	Err      error
+}
+
+func (e *JobError) Error() string {
+	if e.Canceled {
+		return fmt.Sprintf("job %s canceled: %v", e.Job, e.Err)
+	}
+	return fmt.Sprintf("job %s failed: %v", e.Job, e.Err)
+}
+
+func (e *JobError) Unwrap() error {
+	return e.Err
+}
+
+// joinedError combines the failures of an execution into a single error,
+// which errors.Is and errors.As see through
+type joinedError []error
+
+func (e joinedError) Error() string {
+	messages := make([]string, len(e))
+	for i, err := range e {
+		messages[i] = err.Error()
+	}
+	return strings.Join(messages, "; ")
+}
+
+func (e joinedError) Unwrap() []error {
+	return e
+}
@@ -0,0 +1,57 @@
+package engine
+
+import (
+	"time"
+)
+
+// EventType identifies a transition in a plan execution
+type EventType string
+
+const (
+	// EventStageStarted is published when a stage begins, before its approval
+	EventStageStarted EventType = "StageStarted"
+	// EventStageFinished is published when all jobs of a stage are done
+	EventStageFinished EventType = "StageFinished"
+	// EventJobStarted is published when a job begins executing
+	EventJobStarted EventType = "JobStarted"
+	// EventJobFinished is published when a job completes, successfully or not
+	EventJobFinished EventType = "JobFinished"
+)
+
+// Event describes a stage or job transition. Job fields are empty for stage events;
+// Success, Canceled, Message and Duration are only set for finished events.
+type Event struct {
+	Type        EventType
+	ExecutionID string
+	Stage       string
+	Job         string
+	JobType     string
+	Time        time.Time
+	Success     bool
+	Canceled    bool
+	Message     string
+	Duration    time.Duration
+}
+
+// EventSink receives execution events. Publish is called from the goroutines
+// executing stages and jobs, so implementations must be safe for concurrent use.
+type EventSink interface {
+	Publish(event Event)
+}
+
+// ChannelSink is an EventSink that sends every event to a channel
+type ChannelSink chan<- Event
+
+// Publish sends the event to the channel, blocking until it is received or buffered
+func (c ChannelSink) Publish(event Event) {
+	c <- event
+}
+
+// publish sends an event to the sink, if any, stamping it with the current time
+func publish(sink EventSink, event Event) {
+	if sink == nil {
+		return
+	}
+	event.Time = time.Now()
+	sink.Publish(event)
+}
@@ -2,28 +2,126 @@ package engine
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"math"
+	"math/rand"
+	"os"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 
+	"github.com/google/uuid"
+
+	"github.com/cuongtl1992/grp-cli/internal/config"
+	"github.com/cuongtl1992/grp-cli/internal/logging"
 	"github.com/cuongtl1992/grp-cli/internal/models"
 	"github.com/cuongtl1992/grp-cli/internal/plugins"
+	"github.com/cuongtl1992/grp-cli/pkg/plugin"
+)
+
+const (
+	// defaultRetryDelay is the wait before the first retry when a job sets no retryDelay
+	defaultRetryDelay = time.Second
+	// defaultRetryBackoff multiplies the delay after each attempt when a job sets no retryBackoff
+	defaultRetryBackoff = 2
+	// maxRetryDelay caps the exponential backoff between attempts
+	maxRetryDelay = time.Minute
+	// DefaultCancelGracePeriod is how long a canceled job's plugin is given to clean up
+	DefaultCancelGracePeriod = 10 * time.Second
 )
 
 // Executor handles the execution of jobs
 type Executor struct {
-	pluginManager *plugins.Manager
+	pluginManager     *plugins.Manager
+	logger            logging.Logger
+	cancelGracePeriod time.Duration
+	// logDir receives the output of each job when set; logMask masks it
+	logDir  string
+	logMask func(string) string
+	// console merges the output of the jobs, prefixed with their stage and job
+	// names when prefixOutput is set
+	console      *Console
+	prefixOutput bool
+	// random returns a number in [0, 1) used to jitter retry delays
+	random func() float64
 }
 
 // NewExecutor creates a new executor
 func NewExecutor(pluginManager *plugins.Manager) *Executor {
 	return &Executor{
-		pluginManager: pluginManager,
+		pluginManager:     pluginManager,
+		logger:            logging.Default(),
+		cancelGracePeriod: DefaultCancelGracePeriod,
+		console:           NewConsole(os.Stderr, nil),
+		prefixOutput:      true,
+		random:            rand.Float64,
+	}
+}
+
+// SetLogger replaces the logger used to report job progress
+func (e *Executor) SetLogger(logger logging.Logger) {
+	e.logger = logger
+}
+
+// SetCancelGracePeriod sets how long a job's plugin may keep running to clean up
+// after the execution is canceled; 0 abandons it immediately
+func (e *Executor) SetCancelGracePeriod(period time.Duration) {
+	e.cancelGracePeriod = period
+}
+
+// SetJobLogs captures the output of each job to <dir>/<execution id>/<stage>/<job>.log,
+// besides the console. mask, if set, is applied to every line of output, e.g. to
+// hide secrets. An empty dir disables job logs.
+func (e *Executor) SetJobLogs(dir string, mask func(string) string) {
+	e.logDir = dir
+	e.logMask = mask
+}
+
+// SetConsole sets the console the output of the jobs is written to, a line at a
+// time, prefixed with the stage and job name when prefix is set. Executors running
+// jobs at the same time should share a console. The default writes to stderr.
+func (e *Executor) SetConsole(console *Console, prefix bool) {
+	e.console = console
+	e.prefixOutput = prefix
+}
+
+// GraphOptions controls how a job graph is executed
+type GraphOptions struct {
+	// DryRun simulates job execution without invoking plugins
+	DryRun bool
+	// MaxParallel caps the number of jobs running at once; 0 means unlimited
+	MaxParallel int
+	// StageName is reported in job events
+	StageName string
+	// Events receives job started/finished events, if set
+	Events EventSink
+	// MaxFailures stops scheduling jobs once more than this many have failed; 0 means no limit
+	MaxFailures int
+	// MaxFailurePercent stops scheduling jobs once more than this percentage of
+	// the graph's jobs have failed; 0 means no limit
+	MaxFailurePercent int
+	// KeepGoing runs every job whose dependencies succeeded after a job fails,
+	// instead of stopping the graph at the first failure. The jobs depending on
+	// failed jobs are skipped and the failures are reported together at the end.
+	KeepGoing bool
+}
+
+// failureBudgetExceeded returns a description of the exceeded failure budget,
+// or an empty string if failed jobs are still within it
+func (o GraphOptions) failureBudgetExceeded(failed, total int) string {
+	if o.MaxFailures > 0 && failed > o.MaxFailures {
+		return fmt.Sprintf("failure budget exceeded: %d jobs failed (maxFailures: %d)", failed, o.MaxFailures)
+	}
+	if o.MaxFailurePercent > 0 && total > 0 && failed*100 > o.MaxFailurePercent*total {
+		return fmt.Sprintf("failure budget exceeded: %d of %d jobs failed (maxFailurePercent: %d)", failed, total, o.MaxFailurePercent)
 	}
+	return ""
 }
 
 // ExecuteGraph runs jobs in the order defined by the dependency graph
-func (e *Executor) ExecuteGraph(ctx context.Context, graph *JobGraph, stageResult *models.StageResult, dryRun bool) error {
+func (e *Executor) ExecuteGraph(ctx context.Context, graph *JobGraph, stageResult *models.StageResult, options GraphOptions) error {
 	// Check for cycles in the dependency graph
 	if graph.HasCycles() {
 		return fmt.Errorf("dependency cycle detected in job graph")
@@ -31,18 +129,54 @@ func (e *Executor) ExecuteGraph(ctx context.Context, graph *JobGraph, stageResul
 
 	// Get ready jobs (those with no dependencies)
 	readyJobs := graph.GetReadyJobs()
+	totalJobs := len(graph.Jobs())
+	slots := newSemaphore(options.MaxParallel)
+	outputs, _ := ctx.Value(outputsKey).(*jobOutputs)
+	artifacts, _ := ctx.Value(artifactsKey).(*artifactCollector)
+	firstResult := len(stageResult.Jobs)
+
+	// Failures collected with KeepGoing, or while a stopping stage runs the jobs
+	// handling a failure, reported once no job is left to run
+	var failures joinedError
+	// stopReason is set once a failed job stops the stage: no more jobs start,
+	// except those depending on a failure with the failure or always condition
+	stopReason := ""
 
 	// Process until no more jobs are available
 	for len(readyJobs) > 0 {
+		batch := make([]string, len(readyJobs))
+		for i, job := range readyJobs {
+			batch[i] = job.Name
+		}
+
+		// Don't schedule the next batch once the execution is canceled
+		if err := ctx.Err(); err != nil {
+			recordSkippedJobs(graph, stageResult, firstResult, "the execution was canceled")
+			return fmt.Errorf("canceled before starting jobs %s: %w", strings.Join(batch, ", "), err)
+		}
+		stageResult.Batches = append(stageResult.Batches, batch)
+
 		var wg sync.WaitGroup
 		jobResults := make([]models.JobResult, len(readyJobs))
 
-		// Execute ready jobs in parallel
+		// Execute ready jobs in parallel. Slots are taken in the order of the
+		// ready jobs, so under a concurrency limit higher priority jobs start first.
 		for i, job := range readyJobs {
+			// Jobs still waiting for a slot when the execution is canceled never start
+			if err := slots.acquireContext(ctx); err != nil {
+				jobResults[i] = models.JobResult{
+					Name:     job.Name,
+					Type:     job.Type,
+					Canceled: true,
+					Message:  fmt.Sprintf("job %s canceled before it started", job.Name),
+				}
+				continue
+			}
 			wg.Add(1)
 
 			go func(i int, job models.Job) {
 				defer wg.Done()
+				defer slots.release()
 
 				// Execute the job
 				result := models.JobResult{
@@ -50,23 +184,84 @@ func (e *Executor) ExecuteGraph(ctx context.Context, graph *JobGraph, stageResul
 					Type:      job.Type,
 					StartTime: time.Now(),
 				}
+				executionID := plugin.ExecutionID(ctx)
+				publish(options.Events, Event{
+					Type:        EventJobStarted,
+					ExecutionID: executionID,
+					Stage:       options.StageName,
+					Job:         job.Name,
+					JobType:     job.Type,
+				})
 
-				if dryRun {
-					// Simulate execution in dry-run mode
-					time.Sleep(100 * time.Millisecond)
-					result.Success = true
-					result.Message = "Dry run simulation"
+				var log *jobLog
+				jobCtx := plugin.WithJobName(ctx, job.Name)
+				if options.DryRun {
+					// Validate the job in dry-run mode without executing it
+					if err := e.validateJob(plugin.WithDryRun(jobCtx, true), job); err != nil {
+						result.Message = fmt.Sprintf("Dry run validation failed: %v", err)
+					} else {
+						result.Success = true
+						result.Message = "Dry run: configuration is valid"
+					}
 				} else {
+					// Give every attempt of the job the same idempotency key, and an
+					// execution ID of its own, so that plugins keep what to roll back
+					// for each job apart
+					jobCtx = plugin.WithIdempotencyKey(jobCtx, idempotencyKey(executionID, options.StageName, job.Name))
+					jobCtx = plugin.WithExecutionID(jobCtx, jobExecutionID(executionID, options.StageName, job.Name))
+
+					// Capture the job's output to its log file, and the console
+					prefix := ""
+					if e.prefixOutput {
+						prefix = options.StageName + "/" + job.Name
+					}
+					if e.logDir != "" {
+						var err error
+						log, err = openJobLog(jobLogPath(e.logDir, executionID, options.StageName, job.Name), e.console, prefix, e.logMask)
+						if err != nil {
+							e.logger.Warn("Job output not captured", "job", job.Name, "error", err)
+						} else {
+							result.LogFile = log.path
+						}
+					}
+					if log == nil {
+						log = newJobOutput(e.console, prefix, e.logMask)
+					}
+					jobCtx = plugin.WithOutput(jobCtx, log)
+
 					// Actual execution
-					success, message, data := e.executeJob(ctx, job)
-					result.Success = success
-					result.Message = message
-					result.Data = data
+					outcome := e.executeJobWithRetries(jobCtx, job)
+					result.Success = outcome.success
+					result.Canceled = outcome.canceled
+					result.Message = outcome.message
+					result.Data = outcome.data
+					result.Attempts = outcome.attempts
+					result.ExecutionID = outcome.executionID
+					artifacts.Add(options.StageName, job.Name, outcome.artifacts)
+				}
+
+				// Make the job's data available to the jobs that run after it
+				if result.Success {
+					outputs.Set(job.Name, result.Data)
 				}
 
 				result.EndTime = time.Now()
 				result.Duration = result.EndTime.Sub(result.StartTime)
+				if log != nil {
+					log.Close(result)
+				}
 				jobResults[i] = result
+				publish(options.Events, Event{
+					Type:        EventJobFinished,
+					ExecutionID: executionID,
+					Stage:       options.StageName,
+					Job:         job.Name,
+					JobType:     job.Type,
+					Success:     result.Success,
+					Canceled:    result.Canceled,
+					Message:     result.Message,
+					Duration:    result.Duration,
+				})
 			}(i, job)
 		}
 
@@ -74,38 +269,395 @@ func (e *Executor) ExecuteGraph(ctx context.Context, graph *JobGraph, stageResul
 		wg.Wait()
 
 		// Process results
-		for _, result := range jobResults {
+		var canceled error
+		for i, result := range jobResults {
+			if result.Canceled {
+				// A canceled job is not a failure, but nothing may run after it
+				if canceled == nil {
+					canceled = &JobError{Stage: options.StageName, Job: result.Name, Canceled: true, Err: context.Canceled}
+				}
+			} else if !result.Success {
+				stageResult.FailedJobs++
+
+				// A failed job stops the stage unless it allows failures
+				jobErr := &JobError{Stage: options.StageName, Job: result.Name, Err: errors.New(result.Message)}
+				if readyJobs[i].ContinueOnError {
+					result.ContinuedOnError = true
+					e.logger.Warn("Job failed, continuing", "job", result.Name, "message", result.Message)
+				} else if options.KeepGoing {
+					failures = append(failures, jobErr)
+					e.logger.Warn("Job failed, running the jobs that do not depend on it", "job", result.Name, "message", result.Message)
+				} else {
+					// Every failure is reported, but the stage stops for the first one;
+					// a job handling that failure may fail too
+					failures = append(failures, jobErr)
+					if stopReason == "" {
+						stopReason = fmt.Sprintf("the stage stopped after job %s failed", result.Name)
+					}
+				}
+			}
 			stageResult.Jobs = append(stageResult.Jobs, result)
 
-			// Mark job as complete in the graph
+			// Record the outcome in the graph so the jobs it releases can run
 			if result.Success {
 				graph.MarkCompleted(result.Name)
 			} else {
-				// If a job fails, stop execution
-				return fmt.Errorf("job %s failed: %s", result.Name, result.Message)
+				graph.MarkFailed(result.Name)
 			}
 		}
 
-		// Get next batch of ready jobs
+		// If a job is canceled, stop execution
+		if canceled != nil {
+			recordSkippedJobs(graph, stageResult, firstResult, "the execution was canceled")
+			return canceled
+		}
+
+		// Stop scheduling jobs once too many have failed, even those allowed to fail
+		if reason := options.failureBudgetExceeded(stageResult.FailedJobs, totalJobs); reason != "" {
+			stageResult.AbortReason = reason
+			recordSkippedJobs(graph, stageResult, firstResult, reason)
+			return errors.New(reason)
+		}
+
+		// Get next batch of ready jobs; a stopping stage only runs the jobs
+		// handling a failure
 		readyJobs = graph.GetReadyJobs()
+		if stopReason != "" {
+			readyJobs = failureHandlers(graph, readyJobs)
+		}
+	}
+
+	if len(failures) > 0 {
+		reason := stopReason
+		if reason == "" {
+			reason = "the stage failed"
+		}
+		recordSkippedJobs(graph, stageResult, firstResult, reason)
+		if len(failures) == 1 {
+			return failures[0]
+		}
+		return failures
 	}
 
+	// The jobs whose dependencies did not end as they require never ran
+	recordSkippedJobs(graph, stageResult, firstResult, "the conditions of its dependencies were not met")
 	return nil
 }
 
+// failureHandlers returns the jobs among jobs that depend on the failure of
+// another job, with the failure or always condition
+func failureHandlers(graph *JobGraph, jobs []models.Job) []models.Job {
+	var handlers []models.Job
+	for _, job := range jobs {
+		if graph.runsOnFailure(job.Name) {
+			handlers = append(handlers, job)
+		}
+	}
+	return handlers
+}
+
+// recordSkippedJobs adds a skipped result to stageResult for every job of the graph
+// that never ran because the stage stopped early. The results of the graph's jobs
+// start at stageResult.Jobs[first]. A job waiting on a failed or canceled job,
+// directly or not, is blocked by it; other jobs were not started for reason.
+func recordSkippedJobs(graph *JobGraph, stageResult *models.StageResult, first int, reason string) {
+	ran := make(map[string]bool)
+	failed := make(map[string]string)
+	for _, result := range stageResult.Jobs[first:] {
+		ran[result.Name] = true
+		switch {
+		case result.Canceled:
+			failed[result.Name] = "canceled"
+		case !result.Success && !result.ContinuedOnError:
+			failed[result.Name] = "failed"
+		}
+	}
+
+	for _, job := range graph.GetRemainingJobs() {
+		if ran[job.Name] {
+			continue
+		}
+		result := models.JobResult{
+			Name:      job.Name,
+			Type:      job.Type,
+			Skipped:   true,
+			BlockedBy: blockingJobs(graph, job.Name, failed),
+		}
+		if len(result.BlockedBy) > 0 {
+			blocking := make([]string, len(result.BlockedBy))
+			for i, dep := range result.BlockedBy {
+				blocking[i] = fmt.Sprintf("%s job %s", failed[dep], dep)
+			}
+			result.Message = "not run: blocked by " + strings.Join(blocking, ", ")
+		} else {
+			result.Message = "not run: " + reason
+		}
+		stageResult.Jobs = append(stageResult.Jobs, result)
+	}
+}
+
+// blockingJobs returns the failed or canceled jobs among the dependencies of a job,
+// direct or not, sorted by name. failed maps those jobs to their state. A failed
+// job does not block the jobs depending on its failure.
+func blockingJobs(graph *JobGraph, name string, failed map[string]string) []string {
+	visited := make(map[string]bool)
+	var blocking []string
+	var visit func(name string)
+	visit = func(name string) {
+		for _, dep := range graph.dependencies[name] {
+			if visited[dep] || (failed[dep] == "failed" && graph.conditions[Edge{From: dep, To: name}] != "") {
+				continue
+			}
+			visited[dep] = true
+			if failed[dep] != "" {
+				blocking = append(blocking, dep)
+				continue
+			}
+			visit(dep)
+		}
+	}
+	visit(name)
+	sort.Strings(blocking)
+	return blocking
+}
+
+// jobOutcome is the result of running a job's plugin
+type jobOutcome struct {
+	success     bool
+	message     string
+	data        map[string]interface{}
+	executionID string
+	attempts    int
+	canceled    bool
+	artifacts   []plugin.Artifact
+}
+
+// retryPolicy is the parsed retry configuration of a job
+type retryPolicy struct {
+	strategy  string
+	baseDelay time.Duration
+	backoff   float64
+	jitter    float64
+	// maxElapsed bounds the time from the first attempt to the start of the last; 0 means unbounded
+	maxElapsed time.Duration
+}
+
+// newRetryPolicy parses the retry settings of a job, applying the defaults
+func newRetryPolicy(job models.Job) (retryPolicy, error) {
+	policy := retryPolicy{
+		strategy:  job.RetryStrategy,
+		baseDelay: defaultRetryDelay,
+		backoff:   defaultRetryBackoff,
+		jitter:    job.RetryJitter,
+	}
+	if job.RetryDelay != "" {
+		delay, err := time.ParseDuration(job.RetryDelay)
+		if err != nil {
+			return policy, fmt.Errorf("invalid retry delay %q: %w", job.RetryDelay, err)
+		}
+		policy.baseDelay = delay
+	}
+	if job.RetryBackoff != 0 {
+		policy.backoff = job.RetryBackoff
+	}
+	if job.RetryMaxElapsed != "" {
+		maxElapsed, err := time.ParseDuration(job.RetryMaxElapsed)
+		if err != nil {
+			return policy, fmt.Errorf("invalid retry max elapsed time %q: %w", job.RetryMaxElapsed, err)
+		}
+		policy.maxElapsed = maxElapsed
+	}
+	return policy, nil
+}
+
+// executeJobWithRetries runs a job and re-invokes it up to job.Retries times on failure,
+// waiting between attempts according to the job's retry policy. Retries stop early
+// when the next attempt would start after the job's retryMaxElapsed.
+func (e *Executor) executeJobWithRetries(ctx context.Context, job models.Job) jobOutcome {
+	policy, err := newRetryPolicy(job)
+	if err != nil {
+		return jobOutcome{message: fmt.Sprintf("Invalid retry settings: %v", err)}
+	}
+
+	var outcome jobOutcome
+	attempts := 0
+	start := time.Now()
+	for attempts <= job.Retries {
+		attempts++
+		if log, ok := plugin.Output(ctx).(*jobLog); ok && job.Retries > 0 {
+			log.StartAttempt(attempts, job.Retries+1)
+		}
+		outcome = e.executeJob(plugin.WithAttempt(ctx, attempts), job)
+		outcome.attempts = attempts
+		if outcome.success || outcome.canceled || attempts > job.Retries {
+			break
+		}
+
+		delay := policy.delay(attempts, e.random())
+		if policy.maxElapsed > 0 && time.Since(start)+delay > policy.maxElapsed {
+			outcome.message = fmt.Sprintf("%s (gave up after %d attempts: retryMaxElapsed of %s reached)", outcome.message, attempts, policy.maxElapsed)
+			return outcome
+		}
+		e.logger.Warn("Job failed, retrying", "job", job.Name, "attempt", attempts, "maxAttempts", job.Retries+1, "delay", delay, "message", outcome.message)
+
+		// Stop retrying immediately if the run is canceled
+		select {
+		case <-ctx.Done():
+			outcome.message = fmt.Sprintf("%s (retries aborted: %v)", outcome.message, ctx.Err())
+			outcome.canceled = errors.Is(ctx.Err(), context.Canceled)
+			return outcome
+		case <-time.After(delay):
+		}
+	}
+
+	return outcome
+}
+
+// delay computes the wait before the next attempt; attempt is 1-based. The delay
+// is spread by up to the policy's jitter fraction either way, random being a
+// number in [0, 1), so jobs failing together do not retry in lockstep.
+func (p retryPolicy) delay(attempt int, random float64) time.Duration {
+	delay := float64(p.baseDelay)
+	if p.strategy != models.RetryStrategyFixed {
+		delay *= math.Pow(p.backoff, float64(attempt-1))
+		if delay > float64(maxRetryDelay) {
+			delay = float64(maxRetryDelay)
+		}
+	}
+	delay *= 1 + p.jitter*(2*random-1)
+	return time.Duration(delay)
+}
+
 // executeJob runs a single job using the appropriate plugin
-func (e *Executor) executeJob(ctx context.Context, job models.Job) (bool, string, map[string]interface{}) {
-	fmt.Printf("Executing job: %s (type: %s)\n", job.Name, job.Type)
+func (e *Executor) executeJob(ctx context.Context, job models.Job) jobOutcome {
+	// Don't start jobs once the execution is canceled
+	if errors.Is(ctx.Err(), context.Canceled) {
+		return jobOutcome{canceled: true, message: fmt.Sprintf("job %s canceled before it started", job.Name)}
+	}
+
+	e.logger.Info("Executing job", "job", job.Name, "type", job.Type)
+
+	// Apply the job timeout, if any, so a hung plugin can't block the stage
+	jobCtx := ctx
+	if job.Timeout != "" {
+		timeout, err := time.ParseDuration(job.Timeout)
+		if err != nil {
+			return jobOutcome{message: fmt.Sprintf("Invalid timeout %q: %v", job.Timeout, err)}
+		}
+
+		var cancel context.CancelFunc
+		jobCtx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	// Resolve references to plan variables and the outputs of earlier jobs
+	jobConfig, err := e.resolveConfig(ctx, job.Config)
+	if err != nil {
+		return jobOutcome{message: fmt.Sprintf("Failed to resolve job config: %v", err)}
+	}
 
 	// Execute the job using the plugin manager
-	result, err := e.pluginManager.ExecutePlugin(ctx, job.Type, job.Config)
+	result, err := e.runPlugin(jobCtx, job.Type, jobConfig)
+	if jobCtx.Err() == context.DeadlineExceeded && ctx.Err() == nil {
+		return jobOutcome{message: fmt.Sprintf("job %s timed out after %s", job.Name, job.Timeout)}
+	}
+	if errors.Is(ctx.Err(), context.Canceled) && (err != nil || !result.Success) {
+		return jobOutcome{canceled: true, message: fmt.Sprintf("job %s canceled", job.Name)}
+	}
+	if err != nil {
+		return jobOutcome{message: fmt.Sprintf("Failed to execute job: %v", err)}
+	}
+
+	// Fall back to the job's execution ID if the plugin did not report one
+	executionID := result.ExecutionID
+	if executionID == "" {
+		executionID = plugin.ExecutionID(ctx)
+	}
+
+	return jobOutcome{
+		success:     result.Success,
+		message:     result.Message,
+		data:        result.Data,
+		executionID: executionID,
+		artifacts:   result.Artifacts,
+	}
+}
+
+// idempotencyKey derives the idempotency key of a job from the execution and the
+// job's stage, as job names are only unique within a stage. The key is a name-based
+// UUID, so it is stable across attempts and has a fixed length.
+func idempotencyKey(executionID, stageName, jobName string) string {
+	return uuid.NewSHA1(uuid.NameSpaceOID, []byte(executionID+"/"+stageName+"/"+jobName)).String()
+}
+
+// jobExecutionID derives the execution ID the plugin of a job is given, and rolls
+// the job back with, from the plan execution and the job's stage. Like the
+// idempotency key it is a name-based UUID, in another namespace so that the two
+// differ.
+func jobExecutionID(executionID, stageName, jobName string) string {
+	return uuid.NewSHA1(uuid.NameSpaceURL, []byte(executionID+"/"+stageName+"/"+jobName)).String()
+}
+
+// resolveConfig substitutes ${variables.*}, ${outputs.*} and ${run.*} references in a job config
+func (e *Executor) resolveConfig(ctx context.Context, jobConfig map[string]interface{}) (map[string]interface{}, error) {
+	if jobConfig == nil {
+		return nil, nil
+	}
+
+	references := map[string]interface{}{
+		"variables": plugin.Variables(ctx),
+		"run":       map[string]interface{}{"workingDir": plugin.WorkingDir(ctx)},
+	}
+
+	// Without an output store (e.g. in dry-run mode) output references are left as is
+	if outputs, ok := ctx.Value(outputsKey).(*jobOutputs); ok {
+		references["outputs"] = outputs.Snapshot()
+	}
+	return config.NewResolver().ResolveValues(jobConfig, references)
+}
+
+// validateJob resolves a job's config and validates it with its plugin, without executing it
+func (e *Executor) validateJob(ctx context.Context, job models.Job) error {
+	jobConfig, err := e.resolveConfig(ctx, job.Config)
 	if err != nil {
-		return false, fmt.Sprintf("Failed to execute job: %v", err), nil
+		return fmt.Errorf("failed to resolve job config: %w", err)
 	}
+	return e.pluginManager.ValidatePlugin(ctx, job.Type, jobConfig)
+}
+
+// pluginOutcome carries the return values of a plugin call across goroutines
+type pluginOutcome struct {
+	result *plugin.Result
+	err    error
+}
 
-	if !result.Success {
-		return false, result.Message, result.Data
+// runPlugin executes the job's plugin and returns early if the context is done,
+// even when the plugin itself does not honor cancellation. When the execution is
+// canceled, rather than timed out, the plugin is first given the cancel grace
+// period to clean up and return.
+func (e *Executor) runPlugin(ctx context.Context, jobType string, config map[string]interface{}) (*plugin.Result, error) {
+	done := make(chan pluginOutcome, 1)
+	go func() {
+		result, err := e.pluginManager.ExecutePlugin(ctx, jobType, config)
+		done <- pluginOutcome{result: result, err: err}
+	}()
+
+	select {
+	case outcome := <-done:
+		return outcome.result, outcome.err
+	case <-ctx.Done():
 	}
 
-	return true, result.Message, result.Data
+	if errors.Is(ctx.Err(), context.Canceled) && e.cancelGracePeriod > 0 {
+		grace := time.NewTimer(e.cancelGracePeriod)
+		defer grace.Stop()
+
+		select {
+		case outcome := <-done:
+			return outcome.result, outcome.err
+		case <-grace.C:
+			e.logger.Warn("Job did not stop within the cancel grace period", "type", jobType, "gracePeriod", e.cancelGracePeriod)
+		}
+	}
+	return nil, ctx.Err()
 }
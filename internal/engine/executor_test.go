@@ -0,0 +1,1289 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cuongtl1992/grp-cli/internal/models"
+	"github.com/cuongtl1992/grp-cli/internal/plugins"
+	"github.com/cuongtl1992/grp-cli/pkg/plugin"
+)
+
+// MockPlugin implements the Plugin interface for testing
+type MockPlugin struct {
+	name     string
+	delay    time.Duration
+	validate func(config map[string]interface{}) error
+	execute  func(ctx context.Context, config map[string]interface{}) (*plugin.Result, error)
+	rollback func(executionID string) error
+}
+
+func (m *MockPlugin) Name() string                     { return m.name }
+func (m *MockPlugin) Description() string              { return "Mock plugin for testing" }
+func (m *MockPlugin) Version() string                  { return "1.0.0" }
+func (m *MockPlugin) ConfigSchema() *plugin.JSONSchema { return nil }
+func (m *MockPlugin) Validate(ctx context.Context, config map[string]interface{}) error {
+	if m.validate != nil {
+		return m.validate(config)
+	}
+	return nil
+}
+func (m *MockPlugin) Execute(ctx context.Context, config map[string]interface{}) (*plugin.Result, error) {
+	if m.delay > 0 {
+		time.Sleep(m.delay)
+	}
+	if m.execute != nil {
+		return m.execute(ctx, config)
+	}
+	return &plugin.Result{Success: true, Message: "ok"}, nil
+}
+func (m *MockPlugin) Rollback(ctx context.Context, executionID string) error {
+	if m.rollback != nil {
+		return m.rollback(executionID)
+	}
+	return nil
+}
+
+// newTestExecutor creates an executor with the given plugins registered
+func newTestExecutor(t *testing.T, plgs ...plugin.Plugin) *Executor {
+	t.Helper()
+	manager := plugins.NewManager("./plugins")
+	for _, plg := range plgs {
+		if err := manager.RegisterPlugin(plg); err != nil {
+			t.Fatalf("Failed to register plugin: %v", err)
+		}
+	}
+	return NewExecutor(manager)
+}
+
+func TestExecuteJobTimeout(t *testing.T) {
+	executor := newTestExecutor(t, &MockPlugin{name: "slow", delay: time.Second})
+	job := models.Job{Name: "hung-job", Type: "slow", Timeout: "50ms"}
+
+	start := time.Now()
+	outcome := executor.executeJob(context.Background(), job)
+
+	if outcome.success {
+		t.Fatal("Expected job to fail on timeout")
+	}
+	if !strings.Contains(outcome.message, "timed out after 50ms") {
+		t.Errorf("Expected timeout message, got %q", outcome.message)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("Expected job to return at the deadline, took %s", elapsed)
+	}
+}
+
+func TestExecuteJobWithoutTimeout(t *testing.T) {
+	executor := newTestExecutor(t, &MockPlugin{
+		name: "fast",
+		execute: func(ctx context.Context, config map[string]interface{}) (*plugin.Result, error) {
+			if _, ok := ctx.Deadline(); ok {
+				t.Error("Expected no deadline for a job without timeout")
+			}
+			return &plugin.Result{Success: true}, nil
+		},
+	})
+
+	outcome := executor.executeJob(context.Background(), models.Job{Name: "job", Type: "fast"})
+	if !outcome.success {
+		t.Errorf("Expected job to succeed, got %q", outcome.message)
+	}
+}
+
+func TestExecuteJobWithoutResult(t *testing.T) {
+	executor := newTestExecutor(t, &MockPlugin{
+		name: "buggy",
+		execute: func(ctx context.Context, config map[string]interface{}) (*plugin.Result, error) {
+			return nil, nil
+		},
+	})
+
+	outcome := executor.executeJob(plugin.WithExecutionID(context.Background(), "exec-1"), models.Job{Name: "job", Type: "buggy"})
+	if outcome.success || outcome.message != "plugin buggy returned no result and no error" {
+		t.Errorf("Expected the job to fail without a result, got %+v", outcome)
+	}
+	if outcome.executionID != "exec-1" {
+		t.Errorf("Expected the execution ID of the plan execution, got %q", outcome.executionID)
+	}
+}
+
+func TestExecuteJobWithRetries(t *testing.T) {
+	tests := []struct {
+		name             string
+		failures         int
+		retries          int
+		expectedSuccess  bool
+		expectedAttempts int
+	}{
+		{name: "succeeds first time", failures: 0, retries: 2, expectedSuccess: true, expectedAttempts: 1},
+		{name: "succeeds after retries", failures: 2, retries: 2, expectedSuccess: true, expectedAttempts: 3},
+		{name: "exhausts retries", failures: 5, retries: 2, expectedSuccess: false, expectedAttempts: 3},
+		{name: "no retries", failures: 1, retries: 0, expectedSuccess: false, expectedAttempts: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			executor := newTestExecutor(t, &MockPlugin{
+				name: "flaky",
+				execute: func(ctx context.Context, config map[string]interface{}) (*plugin.Result, error) {
+					calls++
+					if calls <= tt.failures {
+						return &plugin.Result{Success: false, Message: fmt.Sprintf("failure %d", calls)}, nil
+					}
+					return &plugin.Result{Success: true}, nil
+				},
+			})
+			job := models.Job{Name: "job", Type: "flaky", Retries: tt.retries, RetryStrategy: models.RetryStrategyFixed, RetryDelay: "1ms"}
+
+			outcome := executor.executeJobWithRetries(context.Background(), job)
+
+			if outcome.success != tt.expectedSuccess {
+				t.Errorf("Expected success=%v, got %v (%s)", tt.expectedSuccess, outcome.success, outcome.message)
+			}
+			if outcome.attempts != tt.expectedAttempts {
+				t.Errorf("Expected %d attempts, got %d", tt.expectedAttempts, outcome.attempts)
+			}
+			if !outcome.success && outcome.message != fmt.Sprintf("failure %d", outcome.attempts) {
+				t.Errorf("Expected last failure message, got %q", outcome.message)
+			}
+		})
+	}
+}
+
+func TestExecuteJobWithRetriesCanceled(t *testing.T) {
+	executor := newTestExecutor(t, &MockPlugin{
+		name: "failing",
+		execute: func(ctx context.Context, config map[string]interface{}) (*plugin.Result, error) {
+			return &plugin.Result{Success: false, Message: "boom"}, nil
+		},
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	job := models.Job{Name: "job", Type: "failing", Retries: 5, RetryDelay: "1h"}
+	outcome := executor.executeJobWithRetries(ctx, job)
+
+	if outcome.success {
+		t.Error("Expected canceled job to fail")
+	}
+	if outcome.attempts != 1 {
+		t.Errorf("Expected retries to stop after 1 attempt, got %d", outcome.attempts)
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	tests := []struct {
+		name     string
+		job      models.Job
+		attempt  int
+		random   float64
+		expected time.Duration
+	}{
+		{name: "fixed", job: models.Job{RetryStrategy: models.RetryStrategyFixed}, attempt: 3, random: 0.5, expected: time.Second},
+		{name: "exponential", job: models.Job{RetryStrategy: models.RetryStrategyExponential}, attempt: 3, random: 0.5, expected: 4 * time.Second},
+		{name: "capped", job: models.Job{}, attempt: 20, random: 0.5, expected: maxRetryDelay},
+		{name: "custom backoff", job: models.Job{RetryDelay: "2s", RetryBackoff: 1.5}, attempt: 3, random: 0.5, expected: 4500 * time.Millisecond},
+		{name: "jitter lower bound", job: models.Job{RetryDelay: "10s", RetryJitter: 0.2}, attempt: 1, random: 0, expected: 8 * time.Second},
+		{name: "jitter upper bound", job: models.Job{RetryDelay: "10s", RetryJitter: 0.2}, attempt: 1, random: 0.999999, expected: 12 * time.Second},
+		{name: "jitter on fixed delay", job: models.Job{RetryStrategy: models.RetryStrategyFixed, RetryDelay: "10s", RetryJitter: 0.5}, attempt: 4, random: 0.25, expected: 7500 * time.Millisecond},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy, err := newRetryPolicy(tt.job)
+			if err != nil {
+				t.Fatalf("Failed to parse retry policy: %v", err)
+			}
+			got := policy.delay(tt.attempt, tt.random)
+			if diff := got - tt.expected; diff < -time.Millisecond || diff > time.Millisecond {
+				t.Errorf("Expected delay of %s, got %s", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestRetryDelayJitterBounds(t *testing.T) {
+	policy, err := newRetryPolicy(models.Job{RetryDelay: "1s", RetryJitter: 0.3})
+	if err != nil {
+		t.Fatalf("Failed to parse retry policy: %v", err)
+	}
+	executor := newTestExecutor(t)
+
+	distinct := make(map[time.Duration]bool)
+	for i := 0; i < 100; i++ {
+		delay := policy.delay(2, executor.random())
+		if delay < 1400*time.Millisecond || delay > 2600*time.Millisecond {
+			t.Fatalf("Expected delay within 2s ± 30%%, got %s", delay)
+		}
+		distinct[delay] = true
+	}
+	if len(distinct) < 2 {
+		t.Error("Expected jittered delays to vary")
+	}
+}
+
+func TestExecuteJobWithRetriesMaxElapsed(t *testing.T) {
+	tests := []struct {
+		name             string
+		job              models.Job
+		expectedAttempts int
+		maxDuration      time.Duration
+	}{
+		{
+			name:             "gives up before retries are exhausted",
+			job:              models.Job{Retries: 100, RetryStrategy: models.RetryStrategyFixed, RetryDelay: "50ms", RetryMaxElapsed: "120ms"},
+			expectedAttempts: 3,
+			maxDuration:      time.Second,
+		},
+		{
+			name:             "next delay would exceed the limit",
+			job:              models.Job{Retries: 3, RetryDelay: "1h", RetryMaxElapsed: "1m"},
+			expectedAttempts: 1,
+			maxDuration:      time.Second,
+		},
+		{
+			name:             "retries exhausted within the limit",
+			job:              models.Job{Retries: 2, RetryDelay: "1ms", RetryMaxElapsed: "1m"},
+			expectedAttempts: 3,
+			maxDuration:      time.Second,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			executor := newTestExecutor(t, &MockPlugin{name: "failing", execute: func(ctx context.Context, config map[string]interface{}) (*plugin.Result, error) {
+				return &plugin.Result{Success: false, Message: "failed"}, nil
+			}})
+			job := tt.job
+			job.Name, job.Type = "job", "failing"
+
+			start := time.Now()
+			outcome := executor.executeJobWithRetries(context.Background(), job)
+			elapsed := time.Since(start)
+
+			if outcome.success {
+				t.Fatal("Expected job to fail")
+			}
+			if outcome.attempts != tt.expectedAttempts {
+				t.Errorf("Expected %d attempts, got %d (%s)", tt.expectedAttempts, outcome.attempts, outcome.message)
+			}
+			if elapsed > tt.maxDuration {
+				t.Errorf("Expected retries to stop within %s, took %s", tt.maxDuration, elapsed)
+			}
+			if limit, _ := time.ParseDuration(job.RetryMaxElapsed); outcome.attempts < job.Retries+1 {
+				if !strings.Contains(outcome.message, "retryMaxElapsed of "+limit.String()+" reached") {
+					t.Errorf("Expected message to mention retryMaxElapsed, got %q", outcome.message)
+				}
+			}
+		})
+	}
+}
+
+func TestExecuteGraphMaxParallel(t *testing.T) {
+	var (
+		mutex   sync.Mutex
+		running int
+		peak    int
+	)
+	executor := newTestExecutor(t, &MockPlugin{
+		name: "tracked",
+		execute: func(ctx context.Context, config map[string]interface{}) (*plugin.Result, error) {
+			mutex.Lock()
+			running++
+			peak = max(peak, running)
+			mutex.Unlock()
+
+			time.Sleep(20 * time.Millisecond)
+
+			mutex.Lock()
+			running--
+			mutex.Unlock()
+			return &plugin.Result{Success: true}, nil
+		},
+	})
+
+	var jobs []models.Job
+	for i := 0; i < 6; i++ {
+		jobs = append(jobs, models.Job{Name: fmt.Sprintf("job%d", i), Type: "tracked"})
+	}
+
+	stageResult := &models.StageResult{Name: "stage"}
+	if err := executor.ExecuteGraph(context.Background(), BuildDependencyGraph(jobs), stageResult, GraphOptions{MaxParallel: 2}); err != nil {
+		t.Fatalf("ExecuteGraph() error = %v", err)
+	}
+
+	if peak != 2 {
+		t.Errorf("Expected at most 2 concurrent jobs, got %d", peak)
+	}
+	if len(stageResult.Jobs) != len(jobs) {
+		t.Errorf("Expected %d job results, got %d", len(jobs), len(stageResult.Jobs))
+	}
+}
+
+func TestExecuteGraphPriority(t *testing.T) {
+	var (
+		mutex   sync.Mutex
+		started []string
+	)
+	executor := newTestExecutor(t, &MockPlugin{
+		name: "tracked",
+		execute: func(ctx context.Context, config map[string]interface{}) (*plugin.Result, error) {
+			mutex.Lock()
+			started = append(started, config["name"].(string))
+			mutex.Unlock()
+			return &plugin.Result{Success: true}, nil
+		},
+	})
+
+	jobs := []models.Job{
+		{Name: "smoke", Type: "tracked", Config: map[string]interface{}{"name": "smoke"}},
+		{Name: "migrate", Type: "tracked", Priority: 10, Config: map[string]interface{}{"name": "migrate"}},
+		{Name: "cleanup", Type: "tracked", Priority: -5, Config: map[string]interface{}{"name": "cleanup"}},
+		{Name: "deploy", Type: "tracked", Config: map[string]interface{}{"name": "deploy"}},
+	}
+
+	stageResult := &models.StageResult{Name: "stage"}
+	if err := executor.ExecuteGraph(context.Background(), BuildDependencyGraph(jobs), stageResult, GraphOptions{MaxParallel: 1}); err != nil {
+		t.Fatalf("ExecuteGraph() error = %v", err)
+	}
+
+	expected := "[migrate deploy smoke cleanup]"
+	if fmt.Sprint(started) != expected {
+		t.Errorf("Expected jobs started in order %s, got %v", expected, started)
+	}
+	if fmt.Sprint(stageResult.Batches) != "["+expected+"]" {
+		t.Errorf("Expected the batch in priority order, got %v", stageResult.Batches)
+	}
+}
+
+func TestExecuteGraphJobOutputs(t *testing.T) {
+	var image interface{}
+	executor := newTestExecutor(t,
+		&MockPlugin{
+			name: "build",
+			execute: func(ctx context.Context, config map[string]interface{}) (*plugin.Result, error) {
+				return &plugin.Result{Success: true, Data: map[string]interface{}{"tag": "v1.2.3"}}, nil
+			},
+		},
+		&MockPlugin{
+			name: "deploy",
+			execute: func(ctx context.Context, config map[string]interface{}) (*plugin.Result, error) {
+				image = config["image"]
+				return &plugin.Result{Success: true}, nil
+			},
+		},
+	)
+
+	graph := BuildDependencyGraph([]models.Job{
+		{Name: "build", Type: "build"},
+		{Name: "deploy", Type: "deploy", DependsOn: []string{"build"}, Config: map[string]interface{}{
+			"image": "${variables.app}:${outputs.build.tag}",
+		}},
+	})
+	ctx := plugin.WithVariables(context.Background(), map[string]interface{}{"app": "web"})
+	ctx = context.WithValue(ctx, outputsKey, newJobOutputs())
+
+	if err := executor.ExecuteGraph(ctx, graph, &models.StageResult{}, GraphOptions{}); err != nil {
+		t.Fatalf("ExecuteGraph() error = %v", err)
+	}
+	if image != "web:v1.2.3" {
+		t.Errorf("Expected image to reference the build output, got %v", image)
+	}
+}
+
+func TestExecuteGraphIdempotencyKey(t *testing.T) {
+	var mutex sync.Mutex
+	keys := make(map[string][]string)
+	executor := newTestExecutor(t, &MockPlugin{
+		name: "mock",
+		execute: func(ctx context.Context, config map[string]interface{}) (*plugin.Result, error) {
+			mutex.Lock()
+			defer mutex.Unlock()
+			job := config["job"].(string)
+			keys[job] = append(keys[job], plugin.IdempotencyKey(ctx))
+			// Fail the first attempt of the flaky job
+			return &plugin.Result{Success: job != "flaky" || len(keys[job]) > 1}, nil
+		},
+	})
+
+	graph := BuildDependencyGraph([]models.Job{
+		{Name: "flaky", Type: "mock", Retries: 1, RetryDelay: "1ms", Config: map[string]interface{}{"job": "flaky"}},
+		{Name: "other", Type: "mock", Config: map[string]interface{}{"job": "other"}},
+	})
+	ctx := plugin.WithExecutionID(context.Background(), "exec-1")
+
+	if err := executor.ExecuteGraph(ctx, graph, &models.StageResult{}, GraphOptions{StageName: "deploy"}); err != nil {
+		t.Fatalf("ExecuteGraph() error = %v", err)
+	}
+
+	flaky := keys["flaky"]
+	if len(flaky) != 2 || flaky[0] == "" || flaky[0] != flaky[1] {
+		t.Errorf("Expected both attempts to get the same key, got %v", flaky)
+	}
+	if flaky[0] != idempotencyKey("exec-1", "deploy", "flaky") {
+		t.Errorf("Expected the key derived from the job, got %s", flaky[0])
+	}
+	if len(keys["other"]) != 1 || keys["other"][0] == flaky[0] {
+		t.Errorf("Expected another job to get another key, got %v", keys["other"])
+	}
+}
+
+func TestIdempotencyKey(t *testing.T) {
+	key := idempotencyKey("exec-1", "deploy", "web")
+	tests := []struct {
+		name        string
+		executionID string
+		stageName   string
+		jobName     string
+	}{
+		{name: "other execution", executionID: "exec-2", stageName: "deploy", jobName: "web"},
+		{name: "other stage", executionID: "exec-1", stageName: "verify", jobName: "web"},
+		{name: "other job", executionID: "exec-1", stageName: "deploy", jobName: "api"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if other := idempotencyKey(tt.executionID, tt.stageName, tt.jobName); other == key {
+				t.Errorf("Expected a different key than %s", key)
+			}
+		})
+	}
+	if again := idempotencyKey("exec-1", "deploy", "web"); again != key {
+		t.Errorf("Expected a stable key, got %s and %s", key, again)
+	}
+}
+
+func TestExecuteGraphJobLogs(t *testing.T) {
+	executor := newTestExecutor(t, &MockPlugin{
+		name: "noisy",
+		execute: func(ctx context.Context, config map[string]interface{}) (*plugin.Result, error) {
+			output := plugin.Output(ctx)
+			fmt.Fprint(output, "deploying with token s3cr3t\n")
+			fmt.Fprint(output, "no trailing newline")
+			return &plugin.Result{Success: false, Message: "rejected s3cr3t"}, nil
+		},
+	})
+	var console strings.Builder
+	executor.SetConsole(NewConsole(&console, nil), true)
+	dir := t.TempDir()
+	executor.SetJobLogs(dir, func(s string) string { return strings.ReplaceAll(s, "s3cr3t", "***") })
+
+	ctx := plugin.WithExecutionID(context.Background(), "exec-1")
+	stageResult := &models.StageResult{}
+	graph := BuildDependencyGraph([]models.Job{{Name: "api/web", Type: "noisy", Retries: 1, RetryDelay: "1ms"}})
+	if err := executor.ExecuteGraph(ctx, graph, stageResult, GraphOptions{StageName: "deploy"}); err == nil {
+		t.Fatal("Expected the job to fail")
+	}
+
+	expectedPath := filepath.Join(dir, "exec-1", "deploy", "api_web.log")
+	if stageResult.Jobs[0].LogFile != expectedPath {
+		t.Errorf("Expected log file %s, got %s", expectedPath, stageResult.Jobs[0].LogFile)
+	}
+	data, err := os.ReadFile(expectedPath)
+	if err != nil {
+		t.Fatalf("Failed to read job log: %v", err)
+	}
+	expectedLog := "--- attempt 1 of 2\ndeploying with token ***\nno trailing newline\n--- attempt 2 of 2\n" +
+		"deploying with token ***\nno trailing newline\n--- job failed (attempts: 2): rejected ***\n"
+	if string(data) != expectedLog {
+		t.Errorf("Expected log:\n%s\ngot:\n%s", expectedLog, data)
+	}
+	if !strings.HasPrefix(console.String(), "[deploy/api/web] --- attempt 1 of 2\n[deploy/api/web] deploying with token ***\n") {
+		t.Errorf("Expected prefixed console output, got:\n%s", console.String())
+	}
+}
+
+// streamingPlugin streams its output through ExecuteStreaming
+type streamingPlugin struct {
+	MockPlugin
+}
+
+func (p *streamingPlugin) ExecuteStreaming(ctx context.Context, config map[string]interface{}, output io.Writer) (*plugin.Result, error) {
+	fmt.Fprintln(output, "step 1/2")
+	fmt.Fprintln(output, "step 2/2")
+	return &plugin.Result{Success: true, Message: "built"}, nil
+}
+
+func TestExecuteGraphStreamingJobLogs(t *testing.T) {
+	executor := newTestExecutor(t, &streamingPlugin{MockPlugin{name: "build"}})
+	var console strings.Builder
+	executor.SetConsole(NewConsole(&console, nil), true)
+	dir := t.TempDir()
+	executor.SetJobLogs(dir, nil)
+
+	ctx := plugin.WithExecutionID(context.Background(), "exec-1")
+	stageResult := &models.StageResult{}
+	graph := BuildDependencyGraph([]models.Job{{Name: "image", Type: "build"}})
+	if err := executor.ExecuteGraph(ctx, graph, stageResult, GraphOptions{StageName: "build"}); err != nil {
+		t.Fatalf("ExecuteGraph() error = %v", err)
+	}
+
+	data, err := os.ReadFile(stageResult.Jobs[0].LogFile)
+	if err != nil {
+		t.Fatalf("Failed to read job log: %v", err)
+	}
+	if !strings.Contains(string(data), "step 1/2\nstep 2/2\n") {
+		t.Errorf("Expected the streamed output in the job log, got:\n%s", data)
+	}
+	if !strings.Contains(console.String(), "[build/image] step 2/2\n") {
+		t.Errorf("Expected the streamed output on the console, got:\n%s", console.String())
+	}
+}
+
+func TestExecuteGraphConsoleOutput(t *testing.T) {
+	tests := []struct {
+		name     string
+		prefix   bool
+		expected []string
+	}{
+		{
+			name:     "prefixed",
+			prefix:   true,
+			expected: []string{"[build/a] step 1/2\n", "[build/a] step 2/2\n", "[build/b] step 1/2\n", "[build/b] step 2/2\n"},
+		},
+		{
+			name:     "unprefixed",
+			expected: []string{"step 1/2\n", "step 1/2\n", "step 2/2\n", "step 2/2\n"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			executor := newTestExecutor(t, &streamingPlugin{MockPlugin{name: "build"}})
+			var console strings.Builder
+			executor.SetConsole(NewConsole(&console, nil), tt.prefix)
+			executor.SetJobLogs("", func(s string) string { return strings.ReplaceAll(s, "1/2", "one of two") })
+
+			stageResult := &models.StageResult{}
+			graph := BuildDependencyGraph([]models.Job{{Name: "a", Type: "build"}, {Name: "b", Type: "build"}})
+			if err := executor.ExecuteGraph(context.Background(), graph, stageResult, GraphOptions{StageName: "build"}); err != nil {
+				t.Fatalf("ExecuteGraph() error = %v", err)
+			}
+			if stageResult.Jobs[0].LogFile != "" {
+				t.Errorf("Expected no log file without a log directory, got %s", stageResult.Jobs[0].LogFile)
+			}
+
+			// Without a log directory the output still goes to the console, whole
+			// lines at a time and masked
+			lines := strings.SplitAfter(console.String(), "\n")
+			lines = lines[:len(lines)-1]
+			sort.Strings(lines)
+			for i := range tt.expected {
+				tt.expected[i] = strings.ReplaceAll(tt.expected[i], "1/2", "one of two")
+			}
+			sort.Strings(tt.expected)
+			if strings.Join(lines, "") != strings.Join(tt.expected, "") {
+				t.Errorf("Expected console lines %q, got %q", tt.expected, lines)
+			}
+		})
+	}
+}
+
+// contextPlugin records the job context values its Validate and Execute receive
+type contextPlugin struct {
+	MockPlugin
+	mutex sync.Mutex
+	seen  []string
+}
+
+func (p *contextPlugin) record(ctx context.Context, call string) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.seen = append(p.seen, fmt.Sprintf("%s %s/%s attempt=%d dryRun=%v", call, plugin.StageName(ctx), plugin.JobName(ctx), plugin.Attempt(ctx), plugin.DryRun(ctx)))
+}
+
+func (p *contextPlugin) Validate(ctx context.Context, config map[string]interface{}) error {
+	p.record(ctx, "validate")
+	return nil
+}
+
+func (p *contextPlugin) Execute(ctx context.Context, config map[string]interface{}) (*plugin.Result, error) {
+	p.record(ctx, "execute")
+	return &plugin.Result{Success: plugin.Attempt(ctx) == 2}, nil
+}
+
+func TestExecuteGraphJobContext(t *testing.T) {
+	recorder := &contextPlugin{MockPlugin: MockPlugin{name: "flaky"}}
+	executor := newTestExecutor(t, recorder)
+
+	tests := []struct {
+		name     string
+		options  GraphOptions
+		expected []string
+	}{
+		{
+			name:    "execution",
+			options: GraphOptions{StageName: "deploy"},
+			expected: []string{
+				"validate deploy/web attempt=1 dryRun=false", "execute deploy/web attempt=1 dryRun=false",
+				"validate deploy/web attempt=2 dryRun=false", "execute deploy/web attempt=2 dryRun=false",
+			},
+		},
+		{
+			name:     "dry run",
+			options:  GraphOptions{StageName: "deploy", DryRun: true},
+			expected: []string{"validate deploy/web attempt=0 dryRun=true"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder.seen = nil
+			ctx := plugin.WithStageName(context.Background(), "deploy")
+			graph := BuildDependencyGraph([]models.Job{{Name: "web", Type: "flaky", Retries: 1, RetryDelay: "1ms"}})
+			if err := executor.ExecuteGraph(ctx, graph, &models.StageResult{}, tt.options); err != nil {
+				t.Fatalf("ExecuteGraph() error = %v", err)
+			}
+			if !reflect.DeepEqual(recorder.seen, tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, recorder.seen)
+			}
+		})
+	}
+}
+
+func TestExecuteGraphDryRun(t *testing.T) {
+	executed := false
+	executor := newTestExecutor(t, &MockPlugin{
+		name: "check",
+		validate: func(config map[string]interface{}) error {
+			if config["target"] != "prod" {
+				return fmt.Errorf("unexpected target %v", config["target"])
+			}
+			return nil
+		},
+		execute: func(ctx context.Context, config map[string]interface{}) (*plugin.Result, error) {
+			executed = true
+			return &plugin.Result{Success: true}, nil
+		},
+	})
+	ctx := plugin.WithVariables(context.Background(), map[string]interface{}{"env": "prod"})
+
+	tests := []struct {
+		name        string
+		target      string
+		wantErr     bool
+		wantBatches [][]string
+	}{
+		{name: "valid config", target: "${variables.env}", wantBatches: [][]string{{"a", "b"}, {"c"}}},
+		{name: "invalid config", target: "staging", wantErr: true, wantBatches: [][]string{{"a", "b"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := map[string]interface{}{"target": tt.target}
+			graph := BuildDependencyGraph([]models.Job{
+				{Name: "a", Type: "check", Config: config},
+				{Name: "b", Type: "check", Config: config},
+				{Name: "c", Type: "check", Config: config, DependsOn: []string{"a", "b"}},
+			})
+
+			stageResult := &models.StageResult{}
+			err := executor.ExecuteGraph(ctx, graph, stageResult, GraphOptions{DryRun: true})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ExecuteGraph() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if fmt.Sprint(stageResult.Batches) != fmt.Sprint(tt.wantBatches) {
+				t.Errorf("Expected batches %v, got %v", tt.wantBatches, stageResult.Batches)
+			}
+		})
+	}
+
+	if executed {
+		t.Error("Expected dry run not to execute plugins")
+	}
+}
+
+func TestExecuteGraphStopsSchedulingWhenCanceled(t *testing.T) {
+	tests := []struct {
+		name         string
+		jobs         []models.Job
+		maxParallel  int
+		wantStarted  string
+		wantErr      string
+		wantCanceled []string
+	}{
+		{
+			name: "next batch",
+			jobs: []models.Job{
+				{Name: "deploy", Type: "cancel"},
+				{Name: "verify", Type: "record", DependsOn: []string{"deploy"}},
+			},
+			wantStarted: "[]",
+			wantErr:     "canceled before starting jobs verify: context canceled",
+		},
+		{
+			name: "jobs waiting for a slot",
+			jobs: []models.Job{
+				{Name: "a", Type: "cancel", Priority: 1},
+				{Name: "b", Type: "record"},
+				{Name: "c", Type: "record"},
+			},
+			maxParallel:  1,
+			wantStarted:  "[]",
+			wantErr:      "job b canceled",
+			wantCanceled: []string{"b", "c"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			var started []string
+			executor := newTestExecutor(t,
+				&MockPlugin{
+					name: "cancel",
+					execute: func(ctx context.Context, config map[string]interface{}) (*plugin.Result, error) {
+						// The job succeeds, but the execution is interrupted meanwhile
+						cancel()
+						return &plugin.Result{Success: true}, nil
+					},
+				},
+				&MockPlugin{
+					name: "record",
+					execute: func(ctx context.Context, config map[string]interface{}) (*plugin.Result, error) {
+						started = append(started, "job")
+						return &plugin.Result{Success: true}, nil
+					},
+				},
+			)
+
+			stageResult := &models.StageResult{}
+			err := executor.ExecuteGraph(ctx, BuildDependencyGraph(tt.jobs), stageResult, GraphOptions{MaxParallel: tt.maxParallel})
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) || !errors.Is(err, context.Canceled) {
+				t.Fatalf("Expected a cancellation error containing %q, got %v", tt.wantErr, err)
+			}
+			if fmt.Sprint(started) != tt.wantStarted {
+				t.Errorf("Expected no job started after the cancellation, got %v", started)
+			}
+
+			var canceled []string
+			for _, job := range stageResult.Jobs {
+				if job.Canceled {
+					canceled = append(canceled, job.Name)
+				}
+			}
+			if fmt.Sprint(canceled) != fmt.Sprint(tt.wantCanceled) {
+				t.Errorf("Expected canceled jobs %v, got %v", tt.wantCanceled, canceled)
+			}
+		})
+	}
+}
+
+func TestExecuteGraphContinueOnError(t *testing.T) {
+	executor := newTestExecutor(t,
+		&MockPlugin{name: "ok"},
+		&MockPlugin{
+			name: "fail",
+			execute: func(ctx context.Context, config map[string]interface{}) (*plugin.Result, error) {
+				return &plugin.Result{Success: false, Message: "boom"}, nil
+			},
+		},
+	)
+
+	tests := []struct {
+		name            string
+		continueOnError bool
+		wantErr         bool
+		wantJobs        int
+		wantSkipped     int
+	}{
+		{name: "failure aborts the stage", wantErr: true, wantJobs: 1, wantSkipped: 1},
+		{name: "continue on error runs dependents", continueOnError: true, wantJobs: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			graph := BuildDependencyGraph([]models.Job{
+				{Name: "notify", Type: "fail", ContinueOnError: tt.continueOnError},
+				{Name: "deploy", Type: "ok", DependsOn: []string{"notify"}},
+			})
+
+			stageResult := &models.StageResult{}
+			err := executor.ExecuteGraph(context.Background(), graph, stageResult, GraphOptions{})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ExecuteGraph() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			skipped := 0
+			for _, job := range stageResult.Jobs {
+				if job.Skipped {
+					skipped++
+				}
+			}
+			if ran := len(stageResult.Jobs) - skipped; ran != tt.wantJobs {
+				t.Errorf("Expected %d jobs to run, got %d", tt.wantJobs, ran)
+			}
+			if skipped != tt.wantSkipped {
+				t.Errorf("Expected %d skipped jobs, got %d", tt.wantSkipped, skipped)
+			}
+			if stageResult.FailedJobs != 1 {
+				t.Errorf("Expected 1 failed job, got %d", stageResult.FailedJobs)
+			}
+			if stageResult.Jobs[0].ContinuedOnError != tt.continueOnError {
+				t.Errorf("Expected ContinuedOnError = %t", tt.continueOnError)
+			}
+		})
+	}
+}
+
+func TestExecuteGraphSkippedJobs(t *testing.T) {
+	executor := newTestExecutor(t,
+		&MockPlugin{name: "ok"},
+		&MockPlugin{
+			name: "fail",
+			execute: func(ctx context.Context, config map[string]interface{}) (*plugin.Result, error) {
+				return &plugin.Result{Success: false, Message: "boom"}, nil
+			},
+		},
+	)
+
+	tests := []struct {
+		name     string
+		jobs     []models.Job
+		options  GraphOptions
+		expected map[string]string
+	}{
+		{
+			name: "dependents of a failed job",
+			jobs: []models.Job{
+				{Name: "build", Type: "fail"},
+				{Name: "lint", Type: "ok"},
+				{Name: "deploy", Type: "ok", DependsOn: []string{"build"}},
+				{Name: "verify", Type: "ok", DependsOn: []string{"deploy", "lint"}},
+				{Name: "docs", Type: "ok", DependsOn: []string{"lint"}},
+			},
+			expected: map[string]string{
+				"deploy": "not run: blocked by failed job build",
+				"verify": "not run: blocked by failed job build",
+				"docs":   "not run: the stage stopped after job build failed",
+			},
+		},
+		{
+			name: "dependents of a failed job when keeping going",
+			jobs: []models.Job{
+				{Name: "build", Type: "fail"},
+				{Name: "lint", Type: "ok"},
+				{Name: "deploy", Type: "ok", DependsOn: []string{"build"}},
+				{Name: "verify", Type: "ok", DependsOn: []string{"deploy", "lint"}},
+				{Name: "docs", Type: "ok", DependsOn: []string{"lint"}},
+			},
+			options: GraphOptions{KeepGoing: true},
+			expected: map[string]string{
+				"deploy": "not run: blocked by failed job build",
+				"verify": "not run: blocked by failed job build",
+			},
+		},
+		{
+			name: "several failed dependencies",
+			jobs: []models.Job{
+				{Name: "build", Type: "fail"},
+				{Name: "test", Type: "fail"},
+				{Name: "deploy", Type: "ok", DependsOn: []string{"test", "build"}},
+			},
+			expected: map[string]string{
+				"deploy": "not run: blocked by failed job build, failed job test",
+			},
+		},
+		{
+			name: "failure budget exceeded",
+			jobs: []models.Job{
+				{Name: "a", Type: "fail", ContinueOnError: true},
+				{Name: "b", Type: "fail", ContinueOnError: true},
+				{Name: "deploy", Type: "ok", DependsOn: []string{"a"}},
+			},
+			options: GraphOptions{MaxFailures: 1},
+			expected: map[string]string{
+				"deploy": "not run: failure budget exceeded: 2 jobs failed (maxFailures: 1)",
+			},
+		},
+		{
+			name:     "no failure",
+			jobs:     []models.Job{{Name: "build", Type: "ok"}, {Name: "deploy", Type: "ok", DependsOn: []string{"build"}}},
+			expected: map[string]string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stageResult := &models.StageResult{}
+			executor.ExecuteGraph(context.Background(), BuildDependencyGraph(tt.jobs), stageResult, tt.options)
+
+			skipped := make(map[string]string)
+			for _, job := range stageResult.Jobs {
+				if !job.Skipped {
+					continue
+				}
+				if job.Success || job.Attempts != 0 {
+					t.Errorf("Expected skipped job %s not to run, got %+v", job.Name, job)
+				}
+				skipped[job.Name] = job.Message
+			}
+			if !reflect.DeepEqual(skipped, tt.expected) {
+				t.Errorf("Expected skipped jobs %v, got %v", tt.expected, skipped)
+			}
+			if len(stageResult.Jobs) != len(tt.jobs) {
+				t.Errorf("Expected a result for each of the %d jobs, got %d", len(tt.jobs), len(stageResult.Jobs))
+			}
+		})
+	}
+}
+
+func TestExecuteGraphKeepGoing(t *testing.T) {
+	executor := newTestExecutor(t,
+		&MockPlugin{name: "ok"},
+		&MockPlugin{
+			name: "fail",
+			execute: func(ctx context.Context, config map[string]interface{}) (*plugin.Result, error) {
+				return &plugin.Result{Success: false, Message: "boom"}, nil
+			},
+		},
+	)
+
+	tests := []struct {
+		name        string
+		jobs        []models.Job
+		options     GraphOptions
+		expectedErr string
+		expectedRan []string
+	}{
+		{
+			name: "all failures are reported",
+			jobs: []models.Job{
+				{Name: "build", Type: "fail"},
+				{Name: "lint", Type: "ok"},
+				{Name: "test", Type: "fail", DependsOn: []string{"lint"}},
+				{Name: "deploy", Type: "ok", DependsOn: []string{"build"}},
+				{Name: "docs", Type: "ok", DependsOn: []string{"lint"}},
+			},
+			options:     GraphOptions{StageName: "ci", KeepGoing: true},
+			expectedErr: "job build failed: boom; job test failed: boom",
+			expectedRan: []string{"build", "docs", "lint", "test"},
+		},
+		{
+			name: "single failure",
+			jobs: []models.Job{
+				{Name: "build", Type: "fail"},
+				{Name: "lint", Type: "ok"},
+			},
+			options:     GraphOptions{StageName: "ci", KeepGoing: true},
+			expectedErr: "job build failed: boom",
+			expectedRan: []string{"build", "lint"},
+		},
+		{
+			name: "failure budget still stops the stage",
+			jobs: []models.Job{
+				{Name: "a", Type: "fail"},
+				{Name: "b", Type: "fail"},
+				{Name: "c", Type: "ok", DependsOn: []string{"a"}},
+				{Name: "d", Type: "ok", DependsOn: []string{"b"}},
+			},
+			options:     GraphOptions{StageName: "ci", KeepGoing: true, MaxFailures: 1},
+			expectedErr: "failure budget exceeded",
+			expectedRan: []string{"a", "b"},
+		},
+		{
+			name: "fail fast",
+			jobs: []models.Job{
+				{Name: "build", Type: "fail"},
+				{Name: "lint", Type: "ok"},
+				{Name: "docs", Type: "ok", DependsOn: []string{"lint"}},
+			},
+			options:     GraphOptions{StageName: "ci"},
+			expectedErr: "job build failed: boom",
+			expectedRan: []string{"build", "lint"},
+		},
+		{
+			name: "fail fast reports every failure of the batch",
+			jobs: []models.Job{
+				{Name: "build", Type: "fail"},
+				{Name: "test", Type: "fail"},
+				{Name: "deploy", Type: "ok", DependsOn: []string{"build"}},
+			},
+			options:     GraphOptions{StageName: "ci"},
+			expectedErr: "job build failed: boom; job test failed: boom",
+			expectedRan: []string{"build", "test"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stageResult := &models.StageResult{}
+			err := executor.ExecuteGraph(context.Background(), BuildDependencyGraph(tt.jobs), stageResult, tt.options)
+			if err == nil || !strings.Contains(err.Error(), tt.expectedErr) {
+				t.Fatalf("Expected error containing %q, got %v", tt.expectedErr, err)
+			}
+			var jobErr *JobError
+			if strings.Contains(tt.expectedErr, "job ") && (!errors.As(err, &jobErr) || jobErr.Stage != "ci") {
+				t.Errorf("Expected a job error of stage ci, got %#v", err)
+			}
+
+			var ran []string
+			for _, job := range stageResult.Jobs {
+				if !job.Skipped {
+					ran = append(ran, job.Name)
+				}
+			}
+			sort.Strings(ran)
+			if !reflect.DeepEqual(ran, tt.expectedRan) {
+				t.Errorf("Expected jobs %v to run, got %v", tt.expectedRan, ran)
+			}
+			if len(stageResult.Jobs) != len(tt.jobs) {
+				t.Errorf("Expected a result for each of the %d jobs, got %d", len(tt.jobs), len(stageResult.Jobs))
+			}
+		})
+	}
+}
+
+func TestExecuteGraphDependencyConditions(t *testing.T) {
+	executor := newTestExecutor(t,
+		&MockPlugin{name: "ok"},
+		&MockPlugin{
+			name: "fail",
+			execute: func(ctx context.Context, config map[string]interface{}) (*plugin.Result, error) {
+				return &plugin.Result{Success: false, Message: "boom"}, nil
+			},
+		},
+	)
+	// jobs returns a deploy job of the given type, with jobs handling each of its outcomes
+	jobs := func(deploy models.Job, cleanupType string) []models.Job {
+		deploy.Name = "deploy"
+		return []models.Job{
+			deploy,
+			{Name: "verify", Type: "ok", DependsOn: []string{"deploy"}},
+			{Name: "cleanup", Type: cleanupType, DependsOn: []string{"deploy"}, DependsOnConditions: map[string]string{"deploy": models.DependencyOnFailure}},
+			{Name: "notify", Type: "ok", DependsOn: []string{"deploy"}, DependsOnConditions: map[string]string{"deploy": models.DependencyOnAlways}},
+			{Name: "report", Type: "ok", DependsOn: []string{"cleanup"}},
+		}
+	}
+
+	tests := []struct {
+		name            string
+		jobs            []models.Job
+		expectedErr     string
+		expectedRan     []string
+		expectedSkipped map[string]string
+	}{
+		{
+			name:        "success",
+			jobs:        jobs(models.Job{Type: "ok"}, "ok"),
+			expectedRan: []string{"deploy", "notify", "verify"},
+			expectedSkipped: map[string]string{
+				"cleanup": "not run: the conditions of its dependencies were not met",
+				"report":  "not run: the conditions of its dependencies were not met",
+			},
+		},
+		{
+			name:        "failure runs its handlers",
+			jobs:        jobs(models.Job{Type: "fail"}, "ok"),
+			expectedErr: "job deploy failed: boom",
+			expectedRan: []string{"cleanup", "deploy", "notify"},
+			expectedSkipped: map[string]string{
+				"verify": "not run: blocked by failed job deploy",
+				"report": "not run: the stage stopped after job deploy failed",
+			},
+		},
+		{
+			name:        "failing handler",
+			jobs:        jobs(models.Job{Type: "fail"}, "fail"),
+			expectedErr: "job deploy failed: boom; job cleanup failed: boom",
+			expectedRan: []string{"cleanup", "deploy", "notify"},
+			expectedSkipped: map[string]string{
+				"verify": "not run: blocked by failed job deploy",
+				"report": "not run: blocked by failed job cleanup",
+			},
+		},
+		{
+			name:        "failure allowed by continueOnError",
+			jobs:        jobs(models.Job{Type: "fail", ContinueOnError: true}, "ok"),
+			expectedRan: []string{"cleanup", "deploy", "notify", "report", "verify"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stageResult := &models.StageResult{}
+			err := executor.ExecuteGraph(context.Background(), BuildDependencyGraph(tt.jobs), stageResult, GraphOptions{StageName: "deploy"})
+			if tt.expectedErr == "" && err != nil {
+				t.Fatalf("ExecuteGraph() error = %v", err)
+			}
+			if tt.expectedErr != "" && (err == nil || !strings.Contains(err.Error(), tt.expectedErr)) {
+				t.Fatalf("Expected error containing %q, got %v", tt.expectedErr, err)
+			}
+
+			var ran []string
+			skipped := make(map[string]string)
+			for _, job := range stageResult.Jobs {
+				if job.Skipped {
+					skipped[job.Name] = job.Message
+				} else {
+					ran = append(ran, job.Name)
+				}
+			}
+			sort.Strings(ran)
+			if !reflect.DeepEqual(ran, tt.expectedRan) {
+				t.Errorf("Expected jobs %v to run, got %v", tt.expectedRan, ran)
+			}
+			if len(skipped) != len(tt.expectedSkipped) {
+				t.Errorf("Expected skipped jobs %v, got %v", tt.expectedSkipped, skipped)
+			}
+			for name, message := range tt.expectedSkipped {
+				if skipped[name] != message {
+					t.Errorf("Expected job %s skipped with %q, got %q", name, message, skipped[name])
+				}
+			}
+		})
+	}
+}
+
+func TestExecuteGraphFailureBudget(t *testing.T) {
+	executor := newTestExecutor(t,
+		&MockPlugin{name: "ok"},
+		&MockPlugin{
+			name: "fail",
+			execute: func(ctx context.Context, config map[string]interface{}) (*plugin.Result, error) {
+				return &plugin.Result{Success: false, Message: "boom"}, nil
+			},
+		},
+	)
+
+	tests := []struct {
+		name        string
+		options     GraphOptions
+		wantReason  string
+		wantBatches int
+	}{
+		{name: "no budget", wantBatches: 3},
+		{name: "within budget", options: GraphOptions{MaxFailures: 2}, wantBatches: 3},
+		{
+			name:        "max failures exceeded",
+			options:     GraphOptions{MaxFailures: 1},
+			wantReason:  "failure budget exceeded: 2 jobs failed (maxFailures: 1)",
+			wantBatches: 1,
+		},
+		{
+			name:        "max failure percent exceeded",
+			options:     GraphOptions{MaxFailurePercent: 25},
+			wantReason:  "failure budget exceeded: 2 of 5 jobs failed (maxFailurePercent: 25)",
+			wantBatches: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Two best-effort jobs fail in the first batch; the others could still run
+			graph := BuildDependencyGraph([]models.Job{
+				{Name: "canary-a", Type: "fail", ContinueOnError: true},
+				{Name: "canary-b", Type: "fail", ContinueOnError: true},
+				{Name: "canary-c", Type: "ok"},
+				{Name: "rollout", Type: "ok", DependsOn: []string{"canary-c"}},
+				{Name: "verify", Type: "ok", DependsOn: []string{"rollout"}},
+			})
+
+			stageResult := &models.StageResult{}
+			err := executor.ExecuteGraph(context.Background(), graph, stageResult, tt.options)
+			if tt.wantReason == "" {
+				if err != nil {
+					t.Fatalf("ExecuteGraph() error = %v", err)
+				}
+			} else if err == nil || err.Error() != tt.wantReason {
+				t.Fatalf("ExecuteGraph() error = %v, want %q", err, tt.wantReason)
+			}
+			if stageResult.AbortReason != tt.wantReason {
+				t.Errorf("AbortReason = %q, want %q", stageResult.AbortReason, tt.wantReason)
+			}
+			if len(stageResult.Batches) != tt.wantBatches {
+				t.Errorf("Expected %d batches, got %v", tt.wantBatches, stageResult.Batches)
+			}
+		})
+	}
+}
+
+func TestExecuteGraphCanceled(t *testing.T) {
+	tests := []struct {
+		name        string
+		gracePeriod time.Duration
+		cleanup     time.Duration
+		cleanedUp   bool
+	}{
+		{name: "cleans up within grace period", gracePeriod: time.Second, cleanup: 10 * time.Millisecond, cleanedUp: true},
+		{name: "abandoned after grace period", gracePeriod: 10 * time.Millisecond, cleanup: time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			started := make(chan struct{})
+			var cleanedUp sync.WaitGroup
+			cleanedUp.Add(1)
+			var mu sync.Mutex
+			cleaned := false
+
+			executor := newTestExecutor(t,
+				&MockPlugin{name: "ok"},
+				&MockPlugin{
+					name: "blocking",
+					execute: func(ctx context.Context, config map[string]interface{}) (*plugin.Result, error) {
+						defer cleanedUp.Done()
+						close(started)
+						<-ctx.Done()
+						time.Sleep(tt.cleanup)
+						mu.Lock()
+						cleaned = true
+						mu.Unlock()
+						return nil, ctx.Err()
+					},
+				},
+			)
+			executor.SetCancelGracePeriod(tt.gracePeriod)
+
+			graph := BuildDependencyGraph([]models.Job{
+				{Name: "deploy", Type: "blocking"},
+				{Name: "verify", Type: "ok", DependsOn: []string{"deploy"}},
+			})
+			ctx, cancel := context.WithCancel(context.Background())
+			go func() {
+				<-started
+				cancel()
+			}()
+
+			stageResult := &models.StageResult{}
+			err := executor.ExecuteGraph(ctx, graph, stageResult, GraphOptions{})
+			if err == nil || !strings.Contains(err.Error(), "job deploy canceled") {
+				t.Errorf("Expected cancellation error, got %v", err)
+			}
+
+			mu.Lock()
+			if cleaned != tt.cleanedUp {
+				t.Errorf("Expected cleaned up = %v before returning, got %v", tt.cleanedUp, cleaned)
+			}
+			mu.Unlock()
+
+			if len(stageResult.Jobs) != 2 || !stageResult.Jobs[1].Skipped {
+				t.Fatalf("Expected only the canceled job to run, got %+v", stageResult.Jobs)
+			}
+			job := stageResult.Jobs[0]
+			if job.Success || !job.Canceled {
+				t.Errorf("Expected job to be canceled, got %+v", job)
+			}
+			if skipped := stageResult.Jobs[1]; skipped.Message != "not run: blocked by canceled job deploy" {
+				t.Errorf("Expected verify to be blocked by the canceled job, got %q", skipped.Message)
+			}
+			if stageResult.FailedJobs != 0 {
+				t.Errorf("Expected canceled job not to count as failed, got %d", stageResult.FailedJobs)
+			}
+			cleanedUp.Wait()
+		})
+	}
+}
@@ -1,15 +1,21 @@
 package engine
 
 import (
+	"sort"
+	
 	"github.com/cuongtl1992/grp-cli/internal/models"
 )
 
 // JobGraph represents a dependency graph of jobs
 type JobGraph struct {
 	jobs           map[string]models.Job
+	order          []string
 	dependencies   map[string][]string
 	dependents     map[string][]string
+	optional       map[Edge]bool
+	conditions     map[Edge]string
 	completed      map[string]bool
+	failed         map[string]bool
 }
 
 // NewJobGraph creates a new job graph
@@ -18,12 +24,18 @@ func NewJobGraph() *JobGraph {
 		jobs:         make(map[string]models.Job),
 		dependencies: make(map[string][]string),
 		dependents:   make(map[string][]string),
+		optional:     make(map[Edge]bool),
+		conditions:   make(map[Edge]string),
 		completed:    make(map[string]bool),
+		failed:       make(map[string]bool),
 	}
 }
 
 // AddJob adds a job to the graph
 func (g *JobGraph) AddJob(job models.Job) {
+	if _, exists := g.jobs[job.Name]; !exists {
+		g.order = append(g.order, job.Name)
+	}
 	g.jobs[job.Name] = job
 	
 	// Initialize empty dependency lists if they don't exist
@@ -45,38 +57,130 @@ func (g *JobGraph) AddDependency(jobName, dependsOn string) {
 	g.dependents[dependsOn] = append(g.dependents[dependsOn], jobName)
 }
 
-// GetReadyJobs returns jobs that are ready to be executed
+// AddConditionalDependency adds a dependency released by an outcome of the job
+// depended on, one of the models.DependencyOn conditions
+func (g *JobGraph) AddConditionalDependency(jobName, dependsOn, on string) {
+	g.AddDependency(jobName, dependsOn)
+	if on != "" && on != models.DependencyOnSuccess {
+		g.conditions[Edge{From: dependsOn, To: jobName}] = on
+	}
+}
+
+// AddOptionalDependency orders a job after another if that job is in the graph;
+// jobs must be added before their optional dependencies
+func (g *JobGraph) AddOptionalDependency(jobName, dependsOn string) {
+	if _, exists := g.jobs[dependsOn]; !exists {
+		return
+	}
+	g.AddDependency(jobName, dependsOn)
+	g.optional[Edge{From: dependsOn, To: jobName}] = true
+}
+
+// Edge is a dependency between two jobs: To depends on From. An optional edge
+// only orders the jobs. On is the outcome of From that releases To, empty for success.
+type Edge struct {
+	From     string
+	To       string
+	Optional bool
+	On       string
+}
+
+// Jobs returns all jobs in the order they were added
+func (g *JobGraph) Jobs() []models.Job {
+	jobs := make([]models.Job, 0, len(g.order))
+	for _, name := range g.order {
+		jobs = append(jobs, g.jobs[name])
+	}
+	return jobs
+}
+
+// Edges returns every dependency in the graph, ordered by dependent job
+func (g *JobGraph) Edges() []Edge {
+	var edges []Edge
+	for _, name := range g.order {
+		for _, dep := range g.dependencies[name] {
+			edge := Edge{From: dep, To: name}
+			edge.Optional = g.optional[edge]
+			edge.On = g.conditions[edge]
+			edges = append(edges, edge)
+		}
+	}
+	return edges
+}
+
+// GetReadyJobs returns jobs that are ready to be executed, highest priority first
+// and then by name, so that the jobs start in a deterministic order
 func (g *JobGraph) GetReadyJobs() []models.Job {
 	var readyJobs []models.Job
 	
-	for name, job := range g.jobs {
-		// Skip already completed jobs
-		if g.completed[name] {
+	for _, name := range g.order {
+		job := g.jobs[name]
+		
+		// Skip already completed or failed jobs
+		if g.completed[name] || g.failed[name] {
 			continue
 		}
 		
-		// Check if all dependencies are completed
-		allDepsCompleted := true
+		// Check if the outcome of every dependency releases the job
+		allDepsReleased := true
 		for _, dep := range g.dependencies[name] {
-			if !g.completed[dep] {
-				allDepsCompleted = false
+			if !g.releases(dep, name) {
+				allDepsReleased = false
 				break
 			}
 		}
 		
-		if allDepsCompleted {
+		if allDepsReleased {
 			readyJobs = append(readyJobs, job)
 		}
 	}
 	
+	sort.Slice(readyJobs, func(i, j int) bool {
+		if readyJobs[i].Priority != readyJobs[j].Priority {
+			return readyJobs[i].Priority > readyJobs[j].Priority
+		}
+		return readyJobs[i].Name < readyJobs[j].Name
+	})
 	return readyJobs
 }
 
+// releases reports whether the outcome of job dep lets job name run: a failure
+// for the jobs depending on its failure, any outcome for those depending on it
+// always, and otherwise a success, or a failure allowed by continueOnError
+func (g *JobGraph) releases(dep, name string) bool {
+	switch g.conditions[Edge{From: dep, To: name}] {
+	case models.DependencyOnFailure:
+		return g.failed[dep]
+	case models.DependencyOnAlways:
+		return g.completed[dep] || g.failed[dep]
+	default:
+		return g.completed[dep] || (g.failed[dep] && g.jobs[dep].ContinueOnError)
+	}
+}
+
+// runsOnFailure reports whether a job depends on the failure of another job,
+// with the failure or always condition
+func (g *JobGraph) runsOnFailure(jobName string) bool {
+	for _, dep := range g.dependencies[jobName] {
+		if g.conditions[Edge{From: dep, To: jobName}] != "" {
+			return true
+		}
+	}
+	return false
+}
+
 // MarkCompleted marks a job as completed
 func (g *JobGraph) MarkCompleted(jobName string) {
 	g.completed[jobName] = true
 }
 
+// MarkFailed marks a job as failed, so that it is not run again and the jobs
+// depending on it only become ready if they run on its failure, or it allows
+// failures with continueOnError
+func (g *JobGraph) MarkFailed(jobName string) {
+	g.failed[jobName] = true
+}
+
 // IsCompleted returns true if all jobs are completed
 func (g *JobGraph) IsCompleted() bool {
 	for name := range g.jobs {
@@ -87,7 +191,7 @@ func (g *JobGraph) IsCompleted() bool {
 	return true
 }
 
-// GetRemainingJobs returns jobs that are not yet completed
+// GetRemainingJobs returns jobs that are not yet completed, sorted by name
 func (g *JobGraph) GetRemainingJobs() []models.Job {
 	var remainingJobs []models.Job
 	
@@ -97,6 +201,9 @@ func (g *JobGraph) GetRemainingJobs() []models.Job {
 		}
 	}
 	
+	sort.Slice(remainingJobs, func(i, j int) bool {
+		return remainingJobs[i].Name < remainingJobs[j].Name
+	})
 	return remainingJobs
 }
 
@@ -0,0 +1,149 @@
+package engine
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/cuongtl1992/grp-cli/internal/models"
+)
+
+func TestGetReadyJobsOrder(t *testing.T) {
+	tests := []struct {
+		name     string
+		jobs     []models.Job
+		expected []string
+	}{
+		{
+			name:     "by name without priorities",
+			jobs:     []models.Job{{Name: "web"}, {Name: "api"}, {Name: "db"}},
+			expected: []string{"api", "db", "web"},
+		},
+		{
+			name:     "higher priority first",
+			jobs:     []models.Job{{Name: "web"}, {Name: "api", Priority: -1}, {Name: "db", Priority: 10}, {Name: "cache", Priority: 10}},
+			expected: []string{"cache", "db", "web", "api"},
+		},
+		{
+			name:     "only jobs whose dependencies completed",
+			jobs:     []models.Job{{Name: "build"}, {Name: "deploy", Priority: 5, DependsOn: []string{"build"}}, {Name: "lint"}},
+			expected: []string{"build", "lint"},
+		},
+		{
+			name:     "optional dependencies in the graph are waited for",
+			jobs:     []models.Job{{Name: "build"}, {Name: "deploy", OptionalDependsOn: []string{"build"}}},
+			expected: []string{"build"},
+		},
+		{
+			name:     "optional dependencies not in the graph are ignored",
+			jobs:     []models.Job{{Name: "deploy", OptionalDependsOn: []string{"migrate"}}, {Name: "lint"}},
+			expected: []string{"deploy", "lint"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var names []string
+			for _, job := range BuildDependencyGraph(tt.jobs).GetReadyJobs() {
+				names = append(names, job.Name)
+			}
+			if fmt.Sprint(names) != fmt.Sprint(tt.expected) {
+				t.Errorf("Expected ready jobs %v, got %v", tt.expected, names)
+			}
+		})
+	}
+}
+
+func TestJobGraphStableOrder(t *testing.T) {
+	var jobs []models.Job
+	for _, name := range []string{"m", "c", "x", "a", "q", "f", "k", "b"} {
+		jobs = append(jobs, models.Job{Name: name})
+	}
+	jobs = append(jobs, models.Job{Name: "z", DependsOn: []string{"m"}}, models.Job{Name: "d", DependsOn: []string{"m"}})
+	graph := BuildDependencyGraph(jobs)
+	graph.MarkCompleted("c")
+	graph.MarkFailed("x")
+
+	jobNames := func(jobs []models.Job) string {
+		names := make([]string, len(jobs))
+		for i, job := range jobs {
+			names[i] = job.Name
+		}
+		return fmt.Sprint(names)
+	}
+
+	tests := []struct {
+		name     string
+		list     func() []models.Job
+		expected string
+	}{
+		{name: "ready jobs", list: graph.GetReadyJobs, expected: "[a b f k m q]"},
+		{name: "remaining jobs", list: graph.GetRemainingJobs, expected: "[a b d f k m q x z]"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Map iteration order varies between calls, the returned order must not
+			for i := 0; i < 20; i++ {
+				if got := jobNames(tt.list()); got != tt.expected {
+					t.Fatalf("Call %d: expected %s, got %s", i+1, tt.expected, got)
+				}
+			}
+		})
+	}
+}
+
+func TestJobGraphEdges(t *testing.T) {
+	graph := BuildDependencyGraph([]models.Job{
+		{Name: "build"},
+		{Name: "migrate"},
+		{Name: "deploy", DependsOn: []string{"build"}, OptionalDependsOn: []string{"migrate", "seed"}},
+		{Name: "undo", DependsOn: []string{"deploy"}, DependsOnConditions: map[string]string{"deploy": models.DependencyOnFailure}},
+	})
+
+	expected := []Edge{{From: "build", To: "deploy"}, {From: "migrate", To: "deploy", Optional: true}, {From: "deploy", To: "undo", On: "failure"}}
+	if got := graph.Edges(); fmt.Sprint(got) != fmt.Sprint(expected) {
+		t.Errorf("Expected edges %v, got %v", expected, got)
+	}
+}
+
+func TestGetReadyJobsDependencyConditions(t *testing.T) {
+	jobs := []models.Job{
+		{Name: "deploy"},
+		{Name: "verify", DependsOn: []string{"deploy"}},
+		{Name: "undo", DependsOn: []string{"deploy"}, DependsOnConditions: map[string]string{"deploy": models.DependencyOnFailure}},
+		{Name: "notify", DependsOn: []string{"deploy"}, DependsOnConditions: map[string]string{"deploy": models.DependencyOnAlways}},
+		{Name: "audit", DependsOn: []string{"deploy"}, DependsOnConditions: map[string]string{"deploy": models.DependencyOnSuccess}},
+	}
+
+	tests := []struct {
+		name     string
+		finish   func(graph *JobGraph)
+		expected []string
+	}{
+		{
+			name:     "deploy succeeded",
+			finish:   func(graph *JobGraph) { graph.MarkCompleted("deploy") },
+			expected: []string{"audit", "notify", "verify"},
+		},
+		{
+			name:     "deploy failed",
+			finish:   func(graph *JobGraph) { graph.MarkFailed("deploy") },
+			expected: []string{"notify", "undo"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			graph := BuildDependencyGraph(jobs)
+			tt.finish(graph)
+
+			var names []string
+			for _, job := range graph.GetReadyJobs() {
+				names = append(names, job.Name)
+			}
+			if fmt.Sprint(names) != fmt.Sprint(tt.expected) {
+				t.Errorf("Expected ready jobs %v, got %v", tt.expected, names)
+			}
+		})
+	}
+}
@@ -0,0 +1,130 @@
+package engine
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/cuongtl1992/grp-cli/internal/models"
+)
+
+// jobLog captures the output of a job to its log file, if any, and copies each
+// line to the console, prefixed with the stage and job name unless the prefix is
+// empty. Lines are masked before they are written anywhere.
+type jobLog struct {
+	path    string
+	file    *os.File
+	console *Console
+	prefix  string
+	mask    func(string) string
+
+	mutex   sync.Mutex
+	partial bytes.Buffer
+}
+
+// jobLogPath returns the log file of a job: <dir>/<executionID>/<stage>/<job>.log
+func jobLogPath(dir, executionID, stage, job string) string {
+	return filepath.Join(dir, pathSegment(executionID), pathSegment(stage), pathSegment(job)+".log")
+}
+
+// pathSegment makes a name usable as a single path element
+func pathSegment(name string) string {
+	name = strings.NewReplacer("/", "_", "\\", "_").Replace(name)
+	if name == "" || name == "." || name == ".." {
+		return "_" + name
+	}
+	return name
+}
+
+// newJobOutput creates the output of a job that is only written to the console
+func newJobOutput(console *Console, prefix string, mask func(string) string) *jobLog {
+	if mask == nil {
+		mask = func(s string) string { return s }
+	}
+	return &jobLog{console: console, prefix: prefix, mask: mask}
+}
+
+// openJobLog creates the log file of a job, and its directories
+func openJobLog(path string, console *Console, prefix string, mask func(string) string) (*jobLog, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create job log directory: %w", err)
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create job log: %w", err)
+	}
+	if mask == nil {
+		mask = func(s string) string { return s }
+	}
+	return &jobLog{path: path, file: file, console: console, prefix: prefix, mask: mask}, nil
+}
+
+// Write buffers the output until complete lines can be written
+func (l *jobLog) Write(p []byte) (int, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	l.partial.Write(p)
+	for {
+		line, err := l.partial.ReadString('\n')
+		if err != nil {
+			// Keep the incomplete line for the next write
+			l.partial.Reset()
+			l.partial.WriteString(line)
+			return len(p), nil
+		}
+		l.writeLine(strings.TrimSuffix(line, "\n"))
+	}
+}
+
+// writeLine writes a masked line to the log file and the console
+func (l *jobLog) writeLine(line string) {
+	line = l.mask(line)
+	if l.file != nil {
+		fmt.Fprintln(l.file, line)
+	}
+	l.console.WriteLine(l.prefix, line)
+}
+
+// StartAttempt marks the start of an attempt of the job in its output, on a line
+// of its own
+func (l *jobLog) StartAttempt(attempt, attempts int) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	l.flush()
+	l.writeLine(fmt.Sprintf("--- attempt %d of %d", attempt, attempts))
+}
+
+// flush writes the incomplete output line, if any
+func (l *jobLog) flush() {
+	if l.partial.Len() > 0 {
+		l.writeLine(l.partial.String())
+		l.partial.Reset()
+	}
+}
+
+// Close writes the outcome of the job to the log file, after any incomplete
+// output line, and closes it
+func (l *jobLog) Close(result models.JobResult) error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	l.flush()
+	if l.file == nil {
+		return nil
+	}
+
+	status := "succeeded"
+	switch {
+	case result.Canceled:
+		status = "canceled"
+	case !result.Success:
+		status = "failed"
+	}
+	fmt.Fprintf(l.file, "--- job %s (attempts: %d): %s\n", status, result.Attempts, l.mask(result.Message))
+	return l.file.Close()
+}
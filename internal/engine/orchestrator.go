@@ -2,42 +2,151 @@ package engine
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/google/uuid"
 
+	"github.com/cuongtl1992/grp-cli/internal/approval"
+	"github.com/cuongtl1992/grp-cli/internal/logging"
+	"github.com/cuongtl1992/grp-cli/internal/metrics"
 	"github.com/cuongtl1992/grp-cli/internal/models"
+	"github.com/cuongtl1992/grp-cli/internal/notify"
 	"github.com/cuongtl1992/grp-cli/internal/plugins"
+	"github.com/cuongtl1992/grp-cli/pkg/plugin"
 )
 
 // ExecuteOptions contains options for plan execution
 type ExecuteOptions struct {
-	AutoRollback bool
-	SkipApproval bool
-	DryRun       bool
+	AutoRollback      bool
+	SkipApproval      bool
+	DryRun            bool
+	MaxParallelStages int
+	// RollbackOnCancel rolls back the work already completed when the execution
+	// is canceled; AutoRollback only applies to failures
+	RollbackOnCancel bool
+	// CancelGracePeriod is how long running jobs are given to clean up when the
+	// execution is canceled; 0 uses DefaultCancelGracePeriod
+	CancelGracePeriod time.Duration
+	// Timeout bounds the whole execution, overriding the plan's timeout; 0 uses
+	// the plan's timeout, if any
+	Timeout time.Duration
+	// Events receives stage and job progress events, if set
+	Events EventSink
+	// LogDir receives a log file of the output of each job, if set; LogMask is
+	// applied to every line of it
+	LogDir  string
+	LogMask func(string) string
+	// ArtifactDir receives a copy of the artifacts of each execution and their
+	// manifest, under a directory named after the execution ID, if set
+	ArtifactDir string
+	// WorkingDir roots the relative paths of job configs and is passed to plugins
+	// as an absolute path; empty uses the current directory
+	WorkingDir string
+	// StageRollbackOnly limits a rollback to the rollback jobs of the failed
+	// stages, skipping plugin rollbacks and the plan's rollback stages
+	StageRollbackOnly bool
+	// KeepGoing lets every job of a stage whose dependencies succeeded run after
+	// a job fails, reporting all the failures at the end instead of stopping the
+	// stage at the first one
+	KeepGoing bool
+	// OnStageFailure chooses what happens to the remaining stages once a stage
+	// fails, overriding the plan's onStageFailure; empty uses the plan's, which
+	// defaults to models.StageFailureAbort
+	OnStageFailure string
+	// RequireRollback fails an execution that would be rolled back on failure
+	// before it starts if a rollback could not undo some of its stages: stages
+	// without rollback jobs none of whose plugins support rollback
+	RequireRollback bool
 }
 
+// ErrTimeout is the cause of the cancellation of an execution that exceeded its
+// timeout, and is wrapped by the error ExecutePlan returns for it
+var ErrTimeout = errors.New("plan timed out")
+
+// ErrRollbackRequired is wrapped by the error ExecutePlan returns, before running
+// any stage, when RequireRollback is set and a rollback could not undo the plan
+var ErrRollbackRequired = errors.New("rollback is required")
+
 // Orchestrator manages the execution of a release plan
 type Orchestrator struct {
-	pluginManager *plugins.Manager
+	pluginManager   *plugins.Manager
+	approvalService approval.Service
+	approvalMutex   sync.Mutex
+	logger          logging.Logger
+	metrics         metrics.Sink
+	console         *Console
+	prefixOutput    bool
 }
 
-// NewOrchestrator creates a new orchestrator
+// NewOrchestrator creates a new orchestrator that prompts for approvals on stdin
 func NewOrchestrator(pluginManager *plugins.Manager) *Orchestrator {
 	return &Orchestrator{
-		pluginManager: pluginManager,
+		pluginManager:   pluginManager,
+		approvalService: approval.NewInteractiveService(os.Stdin, os.Stdout),
+		logger:          logging.Default(),
+		console:         NewConsole(os.Stderr, nil),
+		prefixOutput:    true,
 	}
 }
 
+// SetLogger replaces the logger used to report execution progress
+func (o *Orchestrator) SetLogger(logger logging.Logger) {
+	o.logger = logger
+}
+
+// newExecutor creates an executor sharing the orchestrator's plugins and logger
+func (o *Orchestrator) newExecutor() *Executor {
+	executor := NewExecutor(o.pluginManager)
+	executor.SetLogger(o.logger)
+	executor.SetConsole(o.console, o.prefixOutput)
+	return executor
+}
+
+// SetConsole sets where the output of the jobs is written, a line at a time so
+// that jobs running in parallel do not interleave, and whether each line is
+// prefixed with the stage and job name. mask, if set, is applied to every line,
+// including the output of rollback stages, which have no job logs. The default
+// writes prefixed lines to stderr.
+func (o *Orchestrator) SetConsole(out io.Writer, prefix bool, mask func(string) string) {
+	o.console = NewConsole(out, mask)
+	o.prefixOutput = prefix
+}
+
+// SetMetricsSink sets the sink receiving the metrics of each finished execution
+func (o *Orchestrator) SetMetricsSink(sink metrics.Sink) {
+	o.metrics = sink
+}
+
+// SetApprovalService replaces the service used to approve gated stages
+func (o *Orchestrator) SetApprovalService(service approval.Service) {
+	o.approvalService = service
+}
+
 // ExecutePlan runs a release plan
 func (o *Orchestrator) ExecutePlan(ctx context.Context, plan *models.Plan, options ExecuteOptions) (*models.ExecutionResult, error) {
 	// Generate unique execution ID
 	executionID := uuid.New().String()
 	
 	// Create execution context with variables
-	execCtx := context.WithValue(ctx, "executionID", executionID)
-	execCtx = context.WithValue(execCtx, "variables", plan.Variables)
+	execCtx := plugin.WithExecutionID(ctx, executionID)
+	execCtx = plugin.WithVariables(execCtx, plan.Variables)
+	if !options.DryRun {
+		// Dry runs produce no job outputs, so references to them stay unresolved
+		execCtx = context.WithValue(execCtx, outputsKey, newJobOutputs())
+		var artifactDir string
+		if options.ArtifactDir != "" {
+			artifactDir = filepath.Join(options.ArtifactDir, executionID)
+		}
+		execCtx = context.WithValue(execCtx, artifactsKey, newArtifactCollector(artifactDir))
+	}
 	
 	// Create execution result
 	result := &models.ExecutionResult{
@@ -46,107 +155,861 @@ func (o *Orchestrator) ExecutePlan(ctx context.Context, plan *models.Plan, optio
 		TotalStages: len(plan.Stages),
 		TotalJobs:   o.countTotalJobs(plan),
 	}
+	onStageFailure := stageFailureBehavior(plan, options)
+	result.OnStageFailure = onStageFailure
 	
-	// Execute stages sequentially
-	for _, stage := range plan.Stages {
-		stageResult := models.StageResult{
-			Name:      stage.Name,
-			StartTime: time.Now(),
+	// Report the execution to the plan's notification channels
+	notifications := o.newDispatcher(plan, options)
+	execCtx = context.WithValue(execCtx, notificationsKey, notifications)
+	notifications.Send(execCtx, notify.Message{Event: models.NotifyOnStart, Plan: plan.Metadata.Name, ExecutionID: executionID})
+	
+	// Root the relative paths of job configs at the working directory
+	workingDir, err := executionWorkingDir(options)
+	if err != nil {
+		return o.finish(execCtx, plan, result, err)
+	}
+	execCtx = plugin.WithWorkingDir(execCtx, workingDir)
+	if (options.AutoRollback || onStageFailure == models.StageFailureRollbackAndAbort) && !options.DryRun {
+		if err := o.checkRollbackSupport(plan, options); err != nil {
+			return o.finish(execCtx, plan, result, err)
 		}
+	}
+	
+	// Cancel the execution like an interrupt once the timeout expires, so that
+	// running jobs get the cancel grace period to clean up
+	timeout, err := planTimeout(plan, options)
+	if err != nil {
+		return o.finish(execCtx, plan, result, err)
+	}
+	if timeout > 0 {
+		var cancel context.CancelCauseFunc
+		execCtx, cancel = context.WithCancelCause(execCtx)
+		defer cancel(nil)
+		timer := time.AfterFunc(timeout, func() { cancel(ErrTimeout) })
+		defer timer.Stop()
+	}
+	
+	// Execute stages in dependency order, running independent stages in parallel
+	graph := buildStageGraph(plan.Stages)
+	if graph.HasCycles() {
+		return o.finish(execCtx, plan, result, errors.New("dependency cycle detected in stage graph"))
+	}
+	
+	var failures joinedError
+	readyStages := graph.GetReadyStages()
+	for len(readyStages) > 0 {
+		stageResults, stageErrs := o.executeStageBatch(execCtx, executionID, plan, readyStages, options)
 		
-		// Check if approval is required
-		if stage.RequireApproval && !options.SkipApproval {
-			// In a real implementation, this would call an approval service
-			fmt.Printf("Stage %s requires approval. Waiting for approval...\n", stage.Name)
+		for i, stageResult := range stageResults {
+			result.Stages = append(result.Stages, stageResult)
+			if stageErrs[i] != nil {
+				failures = append(failures, &StageError{Stage: stageResult.Name, Canceled: stageResult.Canceled, Err: stageErrs[i]})
+				graph.MarkFailed(stageResult.Name)
+				continue
+			}
 			
-			// For now, we'll just simulate approval
-			fmt.Printf("Stage %s approved.\n", stage.Name)
+			graph.MarkCompleted(stageResult.Name)
+			o.logger.Info("Stage completed successfully", "stage", stageResult.Name, "duration", stageResult.Duration)
 		}
 		
-		// Execute the stage
-		stageErr := o.executeStage(execCtx, &stage, &stageResult, options)
+		// Stop at the first failure, unless the stages that do not depend on the
+		// failed ones should still run; a canceled execution always stops
+		if len(failures) > 0 && (onStageFailure != models.StageFailureContinue || execCtx.Err() != nil) {
+			break
+		}
 		
-		// Update stage result
-		stageResult.EndTime = time.Now()
-		stageResult.Duration = stageResult.EndTime.Sub(stageResult.StartTime)
-		stageResult.Success = stageErr == nil
-		result.Stages = append(result.Stages, stageResult)
+		readyStages = graph.GetReadyStages()
+	}
+	
+	// Handle stage failure or cancellation
+	if len(failures) > 0 {
+		result.SkippedStages = graph.PendingStages()
+		if len(result.SkippedStages) > 0 {
+			o.logger.Warn("Stages skipped after a stage failure", "stages", result.SkippedStages, "onStageFailure", onStageFailure)
+		}
 		
-		// Handle stage failure
-		if stageErr != nil {
-			// Execute rollback if configured
-			if options.AutoRollback && plan.Rollback != nil {
-				o.executeRollback(execCtx, plan.Rollback)
+		rollback := options.AutoRollback || onStageFailure == models.StageFailureRollbackAndAbort
+		rollbackCtx := execCtx
+		if errors.Is(execCtx.Err(), context.Canceled) {
+			// The execution context is canceled, so the rollback needs one that is not
+			rollbackCtx = context.WithoutCancel(execCtx)
+			if errors.Is(context.Cause(execCtx), ErrTimeout) {
+				// A timeout is a failure, rolled back like any other
+				result.TimedOut = true
+				failures = append(joinedError{fmt.Errorf("%w after %s", ErrTimeout, timeout)}, failures...)
+			} else {
+				result.Canceled = true
+				rollback = options.RollbackOnCancel
+			}
+		}
+		
+		// Execute rollback if configured
+		if rollback && !options.DryRun {
+			rollbackResult, rollbackErr := o.executeRollback(rollbackCtx, plan, result.Stages, options)
+			result.Rollback = rollbackResult
+			if rollbackErr != nil {
+				failures = append(failures, fmt.Errorf("rollback failed: %w", rollbackErr))
+			}
+		}
+		if options.DryRun {
+			if err := o.dryRunRollback(execCtx, plan, result, options); err != nil {
+				failures = append(failures, err)
+			}
+		}
+		
+		// An interrupted execution stops without running the plan's hooks
+		if !result.Canceled {
+			if err := o.executePlanHook(rollbackCtx, plan, result, models.PlanHookOnFailure, plan.OnFailure, options); err != nil {
+				failures = append(failures, err)
 			}
-			
-			return o.finalizeResult(result, false, fmt.Sprintf("Stage %s failed: %v", stage.Name, stageErr))
 		}
 		
-		fmt.Printf("Stage %s completed successfully.\n", stage.Name)
+		return o.finish(execCtx, plan, result, failures)
+	}
+	
+	// A dry run also checks that the rollback stages would run
+	if options.DryRun {
+		if err := o.dryRunRollback(execCtx, plan, result, options); err != nil {
+			return o.finish(execCtx, plan, result, err)
+		}
 	}
 	
 	// All stages completed successfully
-	return o.finalizeResult(result, true, "Plan execution completed successfully")
+	err = o.executePlanHook(execCtx, plan, result, models.PlanHookOnSuccess, plan.OnSuccess, options)
+	return o.finish(execCtx, plan, result, err)
+}
+
+// executePlanHook runs the jobs of the plan's onSuccess or onFailure hook, named
+// hook, in dependency order, recording them in result.Hooks. Like the jobs of a
+// stage, they may use the outputs of the jobs that ran before them; a failed hook
+// job fails the execution but triggers no rollback or other hook.
+func (o *Orchestrator) executePlanHook(ctx context.Context, plan *models.Plan, result *models.ExecutionResult, hook string, jobs []models.Job, options ExecuteOptions) error {
+	if len(jobs) == 0 {
+		return nil
+	}
+	
+	o.logger.Info("Running plan hook", "hook", hook)
+	executor := o.newExecutor()
+	executor.SetJobLogs(options.LogDir, options.LogMask)
+	hookResult := &models.StageResult{Name: hook, StartTime: time.Now()}
+	err := executor.ExecuteGraph(plugin.WithStageName(ctx, hook), BuildDependencyGraph(jobs), hookResult,
+		GraphOptions{DryRun: options.DryRun, MaxParallel: plan.MaxParallel, StageName: hook})
+	
+	hookResult.EndTime = time.Now()
+	hookResult.Duration = hookResult.EndTime.Sub(hookResult.StartTime)
+	hookResult.Success = err == nil
+	result.Hooks = hookResult
+	if err != nil {
+		o.logger.Error("Plan hook failed", "hook", hook, "error", err)
+		return fmt.Errorf("%s hook %w", hook, err)
+	}
+	return nil
+}
+
+// dryRunRollback validates the rollback jobs of the plan's stages and its rollback
+// stages without executing them, recording the simulated rollback in the result
+func (o *Orchestrator) dryRunRollback(ctx context.Context, plan *models.Plan, result *models.ExecutionResult, options ExecuteOptions) error {
+	var stageRollbacks []models.Stage
+	for _, stage := range plan.Stages {
+		if len(stage.Rollback) > 0 {
+			stageRollbacks = append(stageRollbacks, stageRollback(stage))
+		}
+	}
+	hasRollbackPlan := plan.Rollback != nil && len(plan.Rollback.Stages) > 0
+	if len(stageRollbacks) == 0 && !hasRollbackPlan {
+		return nil
+	}
+	
+	rollback := &models.RollbackResult{StartTime: time.Now()}
+	var errs []error
+	for _, stage := range stageRollbacks {
+		stageResult, err := o.executeRollbackStage(ctx, stage, true)
+		rollback.Stages = append(rollback.Stages, stageResult)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("rollback stage %s: %w", stage.Name, err))
+		}
+	}
+	if hasRollbackPlan {
+		errs = append(errs, o.executeRollbackStages(ctx, plan.Rollback.Stages, rollback, true, options.MaxParallelStages)...)
+	}
+	rollback.EndTime = time.Now()
+	rollback.Duration = rollback.EndTime.Sub(rollback.StartTime)
+	rollback.Success = len(errs) == 0
+	result.Rollback = rollback
+	
+	if len(errs) > 0 {
+		return fmt.Errorf("rollback validation failed: %w", errors.Join(errs...))
+	}
+	return nil
+}
+
+// ExecuteRollback runs only the rollback stages of a plan, for manual recovery
+// after a release failed without rolling back. Nothing was executed by this
+// execution, so no plugin Rollback is called.
+func (o *Orchestrator) ExecuteRollback(ctx context.Context, plan *models.Plan, options ExecuteOptions) (*models.RollbackResult, error) {
+	if plan.Rollback == nil || len(plan.Rollback.Stages) == 0 {
+		return nil, errors.New("plan has no rollback stages")
+	}
+	
+	workingDir, err := executionWorkingDir(options)
+	if err != nil {
+		return nil, err
+	}
+	
+	executionID := uuid.New().String()
+	execCtx := plugin.WithExecutionID(ctx, executionID)
+	execCtx = plugin.WithVariables(execCtx, plan.Variables)
+	execCtx = plugin.WithWorkingDir(execCtx, workingDir)
+	if !options.DryRun {
+		execCtx = context.WithValue(execCtx, outputsKey, newJobOutputs())
+	}
+	
+	o.logger.Warn("Starting rollback execution", "executionID", executionID)
+	result := &models.RollbackResult{StartTime: time.Now()}
+	errs := o.executeRollbackStages(execCtx, plan.Rollback.Stages, result, options.DryRun, options.MaxParallelStages)
+	result.EndTime = time.Now()
+	result.Duration = result.EndTime.Sub(result.StartTime)
+	result.Success = len(errs) == 0
+	
+	o.logger.Info("Rollback execution completed", "success", result.Success, "duration", result.Duration)
+	return result, errors.Join(errs...)
+}
+
+// executionWorkingDir resolves the working directory of an execution to an
+// absolute path and checks that it is a directory
+func executionWorkingDir(options ExecuteOptions) (string, error) {
+	dir, err := filepath.Abs(options.WorkingDir)
+	if err != nil {
+		return "", fmt.Errorf("invalid working directory %q: %w", options.WorkingDir, err)
+	}
+	info, err := os.Stat(dir)
+	if err != nil {
+		return "", fmt.Errorf("invalid working directory: %w", err)
+	}
+	if !info.IsDir() {
+		return "", fmt.Errorf("invalid working directory: %s is not a directory", dir)
+	}
+	return dir, nil
+}
+
+// planTimeout returns the timeout of the execution: the option if set, else the plan's
+func planTimeout(plan *models.Plan, options ExecuteOptions) (time.Duration, error) {
+	if options.Timeout > 0 || plan.Timeout == "" {
+		return options.Timeout, nil
+	}
+	
+	timeout, err := time.ParseDuration(plan.Timeout)
+	if err != nil {
+		return 0, fmt.Errorf("invalid plan timeout %q: %w", plan.Timeout, err)
+	}
+	return timeout, nil
+}
+
+// stageFailureBehavior returns what happens to the remaining stages once a stage
+// fails: the option if set, else the plan's, else models.StageFailureAbort
+func stageFailureBehavior(plan *models.Plan, options ExecuteOptions) string {
+	if options.OnStageFailure != "" {
+		return options.OnStageFailure
+	}
+	if plan.OnStageFailure != "" {
+		return plan.OnStageFailure
+	}
+	return models.StageFailureAbort
 }
 
-// executeStage runs all jobs in a stage with proper dependency handling
-func (o *Orchestrator) executeStage(ctx context.Context, stage *models.Stage, result *models.StageResult, options ExecuteOptions) error {
-	// Build job dependency graph
-	graph := buildDependencyGraph(stage.Jobs)
+// executeStageBatch runs a set of independent stages concurrently, bounded by
+// options.MaxParallelStages, and returns their results in the same order
+func (o *Orchestrator) executeStageBatch(ctx context.Context, executionID string, plan *models.Plan, stages []models.Stage, options ExecuteOptions) ([]models.StageResult, []error) {
+	stageResults := make([]models.StageResult, len(stages))
+	stageErrs := make([]error, len(stages))
+	slots := newSemaphore(options.MaxParallelStages)
+	
+	var wg sync.WaitGroup
+	for i, stage := range stages {
+		wg.Add(1)
+		go func(i int, stage models.Stage) {
+			defer wg.Done()
+			slots.acquire()
+			defer slots.release()
+			
+			stageResults[i], stageErrs[i] = o.runStage(ctx, executionID, plan, stage, options)
+		}(i, stage)
+	}
+	wg.Wait()
 	
+	return stageResults, stageErrs
+}
+
+// runStage requests approval for a stage if needed and executes its jobs
+func (o *Orchestrator) runStage(ctx context.Context, executionID string, plan *models.Plan, stage models.Stage, options ExecuteOptions) (models.StageResult, error) {
+	stageResult := models.StageResult{
+		Name:      stage.Name,
+		StartTime: time.Now(),
+	}
+	publish(options.Events, Event{Type: EventStageStarted, ExecutionID: executionID, Stage: stage.Name})
+	
+	// Check if approval is required
+	var stageErr error
+	if stage.RequireApproval && !options.SkipApproval {
+		notifications, _ := ctx.Value(notificationsKey).(*notify.Dispatcher)
+		notifications.Send(ctx, notify.Message{Event: models.NotifyOnApproval, Plan: plan.Metadata.Name, ExecutionID: executionID, Stage: stage.Name})
+		stageErr = o.requestApproval(ctx, executionID, &stage, options)
+		stageResult.Approval = approvalStatus(stageErr)
+	}
+	
+	// Execute the stage once approved
+	if stageErr == nil {
+		stageErr = o.executeStage(ctx, plan, &stage, &stageResult, options)
+	}
+	
+	// Update stage result
+	stageResult.EndTime = time.Now()
+	stageResult.Duration = stageResult.EndTime.Sub(stageResult.StartTime)
+	stageResult.Success = stageErr == nil
+	stageResult.Canceled = stageErr != nil && errors.Is(ctx.Err(), context.Canceled)
+	
+	event := Event{
+		Type:        EventStageFinished,
+		ExecutionID: executionID,
+		Stage:       stage.Name,
+		Success:     stageResult.Success,
+		Duration:    stageResult.Duration,
+	}
+	if stageErr != nil {
+		event.Message = stageErr.Error()
+	}
+	publish(options.Events, event)
+	
+	return stageResult, stageErr
+}
+
+// requestApproval asks the approval service to approve a stage and returns an
+// error if the request is rejected, expires or cannot be processed. The request
+// describes the stage's jobs, with secrets masked by options.LogMask. With an
+// approval timeout, a request still unanswered when it expires is marked expired,
+// or rejected if the stage's onApprovalTimeout is reject.
+func (o *Orchestrator) requestApproval(ctx context.Context, executionID string, stage *models.Stage, options ExecuteOptions) error {
+	request := &models.ApprovalRequest{
+		ID:          uuid.New().String(),
+		ExecutionID: executionID,
+		StageName:   stage.Name,
+		Approvers:   stage.Approvers,
+		Status:      models.ApprovalStatusPending,
+		Jobs:        o.previewStage(ctx, stage, options.LogMask),
+	}
+	request.RequestedAt = time.Now()
+	
+	// Prompt for one approval at a time when stages run in parallel
+	o.approvalMutex.Lock()
+	defer o.approvalMutex.Unlock()
+	
+	// Enforce the expiry even if the approval service ignores it
+	approvalCtx := ctx
+	if stage.ApprovalTimeout != "" {
+		timeout, err := time.ParseDuration(stage.ApprovalTimeout)
+		if err != nil {
+			return fmt.Errorf("invalid approval timeout %q for stage %s: %w", stage.ApprovalTimeout, stage.Name, err)
+		}
+		request.ExpiresAt = time.Now().Add(timeout)
+		
+		var cancel context.CancelFunc
+		approvalCtx, cancel = context.WithDeadline(ctx, request.ExpiresAt)
+		defer cancel()
+	}
+	
+	o.logger.Info("Waiting for stage approval", "stage", stage.Name, "approvers", stage.Approvers)
+	response, err := o.approvalService.RequestApproval(approvalCtx, request)
+	if err != nil && approvalCtx.Err() == context.DeadlineExceeded && ctx.Err() == nil {
+		err = approval.ErrExpired
+	}
+	if errors.Is(err, approval.ErrExpired) && stage.OnApprovalTimeout == models.ApprovalTimeoutReject {
+		// Reject the request on behalf of the missing approvers
+		response = &models.ApprovalResponse{
+			RequestID:   request.ID,
+			ResponderID: "grp-cli",
+			Comment:     fmt.Sprintf("no decision within %s", stage.ApprovalTimeout),
+			RespondedAt: time.Now(),
+		}
+		err = nil
+	}
+	if err != nil {
+		if errors.Is(err, approval.ErrExpired) {
+			request.Status = models.ApprovalStatusExpired
+			o.logger.Warn("Stage approval expired", "stage", stage.Name, "timeout", stage.ApprovalTimeout)
+		}
+		return fmt.Errorf("approval for stage %s failed: %w", stage.Name, err)
+	}
+	
+	if err := approval.Apply(request, response); err != nil {
+		if response.Comment != "" {
+			return fmt.Errorf("stage %s %w by %s: %s", stage.Name, err, response.ResponderID, response.Comment)
+		}
+		return fmt.Errorf("stage %s %w by %s", stage.Name, err, response.ResponderID)
+	}
+	
+	o.logger.Info("Stage approved", "stage", stage.Name, "approver", response.ResponderID)
+	return nil
+}
+
+// approvalStatus returns the status of an approval request from its outcome
+func approvalStatus(err error) models.ApprovalStatus {
+	switch {
+	case err == nil:
+		return models.ApprovalStatusApproved
+	case errors.Is(err, approval.ErrRejected):
+		return models.ApprovalStatusRejected
+	case errors.Is(err, approval.ErrExpired):
+		return models.ApprovalStatusExpired
+	}
+	return models.ApprovalStatusPending
+}
+
+// executeStage runs the stage's pre hooks, then its jobs with proper dependency
+// handling, then its post hooks. The jobs are skipped if a pre hook fails, but the
+// post hooks run even if the stage failed or the execution was canceled.
+func (o *Orchestrator) executeStage(ctx context.Context, plan *models.Plan, stage *models.Stage, result *models.StageResult, options ExecuteOptions) error {
 	// Create a new execution context for this stage
-	stageCtx := context.WithValue(ctx, "stageName", stage.Name)
+	stageCtx := plugin.WithStageName(ctx, stage.Name)
 	
 	// Execute jobs in dependency order
-	executor := NewExecutor(o.pluginManager)
-	return executor.ExecuteGraph(stageCtx, graph, result, options.DryRun)
+	executor := o.newExecutor()
+	if options.CancelGracePeriod > 0 {
+		executor.SetCancelGracePeriod(options.CancelGracePeriod)
+	}
+	executor.SetJobLogs(options.LogDir, options.LogMask)
+	graphOptions := GraphOptions{
+		DryRun:      options.DryRun,
+		MaxParallel: plan.MaxParallel,
+		StageName:   stage.Name,
+		Events:      options.Events,
+	}
+	if stage.MaxParallel > 0 {
+		graphOptions.MaxParallel = stage.MaxParallel
+	}
+	
+	err := executeHooks(stageCtx, executor, "pre", stage.PreJobs, &result.PreJobs, graphOptions)
+	if err == nil {
+		// The failure budget only applies to the stage's jobs
+		jobOptions := graphOptions
+		jobOptions.MaxFailures = stage.MaxFailures
+		jobOptions.MaxFailurePercent = stage.MaxFailurePercent
+		jobOptions.KeepGoing = options.KeepGoing
+		err = executor.ExecuteGraph(stageCtx, BuildDependencyGraph(stage.Jobs), result, jobOptions)
+	}
+	
+	if len(stage.PostJobs) > 0 {
+		// Tear down even when canceled; the hooks' own timeouts still apply
+		postCtx := stageCtx
+		if stageCtx.Err() != nil {
+			postCtx = context.WithoutCancel(stageCtx)
+		}
+		if postErr := executeHooks(postCtx, executor, "post", stage.PostJobs, &result.PostJobs, graphOptions); postErr != nil {
+			if err == nil {
+				return postErr
+			}
+			return joinedError{err, postErr}
+		}
+	}
+	return err
+}
+
+// executeHooks runs the pre or post hooks of a stage, recording their results
+func executeHooks(ctx context.Context, executor *Executor, kind string, jobs []models.Job, results *[]models.JobResult, options GraphOptions) error {
+	if len(jobs) == 0 {
+		return nil
+	}
+	
+	hookResult := models.StageResult{}
+	err := executor.ExecuteGraph(ctx, BuildDependencyGraph(jobs), &hookResult, options)
+	*results = hookResult.Jobs
+	if err != nil {
+		return fmt.Errorf("%s hook %w", kind, err)
+	}
+	return nil
 }
 
-// executeRollback runs the rollback plan
-func (o *Orchestrator) executeRollback(ctx context.Context, rollback *models.Rollback) error {
+// executeRollback runs the rollback jobs of the failed stages, then, unless
+// options.StageRollbackOnly is set, compensates the jobs that executed successfully
+// by calling their plugin's Rollback in reverse dependency order and runs the
+// rollback plan if one is defined. Every step is attempted even if some fail;
+// errors are aggregated into the returned error.
+func (o *Orchestrator) executeRollback(ctx context.Context, plan *models.Plan, executed []models.StageResult, options ExecuteOptions) (*models.RollbackResult, error) {
 	// Log rollback start
-	fmt.Println("Starting rollback execution...")
+	o.logger.Warn("Starting rollback execution")
 	
-	// Execute rollback stages
-	for _, stage := range rollback.Stages {
-		// Build job dependency graph
-		graph := buildDependencyGraph(stage.Jobs)
+	result := &models.RollbackResult{StartTime: time.Now()}
+	errs := o.executeStageRollbacks(ctx, plan, executed, result)
+	
+	if !options.StageRollbackOnly {
+		errs = append(errs, o.rollbackJobs(ctx, plan, executed, result)...)
 		
-		// Execute jobs in dependency order
-		executor := NewExecutor(o.pluginManager)
-		stageResult := &models.StageResult{Name: stage.Name}
-		if err := executor.ExecuteGraph(ctx, graph, stageResult, false); err != nil {
-			fmt.Printf("Rollback stage %s failed: %v\n", stage.Name, err)
-			// Continue with other rollback stages even if one fails
+		// Execute rollback stages
+		if plan.Rollback != nil {
+			errs = append(errs, o.executeRollbackStages(ctx, plan.Rollback.Stages, result, false, options.MaxParallelStages)...)
+		}
+	}
+	
+	result.EndTime = time.Now()
+	result.Duration = result.EndTime.Sub(result.StartTime)
+	result.Success = len(errs) == 0
+	
+	o.logger.Info("Rollback execution completed", "success", result.Success, "duration", result.Duration)
+	return result, errors.Join(errs...)
+}
+
+// executeStageRollbacks runs the rollback jobs of the stages that did not succeed,
+// most recently finished first. Every stage's rollback runs even if another one
+// failed.
+func (o *Orchestrator) executeStageRollbacks(ctx context.Context, plan *models.Plan, executed []models.StageResult, result *models.RollbackResult) []error {
+	stages := make(map[string]models.Stage, len(plan.Stages))
+	for _, stage := range plan.Stages {
+		stages[stage.Name] = stage
+	}
+	
+	var errs []error
+	for i := len(executed) - 1; i >= 0; i-- {
+		stage := stages[executed[i].Name]
+		if executed[i].Success || len(stage.Rollback) == 0 {
+			continue
+		}
+		
+		o.logger.Warn("Rolling back stage", "stage", stage.Name)
+		stageResult, err := o.executeRollbackStage(ctx, stageRollback(stage), false)
+		result.Stages = append(result.Stages, stageResult)
+		if err != nil {
+			o.logger.Error("Stage rollback failed", "stage", stage.Name, "error", err)
+			errs = append(errs, fmt.Errorf("rollback of stage %s: %w", stage.Name, err))
+		}
+	}
+	return errs
+}
+
+// stageRollback returns the rollback jobs of a stage as a rollback stage, named
+// after the stage with a "/rollback" suffix
+func stageRollback(stage models.Stage) models.Stage {
+	return models.Stage{Name: stage.Name + "/rollback", MaxParallel: stage.MaxParallel, Jobs: stage.Rollback}
+}
+
+// executeRollbackStages runs the rollback stages in dependency order, running
+// independent stages in parallel up to maxParallel, or only validates their jobs
+// for a dry run. Like the plan's stages, rollback stages run one after the other
+// when none declares dependsOn. Every stage runs even if a stage it depends on
+// failed, and the failures of all stages are returned.
+func (o *Orchestrator) executeRollbackStages(ctx context.Context, stages []models.Stage, result *models.RollbackResult, dryRun bool, maxParallel int) []error {
+	graph := buildStageGraph(stages)
+	if graph.HasCycles() {
+		return []error{errors.New("dependency cycle detected in rollback stage graph")}
+	}
+	
+	var errs []error
+	for ready := graph.GetReadyStages(); len(ready) > 0; ready = graph.GetReadyStages() {
+		stageResults := make([]models.StageResult, len(ready))
+		stageErrs := make([]error, len(ready))
+		slots := newSemaphore(maxParallel)
+		
+		var wg sync.WaitGroup
+		for i, stage := range ready {
+			wg.Add(1)
+			go func(i int, stage models.Stage) {
+				defer wg.Done()
+				slots.acquire()
+				defer slots.release()
+				
+				stageResults[i], stageErrs[i] = o.executeRollbackStage(ctx, stage, dryRun)
+			}(i, stage)
+		}
+		wg.Wait()
+		
+		for i, stageResult := range stageResults {
+			result.Stages = append(result.Stages, stageResult)
+			graph.MarkCompleted(stageResult.Name)
+			if stageErrs[i] != nil {
+				o.logger.Error("Rollback stage failed", "stage", stageResult.Name, "error", stageErrs[i])
+				errs = append(errs, fmt.Errorf("rollback stage %s: %w", stageResult.Name, stageErrs[i]))
+			}
+		}
+	}
+	
+	return errs
+}
+
+// executeRollbackStage runs the jobs of a rollback stage in dependency order
+func (o *Orchestrator) executeRollbackStage(ctx context.Context, stage models.Stage, dryRun bool) (models.StageResult, error) {
+	executor := o.newExecutor()
+	stageResult := models.StageResult{Name: stage.Name, StartTime: time.Now()}
+	err := executor.ExecuteGraph(plugin.WithStageName(ctx, stage.Name), BuildDependencyGraph(stage.Jobs), &stageResult,
+		GraphOptions{DryRun: dryRun, MaxParallel: stage.MaxParallel, StageName: stage.Name})
+	
+	stageResult.EndTime = time.Now()
+	stageResult.Duration = stageResult.EndTime.Sub(stageResult.StartTime)
+	stageResult.Success = err == nil
+	return stageResult, err
+}
+
+// rollbackJobs calls the plugin Rollback for every successfully executed job in
+// reverse dependency order: a job is rolled back once the jobs that depended on
+// it, in its stage or in the stages depending on its stage, are rolled back.
+// Independent jobs are rolled back in parallel, up to the plan's maxParallel.
+// Every job is rolled back even if others fail, and each call is recorded in the
+// rollback result.
+func (o *Orchestrator) rollbackJobs(ctx context.Context, plan *models.Plan, executed []models.StageResult, result *models.RollbackResult) []error {
+	graph, executedJobs := buildRollbackGraph(plan, executed)
+	
+	var errs []error
+	slots := newSemaphore(plan.MaxParallel)
+	for ready := graph.GetReadyJobs(); len(ready) > 0; ready = graph.GetReadyJobs() {
+		jobResults := make([]models.JobResult, len(ready))
+		jobErrs := make([]error, len(ready))
+		
+		var wg sync.WaitGroup
+		for i, node := range ready {
+			wg.Add(1)
+			go func(i int, executedJob rollbackJob) {
+				defer wg.Done()
+				slots.acquire()
+				defer slots.release()
+				
+				jobResults[i], jobErrs[i] = o.rollbackJob(ctx, executedJob)
+			}(i, executedJobs[node.Name])
+		}
+		wg.Wait()
+		
+		for i, node := range ready {
+			graph.MarkCompleted(node.Name)
+			result.Jobs = append(result.Jobs, jobResults[i])
+			if jobErrs[i] != nil {
+				errs = append(errs, fmt.Errorf("rollback job %s: %w", node.Name, jobErrs[i]))
+			}
+		}
+	}
+	
+	return errs
+}
+
+// checkRollbackSupport warns, before an execution relying on rollback starts, about
+// the job types of the plan whose plugins cannot roll their jobs back and about the
+// stages a rollback could not undo at all. With RequireRollback, such stages fail
+// the execution instead.
+func (o *Orchestrator) checkRollbackSupport(plan *models.Plan, options ExecuteOptions) error {
+	var jobTypes []string
+	checked := make(map[string]bool)
+	for _, stage := range plan.Stages {
+		for _, job := range stage.Jobs {
+			if checked[job.Type] {
+				continue
+			}
+			checked[job.Type] = true
+			if !o.canRollBack(job.Type) {
+				jobTypes = append(jobTypes, job.Type)
+			}
 		}
 	}
 	
-	fmt.Println("Rollback execution completed")
+	if len(jobTypes) > 0 {
+		sort.Strings(jobTypes)
+		o.logger.Warn("Auto-rollback will not undo the jobs of plugins without rollback support", "types", strings.Join(jobTypes, ", "))
+	}
+	
+	stages := o.uncompensatedStages(plan, options)
+	if len(stages) == 0 {
+		return nil
+	}
+	if options.RequireRollback {
+		return fmt.Errorf("%w but could not undo stages %s: they have no rollback jobs and no job their plugins can roll back", ErrRollbackRequired, strings.Join(stages, ", "))
+	}
+	for _, stage := range stages {
+		o.logger.Warn("Stage has no real compensation: a rollback would not undo it", "stage", stage)
+	}
 	return nil
 }
 
+// uncompensatedStages returns the stages of a plan a rollback could not undo: the
+// stages without rollback jobs none of whose jobs their plugins can roll back, or
+// with StageRollbackOnly, every stage without rollback jobs. Hooks are not rolled
+// back, so they do not count. The plan's rollback stages are taken to compensate
+// every stage, unless StageRollbackOnly skips them.
+func (o *Orchestrator) uncompensatedStages(plan *models.Plan, options ExecuteOptions) []string {
+	if plan.Rollback != nil && len(plan.Rollback.Stages) > 0 && !options.StageRollbackOnly {
+		return nil
+	}
+	
+	var stages []string
+	for _, stage := range plan.Stages {
+		if len(stage.Rollback) > 0 {
+			continue
+		}
+		compensated := false
+		if !options.StageRollbackOnly {
+			for _, job := range stage.Jobs {
+				compensated = compensated || o.canRollBackJob(job.Type, job.Config)
+			}
+		}
+		if !compensated {
+			stages = append(stages, stage.Name)
+		}
+	}
+	return stages
+}
+
+// canRollBack reports whether the plugin of a job type declares rollback support.
+// Unknown job types are left to validation and assumed to support it.
+func (o *Orchestrator) canRollBack(jobType string) bool {
+	capabilities, err := o.pluginManager.PluginCapabilities(jobType)
+	return err != nil || capabilities.Rollback
+}
+
+// canRollBackJob reports whether the plugin of a job would undo it: the plugin
+// declares rollback support and, if it checks job configs, finds a compensating
+// action in config. Unknown job types are assumed to support it, as above.
+func (o *Orchestrator) canRollBackJob(jobType string, config map[string]interface{}) bool {
+	canRollBack, err := o.pluginManager.CanRollBack(jobType, config)
+	return err != nil || canRollBack
+}
+
+// rollbackJob calls the plugin Rollback of an executed job
+func (o *Orchestrator) rollbackJob(ctx context.Context, executed rollbackJob) (models.JobResult, error) {
+	job := executed.job
+	jobResult := models.JobResult{
+		Name:        job.Name,
+		Type:        job.Type,
+		ExecutionID: job.ExecutionID,
+		StartTime:   time.Now(),
+	}
+	
+	// Jobs of plugins whose Rollback does nothing, and jobs configured without a
+	// compensating action, have nothing to undo
+	if !o.canRollBackJob(job.Type, executed.config) {
+		reason := "plugin does not support rollback"
+		jobResult.Message = fmt.Sprintf("Not rolled back: plugin %s does not support rollback", job.Type)
+		if o.canRollBack(job.Type) {
+			reason = "no compensating action"
+			jobResult.Message = fmt.Sprintf("Not rolled back: the job has no compensating action for plugin %s", job.Type)
+		}
+		jobResult.EndTime = jobResult.StartTime
+		jobResult.Success = true
+		jobResult.Skipped = true
+		o.logger.Info("Job not rolled back", "stage", executed.stage, "job", job.Name, "reason", reason)
+		return jobResult, nil
+	}
+	
+	ctx = plugin.WithJobName(plugin.WithStageName(ctx, executed.stage), job.Name)
+	err := o.pluginManager.RollbackPlugin(ctx, job.Type, job.ExecutionID)
+	jobResult.EndTime = time.Now()
+	jobResult.Duration = jobResult.EndTime.Sub(jobResult.StartTime)
+	
+	// The plugin may also find that the job left nothing to undo, such as a job
+	// without a compensating action
+	if errors.Is(err, plugin.ErrNothingToRollBack) {
+		jobResult.Success = true
+		jobResult.Skipped = true
+		jobResult.Message = fmt.Sprintf("Not rolled back: plugin %s had nothing to undo for the job", job.Type)
+		o.logger.Warn("Job not rolled back", "stage", executed.stage, "job", job.Name, "reason", "nothing to roll back")
+		return jobResult, nil
+	}
+	
+	jobResult.Success = err == nil
+	if err != nil {
+		jobResult.Message = err.Error()
+		o.logger.Error("Rollback of job failed", "stage", executed.stage, "job", job.Name, "error", err)
+	} else {
+		jobResult.Message = "Rolled back"
+		o.logger.Info("Rolled back job", "stage", executed.stage, "job", job.Name, "executionID", job.ExecutionID)
+	}
+	return jobResult, err
+}
+
+// newDispatcher creates the dispatcher for the plan's notifications; dry runs send none
+func (o *Orchestrator) newDispatcher(plan *models.Plan, options ExecuteOptions) *notify.Dispatcher {
+	if options.DryRun {
+		return nil
+	}
+	
+	notifier, err := notify.FromConfig(plan.Notifications)
+	if err != nil {
+		o.logger.Warn("Notifications disabled", "error", err)
+		return nil
+	}
+	return notify.NewDispatcher(notifier, o.logger)
+}
+
+// finish finalizes the execution result, failed if err is set, sends the success or
+// failure notification and waits for pending notifications to be delivered
+func (o *Orchestrator) finish(ctx context.Context, plan *models.Plan, result *models.ExecutionResult, err error) (*models.ExecutionResult, error) {
+	result = o.finalizeResult(result, err == nil)
+	o.collectArtifacts(ctx, result)
+	
+	// Record metrics even for canceled executions; failing to is not fatal
+	if o.metrics != nil {
+		if metricsErr := o.metrics.Record(context.WithoutCancel(ctx), plan.Metadata.Name, result); metricsErr != nil {
+			o.logger.Warn("Failed to record metrics", "error", metricsErr)
+		}
+	}
+	
+	notification := notify.Message{
+		Event:       models.NotifyOnSuccess,
+		Plan:        plan.Metadata.Name,
+		ExecutionID: result.ID,
+		Duration:    result.Duration,
+	}
+	if err != nil {
+		notification.Event = models.NotifyOnFailure
+		for _, stage := range result.Stages {
+			for _, job := range stage.AllJobs() {
+				if !job.Success && !job.Canceled && !job.Skipped {
+					notification.FailedJobs = append(notification.FailedJobs, stage.Name+"/"+job.Name)
+				}
+			}
+		}
+	}
+	
+	notifications, _ := ctx.Value(notificationsKey).(*notify.Dispatcher)
+	notifications.Send(ctx, notification)
+	notifications.Wait()
+	
+	return result, err
+}
+
+// collectArtifacts adds the artifacts returned by the jobs to the result, storing
+// them in the artifact directory if one is set; failing to is not fatal
+func (o *Orchestrator) collectArtifacts(ctx context.Context, result *models.ExecutionResult) {
+	collector, _ := ctx.Value(artifactsKey).(*artifactCollector)
+	result.Artifacts = collector.Artifacts()
+	if collector == nil || collector.dir == "" || len(result.Artifacts) == 0 {
+		return
+	}
+	
+	if err := storeArtifacts(collector.dir, result.ID, result.Artifacts); err != nil {
+		o.logger.Warn("Failed to store artifacts", "error", err)
+	}
+	for _, artifact := range result.Artifacts {
+		if artifact.Error != "" {
+			o.logger.Warn("Failed to store artifact", "stage", artifact.Stage, "job", artifact.Job, "error", artifact.Error)
+		}
+	}
+}
+
 // finalizeResult completes the execution result
-func (o *Orchestrator) finalizeResult(result *models.ExecutionResult, success bool, message string) (*models.ExecutionResult, error) {
+func (o *Orchestrator) finalizeResult(result *models.ExecutionResult, success bool) *models.ExecutionResult {
 	result.EndTime = time.Now()
 	result.Duration = result.EndTime.Sub(result.StartTime)
 	result.Success = success
 	
-	// Count completed and failed jobs
+	// Count completed, failed, canceled and skipped jobs
 	for _, stage := range result.Stages {
 		for _, job := range stage.Jobs {
 			if job.Success {
 				result.CompletedJobs++
+			} else if job.Canceled {
+				result.CanceledJobs++
+			} else if job.Skipped {
+				result.SkippedJobs++
 			} else {
 				result.FailedJobs++
 			}
 		}
 	}
 	
-	if !success {
-		return result, fmt.Errorf(message)
-	}
-	
-	return result, nil
+	return result
 }
 
 // countTotalJobs counts the total number of jobs in a plan
@@ -158,8 +1021,10 @@ func (o *Orchestrator) countTotalJobs(plan *models.Plan) int {
 	return count
 }
 
-// buildDependencyGraph creates a graph of jobs based on dependencies
-func buildDependencyGraph(jobs []models.Job) *JobGraph {
+// BuildDependencyGraph creates a graph of jobs based on their dependsOn lists and
+// the conditions of these dependencies, and their optionalDependsOn lists for the
+// jobs that are part of jobs
+func BuildDependencyGraph(jobs []models.Job) *JobGraph {
 	graph := NewJobGraph()
 	
 	// Add all jobs to the graph
@@ -170,7 +1035,10 @@ func buildDependencyGraph(jobs []models.Job) *JobGraph {
 	// Add dependencies
 	for _, job := range jobs {
 		for _, depName := range job.DependsOn {
-			graph.AddDependency(job.Name, depName)
+			graph.AddConditionalDependency(job.Name, depName, job.DependsOnConditions[depName])
+		}
+		for _, depName := range job.OptionalDependsOn {
+			graph.AddOptionalDependency(job.Name, depName)
 		}
 	}
 	
@@ -0,0 +1,1352 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cuongtl1992/grp-cli/internal/models"
+	"github.com/cuongtl1992/grp-cli/pkg/plugin"
+	_ "github.com/cuongtl1992/grp-cli/plugins/shell"
+)
+
+// newTestOrchestrator creates an orchestrator with "ok" and "fail" plugins registered
+func newTestOrchestrator(t *testing.T) *Orchestrator {
+	t.Helper()
+	executor := newTestExecutor(t,
+		&MockPlugin{name: "ok"},
+		&MockPlugin{
+			name: "fail",
+			execute: func(ctx context.Context, config map[string]interface{}) (*plugin.Result, error) {
+				return &plugin.Result{Success: false, Message: "boom"}, nil
+			},
+		},
+	)
+	return NewOrchestrator(executor.pluginManager)
+}
+
+// newTestPlan creates a plan with one stage per job type
+func newTestPlan(jobTypes ...string) *models.Plan {
+	plan := &models.Plan{APIVersion: "v1", Kind: "ReleasePlan", Metadata: models.Metadata{Name: "test"}}
+	for i, jobType := range jobTypes {
+		plan.Stages = append(plan.Stages, models.Stage{
+			Name: fmt.Sprintf("stage%d-%s", i, jobType),
+			Jobs: []models.Job{{Name: "job-" + jobType, Type: jobType}},
+		})
+	}
+	return plan
+}
+
+func TestExecutePlanRollbackResult(t *testing.T) {
+	orchestrator := newTestOrchestrator(t)
+	plan := newTestPlan("ok", "fail")
+	plan.Rollback = &models.Rollback{Stages: []models.Stage{
+		{Name: "undo-ok", Jobs: []models.Job{{Name: "undo1", Type: "ok"}}},
+		{Name: "undo-fail", Jobs: []models.Job{{Name: "undo2", Type: "fail"}}},
+	}}
+
+	result, err := orchestrator.ExecutePlan(context.Background(), plan, ExecuteOptions{AutoRollback: true})
+	if err == nil {
+		t.Fatal("Expected execution to fail")
+	}
+	if !strings.Contains(err.Error(), "rollback failed") {
+		t.Errorf("Expected rollback failure in error, got %v", err)
+	}
+
+	if result.Rollback == nil {
+		t.Fatal("Expected rollback result to be recorded")
+	}
+	if result.Rollback.Success {
+		t.Error("Expected rollback to be reported as failed")
+	}
+	if len(result.Rollback.Stages) != 2 || result.Rollback.FailedStages() != 1 {
+		t.Errorf("Expected 1 of 2 rollback stages to fail, got %+v", result.Rollback.Stages)
+	}
+}
+
+func TestExecutePlanOnStageFailure(t *testing.T) {
+	tests := []struct {
+		name             string
+		planBehavior     string
+		optionBehavior   string
+		expected         string
+		expectedStages   []string
+		expectedSkipped  []string
+		expectedRollback bool
+	}{
+		{
+			name:            "abort by default",
+			expected:        models.StageFailureAbort,
+			expectedStages:  []string{"build", "migrate", "verify"},
+			expectedSkipped: []string{"deploy", "cleanup"},
+		},
+		{
+			name:            "continue runs independent stages",
+			planBehavior:    models.StageFailureContinue,
+			expected:        models.StageFailureContinue,
+			expectedStages:  []string{"build", "migrate", "verify", "cleanup"},
+			expectedSkipped: []string{"deploy"},
+		},
+		{
+			name:            "option overrides the plan",
+			planBehavior:    models.StageFailureContinue,
+			optionBehavior:  models.StageFailureAbort,
+			expected:        models.StageFailureAbort,
+			expectedStages:  []string{"build", "migrate", "verify"},
+			expectedSkipped: []string{"deploy", "cleanup"},
+		},
+		{
+			name:             "rollback and abort",
+			optionBehavior:   models.StageFailureRollbackAndAbort,
+			expected:         models.StageFailureRollbackAndAbort,
+			expectedStages:   []string{"build", "migrate", "verify"},
+			expectedSkipped:  []string{"deploy", "cleanup"},
+			expectedRollback: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			orchestrator := newTestOrchestrator(t)
+			plan := &models.Plan{APIVersion: "v1", Kind: "ReleasePlan", Metadata: models.Metadata{Name: "test"}, OnStageFailure: tt.planBehavior}
+			plan.Stages = []models.Stage{
+				{Name: "build", Jobs: []models.Job{{Name: "compile", Type: "ok"}}},
+				{Name: "migrate", DependsOn: []string{"build"}, Jobs: []models.Job{{Name: "schema", Type: "fail"}}},
+				{Name: "verify", DependsOn: []string{"build"}, Jobs: []models.Job{{Name: "smoke", Type: "ok"}}},
+				{Name: "deploy", DependsOn: []string{"migrate"}, Jobs: []models.Job{{Name: "web", Type: "ok"}}},
+				{Name: "cleanup", DependsOn: []string{"verify"}, Jobs: []models.Job{{Name: "prune", Type: "ok"}}},
+			}
+			plan.Rollback = &models.Rollback{Stages: []models.Stage{{Name: "undo", Jobs: []models.Job{{Name: "restore", Type: "ok"}}}}}
+
+			result, err := orchestrator.ExecutePlan(context.Background(), plan, ExecuteOptions{OnStageFailure: tt.optionBehavior})
+			var stageErr *StageError
+			if !errors.As(err, &stageErr) || stageErr.Stage != "migrate" {
+				t.Fatalf("Expected a failure of stage migrate, got %v", err)
+			}
+
+			var stages []string
+			for _, stage := range result.Stages {
+				stages = append(stages, stage.Name)
+			}
+			if !reflect.DeepEqual(stages, tt.expectedStages) {
+				t.Errorf("Expected stages %v to run, got %v", tt.expectedStages, stages)
+			}
+			if !reflect.DeepEqual(result.SkippedStages, tt.expectedSkipped) {
+				t.Errorf("Expected stages %v to be skipped, got %v", tt.expectedSkipped, result.SkippedStages)
+			}
+			if result.OnStageFailure != tt.expected {
+				t.Errorf("Expected onStageFailure %q in the result, got %q", tt.expected, result.OnStageFailure)
+			}
+			if (result.Rollback != nil) != tt.expectedRollback {
+				t.Errorf("Expected rollback %v, got %+v", tt.expectedRollback, result.Rollback)
+			}
+		})
+	}
+}
+
+func TestExecutePlanDryRunRollback(t *testing.T) {
+	executed := false
+	executor := newTestExecutor(t, &MockPlugin{
+		name: "revert",
+		validate: func(config map[string]interface{}) error {
+			if config["target"] != "prod" {
+				return fmt.Errorf("unexpected target %v", config["target"])
+			}
+			return nil
+		},
+		execute: func(ctx context.Context, config map[string]interface{}) (*plugin.Result, error) {
+			executed = true
+			return &plugin.Result{Success: true}, nil
+		},
+	})
+	orchestrator := NewOrchestrator(executor.pluginManager)
+
+	tests := []struct {
+		name    string
+		target  string
+		wantErr bool
+	}{
+		{name: "valid rollback", target: "${variables.env}"},
+		{name: "invalid rollback", target: "staging", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan := &models.Plan{APIVersion: "v1", Kind: "ReleasePlan", Metadata: models.Metadata{Name: "test"}}
+			plan.Variables = map[string]interface{}{"env": "prod"}
+			plan.Stages = []models.Stage{{Name: "deploy", Jobs: []models.Job{{Name: "a", Type: "revert", Config: map[string]interface{}{"target": "prod"}}}}}
+			plan.Rollback = &models.Rollback{Stages: []models.Stage{
+				{Name: "undo", Jobs: []models.Job{{Name: "b", Type: "revert", Config: map[string]interface{}{"target": tt.target}}}},
+			}}
+
+			result, err := orchestrator.ExecutePlan(context.Background(), plan, ExecuteOptions{DryRun: true})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ExecutePlan() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !strings.Contains(err.Error(), "rollback validation failed: rollback stage undo") {
+				t.Errorf("Expected a rollback validation error, got %v", err)
+			}
+			if result.Rollback == nil || len(result.Rollback.Stages) != 1 || result.Rollback.Success == tt.wantErr {
+				t.Fatalf("Expected the simulated rollback in the result, got %+v", result.Rollback)
+			}
+			if fmt.Sprint(result.Rollback.Stages[0].Batches) != "[[b]]" {
+				t.Errorf("Expected the rollback batches, got %v", result.Rollback.Stages[0].Batches)
+			}
+		})
+	}
+
+	if executed {
+		t.Error("Expected dry run not to execute plugins")
+	}
+}
+
+func TestExecuteRollback(t *testing.T) {
+	var executed []string
+	executor := newTestExecutor(t, &MockPlugin{
+		name: "revert",
+		execute: func(ctx context.Context, config map[string]interface{}) (*plugin.Result, error) {
+			executed = append(executed, config["target"].(string))
+			return &plugin.Result{Success: true}, nil
+		},
+		rollback: func(executionID string) error {
+			t.Errorf("Expected no plugin rollback, got one for %s", executionID)
+			return nil
+		},
+	})
+	orchestrator := NewOrchestrator(executor.pluginManager)
+
+	plan := newTestPlan("revert")
+	plan.Variables = map[string]interface{}{"env": "prod"}
+	if _, err := orchestrator.ExecuteRollback(context.Background(), plan, ExecuteOptions{}); err == nil || err.Error() != "plan has no rollback stages" {
+		t.Errorf("Expected an error for a plan without rollback, got %v", err)
+	}
+
+	plan.Rollback = &models.Rollback{Stages: []models.Stage{
+		{Name: "undo-app", Jobs: []models.Job{{Name: "app", Type: "revert", Config: map[string]interface{}{"target": "${variables.env}-app"}}}},
+		{Name: "undo-db", Jobs: []models.Job{{Name: "db", Type: "revert", Config: map[string]interface{}{"target": "${variables.env}-db"}}}},
+	}}
+	result, err := orchestrator.ExecuteRollback(context.Background(), plan, ExecuteOptions{})
+	if err != nil {
+		t.Fatalf("ExecuteRollback() error = %v", err)
+	}
+	if !result.Success || len(result.Stages) != 2 {
+		t.Errorf("Expected 2 successful rollback stages, got %+v", result)
+	}
+	if strings.Join(executed, ",") != "prod-app,prod-db" {
+		t.Errorf("Expected only the rollback stages executed in order, got %v", executed)
+	}
+}
+
+func TestExecuteRollbackParallelStages(t *testing.T) {
+	// Both independent stages must be running for either to finish
+	started := make(chan string, 2)
+	release := make(chan struct{})
+	var mutex sync.Mutex
+	var finished []string
+	executor := newTestExecutor(t, &MockPlugin{
+		name: "revert",
+		execute: func(ctx context.Context, config map[string]interface{}) (*plugin.Result, error) {
+			target := config["target"].(string)
+			if target != "dns" {
+				started <- target
+				<-release
+			}
+			mutex.Lock()
+			finished = append(finished, target)
+			mutex.Unlock()
+			return &plugin.Result{Success: target != "app", Message: "revert " + target}, nil
+		},
+	})
+	orchestrator := NewOrchestrator(executor.pluginManager)
+
+	plan := newTestPlan("revert")
+	plan.Rollback = &models.Rollback{Stages: []models.Stage{
+		{Name: "undo-app", Jobs: []models.Job{{Name: "app", Type: "revert", Config: map[string]interface{}{"target": "app"}}}},
+		{Name: "undo-db", Jobs: []models.Job{{Name: "db", Type: "revert", Config: map[string]interface{}{"target": "db"}}}},
+		{Name: "undo-dns", DependsOn: []string{"undo-app", "undo-db"}, Jobs: []models.Job{{Name: "dns", Type: "revert", Config: map[string]interface{}{"target": "dns"}}}},
+	}}
+
+	go func() {
+		for i := 0; i < 2; i++ {
+			select {
+			case <-started:
+			case <-time.After(5 * time.Second):
+				t.Error("Expected the independent rollback stages to run in parallel")
+			}
+		}
+		close(release)
+	}()
+
+	result, err := orchestrator.ExecuteRollback(context.Background(), plan, ExecuteOptions{})
+	if err == nil || !strings.Contains(err.Error(), "rollback stage undo-app") {
+		t.Fatalf("Expected the failure of undo-app, got %v", err)
+	}
+	if len(finished) != 3 || finished[2] != "dns" {
+		t.Errorf("Expected undo-dns to run last despite the failure, got %v", finished)
+	}
+	if result.Success || result.FailedStages() != 1 || len(result.Stages) != 3 {
+		t.Errorf("Expected 3 rollback stages with 1 failure, got %+v", result)
+	}
+}
+
+func TestExecutePlanTypedErrors(t *testing.T) {
+	orchestrator := newTestOrchestrator(t)
+
+	_, err := orchestrator.ExecutePlan(context.Background(), newTestPlan("ok", "fail"), ExecuteOptions{})
+	if err == nil {
+		t.Fatal("Expected execution to fail")
+	}
+
+	var stageErr *StageError
+	if !errors.As(err, &stageErr) || stageErr.Stage != "stage1-fail" || stageErr.Canceled {
+		t.Errorf("Expected a failed StageError for stage1-fail, got %#v", stageErr)
+	}
+	var jobErr *JobError
+	if !errors.As(err, &jobErr) || jobErr.Stage != "stage1-fail" || jobErr.Job != "job-fail" {
+		t.Errorf("Expected a JobError for job-fail, got %#v", jobErr)
+	}
+	if !strings.HasPrefix(err.Error(), "Stage stage1-fail failed: job job-fail failed: ") {
+		t.Errorf("Expected a readable message, got %q", err.Error())
+	}
+}
+
+func TestExecutePlanHooks(t *testing.T) {
+	tests := []struct {
+		name            string
+		preJobs         []models.Job
+		jobType         string
+		expectSuccess   bool
+		expectJobs      int
+		expectPostJobs  int
+		expectErrPrefix string
+	}{
+		{
+			name:           "successful stage",
+			preJobs:        []models.Job{{Name: "maintenance-on", Type: "ok"}},
+			jobType:        "ok",
+			expectSuccess:  true,
+			expectJobs:     1,
+			expectPostJobs: 1,
+		},
+		{
+			name:            "post hooks run after a failed job",
+			preJobs:         []models.Job{{Name: "maintenance-on", Type: "ok"}},
+			jobType:         "fail",
+			expectJobs:      1,
+			expectPostJobs:  1,
+			expectErrPrefix: "Stage deploy failed: job deploy failed",
+		},
+		{
+			name:            "failed pre hook skips the jobs",
+			preJobs:         []models.Job{{Name: "maintenance-on", Type: "fail"}},
+			jobType:         "ok",
+			expectJobs:      0,
+			expectPostJobs:  1,
+			expectErrPrefix: "Stage deploy failed: pre hook job maintenance-on failed",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan := newTestPlan()
+			plan.Stages = []models.Stage{{
+				Name:     "deploy",
+				PreJobs:  tt.preJobs,
+				Jobs:     []models.Job{{Name: "deploy", Type: tt.jobType}},
+				PostJobs: []models.Job{{Name: "maintenance-off", Type: "ok"}},
+			}}
+
+			result, err := newTestOrchestrator(t).ExecutePlan(context.Background(), plan, ExecuteOptions{})
+			if (err == nil) != tt.expectSuccess {
+				t.Fatalf("ExecutePlan() error = %v, expected success %v", err, tt.expectSuccess)
+			}
+			if err != nil && !strings.HasPrefix(err.Error(), tt.expectErrPrefix) {
+				t.Errorf("Expected error starting with %q, got %q", tt.expectErrPrefix, err.Error())
+			}
+
+			stage := result.Stages[0]
+			if len(stage.PreJobs) != len(tt.preJobs) || len(stage.Jobs) != tt.expectJobs || len(stage.PostJobs) != tt.expectPostJobs {
+				t.Fatalf("Expected %d pre, %d and %d post job results, got %+v", len(tt.preJobs), tt.expectJobs, tt.expectPostJobs, stage)
+			}
+			if !stage.PostJobs[0].Success {
+				t.Errorf("Expected the post hook to succeed, got %+v", stage.PostJobs[0])
+			}
+		})
+	}
+}
+
+func TestExecutePlanPlanHooks(t *testing.T) {
+	tests := []struct {
+		name          string
+		jobTypes      []string
+		onSuccess     []models.Job
+		onFailure     []models.Job
+		options       ExecuteOptions
+		canceled      bool
+		expectSuccess bool
+		expectHook    string
+		expectJobs    int
+		expectError   string
+	}{
+		{
+			name:          "success runs onSuccess",
+			jobTypes:      []string{"ok"},
+			onSuccess:     []models.Job{{Name: "tag", Type: "ok"}, {Name: "notify", Type: "ok", DependsOn: []string{"tag"}}},
+			onFailure:     []models.Job{{Name: "page", Type: "ok"}},
+			expectSuccess: true,
+			expectHook:    models.PlanHookOnSuccess,
+			expectJobs:    2,
+		},
+		{
+			name:        "failure runs onFailure after the rollback",
+			jobTypes:    []string{"ok", "fail"},
+			onSuccess:   []models.Job{{Name: "tag", Type: "ok"}},
+			onFailure:   []models.Job{{Name: "page", Type: "ok"}},
+			options:     ExecuteOptions{AutoRollback: true},
+			expectHook:  models.PlanHookOnFailure,
+			expectJobs:  1,
+			expectError: "boom",
+		},
+		{
+			name:        "failed onSuccess job fails the execution",
+			jobTypes:    []string{"ok"},
+			onSuccess:   []models.Job{{Name: "tag", Type: "fail"}},
+			onFailure:   []models.Job{{Name: "page", Type: "ok"}},
+			expectHook:  models.PlanHookOnSuccess,
+			expectJobs:  1,
+			expectError: "onSuccess hook",
+		},
+		{
+			name:          "dry run validates onSuccess",
+			jobTypes:      []string{"ok"},
+			onSuccess:     []models.Job{{Name: "tag", Type: "ok"}},
+			options:       ExecuteOptions{DryRun: true},
+			expectSuccess: true,
+			expectHook:    models.PlanHookOnSuccess,
+			expectJobs:    1,
+		},
+		{
+			name:          "no hook for the outcome",
+			jobTypes:      []string{"ok"},
+			onFailure:     []models.Job{{Name: "page", Type: "ok"}},
+			expectSuccess: true,
+		},
+		{
+			name:        "interrupted execution runs no hook",
+			jobTypes:    []string{"ok"},
+			onFailure:   []models.Job{{Name: "page", Type: "ok"}},
+			canceled:    true,
+			expectError: "canceled",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			orchestrator := newTestOrchestrator(t)
+			plan := newTestPlan(tt.jobTypes...)
+			plan.OnSuccess = tt.onSuccess
+			plan.OnFailure = tt.onFailure
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			if tt.canceled {
+				cancel()
+			}
+
+			result, err := orchestrator.ExecutePlan(ctx, plan, tt.options)
+			if tt.expectError == "" && err != nil {
+				t.Fatalf("ExecutePlan() error = %v", err)
+			}
+			if tt.expectError != "" && (err == nil || !strings.Contains(err.Error(), tt.expectError)) {
+				t.Fatalf("Expected an error containing %q, got %v", tt.expectError, err)
+			}
+			if result.Success != tt.expectSuccess {
+				t.Errorf("Expected success %v, got %v", tt.expectSuccess, result.Success)
+			}
+
+			if tt.expectHook == "" {
+				if result.Hooks != nil {
+					t.Errorf("Expected no hook to run, got %+v", result.Hooks)
+				}
+				return
+			}
+			if result.Hooks == nil || result.Hooks.Name != tt.expectHook || len(result.Hooks.Jobs) != tt.expectJobs {
+				t.Fatalf("Expected %d job results of the %s hook, got %+v", tt.expectJobs, tt.expectHook, result.Hooks)
+			}
+			if result.Hooks.Success != (tt.expectSuccess || tt.expectHook == models.PlanHookOnFailure) {
+				t.Errorf("Expected the hook to succeed, got %+v", result.Hooks)
+			}
+			if result.Rollback != nil && result.Hooks.StartTime.Before(result.Rollback.EndTime) {
+				t.Errorf("Expected the hook to run after the rollback")
+			}
+			if result.TotalJobs != len(plan.Stages) {
+				t.Errorf("Expected hook jobs not to count as plan jobs, got %d", result.TotalJobs)
+			}
+		})
+	}
+}
+
+func TestExecutePlanSuccess(t *testing.T) {
+	orchestrator := newTestOrchestrator(t)
+
+	result, err := orchestrator.ExecutePlan(context.Background(), newTestPlan("ok", "ok"), ExecuteOptions{AutoRollback: true})
+	if err != nil {
+		t.Fatalf("ExecutePlan() error = %v", err)
+	}
+	if result.Rollback != nil {
+		t.Error("Expected no rollback for a successful plan")
+	}
+	if !result.Success || result.CompletedJobs != 2 {
+		t.Errorf("Expected 2 completed jobs, got %+v", result)
+	}
+}
+
+func TestExecutePlanWorkingDir(t *testing.T) {
+	dir := t.TempDir()
+
+	var mu sync.Mutex
+	var seen []string
+	executor := newTestExecutor(t, &MockPlugin{
+		name: "record",
+		execute: func(ctx context.Context, config map[string]interface{}) (*plugin.Result, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			seen = append(seen, plugin.WorkingDir(ctx), fmt.Sprint(config["dir"]))
+			return &plugin.Result{Success: true}, nil
+		},
+	})
+	orchestrator := NewOrchestrator(executor.pluginManager)
+
+	tests := []struct {
+		name       string
+		workingDir string
+		expected   []string
+		errMsg     string
+	}{
+		{name: "absolute", workingDir: dir, expected: []string{dir, dir + "/infra"}},
+		{name: "missing", workingDir: filepath.Join(dir, "missing"), errMsg: "invalid working directory"},
+		{name: "not a directory", workingDir: filepath.Join(dir, "file"), errMsg: "is not a directory"},
+	}
+	if err := os.WriteFile(filepath.Join(dir, "file"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			seen = nil
+			plan := newTestPlan("record")
+			plan.Stages[0].Jobs[0].Config = map[string]interface{}{"dir": "${run.workingDir}/infra"}
+
+			_, err := orchestrator.ExecutePlan(context.Background(), plan, ExecuteOptions{WorkingDir: tt.workingDir})
+			if tt.errMsg != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+					t.Fatalf("Expected error containing %q, got %v", tt.errMsg, err)
+				}
+				if len(seen) > 0 {
+					t.Errorf("Expected no job to run, got %v", seen)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ExecutePlan() error = %v", err)
+			}
+			if strings.Join(seen, " ") != strings.Join(tt.expected, " ") {
+				t.Errorf("Expected working directory and resolved config %v, got %v", tt.expected, seen)
+			}
+		})
+	}
+
+	// Without a working directory the execution is rooted at the current directory
+	cwd, _ := os.Getwd()
+	seen = nil
+	if _, err := orchestrator.ExecutePlan(context.Background(), newTestPlan("record"), ExecuteOptions{}); err != nil {
+		t.Fatalf("ExecutePlan() error = %v", err)
+	}
+	if len(seen) == 0 || seen[0] != cwd {
+		t.Errorf("Expected the current directory %s, got %v", cwd, seen)
+	}
+}
+
+func TestExecutePlanArtifacts(t *testing.T) {
+	source := filepath.Join(t.TempDir(), "app.tar.gz")
+	if err := os.WriteFile(source, []byte("archive"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	executor := newTestExecutor(t, &MockPlugin{
+		name: "build",
+		execute: func(ctx context.Context, config map[string]interface{}) (*plugin.Result, error) {
+			return &plugin.Result{Success: true, Artifacts: []plugin.Artifact{
+				{Name: "app", Type: "file", Path: source},
+				{Name: "version.txt", Type: "text", Data: []byte("1.2.3")},
+				{Name: "missing", Type: "file", Path: filepath.Join(t.TempDir(), "missing")},
+			}}, nil
+		},
+	})
+	orchestrator := NewOrchestrator(executor.pluginManager)
+	dir := t.TempDir()
+
+	result, err := orchestrator.ExecutePlan(context.Background(), newTestPlan("build"), ExecuteOptions{ArtifactDir: dir})
+	if err != nil {
+		t.Fatalf("ExecutePlan() error = %v", err)
+	}
+	if len(result.Artifacts) != 3 {
+		t.Fatalf("Expected 3 artifacts, got %+v", result.Artifacts)
+	}
+
+	var manifest struct {
+		ExecutionID string                       `json:"executionId"`
+		Jobs        map[string][]models.Artifact `json:"jobs"`
+	}
+	data, err := os.ReadFile(filepath.Join(dir, result.ID, ArtifactManifest))
+	if err != nil {
+		t.Fatalf("Failed to read manifest: %v", err)
+	}
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		t.Fatalf("Failed to decode manifest: %v", err)
+	}
+	artifacts := manifest.Jobs["stage0-build/job-build"]
+	if manifest.ExecutionID != result.ID || len(artifacts) != 3 {
+		t.Fatalf("Expected the 3 artifacts of the job in the manifest, got %+v", manifest)
+	}
+
+	tests := []struct {
+		name     string
+		artifact models.Artifact
+		content  string
+		source   string
+	}{
+		{name: "file", artifact: artifacts[0], content: "archive", source: source},
+		{name: "inline", artifact: artifacts[1], content: "1.2.3"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.artifact.Error != "" || tt.artifact.Source != tt.source || tt.artifact.Data != nil {
+				t.Errorf("Unexpected artifact %+v", tt.artifact)
+			}
+			if !strings.HasPrefix(tt.artifact.Path, filepath.Join(dir, result.ID, "stage0-build", "job-build")) {
+				t.Errorf("Expected the artifact to be stored in the job's directory, got %s", tt.artifact.Path)
+			}
+			stored, err := os.ReadFile(tt.artifact.Path)
+			if err != nil || string(stored) != tt.content || tt.artifact.Size != int64(len(tt.content)) {
+				t.Errorf("Expected %q of size %d, got %q of size %d (%v)", tt.content, len(tt.content), stored, tt.artifact.Size, err)
+			}
+		})
+	}
+	if artifacts[2].Error == "" {
+		t.Error("Expected an error for the missing artifact")
+	}
+}
+
+func TestExecutePlanRollsBackExecutedJobs(t *testing.T) {
+	var rolledBack []string
+	executor := newTestExecutor(t,
+		&MockPlugin{
+			name: "deploy",
+			execute: func(ctx context.Context, config map[string]interface{}) (*plugin.Result, error) {
+				return &plugin.Result{Success: true, ExecutionID: config["id"].(string)}, nil
+			},
+			rollback: func(executionID string) error {
+				rolledBack = append(rolledBack, executionID)
+				return nil
+			},
+		},
+		&MockPlugin{
+			name: "fail",
+			execute: func(ctx context.Context, config map[string]interface{}) (*plugin.Result, error) {
+				return &plugin.Result{Success: false, Message: "boom"}, nil
+			},
+		},
+	)
+	orchestrator := NewOrchestrator(executor.pluginManager)
+
+	plan := &models.Plan{APIVersion: "v1", Kind: "ReleasePlan", Metadata: models.Metadata{Name: "test"}}
+	plan.Stages = []models.Stage{
+		{Name: "first", Jobs: []models.Job{{Name: "a", Type: "deploy", Config: map[string]interface{}{"id": "exec-a"}}}},
+		{Name: "second", Jobs: []models.Job{
+			{Name: "b", Type: "deploy", Config: map[string]interface{}{"id": "exec-b"}},
+			{Name: "c", Type: "fail", DependsOn: []string{"b"}},
+		}},
+	}
+
+	result, err := orchestrator.ExecutePlan(context.Background(), plan, ExecuteOptions{AutoRollback: true})
+	if err == nil {
+		t.Fatal("Expected execution to fail")
+	}
+
+	if strings.Join(rolledBack, ",") != "exec-b,exec-a" {
+		t.Errorf("Expected executed jobs rolled back in reverse order, got %v", rolledBack)
+	}
+	if result.Rollback == nil || len(result.Rollback.Jobs) != 2 || !result.Rollback.Success {
+		t.Errorf("Expected 2 successful job rollbacks, got %+v", result.Rollback)
+	}
+}
+
+func TestExecutePlanRollsBackJobsOfTheSamePlugin(t *testing.T) {
+	orchestrator := newTestOrchestrator(t)
+	undone := filepath.Join(t.TempDir(), "undone")
+	shellJob := func(name string) models.Job {
+		return models.Job{Name: name, Type: "shell", Config: map[string]interface{}{
+			"command":         "true",
+			"rollbackCommand": fmt.Sprintf("echo %s >> %s", name, undone),
+		}}
+	}
+
+	plan := &models.Plan{APIVersion: "v1", Kind: "ReleasePlan", Metadata: models.Metadata{Name: "test"}}
+	plan.Stages = []models.Stage{
+		{Name: "first", Jobs: []models.Job{shellJob("a")}},
+		{Name: "second", DependsOn: []string{"first"}, Jobs: []models.Job{
+			shellJob("b"),
+			{Name: "c", Type: "fail", DependsOn: []string{"b"}},
+		}},
+	}
+
+	result, err := orchestrator.ExecutePlan(context.Background(), plan, ExecuteOptions{AutoRollback: true})
+	if err == nil {
+		t.Fatal("Expected execution to fail")
+	}
+
+	a, b := result.Stages[0].Jobs[0], result.Stages[1].Jobs[0]
+	if a.ExecutionID == "" || a.ExecutionID == b.ExecutionID || a.ExecutionID == result.ID {
+		t.Errorf("Expected each job to get an execution ID of its own, got %q and %q", a.ExecutionID, b.ExecutionID)
+	}
+
+	// Each job's rollback undoes its own command only, most recent job first
+	data, readErr := os.ReadFile(undone)
+	if readErr != nil {
+		t.Fatalf("Failed to read the rollback output: %v", readErr)
+	}
+	if string(data) != "b\na\n" {
+		t.Errorf("Expected b then a to be undone, got %q", data)
+	}
+	if result.Rollback == nil || len(result.Rollback.Jobs) != 2 || result.Rollback.SkippedJobs() != 0 {
+		t.Errorf("Expected 2 compensated jobs, got %+v", result.Rollback)
+	}
+}
+
+func TestExecutePlanRollsBackOnlyCompensatedJobs(t *testing.T) {
+	var mutex sync.Mutex
+	var rolledBack []string
+	executor := newTestExecutor(t,
+		checkedRollbackPlugin{&MockPlugin{name: "script", rollback: func(executionID string) error {
+			mutex.Lock()
+			defer mutex.Unlock()
+			rolledBack = append(rolledBack, executionID)
+			return nil
+		}}},
+		&MockPlugin{name: "fail", execute: func(ctx context.Context, config map[string]interface{}) (*plugin.Result, error) {
+			return &plugin.Result{Success: false, Message: "boom"}, nil
+		}},
+	)
+	orchestrator := NewOrchestrator(executor.pluginManager)
+
+	plan := &models.Plan{APIVersion: "v1", Kind: "ReleasePlan", Metadata: models.Metadata{Name: "test"}}
+	plan.Stages = []models.Stage{
+		{Name: "migrate", Jobs: []models.Job{
+			{Name: "schema", Type: "script", Config: map[string]interface{}{"undo": "migrate down"}},
+			{Name: "report", Type: "script"},
+		}},
+		{Name: "verify", DependsOn: []string{"migrate"}, Jobs: []models.Job{{Name: "check", Type: "fail"}}},
+	}
+
+	result, err := orchestrator.ExecutePlan(context.Background(), plan, ExecuteOptions{AutoRollback: true, RequireRollback: true})
+	if err == nil {
+		t.Fatal("Expected execution to fail")
+	}
+
+	executionIDs := make(map[string]string)
+	for _, job := range result.Stages[0].Jobs {
+		executionIDs[job.Name] = job.ExecutionID
+	}
+	if !reflect.DeepEqual(rolledBack, []string{executionIDs["schema"]}) {
+		t.Errorf("Expected only the execution of schema (%s) to be rolled back, got %v", executionIDs["schema"], rolledBack)
+	}
+
+	skipped := make(map[string]bool)
+	for _, job := range result.Rollback.Jobs {
+		skipped[job.Name] = job.Skipped
+	}
+	if !reflect.DeepEqual(skipped, map[string]bool{"schema": false, "report": true}) {
+		t.Errorf("Expected report to be skipped and schema rolled back, got %+v", result.Rollback.Jobs)
+	}
+}
+
+func TestExecutePlanStageRollback(t *testing.T) {
+	tests := []struct {
+		name     string
+		options  ExecuteOptions
+		expected []string
+	}{
+		{name: "no rollback", options: ExecuteOptions{}},
+		{
+			name:     "stage rollback before the global rollback",
+			options:  ExecuteOptions{AutoRollback: true},
+			expected: []string{"undo-deploy", "plugin rollback", "undo-plan"},
+		},
+		{
+			name:     "stage rollback only",
+			options:  ExecuteOptions{AutoRollback: true, StageRollbackOnly: true},
+			expected: []string{"undo-deploy"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var mutex sync.Mutex
+			var calls []string
+			record := func(call string) {
+				mutex.Lock()
+				defer mutex.Unlock()
+				calls = append(calls, call)
+			}
+			executor := newTestExecutor(t,
+				&MockPlugin{
+					name: "record",
+					execute: func(ctx context.Context, config map[string]interface{}) (*plugin.Result, error) {
+						if id, _ := config["id"].(string); strings.HasPrefix(id, "undo") {
+							record(id)
+						}
+						return &plugin.Result{Success: config["fail"] != true, Message: "done"}, nil
+					},
+					rollback: func(executionID string) error {
+						record("plugin rollback")
+						return nil
+					},
+				},
+			)
+			orchestrator := NewOrchestrator(executor.pluginManager)
+
+			plan := &models.Plan{APIVersion: "v1", Kind: "ReleasePlan", Metadata: models.Metadata{Name: "test"}}
+			plan.Stages = []models.Stage{
+				{
+					Name:     "build",
+					Jobs:     []models.Job{{Name: "image", Type: "record"}},
+					Rollback: []models.Job{{Name: "undo", Type: "record", Config: map[string]interface{}{"id": "undo-build"}}},
+				},
+				{
+					Name:     "deploy",
+					Jobs:     []models.Job{{Name: "apply", Type: "record", Config: map[string]interface{}{"fail": true}}},
+					Rollback: []models.Job{{Name: "undo", Type: "record", Config: map[string]interface{}{"id": "undo-deploy"}}},
+				},
+			}
+			plan.Rollback = &models.Rollback{Stages: []models.Stage{
+				{Name: "undo", Jobs: []models.Job{{Name: "undo", Type: "record", Config: map[string]interface{}{"id": "undo-plan"}}}},
+			}}
+
+			result, err := orchestrator.ExecutePlan(context.Background(), plan, tt.options)
+			if err == nil {
+				t.Fatal("Expected execution to fail")
+			}
+
+			if !reflect.DeepEqual(calls, tt.expected) {
+				t.Errorf("Expected calls %v, got %v", tt.expected, calls)
+			}
+			if tt.expected == nil {
+				return
+			}
+			if result.Rollback == nil || len(result.Rollback.Stages) == 0 || result.Rollback.Stages[0].Name != "deploy/rollback" {
+				t.Errorf("Expected the stage rollback to be recorded first, got %+v", result.Rollback)
+			}
+		})
+	}
+}
+
+// noRollbackPlugin is a mock plugin declaring that it cannot roll back its jobs
+type noRollbackPlugin struct {
+	*MockPlugin
+}
+
+func (noRollbackPlugin) Capabilities() plugin.Capabilities { return plugin.Capabilities{} }
+
+// checkedRollbackPlugin is a mock plugin only rolling back the jobs configured
+// with an undo action
+type checkedRollbackPlugin struct {
+	*MockPlugin
+}
+
+func (checkedRollbackPlugin) CanRollBack(config map[string]interface{}) bool {
+	return config["undo"] != nil
+}
+
+func TestExecutePlanRollbackCapabilities(t *testing.T) {
+	var rolledBack []string
+	rollback := func(executionID string) error {
+		rolledBack = append(rolledBack, executionID)
+		return nil
+	}
+	executor := newTestExecutor(t,
+		&MockPlugin{name: "deploy", rollback: rollback},
+		noRollbackPlugin{&MockPlugin{name: "notify", rollback: rollback}},
+		// configure stands for a plugin whose jobs may have no compensating action
+		&MockPlugin{name: "configure", rollback: func(executionID string) error {
+			return fmt.Errorf("no rollback command: %w", plugin.ErrNothingToRollBack)
+		}},
+		&MockPlugin{
+			name: "fail",
+			execute: func(ctx context.Context, config map[string]interface{}) (*plugin.Result, error) {
+				return &plugin.Result{Success: false, Message: "boom"}, nil
+			},
+		},
+	)
+	orchestrator := NewOrchestrator(executor.pluginManager)
+	plan := newTestPlan("deploy", "notify", "configure", "fail")
+
+	result, err := orchestrator.ExecutePlan(context.Background(), plan, ExecuteOptions{AutoRollback: true})
+	if err == nil {
+		t.Fatal("Expected execution to fail")
+	}
+
+	if len(rolledBack) != 1 {
+		t.Errorf("Expected only the deploy job to be rolled back, got %v", rolledBack)
+	}
+	if result.Rollback == nil || len(result.Rollback.Jobs) != 3 || !result.Rollback.Success {
+		t.Fatalf("Expected 3 successful job rollbacks, got %+v", result.Rollback)
+	}
+	for _, job := range result.Rollback.Jobs {
+		if skipped := job.Type != "deploy"; job.Skipped != skipped {
+			t.Errorf("Expected rollback of %s skipped=%v, got %+v", job.Name, skipped, job)
+		}
+	}
+}
+
+func TestExecutePlanRequireRollback(t *testing.T) {
+	executor := newTestExecutor(t,
+		&MockPlugin{name: "deploy"},
+		noRollbackPlugin{&MockPlugin{name: "notify"}},
+		checkedRollbackPlugin{&MockPlugin{name: "script"}},
+	)
+	orchestrator := NewOrchestrator(executor.pluginManager)
+
+	tests := []struct {
+		name        string
+		plan        func() *models.Plan
+		options     ExecuteOptions
+		expectedErr string
+	}{
+		{
+			name:        "jobs without a compensating action",
+			plan:        func() *models.Plan { return newTestPlan("deploy", "script") },
+			options:     ExecuteOptions{AutoRollback: true, RequireRollback: true},
+			expectedErr: "could not undo stages stage1-script",
+		},
+		{
+			name: "job with a compensating action",
+			plan: func() *models.Plan {
+				plan := newTestPlan("deploy", "script")
+				plan.Stages[1].Jobs = append(plan.Stages[1].Jobs, models.Job{Name: "migrate", Type: "script", Config: map[string]interface{}{"undo": "migrate down"}})
+				return plan
+			},
+			options: ExecuteOptions{AutoRollback: true, RequireRollback: true},
+		},
+		{
+			name:        "stage without compensation",
+			plan:        func() *models.Plan { return newTestPlan("deploy", "notify") },
+			options:     ExecuteOptions{AutoRollback: true, RequireRollback: true},
+			expectedErr: "could not undo stages stage1-notify",
+		},
+		{
+			name: "hooks are not rolled back",
+			plan: func() *models.Plan {
+				plan := newTestPlan("deploy", "notify")
+				plan.Stages[1].PreJobs = []models.Job{{Name: "maintenance-on", Type: "deploy"}}
+				plan.Stages[1].PostJobs = []models.Job{{Name: "maintenance-off", Type: "deploy"}}
+				return plan
+			},
+			options:     ExecuteOptions{AutoRollback: true, RequireRollback: true},
+			expectedErr: "could not undo stages stage1-notify",
+		},
+		{
+			name:    "only a warning without RequireRollback",
+			plan:    func() *models.Plan { return newTestPlan("deploy", "notify") },
+			options: ExecuteOptions{AutoRollback: true},
+		},
+		{
+			name:    "not checked without rollback",
+			plan:    func() *models.Plan { return newTestPlan("deploy", "notify") },
+			options: ExecuteOptions{RequireRollback: true},
+		},
+		{
+			name: "stage rollback jobs",
+			plan: func() *models.Plan {
+				plan := newTestPlan("deploy", "notify")
+				plan.Stages[1].Rollback = []models.Job{{Name: "retract", Type: "deploy"}}
+				return plan
+			},
+			options: ExecuteOptions{AutoRollback: true, RequireRollback: true},
+		},
+		{
+			name: "plan rollback stages",
+			plan: func() *models.Plan {
+				plan := newTestPlan("deploy", "notify")
+				plan.Rollback = &models.Rollback{Stages: []models.Stage{{Name: "undo", Jobs: []models.Job{{Name: "undo", Type: "deploy"}}}}}
+				return plan
+			},
+			options: ExecuteOptions{OnStageFailure: models.StageFailureRollbackAndAbort, RequireRollback: true},
+		},
+		{
+			name: "plugin rollbacks skipped by StageRollbackOnly",
+			plan: func() *models.Plan {
+				plan := newTestPlan("deploy", "notify")
+				plan.Stages[1].Rollback = []models.Job{{Name: "retract", Type: "deploy"}}
+				return plan
+			},
+			options:     ExecuteOptions{AutoRollback: true, RequireRollback: true, StageRollbackOnly: true},
+			expectedErr: "could not undo stages stage0-deploy:",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := orchestrator.ExecutePlan(context.Background(), tt.plan(), tt.options)
+			if tt.expectedErr == "" {
+				if err != nil {
+					t.Fatalf("ExecutePlan() error = %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, ErrRollbackRequired) || !strings.Contains(err.Error(), tt.expectedErr) {
+				t.Fatalf("Expected error containing %q, got %v", tt.expectedErr, err)
+			}
+			if len(result.Stages) != 0 {
+				t.Errorf("Expected no stage to run, got %+v", result.Stages)
+			}
+		})
+	}
+}
+
+func TestExecutePlanEvents(t *testing.T) {
+	orchestrator := newTestOrchestrator(t)
+	events := make(chan Event, 16)
+
+	_, err := orchestrator.ExecutePlan(context.Background(), newTestPlan("ok", "fail"), ExecuteOptions{Events: ChannelSink(events)})
+	if err == nil {
+		t.Fatal("Expected execution to fail")
+	}
+	close(events)
+
+	var got []string
+	for event := range events {
+		if event.Time.IsZero() {
+			t.Errorf("Expected %s event to be timestamped", event.Type)
+		}
+		got = append(got, fmt.Sprintf("%s %s %s %t", event.Type, event.Stage, event.Job, event.Success))
+	}
+
+	want := []string{
+		"StageStarted stage0-ok  false",
+		"JobStarted stage0-ok job-ok false",
+		"JobFinished stage0-ok job-ok true",
+		"StageFinished stage0-ok  true",
+		"StageStarted stage1-fail  false",
+		"JobStarted stage1-fail job-fail false",
+		"JobFinished stage1-fail job-fail false",
+		"StageFinished stage1-fail  false",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Unexpected events:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestExecutePlanNotifications(t *testing.T) {
+	var mu sync.Mutex
+	var messages []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload map[string]string
+		json.NewDecoder(r.Body).Decode(&payload)
+		mu.Lock()
+		messages = append(messages, payload["text"])
+		mu.Unlock()
+	}))
+	defer server.Close()
+
+	orchestrator := newTestOrchestrator(t)
+	plan := newTestPlan("ok", "fail")
+	plan.Notifications = &models.Notifications{Slack: &models.SlackNotification{
+		WebhookURL: server.URL,
+		Events:     []string{models.NotifyOnStart, models.NotifyOnFailure},
+		Template:   "{{.Event}} {{join .FailedJobs \",\"}}",
+	}}
+
+	if _, err := orchestrator.ExecutePlan(context.Background(), plan, ExecuteOptions{}); err == nil {
+		t.Fatal("Expected execution to fail")
+	}
+
+	// Notifications are delivered before ExecutePlan returns
+	mu.Lock()
+	defer mu.Unlock()
+	if strings.Join(messages, "; ") != "start ; failure stage1-fail/job-fail" {
+		t.Errorf("Unexpected notifications: %q", messages)
+	}
+}
+
+func TestExecutePlanCanceled(t *testing.T) {
+	tests := []struct {
+		name       string
+		options    ExecuteOptions
+		rolledBack []string
+	}{
+		{name: "auto rollback only applies to failures", options: ExecuteOptions{AutoRollback: true}},
+		{name: "rollback on cancel", options: ExecuteOptions{RollbackOnCancel: true}, rolledBack: []string{"exec-a"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			var rolledBack []string
+			executor := newTestExecutor(t,
+				&MockPlugin{
+					name: "deploy",
+					execute: func(ctx context.Context, config map[string]interface{}) (*plugin.Result, error) {
+						return &plugin.Result{Success: true, ExecutionID: "exec-a"}, nil
+					},
+					rollback: func(executionID string) error {
+						rolledBack = append(rolledBack, executionID)
+						return nil
+					},
+				},
+				&MockPlugin{
+					name: "interrupted",
+					execute: func(jobCtx context.Context, config map[string]interface{}) (*plugin.Result, error) {
+						cancel()
+						<-jobCtx.Done()
+						return nil, jobCtx.Err()
+					},
+				},
+			)
+			orchestrator := NewOrchestrator(executor.pluginManager)
+			plan := newTestPlan("deploy", "interrupted")
+
+			result, err := orchestrator.ExecutePlan(ctx, plan, tt.options)
+			if err == nil || !strings.Contains(err.Error(), "canceled") {
+				t.Fatalf("Expected cancellation error, got %v", err)
+			}
+			if !result.Canceled || result.CanceledJobs != 1 || result.FailedJobs != 0 || result.CompletedJobs != 1 {
+				t.Errorf("Expected 1 completed and 1 canceled job, got %+v", result)
+			}
+			if !result.Stages[1].Canceled {
+				t.Errorf("Expected stage to be canceled, got %+v", result.Stages[1])
+			}
+			if strings.Join(rolledBack, ",") != strings.Join(tt.rolledBack, ",") {
+				t.Errorf("Expected rolled back %v, got %v", tt.rolledBack, rolledBack)
+			}
+		})
+	}
+}
+
+func TestExecutePlanTimeout(t *testing.T) {
+	tests := []struct {
+		name        string
+		planTimeout string
+		options     ExecuteOptions
+		rolledBack  []string
+	}{
+		{name: "plan timeout", planTimeout: "20ms"},
+		{name: "option overrides plan", planTimeout: "1h", options: ExecuteOptions{Timeout: 20 * time.Millisecond}},
+		{name: "auto rollback applies", planTimeout: "20ms", options: ExecuteOptions{AutoRollback: true}, rolledBack: []string{"exec-a"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var rolledBack []string
+			executor := newTestExecutor(t,
+				&MockPlugin{
+					name: "deploy",
+					execute: func(ctx context.Context, config map[string]interface{}) (*plugin.Result, error) {
+						return &plugin.Result{Success: true, ExecutionID: "exec-a"}, nil
+					},
+					rollback: func(executionID string) error {
+						rolledBack = append(rolledBack, executionID)
+						return nil
+					},
+				},
+				&MockPlugin{
+					name: "stuck",
+					execute: func(jobCtx context.Context, config map[string]interface{}) (*plugin.Result, error) {
+						<-jobCtx.Done()
+						return nil, jobCtx.Err()
+					},
+				},
+			)
+			orchestrator := NewOrchestrator(executor.pluginManager)
+			plan := newTestPlan("deploy", "stuck")
+			plan.Timeout = tt.planTimeout
+
+			result, err := orchestrator.ExecutePlan(context.Background(), plan, tt.options)
+			if err == nil || !strings.HasPrefix(err.Error(), "plan timed out after 20ms") {
+				t.Fatalf("Expected timeout error, got %v", err)
+			}
+			if !result.TimedOut || result.Canceled || result.CanceledJobs != 1 {
+				t.Errorf("Expected a timed out execution with 1 canceled job, got %+v", result)
+			}
+			if strings.Join(rolledBack, ",") != strings.Join(tt.rolledBack, ",") {
+				t.Errorf("Expected rolled back %v, got %v", tt.rolledBack, rolledBack)
+			}
+		})
+	}
+}
+
+// recordingSink is a metrics sink remembering the executions it received
+type recordingSink struct {
+	plans   []string
+	results []*models.ExecutionResult
+	err     error
+}
+
+func (s *recordingSink) Record(ctx context.Context, plan string, result *models.ExecutionResult) error {
+	s.plans = append(s.plans, plan)
+	s.results = append(s.results, result)
+	return s.err
+}
+
+func TestExecutePlanMetrics(t *testing.T) {
+	tests := []struct {
+		name    string
+		sinkErr error
+	}{
+		{name: "recorded"},
+		{name: "sink failure is not fatal", sinkErr: fmt.Errorf("pushgateway unavailable")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			orchestrator := newTestOrchestrator(t)
+			sink := &recordingSink{err: tt.sinkErr}
+			orchestrator.SetMetricsSink(sink)
+
+			result, err := orchestrator.ExecutePlan(context.Background(), newTestPlan("ok"), ExecuteOptions{})
+			if err != nil {
+				t.Fatalf("ExecutePlan() error = %v", err)
+			}
+			if len(sink.results) != 1 || sink.plans[0] != "test" || sink.results[0] != result {
+				t.Errorf("Expected the finished execution to be recorded once, got %v", sink.plans)
+			}
+		})
+	}
+}
+
+// unansweredApprovals is an approval service that never receives a decision
+type unansweredApprovals struct {
+	request *models.ApprovalRequest
+}
+
+func (s *unansweredApprovals) RequestApproval(ctx context.Context, request *models.ApprovalRequest) (*models.ApprovalResponse, error) {
+	s.request = request
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func TestExecutePlanApprovalTimeout(t *testing.T) {
+	tests := []struct {
+		name      string
+		onTimeout string
+		wantErr   string
+		status    models.ApprovalStatus
+	}{
+		{name: "expires", wantErr: "approval for stage stage0-ok failed: approval request expired", status: models.ApprovalStatusExpired},
+		{name: "auto-rejects", onTimeout: models.ApprovalTimeoutReject, wantErr: "stage stage0-ok approval rejected by grp-cli: no decision within 20ms", status: models.ApprovalStatusRejected},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			orchestrator := newTestOrchestrator(t)
+			approvals := &unansweredApprovals{}
+			orchestrator.SetApprovalService(approvals)
+
+			plan := newTestPlan("ok")
+			plan.Stages[0].RequireApproval = true
+			plan.Stages[0].ApprovalTimeout = "20ms"
+			plan.Stages[0].OnApprovalTimeout = tt.onTimeout
+
+			result, err := orchestrator.ExecutePlan(context.Background(), plan, ExecuteOptions{})
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("Expected error %q, got %v", tt.wantErr, err)
+			}
+			if len(result.Stages[0].Jobs) != 0 {
+				t.Errorf("Expected no jobs to run without approval, got %+v", result.Stages[0].Jobs)
+			}
+			if approvals.request.ExpiresAt.IsZero() {
+				t.Error("Expected the approval request to have an expiry")
+			}
+			if approvals.request.Status != tt.status {
+				t.Errorf("Expected request status %s, got %s", tt.status, approvals.request.Status)
+			}
+			if result.Stages[0].Approval != tt.status {
+				t.Errorf("Expected stage approval %s, got %s", tt.status, result.Stages[0].Approval)
+			}
+		})
+	}
+}
+
+// previewingPlugin is a mock plugin implementing plugin.Previewer
+type previewingPlugin struct {
+	MockPlugin
+	preview func(config map[string]interface{}) (string, error)
+}
+
+func (p *previewingPlugin) Preview(ctx context.Context, config map[string]interface{}) (string, error) {
+	return p.preview(config)
+}
+
+func TestExecutePlanApprovalPreview(t *testing.T) {
+	executor := newTestExecutor(t,
+		&MockPlugin{name: "ok"},
+		&previewingPlugin{
+			MockPlugin: MockPlugin{name: "infra"},
+			preview: func(config map[string]interface{}) (string, error) {
+				return fmt.Sprintf("+ create %v (token %v)", config["resource"], config["token"]), nil
+			},
+		},
+		&previewingPlugin{
+			MockPlugin: MockPlugin{name: "broken"},
+			preview: func(config map[string]interface{}) (string, error) {
+				return "", errors.New("no state")
+			},
+		},
+	)
+	orchestrator := NewOrchestrator(executor.pluginManager)
+	approvals := &unansweredApprovals{}
+	orchestrator.SetApprovalService(approvals)
+
+	plan := newTestPlan("ok")
+	plan.Variables = map[string]interface{}{"env": "prod"}
+	plan.Stages[0].RequireApproval = true
+	plan.Stages[0].ApprovalTimeout = "20ms"
+	plan.Stages[0].Jobs = []models.Job{
+		{Name: "network", Type: "infra", Config: map[string]interface{}{"resource": "vpc-${variables.env}", "token": "s3cret"}},
+		{Name: "cluster", Type: "broken"},
+		{Name: "smoke", Type: "ok", Config: map[string]interface{}{"url": "${outputs.network.url}"}},
+	}
+	mask := func(s string) string { return strings.ReplaceAll(s, "s3cret", "***") }
+
+	if _, err := orchestrator.ExecutePlan(context.Background(), plan, ExecuteOptions{LogMask: mask}); err == nil {
+		t.Fatal("Expected the unanswered approval to fail the execution")
+	}
+
+	expected := []models.ApprovalJob{
+		{Name: "network", Type: "infra", Config: map[string]interface{}{"resource": "vpc-prod", "token": "***"}, Preview: "+ create vpc-prod (token ***)"},
+		{Name: "cluster", Type: "broken", PreviewError: "no state"},
+		// Outputs of jobs that have not run yet cannot be resolved
+		{Name: "smoke", Type: "ok", Config: map[string]interface{}{"url": "${outputs.network.url}"},
+			PreviewError: "failed to resolve job config: error resolving value for key url: reference path not found: outputs.network.url"},
+	}
+	jobs := approvals.request.Jobs
+	if len(jobs) != len(expected) {
+		t.Fatalf("Expected %d jobs in the approval request, got %+v", len(expected), jobs)
+	}
+	for i, job := range jobs {
+		if job.Name != expected[i].Name || job.Preview != expected[i].Preview || job.PreviewError != expected[i].PreviewError ||
+			fmt.Sprint(job.Config) != fmt.Sprint(expected[i].Config) {
+			t.Errorf("Expected job %d to be %+v, got %+v", i, expected[i], job)
+		}
+	}
+}
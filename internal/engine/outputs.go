@@ -0,0 +1,42 @@
+package engine
+
+import (
+	"sync"
+)
+
+// jobOutputs collects the data returned by completed jobs so later jobs can
+// reference it as ${outputs.<job>.<key>}. Jobs run in parallel, so access is
+// guarded by a mutex.
+type jobOutputs struct {
+	mu   sync.RWMutex
+	jobs map[string]interface{}
+}
+
+// newJobOutputs creates an empty output store
+func newJobOutputs() *jobOutputs {
+	return &jobOutputs{jobs: make(map[string]interface{})}
+}
+
+// Set records the data returned by a job
+func (o *jobOutputs) Set(jobName string, data map[string]interface{}) {
+	if o == nil || data == nil {
+		return
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.jobs[jobName] = data
+}
+
+// Snapshot returns a copy of the outputs recorded so far, keyed by job name
+func (o *jobOutputs) Snapshot() map[string]interface{} {
+	snapshot := make(map[string]interface{})
+	if o == nil {
+		return snapshot
+	}
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	for name, data := range o.jobs {
+		snapshot[name] = data
+	}
+	return snapshot
+}
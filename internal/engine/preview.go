@@ -0,0 +1,76 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/cuongtl1992/grp-cli/internal/models"
+	"github.com/cuongtl1992/grp-cli/pkg/plugin"
+)
+
+// previewStage describes each job of a stage for its approvers, hooks included
+func (o *Orchestrator) previewStage(ctx context.Context, stage *models.Stage, mask func(string) string) []models.ApprovalJob {
+	executor := o.newExecutor()
+	jobs := stage.AllJobs()
+	previews := make([]models.ApprovalJob, 0, len(jobs))
+	for _, job := range jobs {
+		previews = append(previews, executor.previewJob(plugin.WithJobName(ctx, job.Name), job, mask))
+	}
+	return previews
+}
+
+// previewJob resolves a job's config and asks its plugin for a preview of the
+// changes the job would make. Both are masked with mask. A config that cannot be
+// resolved yet, e.g. because it references the outputs of jobs of the same stage,
+// is shown unresolved.
+func (e *Executor) previewJob(ctx context.Context, job models.Job, mask func(string) string) models.ApprovalJob {
+	if mask == nil {
+		mask = func(s string) string { return s }
+	}
+	preview := models.ApprovalJob{Name: job.Name, Type: job.Type}
+
+	jobConfig, err := e.resolveConfig(ctx, job.Config)
+	if err != nil {
+		preview.Config = maskConfig(job.Config, mask)
+		preview.PreviewError = mask(fmt.Sprintf("failed to resolve job config: %v", err))
+		return preview
+	}
+	preview.Config = maskConfig(jobConfig, mask)
+
+	output, err := e.pluginManager.PreviewPlugin(ctx, job.Type, jobConfig)
+	if err != nil {
+		e.logger.Warn("Failed to preview job", "job", job.Name, "error", mask(err.Error()))
+		preview.PreviewError = mask(err.Error())
+	}
+	preview.Preview = mask(output)
+	return preview
+}
+
+// maskConfig returns a copy of a job config with mask applied to its strings
+func maskConfig(config map[string]interface{}, mask func(string) string) map[string]interface{} {
+	if config == nil {
+		return nil
+	}
+	masked := make(map[string]interface{}, len(config))
+	for key, value := range config {
+		masked[key] = maskConfigValue(value, mask)
+	}
+	return masked
+}
+
+// maskConfigValue applies mask to the strings of a config value
+func maskConfigValue(value interface{}, mask func(string) string) interface{} {
+	switch v := value.(type) {
+	case string:
+		return mask(v)
+	case map[string]interface{}:
+		return maskConfig(v, mask)
+	case []interface{}:
+		masked := make([]interface{}, len(v))
+		for i, item := range v {
+			masked[i] = maskConfigValue(item, mask)
+		}
+		return masked
+	}
+	return value
+}
@@ -0,0 +1,88 @@
+package engine
+
+import (
+	"github.com/cuongtl1992/grp-cli/internal/models"
+)
+
+// rollbackJob is a successfully executed job to roll back, with its config in the plan
+type rollbackJob struct {
+	stage  string
+	job    models.JobResult
+	config map[string]interface{}
+}
+
+// buildRollbackGraph creates the graph of the executed jobs to roll back, named
+// STAGE/JOB, with the plan's dependencies reversed: each job depends on the jobs
+// of its stage that listed it in dependsOn, and on the jobs of the stages that
+// depended on its stage, directly or not. It returns the graph and the executed
+// job of each of its names.
+func buildRollbackGraph(plan *models.Plan, executed []models.StageResult) (*JobGraph, map[string]rollbackJob) {
+	graph := NewJobGraph()
+	jobs := make(map[string]rollbackJob)
+	stageJobs := make(map[string][]string)
+	for _, stageResult := range executed {
+		for _, job := range stageResult.Jobs {
+			if !job.Success {
+				continue
+			}
+			name := stageResult.Name + "/" + job.Name
+			graph.AddJob(models.Job{Name: name, Type: job.Type})
+			jobs[name] = rollbackJob{stage: stageResult.Name, job: job}
+			stageJobs[stageResult.Name] = append(stageJobs[stageResult.Name], name)
+		}
+	}
+
+	// Within a stage, a job is rolled back after the jobs that depended on it, even
+	// optionally
+	for _, stage := range plan.Stages {
+		for _, job := range stage.Jobs {
+			name := stage.Name + "/" + job.Name
+			executed, ok := jobs[name]
+			if !ok {
+				continue
+			}
+			executed.config = job.Config
+			jobs[name] = executed
+			for _, dep := range append(job.DependsOn[:len(job.DependsOn):len(job.DependsOn)], job.OptionalDependsOn...) {
+				if depName := stage.Name + "/" + dep; jobs[depName].job.Success {
+					graph.AddDependency(depName, name)
+				}
+			}
+		}
+	}
+
+	// Across stages, the jobs of a stage are rolled back after those of the stages
+	// that depended on it. The stages in between may have no jobs to roll back, so
+	// indirect dependencies are followed too.
+	stageGraph := buildStageGraph(plan.Stages)
+	for _, stage := range plan.Stages {
+		for _, ancestor := range stageAncestors(stageGraph, stage.Name) {
+			for _, ancestorJob := range stageJobs[ancestor] {
+				for _, job := range stageJobs[stage.Name] {
+					graph.AddDependency(ancestorJob, job)
+				}
+			}
+		}
+	}
+
+	return graph, jobs
+}
+
+// stageAncestors returns the stages a stage depends on, directly or not
+func stageAncestors(graph *StageGraph, stageName string) []string {
+	var ancestors []string
+	seen := map[string]bool{stageName: true}
+
+	pending := append([]string(nil), graph.dependencies[stageName]...)
+	for len(pending) > 0 {
+		name := pending[0]
+		pending = pending[1:]
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+		ancestors = append(ancestors, name)
+		pending = append(pending, graph.dependencies[name]...)
+	}
+	return ancestors
+}
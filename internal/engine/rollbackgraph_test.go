@@ -0,0 +1,102 @@
+package engine
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/cuongtl1992/grp-cli/internal/models"
+)
+
+func TestBuildRollbackGraph(t *testing.T) {
+	succeeded := func(stage string, jobs ...string) models.StageResult {
+		result := models.StageResult{Name: stage}
+		for _, job := range jobs {
+			result.Jobs = append(result.Jobs, models.JobResult{Name: job, Success: true})
+		}
+		return result
+	}
+
+	tests := []struct {
+		name     string
+		stages   []models.Stage
+		executed []models.StageResult
+		// expected lists the batches of jobs rolled back together, in order
+		expected []string
+	}{
+		{
+			name: "job dependencies reversed",
+			stages: []models.Stage{{Name: "deploy", Jobs: []models.Job{
+				{Name: "db"},
+				{Name: "api", DependsOn: []string{"db"}},
+				{Name: "web", DependsOn: []string{"api"}},
+				{Name: "docs"},
+			}}},
+			executed: []models.StageResult{succeeded("deploy", "db", "docs", "api", "web")},
+			expected: []string{"deploy/docs deploy/web", "deploy/api", "deploy/db"},
+		},
+		{
+			name: "sequential stages reversed",
+			stages: []models.Stage{
+				{Name: "build", Jobs: []models.Job{{Name: "image"}}},
+				{Name: "deploy", Jobs: []models.Job{{Name: "api"}, {Name: "web"}}},
+			},
+			executed: []models.StageResult{succeeded("build", "image"), succeeded("deploy", "api", "web")},
+			expected: []string{"deploy/api deploy/web", "build/image"},
+		},
+		{
+			name: "independent stages in parallel",
+			stages: []models.Stage{
+				{Name: "infra", Jobs: []models.Job{{Name: "network"}}},
+				{Name: "api", DependsOn: []string{"infra"}, Jobs: []models.Job{{Name: "deploy"}}},
+				{Name: "web", DependsOn: []string{"infra"}, Jobs: []models.Job{{Name: "deploy"}}},
+			},
+			executed: []models.StageResult{succeeded("infra", "network"), succeeded("api", "deploy"), succeeded("web", "deploy")},
+			expected: []string{"api/deploy web/deploy", "infra/network"},
+		},
+		{
+			name: "through a stage without jobs to roll back",
+			stages: []models.Stage{
+				{Name: "infra", Jobs: []models.Job{{Name: "network"}}},
+				{Name: "check", DependsOn: []string{"infra"}, Jobs: []models.Job{{Name: "smoke"}}},
+				{Name: "api", DependsOn: []string{"check"}, Jobs: []models.Job{{Name: "deploy"}}},
+			},
+			executed: []models.StageResult{
+				succeeded("infra", "network"),
+				{Name: "check", Jobs: []models.JobResult{{Name: "smoke", Success: false}}},
+				succeeded("api", "deploy"),
+			},
+			expected: []string{"api/deploy", "infra/network"},
+		},
+		{
+			name: "failed jobs skipped",
+			stages: []models.Stage{{Name: "deploy", Jobs: []models.Job{
+				{Name: "db"},
+				{Name: "api", DependsOn: []string{"db"}},
+			}}},
+			executed: []models.StageResult{{Name: "deploy", Jobs: []models.JobResult{{Name: "db", Success: true}, {Name: "api"}}}},
+			expected: []string{"deploy/db"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			graph, jobs := buildRollbackGraph(&models.Plan{Stages: tt.stages}, tt.executed)
+
+			var batches []string
+			for ready := graph.GetReadyJobs(); len(ready) > 0; ready = graph.GetReadyJobs() {
+				var names []string
+				for _, job := range ready {
+					if _, ok := jobs[job.Name]; !ok {
+						t.Errorf("Expected executed job %s to be known", job.Name)
+					}
+					names = append(names, job.Name)
+					graph.MarkCompleted(job.Name)
+				}
+				batches = append(batches, strings.Join(names, " "))
+			}
+			if strings.Join(batches, ", ") != strings.Join(tt.expected, ", ") {
+				t.Errorf("Expected rollback batches %q, got %q", tt.expected, batches)
+			}
+		})
+	}
+}
@@ -0,0 +1,239 @@
+package engine
+
+import (
+	"fmt"
+
+	"github.com/cuongtl1992/grp-cli/internal/config"
+	"github.com/cuongtl1992/grp-cli/internal/models"
+)
+
+// SelectJobs returns a copy of the plan limited to the jobs tagged with any of tags,
+// or all jobs if tags is empty, minus the jobs tagged with any of excludeTags. A
+// job's tags include the tags of its stage.
+//
+// The dependencies of a selected job are selected too, even if they do not match
+// tags; it is an error for one of them to be excluded by excludeTags. Optional
+// dependencies are not selected: the job runs without them. Stages left
+// without jobs are dropped, and the stages depending on them inherit their
+// dependencies so the stage order is preserved; a stage losing all its
+// dependencies keeps an empty dependsOn, so the selected stages still run as a
+// graph. Rollback stages are not filtered.
+func SelectJobs(plan *models.Plan, tags, excludeTags []string) (*models.Plan, error) {
+	if len(tags) == 0 && len(excludeTags) == 0 {
+		return plan, nil
+	}
+
+	selected := *plan
+	selected.Stages = nil
+	dropped := make(map[string][]string)
+
+	for _, stage := range plan.Stages {
+		jobs, err := selectStageJobs(stage, tags, excludeTags)
+		if err != nil {
+			return nil, fmt.Errorf("stage %s: %w", stage.Name, err)
+		}
+		if len(jobs) == 0 {
+			dropped[stage.Name] = stage.DependsOn
+			continue
+		}
+
+		stage.Jobs = jobs
+		selected.Stages = append(selected.Stages, stage)
+	}
+
+	if len(selected.Stages) == 0 {
+		return nil, fmt.Errorf("no jobs match the selected tags")
+	}
+
+	// Point dependencies on dropped stages at what those stages depended on
+	for i := range selected.Stages {
+		selected.Stages[i].DependsOn = replaceDropped(selected.Stages[i].DependsOn, dropped)
+	}
+
+	return &selected, nil
+}
+
+// SelectStages returns a copy of the plan limited to the stages from fromStage to
+// toStage, in plan order; an empty name leaves that end of the range open.
+//
+// The stages before fromStage are assumed to have completed in an earlier run, so
+// dependencies on them are dropped, but a selected job may not use the outputs of
+// a job in a skipped stage since they are not available. It is an error for a
+// selected stage to depend on a stage after toStage. Rollback stages are not filtered.
+func SelectStages(plan *models.Plan, fromStage, toStage string) (*models.Plan, error) {
+	if fromStage == "" && toStage == "" {
+		return plan, nil
+	}
+
+	from, to := 0, len(plan.Stages)-1
+	if fromStage != "" {
+		if from = stageIndex(plan.Stages, fromStage); from < 0 {
+			return nil, fmt.Errorf("--from-stage: stage %s not found", fromStage)
+		}
+	}
+	if toStage != "" {
+		if to = stageIndex(plan.Stages, toStage); to < 0 {
+			return nil, fmt.Errorf("--to-stage: stage %s not found", toStage)
+		}
+	}
+	if from > to {
+		return nil, fmt.Errorf("--from-stage %s comes after --to-stage %s", fromStage, toStage)
+	}
+
+	// Record where each skipped stage is, and the stages of the selected and
+	// skipped jobs, to report broken dependencies. Job names are only unique
+	// within a stage, and outputs are looked up by job name, so a reference is
+	// only broken if no selected stage has a job of that name.
+	skippedStages := make(map[string]int)
+	selectedJobs := make(map[string]bool)
+	skippedJobs := make(map[string]string)
+	for i, stage := range plan.Stages {
+		selected := i >= from && i <= to
+		if !selected {
+			skippedStages[stage.Name] = i
+		}
+		for _, job := range stage.AllJobs() {
+			if selected {
+				selectedJobs[job.Name] = true
+			} else if _, ok := skippedJobs[job.Name]; !ok {
+				skippedJobs[job.Name] = stage.Name
+			}
+		}
+	}
+
+	selected := *plan
+	selected.Stages = nil
+	resolver := config.NewResolver()
+
+	for _, stage := range plan.Stages[from : to+1] {
+		var dependsOn []string
+		for _, dep := range stage.DependsOn {
+			index, skipped := skippedStages[dep]
+			if !skipped {
+				dependsOn = append(dependsOn, dep)
+				continue
+			}
+			if index > to {
+				return nil, fmt.Errorf("stage %s depends on %s, which is after --to-stage %s", stage.Name, dep, toStage)
+			}
+		}
+
+		for _, job := range stage.AllJobs() {
+			for _, ref := range resolver.OutputReferences(job.Config) {
+				if skippedStage, skipped := skippedJobs[ref]; skipped && !selectedJobs[ref] {
+					return nil, fmt.Errorf("stage %s: job %s uses the outputs of job %s in skipped stage %s", stage.Name, job.Name, ref, skippedStage)
+				}
+			}
+		}
+
+		stage.DependsOn = dependsOn
+		selected.Stages = append(selected.Stages, stage)
+	}
+
+	return &selected, nil
+}
+
+// stageIndex returns the position of the named stage in stages, or -1
+func stageIndex(stages []models.Stage, name string) int {
+	for i, stage := range stages {
+		if stage.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// selectStageJobs returns the jobs of a stage matching the selectors, with their
+// dependencies, in plan order
+func selectStageJobs(stage models.Stage, tags, excludeTags []string) ([]models.Job, error) {
+	byName := make(map[string]models.Job, len(stage.Jobs))
+	for _, job := range stage.Jobs {
+		byName[job.Name] = job
+	}
+	excluded := func(job models.Job) bool {
+		return hasAnyTag(stage.Tags, excludeTags) || hasAnyTag(job.Tags, excludeTags)
+	}
+
+	chosen := make(map[string]bool)
+	var include func(job models.Job) error
+	include = func(job models.Job) error {
+		if chosen[job.Name] {
+			return nil
+		}
+		chosen[job.Name] = true
+
+		for _, depName := range job.DependsOn {
+			dep, ok := byName[depName]
+			if !ok {
+				// Left for the job graph to report
+				continue
+			}
+			if excluded(dep) {
+				return fmt.Errorf("job %s depends on %s, which is excluded by --exclude-tags", job.Name, depName)
+			}
+			if err := include(dep); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	for _, job := range stage.Jobs {
+		matches := len(tags) == 0 || hasAnyTag(stage.Tags, tags) || hasAnyTag(job.Tags, tags)
+		if matches && !excluded(job) {
+			if err := include(job); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	var jobs []models.Job
+	for _, job := range stage.Jobs {
+		if chosen[job.Name] {
+			jobs = append(jobs, job)
+		}
+	}
+	return jobs, nil
+}
+
+// replaceDropped replaces the dropped stages in dependsOn with their own
+// dependencies, recursively. A list losing all its stages stays empty rather
+// than nil, so that the plan's stages still run as a graph and not in plan order.
+func replaceDropped(dependsOn []string, dropped map[string][]string) []string {
+	if dependsOn == nil {
+		return nil
+	}
+	result := []string{}
+	seen := make(map[string]bool)
+
+	var add func(names []string)
+	add = func(names []string) {
+		for _, name := range names {
+			if seen[name] {
+				continue
+			}
+			seen[name] = true
+
+			if deps, ok := dropped[name]; ok {
+				add(deps)
+				continue
+			}
+			result = append(result, name)
+		}
+	}
+	add(dependsOn)
+
+	return result
+}
+
+// hasAnyTag reports whether tags contains any of wanted
+func hasAnyTag(tags, wanted []string) bool {
+	for _, tag := range tags {
+		for _, w := range wanted {
+			if tag == w {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,320 @@
+package engine
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/cuongtl1992/grp-cli/internal/models"
+)
+
+func TestSelectJobs(t *testing.T) {
+	plan := &models.Plan{
+		Stages: []models.Stage{
+			{
+				Name: "database",
+				Tags: []string{"db"},
+				Jobs: []models.Job{
+					{Name: "backup"},
+					{Name: "migrate", DependsOn: []string{"backup"}, Tags: []string{"migrations"}},
+				},
+			},
+			{
+				Name:      "app",
+				DependsOn: []string{"database"},
+				Jobs: []models.Job{
+					{Name: "build", Tags: []string{"build"}},
+					{Name: "smoke-test", Tags: []string{"slow"}},
+					{Name: "deploy", DependsOn: []string{"build"}, Tags: []string{"deploy"}},
+				},
+			},
+			{
+				Name:      "verify",
+				DependsOn: []string{"app"},
+				Jobs:      []models.Job{{Name: "check", Tags: []string{"slow", "verify"}}},
+			},
+		},
+	}
+
+	tests := []struct {
+		name         string
+		tags         []string
+		excludeTags  []string
+		expected     map[string][]string
+		expectedDeps map[string][]string
+		wantErr      string
+	}{
+		{
+			name:     "no selectors",
+			expected: map[string][]string{"database": {"backup", "migrate"}, "app": {"build", "smoke-test", "deploy"}, "verify": {"check"}},
+		},
+		{
+			name:     "stage tags apply to jobs",
+			tags:     []string{"db"},
+			expected: map[string][]string{"database": {"backup", "migrate"}},
+		},
+		{
+			name:         "dependencies are pulled in",
+			tags:         []string{"deploy"},
+			expected:     map[string][]string{"app": {"build", "deploy"}},
+			expectedDeps: map[string][]string{"app": {}},
+		},
+		{
+			name:         "excluded jobs are skipped",
+			excludeTags:  []string{"slow"},
+			expected:     map[string][]string{"database": {"backup", "migrate"}, "app": {"build", "deploy"}},
+			expectedDeps: map[string][]string{"app": {"database"}},
+		},
+		{
+			name:         "dependencies are pulled in from untagged jobs",
+			tags:         []string{"slow", "migrations"},
+			expected:     map[string][]string{"database": {"backup", "migrate"}, "app": {"smoke-test"}, "verify": {"check"}},
+			expectedDeps: map[string][]string{"verify": {"app"}},
+		},
+		{
+			name:         "dependencies of dropped stages are inherited",
+			tags:         []string{"db", "verify"},
+			expected:     map[string][]string{"database": {"backup", "migrate"}, "verify": {"check"}},
+			expectedDeps: map[string][]string{"verify": {"database"}},
+		},
+		{
+			name:    "nothing selected",
+			tags:    []string{"missing"},
+			wantErr: "no jobs match the selected tags",
+		},
+		{
+			name:        "excluded dependency",
+			tags:        []string{"deploy"},
+			excludeTags: []string{"build"},
+			wantErr:     "stage app: job deploy depends on build, which is excluded by --exclude-tags",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			selected, err := SelectJobs(plan, tt.tags, tt.excludeTags)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Expected error %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			jobs := make(map[string][]string)
+			deps := make(map[string][]string)
+			for _, stage := range selected.Stages {
+				for _, job := range stage.Jobs {
+					jobs[stage.Name] = append(jobs[stage.Name], job.Name)
+				}
+				deps[stage.Name] = stage.DependsOn
+			}
+			if !reflect.DeepEqual(jobs, tt.expected) {
+				t.Errorf("Expected jobs %v, got %v", tt.expected, jobs)
+			}
+			for stage, expected := range tt.expectedDeps {
+				if !reflect.DeepEqual(deps[stage], expected) {
+					t.Errorf("Expected stage %s to depend on %v, got %v", stage, expected, deps[stage])
+				}
+			}
+		})
+	}
+
+	// The original plan is left untouched
+	if len(plan.Stages) != 3 || len(plan.Stages[1].Jobs) != 3 {
+		t.Errorf("SelectJobs modified the plan: %+v", plan.Stages)
+	}
+}
+
+func TestSelectJobsKeepsStageGraph(t *testing.T) {
+	plan := &models.Plan{
+		Stages: []models.Stage{
+			{Name: "build", Jobs: []models.Job{{Name: "compile", Tags: []string{"build"}}}},
+			{Name: "deploy-api", DependsOn: []string{"build"}, Jobs: []models.Job{{Name: "api", Tags: []string{"deploy"}}}},
+			{Name: "deploy-web", DependsOn: []string{"build"}, Jobs: []models.Job{{Name: "web", Tags: []string{"deploy"}}}},
+		},
+	}
+
+	selected, err := SelectJobs(plan, []string{"deploy"}, nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// The deploy stages lost their only dependency but still run in parallel
+	ready := stageNames(buildStageGraph(selected.Stages).GetReadyStages())
+	if !reflect.DeepEqual(ready, []string{"deploy-api", "deploy-web"}) {
+		t.Errorf("Expected both deploy stages to be ready together, got %v", ready)
+	}
+}
+
+func TestSelectJobsOptionalDependencies(t *testing.T) {
+	plan := &models.Plan{
+		Stages: []models.Stage{{
+			Name: "app",
+			Jobs: []models.Job{
+				{Name: "migrate", Tags: []string{"db"}},
+				{Name: "deploy", OptionalDependsOn: []string{"migrate"}, Tags: []string{"deploy"}},
+			},
+		}},
+	}
+
+	tests := []struct {
+		name        string
+		tags        []string
+		excludeTags []string
+	}{
+		{name: "not pulled in by tags", tags: []string{"deploy"}},
+		{name: "may be excluded", excludeTags: []string{"db"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			selected, err := SelectJobs(plan, tt.tags, tt.excludeTags)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if jobs := selected.Stages[0].Jobs; len(jobs) != 1 || jobs[0].Name != "deploy" {
+				t.Errorf("Expected only deploy to be selected, got %+v", jobs)
+			}
+		})
+	}
+}
+
+func TestSelectStages(t *testing.T) {
+	plan := &models.Plan{
+		Stages: []models.Stage{
+			{Name: "build", Jobs: []models.Job{{Name: "image"}}},
+			{Name: "database", DependsOn: []string{"build"}, Jobs: []models.Job{{Name: "migrate"}}},
+			{Name: "deploy", DependsOn: []string{"build", "database"}, Jobs: []models.Job{
+				{Name: "rollout", Config: map[string]interface{}{"version": "${variables.version}"}},
+			}},
+			{Name: "verify", DependsOn: []string{"deploy"}, Jobs: []models.Job{{Name: "smoke"}}},
+		},
+	}
+
+	tests := []struct {
+		name         string
+		from, to     string
+		expected     []string
+		expectedDeps map[string][]string
+		wantErr      string
+	}{
+		{name: "no range", expected: []string{"build", "database", "deploy", "verify"}},
+		{
+			name:         "from stage drops dependencies on skipped stages",
+			from:         "deploy",
+			expected:     []string{"deploy", "verify"},
+			expectedDeps: map[string][]string{"deploy": nil, "verify": {"deploy"}},
+		},
+		{name: "to stage", to: "database", expected: []string{"build", "database"}},
+		{
+			name:         "single stage",
+			from:         "database",
+			to:           "database",
+			expected:     []string{"database"},
+			expectedDeps: map[string][]string{"database": nil},
+		},
+		{name: "unknown from stage", from: "missing", wantErr: "--from-stage: stage missing not found"},
+		{name: "unknown to stage", to: "missing", wantErr: "--to-stage: stage missing not found"},
+		{name: "reversed range", from: "verify", to: "build", wantErr: "--from-stage verify comes after --to-stage build"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			selected, err := SelectStages(plan, tt.from, tt.to)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Expected error %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			var names []string
+			deps := make(map[string][]string)
+			for _, stage := range selected.Stages {
+				names = append(names, stage.Name)
+				deps[stage.Name] = stage.DependsOn
+			}
+			if !reflect.DeepEqual(names, tt.expected) {
+				t.Errorf("Expected stages %v, got %v", tt.expected, names)
+			}
+			for stage, expected := range tt.expectedDeps {
+				if !reflect.DeepEqual(deps[stage], expected) {
+					t.Errorf("Expected stage %s to depend on %v, got %v", stage, expected, deps[stage])
+				}
+			}
+		})
+	}
+
+	// The original plan is left untouched
+	if len(plan.Stages) != 4 || len(plan.Stages[2].DependsOn) != 2 {
+		t.Errorf("SelectStages modified the plan: %+v", plan.Stages)
+	}
+}
+
+func TestSelectStagesBrokenDependencies(t *testing.T) {
+	tests := []struct {
+		name    string
+		stages  []models.Stage
+		from    string
+		to      string
+		wantErr string
+	}{
+		{
+			name: "outputs of a skipped job",
+			stages: []models.Stage{
+				{Name: "build", Jobs: []models.Job{{Name: "image"}}},
+				{Name: "deploy", Jobs: []models.Job{{Name: "rollout", Config: map[string]interface{}{"image": "app:${outputs.image.tag}"}}}},
+			},
+			from:    "deploy",
+			wantErr: "stage deploy: job rollout uses the outputs of job image in skipped stage build",
+		},
+		{
+			name: "outputs of a skipped hook",
+			stages: []models.Stage{
+				{Name: "build", Jobs: []models.Job{{Name: "compile"}}, PostJobs: []models.Job{{Name: "image"}}},
+				{Name: "deploy", Jobs: []models.Job{{Name: "rollout", Config: map[string]interface{}{"image": "app:${outputs.image.tag}"}}}},
+			},
+			from:    "deploy",
+			wantErr: "stage deploy: job rollout uses the outputs of job image in skipped stage build",
+		},
+		{
+			name: "outputs of a selected job named like a skipped one",
+			stages: []models.Stage{
+				{Name: "build", Jobs: []models.Job{{Name: "image"}}},
+				{Name: "rebuild", Jobs: []models.Job{{Name: "image"}}},
+				{Name: "deploy", DependsOn: []string{"rebuild"}, Jobs: []models.Job{{Name: "rollout", Config: map[string]interface{}{"image": "app:${outputs.image.tag}"}}}},
+			},
+			from: "rebuild",
+		},
+		{
+			name: "dependency after the range",
+			stages: []models.Stage{
+				{Name: "deploy", DependsOn: []string{"approve"}, Jobs: []models.Job{{Name: "rollout"}}},
+				{Name: "approve", Jobs: []models.Job{{Name: "gate"}}},
+			},
+			to:      "deploy",
+			wantErr: "stage deploy depends on approve, which is after --to-stage deploy",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := SelectStages(&models.Plan{Stages: tt.stages}, tt.from, tt.to)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected error %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
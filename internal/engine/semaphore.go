@@ -0,0 +1,44 @@
+package engine
+
+import "context"
+
+// semaphore bounds the number of concurrently running goroutines.
+// A nil semaphore imposes no limit.
+type semaphore chan struct{}
+
+// newSemaphore creates a semaphore allowing limit concurrent holders,
+// or an unlimited one if limit is not positive
+func newSemaphore(limit int) semaphore {
+	if limit <= 0 {
+		return nil
+	}
+	return make(semaphore, limit)
+}
+
+// acquire blocks until a slot is available
+func (s semaphore) acquire() {
+	if s != nil {
+		s <- struct{}{}
+	}
+}
+
+// acquireContext blocks until a slot is available or ctx is done, in which case
+// it returns ctx's error without taking a slot
+func (s semaphore) acquireContext(ctx context.Context) error {
+	if err := ctx.Err(); err != nil || s == nil {
+		return err
+	}
+	select {
+	case s <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// release frees a previously acquired slot
+func (s semaphore) release() {
+	if s != nil {
+		<-s
+	}
+}
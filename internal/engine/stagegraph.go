@@ -0,0 +1,150 @@
+package engine
+
+import (
+	"github.com/cuongtl1992/grp-cli/internal/models"
+)
+
+// StageGraph represents a dependency graph of stages
+type StageGraph struct {
+	stages       map[string]models.Stage
+	order        []string
+	dependencies map[string][]string
+	completed    map[string]bool
+	failed       map[string]bool
+}
+
+// NewStageGraph creates a new stage graph
+func NewStageGraph() *StageGraph {
+	return &StageGraph{
+		stages:       make(map[string]models.Stage),
+		dependencies: make(map[string][]string),
+		completed:    make(map[string]bool),
+		failed:       make(map[string]bool),
+	}
+}
+
+// AddStage adds a stage to the graph
+func (g *StageGraph) AddStage(stage models.Stage) {
+	if _, exists := g.stages[stage.Name]; !exists {
+		g.order = append(g.order, stage.Name)
+	}
+	g.stages[stage.Name] = stage
+}
+
+// AddDependency adds a dependency between stages
+func (g *StageGraph) AddDependency(stageName, dependsOn string) {
+	g.dependencies[stageName] = append(g.dependencies[stageName], dependsOn)
+}
+
+// GetReadyStages returns stages whose dependencies are all completed,
+// in the order they were added to the graph. Failed stages are never ready
+// again, and neither are the stages depending on them.
+func (g *StageGraph) GetReadyStages() []models.Stage {
+	var readyStages []models.Stage
+
+	for _, name := range g.order {
+		if g.completed[name] || g.failed[name] {
+			continue
+		}
+
+		allDepsCompleted := true
+		for _, dep := range g.dependencies[name] {
+			if !g.completed[dep] {
+				allDepsCompleted = false
+				break
+			}
+		}
+
+		if allDepsCompleted {
+			readyStages = append(readyStages, g.stages[name])
+		}
+	}
+
+	return readyStages
+}
+
+// MarkCompleted marks a stage as completed
+func (g *StageGraph) MarkCompleted(stageName string) {
+	g.completed[stageName] = true
+}
+
+// MarkFailed marks a stage as failed
+func (g *StageGraph) MarkFailed(stageName string) {
+	g.failed[stageName] = true
+}
+
+// PendingStages returns the names of the stages that neither completed nor
+// failed, in the order they were added to the graph
+func (g *StageGraph) PendingStages() []string {
+	var pending []string
+	for _, name := range g.order {
+		if !g.completed[name] && !g.failed[name] {
+			pending = append(pending, name)
+		}
+	}
+	return pending
+}
+
+// HasCycles checks if the stage dependency graph has cycles
+func (g *StageGraph) HasCycles() bool {
+	visited := make(map[string]bool)
+	recStack := make(map[string]bool)
+
+	var visit func(node string) bool
+	visit = func(node string) bool {
+		visited[node] = true
+		recStack[node] = true
+
+		for _, dep := range g.dependencies[node] {
+			if !visited[dep] {
+				if visit(dep) {
+					return true
+				}
+			} else if recStack[dep] {
+				return true
+			}
+		}
+
+		recStack[node] = false
+		return false
+	}
+
+	for _, name := range g.order {
+		if !visited[name] && visit(name) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// buildStageGraph creates a graph of stages based on their dependencies.
+// When no stage declares dependsOn, not even an empty list, each stage depends
+// on the previous one so that plans without stage dependencies keep running
+// sequentially.
+func buildStageGraph(stages []models.Stage) *StageGraph {
+	graph := NewStageGraph()
+
+	hasDependencies := false
+	for _, stage := range stages {
+		graph.AddStage(stage)
+		if stage.DependsOn != nil {
+			hasDependencies = true
+		}
+	}
+
+	for i, stage := range stages {
+		if !hasDependencies {
+			if i > 0 {
+				graph.AddDependency(stage.Name, stages[i-1].Name)
+			}
+			continue
+		}
+
+		for _, depName := range stage.DependsOn {
+			graph.AddDependency(stage.Name, depName)
+		}
+	}
+
+	return graph
+}
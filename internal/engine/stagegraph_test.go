@@ -0,0 +1,97 @@
+package engine
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/cuongtl1992/grp-cli/internal/models"
+)
+
+// stageNames returns the names of the given stages
+func stageNames(stages []models.Stage) []string {
+	names := make([]string, len(stages))
+	for i, stage := range stages {
+		names[i] = stage.Name
+	}
+	return names
+}
+
+func TestBuildStageGraphSequentialByDefault(t *testing.T) {
+	graph := buildStageGraph([]models.Stage{{Name: "a"}, {Name: "b"}, {Name: "c"}})
+
+	for _, expected := range []string{"a", "b", "c"} {
+		ready := stageNames(graph.GetReadyStages())
+		if len(ready) != 1 || ready[0] != expected {
+			t.Fatalf("Expected only %s to be ready, got %v", expected, ready)
+		}
+		graph.MarkCompleted(expected)
+	}
+
+	if ready := graph.GetReadyStages(); len(ready) != 0 {
+		t.Errorf("Expected no remaining stages, got %v", stageNames(ready))
+	}
+}
+
+func TestBuildStageGraphWithDependencies(t *testing.T) {
+	graph := buildStageGraph([]models.Stage{
+		{Name: "build-api"},
+		{Name: "build-web"},
+		{Name: "deploy", DependsOn: []string{"build-api", "build-web"}},
+	})
+
+	ready := stageNames(graph.GetReadyStages())
+	if len(ready) != 2 || ready[0] != "build-api" || ready[1] != "build-web" {
+		t.Fatalf("Expected independent build stages to be ready together, got %v", ready)
+	}
+
+	graph.MarkCompleted("build-api")
+	graph.MarkCompleted("build-web")
+	ready = stageNames(graph.GetReadyStages())
+	if len(ready) != 1 || ready[0] != "deploy" {
+		t.Errorf("Expected deploy to be ready, got %v", ready)
+	}
+}
+
+func TestBuildStageGraphWithEmptyDependencies(t *testing.T) {
+	graph := buildStageGraph([]models.Stage{
+		{Name: "deploy-api", DependsOn: []string{}},
+		{Name: "deploy-web"},
+	})
+
+	ready := stageNames(graph.GetReadyStages())
+	if !reflect.DeepEqual(ready, []string{"deploy-api", "deploy-web"}) {
+		t.Errorf("Expected an empty dependsOn to run the stages as a graph, got %v", ready)
+	}
+}
+
+func TestStageGraphMarkFailed(t *testing.T) {
+	graph := buildStageGraph([]models.Stage{
+		{Name: "build"},
+		{Name: "migrate", DependsOn: []string{"build"}},
+		{Name: "deploy", DependsOn: []string{"migrate"}},
+		{Name: "cleanup", DependsOn: []string{"build"}},
+	})
+
+	graph.MarkCompleted("build")
+	graph.MarkFailed("migrate")
+	ready := stageNames(graph.GetReadyStages())
+	if len(ready) != 1 || ready[0] != "cleanup" {
+		t.Fatalf("Expected only cleanup to be ready, got %v", ready)
+	}
+
+	pending := graph.PendingStages()
+	if len(pending) != 2 || pending[0] != "deploy" || pending[1] != "cleanup" {
+		t.Errorf("Expected deploy and cleanup to be pending, got %v", pending)
+	}
+}
+
+func TestStageGraphHasCycles(t *testing.T) {
+	graph := buildStageGraph([]models.Stage{
+		{Name: "a", DependsOn: []string{"b"}},
+		{Name: "b", DependsOn: []string{"a"}},
+	})
+
+	if !graph.HasCycles() {
+		t.Error("Expected cycle to be detected")
+	}
+}
@@ -0,0 +1,88 @@
+package logging
+
+import (
+	"io"
+	"log/slog"
+	"os"
+)
+
+// Logger is a leveled, structured logger. Arguments after the message are
+// alternating key/value pairs, as with log/slog.
+type Logger interface {
+	Debug(msg string, args ...any)
+	Info(msg string, args ...any)
+	Warn(msg string, args ...any)
+	Error(msg string, args ...any)
+}
+
+// New creates a logger writing text records at or above level to w
+func New(w io.Writer, level slog.Level) Logger {
+	return slog.New(slog.NewTextHandler(w, &slog.HandlerOptions{Level: level}))
+}
+
+// Default creates a logger writing warnings and errors to stderr
+func Default() Logger {
+	return New(os.Stderr, slog.LevelWarn)
+}
+
+// Nop creates a logger that discards all records
+func Nop() Logger {
+	return New(io.Discard, slog.LevelError+1)
+}
+
+// redactingLogger masks secrets in messages and string or error values
+type redactingLogger struct {
+	logger Logger
+	redact func(string) string
+}
+
+// WithRedaction wraps a logger so redact is applied to every message, and to
+// every string and error value, before it is logged
+func WithRedaction(logger Logger, redact func(string) string) Logger {
+	return &redactingLogger{logger: logger, redact: redact}
+}
+
+func (l *redactingLogger) Debug(msg string, args ...any) {
+	l.logger.Debug(l.redact(msg), l.redactArgs(args)...)
+}
+
+func (l *redactingLogger) Info(msg string, args ...any) {
+	l.logger.Info(l.redact(msg), l.redactArgs(args)...)
+}
+
+func (l *redactingLogger) Warn(msg string, args ...any) {
+	l.logger.Warn(l.redact(msg), l.redactArgs(args)...)
+}
+
+func (l *redactingLogger) Error(msg string, args ...any) {
+	l.logger.Error(l.redact(msg), l.redactArgs(args)...)
+}
+
+// redactArgs returns a copy of the key/value arguments with strings and errors redacted
+func (l *redactingLogger) redactArgs(args []any) []any {
+	redacted := make([]any, len(args))
+	for i, arg := range args {
+		switch v := arg.(type) {
+		case string:
+			redacted[i] = l.redact(v)
+		case error:
+			redacted[i] = l.redact(v.Error())
+		default:
+			redacted[i] = arg
+		}
+	}
+	return redacted
+}
+
+// LevelFromFlags maps the --verbose and --debug flags to a log level.
+// Without either flag only warnings and errors are logged.
+func LevelFromFlags(verbose, debug bool) slog.Level {
+	switch {
+	case debug:
+		return slog.LevelDebug
+	case verbose:
+		return slog.LevelInfo
+	default:
+		return slog.LevelWarn
+	}
+}
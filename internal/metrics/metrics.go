@@ -0,0 +1,163 @@
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/cuongtl1992/grp-cli/internal/models"
+)
+
+// Metric describes a metric exported for each execution
+type Metric struct {
+	Name string
+	Help string
+	Type string
+}
+
+// Metrics recorded for an execution. Every sample is labeled with the plan name
+// and execution ID; job and stage metrics also carry their status.
+var (
+	PlanDuration  = Metric{Name: "grp_plan_duration_seconds", Help: "Duration of the plan execution in seconds.", Type: "gauge"}
+	PlanSuccess   = Metric{Name: "grp_plan_success", Help: "Whether the plan execution succeeded (1) or not (0).", Type: "gauge"}
+	Jobs          = Metric{Name: "grp_jobs", Help: "Number of jobs of the execution by status.", Type: "gauge"}
+	StageDuration = Metric{Name: "grp_stage_duration_seconds", Help: "Duration of a stage in seconds.", Type: "gauge"}
+	JobDuration   = Metric{Name: "grp_job_duration_seconds", Help: "Duration of a job in seconds, including retries.", Type: "gauge"}
+	JobRetries    = Metric{Name: "grp_job_retries", Help: "Number of times a job was retried.", Type: "gauge"}
+)
+
+// Definitions lists the metrics recorded for an execution, in export order
+var Definitions = []Metric{PlanDuration, PlanSuccess, Jobs, StageDuration, JobDuration, JobRetries}
+
+// Status label values
+const (
+	StatusSucceeded = "succeeded"
+	StatusFailed    = "failed"
+	StatusCanceled  = "canceled"
+	StatusSkipped   = "skipped"
+)
+
+// Sample is a value of a metric with its labels
+type Sample struct {
+	Metric Metric
+	Labels map[string]string
+	Value  float64
+}
+
+// Sink receives the metrics of a finished execution
+type Sink interface {
+	Record(ctx context.Context, plan string, result *models.ExecutionResult) error
+}
+
+// FromResult returns the samples of every metric for an execution result
+func FromResult(plan string, result *models.ExecutionResult) []Sample {
+	labels := func(extra ...string) map[string]string {
+		l := map[string]string{"plan": plan, "execution_id": result.ID}
+		for i := 0; i+1 < len(extra); i += 2 {
+			l[extra[i]] = extra[i+1]
+		}
+		return l
+	}
+
+	success := 0.0
+	if result.Success {
+		success = 1
+	}
+	samples := []Sample{
+		{Metric: PlanDuration, Labels: labels(), Value: result.Duration.Seconds()},
+		{Metric: PlanSuccess, Labels: labels(), Value: success},
+	}
+
+	counts := map[string]int{StatusSucceeded: 0, StatusFailed: 0, StatusCanceled: 0, StatusSkipped: 0}
+	for _, stage := range result.Stages {
+		stageStatus := StatusSucceeded
+		switch {
+		case stage.Canceled:
+			stageStatus = StatusCanceled
+		case !stage.Success:
+			stageStatus = StatusFailed
+		}
+		samples = append(samples, Sample{Metric: StageDuration, Labels: labels("stage", stage.Name, "status", stageStatus), Value: stage.Duration.Seconds()})
+
+		for _, job := range stage.Jobs {
+			status := jobStatus(job)
+			counts[status]++
+			samples = append(samples, Sample{
+				Metric: JobDuration,
+				Labels: labels("stage", stage.Name, "job", job.Name, "type", job.Type, "status", status),
+				Value:  job.Duration.Seconds(),
+			})
+			if job.Attempts > 0 {
+				samples = append(samples, Sample{
+					Metric: JobRetries,
+					Labels: labels("stage", stage.Name, "job", job.Name, "type", job.Type),
+					Value:  float64(job.Attempts - 1),
+				})
+			}
+		}
+	}
+
+	for _, status := range []string{StatusSucceeded, StatusFailed, StatusCanceled, StatusSkipped} {
+		samples = append(samples, Sample{Metric: Jobs, Labels: labels("status", status), Value: float64(counts[status])})
+	}
+	return samples
+}
+
+// jobStatus returns the status label of a job
+func jobStatus(job models.JobResult) string {
+	switch {
+	case job.Canceled:
+		return StatusCanceled
+	case job.Skipped:
+		return StatusSkipped
+	case job.Success:
+		return StatusSucceeded
+	default:
+		return StatusFailed
+	}
+}
+
+// WriteText writes samples in the Prometheus text exposition format, grouped by
+// metric in the order of Definitions
+func WriteText(w io.Writer, samples []Sample) error {
+	for _, metric := range Definitions {
+		var lines []string
+		for _, sample := range samples {
+			if sample.Metric.Name == metric.Name {
+				lines = append(lines, fmt.Sprintf("%s%s %g", metric.Name, formatLabels(sample.Labels), sample.Value))
+			}
+		}
+		if len(lines) == 0 {
+			continue
+		}
+
+		if _, err := fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n%s\n", metric.Name, metric.Help, metric.Name, metric.Type, strings.Join(lines, "\n")); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// labelEscaper escapes label values as required by the text exposition format
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// formatLabels renders labels as {name="value",...}, sorted by name
+func formatLabels(labels map[string]string) string {
+	if len(labels) == 0 {
+		return ""
+	}
+
+	names := make([]string, 0, len(labels))
+	for name := range labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	pairs := make([]string, len(names))
+	for i, name := range names {
+		pairs[i] = fmt.Sprintf(`%s="%s"`, name, labelEscaper.Replace(labels[name]))
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
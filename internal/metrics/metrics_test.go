@@ -0,0 +1,65 @@
+package metrics
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cuongtl1992/grp-cli/internal/models"
+)
+
+func newTestResult() *models.ExecutionResult {
+	return &models.ExecutionResult{
+		ID:       "exec-1",
+		Duration: 90 * time.Second,
+		Stages: []models.StageResult{
+			{
+				Name:     "deploy",
+				Duration: time.Minute,
+				Jobs: []models.JobResult{
+					{Name: "build", Type: "docker", Success: true, Attempts: 1, Duration: 20 * time.Second},
+					{Name: "rollout", Type: "kubernetes", Attempts: 3, Duration: 40 * time.Second},
+					{Name: "migrate", Type: "shell", Skipped: true, BlockedBy: []string{"rollout"}},
+				},
+			},
+			{
+				Name:     "verify",
+				Canceled: true,
+				Jobs:     []models.JobResult{{Name: "smoke", Type: "http", Canceled: true, Message: "say \"hi\""}},
+			},
+		},
+	}
+}
+
+func TestWriteText(t *testing.T) {
+	buf := new(bytes.Buffer)
+	if err := WriteText(buf, FromResult("web \"release\"", newTestResult())); err != nil {
+		t.Fatalf("WriteText() error = %v", err)
+	}
+	output := buf.String()
+
+	expected := []string{
+		"# HELP grp_plan_duration_seconds Duration of the plan execution in seconds.\n# TYPE grp_plan_duration_seconds gauge\n",
+		`grp_plan_duration_seconds{execution_id="exec-1",plan="web \"release\""} 90` + "\n",
+		`grp_plan_success{execution_id="exec-1",plan="web \"release\""} 0` + "\n",
+		`grp_jobs{execution_id="exec-1",plan="web \"release\"",status="succeeded"} 1` + "\n",
+		`grp_jobs{execution_id="exec-1",plan="web \"release\"",status="failed"} 1` + "\n",
+		`grp_jobs{execution_id="exec-1",plan="web \"release\"",status="canceled"} 1` + "\n",
+		`grp_jobs{execution_id="exec-1",plan="web \"release\"",status="skipped"} 1` + "\n",
+		`grp_stage_duration_seconds{execution_id="exec-1",plan="web \"release\"",stage="deploy",status="failed"} 60` + "\n",
+		`grp_stage_duration_seconds{execution_id="exec-1",plan="web \"release\"",stage="verify",status="canceled"} 0` + "\n",
+		`grp_job_duration_seconds{execution_id="exec-1",job="rollout",plan="web \"release\"",stage="deploy",status="failed",type="kubernetes"} 40` + "\n",
+		`grp_job_retries{execution_id="exec-1",job="rollout",plan="web \"release\"",stage="deploy",type="kubernetes"} 2` + "\n",
+	}
+	for _, line := range expected {
+		if !strings.Contains(output, line) {
+			t.Errorf("Expected output to contain %q, got:\n%s", line, output)
+		}
+	}
+
+	// Jobs that never ran have no retries sample
+	if strings.Contains(output, `grp_job_retries{execution_id="exec-1",job="smoke"`) {
+		t.Errorf("Expected no retries for a job without attempts, got:\n%s", output)
+	}
+}
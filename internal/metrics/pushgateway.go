@@ -0,0 +1,73 @@
+package metrics
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/cuongtl1992/grp-cli/internal/models"
+)
+
+// pushTimeout bounds a single push request
+const pushTimeout = 10 * time.Second
+
+// pushJob is the job label of the metrics group pushed for each execution
+const pushJob = "grp-cli"
+
+// Pushgateway is a Sink pushing the metrics of each execution to a Prometheus
+// Pushgateway, grouped by plan name and execution ID
+type Pushgateway struct {
+	url    string
+	client *http.Client
+}
+
+// NewPushgateway creates a sink pushing to the Pushgateway at the given base URL
+func NewPushgateway(url string) *Pushgateway {
+	return &Pushgateway{
+		url:    strings.TrimSuffix(url, "/"),
+		client: &http.Client{Timeout: pushTimeout},
+	}
+}
+
+// Record replaces the metrics group of the execution with its metrics
+func (p *Pushgateway) Record(ctx context.Context, plan string, result *models.ExecutionResult) error {
+	var body bytes.Buffer
+	if err := WriteText(&body, FromResult(plan, result)); err != nil {
+		return fmt.Errorf("failed to encode metrics: %w", err)
+	}
+
+	endpoint := p.url + "/metrics/job/" + url.PathEscape(pushJob) + groupingKey("plan", plan) + groupingKey("execution_id", result.ID)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, endpoint, &body)
+	if err != nil {
+		return fmt.Errorf("failed to create metrics request: %w", err)
+	}
+	req.Header.Set("Content-Type", "text/plain; version=0.0.4")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to push metrics: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("failed to push metrics: pushgateway returned %s", resp.Status)
+	}
+	return nil
+}
+
+// groupingKey renders a label of the Pushgateway grouping key as a URL path
+// segment, base64-encoding values that cannot appear in a path
+func groupingKey(name, value string) string {
+	if value == "" {
+		return "/" + name + "@base64/="
+	}
+	if strings.Contains(value, "/") {
+		return "/" + name + "@base64/" + base64.RawURLEncoding.EncodeToString([]byte(value))
+	}
+	return "/" + name + "/" + url.PathEscape(value)
+}
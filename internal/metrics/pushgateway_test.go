@@ -0,0 +1,54 @@
+package metrics
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestPushgatewayRecord(t *testing.T) {
+	tests := []struct {
+		name     string
+		plan     string
+		status   int
+		wantPath string
+		wantErr  string
+	}{
+		{name: "pushed", plan: "web", status: http.StatusOK, wantPath: "/metrics/job/grp-cli/plan/web/execution_id/exec-1"},
+		{name: "plan name with slash", plan: "team/web", status: http.StatusOK, wantPath: "/metrics/job/grp-cli/plan@base64/dGVhbS93ZWI/execution_id/exec-1"},
+		{name: "pushgateway error", plan: "web", status: http.StatusBadRequest, wantErr: "pushgateway returned 400 Bad Request"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var method, path, body string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				data, _ := io.ReadAll(r.Body)
+				method, path, body = r.Method, r.URL.EscapedPath(), string(data)
+				w.WriteHeader(tt.status)
+			}))
+			defer server.Close()
+
+			err := NewPushgateway(server.URL+"/").Record(context.Background(), tt.plan, newTestResult())
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Expected error %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Record() error = %v", err)
+			}
+
+			if method != http.MethodPut || path != tt.wantPath {
+				t.Errorf("Expected PUT %s, got %s %s", tt.wantPath, method, path)
+			}
+			if !strings.Contains(body, "# TYPE grp_job_duration_seconds gauge") {
+				t.Errorf("Expected metrics in the request body, got:\n%s", body)
+			}
+		})
+	}
+}
@@ -29,14 +29,27 @@ type ApprovalRequest struct {
 	ResponderName string
 	Comment       string
 	ExpiresAt     time.Time
+	// Jobs describes what the stage will do, for the approvers
+	Jobs []ApprovalJob
 }
 
-// ApprovalResponse represents a response to an approval request
+// ApprovalJob describes a job of a stage awaiting approval: its resolved config,
+// secrets masked, and its plugin's preview of the changes it would make, if any
+type ApprovalJob struct {
+	Name         string                 `json:"name"`
+	Type         string                 `json:"type"`
+	Config       map[string]interface{} `json:"config,omitempty"`
+	Preview      string                 `json:"preview,omitempty"`
+	PreviewError string                 `json:"previewError,omitempty"`
+}
+
+// ApprovalResponse represents a response to an approval request. The JSON form is
+// written by external approvers for the file approval service.
 type ApprovalResponse struct {
-	RequestID     string
-	Approved      bool
-	ResponderID   string
-	ResponderName string
-	Comment       string
-	RespondedAt   time.Time
-} 
\ No newline at end of file
+	RequestID     string    `json:"requestId"`
+	Approved      bool      `json:"approved"`
+	ResponderID   string    `json:"responderId"`
+	ResponderName string    `json:"responderName,omitempty"`
+	Comment       string    `json:"comment,omitempty"`
+	RespondedAt   time.Time `json:"respondedAt"`
+}
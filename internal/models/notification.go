@@ -0,0 +1,27 @@
+package models
+
+// Events that can trigger a notification
+const (
+	// NotifyOnStart is sent when a plan execution starts
+	NotifyOnStart = "start"
+	// NotifyOnSuccess is sent when a plan execution completes successfully
+	NotifyOnSuccess = "success"
+	// NotifyOnFailure is sent when a plan execution fails
+	NotifyOnFailure = "failure"
+	// NotifyOnApproval is sent when a stage is waiting for approval
+	NotifyOnApproval = "approval"
+)
+
+// Notifications configures where plan execution events are reported
+type Notifications struct {
+	Slack *SlackNotification `yaml:"slack,omitempty"`
+}
+
+// SlackNotification posts messages to a Slack incoming webhook. Events defaults
+// to success and failure; Template is a Go text/template rendered with a
+// notify.Message.
+type SlackNotification struct {
+	WebhookURL string   `yaml:"webhookUrl"`
+	Events     []string `yaml:"events,omitempty"`
+	Template   string   `yaml:"template,omitempty"`
+}
@@ -1,14 +1,57 @@
 package models
 
-// Plan represents a release plan
+// Plan represents a release plan. Timeout limits how long a whole execution may
+// run; when it expires the running jobs are canceled and the execution fails.
+// WorkingDir roots the relative paths of job configs, relative to the plan file's
+// directory, which is the default. RateLimits caps how often the plugins, keyed by
+// name, are executed. OnStageFailure chooses what happens to the remaining stages
+// once a stage fails, one of the StageFailure constants. OnSuccess and OnFailure
+// are jobs run once the whole execution succeeded or failed, after any rollback,
+// e.g. to tag a release or page on-call.
 type Plan struct {
-	APIVersion string                 `yaml:"apiVersion"`
-	Kind       string                 `yaml:"kind"`
-	Metadata   Metadata               `yaml:"metadata"`
-	Includes   []Include              `yaml:"includes,omitempty"`
-	Variables  map[string]interface{} `yaml:"variables,omitempty"`
-	Stages     []Stage                `yaml:"stages"`
-	Rollback   *Rollback              `yaml:"rollback,omitempty"`
+	APIVersion     string                 `yaml:"apiVersion"`
+	Kind           string                 `yaml:"kind"`
+	Metadata       Metadata               `yaml:"metadata"`
+	Includes       []Include              `yaml:"includes,omitempty"`
+	Variables      map[string]interface{} `yaml:"variables,omitempty"`
+	Secrets        []string               `yaml:"secrets,omitempty"`
+	MaxParallel    int                    `yaml:"maxParallel,omitempty"`
+	Timeout        string                 `yaml:"timeout,omitempty"`
+	WorkingDir     string                 `yaml:"workingDir,omitempty"`
+	Stages         []Stage                `yaml:"stages"`
+	Rollback       *Rollback              `yaml:"rollback,omitempty"`
+	OnSuccess      []Job                  `yaml:"onSuccess,omitempty"`
+	OnFailure      []Job                  `yaml:"onFailure,omitempty"`
+	Notifications  *Notifications         `yaml:"notifications,omitempty"`
+	RateLimits     map[string]RateLimit   `yaml:"rateLimits,omitempty"`
+	OnStageFailure string                 `yaml:"onStageFailure,omitempty"`
+}
+
+// Names of the plan's hooks, which also name their results
+const (
+	// PlanHookOnSuccess runs Plan.OnSuccess after a successful execution
+	PlanHookOnSuccess = "onSuccess"
+	// PlanHookOnFailure runs Plan.OnFailure after a failed execution
+	PlanHookOnFailure = "onFailure"
+)
+
+// Behaviors supported by Plan.OnStageFailure
+const (
+	// StageFailureAbort stops starting stages after the first stage failure, the default
+	StageFailureAbort = "abort"
+	// StageFailureContinue keeps running the stages that do not depend on a failed
+	// stage, reporting every failure at the end
+	StageFailureContinue = "continue"
+	// StageFailureRollbackAndAbort stops like StageFailureAbort and rolls back the
+	// completed work, even without auto rollback
+	StageFailureRollbackAndAbort = "rollback-and-abort"
+)
+
+// RateLimit caps the rate of executions of a plugin: RequestsPerSecond on average,
+// with bursts of up to Burst executions (default: 1)
+type RateLimit struct {
+	RequestsPerSecond float64 `yaml:"requestsPerSecond"`
+	Burst             int     `yaml:"burst,omitempty"`
 }
 
 // Metadata contains information about the plan
@@ -19,30 +62,100 @@ type Metadata struct {
 	Version     string `yaml:"version,omitempty"`
 }
 
-// Include represents a reference to an external file
+// Include represents a reference to an external file. The variables of the
+// included file are merged into the plan's variables, namespaced under As if set.
+// Conflicting variables are an error unless Override is set, in which case the
+// included values win.
 type Include struct {
-	Path string `yaml:"path"`
+	Path     string `yaml:"path"`
+	As       string `yaml:"as,omitempty"`
+	Override bool   `yaml:"override,omitempty"`
 }
 
-// Stage represents a stage in the release plan
+// Stage represents a stage in the release plan. Its Tags apply to all of its jobs.
+// MaxFailures and MaxFailurePercent set the stage's failure budget: once more jobs
+// fail than either allows, no further jobs of the stage are started. ApprovalTimeout
+// limits how long an approval request waits for a decision; OnApprovalTimeout
+// selects whether an unanswered request expires or is rejected. PreJobs run
+// before the stage's jobs, and PostJobs after them even if the stage failed, e.g.
+// to enable and disable a maintenance mode.
 type Stage struct {
-	Name            string   `yaml:"name"`
-	Description     string   `yaml:"description,omitempty"`
-	RequireApproval bool     `yaml:"requireApproval,omitempty"`
-	Approvers       []string `yaml:"approvers,omitempty"`
-	Jobs            []Job    `yaml:"jobs"`
+	Name              string   `yaml:"name"`
+	Description       string   `yaml:"description,omitempty"`
+	DependsOn         []string `yaml:"dependsOn,omitempty"`
+	RequireApproval   bool     `yaml:"requireApproval,omitempty"`
+	Approvers         []string `yaml:"approvers,omitempty"`
+	ApprovalTimeout   string   `yaml:"approvalTimeout,omitempty"`
+	OnApprovalTimeout string   `yaml:"onApprovalTimeout,omitempty"`
+	MaxParallel       int      `yaml:"maxParallel,omitempty"`
+	MaxFailures       int      `yaml:"maxFailures,omitempty"`
+	MaxFailurePercent int      `yaml:"maxFailurePercent,omitempty"`
+	Tags              []string `yaml:"tags,omitempty"`
+	PreJobs           []Job    `yaml:"preJobs,omitempty"`
+	Jobs              []Job    `yaml:"jobs"`
+	PostJobs          []Job    `yaml:"postJobs,omitempty"`
+	// Rollback lists jobs compensating the stage, run when it fails and the
+	// execution is rolled back
+	Rollback []Job `yaml:"rollback,omitempty"`
 }
 
-// Job represents a job to be executed
+// AllJobs returns the stage's pre hooks, jobs and post hooks, in the order they run
+func (s Stage) AllJobs() []Job {
+	return append(append(append([]Job(nil), s.PreJobs...), s.Jobs...), s.PostJobs...)
+}
+
+// Actions supported by Stage.OnApprovalTimeout
+const (
+	// ApprovalTimeoutExpire marks an unanswered approval request expired and fails the stage
+	ApprovalTimeoutExpire = "expire"
+	// ApprovalTimeoutReject rejects an unanswered approval request, failing the stage
+	ApprovalTimeoutReject = "reject"
+)
+
+// Job represents a job to be executed. Tags select the job for partial runs, and
+// Priority orders the jobs ready to start at the same time: higher first.
+// OptionalDependsOn only orders the job after the listed jobs that are part of
+// the run; unlike DependsOn, the listed jobs need not exist or be selected.
+// DependsOnConditions holds the outcome the job requires of some of its DependsOn
+// jobs, one of the DependencyOn constants keyed by job name; the others must succeed.
+// The loader fills it from the dependsOn entries written as {job, on}.
 type Job struct {
-	Name      string                 `yaml:"name"`
-	Type      string                 `yaml:"type"`
-	DependsOn []string               `yaml:"dependsOn,omitempty"`
-	Timeout   string                 `yaml:"timeout,omitempty"`
-	Retries   int                    `yaml:"retries,omitempty"`
-	Config    map[string]interface{} `yaml:"config"`
+	Name                string                 `yaml:"name"`
+	Type                string                 `yaml:"type"`
+	DependsOn           []string               `yaml:"dependsOn,omitempty"`
+	DependsOnConditions map[string]string      `yaml:"dependsOnConditions,omitempty"`
+	OptionalDependsOn   []string               `yaml:"optionalDependsOn,omitempty"`
+	Timeout             string                 `yaml:"timeout,omitempty"`
+	Retries             int                    `yaml:"retries,omitempty"`
+	RetryStrategy       string                 `yaml:"retryStrategy,omitempty"`
+	RetryDelay          string                 `yaml:"retryDelay,omitempty"`
+	RetryBackoff        float64                `yaml:"retryBackoff,omitempty"`
+	RetryJitter         float64                `yaml:"retryJitter,omitempty"`
+	RetryMaxElapsed     string                 `yaml:"retryMaxElapsed,omitempty"`
+	ContinueOnError     bool                   `yaml:"continueOnError,omitempty"`
+	Priority            int                    `yaml:"priority,omitempty"`
+	Tags                []string               `yaml:"tags,omitempty"`
+	Config              map[string]interface{} `yaml:"config"`
 }
 
+// Conditions supported by Job.DependsOnConditions
+const (
+	// DependencyOnSuccess runs the job once the dependency succeeded, the default
+	DependencyOnSuccess = "success"
+	// DependencyOnFailure runs the job only if the dependency failed, e.g. to clean up after it
+	DependencyOnFailure = "failure"
+	// DependencyOnAlways runs the job once the dependency finished, whatever its outcome
+	DependencyOnAlways = "always"
+)
+
+// Retry strategies supported by Job.RetryStrategy
+const (
+	// RetryStrategyExponential doubles the delay after each failed attempt
+	RetryStrategyExponential = "exponential"
+	// RetryStrategyFixed waits the same delay between every attempt
+	RetryStrategyFixed = "fixed"
+)
+
 // Rollback represents a rollback plan
 type Rollback struct {
 	Stages []Stage `yaml:"stages"`
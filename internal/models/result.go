@@ -2,47 +2,143 @@ package models
 
 import "time"
 
-// ExecutionResult contains the outcome of a plan execution
+// ExecutionResult contains the outcome of a plan execution. Artifacts lists the
+// artifacts returned by its jobs, in the order the jobs finished. OnStageFailure
+// is the behavior the execution applied once a stage failed, and SkippedStages
+// lists the stages that never ran because of a stage failure. Hooks holds the
+// results of the plan's onSuccess or onFailure jobs, as a stage named after them.
 type ExecutionResult struct {
-	ID            string
-	Success       bool
-	TotalStages   int
-	TotalJobs     int
-	CompletedJobs int
-	FailedJobs    int
-	StartTime     time.Time
-	EndTime       time.Time
-	Duration      time.Duration
-	Stages        []StageResult
-}
-
-// StageResult contains the outcome of a stage execution
+	ID             string          `json:"id" yaml:"id"`
+	Success        bool            `json:"success" yaml:"success"`
+	TotalStages    int             `json:"totalStages" yaml:"totalStages"`
+	TotalJobs      int             `json:"totalJobs" yaml:"totalJobs"`
+	CompletedJobs  int             `json:"completedJobs" yaml:"completedJobs"`
+	FailedJobs     int             `json:"failedJobs" yaml:"failedJobs"`
+	CanceledJobs   int             `json:"canceledJobs,omitempty" yaml:"canceledJobs,omitempty"`
+	SkippedJobs    int             `json:"skippedJobs,omitempty" yaml:"skippedJobs,omitempty"`
+	Canceled       bool            `json:"canceled,omitempty" yaml:"canceled,omitempty"`
+	TimedOut       bool            `json:"timedOut,omitempty" yaml:"timedOut,omitempty"`
+	OnStageFailure string          `json:"onStageFailure,omitempty" yaml:"onStageFailure,omitempty"`
+	SkippedStages  []string        `json:"skippedStages,omitempty" yaml:"skippedStages,omitempty"`
+	StartTime      time.Time       `json:"startTime" yaml:"startTime"`
+	EndTime        time.Time       `json:"endTime" yaml:"endTime"`
+	Duration       time.Duration   `json:"duration" yaml:"duration"`
+	Stages         []StageResult   `json:"stages" yaml:"stages"`
+	Artifacts      []Artifact      `json:"artifacts,omitempty" yaml:"artifacts,omitempty"`
+	Rollback       *RollbackResult `json:"rollback,omitempty" yaml:"rollback,omitempty"`
+	Hooks          *StageResult    `json:"hooks,omitempty" yaml:"hooks,omitempty"`
+}
+
+// RollbackResult contains the outcome of a rollback plan execution
+type RollbackResult struct {
+	Success   bool          `json:"success" yaml:"success"`
+	Jobs      []JobResult   `json:"jobs,omitempty" yaml:"jobs,omitempty"`
+	Stages    []StageResult `json:"stages" yaml:"stages"`
+	StartTime time.Time     `json:"startTime" yaml:"startTime"`
+	EndTime   time.Time     `json:"endTime" yaml:"endTime"`
+	Duration  time.Duration `json:"duration" yaml:"duration"`
+}
+
+// FailedJobs returns the number of plugin rollbacks that failed
+func (r *RollbackResult) FailedJobs() int {
+	failed := 0
+	for _, job := range r.Jobs {
+		if !job.Success {
+			failed++
+		}
+	}
+	return failed
+}
+
+// SkippedJobs returns the number of job rollbacks skipped because their plugin
+// could not or had nothing to undo
+func (r *RollbackResult) SkippedJobs() int {
+	skipped := 0
+	for _, job := range r.Jobs {
+		if job.Skipped {
+			skipped++
+		}
+	}
+	return skipped
+}
+
+// FailedStages returns the number of rollback stages that failed
+func (r *RollbackResult) FailedStages() int {
+	failed := 0
+	for _, stage := range r.Stages {
+		if !stage.Success {
+			failed++
+		}
+	}
+	return failed
+}
+
+// StageResult contains the outcome of a stage execution. FailedJobs includes
+// jobs whose continueOnError flag let the stage proceed; Batches lists the job
+// names of each wave of jobs started together, in order. AbortReason explains
+// why the stage stopped before running all of its jobs, e.g. an exceeded
+// failure budget. Approval is the outcome of the stage's approval request, if any.
+// PreJobs and PostJobs hold the results of the stage's hooks, apart from its jobs.
 type StageResult struct {
-	Name      string
-	Success   bool
-	Jobs      []JobResult
-	StartTime time.Time
-	EndTime   time.Time
-	Duration  time.Duration
+	Name        string         `json:"name" yaml:"name"`
+	Success     bool           `json:"success" yaml:"success"`
+	Canceled    bool           `json:"canceled,omitempty" yaml:"canceled,omitempty"`
+	Approval    ApprovalStatus `json:"approval,omitempty" yaml:"approval,omitempty"`
+	PreJobs     []JobResult    `json:"preJobs,omitempty" yaml:"preJobs,omitempty"`
+	Jobs        []JobResult    `json:"jobs" yaml:"jobs"`
+	PostJobs    []JobResult    `json:"postJobs,omitempty" yaml:"postJobs,omitempty"`
+	FailedJobs  int            `json:"failedJobs" yaml:"failedJobs"`
+	Batches     [][]string     `json:"batches,omitempty" yaml:"batches,omitempty"`
+	AbortReason string         `json:"abortReason,omitempty" yaml:"abortReason,omitempty"`
+	StartTime   time.Time      `json:"startTime" yaml:"startTime"`
+	EndTime     time.Time      `json:"endTime" yaml:"endTime"`
+	Duration    time.Duration  `json:"duration" yaml:"duration"`
 }
 
-// JobResult contains the outcome of a job execution
+// AllJobs returns the results of the stage's pre hooks, jobs and post hooks, in
+// the order they ran
+func (r StageResult) AllJobs() []JobResult {
+	return append(append(append([]JobResult(nil), r.PreJobs...), r.Jobs...), r.PostJobs...)
+}
+
+// JobResult contains the outcome of a job execution. ContinuedOnError is set when
+// the job failed but its continueOnError flag let the stage proceed; Canceled is set
+// instead of a failure when the job was interrupted by canceling the execution.
+// Skipped is set for a job that never ran because its stage stopped early; BlockedBy
+// names the failed jobs it depended on, directly or not, and Message gives the reason.
+// LogFile is the file the job's output was captured to, if any. ExecutionID is
+// the job's own execution ID, which its plugin is rolled back with.
 type JobResult struct {
-	Name      string
-	Type      string
-	Success   bool
-	Message   string
-	StartTime time.Time
-	EndTime   time.Time
-	Duration  time.Duration
-	Data      map[string]interface{}
+	Name             string                 `json:"name" yaml:"name"`
+	Type             string                 `json:"type" yaml:"type"`
+	Success          bool                   `json:"success" yaml:"success"`
+	ContinuedOnError bool                   `json:"continuedOnError,omitempty" yaml:"continuedOnError,omitempty"`
+	Canceled         bool                   `json:"canceled,omitempty" yaml:"canceled,omitempty"`
+	Skipped          bool                   `json:"skipped,omitempty" yaml:"skipped,omitempty"`
+	BlockedBy        []string               `json:"blockedBy,omitempty" yaml:"blockedBy,omitempty"`
+	Message          string                 `json:"message,omitempty" yaml:"message,omitempty"`
+	Attempts         int                    `json:"attempts" yaml:"attempts"`
+	ExecutionID      string                 `json:"executionId,omitempty" yaml:"executionId,omitempty"`
+	LogFile          string                 `json:"logFile,omitempty" yaml:"logFile,omitempty"`
+	StartTime        time.Time              `json:"startTime" yaml:"startTime"`
+	EndTime          time.Time              `json:"endTime" yaml:"endTime"`
+	Duration         time.Duration          `json:"duration" yaml:"duration"`
+	Data             map[string]interface{} `json:"data,omitempty" yaml:"data,omitempty"`
 }
 
-// Artifact represents a file or data produced by a plugin
+// Artifact is a file or data produced by a job. Path is the file holding it, if
+// any: the copy in the artifact directory when one is set, with Source the file
+// the plugin reported. Inline Data is only kept when there is no artifact
+// directory to write it to. Error explains why the artifact could not be stored.
 type Artifact struct {
-	Name        string
-	Type        string
-	ContentType string
-	Path        string
-	Data        []byte
-} 
\ No newline at end of file
+	Stage       string `json:"stage" yaml:"stage"`
+	Job         string `json:"job" yaml:"job"`
+	Name        string `json:"name" yaml:"name"`
+	Type        string `json:"type,omitempty" yaml:"type,omitempty"`
+	ContentType string `json:"contentType,omitempty" yaml:"contentType,omitempty"`
+	Path        string `json:"path,omitempty" yaml:"path,omitempty"`
+	Source      string `json:"source,omitempty" yaml:"source,omitempty"`
+	Size        int64  `json:"size,omitempty" yaml:"size,omitempty"`
+	Data        []byte `json:"data,omitempty" yaml:"data,omitempty"`
+	Error       string `json:"error,omitempty" yaml:"error,omitempty"`
+}
@@ -0,0 +1,91 @@
+package notify
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/cuongtl1992/grp-cli/internal/logging"
+	"github.com/cuongtl1992/grp-cli/internal/models"
+)
+
+// Message describes a plan execution event to notify about
+type Message struct {
+	// Event is one of the models.NotifyOn* events
+	Event       string
+	Plan        string
+	ExecutionID string
+	// Stage is set for approval events
+	Stage    string
+	Duration time.Duration
+	// FailedJobs lists failed jobs as stage/job for failure events
+	FailedJobs []string
+}
+
+// Notifier delivers messages to an external service
+type Notifier interface {
+	Notify(ctx context.Context, message Message) error
+}
+
+// FromConfig creates the notifiers configured for a plan; it returns nil if none are configured
+func FromConfig(config *models.Notifications) (Notifier, error) {
+	if config == nil || config.Slack == nil {
+		return nil, nil
+	}
+	return NewSlackNotifier(config.Slack)
+}
+
+// Dispatcher sends messages in the background so slow or failing deliveries
+// never block or fail an execution. Messages are delivered in the order sent.
+type Dispatcher struct {
+	notifier Notifier
+	logger   logging.Logger
+	wg       sync.WaitGroup
+	mu       sync.Mutex
+	// last is closed once the most recently sent message is delivered
+	last chan struct{}
+}
+
+// NewDispatcher creates a dispatcher for the notifier; delivery failures are logged
+func NewDispatcher(notifier Notifier, logger logging.Logger) *Dispatcher {
+	return &Dispatcher{notifier: notifier, logger: logger}
+}
+
+// Send delivers the message asynchronously. It is a no-op on a nil dispatcher or
+// when no notifier is configured.
+func (d *Dispatcher) Send(ctx context.Context, message Message) {
+	if d == nil || d.notifier == nil {
+		return
+	}
+
+	// Deliver even if the execution was canceled, e.g. to report the failure
+	ctx = context.WithoutCancel(ctx)
+
+	d.mu.Lock()
+	previous := d.last
+	done := make(chan struct{})
+	d.last = done
+	d.mu.Unlock()
+
+	d.wg.Add(1)
+	go func() {
+		defer d.wg.Done()
+		defer close(done)
+
+		// Wait for the previous message to keep notifications in order
+		if previous != nil {
+			<-previous
+		}
+		if err := d.notifier.Notify(ctx, message); err != nil {
+			d.logger.Warn("Failed to send notification", "event", message.Event, "error", err)
+		}
+	}()
+}
+
+// Wait blocks until all messages sent so far are delivered or have failed
+func (d *Dispatcher) Wait() {
+	if d == nil {
+		return
+	}
+	d.wg.Wait()
+}
@@ -0,0 +1,105 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/cuongtl1992/grp-cli/internal/models"
+)
+
+// defaultSlackTemplate is used when the plan does not define a message template
+const defaultSlackTemplate = `{{if eq .Event "start"}}:rocket: Plan *{{.Plan}}* started` +
+	`{{else if eq .Event "success"}}:white_check_mark: Plan *{{.Plan}}* succeeded in {{.Duration}}` +
+	`{{else if eq .Event "failure"}}:x: Plan *{{.Plan}}* failed after {{.Duration}}` +
+	`{{if .FailedJobs}}, failed jobs: {{join .FailedJobs ", "}}{{end}}` +
+	`{{else if eq .Event "approval"}}:raised_hand: Stage *{{.Stage}}* of plan *{{.Plan}}* is waiting for approval` +
+	`{{end}} (execution {{.ExecutionID}})`
+
+// slackTimeout bounds a single webhook request
+const slackTimeout = 10 * time.Second
+
+// SlackNotifier posts messages to a Slack incoming webhook
+type SlackNotifier struct {
+	webhookURL string
+	events     map[string]bool
+	template   *template.Template
+	client     *http.Client
+}
+
+// NewSlackNotifier creates a Slack notifier. Without configured events it
+// notifies on success and failure.
+func NewSlackNotifier(config *models.SlackNotification) (*SlackNotifier, error) {
+	tmpl, err := ParseTemplate(config.Template)
+	if err != nil {
+		return nil, err
+	}
+
+	events := config.Events
+	if len(events) == 0 {
+		events = []string{models.NotifyOnSuccess, models.NotifyOnFailure}
+	}
+
+	notifier := &SlackNotifier{
+		webhookURL: config.WebhookURL,
+		events:     make(map[string]bool),
+		template:   tmpl,
+		client:     &http.Client{Timeout: slackTimeout},
+	}
+	for _, event := range events {
+		notifier.events[event] = true
+	}
+	return notifier, nil
+}
+
+// ParseTemplate parses a message template, falling back to the default one if text is empty
+func ParseTemplate(text string) (*template.Template, error) {
+	if text == "" {
+		text = defaultSlackTemplate
+	}
+
+	tmpl, err := template.New("message").Funcs(template.FuncMap{"join": strings.Join}).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid notification template: %w", err)
+	}
+	return tmpl, nil
+}
+
+// Notify posts the message if the notifier is subscribed to its event
+func (s *SlackNotifier) Notify(ctx context.Context, message Message) error {
+	if !s.events[message.Event] {
+		return nil
+	}
+
+	var text bytes.Buffer
+	if err := s.template.Execute(&text, message); err != nil {
+		return fmt.Errorf("failed to render notification: %w", err)
+	}
+
+	payload, err := json.Marshal(map[string]string{"text": text.String()})
+	if err != nil {
+		return fmt.Errorf("failed to encode notification: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.webhookURL, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("failed to create slack request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to post to slack: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("slack webhook returned status %d", resp.StatusCode)
+	}
+	return nil
+}
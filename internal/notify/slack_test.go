@@ -0,0 +1,84 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/cuongtl1992/grp-cli/internal/logging"
+	"github.com/cuongtl1992/grp-cli/internal/models"
+)
+
+func TestSlackNotifier(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   models.SlackNotification
+		message  Message
+		expected string
+	}{
+		{
+			name:     "default template on failure",
+			message:  Message{Event: models.NotifyOnFailure, Plan: "release", ExecutionID: "42", Duration: 2 * time.Second, FailedJobs: []string{"deploy/api", "deploy/web"}},
+			expected: ":x: Plan *release* failed after 2s, failed jobs: deploy/api, deploy/web (execution 42)",
+		},
+		{
+			name:     "custom template",
+			config:   models.SlackNotification{Events: []string{models.NotifyOnApproval}, Template: "{{.Stage}} needs approval"},
+			message:  Message{Event: models.NotifyOnApproval, Stage: "prod"},
+			expected: "prod needs approval",
+		},
+		{
+			name:    "event not subscribed",
+			message: Message{Event: models.NotifyOnStart, Plan: "release"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var received string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				var payload map[string]string
+				if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+					t.Errorf("Failed to decode payload: %v", err)
+				}
+				received = payload["text"]
+			}))
+			defer server.Close()
+
+			tt.config.WebhookURL = server.URL
+			notifier, err := NewSlackNotifier(&tt.config)
+			if err != nil {
+				t.Fatalf("NewSlackNotifier() error = %v", err)
+			}
+			if err := notifier.Notify(context.Background(), tt.message); err != nil {
+				t.Fatalf("Notify() error = %v", err)
+			}
+			if received != tt.expected {
+				t.Errorf("Expected message %q, got %q", tt.expected, received)
+			}
+		})
+	}
+}
+
+func TestSlackNotifierError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer server.Close()
+
+	notifier, err := NewSlackNotifier(&models.SlackNotification{WebhookURL: server.URL})
+	if err != nil {
+		t.Fatalf("NewSlackNotifier() error = %v", err)
+	}
+	if err := notifier.Notify(context.Background(), Message{Event: models.NotifyOnSuccess}); err == nil {
+		t.Fatal("Expected an error for a rejected webhook call")
+	}
+
+	// Dispatching the same message must not fail or block
+	dispatcher := NewDispatcher(notifier, logging.Nop())
+	dispatcher.Send(context.Background(), Message{Event: models.NotifyOnSuccess})
+	dispatcher.Wait()
+}
@@ -0,0 +1,35 @@
+package plugins
+
+import (
+	"sync"
+
+	"github.com/cuongtl1992/grp-cli/pkg/plugin"
+)
+
+var (
+	builtinMutex sync.Mutex
+	// builtins holds the plugins compiled into the binary, by name
+	builtins = make(map[string]plugin.Plugin)
+)
+
+// RegisterBuiltin registers a plugin compiled into the binary. Every Manager
+// created afterwards starts with it, and plugins loaded from the plugin
+// directory replace it if they have the same name. It is meant to be called
+// from the init function of the plugin's package.
+func RegisterBuiltin(plg plugin.Plugin) {
+	builtinMutex.Lock()
+	defer builtinMutex.Unlock()
+	builtins[plg.Name()] = plg
+}
+
+// builtinPlugins returns a copy of the registered built-in plugins
+func builtinPlugins() map[string]plugin.Plugin {
+	builtinMutex.Lock()
+	defer builtinMutex.Unlock()
+
+	registry := make(map[string]plugin.Plugin, len(builtins))
+	for name, plg := range builtins {
+		registry[name] = plg
+	}
+	return registry
+}
@@ -0,0 +1,185 @@
+package plugins
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const (
+	// downloadTimeout bounds the download of a plugin from a URL
+	downloadTimeout = 5 * time.Minute
+	// stagingPattern names the file a plugin is written to before it is installed
+	stagingPattern = ".install-*.so"
+)
+
+// Info identifies the plugin provided by a plugin file
+type Info struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+	Path    string `json:"path"`
+}
+
+// Inspector reports the plugin provided by a plugin file, failing if the file
+// cannot be loaded as a plugin
+type Inspector func(path string) (Info, error)
+
+// InspectFile loads a plugin file in this process and reports its plugin. A Go
+// plugin cannot be loaded twice in a process, not even from another file, so
+// callers comparing two builds of the same plugin should inspect each of them in
+// a separate process.
+func InspectFile(path string) (Info, error) {
+	plg, err := openPlugin(path)
+	if err != nil {
+		return Info{}, err
+	}
+	return Info{Name: plg.Name(), Version: plg.Version(), Path: path}, nil
+}
+
+// InstallResult describes an installed plugin and the plugin it replaced, if any
+type InstallResult struct {
+	Installed Info
+	Replaced  *Info
+}
+
+// Installer installs plugin files into a plugin directory
+type Installer struct {
+	dir     string
+	inspect Inspector
+	client  *http.Client
+	force   bool
+}
+
+// NewInstaller creates an installer for dir that checks plugin files with inspect
+func NewInstaller(dir string, inspect Inspector) *Installer {
+	return &Installer{
+		dir:     dir,
+		inspect: inspect,
+		client:  &http.Client{Timeout: downloadTimeout},
+	}
+}
+
+// SetForce allows replacing an installed plugin of a different version
+func (i *Installer) SetForce(force bool) {
+	i.force = force
+}
+
+// Install copies a plugin file, or downloads it when source is an http(s) URL,
+// into the plugin directory, creating the directory if needed. The file must load
+// as a plugin. It is installed as NAME.so, or replaces the file already providing
+// a plugin of the same name; replacing a plugin of a different version requires
+// SetForce.
+func (i *Installer) Install(ctx context.Context, source string) (*InstallResult, error) {
+	if err := os.MkdirAll(i.dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create plugin directory: %w", err)
+	}
+
+	// Stage the file in the plugin directory so that installing it is a rename
+	staged, err := os.CreateTemp(i.dir, stagingPattern)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create plugin file: %w", err)
+	}
+	defer os.Remove(staged.Name())
+
+	err = i.fetch(ctx, source, staged)
+	if closeErr := staged.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(staged.Name(), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create plugin file: %w", err)
+	}
+
+	info, err := i.inspect(staged.Name())
+	if err != nil {
+		return nil, fmt.Errorf("%s is not a valid plugin: %w", source, err)
+	}
+
+	result := &InstallResult{Installed: info}
+	target := filepath.Join(i.dir, info.Name+".so")
+	previous, err := i.findInstalled(info.Name)
+	if err != nil {
+		return nil, err
+	}
+	if previous != nil {
+		if previous.Version != info.Version && !i.force {
+			return nil, fmt.Errorf("plugin %s %s is already installed in %s; use --force to replace it with version %s",
+				previous.Name, previous.Version, previous.Path, info.Version)
+		}
+		result.Replaced = previous
+		target = previous.Path
+	}
+
+	if err := os.Rename(staged.Name(), target); err != nil {
+		return nil, fmt.Errorf("failed to install plugin: %w", err)
+	}
+	result.Installed.Path = target
+	return result, nil
+}
+
+// fetch writes the plugin file at source, a path or an http(s) URL, to w
+func (i *Installer) fetch(ctx context.Context, source string, w io.Writer) error {
+	if !isURL(source) {
+		file, err := os.Open(source)
+		if err != nil {
+			return fmt.Errorf("failed to read plugin: %w", err)
+		}
+		defer file.Close()
+
+		if _, err := io.Copy(w, file); err != nil {
+			return fmt.Errorf("failed to read plugin: %w", err)
+		}
+		return nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, source, nil)
+	if err != nil {
+		return fmt.Errorf("failed to download plugin: %w", err)
+	}
+	resp, err := i.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to download plugin: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to download plugin: %s returned status %d", source, resp.StatusCode)
+	}
+	if _, err := io.Copy(w, resp.Body); err != nil {
+		return fmt.Errorf("failed to download plugin: %w", err)
+	}
+	return nil
+}
+
+// findInstalled returns the plugin file of the plugin directory providing a plugin
+// named name. Files that cannot be loaded are skipped, as the plugin manager does.
+func (i *Installer) findInstalled(name string) (*Info, error) {
+	files, err := findPlugins(i.dir, false)
+	if err != nil {
+		return nil, err
+	}
+	for _, file := range files {
+		info, err := i.inspect(file)
+		if err != nil {
+			continue
+		}
+		if info.Name == name {
+			info.Path = file
+			return &info, nil
+		}
+	}
+	return nil, nil
+}
+
+// isURL reports whether a plugin source is an http(s) URL rather than a path
+func isURL(source string) bool {
+	parsed, err := url.Parse(source)
+	return err == nil && (parsed.Scheme == "http" || parsed.Scheme == "https") && parsed.Host != ""
+}
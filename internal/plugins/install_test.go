@@ -0,0 +1,138 @@
+package plugins
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// inspectText is an Inspector for test plugin files holding "NAME VERSION"
+func inspectText(path string) (Info, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Info{}, err
+	}
+	name, version, found := strings.Cut(string(data), " ")
+	if !found {
+		return Info{}, fmt.Errorf("not a plugin")
+	}
+	return Info{Name: name, Version: version, Path: path}, nil
+}
+
+func TestInstall(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/deploy.so" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte("deploy 2.0.0"))
+	}))
+	defer server.Close()
+
+	source := t.TempDir()
+	for file, content := range map[string]string{
+		"deploy-1.so":   "deploy 1.0.0",
+		"deploy-2.so":   "deploy 2.0.0",
+		"notify.so":     "notify 1.0.0",
+		"not-plugin.so": "garbage",
+	} {
+		if err := os.WriteFile(filepath.Join(source, file), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name string
+		// installed maps the files already in the plugin directory to their content
+		installed    map[string]string
+		source       string
+		force        bool
+		wantPath     string
+		wantReplaced string
+		wantErr      string
+	}{
+		{name: "new plugin", source: "deploy-1.so", wantPath: "deploy.so"},
+		{name: "download", source: server.URL + "/deploy.so", wantPath: "deploy.so"},
+		{name: "other plugin installed", installed: map[string]string{"notify.so": "notify 1.0.0"}, source: "deploy-1.so", wantPath: "deploy.so"},
+		{name: "same version", installed: map[string]string{"deploy.so": "deploy 2.0.0"}, source: "deploy-2.so", wantPath: "deploy.so", wantReplaced: "2.0.0"},
+		{
+			name:      "other version",
+			installed: map[string]string{"deploy.so": "deploy 1.0.0"},
+			source:    "deploy-2.so",
+			wantErr:   "plugin deploy 1.0.0 is already installed",
+		},
+		{
+			name:         "other version forced",
+			installed:    map[string]string{"custom-deploy.so": "deploy 1.0.0"},
+			source:       "deploy-2.so",
+			force:        true,
+			wantPath:     "custom-deploy.so",
+			wantReplaced: "1.0.0",
+		},
+		{name: "not a plugin", source: "not-plugin.so", wantErr: "is not a valid plugin"},
+		{name: "missing file", source: "missing.so", wantErr: "failed to read plugin"},
+		{name: "download not found", source: server.URL + "/missing.so", wantErr: "returned status 404"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// The plugin directory is created by the installer
+			dir := filepath.Join(t.TempDir(), "plugins")
+			for file, content := range tt.installed {
+				if err := os.MkdirAll(dir, 0o755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(filepath.Join(dir, file), []byte(content), 0o755); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			src := tt.source
+			if !strings.HasPrefix(src, "http") {
+				src = filepath.Join(source, src)
+			}
+			installer := NewInstaller(dir, inspectText)
+			installer.SetForce(tt.force)
+			result, err := installer.Install(context.Background(), src)
+
+			files, _ := filepath.Glob(filepath.Join(dir, ".install-*"))
+			if len(files) > 0 {
+				t.Errorf("Expected the staged file to be removed, got %v", files)
+			}
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Install() error = %v, want %q", err, tt.wantErr)
+				}
+				for file, content := range tt.installed {
+					if data, _ := os.ReadFile(filepath.Join(dir, file)); string(data) != content {
+						t.Errorf("Expected %s to be kept, got %q", file, data)
+					}
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Install() error = %v", err)
+			}
+
+			if result.Installed.Path != filepath.Join(dir, tt.wantPath) {
+				t.Errorf("Expected the plugin to be installed to %s, got %s", tt.wantPath, result.Installed.Path)
+			}
+			installed, err := inspectText(result.Installed.Path)
+			if err != nil || installed.Name != result.Installed.Name || installed.Version != result.Installed.Version {
+				t.Errorf("Expected %s to hold %+v, got %+v (%v)", tt.wantPath, result.Installed, installed, err)
+			}
+			replaced := ""
+			if result.Replaced != nil {
+				replaced = result.Replaced.Version
+			}
+			if replaced != tt.wantReplaced {
+				t.Errorf("Expected to replace version %q, got %q", tt.wantReplaced, replaced)
+			}
+		})
+	}
+}
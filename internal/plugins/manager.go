@@ -2,84 +2,222 @@ package plugins
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
+	"io/fs"
 	"os"
 	"path/filepath"
+	"runtime/debug"
+	"sort"
 	"sync"
+	"time"
 
 	goplugin "plugin"
 
+	"github.com/cuongtl1992/grp-cli/internal/logging"
 	"github.com/cuongtl1992/grp-cli/pkg/plugin"
 )
 
 // Manager handles plugin discovery, loading and execution
 type Manager struct {
 	registry       map[string]plugin.Plugin
-	pluginDir      string
+	pluginDirs     []string
+	recursive      bool
 	mutex          sync.RWMutex
+	logger         logging.Logger
+	// executionTimeout caps each plugin execution; 0 means no limit
+	executionTimeout time.Duration
+	// rateLimiters caps the rate of executions of the plugins, by name
+	rateLimiters map[string]*rateLimiter
 }
 
-// NewManager creates a new plugin manager
-func NewManager(pluginDir string) *Manager {
+// NewManager creates a new plugin manager holding the built-in plugins, which
+// loads external plugins from the given directories
+func NewManager(pluginDirs ...string) *Manager {
 	return &Manager{
-		registry:       make(map[string]plugin.Plugin),
-		pluginDir:      pluginDir,
+		registry:       builtinPlugins(),
+		pluginDirs:     pluginDirs,
+		logger:         logging.Default(),
 	}
 }
 
-// LoadPlugins discovers and loads all plugins from the plugin directory
+// SetLogger replaces the logger used to report plugin loading and execution
+func (pm *Manager) SetLogger(logger logging.Logger) {
+	pm.logger = logger
+}
+
+// SetRecursive makes LoadPlugins search the subdirectories of the plugin directories too
+func (pm *Manager) SetRecursive(recursive bool) {
+	pm.recursive = recursive
+}
+
+// SetExecutionTimeout caps how long a single plugin execution may take. A plugin
+// still running after the timeout is abandoned and its execution fails, even if
+// it ignores the cancellation of its context. 0 removes the limit.
+func (pm *Manager) SetExecutionTimeout(timeout time.Duration) {
+	pm.executionTimeout = timeout
+}
+
+// SetRateLimit caps the executions of the named plugin to requestsPerSecond on
+// average, with bursts of up to burst executions. Executions over the limit wait
+// for their turn. A non-positive requestsPerSecond removes the limit.
+func (pm *Manager) SetRateLimit(name string, requestsPerSecond float64, burst int) {
+	pm.mutex.Lock()
+	defer pm.mutex.Unlock()
+	
+	if requestsPerSecond <= 0 {
+		delete(pm.rateLimiters, name)
+		return
+	}
+	if pm.rateLimiters == nil {
+		pm.rateLimiters = make(map[string]*rateLimiter)
+	}
+	pm.rateLimiters[name] = newRateLimiter(requestsPerSecond, burst)
+}
+
+// LoadPlugins discovers and loads all plugins from the plugin directories, in
+// order. A plugin that cannot be loaded is skipped with a warning; two plugins of
+// the same name in different files are a conflict, reported in the returned error.
 func (pm *Manager) LoadPlugins() error {
 	pm.mutex.Lock()
 	defer pm.mutex.Unlock()
 	
+	var errs []error
+	loadedFrom := make(map[string]string)
+	for _, dir := range pm.pluginDirs {
+		files, err := findPlugins(dir, pm.recursive)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		
+		// Load each plugin
+		for _, file := range files {
+			plg, err := openPlugin(file)
+			if err != nil {
+				pm.logger.Warn("Failed to load plugin", "path", file, "error", err)
+				continue
+			}
+			if err := pm.registerLoaded(plg, file, loadedFrom); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	
+	return errors.Join(errs...)
+}
+
+// findPlugins returns the .so files in a plugin directory, and in its
+// subdirectories if recursive is set, in path order. Files being installed by
+// an Installer are skipped.
+func findPlugins(dir string, recursive bool) ([]string, error) {
 	// Ensure plugin directory exists
-	if _, err := os.Stat(pm.pluginDir); os.IsNotExist(err) {
-		return fmt.Errorf("plugin directory does not exist: %s", pm.pluginDir)
+	if _, err := os.Stat(dir); os.IsNotExist(err) {
+		return nil, fmt.Errorf("plugin directory does not exist: %s", dir)
 	}
 	
-	// Find all .so files in the plugin directory
-	files, err := filepath.Glob(filepath.Join(pm.pluginDir, "*.so"))
-	if err != nil {
-		return fmt.Errorf("failed to search plugin directory: %w", err)
+	if !recursive {
+		files, err := filepath.Glob(filepath.Join(dir, "*.so"))
+		if err != nil {
+			return nil, fmt.Errorf("failed to search plugin directory: %w", err)
+		}
+		return skipStaging(files), nil
 	}
 	
-	// Load each plugin
+	var files []string
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !entry.IsDir() && filepath.Ext(path) == ".so" {
+			files = append(files, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to search plugin directory: %w", err)
+	}
+	sort.Strings(files)
+	return skipStaging(files), nil
+}
+
+// skipStaging removes the files of plugins still being installed
+func skipStaging(files []string) []string {
+	kept := files[:0]
 	for _, file := range files {
-		if err := pm.loadPlugin(file); err != nil {
-			fmt.Printf("Warning: Failed to load plugin %s: %v\n", file, err)
-			continue
+		if staging, _ := filepath.Match(stagingPattern, filepath.Base(file)); !staging {
+			kept = append(kept, file)
 		}
 	}
-	
-	return nil
+	return kept
 }
 
-// loadPlugin loads a single plugin from a .so file
-func (pm *Manager) loadPlugin(path string) error {
+// openPlugin loads a single plugin from a .so file
+func openPlugin(path string) (plugin.Plugin, error) {
 	// Open the plugin
 	plug, err := goplugin.Open(path)
 	if err != nil {
-		return fmt.Errorf("failed to open plugin: %w", err)
+		return nil, fmt.Errorf("failed to open plugin: %w", err)
+	}
+	
+	// Refuse plugins built against an incompatible plugin API before calling into them
+	if err := checkPluginAPIVersion(plug.Lookup); err != nil {
+		return nil, err
 	}
 	
 	// Look up the exported Plugin symbol
 	symPlugin, err := plug.Lookup("Plugin")
 	if err != nil {
-		return fmt.Errorf("plugin does not export 'Plugin' symbol: %w", err)
+		return nil, fmt.Errorf("plugin does not export 'Plugin' symbol: %w", err)
 	}
 	
 	// Assert that the symbol is a Plugin
 	plg, ok := symPlugin.(plugin.Plugin)
 	if !ok {
-		return fmt.Errorf("plugin does not implement the Plugin interface")
+		return nil, fmt.Errorf("plugin does not implement the Plugin interface")
 	}
+	return plg, nil
+}
+
+// registerLoaded registers a plugin loaded from path, replacing a built-in plugin of
+// the same name. loadedFrom records the file each plugin was loaded from so far; a
+// plugin whose name was already loaded from another file is a conflict and is not registered.
+func (pm *Manager) registerLoaded(plg plugin.Plugin, path string, loadedFrom map[string]string) error {
+	name := plg.Name()
+	if previous, exists := loadedFrom[name]; exists {
+		return fmt.Errorf("plugin %s is defined in both %s and %s", name, previous, path)
+	}
+	loadedFrom[name] = path
 	
-	// Register the plugin
-	pm.registry[plg.Name()] = plg
+	if _, exists := pm.registry[name]; exists {
+		pm.logger.Info("Plugin replaces an already registered plugin", "name", name, "path", path)
+	}
+	pm.registry[name] = plg
+	pm.logger.Debug("Loaded plugin", "name", name, "version", plg.Version(), "path", path)
 	
 	return nil
 }
 
+// checkPluginAPIVersion checks the APIVersion exported by a plugin, looked up with
+// lookup, against the plugin API implemented by this build
+func checkPluginAPIVersion(lookup func(string) (goplugin.Symbol, error)) error {
+	symVersion, err := lookup("APIVersion")
+	if err != nil {
+		return fmt.Errorf("plugin does not export 'APIVersion'; rebuild it against plugin API %s", plugin.APIVersion)
+	}
+	
+	version, ok := symVersion.(*string)
+	if !ok {
+		return fmt.Errorf("plugin 'APIVersion' must be a string variable")
+	}
+	
+	if err := plugin.CheckAPIVersion(*version); err != nil {
+		return fmt.Errorf("%w; rebuild the plugin against this grp-cli version", err)
+	}
+	return nil
+}
+
 // RegisterPlugin adds a plugin to the registry
 func (pm *Manager) RegisterPlugin(plg plugin.Plugin) error {
 	pm.mutex.Lock()
@@ -107,7 +245,11 @@ func (pm *Manager) GetPlugin(name string) (plugin.Plugin, error) {
 	return plg, nil
 }
 
-// ExecutePlugin runs a specific plugin with provided configuration
+// ExecutePlugin runs a specific plugin with provided configuration, in which
+// properties missing from the config take their schema default. A panic in the
+// plugin is turned into a failed result rather than crashing the CLI, and the
+// execution is abandoned once the execution timeout, if any, has elapsed. With a
+// rate limit set for the plugin, the execution first waits for its turn.
 func (pm *Manager) ExecutePlugin(ctx context.Context, jobType string, config map[string]interface{}) (*plugin.Result, error) {
 	// Get the plugin
 	plg, err := pm.GetPlugin(jobType)
@@ -115,31 +257,246 @@ func (pm *Manager) ExecutePlugin(ctx context.Context, jobType string, config map
 		return nil, err
 	}
 	
-	// Get variables from context
-	vars, ok := ctx.Value("variables").(map[string]interface{})
-	if !ok {
-		vars = make(map[string]interface{})
-	}
-	
-	// Create a new context with variables if needed
-	execCtx := ctx
-	if len(vars) > 0 && ctx.Value("variables") == nil {
-		execCtx = context.WithValue(ctx, "variables", vars)
+	// Fill in the defaults of the plugin's schema, then validate the configuration
+	config = plugin.ApplyDefaults(plg.ConfigSchema(), config)
+	if err := validateConfig(ctx, plg, config); err != nil {
+		return nil, err
 	}
 	
-	// Validate plugin configuration
-	if err := plg.Validate(execCtx, config); err != nil {
-		return nil, fmt.Errorf("invalid configuration: %w", err)
+	if err := pm.waitForRateLimit(ctx, jobType); err != nil {
+		return nil, err
 	}
 	
 	// Execute the plugin
-	result, err := plg.Execute(execCtx, config)
+	pm.logger.Debug("Plugin execution started", "plugin", jobType)
+	start := time.Now()
+	result, err := pm.execute(ctx, plg, config)
 	if err != nil {
+		pm.logger.Debug("Plugin execution failed", "plugin", jobType, "duration", time.Since(start), "error", err)
 		return nil, fmt.Errorf("plugin execution failed: %w", err)
 	}
+	result = pm.checkResult(ctx, plg, result)
+	pm.logger.Debug("Plugin execution finished", "plugin", jobType, "duration", time.Since(start), "success", result.Success)
 	return result, nil
 }
 
+// waitForRateLimit blocks until the rate limit of the named plugin, if any,
+// allows another execution, or ctx is done
+func (pm *Manager) waitForRateLimit(ctx context.Context, name string) error {
+	pm.mutex.RLock()
+	limiter := pm.rateLimiters[name]
+	pm.mutex.RUnlock()
+	if limiter == nil {
+		return nil
+	}
+	
+	delay, err := limiter.wait(ctx)
+	if err != nil {
+		return fmt.Errorf("stopped waiting for the rate limit of plugin %s: %w", name, err)
+	}
+	if delay > 0 {
+		pm.logger.Debug("Plugin execution delayed by its rate limit", "plugin", name, "delay", delay)
+	}
+	return nil
+}
+
+// execute runs the plugin's Execute method, recovering from panics and enforcing
+// the execution timeout
+func (pm *Manager) execute(ctx context.Context, plg plugin.Plugin, config map[string]interface{}) (*plugin.Result, error) {
+	if pm.executionTimeout <= 0 {
+		return safeExecute(ctx, plg, config)
+	}
+	
+	ctx, cancel := context.WithTimeout(ctx, pm.executionTimeout)
+	defer cancel()
+	
+	type outcome struct {
+		result *plugin.Result
+		err    error
+	}
+	done := make(chan outcome, 1)
+	go func() {
+		result, err := safeExecute(ctx, plg, config)
+		done <- outcome{result: result, err: err}
+	}()
+	
+	select {
+	case o := <-done:
+		if ctx.Err() != context.DeadlineExceeded {
+			return o.result, o.err
+		}
+	case <-ctx.Done():
+		if ctx.Err() != context.DeadlineExceeded {
+			// Leave canceled executions to the caller, which may grant a grace period
+			o := <-done
+			return o.result, o.err
+		}
+	}
+	
+	pm.logger.Warn("Plugin exceeded its execution timeout", "plugin", plg.Name(), "timeout", pm.executionTimeout)
+	return nil, fmt.Errorf("plugin %s did not finish within %s", plg.Name(), pm.executionTimeout)
+}
+
+// safeExecute runs the plugin's Execute method, or ExecuteStreaming for streaming
+// plugins, turning a panic into a failed result carrying the panic value and
+// stack trace
+func safeExecute(ctx context.Context, plg plugin.Plugin, config map[string]interface{}) (result *plugin.Result, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			executionID := plugin.ExecutionID(ctx)
+			result = &plugin.Result{
+				Success:     false,
+				Message:     fmt.Sprintf("plugin %s panicked: %v\n%s", plg.Name(), r, debug.Stack()),
+				ExecutionID: executionID,
+			}
+			err = nil
+		}
+	}()
+	if streaming, ok := plg.(plugin.StreamingPlugin); ok {
+		return streaming.ExecuteStreaming(ctx, config, streamOutput(ctx))
+	}
+	return plg.Execute(ctx, config)
+}
+
+// streamOutput returns the writer streaming plugins write job output to: the
+// job's output, which goes to the console and its log file, or stderr outside
+// of a job
+func streamOutput(ctx context.Context) io.Writer {
+	if output := plugin.Output(ctx); output != nil {
+		return output
+	}
+	return os.Stderr
+}
+
+// checkResult guards against plugins returning an incomplete result: no result
+// without an error is a failed result, and a missing execution ID is filled in
+// with the ID of the plan execution from the context
+func (pm *Manager) checkResult(ctx context.Context, plg plugin.Plugin, result *plugin.Result) *plugin.Result {
+	if result == nil {
+		pm.logger.Warn("Plugin returned no result and no error", "plugin", plg.Name())
+		return &plugin.Result{
+			Success:     false,
+			Message:     fmt.Sprintf("plugin %s returned no result and no error", plg.Name()),
+			ExecutionID: plugin.ExecutionID(ctx),
+		}
+	}
+	if result.ExecutionID == "" {
+		result.ExecutionID = plugin.ExecutionID(ctx)
+	}
+	return result
+}
+
+// ValidatePlugin checks a configuration, with the defaults of the plugin's schema
+// filled in, against the schema and the plugin's own Validate method without
+// executing the plugin
+func (pm *Manager) ValidatePlugin(ctx context.Context, jobType string, config map[string]interface{}) error {
+	plg, err := pm.GetPlugin(jobType)
+	if err != nil {
+		return err
+	}
+	return validateConfig(ctx, plg, plugin.ApplyDefaults(plg.ConfigSchema(), config))
+}
+
+// validateConfig runs the schema check followed by the plugin's Validate method
+func validateConfig(ctx context.Context, plg plugin.Plugin, config map[string]interface{}) error {
+	// Check the configuration against the plugin's schema
+	if err := plugin.ValidateConfig(plg.ConfigSchema(), config); err != nil {
+		return fmt.Errorf("invalid configuration for plugin %s: %w", plg.Name(), err)
+	}
+	
+	if err := safeValidate(ctx, plg, config); err != nil {
+		return fmt.Errorf("invalid configuration: %w", err)
+	}
+	return nil
+}
+
+// safeValidate runs the plugin's Validate method, turning a panic into an error
+func safeValidate(ctx context.Context, plg plugin.Plugin, config map[string]interface{}) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("plugin %s panicked: %v\n%s", plg.Name(), r, debug.Stack())
+		}
+	}()
+	return plg.Validate(ctx, config)
+}
+
+// PreviewPlugin returns the plugin's preview of the changes a job with the given
+// configuration would make, with the defaults of its schema filled in. Plugins
+// that do not implement plugin.Previewer have no preview.
+func (pm *Manager) PreviewPlugin(ctx context.Context, jobType string, config map[string]interface{}) (string, error) {
+	plg, err := pm.GetPlugin(jobType)
+	if err != nil {
+		return "", err
+	}
+	previewer, ok := plg.(plugin.Previewer)
+	if !ok {
+		return "", nil
+	}
+	
+	pm.logger.Debug("Plugin preview started", "plugin", jobType)
+	return safePreview(ctx, plg, previewer, plugin.ApplyDefaults(plg.ConfigSchema(), config))
+}
+
+// safePreview runs the plugin's Preview method, turning a panic into an error
+func safePreview(ctx context.Context, plg plugin.Plugin, previewer plugin.Previewer, config map[string]interface{}) (preview string, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("plugin %s panicked: %v\n%s", plg.Name(), r, debug.Stack())
+		}
+	}()
+	return previewer.Preview(ctx, config)
+}
+
+// PluginCapabilities returns the optional features supported by a specific plugin
+func (pm *Manager) PluginCapabilities(jobType string) (plugin.Capabilities, error) {
+	plg, err := pm.GetPlugin(jobType)
+	if err != nil {
+		return plugin.Capabilities{}, err
+	}
+	return plugin.CapabilitiesOf(plg), nil
+}
+
+// CanRollBack reports whether the plugin of a job type would undo a job executed
+// with config: the plugin supports rollback and, if it implements
+// plugin.RollbackChecker, the config has a compensating action
+func (pm *Manager) CanRollBack(jobType string, config map[string]interface{}) (bool, error) {
+	plg, err := pm.GetPlugin(jobType)
+	if err != nil {
+		return false, err
+	}
+	if !plugin.CapabilitiesOf(plg).Rollback {
+		return false, nil
+	}
+	if checker, ok := plg.(plugin.RollbackChecker); ok {
+		return checker.CanRollBack(config), nil
+	}
+	return true, nil
+}
+
+// RollbackPlugin reverts a previous execution of a specific plugin
+func (pm *Manager) RollbackPlugin(ctx context.Context, jobType string, executionID string) error {
+	plg, err := pm.GetPlugin(jobType)
+	if err != nil {
+		return err
+	}
+	
+	pm.logger.Debug("Plugin rollback started", "plugin", jobType, "executionID", executionID)
+	if err := safeRollback(ctx, plg, executionID); err != nil {
+		return fmt.Errorf("plugin rollback failed: %w", err)
+	}
+	return nil
+}
+
+// safeRollback runs the plugin's Rollback method, turning a panic into an error
+func safeRollback(ctx context.Context, plg plugin.Plugin, executionID string) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("plugin %s panicked: %v\n%s", plg.Name(), r, debug.Stack())
+		}
+	}()
+	return plg.Rollback(ctx, executionID)
+}
+
 // ListPlugins returns all registered plugins
 func (pm *Manager) ListPlugins() []plugin.Plugin {
 	pm.mutex.RLock()
@@ -1,9 +1,17 @@
 package plugins
 
 import (
+	"bytes"
 	"context"
+	"errors"
+	"fmt"
+	"io"
 	"os"
+	"path/filepath"
+	goplugin "plugin"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/cuongtl1992/grp-cli/pkg/plugin"
 )
@@ -11,18 +19,26 @@ import (
 // MockPlugin implements the Plugin interface for testing
 type MockPlugin struct {
 	name        string
+	schema      *plugin.JSONSchema
 	validateErr error
 	executeErr  error
+	execute     func(ctx context.Context) (*plugin.Result, error)
+	// executed holds the config of the last execution
+	executed map[string]interface{}
 }
 
 func (m *MockPlugin) Name() string                                       { return m.name }
 func (m *MockPlugin) Description() string                               { return "Mock plugin for testing" }
 func (m *MockPlugin) Version() string                                   { return "1.0.0" }
-func (m *MockPlugin) ConfigSchema() *plugin.JSONSchema                  { return nil }
+func (m *MockPlugin) ConfigSchema() *plugin.JSONSchema                  { return m.schema }
 func (m *MockPlugin) Validate(ctx context.Context, config map[string]interface{}) error { 
 	return m.validateErr 
 }
 func (m *MockPlugin) Execute(ctx context.Context, config map[string]interface{}) (*plugin.Result, error) {
+	m.executed = config
+	if m.execute != nil {
+		return m.execute(ctx)
+	}
 	if m.executeErr != nil {
 		return nil, m.executeErr
 	}
@@ -31,13 +47,83 @@ func (m *MockPlugin) Execute(ctx context.Context, config map[string]interface{})
 func (m *MockPlugin) Rollback(ctx context.Context, executionID string) error { return nil }
 
 func TestNewManager(t *testing.T) {
-	manager := NewManager("./plugins")
+	manager := NewManager("./plugins", "/opt/grp/plugins")
 	if manager == nil {
 		t.Fatal("Expected non-nil manager")
 		return
 	}
-	if manager.pluginDir != "./plugins" {
-		t.Errorf("Expected plugin dir to be './plugins', got %s", manager.pluginDir)
+	if len(manager.pluginDirs) != 2 || manager.pluginDirs[0] != "./plugins" || manager.pluginDirs[1] != "/opt/grp/plugins" {
+		t.Errorf("Expected plugin dirs './plugins' and '/opt/grp/plugins', got %v", manager.pluginDirs)
+	}
+}
+
+func TestFindPlugins(t *testing.T) {
+	dir := t.TempDir()
+	for _, file := range []string{"http.so", ".install-123.so", "README.md", "team/deploy.so", "team/nested/notify.so"} {
+		path := filepath.Join(dir, file)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name      string
+		dir       string
+		recursive bool
+		expected  []string
+		wantErr   string
+	}{
+		{name: "top level only", dir: dir, expected: []string{"http.so"}},
+		{name: "recursive", dir: dir, recursive: true, expected: []string{"http.so", "team/deploy.so", "team/nested/notify.so"}},
+		{name: "missing directory", dir: filepath.Join(dir, "missing"), wantErr: "plugin directory does not exist"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			files, err := findPlugins(tt.dir, tt.recursive)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Expected error %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("findPlugins() error = %v", err)
+			}
+
+			var relative []string
+			for _, file := range files {
+				rel, _ := filepath.Rel(dir, file)
+				relative = append(relative, filepath.ToSlash(rel))
+			}
+			if strings.Join(relative, ",") != strings.Join(tt.expected, ",") {
+				t.Errorf("Expected %v, got %v", tt.expected, relative)
+			}
+		})
+	}
+}
+
+func TestRegisterLoadedConflict(t *testing.T) {
+	manager := NewManager()
+	loadedFrom := make(map[string]string)
+
+	if err := manager.registerLoaded(&MockPlugin{name: "deploy"}, "/org/deploy.so", loadedFrom); err != nil {
+		t.Fatalf("registerLoaded() error = %v", err)
+	}
+	err := manager.registerLoaded(&MockPlugin{name: "deploy"}, "./plugins/deploy.so", loadedFrom)
+	if err == nil || !strings.Contains(err.Error(), "plugin deploy is defined in both /org/deploy.so and ./plugins/deploy.so") {
+		t.Errorf("Expected conflict error naming both paths, got %v", err)
+	}
+
+	// Replacing a plugin that was not loaded from a file, such as a built-in one, is not a conflict
+	if err := manager.RegisterPlugin(&MockPlugin{name: "shell"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := manager.registerLoaded(&MockPlugin{name: "shell"}, "/org/shell.so", loadedFrom); err != nil {
+		t.Errorf("Expected a loaded plugin to replace a built-in one, got %v", err)
 	}
 }
 
@@ -101,6 +187,15 @@ func TestExecutePlugin(t *testing.T) {
 			config:    map[string]interface{}{},
 			expectErr: true,
 		},
+		{
+			name: "schema error",
+			plugin: &MockPlugin{name: "schema-error", schema: &plugin.JSONSchema{
+				Type:     "object",
+				Required: []string{"namespace"},
+			}},
+			config:    map[string]interface{}{"key": "value"},
+			expectErr: true,
+		},
 		{
 			name:      "execution error",
 			plugin:    &MockPlugin{name: "execution-error", executeErr: os.ErrInvalid},
@@ -129,6 +224,177 @@ func TestExecutePlugin(t *testing.T) {
 	}
 }
 
+func TestExecutePluginDefaults(t *testing.T) {
+	mock := &MockPlugin{name: "defaults", schema: &plugin.JSONSchema{
+		Type: "object",
+		Properties: map[string]*plugin.JSONSchema{
+			"namespace": {Type: "string", Default: "default"},
+			"replicas":  {Type: "integer", Default: 2},
+		},
+		Required: []string{"namespace"},
+	}}
+	manager := NewManager("./plugins")
+	if err := manager.RegisterPlugin(mock); err != nil {
+		t.Fatalf("Failed to register plugin: %v", err)
+	}
+
+	config := map[string]interface{}{"replicas": 5}
+	if err := manager.ValidatePlugin(context.Background(), "defaults", config); err != nil {
+		t.Fatalf("ValidatePlugin() error = %v", err)
+	}
+	if _, err := manager.ExecutePlugin(context.Background(), "defaults", config); err != nil {
+		t.Fatalf("ExecutePlugin() error = %v", err)
+	}
+	if mock.executed["namespace"] != "default" || mock.executed["replicas"] != 5 {
+		t.Errorf("Expected the default namespace and the explicit replicas, got %v", mock.executed)
+	}
+	if _, ok := config["namespace"]; ok {
+		t.Error("Expected the job config to be left untouched")
+	}
+}
+
+func TestExecutePluginIsolation(t *testing.T) {
+	tests := []struct {
+		name          string
+		execute       func(ctx context.Context) (*plugin.Result, error)
+		timeout       time.Duration
+		expectErr     string
+		expectMessage string
+	}{
+		{
+			name:          "panic becomes a failed result",
+			execute:       func(ctx context.Context) (*plugin.Result, error) { panic("nil map write") },
+			expectMessage: "plugin unsafe panicked: nil map write",
+		},
+		{
+			name: "plugin ignoring its context is abandoned",
+			execute: func(ctx context.Context) (*plugin.Result, error) {
+				time.Sleep(time.Second)
+				return &plugin.Result{Success: true}, nil
+			},
+			timeout:   20 * time.Millisecond,
+			expectErr: "plugin unsafe did not finish within 20ms",
+		},
+		{
+			name: "plugin honoring its context times out",
+			execute: func(ctx context.Context) (*plugin.Result, error) {
+				<-ctx.Done()
+				return nil, ctx.Err()
+			},
+			timeout:   20 * time.Millisecond,
+			expectErr: "plugin unsafe did not finish within 20ms",
+		},
+		{
+			name:    "fast plugin is unaffected by the timeout",
+			execute: func(ctx context.Context) (*plugin.Result, error) { return &plugin.Result{Success: true}, nil },
+			timeout: time.Second,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			manager := NewManager("./plugins")
+			manager.SetExecutionTimeout(tt.timeout)
+			if err := manager.RegisterPlugin(&MockPlugin{name: "unsafe", execute: tt.execute}); err != nil {
+				t.Fatalf("Failed to register plugin: %v", err)
+			}
+
+			result, err := manager.ExecutePlugin(context.Background(), "unsafe", map[string]interface{}{})
+			if tt.expectErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.expectErr) {
+					t.Fatalf("Expected error %q, got %v", tt.expectErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ExecutePlugin() error = %v", err)
+			}
+			if tt.expectMessage != "" {
+				if result.Success || !strings.HasPrefix(result.Message, tt.expectMessage) {
+					t.Errorf("Expected failed result %q, got %+v", tt.expectMessage, result)
+				}
+				if !strings.Contains(result.Message, "goroutine") {
+					t.Errorf("Expected the stack trace in the message, got %q", result.Message)
+				}
+			} else if !result.Success {
+				t.Errorf("Expected success, got %+v", result)
+			}
+		})
+	}
+}
+
+func TestPluginCapabilities(t *testing.T) {
+	manager := NewManager()
+	if err := manager.RegisterPlugin(&MockPlugin{name: "mock"}); err != nil {
+		t.Fatalf("Failed to register plugin: %v", err)
+	}
+
+	capabilities, err := manager.PluginCapabilities("mock")
+	if err != nil {
+		t.Fatalf("Expected capabilities, got error: %v", err)
+	}
+	if capabilities != (plugin.Capabilities{Rollback: true}) {
+		t.Errorf("Expected only rollback for a plugin declaring no capabilities, got %+v", capabilities)
+	}
+
+	if _, err := manager.PluginCapabilities("missing"); err == nil {
+		t.Error("Expected error for an unknown plugin")
+	}
+}
+
+// checkedRollbackPlugin only rolls back the jobs configured with an undo command
+type checkedRollbackPlugin struct {
+	*MockPlugin
+	rollback bool
+}
+
+func (p *checkedRollbackPlugin) Capabilities() plugin.Capabilities {
+	return plugin.Capabilities{Rollback: p.rollback}
+}
+
+func (p *checkedRollbackPlugin) CanRollBack(config map[string]interface{}) bool {
+	return config["undo"] != nil
+}
+
+func TestCanRollBack(t *testing.T) {
+	manager := NewManager()
+	for _, plg := range []plugin.Plugin{
+		&MockPlugin{name: "mock"},
+		&checkedRollbackPlugin{MockPlugin: &MockPlugin{name: "checked"}, rollback: true},
+		&checkedRollbackPlugin{MockPlugin: &MockPlugin{name: "norollback"}},
+	} {
+		if err := manager.RegisterPlugin(plg); err != nil {
+			t.Fatalf("Failed to register plugin: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name     string
+		jobType  string
+		config   map[string]interface{}
+		expected bool
+		wantErr  bool
+	}{
+		{name: "plugin without checker", jobType: "mock", expected: true},
+		{name: "compensated job", jobType: "checked", config: map[string]interface{}{"undo": "rm -rf out"}, expected: true},
+		{name: "job without compensation", jobType: "checked", config: map[string]interface{}{}, expected: false},
+		{name: "plugin without rollback support", jobType: "norollback", config: map[string]interface{}{"undo": "rm -rf out"}, expected: false},
+		{name: "unknown plugin", jobType: "missing", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			canRollBack, err := manager.CanRollBack(tt.jobType, tt.config)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("CanRollBack() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if canRollBack != tt.expected {
+				t.Errorf("Expected CanRollBack() = %v, got %v", tt.expected, canRollBack)
+			}
+		})
+	}
+}
+
 func TestListPlugins(t *testing.T) {
 	manager := NewManager("./plugins")
 	plugins := []plugin.Plugin{
@@ -159,3 +425,212 @@ func TestListPlugins(t *testing.T) {
 		}
 	}
 }
+
+func TestCheckPluginAPIVersion(t *testing.T) {
+	version := func(v string) *string { return &v }
+
+	tests := []struct {
+		name    string
+		symbols map[string]goplugin.Symbol
+		wantErr string
+	}{
+		{name: "compatible", symbols: map[string]goplugin.Symbol{"APIVersion": version(plugin.APIVersion)}},
+		{name: "missing", symbols: map[string]goplugin.Symbol{}, wantErr: "does not export 'APIVersion'"},
+		{name: "not a string", symbols: map[string]goplugin.Symbol{"APIVersion": new(int)}, wantErr: "must be a string variable"},
+		{name: "incompatible", symbols: map[string]goplugin.Symbol{"APIVersion": version("99.0")}, wantErr: "not compatible"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lookup := func(name string) (goplugin.Symbol, error) {
+				if symbol, ok := tt.symbols[name]; ok {
+					return symbol, nil
+				}
+				return nil, errors.New("symbol not found")
+			}
+
+			err := checkPluginAPIVersion(lookup)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestRegisterBuiltin(t *testing.T) {
+	builtin := &MockPlugin{name: "builtin-test"}
+	RegisterBuiltin(builtin)
+	defer func() {
+		builtinMutex.Lock()
+		delete(builtins, builtin.name)
+		builtinMutex.Unlock()
+	}()
+
+	manager := NewManager("./plugins")
+	plg, err := manager.GetPlugin("builtin-test")
+	if err != nil {
+		t.Fatalf("Expected built-in plugin to be registered: %v", err)
+	}
+	if plg != builtin {
+		t.Errorf("Expected the registered built-in plugin instance")
+	}
+
+	// Managers get their own registry, so registering a plugin does not leak into others
+	if err := manager.RegisterPlugin(&MockPlugin{name: "local"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewManager("./plugins").GetPlugin("local"); err == nil {
+		t.Errorf("Expected plugins registered on a manager to stay local to it")
+	}
+}
+
+func TestExecutePluginResultChecks(t *testing.T) {
+	tests := []struct {
+		name            string
+		execute         func(ctx context.Context) (*plugin.Result, error)
+		expectSuccess   bool
+		expectMessage   string
+		expectExecution string
+	}{
+		{
+			name:            "no result and no error is a failure",
+			execute:         func(ctx context.Context) (*plugin.Result, error) { return nil, nil },
+			expectMessage:   "plugin buggy returned no result and no error",
+			expectExecution: "exec-1",
+		},
+		{
+			name:            "missing execution ID is filled from the context",
+			execute:         func(ctx context.Context) (*plugin.Result, error) { return &plugin.Result{Success: true}, nil },
+			expectSuccess:   true,
+			expectExecution: "exec-1",
+		},
+		{
+			name: "execution ID set by the plugin is kept",
+			execute: func(ctx context.Context) (*plugin.Result, error) {
+				return &plugin.Result{Success: true, ExecutionID: "deploy-42"}, nil
+			},
+			expectSuccess:   true,
+			expectExecution: "deploy-42",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			manager := NewManager("./plugins")
+			if err := manager.RegisterPlugin(&MockPlugin{name: "buggy", execute: tt.execute}); err != nil {
+				t.Fatalf("Failed to register plugin: %v", err)
+			}
+
+			ctx := plugin.WithExecutionID(context.Background(), "exec-1")
+			result, err := manager.ExecutePlugin(ctx, "buggy", map[string]interface{}{})
+			if err != nil {
+				t.Fatalf("ExecutePlugin() error = %v", err)
+			}
+			if result.Success != tt.expectSuccess || result.Message != tt.expectMessage {
+				t.Errorf("Expected success %v with message %q, got %+v", tt.expectSuccess, tt.expectMessage, result)
+			}
+			if result.ExecutionID != tt.expectExecution {
+				t.Errorf("Expected execution ID %q, got %q", tt.expectExecution, result.ExecutionID)
+			}
+		})
+	}
+}
+
+// streamingMockPlugin writes its output while it runs
+type streamingMockPlugin struct {
+	MockPlugin
+	lines []string
+}
+
+func (m *streamingMockPlugin) ExecuteStreaming(ctx context.Context, config map[string]interface{}, output io.Writer) (*plugin.Result, error) {
+	for _, line := range m.lines {
+		fmt.Fprintln(output, line)
+	}
+	return &plugin.Result{Success: true, Message: "streamed"}, nil
+}
+
+func TestExecuteStreamingPlugin(t *testing.T) {
+	tests := []struct {
+		name     string
+		plugin   plugin.Plugin
+		expected string
+		message  string
+	}{
+		{
+			name:     "streaming plugin writes to the job output",
+			plugin:   &streamingMockPlugin{MockPlugin: MockPlugin{name: "stream"}, lines: []string{"building", "done"}},
+			expected: "building\ndone\n",
+			message:  "streamed",
+		},
+		{
+			name:   "non-streaming plugin uses Execute",
+			plugin: &MockPlugin{name: "stream"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			manager := NewManager("./plugins")
+			if err := manager.RegisterPlugin(tt.plugin); err != nil {
+				t.Fatalf("Failed to register plugin: %v", err)
+			}
+
+			var output bytes.Buffer
+			ctx := plugin.WithOutput(context.Background(), &output)
+			result, err := manager.ExecutePlugin(ctx, "stream", map[string]interface{}{})
+			if err != nil {
+				t.Fatalf("ExecutePlugin() error = %v", err)
+			}
+			if !result.Success || result.Message != tt.message {
+				t.Errorf("Expected success with message %q, got %+v", tt.message, result)
+			}
+			if output.String() != tt.expected {
+				t.Errorf("Expected output %q, got %q", tt.expected, output.String())
+			}
+		})
+	}
+}
+
+func TestExecutePluginRateLimit(t *testing.T) {
+	tests := []struct {
+		name      string
+		limit     float64
+		burst     int
+		minWaited time.Duration
+	}{
+		{name: "limited", limit: 40, burst: 1, minWaited: 40 * time.Millisecond},
+		{name: "burst", limit: 40, burst: 3},
+		{name: "removed limit", limit: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			manager := NewManager("./plugins")
+			if err := manager.RegisterPlugin(&MockPlugin{name: "api"}); err != nil {
+				t.Fatalf("Failed to register plugin: %v", err)
+			}
+			manager.SetRateLimit("api", 1, 1)
+			manager.SetRateLimit("api", tt.limit, tt.burst)
+
+			start := time.Now()
+			for i := 0; i < 3; i++ {
+				if _, err := manager.ExecutePlugin(context.Background(), "api", map[string]interface{}{}); err != nil {
+					t.Fatalf("ExecutePlugin() error = %v", err)
+				}
+			}
+			waited := time.Since(start)
+			if waited < tt.minWaited {
+				t.Errorf("Expected the executions to take at least %s, took %s", tt.minWaited, waited)
+			}
+			if tt.minWaited == 0 && waited > 20*time.Millisecond {
+				t.Errorf("Expected the executions not to wait, took %s", waited)
+			}
+		})
+	}
+}
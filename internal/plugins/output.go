@@ -0,0 +1,10 @@
+package plugins
+
+import "strings"
+
+// LastLine returns the last non-empty line of the output of a command, for the
+// error messages of the bundled plugins. It is empty if the output is blank.
+func LastLine(output string) string {
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	return strings.TrimSpace(lines[len(lines)-1])
+}
@@ -0,0 +1,25 @@
+package plugins
+
+import "testing"
+
+func TestLastLine(t *testing.T) {
+	tests := []struct {
+		name     string
+		output   string
+		expected string
+	}{
+		{name: "single line", output: "permission denied\n", expected: "permission denied"},
+		{name: "several lines", output: "building\nerror: no space left\n\n", expected: "error: no space left"},
+		{name: "carriage returns", output: "step 1\r\nfailed\r\n", expected: "failed"},
+		{name: "empty", output: "", expected: ""},
+		{name: "blank", output: " \n\n", expected: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := LastLine(tt.output); got != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,57 @@
+package plugins
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRateLimiterWait(t *testing.T) {
+	tests := []struct {
+		name      string
+		rate      float64
+		burst     int
+		calls     int
+		minWaited time.Duration
+		maxWaited time.Duration
+	}{
+		{name: "within burst", rate: 10, burst: 3, calls: 3, maxWaited: 20 * time.Millisecond},
+		{name: "beyond burst", rate: 20, burst: 2, calls: 4, minWaited: 90 * time.Millisecond, maxWaited: 200 * time.Millisecond},
+		{name: "default burst", rate: 50, burst: 0, calls: 3, minWaited: 35 * time.Millisecond, maxWaited: 150 * time.Millisecond},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			limiter := newRateLimiter(tt.rate, tt.burst)
+			start := time.Now()
+			for i := 0; i < tt.calls; i++ {
+				if _, err := limiter.wait(context.Background()); err != nil {
+					t.Fatalf("wait() error = %v", err)
+				}
+			}
+			waited := time.Since(start)
+			if waited < tt.minWaited || waited > tt.maxWaited {
+				t.Errorf("Expected %d calls to take between %s and %s, took %s", tt.calls, tt.minWaited, tt.maxWaited, waited)
+			}
+		})
+	}
+}
+
+func TestRateLimiterCanceled(t *testing.T) {
+	limiter := newRateLimiter(1, 1)
+	if _, err := limiter.wait(context.Background()); err != nil {
+		t.Fatalf("wait() error = %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := limiter.wait(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected the wait to stop with its context, got %v", err)
+	}
+
+	// The abandoned token is returned, so the next caller is not delayed further
+	if delay := limiter.reserve(); delay > time.Second {
+		t.Errorf("Expected at most a second to wait, got %s", delay)
+	}
+}
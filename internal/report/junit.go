@@ -0,0 +1,122 @@
+package report
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/cuongtl1992/grp-cli/internal/models"
+)
+
+// junitTestSuites is the root element of a JUnit XML report: the execution
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Skipped  int              `xml:"skipped,attr"`
+	Time     string           `xml:"time,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+// junitTestSuite is a stage of the execution
+type junitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	Skipped   int             `xml:"skipped,attr"`
+	Time      string          `xml:"time,attr"`
+	Timestamp string          `xml:"timestamp,attr,omitempty"`
+	Cases     []junitTestCase `xml:"testcase"`
+}
+
+// junitTestCase is a job of a stage
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	Classname string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitMessage `xml:"failure"`
+	Skipped   *junitMessage `xml:"skipped"`
+}
+
+// junitMessage is the failure or skipped element of a test case
+type junitMessage struct {
+	Message string `xml:"message,attr,omitempty"`
+	Type    string `xml:"type,attr,omitempty"`
+	Text    string `xml:",chardata"`
+}
+
+// writeJUnit writes the execution result as a JUnit XML report: each stage, and
+// the plan's hook that ran, is a test suite and each job a test case. Failed jobs, including those that continued
+// on error, are failures and canceled jobs are skipped.
+func writeJUnit(w io.Writer, result *models.ExecutionResult) error {
+	report := junitTestSuites{
+		Name: "grp-cli " + result.ID,
+		Time: seconds(result.Duration),
+	}
+
+	stages := result.Stages
+	if result.Hooks != nil {
+		stages = append(stages[:len(stages):len(stages)], *result.Hooks)
+	}
+	for _, stage := range stages {
+		// Hooks are reported as test cases too, so that a failed hook shows up
+		jobs := stage.AllJobs()
+		suite := junitTestSuite{
+			Name:  stage.Name,
+			Tests: len(jobs),
+			Time:  seconds(stage.Duration),
+		}
+		if !stage.StartTime.IsZero() {
+			suite.Timestamp = stage.StartTime.UTC().Format("2006-01-02T15:04:05")
+		}
+
+		for _, job := range jobs {
+			testCase := junitTestCase{
+				Name:      job.Name,
+				Classname: stage.Name,
+				Time:      seconds(job.Duration),
+			}
+			switch {
+			case job.Canceled, job.Skipped:
+				suite.Skipped++
+				testCase.Skipped = &junitMessage{Message: firstLine(job.Message)}
+			case !job.Success:
+				suite.Failures++
+				testCase.Failure = &junitMessage{Message: firstLine(job.Message), Type: job.Type, Text: job.Message}
+			}
+			suite.Cases = append(suite.Cases, testCase)
+		}
+
+		report.Tests += suite.Tests
+		report.Failures += suite.Failures
+		report.Skipped += suite.Skipped
+		report.Suites = append(report.Suites, suite)
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return fmt.Errorf("failed to encode JUnit report: %w", err)
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(report); err != nil {
+		return fmt.Errorf("failed to encode JUnit report: %w", err)
+	}
+	if _, err := io.WriteString(w, "\n"); err != nil {
+		return fmt.Errorf("failed to encode JUnit report: %w", err)
+	}
+	return nil
+}
+
+// seconds formats a duration as JUnit expects: seconds with millisecond precision
+func seconds(d time.Duration) string {
+	return fmt.Sprintf("%.3f", d.Seconds())
+}
+
+// firstLine returns the first line of a message, for use as a summary attribute
+func firstLine(message string) string {
+	line, _, _ := strings.Cut(message, "\n")
+	return line
+}
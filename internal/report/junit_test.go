@@ -0,0 +1,63 @@
+package report
+
+import (
+	"bytes"
+	"encoding/xml"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cuongtl1992/grp-cli/internal/models"
+)
+
+func TestWriteJUnit(t *testing.T) {
+	result := newTestResult()
+	result.Stages[0].Duration = 1500 * time.Millisecond
+	result.Stages[0].Jobs[1].Message = "exit status 1: <stderr> & \"quoted\"\nsecond line"
+	result.Stages = append(result.Stages, models.StageResult{
+		Name: "verify",
+		Jobs: []models.JobResult{{Name: "smoke", Type: "http", Canceled: true, Message: "job smoke canceled"}},
+	})
+	result.Hooks = &models.StageResult{
+		Name: models.PlanHookOnFailure,
+		Jobs: []models.JobResult{{Name: "page", Type: "http", Success: true}},
+	}
+
+	buf := new(bytes.Buffer)
+	if err := Write(buf, result, FormatJUnit); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	output := buf.String()
+	if !strings.HasPrefix(output, xml.Header) {
+		t.Errorf("Expected XML header, got %s", output)
+	}
+	if !strings.Contains(output, "&lt;stderr&gt; &amp;") {
+		t.Errorf("Expected special characters to be escaped, got %s", output)
+	}
+
+	var decoded junitTestSuites
+	if err := xml.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatalf("Report is not valid XML: %v", err)
+	}
+	if decoded.Tests != 4 || decoded.Failures != 1 || decoded.Skipped != 1 || len(decoded.Suites) != 3 {
+		t.Fatalf("Expected 4 tests, 1 failure and 1 skipped in 3 suites, got %+v", decoded)
+	}
+
+	deploy := decoded.Suites[0]
+	if deploy.Name != "deploy" || deploy.Time != "1.500" || deploy.Failures != 1 {
+		t.Errorf("Unexpected deploy suite: %+v", deploy)
+	}
+	if deploy.Cases[0].Failure != nil || deploy.Cases[0].Classname != "deploy" {
+		t.Errorf("Expected job1 to pass, got %+v", deploy.Cases[0])
+	}
+	failure := deploy.Cases[1].Failure
+	if failure == nil || failure.Message != `exit status 1: <stderr> & "quoted"` || failure.Text != result.Stages[0].Jobs[1].Message {
+		t.Errorf("Expected job2 failure with its message, got %+v", failure)
+	}
+	if skipped := decoded.Suites[1].Cases[0].Skipped; skipped == nil || skipped.Message != "job smoke canceled" {
+		t.Errorf("Expected canceled job to be skipped, got %+v", decoded.Suites[1].Cases[0])
+	}
+	if hook := decoded.Suites[2]; hook.Name != "onFailure" || hook.Cases[0].Name != "page" || hook.Cases[0].Failure != nil {
+		t.Errorf("Expected the plan hook as a suite, got %+v", hook)
+	}
+}
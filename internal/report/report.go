@@ -0,0 +1,64 @@
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/cuongtl1992/grp-cli/internal/models"
+)
+
+// Supported report formats
+const (
+	// FormatText is the default human-readable summary
+	FormatText = "text"
+	// FormatJSON serializes the full execution result as JSON
+	FormatJSON = "json"
+	// FormatYAML serializes the full execution result as YAML
+	FormatYAML = "yaml"
+	// FormatJUnit reports stages and jobs as JUnit XML test suites and test cases
+	FormatJUnit = "junit"
+)
+
+// IsStructured returns true if the format produces a machine-readable report
+func IsStructured(format string) bool {
+	return format == FormatJSON || format == FormatYAML || format == FormatJUnit
+}
+
+// ValidateFormat checks that format is a supported report format
+func ValidateFormat(format string) error {
+	switch format {
+	case FormatText, FormatJSON, FormatYAML, FormatJUnit:
+		return nil
+	default:
+		return fmt.Errorf("unsupported output format: %s (expected %s, %s, %s or %s)", format, FormatText, FormatJSON, FormatYAML, FormatJUnit)
+	}
+}
+
+// Write serializes the execution result to w in a structured format
+func Write(w io.Writer, result *models.ExecutionResult, format string) error {
+	switch format {
+	case FormatJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(result); err != nil {
+			return fmt.Errorf("failed to encode JSON report: %w", err)
+		}
+	case FormatYAML:
+		encoder := yaml.NewEncoder(w)
+		encoder.SetIndent(2)
+		if err := encoder.Encode(result); err != nil {
+			return fmt.Errorf("failed to encode YAML report: %w", err)
+		}
+		if err := encoder.Close(); err != nil {
+			return fmt.Errorf("failed to encode YAML report: %w", err)
+		}
+	case FormatJUnit:
+		return writeJUnit(w, result)
+	default:
+		return fmt.Errorf("format %s is not a structured report format", format)
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package report
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/cuongtl1992/grp-cli/internal/models"
+)
+
+func newTestResult() *models.ExecutionResult {
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	return &models.ExecutionResult{
+		ID:            "exec-1",
+		Success:       false,
+		TotalStages:   1,
+		TotalJobs:     2,
+		CompletedJobs: 1,
+		FailedJobs:    1,
+		StartTime:     start,
+		EndTime:       start.Add(time.Minute),
+		Duration:      time.Minute,
+		Stages: []models.StageResult{
+			{
+				Name: "deploy",
+				Jobs: []models.JobResult{
+					{Name: "job1", Type: "shell", Success: true, Attempts: 1},
+					{Name: "job2", Type: "shell", Success: false, Message: "exit status 1", Attempts: 2},
+				},
+			},
+		},
+	}
+}
+
+func TestWriteJSON(t *testing.T) {
+	buf := new(bytes.Buffer)
+	if err := Write(buf, newTestResult(), FormatJSON); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+
+	var decoded models.ExecutionResult
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatalf("Report is not valid JSON: %v", err)
+	}
+	if decoded.ID != "exec-1" || len(decoded.Stages) != 1 || len(decoded.Stages[0].Jobs) != 2 {
+		t.Errorf("Unexpected decoded report: %+v", decoded)
+	}
+	if !strings.Contains(buf.String(), `"message": "exit status 1"`) {
+		t.Errorf("Expected job message in report, got %s", buf.String())
+	}
+}
+
+func TestWriteYAML(t *testing.T) {
+	buf := new(bytes.Buffer)
+	if err := Write(buf, newTestResult(), FormatYAML); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+
+	var decoded map[string]interface{}
+	if err := yaml.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatalf("Report is not valid YAML: %v", err)
+	}
+	if decoded["id"] != "exec-1" {
+		t.Errorf("Expected id exec-1, got %v", decoded["id"])
+	}
+}
+
+func TestValidateFormat(t *testing.T) {
+	for _, format := range []string{FormatText, FormatJSON, FormatYAML, FormatJUnit} {
+		if err := ValidateFormat(format); err != nil {
+			t.Errorf("Expected %s to be valid, got %v", format, err)
+		}
+	}
+	if err := ValidateFormat("xml"); err == nil {
+		t.Error("Expected error for unsupported format")
+	}
+}
@@ -0,0 +1,162 @@
+package secrets
+
+import (
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/cuongtl1992/grp-cli/internal/models"
+)
+
+// Redacted replaces secret values in output
+const Redacted = "***"
+
+// Masker redacts known secret values from strings and execution results. It is
+// safe for concurrent use.
+type Masker struct {
+	mu     sync.RWMutex
+	values []string
+}
+
+// NewMasker creates a masker for the given secret values
+func NewMasker(values ...string) *Masker {
+	m := &Masker{}
+	m.Add(values...)
+	return m
+}
+
+// Add registers additional secret values; empty values are ignored
+func (m *Masker) Add(values ...string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, value := range values {
+		if value != "" {
+			m.values = append(m.values, value)
+		}
+	}
+
+	// Replace longer values first so a secret containing another is fully masked
+	sort.Slice(m.values, func(i, j int) bool {
+		return len(m.values[i]) > len(m.values[j])
+	})
+}
+
+// Mask replaces every occurrence of a secret value in s
+func (m *Masker) Mask(s string) string {
+	if m == nil {
+		return s
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, value := range m.values {
+		s = strings.ReplaceAll(s, value, Redacted)
+	}
+	return s
+}
+
+// MaskValue masks strings in a value, recursing into maps and slices
+func (m *Masker) MaskValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case string:
+		return m.Mask(v)
+	case map[string]interface{}:
+		return m.MaskMap(v)
+	case []interface{}:
+		masked := make([]interface{}, len(v))
+		for i, item := range v {
+			masked[i] = m.MaskValue(item)
+		}
+		return masked
+	default:
+		return v
+	}
+}
+
+// MaskMap returns a copy of the map with secret values masked
+func (m *Masker) MaskMap(values map[string]interface{}) map[string]interface{} {
+	if values == nil {
+		return nil
+	}
+	masked := make(map[string]interface{}, len(values))
+	for key, value := range values {
+		masked[key] = m.MaskValue(value)
+	}
+	return masked
+}
+
+// MaskResult masks the messages and data of the jobs and stage hooks of an
+// execution result, including its rollback and the plan's onSuccess or onFailure hook
+func (m *Masker) MaskResult(result *models.ExecutionResult) {
+	if result == nil {
+		return
+	}
+	m.maskStages(result.Stages)
+	if result.Rollback != nil {
+		m.maskJobs(result.Rollback.Jobs)
+		m.maskStages(result.Rollback.Stages)
+	}
+	if result.Hooks != nil {
+		m.maskStage(result.Hooks)
+	}
+}
+
+// maskStages masks the jobs and hooks of each stage result in place
+func (m *Masker) maskStages(stages []models.StageResult) {
+	for i := range stages {
+		m.maskStage(&stages[i])
+	}
+}
+
+// maskStage masks the pre-jobs, jobs and post-jobs of a stage result in place
+func (m *Masker) maskStage(stage *models.StageResult) {
+	m.maskJobs(stage.PreJobs)
+	m.maskJobs(stage.Jobs)
+	m.maskJobs(stage.PostJobs)
+}
+
+// maskJobs masks the message and data of each job result in place
+func (m *Masker) maskJobs(jobs []models.JobResult) {
+	for i := range jobs {
+		jobs[i].Message = m.Mask(jobs[i].Message)
+		jobs[i].Data = m.MaskMap(jobs[i].Data)
+	}
+}
+
+// ConfigValues returns the string values stored under any of the given keys,
+// at any depth of the config
+func ConfigValues(config map[string]interface{}, keys []string) []string {
+	if len(keys) == 0 {
+		return nil
+	}
+
+	var values []string
+	for key, value := range config {
+		for _, secretKey := range keys {
+			if key == secretKey {
+				if s, ok := value.(string); ok {
+					values = append(values, s)
+				}
+			}
+		}
+		values = append(values, nestedConfigValues(value, keys)...)
+	}
+	return values
+}
+
+// nestedConfigValues looks for secret keys in maps and slices nested in a config value
+func nestedConfigValues(value interface{}, keys []string) []string {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		return ConfigValues(v, keys)
+	case []interface{}:
+		var values []string
+		for _, item := range v {
+			values = append(values, nestedConfigValues(item, keys)...)
+		}
+		return values
+	default:
+		return nil
+	}
+}
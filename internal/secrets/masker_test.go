@@ -0,0 +1,111 @@
+package secrets
+
+import (
+	"testing"
+
+	"github.com/cuongtl1992/grp-cli/internal/models"
+)
+
+func TestMask(t *testing.T) {
+	masker := NewMasker("s3cr3t", "", "s3cr3t-token")
+
+	tests := []struct {
+		name     string
+		value    string
+		expected string
+	}{
+		{name: "no secret", value: "deployed v1", expected: "deployed v1"},
+		{name: "secret", value: "password=s3cr3t", expected: "password=***"},
+		{name: "longer secret first", value: "token s3cr3t-token", expected: "token ***"},
+		{name: "repeated secret", value: "s3cr3t:s3cr3t", expected: "***:***"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := masker.Mask(tt.value); got != tt.expected {
+				t.Errorf("Mask(%q) = %q, expected %q", tt.value, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestMaskResult(t *testing.T) {
+	masker := NewMasker("hunter2")
+	result := &models.ExecutionResult{
+		Stages: []models.StageResult{{Jobs: []models.JobResult{{
+			Message: "login with hunter2 failed",
+			Data: map[string]interface{}{
+				"stdout": "hunter2",
+				"lines":  []interface{}{"user: admin", "pass: hunter2"},
+				"code":   1,
+			},
+		}}}},
+		Rollback: &models.RollbackResult{
+			Jobs: []models.JobResult{{Message: "undo hunter2"}},
+			Stages: []models.StageResult{{
+				PreJobs:  []models.JobResult{{Message: "notify hunter2"}},
+				PostJobs: []models.JobResult{{Data: map[string]interface{}{"stdout": "hunter2"}}},
+			}},
+		},
+	}
+	result.Stages[0].PreJobs = []models.JobResult{{Message: "pre hunter2"}}
+	result.Stages[0].PostJobs = []models.JobResult{{Data: map[string]interface{}{"stdout": "echo hunter2"}}}
+
+	masker.MaskResult(result)
+
+	job := result.Stages[0].Jobs[0]
+	if job.Message != "login with *** failed" {
+		t.Errorf("Expected message to be masked, got %q", job.Message)
+	}
+	if job.Data["stdout"] != Redacted || job.Data["lines"].([]interface{})[1] != "pass: ***" || job.Data["code"] != 1 {
+		t.Errorf("Expected data to be masked, got %v", job.Data)
+	}
+	if result.Rollback.Jobs[0].Message != "undo ***" {
+		t.Errorf("Expected rollback message to be masked, got %q", result.Rollback.Jobs[0].Message)
+	}
+	if stage := result.Stages[0]; stage.PreJobs[0].Message != "pre ***" || stage.PostJobs[0].Data["stdout"] != "echo ***" {
+		t.Errorf("Expected stage hooks to be masked, got %v and %v", stage.PreJobs, stage.PostJobs)
+	}
+	if stage := result.Rollback.Stages[0]; stage.PreJobs[0].Message != "notify ***" || stage.PostJobs[0].Data["stdout"] != Redacted {
+		t.Errorf("Expected rollback stage hooks to be masked, got %v and %v", stage.PreJobs, stage.PostJobs)
+	}
+}
+
+func TestMaskResultPlanHook(t *testing.T) {
+	masker := NewMasker("hunter2")
+	result := &models.ExecutionResult{
+		Hooks: &models.StageResult{
+			Name: models.PlanHookOnSuccess,
+			Jobs: []models.JobResult{{
+				Message: "notified with hunter2",
+				Data:    map[string]interface{}{"stdout": "token=hunter2"},
+			}},
+		},
+	}
+
+	masker.MaskResult(result)
+
+	if job := result.Hooks.Jobs[0]; job.Message != "notified with ***" || job.Data["stdout"] != "token=***" {
+		t.Errorf("Expected the plan hook to be masked, got %q with %v", job.Message, job.Data)
+	}
+}
+
+func TestConfigValues(t *testing.T) {
+	config := map[string]interface{}{
+		"url":      "https://example.com",
+		"password": "p4ss",
+		"headers":  map[string]interface{}{"token": "t0k3n"},
+		"targets":  []interface{}{map[string]interface{}{"password": "other"}},
+	}
+
+	values := ConfigValues(config, []string{"password", "token"})
+	masker := NewMasker(values...)
+	for _, secret := range []string{"p4ss", "t0k3n", "other"} {
+		if masker.Mask(secret) != Redacted {
+			t.Errorf("Expected %q to be collected as a secret, got %v", secret, values)
+		}
+	}
+	if masker.Mask("https://example.com") != "https://example.com" {
+		t.Errorf("Expected non-secret values to be left alone, got %v", values)
+	}
+}
@@ -0,0 +1,118 @@
+package state
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/cuongtl1992/grp-cli/internal/models"
+)
+
+// Record is the persisted outcome of an execution of a plan
+type Record struct {
+	Plan   string                  `json:"plan"`
+	Result *models.ExecutionResult `json:"result"`
+}
+
+// Store persists execution records as JSON files in a directory, one
+// <execution id>.json file per execution
+type Store struct {
+	dir string
+}
+
+// NewStore creates a store keeping its records in dir
+func NewStore(dir string) *Store {
+	return &Store{dir: dir}
+}
+
+// Path returns the file the record of the execution with the given ID is written to
+func (s *Store) Path(executionID string) string {
+	return filepath.Join(s.dir, executionID+".json")
+}
+
+// Save records the result of an execution of plan, creating the directory if
+// needed. The file is replaced atomically so readers never see a partial record.
+func (s *Store) Save(plan string, result *models.ExecutionResult) error {
+	if result == nil || result.ID == "" {
+		return errors.New("cannot record an execution without an ID")
+	}
+	if err := os.MkdirAll(s.dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create state directory: %w", err)
+	}
+
+	data, err := json.MarshalIndent(Record{Plan: plan, Result: result}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode execution record: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(s.dir, ".record-*")
+	if err != nil {
+		return fmt.Errorf("failed to write execution record: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write execution record: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write execution record: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), s.Path(result.ID)); err != nil {
+		return fmt.Errorf("failed to write execution record: %w", err)
+	}
+	return nil
+}
+
+// List returns the recorded executions, most recent first. A missing directory
+// holds no records. Files that cannot be read or decoded are skipped; the
+// returned errors describe them.
+func (s *Store) List() ([]Record, []error) {
+	entries, err := os.ReadDir(s.dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, []error{fmt.Errorf("failed to read state directory: %w", err)}
+	}
+
+	var records []Record
+	var errs []error
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || strings.HasPrefix(name, ".") || filepath.Ext(name) != ".json" {
+			continue
+		}
+
+		record, err := readRecord(filepath.Join(s.dir, name))
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		records = append(records, record)
+	}
+
+	sort.SliceStable(records, func(i, j int) bool {
+		return records[i].Result.StartTime.After(records[j].Result.StartTime)
+	})
+	return records, errs
+}
+
+// readRecord reads and decodes a record file
+func readRecord(path string) (Record, error) {
+	var record Record
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return record, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	if err := json.Unmarshal(data, &record); err != nil {
+		return record, fmt.Errorf("failed to decode %s: %w", path, err)
+	}
+	if record.Result == nil || record.Result.ID == "" {
+		return record, fmt.Errorf("failed to decode %s: missing execution result", path)
+	}
+	return record, nil
+}
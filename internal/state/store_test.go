@@ -0,0 +1,57 @@
+package state
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/cuongtl1992/grp-cli/internal/models"
+)
+
+func TestStoreSaveList(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "state")
+	store := NewStore(dir)
+
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	for i, id := range []string{"first", "second", "third"} {
+		result := &models.ExecutionResult{ID: id, Success: i != 1, StartTime: start.Add(time.Duration(i) * time.Hour)}
+		if err := store.Save("release", result); err != nil {
+			t.Fatalf("Save() error = %v", err)
+		}
+	}
+
+	// Unreadable and unrelated files are skipped
+	if err := os.WriteFile(filepath.Join(dir, "corrupt.json"), []byte("{"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "empty.json"), []byte("{}"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("not a record"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	records, errs := store.List()
+	if len(errs) != 2 {
+		t.Errorf("Expected 2 errors for the corrupt records, got %v", errs)
+	}
+	if len(records) != 3 {
+		t.Fatalf("Expected 3 records, got %d", len(records))
+	}
+	for i, id := range []string{"third", "second", "first"} {
+		if records[i].Result.ID != id || records[i].Plan != "release" {
+			t.Errorf("Expected record %d to be %s of release, got %s of %s", i, id, records[i].Result.ID, records[i].Plan)
+		}
+	}
+	if records[1].Result.Success {
+		t.Error("Expected the second execution to be recorded as failed")
+	}
+}
+
+func TestStoreListMissingDir(t *testing.T) {
+	records, errs := NewStore(filepath.Join(t.TempDir(), "missing")).List()
+	if len(records) != 0 || len(errs) != 0 {
+		t.Errorf("Expected no records and no errors, got %v, %v", records, errs)
+	}
+}
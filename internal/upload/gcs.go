@@ -0,0 +1,62 @@
+package upload
+
+import (
+	"context"
+	"fmt"
+
+	"cloud.google.com/go/storage"
+)
+
+// GCS uploads objects to a Google Cloud Storage bucket
+type GCS struct {
+	bucket string
+	client *storage.Client
+}
+
+// NewGCSFromEnv creates a GCS uploader for bucket authenticated with the
+// application default credentials: GOOGLE_APPLICATION_CREDENTIALS, the gcloud
+// application default login, or the service account of a Google Cloud
+// instance. STORAGE_EMULATOR_HOST selects a storage emulator, which needs no
+// credentials.
+func NewGCSFromEnv(bucket string) *GCS {
+	return &GCS{bucket: bucket}
+}
+
+// Upload stores body as the object key in a single request
+func (g *GCS) Upload(ctx context.Context, key string, body []byte, contentType string) error {
+	client, err := g.storageClient(ctx)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+	writer := client.Bucket(g.bucket).Object(key).NewWriter(ctx)
+	writer.ContentType = contentType
+	// The objects are small: a single upload rather than a resumable one
+	writer.ChunkSize = 0
+
+	_, err = writer.Write(body)
+	if closeErr := writer.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to upload to GCS: %w", err)
+	}
+	return nil
+}
+
+// storageClient looks up the credentials on the first upload, so that creating
+// the uploader never fails, and keeps the client for the following uploads
+func (g *GCS) storageClient(ctx context.Context) (*storage.Client, error) {
+	if g.client != nil {
+		return g.client, nil
+	}
+
+	client, err := storage.NewClient(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("no GCS credentials: %w", err)
+	}
+	g.client = client
+	return g.client, nil
+}
@@ -0,0 +1,78 @@
+package upload
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestGCSUpload(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		wantErr string
+	}{
+		{name: "uploaded", status: http.StatusOK},
+		{name: "rejected", status: http.StatusForbidden, wantErr: "failed to upload to GCS: googleapi: Error 403: denied"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var name, contentType, body string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/upload/storage/v1/b/releases/o" {
+					t.Errorf("Unexpected request to %s", r.URL.Path)
+				}
+				if tt.status != http.StatusOK {
+					w.WriteHeader(tt.status)
+					io.WriteString(w, `{"error":{"code":403,"message":"denied"}}`)
+					return
+				}
+
+				// A multipart upload: the object metadata, then its content
+				_, params, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+				if err != nil {
+					t.Fatalf("Expected a multipart upload: %v", err)
+				}
+				parts := multipart.NewReader(r.Body, params["boundary"])
+				var metadata struct {
+					Name        string `json:"name"`
+					ContentType string `json:"contentType"`
+				}
+				part, err := parts.NextPart()
+				if err != nil || json.NewDecoder(part).Decode(&metadata) != nil {
+					t.Fatalf("Expected the object metadata: %v", err)
+				}
+				name, contentType = metadata.Name, metadata.ContentType
+				if part, err = parts.NextPart(); err != nil {
+					t.Fatalf("Expected the object content: %v", err)
+				}
+				data, _ := io.ReadAll(part)
+				body = string(data)
+				io.WriteString(w, `{"bucket":"releases","name":"`+name+`"}`)
+			}))
+			defer server.Close()
+
+			t.Setenv("STORAGE_EMULATOR_HOST", server.URL)
+			err := NewGCSFromEnv("releases").Upload(context.Background(), "audit/web/exec-1/report.json", []byte(`{"id":"1"}`), "application/json")
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Expected error %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Upload() error = %v", err)
+			}
+			if name != "audit/web/exec-1/report.json" || body != `{"id":"1"}` || contentType != "application/json" {
+				t.Errorf("Expected the report to be uploaded as JSON, got %s (%s) with %q", name, contentType, body)
+			}
+		})
+	}
+}
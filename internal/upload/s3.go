@@ -0,0 +1,85 @@
+package upload
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// requestTimeout bounds a single upload request
+const requestTimeout = 60 * time.Second
+
+// defaultS3Region is used when no region is configured, as the AWS CLI does
+const defaultS3Region = "us-east-1"
+
+// S3 uploads objects to an Amazon S3 bucket, or a bucket of an S3-compatible store
+type S3 struct {
+	bucket string
+	client *s3.Client
+}
+
+// NewS3FromEnv creates an S3 uploader for bucket configured like the AWS CLI,
+// from the default configuration chain: the AWS_* environment variables, the
+// shared config and credentials files, and container or instance roles.
+// AWS_ENDPOINT_URL_S3 or AWS_ENDPOINT_URL selects an S3-compatible store,
+// addressed with path-style URLs.
+func NewS3FromEnv(bucket string) *S3 {
+	return &S3{bucket: bucket}
+}
+
+// Upload stores body as the object key with a PutObject request
+func (s *S3) Upload(ctx context.Context, key string, body []byte, contentType string) error {
+	client, err := s.s3Client(ctx)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+	_, err = client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:      aws.String(s.bucket),
+		Key:         aws.String(key),
+		Body:        bytes.NewReader(body),
+		ContentType: aws.String(contentType),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to upload to S3: %w", err)
+	}
+	return nil
+}
+
+// s3Client loads the AWS configuration on the first upload, so that creating
+// the uploader never fails, and keeps the client for the following uploads
+func (s *S3) s3Client(ctx context.Context) (*s3.Client, error) {
+	if s.client != nil {
+		return s.client, nil
+	}
+
+	cfg, err := config.LoadDefaultConfig(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load the AWS configuration: %w", err)
+	}
+	if cfg.Region == "" {
+		cfg.Region = defaultS3Region
+	}
+	s.client = s3.NewFromConfig(cfg, func(options *s3.Options) {
+		options.UsePathStyle = firstEnv("AWS_ENDPOINT_URL_S3", "AWS_ENDPOINT_URL") != ""
+	})
+	return s.client, nil
+}
+
+// firstEnv returns the value of the first of the environment variables that is set
+func firstEnv(names ...string) string {
+	for _, name := range names {
+		if value := os.Getenv(name); value != "" {
+			return value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,76 @@
+package upload
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestS3Upload(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		key     string
+		path    string
+		noCreds bool
+		wantErr string
+	}{
+		{name: "uploaded", status: http.StatusOK, key: "audit/web/exec 1/report.json", path: "/releases/audit/web/exec%201/report.json"},
+		{name: "rejected", status: http.StatusForbidden, key: "report.json", path: "/releases/report.json", wantErr: "failed to upload to S3: operation error S3: PutObject, https response error StatusCode: 403"},
+		{name: "no credentials", noCreds: true, key: "report.json", wantErr: "failed to upload to S3: operation error S3: PutObject, get identity: get credentials"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var path, body, contentType, authorization string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				path = r.URL.EscapedPath()
+				contentType = r.Header.Get("Content-Type")
+				authorization = r.Header.Get("Authorization")
+				data, _ := io.ReadAll(r.Body)
+				body = string(data)
+				w.WriteHeader(tt.status)
+				if tt.status != http.StatusOK {
+					io.WriteString(w, "<Error><Code>AccessDenied</Code><Message>Access Denied</Message></Error>")
+				}
+			}))
+			defer server.Close()
+
+			// Keep the configuration of the machine running the tests out of the way
+			dir := t.TempDir()
+			t.Setenv("AWS_CONFIG_FILE", filepath.Join(dir, "config"))
+			t.Setenv("AWS_SHARED_CREDENTIALS_FILE", filepath.Join(dir, "credentials"))
+			t.Setenv("AWS_EC2_METADATA_DISABLED", "true")
+			t.Setenv("AWS_PROFILE", "")
+			t.Setenv("AWS_ENDPOINT_URL_S3", server.URL)
+			t.Setenv("AWS_REGION", "eu-west-1")
+			t.Setenv("AWS_ACCESS_KEY_ID", "AKID")
+			t.Setenv("AWS_SECRET_ACCESS_KEY", "secret")
+			if tt.noCreds {
+				t.Setenv("AWS_ACCESS_KEY_ID", "")
+				t.Setenv("AWS_SECRET_ACCESS_KEY", "")
+			}
+
+			err := NewS3FromEnv("releases").Upload(context.Background(), tt.key, []byte(`{"id":"1"}`), "application/json")
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Expected error %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Upload() error = %v", err)
+			}
+			if path != tt.path || body != `{"id":"1"}` || contentType != "application/json" {
+				t.Errorf("Expected %s to be uploaded as JSON, got %s (%s) with %q", tt.path, path, contentType, body)
+			}
+			if !strings.HasPrefix(authorization, "AWS4-HMAC-SHA256 Credential=AKID/") || !strings.Contains(authorization, "/eu-west-1/s3/aws4_request") {
+				t.Errorf("Expected a signed request, got Authorization %q", authorization)
+			}
+		})
+	}
+}
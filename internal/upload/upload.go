@@ -0,0 +1,114 @@
+// Package upload stores the results of executions in object storage, such as S3
+// or Google Cloud Storage, for a central audit trail.
+package upload
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/cuongtl1992/grp-cli/internal/models"
+	"github.com/cuongtl1992/grp-cli/internal/report"
+)
+
+// Uploader stores objects in a bucket of an object storage service
+type Uploader interface {
+	// Upload stores body as the object key of the bucket, replacing any object with that key
+	Upload(ctx context.Context, key string, body []byte, contentType string) error
+}
+
+// Destination is a bucket and the prefix under which executions are stored
+type Destination struct {
+	Uploader Uploader
+	Prefix   string
+}
+
+// New parses an upload URL, s3://bucket/prefix or gs://bucket/prefix, into the
+// uploader of its service and the key prefix. Credentials are taken from the
+// environment, as the services' command line tools do.
+func New(location string) (*Destination, error) {
+	parsed, err := url.Parse(location)
+	if err != nil {
+		return nil, fmt.Errorf("invalid upload URL %s: %w", location, err)
+	}
+	if parsed.Host == "" {
+		return nil, fmt.Errorf("invalid upload URL %s: missing bucket", location)
+	}
+	prefix := strings.Trim(parsed.Path, "/")
+
+	switch parsed.Scheme {
+	case "s3":
+		return &Destination{Uploader: NewS3FromEnv(parsed.Host), Prefix: prefix}, nil
+	case "gs":
+		return &Destination{Uploader: NewGCSFromEnv(parsed.Host), Prefix: prefix}, nil
+	}
+	return nil, fmt.Errorf("unsupported upload URL %s (expected s3://bucket/prefix or gs://bucket/prefix)", location)
+}
+
+// ExecutionKey returns the key of an object of an execution:
+// <prefix>/<plan>/<execution id>/<name>. The plan name and execution ID are
+// single segments of the key, so that they cannot escape the prefix.
+func (d *Destination) ExecutionKey(plan, executionID, name string) string {
+	return strings.TrimPrefix(path.Join(d.Prefix, keySegment(plan), keySegment(executionID), name), "/")
+}
+
+// keySegment makes a name safe to use as a single segment of an object key
+func keySegment(name string) string {
+	name = strings.NewReplacer("/", "_", "\\", "_").Replace(name)
+	if name == "" || name == "." || name == ".." {
+		return "_" + name
+	}
+	return name
+}
+
+// UploadExecution uploads the JSON report of an execution and, if logDir is set,
+// the job logs captured to <logDir>/<execution id>/ under logs/. It returns the
+// keys of the uploaded objects; on error, those uploaded before it.
+func (d *Destination) UploadExecution(ctx context.Context, plan string, result *models.ExecutionResult, logDir string) ([]string, error) {
+	var body bytes.Buffer
+	if err := report.Write(&body, result, report.FormatJSON); err != nil {
+		return nil, fmt.Errorf("failed to encode report: %w", err)
+	}
+	key := d.ExecutionKey(plan, result.ID, "report.json")
+	if err := d.Uploader.Upload(ctx, key, body.Bytes(), "application/json"); err != nil {
+		return nil, fmt.Errorf("failed to upload %s: %w", key, err)
+	}
+	uploaded := []string{key}
+	if logDir == "" {
+		return uploaded, nil
+	}
+
+	root := filepath.Join(logDir, result.ID)
+	err := filepath.WalkDir(root, func(file string, entry os.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) && file == root {
+				// No job captured any output
+				return filepath.SkipDir
+			}
+			return err
+		}
+		if entry.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(root, file)
+		if err != nil {
+			return err
+		}
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		key := d.ExecutionKey(plan, result.ID, path.Join("logs", filepath.ToSlash(rel)))
+		if err := d.Uploader.Upload(ctx, key, data, "text/plain; charset=utf-8"); err != nil {
+			return fmt.Errorf("failed to upload %s: %w", key, err)
+		}
+		uploaded = append(uploaded, key)
+		return nil
+	})
+	return uploaded, err
+}
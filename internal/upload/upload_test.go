@@ -0,0 +1,147 @@
+package upload
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/cuongtl1992/grp-cli/internal/models"
+)
+
+// recordingUploader keeps the objects it is given, failing for the keys in fail
+type recordingUploader struct {
+	objects map[string]string
+	fail    string
+}
+
+func (u *recordingUploader) Upload(ctx context.Context, key string, body []byte, contentType string) error {
+	if key == u.fail {
+		return errors.New("access denied")
+	}
+	u.objects[key] = string(body)
+	return nil
+}
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		location string
+		service  string
+		prefix   string
+		wantErr  string
+	}{
+		{location: "s3://releases/audit/grp", service: "*upload.S3", prefix: "audit/grp"},
+		{location: "gs://releases", service: "*upload.GCS"},
+		{location: "gs://releases/audit/", service: "*upload.GCS", prefix: "audit"},
+		{location: "azure://releases", wantErr: "unsupported upload URL azure://releases"},
+		{location: "s3:///audit", wantErr: "missing bucket"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.location, func(t *testing.T) {
+			destination, err := New(tt.location)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Expected error %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("New() error = %v", err)
+			}
+			if service := reflect.TypeOf(destination.Uploader).String(); service != tt.service || destination.Prefix != tt.prefix {
+				t.Errorf("Expected %s with prefix %q, got %s with prefix %q", tt.service, tt.prefix, service, destination.Prefix)
+			}
+		})
+	}
+}
+
+func TestExecutionKey(t *testing.T) {
+	tests := []struct {
+		plan        string
+		executionID string
+		expected    string
+	}{
+		{plan: "web", executionID: "exec-1", expected: "audit/web/exec-1/report.json"},
+		{plan: "../../other", executionID: "exec-1", expected: "audit/.._.._other/exec-1/report.json"},
+		{plan: "..", executionID: "..", expected: "audit/_../_../report.json"},
+		{plan: "web\\prod", executionID: "", expected: "audit/web_prod/_/report.json"},
+	}
+
+	destination := &Destination{Prefix: "audit"}
+	for _, tt := range tests {
+		t.Run(tt.plan, func(t *testing.T) {
+			if key := destination.ExecutionKey(tt.plan, tt.executionID, "report.json"); key != tt.expected {
+				t.Errorf("Expected key %s, got %s", tt.expected, key)
+			}
+		})
+	}
+}
+
+func TestUploadExecution(t *testing.T) {
+	logDir := t.TempDir()
+	logFile := filepath.Join(logDir, "exec-1", "deploy", "app.log")
+	if err := os.MkdirAll(filepath.Dir(logFile), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(logFile, []byte("applied\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	result := &models.ExecutionResult{ID: "exec-1", Success: true}
+
+	tests := []struct {
+		name     string
+		prefix   string
+		logDir   string
+		fail     string
+		expected []string
+		wantErr  string
+	}{
+		{
+			name:     "report and logs",
+			prefix:   "audit",
+			logDir:   logDir,
+			expected: []string{"audit/web/exec-1/report.json", "audit/web/exec-1/logs/deploy/app.log"},
+		},
+		{
+			name:     "no prefix or logs",
+			expected: []string{"web/exec-1/report.json"},
+		},
+		{
+			name:     "no captured logs",
+			logDir:   filepath.Join(logDir, "missing"),
+			expected: []string{"web/exec-1/report.json"},
+		},
+		{
+			name:     "failed upload",
+			logDir:   logDir,
+			fail:     "web/exec-1/logs/deploy/app.log",
+			expected: []string{"web/exec-1/report.json"},
+			wantErr:  "failed to upload web/exec-1/logs/deploy/app.log: access denied",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			uploader := &recordingUploader{objects: make(map[string]string), fail: tt.fail}
+			destination := &Destination{Uploader: uploader, Prefix: tt.prefix}
+
+			uploaded, err := destination.UploadExecution(context.Background(), "web", result, tt.logDir)
+			if tt.wantErr == "" && err != nil {
+				t.Fatalf("UploadExecution() error = %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Fatalf("Expected error %q, got %v", tt.wantErr, err)
+			}
+			if !reflect.DeepEqual(uploaded, tt.expected) {
+				t.Errorf("Expected uploads %v, got %v", tt.expected, uploaded)
+			}
+			if report := uploader.objects[tt.expected[0]]; !strings.Contains(report, `"id": "exec-1"`) {
+				t.Errorf("Expected the JSON report, got %q", report)
+			}
+		})
+	}
+}
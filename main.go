@@ -1,7 +1,19 @@
 package main
 
-import "github.com/cuongtl1992/grp-cli/cmd"
+import (
+	"github.com/cuongtl1992/grp-cli/cmd"
+
+	// Built-in plugins register themselves with the plugin manager
+	_ "github.com/cuongtl1992/grp-cli/plugins/docker"
+	_ "github.com/cuongtl1992/grp-cli/plugins/email"
+	_ "github.com/cuongtl1992/grp-cli/plugins/gate"
+	_ "github.com/cuongtl1992/grp-cli/plugins/http"
+	_ "github.com/cuongtl1992/grp-cli/plugins/kubernetes"
+	_ "github.com/cuongtl1992/grp-cli/plugins/publish"
+	_ "github.com/cuongtl1992/grp-cli/plugins/shell"
+	_ "github.com/cuongtl1992/grp-cli/plugins/terraform"
+)
 
 func main() {
 	cmd.Execute()
-} 
\ No newline at end of file
+}
@@ -0,0 +1,54 @@
+package plugin
+
+import "errors"
+
+// ErrNothingToRollBack is returned by Rollback, possibly wrapped, when the execution
+// left nothing the plugin can undo, such as a job configured without a compensating
+// action. grp-cli then reports the job as not rolled back rather than rolled back.
+// Added in plugin API 1.10.
+var ErrNothingToRollBack = errors.New("nothing to roll back")
+
+// RollbackChecker is implemented by plugins supporting rollback whose Rollback
+// only undoes the jobs configured with a compensating action, so that grp-cli can
+// tell before an execution which jobs a rollback would undo. Implementing it is
+// optional. Added in plugin API 1.11.
+type RollbackChecker interface {
+	// CanRollBack reports whether Rollback would undo a job executed with config
+	CanRollBack(config map[string]interface{}) bool
+}
+
+// Capabilities lists the optional features of a plugin, so that grp-cli knows what
+// it can rely on before using them
+type Capabilities struct {
+	// Rollback is set when Rollback undoes the changes made by Execute. Plugins whose
+	// Rollback does nothing leave it unset, and their jobs are not rolled back.
+	Rollback bool `json:"rollback"`
+	// Preview is set when the plugin implements Previewer
+	Preview bool `json:"preview"`
+	// Streaming is set when the plugin writes the output of its jobs to Output as
+	// they run
+	Streaming bool `json:"streaming"`
+}
+
+// CapabilityReporter is implemented by plugins that declare their capabilities.
+// Implementing it is optional. Added in plugin API 1.7.
+type CapabilityReporter interface {
+	// Capabilities returns the optional features the plugin supports
+	Capabilities() Capabilities
+}
+
+// CapabilitiesOf returns the capabilities of a plugin: those it declares if it
+// implements CapabilityReporter, and otherwise only Rollback, which every plugin
+// implements. Preview is always set from whether the plugin implements Previewer,
+// and Streaming is also set for plugins implementing StreamingPlugin.
+func CapabilitiesOf(plg Plugin) Capabilities {
+	capabilities := Capabilities{Rollback: true}
+	if reporter, ok := plg.(CapabilityReporter); ok {
+		capabilities = reporter.Capabilities()
+	}
+	_, capabilities.Preview = plg.(Previewer)
+	if _, ok := plg.(StreamingPlugin); ok {
+		capabilities.Streaming = true
+	}
+	return capabilities
+}
@@ -0,0 +1,70 @@
+package plugin
+
+import (
+	"context"
+	"io"
+	"testing"
+)
+
+// basicPlugin implements only the Plugin interface
+type basicPlugin struct{}
+
+func (basicPlugin) Name() string              { return "basic" }
+func (basicPlugin) Description() string       { return "Basic plugin" }
+func (basicPlugin) Version() string           { return "1.0.0" }
+func (basicPlugin) ConfigSchema() *JSONSchema { return nil }
+func (basicPlugin) Validate(ctx context.Context, config map[string]interface{}) error {
+	return nil
+}
+func (basicPlugin) Execute(ctx context.Context, config map[string]interface{}) (*Result, error) {
+	return &Result{Success: true}, nil
+}
+func (basicPlugin) Rollback(ctx context.Context, executionID string) error { return nil }
+
+// reportingPlugin declares its capabilities
+type reportingPlugin struct {
+	basicPlugin
+	capabilities Capabilities
+}
+
+func (p reportingPlugin) Capabilities() Capabilities { return p.capabilities }
+
+// previewingPlugin implements Previewer
+type previewingPlugin struct {
+	reportingPlugin
+}
+
+func (previewingPlugin) Preview(ctx context.Context, config map[string]interface{}) (string, error) {
+	return "", nil
+}
+
+// streamingPlugin implements StreamingPlugin
+type streamingPlugin struct {
+	basicPlugin
+}
+
+func (streamingPlugin) ExecuteStreaming(ctx context.Context, config map[string]interface{}, output io.Writer) (*Result, error) {
+	return &Result{Success: true}, nil
+}
+
+func TestCapabilitiesOf(t *testing.T) {
+	tests := []struct {
+		name     string
+		plugin   Plugin
+		expected Capabilities
+	}{
+		{name: "undeclared", plugin: basicPlugin{}, expected: Capabilities{Rollback: true}},
+		{name: "declared", plugin: reportingPlugin{capabilities: Capabilities{Streaming: true}}, expected: Capabilities{Streaming: true}},
+		{name: "preview detected", plugin: previewingPlugin{reportingPlugin{capabilities: Capabilities{Rollback: true}}}, expected: Capabilities{Rollback: true, Preview: true}},
+		{name: "preview not implemented", plugin: reportingPlugin{capabilities: Capabilities{Preview: true}}, expected: Capabilities{}},
+		{name: "streaming detected", plugin: streamingPlugin{}, expected: Capabilities{Rollback: true, Streaming: true}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := CapabilitiesOf(tt.plugin); got != tt.expected {
+				t.Errorf("Expected %+v, got %+v", tt.expected, got)
+			}
+		})
+	}
+}
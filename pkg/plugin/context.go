@@ -0,0 +1,109 @@
+package plugin
+
+import "context"
+
+// contextKey is the type of the keys of the values grp-cli passes to plugins in
+// their context, so that they cannot collide with the keys of other packages
+type contextKey string
+
+const (
+	executionIDKey    contextKey = "executionID"
+	variablesKey      contextKey = "variables"
+	stageNameKey      contextKey = "stageName"
+	outputKey         contextKey = "output"
+	idempotencyKeyKey contextKey = "idempotencyKey"
+	workingDirKey     contextKey = "workingDir"
+	jobNameKey        contextKey = "jobName"
+	attemptKey        contextKey = "attempt"
+	dryRunKey         contextKey = "dryRun"
+)
+
+// The plain string keys the values were set with before plugin API 1.5. They are
+// still set for plugins reading the context directly, e.g. ctx.Value("executionID").
+const (
+	legacyExecutionIDKey = "executionID"
+	legacyVariablesKey   = "variables"
+	legacyStageNameKey   = "stageName"
+)
+
+// WithExecutionID returns a copy of ctx carrying the ID of the plan execution, or
+// of the job execution once a job runs
+func WithExecutionID(ctx context.Context, executionID string) context.Context {
+	ctx = context.WithValue(ctx, executionIDKey, executionID)
+	return context.WithValue(ctx, legacyExecutionIDKey, executionID)
+}
+
+// ExecutionID returns the ID of the execution of the job executed with ctx, which
+// its Rollback is later called with: each job of a plan execution gets its own,
+// derived from the plan execution's. Outside of a job it is the ID of the plan
+// execution, or an empty string if there is none. Added in plugin API 1.5.
+func ExecutionID(ctx context.Context) string {
+	executionID, _ := ctx.Value(executionIDKey).(string)
+	return executionID
+}
+
+// WithVariables returns a copy of ctx carrying the variables of the plan
+func WithVariables(ctx context.Context, variables map[string]interface{}) context.Context {
+	ctx = context.WithValue(ctx, variablesKey, variables)
+	return context.WithValue(ctx, legacyVariablesKey, variables)
+}
+
+// Variables returns the variables of the plan executed with ctx, or nil if there
+// are none. Added in plugin API 1.5.
+func Variables(ctx context.Context) map[string]interface{} {
+	variables, _ := ctx.Value(variablesKey).(map[string]interface{})
+	return variables
+}
+
+// WithStageName returns a copy of ctx carrying the name of the running stage
+func WithStageName(ctx context.Context, stageName string) context.Context {
+	ctx = context.WithValue(ctx, stageNameKey, stageName)
+	return context.WithValue(ctx, legacyStageNameKey, stageName)
+}
+
+// StageName returns the name of the stage of the job executed with ctx, or an
+// empty string if there is none. Added in plugin API 1.5.
+func StageName(ctx context.Context) string {
+	stageName, _ := ctx.Value(stageNameKey).(string)
+	return stageName
+}
+
+// WithJobName returns a copy of ctx carrying the name of the running job
+func WithJobName(ctx context.Context, jobName string) context.Context {
+	return context.WithValue(ctx, jobNameKey, jobName)
+}
+
+// JobName returns the name of the job executed, validated, previewed or rolled
+// back with ctx, or an empty string if there is none. Added in plugin API 1.9.
+func JobName(ctx context.Context) string {
+	jobName, _ := ctx.Value(jobNameKey).(string)
+	return jobName
+}
+
+// WithAttempt returns a copy of ctx carrying the number of the running attempt
+// of a job, starting at 1
+func WithAttempt(ctx context.Context, attempt int) context.Context {
+	return context.WithValue(ctx, attemptKey, attempt)
+}
+
+// Attempt returns the number of the attempt of the job executed with ctx, 1 for
+// the first one and higher for its retries, or 0 outside of a job execution.
+// Added in plugin API 1.9.
+func Attempt(ctx context.Context) int {
+	attempt, _ := ctx.Value(attemptKey).(int)
+	return attempt
+}
+
+// WithDryRun returns a copy of ctx marking a dry run, in which jobs are validated
+// but not executed
+func WithDryRun(ctx context.Context, dryRun bool) context.Context {
+	return context.WithValue(ctx, dryRunKey, dryRun)
+}
+
+// DryRun reports whether ctx belongs to a dry run. Plugins whose Validate has side
+// effects, such as calling remote APIs, should skip them in dry runs. Added in
+// plugin API 1.9.
+func DryRun(ctx context.Context) bool {
+	dryRun, _ := ctx.Value(dryRunKey).(bool)
+	return dryRun
+}
@@ -0,0 +1,81 @@
+package plugin
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestContextValues(t *testing.T) {
+	variables := map[string]interface{}{"env": "prod"}
+
+	tests := []struct {
+		name            string
+		ctx             context.Context
+		wantExecutionID string
+		wantVariables   map[string]interface{}
+		wantStageName   string
+		wantJobName     string
+		wantAttempt     int
+		wantDryRun      bool
+	}{
+		{name: "empty", ctx: context.Background()},
+		{
+			name:            "set",
+			ctx:             WithStageName(WithVariables(WithExecutionID(context.Background(), "exec-1"), variables), "deploy"),
+			wantExecutionID: "exec-1",
+			wantVariables:   variables,
+			wantStageName:   "deploy",
+		},
+		{
+			name:        "job",
+			ctx:         WithDryRun(WithAttempt(WithJobName(context.Background(), "web"), 2), true),
+			wantJobName: "web",
+			wantAttempt: 2,
+			wantDryRun:  true,
+		},
+		{
+			// Values set with plain string keys of other types are not read, nor panic
+			name: "plain string keys",
+			ctx: context.WithValue(context.WithValue(context.WithValue(context.Background(),
+				"executionID", 1), "variables", "env=prod"), "stageName", []string{"deploy"}),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ExecutionID(tt.ctx); got != tt.wantExecutionID {
+				t.Errorf("ExecutionID() = %q, want %q", got, tt.wantExecutionID)
+			}
+			if got := Variables(tt.ctx); !reflect.DeepEqual(got, tt.wantVariables) {
+				t.Errorf("Variables() = %v, want %v", got, tt.wantVariables)
+			}
+			if got := StageName(tt.ctx); got != tt.wantStageName {
+				t.Errorf("StageName() = %q, want %q", got, tt.wantStageName)
+			}
+			if got := JobName(tt.ctx); got != tt.wantJobName {
+				t.Errorf("JobName() = %q, want %q", got, tt.wantJobName)
+			}
+			if got := Attempt(tt.ctx); got != tt.wantAttempt {
+				t.Errorf("Attempt() = %d, want %d", got, tt.wantAttempt)
+			}
+			if got := DryRun(tt.ctx); got != tt.wantDryRun {
+				t.Errorf("DryRun() = %v, want %v", got, tt.wantDryRun)
+			}
+		})
+	}
+}
+
+func TestContextValuesLegacyKeys(t *testing.T) {
+	ctx := WithStageName(WithVariables(WithExecutionID(context.Background(), "exec-1"), map[string]interface{}{"env": "prod"}), "deploy")
+
+	if executionID, _ := ctx.Value("executionID").(string); executionID != "exec-1" {
+		t.Errorf("Expected the execution ID under the plain string key, got %q", executionID)
+	}
+	if variables, _ := ctx.Value("variables").(map[string]interface{}); variables["env"] != "prod" {
+		t.Errorf("Expected the variables under the plain string key, got %v", variables)
+	}
+	if stageName, _ := ctx.Value("stageName").(string); stageName != "deploy" {
+		t.Errorf("Expected the stage name under the plain string key, got %q", stageName)
+	}
+}
@@ -0,0 +1,19 @@
+package plugin
+
+import "context"
+
+// WithIdempotencyKey returns a copy of ctx carrying the idempotency key of a job
+func WithIdempotencyKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, idempotencyKeyKey, key)
+}
+
+// IdempotencyKey returns the idempotency key of the job executed with ctx, or an
+// empty string if there is none (e.g. in dry-run mode). The key is the same for
+// every attempt of a job within an execution and differs between jobs and
+// executions, so plugins can pass it to the systems they call, such as in an
+// Idempotency-Key header, to avoid repeating the side effects of an attempt that
+// failed after taking effect. Added in plugin API 1.4.
+func IdempotencyKey(ctx context.Context) string {
+	key, _ := ctx.Value(idempotencyKeyKey).(string)
+	return key
+}
@@ -0,0 +1,19 @@
+package plugin
+
+import (
+	"context"
+	"io"
+)
+
+// WithOutput returns a copy of ctx whose job output is written to w
+func WithOutput(ctx context.Context, w io.Writer) context.Context {
+	return context.WithValue(ctx, outputKey, w)
+}
+
+// Output returns the writer capturing the output of the job executed with ctx,
+// such as the output of the commands a plugin runs, or nil if it is not
+// captured. Added in plugin API 1.1.
+func Output(ctx context.Context) io.Writer {
+	w, _ := ctx.Value(outputKey).(io.Writer)
+	return w
+}
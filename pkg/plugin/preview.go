@@ -0,0 +1,13 @@
+package plugin
+
+import "context"
+
+// Previewer is implemented by plugins that can show what a job would change
+// before it runs, such as the output of terraform plan or kubectl diff. The
+// preview is shown to the approvers of the job's stage. Implementing it is
+// optional. Added in plugin API 1.3.
+type Previewer interface {
+	// Preview returns a description of the changes Execute would make with
+	// config, or an empty string if there is nothing to preview
+	Preview(ctx context.Context, config map[string]interface{}) (string, error)
+}
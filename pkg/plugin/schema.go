@@ -0,0 +1,225 @@
+package plugin
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ConfigError lists every problem found while validating a config against a schema
+type ConfigError struct {
+	Problems []string
+}
+
+// Error returns all problems as a single message
+func (e *ConfigError) Error() string {
+	return "config does not match schema: " + strings.Join(e.Problems, "; ")
+}
+
+// ValidateConfig checks that config conforms to schema: the value types, the
+// required properties of objects and the items of arrays. A nil schema accepts
+// any config. The returned error is a *ConfigError.
+func ValidateConfig(schema *JSONSchema, config map[string]interface{}) error {
+	if schema == nil {
+		return nil
+	}
+
+	var problems []string
+	validateValue(schema, config, "", &problems)
+	if len(problems) > 0 {
+		return &ConfigError{Problems: problems}
+	}
+	return nil
+}
+
+// ApplyDefaults returns a copy of config in which the properties missing from it,
+// or from the objects it contains, are set to their default in schema. Values set
+// in config, even to null, are kept. A nil schema returns config unchanged.
+func ApplyDefaults(schema *JSONSchema, config map[string]interface{}) map[string]interface{} {
+	if schema == nil {
+		return config
+	}
+	if config == nil {
+		config = make(map[string]interface{})
+	}
+	return applyDefaults(schema, config).(map[string]interface{})
+}
+
+// applyDefaults returns value with the defaults of schema applied, copying the
+// objects and arrays it changes
+func applyDefaults(schema *JSONSchema, value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		if len(schema.Properties) == 0 {
+			return v
+		}
+		result := make(map[string]interface{}, len(v)+len(schema.Properties))
+		for name, propValue := range v {
+			result[name] = propValue
+		}
+		for name, property := range schema.Properties {
+			if propValue, ok := v[name]; ok {
+				result[name] = applyDefaults(property, propValue)
+			} else if property.Default != nil {
+				result[name] = copyValue(property.Default)
+			}
+		}
+		return result
+	case []interface{}:
+		if schema.Items == nil {
+			return v
+		}
+		result := make([]interface{}, len(v))
+		for i, item := range v {
+			result[i] = applyDefaults(schema.Items, item)
+		}
+		return result
+	}
+	return value
+}
+
+// copyValue deep-copies the objects and arrays of a default, so configs never
+// share them with the schema
+func copyValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		copied := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			copied[key] = copyValue(item)
+		}
+		return copied
+	case []interface{}:
+		copied := make([]interface{}, len(v))
+		for i, item := range v {
+			copied[i] = copyValue(item)
+		}
+		return copied
+	}
+	return value
+}
+
+// validateValue appends a problem for every mismatch between value and schema
+func validateValue(schema *JSONSchema, value interface{}, path string, problems *[]string) {
+	if schema.Type != "" && !matchesType(schema.Type, value) {
+		*problems = append(*problems, fmt.Sprintf("%s must be %s, got %s", describePath(path), schema.Type, typeName(value)))
+		return
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for _, name := range schema.Required {
+			if _, ok := v[name]; !ok {
+				*problems = append(*problems, fmt.Sprintf("missing required field %s", describePath(joinPath(path, name))))
+			}
+		}
+
+		// Reject undeclared properties when the schema forbids them
+		if schema.AdditionalProperties != nil && !*schema.AdditionalProperties {
+			var unknown []string
+			for name := range v {
+				if _, ok := schema.Properties[name]; !ok {
+					unknown = append(unknown, name)
+				}
+			}
+			sort.Strings(unknown)
+			for _, name := range unknown {
+				*problems = append(*problems, fmt.Sprintf("unknown field %s", describePath(joinPath(path, name))))
+			}
+		}
+
+		// Check properties in a stable order so messages are deterministic
+		names := make([]string, 0, len(schema.Properties))
+		for name := range schema.Properties {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			if propValue, ok := v[name]; ok && propValue != nil {
+				validateValue(schema.Properties[name], propValue, joinPath(path, name), problems)
+			}
+		}
+	case []interface{}:
+		if schema.Items == nil {
+			return
+		}
+		for i, item := range v {
+			validateValue(schema.Items, item, fmt.Sprintf("%s[%d]", path, i), problems)
+		}
+	}
+}
+
+// matchesType returns true if value has the given JSON schema type
+func matchesType(schemaType string, value interface{}) bool {
+	switch schemaType {
+	case "object":
+		_, ok := value.(map[string]interface{})
+		return ok
+	case "array":
+		_, ok := value.([]interface{})
+		return ok
+	case "string":
+		_, ok := value.(string)
+		return ok
+	case "boolean":
+		_, ok := value.(bool)
+		return ok
+	case "integer":
+		switch v := value.(type) {
+		case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+			return true
+		case float64:
+			return v == float64(int64(v))
+		}
+		return false
+	case "number":
+		switch value.(type) {
+		case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+			return true
+		}
+		return false
+	case "null":
+		return value == nil
+	default:
+		// Unknown types are not enforced
+		return true
+	}
+}
+
+// typeName returns the JSON schema type name of a Go value
+func typeName(value interface{}) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case map[string]interface{}:
+		return "object"
+	case []interface{}:
+		return "array"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		return "integer"
+	case float32, float64:
+		return "number"
+	default:
+		return fmt.Sprintf("%T", value)
+	}
+}
+
+// joinPath appends a property name to a dot-separated path
+func joinPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+// describePath formats a path for messages, naming the root config when empty
+func describePath(path string) string {
+	if path == "" {
+		return "config"
+	}
+	return fmt.Sprintf("%q", path)
+}
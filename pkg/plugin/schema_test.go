@@ -0,0 +1,183 @@
+package plugin
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestValidateConfig(t *testing.T) {
+	schema := &JSONSchema{
+		Type: "object",
+		Properties: map[string]*JSONSchema{
+			"namespace": {Type: "string"},
+			"replicas":  {Type: "integer"},
+			"wait":      {Type: "boolean"},
+			"ports":     {Type: "array", Items: &JSONSchema{Type: "integer"}},
+			"labels": {
+				Type:       "object",
+				Properties: map[string]*JSONSchema{"app": {Type: "string"}},
+				Required:   []string{"app"},
+			},
+		},
+		Required: []string{"namespace"},
+	}
+
+	tests := []struct {
+		name             string
+		config           map[string]interface{}
+		expectedProblems []string
+	}{
+		{
+			name: "valid config",
+			config: map[string]interface{}{
+				"namespace": "default",
+				"replicas":  3,
+				"wait":      true,
+				"ports":     []interface{}{80, 443},
+				"labels":    map[string]interface{}{"app": "web"},
+				"extra":     "ignored",
+			},
+		},
+		{
+			name:             "missing required field",
+			config:           map[string]interface{}{},
+			expectedProblems: []string{`missing required field "namespace"`},
+		},
+		{
+			name: "wrong types",
+			config: map[string]interface{}{
+				"namespace": 42,
+				"wait":      "yes",
+				"ports":     []interface{}{80, "https"},
+				"labels":    map[string]interface{}{},
+			},
+			expectedProblems: []string{
+				`missing required field "labels.app"`,
+				`"namespace" must be string, got integer`,
+				`"ports[1]" must be integer, got string`,
+				`"wait" must be boolean, got string`,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateConfig(schema, tt.config)
+			if len(tt.expectedProblems) == 0 {
+				if err != nil {
+					t.Errorf("Expected no error, got %v", err)
+				}
+				return
+			}
+
+			var configErr *ConfigError
+			if !errors.As(err, &configErr) {
+				t.Fatalf("Expected *ConfigError, got %v", err)
+			}
+			if strings.Join(configErr.Problems, "\n") != strings.Join(tt.expectedProblems, "\n") {
+				t.Errorf("Expected problems %v, got %v", tt.expectedProblems, configErr.Problems)
+			}
+		})
+	}
+}
+
+func TestValidateConfigNilSchema(t *testing.T) {
+	if err := ValidateConfig(nil, map[string]interface{}{"any": "thing"}); err != nil {
+		t.Errorf("Expected nil schema to accept any config, got %v", err)
+	}
+}
+
+func TestValidateConfigAdditionalProperties(t *testing.T) {
+	closed := false
+	schema := &JSONSchema{
+		Type:                 "object",
+		Properties:           map[string]*JSONSchema{"namespace": {Type: "string"}},
+		AdditionalProperties: &closed,
+	}
+
+	err := ValidateConfig(schema, map[string]interface{}{"namespace": "default", "replcias": 3, "extra": true})
+	var configErr *ConfigError
+	if !errors.As(err, &configErr) {
+		t.Fatalf("Expected *ConfigError, got %v", err)
+	}
+	expected := []string{`unknown field "extra"`, `unknown field "replcias"`}
+	if strings.Join(configErr.Problems, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Expected problems %v, got %v", expected, configErr.Problems)
+	}
+}
+
+func TestApplyDefaults(t *testing.T) {
+	schema := &JSONSchema{
+		Type: "object",
+		Properties: map[string]*JSONSchema{
+			"namespace": {Type: "string", Default: "default"},
+			"replicas":  {Type: "integer", Default: 1},
+			"labels":    {Type: "object", Default: map[string]interface{}{"team": "platform"}},
+			"target": {
+				Type:       "object",
+				Properties: map[string]*JSONSchema{"port": {Type: "integer", Default: 80}},
+			},
+			"checks": {
+				Type: "array",
+				Items: &JSONSchema{
+					Type:       "object",
+					Properties: map[string]*JSONSchema{"method": {Type: "string", Default: "GET"}},
+				},
+			},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		config   map[string]interface{}
+		expected map[string]interface{}
+	}{
+		{
+			name:   "missing properties",
+			config: nil,
+			expected: map[string]interface{}{
+				"namespace": "default", "replicas": 1, "labels": map[string]interface{}{"team": "platform"},
+			},
+		},
+		{
+			name:   "explicit values win",
+			config: map[string]interface{}{"namespace": "prod", "replicas": 0, "labels": nil},
+			expected: map[string]interface{}{
+				"namespace": "prod", "replicas": 0, "labels": nil,
+			},
+		},
+		{
+			name: "nested objects and array items",
+			config: map[string]interface{}{
+				"target": map[string]interface{}{},
+				"checks": []interface{}{map[string]interface{}{"method": "POST"}, map[string]interface{}{}},
+			},
+			expected: map[string]interface{}{
+				"namespace": "default", "replicas": 1, "labels": map[string]interface{}{"team": "platform"},
+				"target": map[string]interface{}{"port": 80},
+				"checks": []interface{}{map[string]interface{}{"method": "POST"}, map[string]interface{}{"method": "GET"}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ApplyDefaults(schema, tt.config); !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("ApplyDefaults() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+
+	// The config and the schema's defaults are left untouched
+	config := map[string]interface{}{"target": map[string]interface{}{}}
+	applied := ApplyDefaults(schema, config)
+	applied["labels"].(map[string]interface{})["team"] = "changed"
+	if len(config["target"].(map[string]interface{})) != 0 || len(config) != 1 {
+		t.Errorf("Expected the config to be left untouched, got %v", config)
+	}
+	if team := schema.Properties["labels"].Default.(map[string]interface{})["team"]; team != "platform" {
+		t.Errorf("Expected the default to be copied, got team %v", team)
+	}
+}
@@ -0,0 +1,19 @@
+package plugin
+
+import (
+	"context"
+	"io"
+)
+
+// StreamingPlugin is implemented by plugins that write the output of their jobs
+// as they run, such as long builds and deploys, rather than returning it all in
+// the result. grp-cli calls ExecuteStreaming instead of Execute for such plugins,
+// so Execute only needs to serve callers that do not stream. Implementing it is
+// optional. Added in plugin API 1.8.
+type StreamingPlugin interface {
+	Plugin
+	// ExecuteStreaming executes the plugin like Execute, writing the output of
+	// the job to output as it is produced. output is safe to write to from
+	// several goroutines and is never nil.
+	ExecuteStreaming(ctx context.Context, config map[string]interface{}, output io.Writer) (*Result, error)
+}
@@ -28,12 +28,16 @@ type Plugin interface {
 	Rollback(ctx context.Context, executionID string) error
 }
 
-// JSONSchema defines a simple JSON schema for config validation
+// JSONSchema defines a simple JSON schema for config validation. Objects accept
+// properties they do not declare unless AdditionalProperties is set to false.
+// Default is the value of a property missing from the config.
 type JSONSchema struct {
-	Type       string                 `json:"type"`
-	Properties map[string]*JSONSchema `json:"properties,omitempty"`
-	Required   []string               `json:"required,omitempty"`
-	Items      *JSONSchema            `json:"items,omitempty"`
+	Type                 string                 `json:"type"`
+	Properties           map[string]*JSONSchema `json:"properties,omitempty"`
+	Required             []string               `json:"required,omitempty"`
+	Items                *JSONSchema            `json:"items,omitempty"`
+	AdditionalProperties *bool                  `json:"additionalProperties,omitempty"`
+	Default              interface{}            `json:"default,omitempty"`
 }
 
 // Result represents the outcome of a plugin execution
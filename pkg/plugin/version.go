@@ -0,0 +1,49 @@
+package plugin
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// APIVersion is the version of the plugin API implemented by this grp-cli build, as
+// MAJOR.MINOR. The major version changes when the Plugin interface or the types it
+// uses change incompatibly; the minor version when backwards-compatible additions
+// are made. Plugins declare the version they were built against by exporting:
+//
+//	var APIVersion = plugin.APIVersion
+const APIVersion = "1.11"
+
+// CheckAPIVersion returns an error if a plugin built against the given API version
+// cannot be used by this build: the major versions must match, and the plugin must
+// not rely on a newer minor version than this build provides
+func CheckAPIVersion(version string) error {
+	major, minor, err := parseAPIVersion(version)
+	if err != nil {
+		return err
+	}
+	hostMajor, hostMinor, _ := parseAPIVersion(APIVersion)
+
+	if major != hostMajor || minor > hostMinor {
+		return fmt.Errorf("plugin API version %s is not compatible with this grp-cli (plugin API %s)", version, APIVersion)
+	}
+	return nil
+}
+
+// parseAPIVersion splits a MAJOR.MINOR version into its numbers
+func parseAPIVersion(version string) (int, int, error) {
+	majorText, minorText, found := strings.Cut(version, ".")
+	if !found {
+		return 0, 0, fmt.Errorf("invalid plugin API version %q (expected MAJOR.MINOR)", version)
+	}
+
+	major, err := strconv.Atoi(majorText)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid plugin API version %q (expected MAJOR.MINOR)", version)
+	}
+	minor, err := strconv.Atoi(minorText)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid plugin API version %q (expected MAJOR.MINOR)", version)
+	}
+	return major, minor, nil
+}
@@ -0,0 +1,47 @@
+package plugin
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCheckAPIVersion(t *testing.T) {
+	tests := []struct {
+		version string
+		wantErr string
+	}{
+		{version: APIVersion},
+		{version: "1.0"},
+		{version: "1.1"},
+		{version: "1.2"},
+		{version: "1.3"},
+		{version: "1.4"},
+		{version: "1.5"},
+		{version: "1.6"},
+		{version: "1.7"},
+		{version: "1.8"},
+		{version: "1.9"},
+		{version: "1.10"},
+		{version: "1.11"},
+		{version: "1.12", wantErr: "not compatible"},
+		{version: "2.0", wantErr: "not compatible"},
+		{version: "0.9", wantErr: "not compatible"},
+		{version: "1", wantErr: "invalid plugin API version"},
+		{version: "one.zero", wantErr: "invalid plugin API version"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.version, func(t *testing.T) {
+			err := CheckAPIVersion(tt.version)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("CheckAPIVersion(%q) error = %v", tt.version, err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("CheckAPIVersion(%q) error = %v, want %q", tt.version, err, tt.wantErr)
+			}
+		})
+	}
+}
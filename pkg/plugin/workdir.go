@@ -0,0 +1,32 @@
+package plugin
+
+import (
+	"context"
+	"path/filepath"
+)
+
+// WithWorkingDir returns a copy of ctx carrying the absolute working directory of
+// the plan execution
+func WithWorkingDir(ctx context.Context, dir string) context.Context {
+	return context.WithValue(ctx, workingDirKey, dir)
+}
+
+// WorkingDir returns the absolute working directory of the plan execution running
+// the job executed with ctx, or an empty string if there is none. It defaults to
+// the directory of the plan file. Added in plugin API 1.6.
+func WorkingDir(ctx context.Context) string {
+	dir, _ := ctx.Value(workingDirKey).(string)
+	return dir
+}
+
+// ResolvePath roots a path from a job config at the working directory of the
+// execution: a relative path is joined to it and an empty path is the working
+// directory itself. Absolute paths, and any path when there is no working
+// directory, are returned unchanged. Added in plugin API 1.6.
+func ResolvePath(ctx context.Context, path string) string {
+	dir := WorkingDir(ctx)
+	if dir == "" || filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(dir, path)
+}
@@ -0,0 +1,33 @@
+package plugin
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+)
+
+func TestResolvePath(t *testing.T) {
+	dir := filepath.Join(string(filepath.Separator), "releases", "web")
+	absolute := filepath.Join(string(filepath.Separator), "etc", "app")
+
+	tests := []struct {
+		name     string
+		ctx      context.Context
+		path     string
+		expected string
+	}{
+		{name: "relative path", ctx: WithWorkingDir(context.Background(), dir), path: "infra/main", expected: filepath.Join(dir, "infra", "main")},
+		{name: "parent path", ctx: WithWorkingDir(context.Background(), dir), path: "../shared", expected: filepath.Join(string(filepath.Separator), "releases", "shared")},
+		{name: "empty path", ctx: WithWorkingDir(context.Background(), dir), expected: dir},
+		{name: "absolute path", ctx: WithWorkingDir(context.Background(), dir), path: absolute, expected: absolute},
+		{name: "no working directory", ctx: context.Background(), path: "infra/main", expected: "infra/main"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ResolvePath(tt.ctx, tt.path); got != tt.expected {
+				t.Errorf("ResolvePath(%q) = %q, want %q", tt.path, got, tt.expected)
+			}
+		})
+	}
+}
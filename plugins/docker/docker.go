@@ -0,0 +1,447 @@
+package docker
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cuongtl1992/grp-cli/internal/plugins"
+	"github.com/cuongtl1992/grp-cli/pkg/plugin"
+)
+
+// Supported actions
+const (
+	actionBuild = "build"
+	actionPush  = "push"
+	actionTag   = "tag"
+)
+
+// registryTimeout bounds registry API calls made during rollback
+const registryTimeout = 30 * time.Second
+
+// digestRegex extracts the manifest digest from `docker push` output
+var digestRegex = regexp.MustCompile(`digest: (sha256:[0-9a-f]{64})`)
+
+// commandRunner runs the docker CLI with the given stdin and arguments, streaming
+// output to progress, and returns the combined output
+type commandRunner func(ctx context.Context, progress io.Writer, stdin string, args ...string) (string, error)
+
+// DockerPlugin implements the Plugin interface for building, tagging and pushing images
+type DockerPlugin struct {
+	mutex     sync.Mutex
+	rollbacks map[string][]undo
+	// runner invokes the docker CLI; nil uses the docker binary on PATH
+	runner commandRunner
+	// progress receives the docker CLI output as it runs; nil uses the job output,
+	// or stderr if it is not captured
+	progress io.Writer
+}
+
+// Plugin is the instance registered as a built-in plugin
+var Plugin DockerPlugin
+
+func init() {
+	plugins.RegisterBuiltin(&Plugin)
+}
+
+// registryAuth holds the credentials used to log in to a registry
+type registryAuth struct {
+	server   string
+	username string
+	password string
+}
+
+// job describes a docker job built from config
+type job struct {
+	action           string
+	image            string
+	tags             []string
+	source           string
+	dockerfile       string
+	context          string
+	buildArgs        map[string]string
+	auth             *registryAuth
+	removeOnRollback bool
+}
+
+// undo records how to revert a tag created or pushed by a job
+type undo struct {
+	ref    string
+	digest string
+	remote bool
+	auth   *registryAuth
+}
+
+// Name returns the plugin name
+func (p *DockerPlugin) Name() string {
+	return "docker"
+}
+
+// Description returns the plugin description
+func (p *DockerPlugin) Description() string {
+	return "Builds, tags and pushes Docker images"
+}
+
+// Version returns the plugin version
+func (p *DockerPlugin) Version() string {
+	return "0.1.0"
+}
+
+// ConfigSchema returns the JSON schema for config validation
+func (p *DockerPlugin) ConfigSchema() *plugin.JSONSchema {
+	return &plugin.JSONSchema{
+		Type: "object",
+		Properties: map[string]*plugin.JSONSchema{
+			"action":     {Type: "string"},
+			"image":      {Type: "string"},
+			"tags":       {Type: "array", Items: &plugin.JSONSchema{Type: "string"}},
+			"source":     {Type: "string"},
+			"dockerfile": {Type: "string"},
+			"context":    {Type: "string"},
+			"buildArgs":  {Type: "object"},
+			"registry": {
+				Type: "object",
+				Properties: map[string]*plugin.JSONSchema{
+					"server":   {Type: "string"},
+					"username": {Type: "string"},
+					"password": {Type: "string"},
+				},
+				Required: []string{"username", "password"},
+			},
+			"removeOnRollback": {Type: "boolean"},
+		},
+		Required: []string{"action", "image"},
+	}
+}
+
+// Validate checks if the configuration is valid
+func (p *DockerPlugin) Validate(ctx context.Context, config map[string]interface{}) error {
+	_, err := parseJob(config)
+	return err
+}
+
+// Execute runs the configured docker action
+func (p *DockerPlugin) Execute(ctx context.Context, config map[string]interface{}) (*plugin.Result, error) {
+	executionID := plugin.ExecutionID(ctx)
+
+	j, err := parseJob(config)
+	if err != nil {
+		return nil, err
+	}
+
+	if j.auth != nil {
+		args := []string{"login", "--username", j.auth.username, "--password-stdin"}
+		if j.auth.server != "" {
+			args = append(args, j.auth.server)
+		}
+		if _, err := p.run(ctx, j.auth.password, args...); err != nil {
+			return failure(executionID, "docker login failed", err), nil
+		}
+	}
+
+	data := map[string]interface{}{"image": j.image, "refs": j.refs()}
+	var undos []undo
+
+	switch j.action {
+	case actionBuild:
+		args := []string{"build", "--file", plugin.ResolvePath(ctx, j.dockerfile)}
+		for _, ref := range j.refs() {
+			args = append(args, "--tag", ref)
+		}
+		for name, value := range j.buildArgs {
+			args = append(args, "--build-arg", name+"="+value)
+		}
+		if _, err := p.run(ctx, "", append(args, plugin.ResolvePath(ctx, j.context))...); err != nil {
+			return failure(executionID, "docker build failed", err), nil
+		}
+
+		imageID, err := p.run(ctx, "", "image", "inspect", "--format", "{{.Id}}", j.refs()[0])
+		if err != nil {
+			return failure(executionID, "docker image inspect failed", err), nil
+		}
+		data["imageId"] = strings.TrimSpace(imageID)
+		for _, ref := range j.refs() {
+			undos = append(undos, undo{ref: ref})
+		}
+
+	case actionTag:
+		for _, ref := range j.refs() {
+			if _, err := p.run(ctx, "", "tag", j.source, ref); err != nil {
+				return failure(executionID, "docker tag failed", err), nil
+			}
+			undos = append(undos, undo{ref: ref})
+		}
+
+	case actionPush:
+		digests := make(map[string]interface{})
+		for _, ref := range j.refs() {
+			output, err := p.run(ctx, "", "push", ref)
+			if err != nil {
+				return failure(executionID, "docker push failed", err), nil
+			}
+
+			digest := ""
+			if match := digestRegex.FindStringSubmatch(output); match != nil {
+				digest = match[1]
+			}
+			digests[ref] = digest
+			data["digest"] = digest
+			undos = append(undos, undo{ref: ref, digest: digest, remote: true, auth: j.auth})
+		}
+		data["digests"] = digests
+	}
+
+	// Remember the tags to remove if the release is rolled back
+	if j.removeOnRollback {
+		p.mutex.Lock()
+		if p.rollbacks == nil {
+			p.rollbacks = make(map[string][]undo)
+		}
+		p.rollbacks[executionID] = append(p.rollbacks[executionID], undos...)
+		p.mutex.Unlock()
+	}
+
+	return &plugin.Result{
+		Success:     true,
+		Message:     fmt.Sprintf("docker %s of %s completed", j.action, strings.Join(j.refs(), ", ")),
+		ExecutionID: executionID,
+		Data:        data,
+	}, nil
+}
+
+// Capabilities reports that tags can be removed on rollback and that the docker
+// output is streamed
+func (p *DockerPlugin) Capabilities() plugin.Capabilities {
+	return plugin.Capabilities{Rollback: true, Streaming: true}
+}
+
+// CanRollBack reports whether the job removes its tags on rollback
+func (p *DockerPlugin) CanRollBack(config map[string]interface{}) bool {
+	removeOnRollback, _ := config["removeOnRollback"].(bool)
+	return removeOnRollback
+}
+
+// Rollback removes the tags recorded for the execution, most recent first:
+// local tags with `docker rmi`, pushed tags by deleting their manifest from the registry
+func (p *DockerPlugin) Rollback(ctx context.Context, executionID string) error {
+	p.mutex.Lock()
+	undos := p.rollbacks[executionID]
+	delete(p.rollbacks, executionID)
+	p.mutex.Unlock()
+	if len(undos) == 0 {
+		return plugin.ErrNothingToRollBack
+	}
+
+	var errs []string
+	for i := len(undos) - 1; i >= 0; i-- {
+		u := undos[i]
+		var err error
+		if u.remote {
+			err = deleteManifest(ctx, u)
+		} else {
+			_, err = p.run(ctx, "", "rmi", u.ref)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", u.ref, err))
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("failed to remove tags: %s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// refs returns the image references the job operates on: one per tag, or the image itself
+func (j job) refs() []string {
+	if len(j.tags) == 0 {
+		return []string{j.image}
+	}
+
+	refs := make([]string, len(j.tags))
+	for i, tag := range j.tags {
+		refs[i] = j.image + ":" + tag
+	}
+	return refs
+}
+
+// parseJob builds a job from config
+func parseJob(config map[string]interface{}) (job, error) {
+	j := job{dockerfile: "Dockerfile", context: "."}
+
+	j.action, _ = config["action"].(string)
+	switch j.action {
+	case actionBuild, actionPush, actionTag:
+	case "":
+		return j, fmt.Errorf("missing required field: action")
+	default:
+		return j, fmt.Errorf("unsupported action %q (expected build, push or tag)", j.action)
+	}
+
+	j.image, _ = config["image"].(string)
+	if j.image == "" {
+		return j, fmt.Errorf("missing required field: image")
+	}
+
+	if tags, ok := config["tags"].([]interface{}); ok {
+		for _, tag := range tags {
+			j.tags = append(j.tags, fmt.Sprintf("%v", tag))
+		}
+	}
+
+	if j.action == actionTag {
+		j.source, _ = config["source"].(string)
+		if j.source == "" {
+			return j, fmt.Errorf("missing required field for tag action: source")
+		}
+	}
+
+	if dockerfile, ok := config["dockerfile"].(string); ok && dockerfile != "" {
+		j.dockerfile = dockerfile
+	}
+	if buildContext, ok := config["context"].(string); ok && buildContext != "" {
+		j.context = buildContext
+	}
+
+	if buildArgs, ok := config["buildArgs"].(map[string]interface{}); ok {
+		j.buildArgs = make(map[string]string)
+		for name, value := range buildArgs {
+			j.buildArgs[name] = fmt.Sprintf("%v", value)
+		}
+	}
+
+	if registry, ok := config["registry"].(map[string]interface{}); ok {
+		j.auth = &registryAuth{}
+		j.auth.server, _ = registry["server"].(string)
+		j.auth.username, _ = registry["username"].(string)
+		j.auth.password, _ = registry["password"].(string)
+		if j.auth.username == "" || j.auth.password == "" {
+			return j, fmt.Errorf("registry requires username and password")
+		}
+	}
+
+	j.removeOnRollback, _ = config["removeOnRollback"].(bool)
+
+	return j, nil
+}
+
+// run invokes the docker CLI, streaming its output to the progress writer
+func (p *DockerPlugin) run(ctx context.Context, stdin string, args ...string) (string, error) {
+	progress := p.progress
+	if progress == nil {
+		progress = plugin.Output(ctx)
+	}
+	if progress == nil {
+		progress = os.Stderr
+	}
+
+	runner := p.runner
+	if runner == nil {
+		runner = runDocker
+	}
+	return runner(ctx, progress, stdin, args...)
+}
+
+// runDocker runs the docker binary, copying each output line to progress
+func runDocker(ctx context.Context, progress io.Writer, stdin string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "docker", args...)
+	cmd.Stdin = strings.NewReader(stdin)
+
+	reader, writer := io.Pipe()
+	cmd.Stdout = writer
+	cmd.Stderr = writer
+
+	var output bytes.Buffer
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		scanner := bufio.NewScanner(reader)
+		for scanner.Scan() {
+			output.WriteString(scanner.Text() + "\n")
+			fmt.Fprintf(progress, "[docker] %s\n", scanner.Text())
+		}
+		// Drain the pipe if a line was too long to scan
+		io.Copy(io.Discard, reader)
+	}()
+
+	err := cmd.Run()
+	writer.Close()
+	<-done
+
+	if err != nil {
+		err = fmt.Errorf("docker %s: %w", args[0], err)
+		if line := plugins.LastLine(output.String()); line != "" {
+			err = fmt.Errorf("%w: %s", err, line)
+		}
+		return output.String(), err
+	}
+	return output.String(), nil
+}
+
+// failure builds an unsuccessful result for a failed docker command
+func failure(executionID, message string, err error) *plugin.Result {
+	return &plugin.Result{
+		Success:     false,
+		Message:     fmt.Sprintf("%s: %v", message, err),
+		ExecutionID: executionID,
+	}
+}
+
+// deleteManifest removes a pushed image from its registry using the registry HTTP API
+func deleteManifest(ctx context.Context, u undo) error {
+	if u.digest == "" {
+		return fmt.Errorf("digest of pushed image is unknown")
+	}
+
+	host, repository := splitReference(u.ref)
+	url := fmt.Sprintf("https://%s/v2/%s/manifests/%s", host, repository, u.digest)
+
+	ctx, cancel := context.WithTimeout(ctx, registryTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, url, nil)
+	if err != nil {
+		return err
+	}
+	if u.auth != nil {
+		req.SetBasicAuth(u.auth.username, u.auth.password)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusAccepted && resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("registry returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// splitReference splits an image reference into its registry host and repository,
+// defaulting to Docker Hub as the docker CLI does
+func splitReference(ref string) (string, string) {
+	// Strip the tag, taking care not to confuse it with a registry port
+	if i := strings.LastIndex(ref, ":"); i > strings.LastIndex(ref, "/") {
+		ref = ref[:i]
+	}
+
+	parts := strings.SplitN(ref, "/", 2)
+	if len(parts) == 2 && (strings.ContainsAny(parts[0], ".:") || parts[0] == "localhost") {
+		return parts[0], parts[1]
+	}
+	if len(parts) == 1 {
+		return "registry-1.docker.io", "library/" + ref
+	}
+	return "registry-1.docker.io", ref
+}
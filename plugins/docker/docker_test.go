@@ -0,0 +1,189 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/cuongtl1992/grp-cli/pkg/plugin"
+)
+
+// fakeDocker records docker invocations and returns canned output per subcommand
+type fakeDocker struct {
+	calls  []string
+	stdin  []string
+	output map[string]string
+	fail   string
+}
+
+func (f *fakeDocker) run(ctx context.Context, progress io.Writer, stdin string, args ...string) (string, error) {
+	f.calls = append(f.calls, strings.Join(args, " "))
+	f.stdin = append(f.stdin, stdin)
+	if args[0] == f.fail {
+		return "", fmt.Errorf("docker %s: exit status 1", args[0])
+	}
+	return f.output[args[0]], nil
+}
+
+func TestExecute(t *testing.T) {
+	digest := "sha256:" + strings.Repeat("ab", 32)
+
+	tests := []struct {
+		name          string
+		config        map[string]interface{}
+		fail          string
+		expectedCalls []string
+		expectedData  map[string]interface{}
+		wantSuccess   bool
+	}{
+		{
+			name: "build",
+			config: map[string]interface{}{
+				"action": "build", "image": "registry.example.com/app", "tags": []interface{}{"v1"},
+				"context": "./app", "buildArgs": map[string]interface{}{"VERSION": "1"},
+			},
+			expectedCalls: []string{
+				"build --file Dockerfile --tag registry.example.com/app:v1 --build-arg VERSION=1 ./app",
+				"image inspect --format {{.Id}} registry.example.com/app:v1",
+			},
+			expectedData: map[string]interface{}{"imageId": "sha256:1234"},
+			wantSuccess:  true,
+		},
+		{
+			name: "push with login",
+			config: map[string]interface{}{
+				"action": "push", "image": "registry.example.com/app", "tags": []interface{}{"v1"},
+				"registry": map[string]interface{}{"server": "registry.example.com", "username": "ci", "password": "secret"},
+			},
+			expectedCalls: []string{
+				"login --username ci --password-stdin registry.example.com",
+				"push registry.example.com/app:v1",
+			},
+			expectedData: map[string]interface{}{"digest": digest},
+			wantSuccess:  true,
+		},
+		{
+			name:          "tag",
+			config:        map[string]interface{}{"action": "tag", "image": "app", "source": "app:build", "tags": []interface{}{"latest"}},
+			expectedCalls: []string{"tag app:build app:latest"},
+			wantSuccess:   true,
+		},
+		{
+			name:          "failed build",
+			config:        map[string]interface{}{"action": "build", "image": "app"},
+			fail:          "build",
+			expectedCalls: []string{"build --file Dockerfile --tag app ."},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			docker := &fakeDocker{
+				fail: tt.fail,
+				output: map[string]string{
+					"image": "sha256:1234\n",
+					"push":  "v1: digest: " + digest + " size: 528\n",
+				},
+			}
+			plg := &DockerPlugin{runner: docker.run, progress: io.Discard}
+
+			result, err := plg.Execute(context.Background(), tt.config)
+			if err != nil {
+				t.Fatalf("Execute() error = %v", err)
+			}
+			if result.Success != tt.wantSuccess {
+				t.Errorf("Expected success=%v, got %v (%s)", tt.wantSuccess, result.Success, result.Message)
+			}
+			if strings.Join(docker.calls, "\n") != strings.Join(tt.expectedCalls, "\n") {
+				t.Errorf("Expected docker calls:\n%s\ngot:\n%s", strings.Join(tt.expectedCalls, "\n"), strings.Join(docker.calls, "\n"))
+			}
+			for key, value := range tt.expectedData {
+				if result.Data[key] != value {
+					t.Errorf("Expected data %s=%v, got %v", key, value, result.Data[key])
+				}
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  map[string]interface{}
+		wantErr bool
+	}{
+		{name: "valid", config: map[string]interface{}{"action": "push", "image": "app"}},
+		{name: "missing action", config: map[string]interface{}{"image": "app"}, wantErr: true},
+		{name: "missing image", config: map[string]interface{}{"action": "build"}, wantErr: true},
+		{name: "unknown action", config: map[string]interface{}{"action": "run", "image": "app"}, wantErr: true},
+		{name: "tag without source", config: map[string]interface{}{"action": "tag", "image": "app"}, wantErr: true},
+		{name: "registry without password", config: map[string]interface{}{"action": "push", "image": "app", "registry": map[string]interface{}{"username": "ci"}}, wantErr: true},
+	}
+
+	plg := &DockerPlugin{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := plg.Validate(context.Background(), tt.config); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRollbackRemovesLocalTags(t *testing.T) {
+	docker := &fakeDocker{}
+	plg := &DockerPlugin{runner: docker.run, progress: io.Discard}
+	ctx := plugin.WithExecutionID(context.Background(), "exec-1")
+
+	config := map[string]interface{}{"action": "tag", "image": "app", "source": "app:build", "tags": []interface{}{"v1", "latest"}, "removeOnRollback": true}
+	if _, err := plg.Execute(ctx, config); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+
+	docker.calls = nil
+	if err := plg.Rollback(ctx, "exec-1"); err != nil {
+		t.Fatalf("Rollback() error = %v", err)
+	}
+	if strings.Join(docker.calls, "\n") != "rmi app:latest\nrmi app:v1" {
+		t.Errorf("Expected tags to be removed in reverse order, got %v", docker.calls)
+	}
+}
+
+func TestSplitReference(t *testing.T) {
+	tests := []struct {
+		ref, host, repository string
+	}{
+		{"nginx:1.25", "registry-1.docker.io", "library/nginx"},
+		{"team/app:v1", "registry-1.docker.io", "team/app"},
+		{"registry.example.com/team/app:v1", "registry.example.com", "team/app"},
+		{"localhost:5000/app", "localhost:5000", "app"},
+	}
+
+	for _, tt := range tests {
+		host, repository := splitReference(tt.ref)
+		if host != tt.host || repository != tt.repository {
+			t.Errorf("splitReference(%q) = %s, %s; expected %s, %s", tt.ref, host, repository, tt.host, tt.repository)
+		}
+	}
+}
+
+func TestCanRollBack(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   map[string]interface{}
+		expected bool
+	}{
+		{name: "tags removed on rollback", config: map[string]interface{}{"action": "push", "removeOnRollback": true}, expected: true},
+		{name: "tags kept", config: map[string]interface{}{"action": "push"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := (&DockerPlugin{}).CanRollBack(tt.config); got != tt.expected {
+				t.Errorf("Expected CanRollBack() = %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,469 @@
+package email
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"os"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/cuongtl1992/grp-cli/internal/plugins"
+	"github.com/cuongtl1992/grp-cli/pkg/plugin"
+)
+
+const (
+	// tlsStartTLS upgrades the connection with STARTTLS before sending, the default
+	tlsStartTLS = "starttls"
+	// tlsImplicit connects over TLS from the start, usually on port 465
+	tlsImplicit = "tls"
+	// tlsNone sends the email unencrypted
+	tlsNone = "none"
+	// defaultTimeout bounds connecting to the server and sending the email
+	defaultTimeout = 30 * time.Second
+)
+
+// defaultSubject and defaultBody are used when the job does not define templates
+const (
+	defaultSubject = `grp-cli: {{if .Stage}}stage {{.Stage}} of {{end}}execution {{.ExecutionID}}`
+	defaultBody    = `Execution: {{.ExecutionID}}
+{{if .Stage}}Stage: {{.Stage}}
+{{end}}{{if .Job}}Job: {{.Job}}
+{{end}}`
+)
+
+// defaultPorts is the port of the server for each TLS mode, when the job does not set one
+var defaultPorts = map[string]string{
+	tlsStartTLS: "587",
+	tlsImplicit: "465",
+	tlsNone:     "25",
+}
+
+// EmailPlugin implements the Plugin interface for jobs that send an email over
+// SMTP, for teams notified by email rather than chat
+type EmailPlugin struct{}
+
+// Plugin is the instance registered as a built-in plugin
+var Plugin EmailPlugin
+
+func init() {
+	plugins.RegisterBuiltin(&Plugin)
+}
+
+// email describes the message and server connection built from job config
+type email struct {
+	server             string
+	host               string
+	tls                string
+	insecureSkipVerify bool
+	username           string
+	password           string
+	from               *mail.Address
+	to                 []*mail.Address
+	cc                 []*mail.Address
+	bcc                []*mail.Address
+	subject            *template.Template
+	body               *template.Template
+	html               bool
+	timeout            time.Duration
+}
+
+// templateData is what the subject and body templates are rendered with
+type templateData struct {
+	ExecutionID string
+	Stage       string
+	Job         string
+	Attempt     int
+	Variables   map[string]interface{}
+}
+
+// Name returns the plugin name
+func (p *EmailPlugin) Name() string {
+	return "email"
+}
+
+// Description returns the plugin description
+func (p *EmailPlugin) Description() string {
+	return "Sends an email over SMTP"
+}
+
+// Version returns the plugin version
+func (p *EmailPlugin) Version() string {
+	return "0.1.0"
+}
+
+// ConfigSchema returns the JSON schema for config validation
+func (p *EmailPlugin) ConfigSchema() *plugin.JSONSchema {
+	// Recipients are an address, a comma-separated list or a list of addresses
+	return &plugin.JSONSchema{
+		Type: "object",
+		Properties: map[string]*plugin.JSONSchema{
+			"server":             {Type: "string"},
+			"tls":                {Type: "string", Default: tlsStartTLS},
+			"insecureSkipVerify": {Type: "boolean"},
+			"username":           {Type: "string"},
+			"password":           {Type: "string"},
+			"from":               {Type: "string"},
+			"to":                 {},
+			"cc":                 {},
+			"bcc":                {},
+			"subject":            {Type: "string"},
+			"body":               {Type: "string"},
+			"html":               {Type: "boolean"},
+			"timeout":            {Type: "string"},
+		},
+		Required: []string{"server", "from", "to"},
+	}
+}
+
+// Validate checks if the configuration is valid
+func (p *EmailPlugin) Validate(ctx context.Context, config map[string]interface{}) error {
+	_, err := parseEmail(config)
+	return err
+}
+
+// Preview describes the email Execute would send
+func (p *EmailPlugin) Preview(ctx context.Context, config map[string]interface{}) (string, error) {
+	msg, err := parseEmail(config)
+	if err != nil {
+		return "", err
+	}
+	subject, _, err := msg.render(ctx)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("Would send %q to %s via %s", subject, strings.Join(addresses(msg.recipients()), ", "), msg.server), nil
+}
+
+// Execute renders the email, sends it through the SMTP server and reports its
+// delivery to the server
+func (p *EmailPlugin) Execute(ctx context.Context, config map[string]interface{}) (*plugin.Result, error) {
+	executionID := plugin.ExecutionID(ctx)
+
+	msg, err := parseEmail(config)
+	if err != nil {
+		return nil, err
+	}
+	subject, body, err := msg.render(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	recipients := addresses(msg.recipients())
+	data := map[string]interface{}{
+		"server":     msg.server,
+		"from":       msg.from.Address,
+		"recipients": recipients,
+		"subject":    subject,
+	}
+	if err := send(ctx, msg, buildMessage(msg, subject, body, time.Now())); err != nil {
+		return &plugin.Result{
+			Success:     false,
+			Message:     fmt.Sprintf("Failed to send email %q via %s: %v", subject, msg.server, err),
+			ExecutionID: executionID,
+			Data:        data,
+		}, nil
+	}
+	data["delivered"] = true
+
+	return &plugin.Result{
+		Success:     true,
+		Message:     fmt.Sprintf("Sent email %q to %d recipients via %s", subject, len(recipients), msg.server),
+		ExecutionID: executionID,
+		Data:        data,
+	}, nil
+}
+
+// Capabilities reports that sent emails cannot be rolled back
+func (p *EmailPlugin) Capabilities() plugin.Capabilities {
+	return plugin.Capabilities{Preview: true}
+}
+
+// Rollback does nothing: a sent email cannot be taken back
+func (p *EmailPlugin) Rollback(ctx context.Context, executionID string) error {
+	return nil
+}
+
+// parseEmail builds an email from job config
+func parseEmail(config map[string]interface{}) (email, error) {
+	msg := email{tls: tlsStartTLS, timeout: defaultTimeout}
+
+	if mode, ok := config["tls"].(string); ok && mode != "" {
+		msg.tls = mode
+	}
+	if _, ok := defaultPorts[msg.tls]; !ok {
+		return msg, fmt.Errorf("tls must be %s, %s or %s, got %q", tlsStartTLS, tlsImplicit, tlsNone, msg.tls)
+	}
+	msg.insecureSkipVerify, _ = config["insecureSkipVerify"].(bool)
+
+	server, _ := config["server"].(string)
+	if server == "" {
+		return msg, fmt.Errorf("missing required field: server")
+	}
+	if strings.Contains(server, "://") {
+		return msg, fmt.Errorf("server must be host or host:port, got %s", server)
+	}
+	host, port, err := net.SplitHostPort(server)
+	if err != nil {
+		// A server without a port uses the usual port of the TLS mode
+		host, port = server, defaultPorts[msg.tls]
+	}
+	if host == "" {
+		return msg, fmt.Errorf("server must be host or host:port, got %s", server)
+	}
+	if _, err := strconv.ParseUint(port, 10, 16); err != nil {
+		return msg, fmt.Errorf("invalid server port %q", port)
+	}
+	msg.host = host
+	msg.server = net.JoinHostPort(host, port)
+
+	msg.username, _ = config["username"].(string)
+	msg.password, _ = config["password"].(string)
+
+	from, _ := config["from"].(string)
+	if from == "" {
+		return msg, fmt.Errorf("missing required field: from")
+	}
+	if msg.from, err = mail.ParseAddress(from); err != nil {
+		return msg, fmt.Errorf("invalid from address %q: %w", from, err)
+	}
+
+	for _, field := range []struct {
+		name string
+		list *[]*mail.Address
+	}{{"to", &msg.to}, {"cc", &msg.cc}, {"bcc", &msg.bcc}} {
+		if *field.list, err = parseAddresses(field.name, config[field.name]); err != nil {
+			return msg, err
+		}
+	}
+	if len(msg.to) == 0 {
+		return msg, fmt.Errorf("missing required field: to")
+	}
+
+	subject, _ := config["subject"].(string)
+	if subject == "" {
+		subject = defaultSubject
+	}
+	if msg.subject, err = parseTemplate("subject", subject); err != nil {
+		return msg, err
+	}
+	body, _ := config["body"].(string)
+	if body == "" {
+		body = defaultBody
+	}
+	if msg.body, err = parseTemplate("body", body); err != nil {
+		return msg, err
+	}
+	msg.html, _ = config["html"].(bool)
+
+	if timeout, ok := config["timeout"].(string); ok && timeout != "" {
+		duration, err := time.ParseDuration(timeout)
+		if err != nil {
+			return msg, fmt.Errorf("invalid timeout: %w", err)
+		}
+		msg.timeout = duration
+	}
+
+	return msg, nil
+}
+
+// parseAddresses parses a list of recipients given as a list of addresses or a
+// comma-separated string of them
+func parseAddresses(field string, value interface{}) ([]*mail.Address, error) {
+	var entries []string
+	switch value := value.(type) {
+	case nil:
+	case string:
+		if strings.TrimSpace(value) != "" {
+			entries = []string{value}
+		}
+	case []interface{}:
+		for _, entry := range value {
+			text, ok := entry.(string)
+			if !ok {
+				return nil, fmt.Errorf("%s must be a list of addresses, got %v", field, entry)
+			}
+			entries = append(entries, text)
+		}
+	case []string:
+		entries = value
+	default:
+		return nil, fmt.Errorf("%s must be an address or a list of addresses, got %T", field, value)
+	}
+
+	var list []*mail.Address
+	for _, entry := range entries {
+		parsed, err := mail.ParseAddressList(entry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s address %q: %w", field, entry, err)
+		}
+		list = append(list, parsed...)
+	}
+	return list, nil
+}
+
+// parseTemplate parses a subject or body template, with the same join function
+// as notification templates
+func parseTemplate(name, text string) (*template.Template, error) {
+	tmpl, err := template.New(name).Funcs(template.FuncMap{"join": strings.Join}).Option("missingkey=zero").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s template: %w", name, err)
+	}
+	return tmpl, nil
+}
+
+// render renders the subject and body with the execution the job belongs to.
+// Line breaks in the subject are replaced by spaces, as a header cannot hold them.
+func (m email) render(ctx context.Context) (string, string, error) {
+	data := templateData{
+		ExecutionID: plugin.ExecutionID(ctx),
+		Stage:       plugin.StageName(ctx),
+		Job:         plugin.JobName(ctx),
+		Attempt:     plugin.Attempt(ctx),
+		Variables:   plugin.Variables(ctx),
+	}
+
+	var subject, body bytes.Buffer
+	if err := m.subject.Execute(&subject, data); err != nil {
+		return "", "", fmt.Errorf("failed to render subject: %w", err)
+	}
+	if err := m.body.Execute(&body, data); err != nil {
+		return "", "", fmt.Errorf("failed to render body: %w", err)
+	}
+	return strings.Join(strings.Fields(subject.String()), " "), body.String(), nil
+}
+
+// recipients returns every address the email is delivered to
+func (m email) recipients() []*mail.Address {
+	return append(append(append([]*mail.Address(nil), m.to...), m.cc...), m.bcc...)
+}
+
+// addresses returns the bare addresses of a list
+func addresses(list []*mail.Address) []string {
+	result := make([]string, len(list))
+	for i, address := range list {
+		result[i] = address.Address
+	}
+	return result
+}
+
+// formatAddresses returns a list of addresses as the value of an address header
+func formatAddresses(list []*mail.Address) string {
+	formatted := make([]string, len(list))
+	for i, address := range list {
+		formatted[i] = address.String()
+	}
+	return strings.Join(formatted, ", ")
+}
+
+// buildMessage returns the email as a MIME message with a quoted-printable body.
+// Bcc recipients are left out of the headers.
+func buildMessage(m email, subject, body string, date time.Time) []byte {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "From: %s\r\n", m.from.String())
+	fmt.Fprintf(&buf, "To: %s\r\n", formatAddresses(m.to))
+	if len(m.cc) > 0 {
+		fmt.Fprintf(&buf, "Cc: %s\r\n", formatAddresses(m.cc))
+	}
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	fmt.Fprintf(&buf, "Date: %s\r\n", date.Format(time.RFC1123Z))
+	contentType := "text/plain"
+	if m.html {
+		contentType = "text/html"
+	}
+	fmt.Fprintf(&buf, "MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&buf, "Content-Type: %s; charset=utf-8\r\n", contentType)
+	fmt.Fprintf(&buf, "Content-Transfer-Encoding: quoted-printable\r\n\r\n")
+
+	writer := quotedprintable.NewWriter(&buf)
+	writer.Write([]byte(strings.ReplaceAll(body, "\r\n", "\n")))
+	writer.Close()
+	return buf.Bytes()
+}
+
+// send delivers the message through the SMTP server
+func send(ctx context.Context, m email, message []byte) error {
+	ctx, cancel := context.WithTimeout(ctx, m.timeout)
+	defer cancel()
+
+	tlsConfig := &tls.Config{ServerName: m.host, InsecureSkipVerify: m.insecureSkipVerify}
+	var conn net.Conn
+	var err error
+	if m.tls == tlsImplicit {
+		dialer := &tls.Dialer{Config: tlsConfig}
+		conn, err = dialer.DialContext(ctx, "tcp", m.server)
+	} else {
+		var dialer net.Dialer
+		conn, err = dialer.DialContext(ctx, "tcp", m.server)
+	}
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	// Unblock reads and writes once the context is done
+	stop := context.AfterFunc(ctx, func() { conn.SetDeadline(time.Now()) })
+	defer stop()
+
+	client, err := smtp.NewClient(conn, m.host)
+	if err != nil {
+		return ctxError(ctx, err)
+	}
+	defer client.Close()
+
+	if hostname, err := os.Hostname(); err == nil {
+		if err := client.Hello(hostname); err != nil {
+			return ctxError(ctx, err)
+		}
+	}
+	if m.tls == tlsStartTLS {
+		if ok, _ := client.Extension("STARTTLS"); !ok {
+			return fmt.Errorf("the server does not support STARTTLS; set tls: none to send unencrypted")
+		}
+		if err := client.StartTLS(tlsConfig); err != nil {
+			return ctxError(ctx, err)
+		}
+	}
+	if m.username != "" {
+		if err := client.Auth(smtp.PlainAuth("", m.username, m.password, m.host)); err != nil {
+			return fmt.Errorf("authentication failed: %w", ctxError(ctx, err))
+		}
+	}
+
+	if err := client.Mail(m.from.Address); err != nil {
+		return ctxError(ctx, err)
+	}
+	for _, recipient := range m.recipients() {
+		if err := client.Rcpt(recipient.Address); err != nil {
+			return fmt.Errorf("recipient %s rejected: %w", recipient.Address, ctxError(ctx, err))
+		}
+	}
+	writer, err := client.Data()
+	if err != nil {
+		return ctxError(ctx, err)
+	}
+	if _, err := writer.Write(message); err != nil {
+		return ctxError(ctx, err)
+	}
+	// Closing the data writer waits for the server to accept the message
+	if err := writer.Close(); err != nil {
+		return ctxError(ctx, err)
+	}
+	return client.Quit()
+}
+
+// ctxError reports the context's error for I/O aborted by its deadline
+func ctxError(ctx context.Context, err error) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return err
+}
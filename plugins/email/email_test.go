@@ -0,0 +1,312 @@
+package email
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cuongtl1992/grp-cli/pkg/plugin"
+)
+
+// fakeSMTP is an SMTP server accepting one connection. It advertises extensions
+// after EHLO, answers RCPT for reject with 550 and records the transaction.
+type fakeSMTP struct {
+	address    string
+	extensions []string
+	reject     string
+	// received receives the recorded transaction once the client quits or disconnects
+	received chan transaction
+}
+
+// transaction is what the client sent to the fake server
+type transaction struct {
+	auth       string
+	from       string
+	recipients []string
+	data       string
+}
+
+func newFakeSMTP(t *testing.T, extensions []string, reject string) *fakeSMTP {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	server := &fakeSMTP{address: listener.Addr().String(), extensions: extensions, reject: reject, received: make(chan transaction, 1)}
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		server.received <- server.serve(conn)
+	}()
+	return server
+}
+
+// serve runs the protocol exchange and returns the recorded transaction
+func (s *fakeSMTP) serve(conn net.Conn) transaction {
+	var tx transaction
+	reader := bufio.NewReader(conn)
+	fmt.Fprintf(conn, "220 fake ESMTP\r\n")
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return tx
+		}
+		line = strings.TrimRight(line, "\r\n")
+		verb, arg, _ := strings.Cut(line, " ")
+		switch strings.ToUpper(verb) {
+		case "EHLO":
+			lines := append([]string{"fake"}, s.extensions...)
+			for i, ext := range lines {
+				separator := "-"
+				if i == len(lines)-1 {
+					separator = " "
+				}
+				fmt.Fprintf(conn, "250%s%s\r\n", separator, ext)
+			}
+		case "AUTH":
+			tx.auth = arg
+			fmt.Fprintf(conn, "235 authenticated\r\n")
+		case "MAIL":
+			tx.from = strings.Trim(strings.TrimPrefix(arg, "FROM:"), "<>")
+			fmt.Fprintf(conn, "250 ok\r\n")
+		case "RCPT":
+			recipient := strings.Trim(strings.TrimPrefix(arg, "TO:"), "<>")
+			if recipient == s.reject {
+				fmt.Fprintf(conn, "550 no such user\r\n")
+				continue
+			}
+			tx.recipients = append(tx.recipients, recipient)
+			fmt.Fprintf(conn, "250 ok\r\n")
+		case "DATA":
+			fmt.Fprintf(conn, "354 go ahead\r\n")
+			var data strings.Builder
+			for {
+				line, err := reader.ReadString('\n')
+				if err != nil || line == ".\r\n" {
+					break
+				}
+				data.WriteString(line)
+			}
+			tx.data = data.String()
+			fmt.Fprintf(conn, "250 queued\r\n")
+		case "QUIT":
+			fmt.Fprintf(conn, "221 bye\r\n")
+			return tx
+		default:
+			fmt.Fprintf(conn, "250 ok\r\n")
+		}
+	}
+}
+
+func TestExecute(t *testing.T) {
+	tests := []struct {
+		name           string
+		config         map[string]interface{}
+		extensions     []string
+		reject         string
+		wantSuccess    bool
+		wantMessage    string
+		wantRecipients []string
+		wantData       []string
+		wantAuth       string
+	}{
+		{
+			name: "templated email",
+			config: map[string]interface{}{
+				"tls":     "none",
+				"to":      []interface{}{"Ops <ops@example.com>", "dev@example.com"},
+				"bcc":     "audit@example.com",
+				"subject": "Release {{.Variables.version}} of {{.Stage}}",
+				"body":    "Execution {{.ExecutionID}} deployed {{join .Variables.services \", \"}}.",
+			},
+			wantSuccess:    true,
+			wantMessage:    `Sent email "Release 1.2.3 of deploy" to 3 recipients`,
+			wantRecipients: []string{"ops@example.com", "dev@example.com", "audit@example.com"},
+			wantData: []string{
+				"From: <release@example.com>\r\n",
+				`To: "Ops" <ops@example.com>, <dev@example.com>` + "\r\n",
+				"Subject: Release 1.2.3 of deploy\r\n",
+				"Execution exec-1 deployed web, api.",
+			},
+		},
+		{
+			name:        "authentication",
+			config:      map[string]interface{}{"tls": "none", "to": "ops@example.com", "username": "grp", "password": "s3cr3t"},
+			extensions:  []string{"AUTH PLAIN"},
+			wantSuccess: true,
+			wantAuth:    "PLAIN AGdycABzM2NyM3Q=",
+		},
+		{
+			name:        "rejected recipient",
+			config:      map[string]interface{}{"tls": "none", "to": "ops@example.com, nobody@example.com"},
+			reject:      "nobody@example.com",
+			wantMessage: "recipient nobody@example.com rejected: 550",
+		},
+		{
+			name:        "STARTTLS not supported",
+			config:      map[string]interface{}{"to": "ops@example.com"},
+			wantMessage: "the server does not support STARTTLS",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newFakeSMTP(t, tt.extensions, tt.reject)
+
+			config := map[string]interface{}{"server": server.address, "from": "release@example.com", "timeout": "5s"}
+			for key, value := range tt.config {
+				config[key] = value
+			}
+			p := &EmailPlugin{}
+			if err := p.Validate(context.Background(), config); err != nil {
+				t.Fatalf("Validate() error = %v", err)
+			}
+
+			ctx := plugin.WithExecutionID(context.Background(), "exec-1")
+			ctx = plugin.WithStageName(ctx, "deploy")
+			ctx = plugin.WithVariables(ctx, map[string]interface{}{"version": "1.2.3", "services": []string{"web", "api"}})
+			result, err := p.Execute(ctx, config)
+			if err != nil {
+				t.Fatalf("Execute() error = %v", err)
+			}
+			if result.Success != tt.wantSuccess {
+				t.Fatalf("Execute() success = %v, want %v (%s)", result.Success, tt.wantSuccess, result.Message)
+			}
+			if !strings.Contains(result.Message, tt.wantMessage) {
+				t.Errorf("Expected message containing %q, got %q", tt.wantMessage, result.Message)
+			}
+			if !tt.wantSuccess {
+				return
+			}
+
+			tx := <-server.received
+			if tx.from != "release@example.com" {
+				t.Errorf("Expected the sender release@example.com, got %q", tx.from)
+			}
+			if tt.wantRecipients != nil && strings.Join(tx.recipients, ",") != strings.Join(tt.wantRecipients, ",") {
+				t.Errorf("Expected recipients %v, got %v", tt.wantRecipients, tx.recipients)
+			}
+			for _, want := range tt.wantData {
+				if !strings.Contains(tx.data, want) {
+					t.Errorf("Expected the message to contain %q, got:\n%s", want, tx.data)
+				}
+			}
+			if strings.Contains(tx.data, "audit@example.com") {
+				t.Errorf("Expected bcc recipients left out of the message, got:\n%s", tx.data)
+			}
+			if tx.auth != tt.wantAuth {
+				t.Errorf("Expected AUTH %q, got %q", tt.wantAuth, tx.auth)
+			}
+			if result.Data["delivered"] != true {
+				t.Errorf("Expected the delivery in the data, got %v", result.Data)
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	valid := func(changes map[string]interface{}) map[string]interface{} {
+		config := map[string]interface{}{"server": "smtp.example.com", "from": "release@example.com", "to": "ops@example.com"}
+		for key, value := range changes {
+			if value == nil {
+				delete(config, key)
+				continue
+			}
+			config[key] = value
+		}
+		return config
+	}
+
+	tests := []struct {
+		name    string
+		config  map[string]interface{}
+		wantErr string
+	}{
+		{name: "valid", config: valid(nil)},
+		{name: "recipient list", config: valid(map[string]interface{}{"to": []interface{}{"a@example.com", "B <b@example.com>"}, "cc": "c@example.com"})},
+		{name: "missing server", config: valid(map[string]interface{}{"server": nil}), wantErr: "missing required field: server"},
+		{name: "missing recipients", config: valid(map[string]interface{}{"to": nil, "cc": "c@example.com"}), wantErr: "missing required field: to"},
+		{name: "missing sender", config: valid(map[string]interface{}{"from": nil}), wantErr: "missing required field: from"},
+		{name: "invalid recipient", config: valid(map[string]interface{}{"to": []interface{}{"ops"}}), wantErr: `invalid to address "ops"`},
+		{name: "invalid port", config: valid(map[string]interface{}{"server": "smtp.example.com:smtp"}), wantErr: `invalid server port "smtp"`},
+		{name: "URL as server", config: valid(map[string]interface{}{"server": "smtp://smtp.example.com"}), wantErr: "server must be host or host:port"},
+		{name: "unknown TLS mode", config: valid(map[string]interface{}{"tls": "ssl"}), wantErr: `tls must be starttls, tls or none, got "ssl"`},
+		{name: "invalid template", config: valid(map[string]interface{}{"body": "{{.Stage"}), wantErr: "invalid body template"},
+		{name: "invalid timeout", config: valid(map[string]interface{}{"timeout": "soon"}), wantErr: "invalid timeout"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := (&EmailPlugin{}).Validate(context.Background(), tt.config)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Validate() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Validate() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParseEmailDefaultPort(t *testing.T) {
+	tests := []struct {
+		tls      string
+		expected string
+	}{
+		{tls: "", expected: "smtp.example.com:587"},
+		{tls: "tls", expected: "smtp.example.com:465"},
+		{tls: "none", expected: "smtp.example.com:25"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.tls, func(t *testing.T) {
+			msg, err := parseEmail(map[string]interface{}{"server": "smtp.example.com", "tls": tt.tls, "from": "a@example.com", "to": "b@example.com"})
+			if err != nil {
+				t.Fatalf("parseEmail() error = %v", err)
+			}
+			if msg.server != tt.expected {
+				t.Errorf("Expected server %s, got %s", tt.expected, msg.server)
+			}
+		})
+	}
+}
+
+func TestBuildMessage(t *testing.T) {
+	msg, err := parseEmail(map[string]interface{}{
+		"server": "smtp.example.com",
+		"from":   "Release Bot <release@example.com>",
+		"to":     "ops@example.com",
+		"cc":     "dev@example.com",
+		"html":   true,
+	})
+	if err != nil {
+		t.Fatalf("parseEmail() error = %v", err)
+	}
+
+	date := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	message := string(buildMessage(msg, "Déploiement réussi", "<p>café</p>\n", date))
+	for _, want := range []string{
+		`From: "Release Bot" <release@example.com>` + "\r\n",
+		"Cc: <dev@example.com>\r\n",
+		"Subject: =?utf-8?q?D=C3=A9ploiement_r=C3=A9ussi?=\r\n",
+		"Date: Wed, 01 May 2024 12:00:00 +0000\r\n",
+		"Content-Type: text/html; charset=utf-8\r\n",
+		"\r\n\r\n<p>caf=C3=A9</p>",
+	} {
+		if !strings.Contains(message, want) {
+			t.Errorf("Expected the message to contain %q, got:\n%s", want, message)
+		}
+	}
+}
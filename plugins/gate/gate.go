@@ -0,0 +1,515 @@
+package gate
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cuongtl1992/grp-cli/internal/config"
+	"github.com/cuongtl1992/grp-cli/internal/plugins"
+	"github.com/cuongtl1992/grp-cli/pkg/plugin"
+)
+
+const (
+	// defaultInterval is the time between two checks
+	defaultInterval = 10 * time.Second
+	// defaultTimeout is how long the gate waits for its condition to pass
+	defaultTimeout = 5 * time.Minute
+	// maxObservations bounds the checks kept in the job data
+	maxObservations = 10
+	// maxOutput bounds the response body or command output kept per check
+	maxOutput = 1024
+)
+
+// GatePlugin implements the Plugin interface for jobs that poll a condition
+// until it passes, e.g. a health endpoint or a metric threshold
+type GatePlugin struct{}
+
+// Plugin is the instance registered as a built-in plugin
+var Plugin GatePlugin
+
+func init() {
+	plugins.RegisterBuiltin(&Plugin)
+}
+
+// gate describes the condition and polling settings built from job config
+type gate struct {
+	url              string
+	headers          map[string]string
+	expectedStatus   []int
+	command          string
+	field            string
+	contains         string
+	min              *float64
+	max              *float64
+	interval         time.Duration
+	timeout          time.Duration
+	successThreshold int
+}
+
+// observation is the outcome of a single check
+type observation struct {
+	time     time.Time
+	passed   bool
+	status   int
+	exitCode int
+	value    interface{}
+	output   string
+	reason   string
+}
+
+// Name returns the plugin name
+func (p *GatePlugin) Name() string {
+	return "gate"
+}
+
+// Description returns the plugin description
+func (p *GatePlugin) Description() string {
+	return "Polls an HTTP endpoint or command until a condition passes"
+}
+
+// Version returns the plugin version
+func (p *GatePlugin) Version() string {
+	return "0.1.0"
+}
+
+// ConfigSchema returns the JSON schema for config validation
+func (p *GatePlugin) ConfigSchema() *plugin.JSONSchema {
+	return &plugin.JSONSchema{
+		Type: "object",
+		Properties: map[string]*plugin.JSONSchema{
+			"url":              {Type: "string"},
+			"headers":          {Type: "object"},
+			"expectedStatus":   {Type: "array", Items: &plugin.JSONSchema{Type: "integer"}},
+			"command":          {Type: "string"},
+			"field":            {Type: "string"},
+			"contains":         {Type: "string"},
+			"min":              {Type: "number"},
+			"max":              {Type: "number"},
+			"interval":         {Type: "string"},
+			"timeout":          {Type: "string"},
+			"successThreshold": {Type: "integer"},
+		},
+	}
+}
+
+// Validate checks if the configuration is valid
+func (p *GatePlugin) Validate(ctx context.Context, config map[string]interface{}) error {
+	_, err := parseGate(config)
+	return err
+}
+
+// Execute checks the condition every interval until it has passed
+// successThreshold times in a row, failing once the timeout has elapsed
+func (p *GatePlugin) Execute(ctx context.Context, config map[string]interface{}) (*plugin.Result, error) {
+	executionID := plugin.ExecutionID(ctx)
+
+	config, err := templateConfig(ctx, config)
+	if err != nil {
+		return nil, err
+	}
+
+	g, err := parseGate(config)
+	if err != nil {
+		return nil, err
+	}
+
+	deadline := time.NewTimer(g.timeout)
+	defer deadline.Stop()
+
+	var observations []observation
+	checks, successes := 0, 0
+	for {
+		obs := g.check(ctx)
+		if ctx.Err() != nil {
+			return nil, fmt.Errorf("gate canceled after %d checks: %w", checks, ctx.Err())
+		}
+
+		checks++
+		if output := plugin.Output(ctx); output != nil {
+			if obs.passed {
+				fmt.Fprintf(output, "check %d passed\n", checks)
+			} else {
+				fmt.Fprintf(output, "check %d failed: %s\n", checks, obs.reason)
+			}
+		}
+		observations = append(observations, obs)
+		if len(observations) > maxObservations {
+			observations = observations[1:]
+		}
+		if obs.passed {
+			successes++
+		} else {
+			successes = 0
+		}
+
+		if successes >= g.successThreshold {
+			return &plugin.Result{
+				Success:     true,
+				Message:     fmt.Sprintf("Gate passed after %d checks", checks),
+				ExecutionID: executionID,
+				Data:        resultData(checks, successes, observations),
+			}, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("gate canceled after %d checks: %w", checks, ctx.Err())
+		case <-deadline.C:
+			message := fmt.Sprintf("Gate did not pass within %s (%d checks)", g.timeout, checks)
+			if !obs.passed {
+				message = fmt.Sprintf("%s: %s", message, obs.reason)
+			}
+			return &plugin.Result{
+				Success:     false,
+				Message:     message,
+				ExecutionID: executionID,
+				Data:        resultData(checks, successes, observations),
+			}, nil
+		case <-time.After(g.interval):
+		}
+	}
+}
+
+// Capabilities reports that gates stream the output of their command and have
+// nothing to roll back
+func (p *GatePlugin) Capabilities() plugin.Capabilities {
+	return plugin.Capabilities{Streaming: true}
+}
+
+// Rollback does nothing, as a gate does not change anything
+func (p *GatePlugin) Rollback(ctx context.Context, executionID string) error {
+	return nil
+}
+
+// templateConfig resolves ${variables.x} references in the url and headers
+// against the variables available in the execution context. The command is left
+// alone, as the shell expands ${NAME} itself.
+func templateConfig(ctx context.Context, cfg map[string]interface{}) (map[string]interface{}, error) {
+	vars := plugin.Variables(ctx)
+
+	request := make(map[string]interface{})
+	for _, key := range []string{"url", "headers"} {
+		if value, ok := cfg[key]; ok {
+			request[key] = value
+		}
+	}
+
+	resolved, err := config.NewResolver().ResolveValues(request, map[string]interface{}{"variables": vars})
+	if err != nil {
+		return nil, fmt.Errorf("failed to template gate: %w", err)
+	}
+
+	templated := make(map[string]interface{}, len(cfg))
+	for key, value := range cfg {
+		templated[key] = value
+	}
+	for key, value := range resolved {
+		templated[key] = value
+	}
+	return templated, nil
+}
+
+// parseGate builds a gate from job config
+func parseGate(config map[string]interface{}) (gate, error) {
+	g := gate{
+		headers:          make(map[string]string),
+		expectedStatus:   []int{http.StatusOK},
+		interval:         defaultInterval,
+		timeout:          defaultTimeout,
+		successThreshold: 1,
+	}
+
+	g.url, _ = config["url"].(string)
+	g.command, _ = config["command"].(string)
+	switch {
+	case g.url == "" && g.command == "":
+		return g, fmt.Errorf("either url or command is required")
+	case g.url != "" && g.command != "":
+		return g, fmt.Errorf("url and command cannot both be set")
+	case g.url != "" && !strings.HasPrefix(g.url, "http://") && !strings.HasPrefix(g.url, "https://"):
+		return g, fmt.Errorf("url must start with http:// or https://: %s", g.url)
+	}
+
+	if headers, ok := config["headers"].(map[string]interface{}); ok {
+		for key, value := range headers {
+			g.headers[key] = fmt.Sprintf("%v", value)
+		}
+	}
+
+	if statuses, ok := config["expectedStatus"].([]interface{}); ok && len(statuses) > 0 {
+		g.expectedStatus = nil
+		for _, status := range statuses {
+			code, ok := toNumber(status)
+			if !ok {
+				return g, fmt.Errorf("expectedStatus must contain integers, got %v", status)
+			}
+			g.expectedStatus = append(g.expectedStatus, int(code))
+		}
+	}
+
+	g.field, _ = config["field"].(string)
+	g.contains, _ = config["contains"].(string)
+
+	for key, bound := range map[string]**float64{"min": &g.min, "max": &g.max} {
+		if value, ok := config[key]; ok {
+			number, ok := toNumber(value)
+			if !ok {
+				return g, fmt.Errorf("%s must be a number, got %v", key, value)
+			}
+			*bound = &number
+		}
+	}
+	if g.min != nil && g.max != nil && *g.min > *g.max {
+		return g, fmt.Errorf("min %v is greater than max %v", *g.min, *g.max)
+	}
+
+	for key, duration := range map[string]*time.Duration{"interval": &g.interval, "timeout": &g.timeout} {
+		if value, ok := config[key].(string); ok && value != "" {
+			parsed, err := time.ParseDuration(value)
+			if err != nil {
+				return g, fmt.Errorf("invalid %s: %w", key, err)
+			}
+			if parsed <= 0 {
+				return g, fmt.Errorf("%s must be positive", key)
+			}
+			*duration = parsed
+		}
+	}
+
+	if value, ok := config["successThreshold"]; ok {
+		threshold, ok := toNumber(value)
+		if !ok || threshold < 1 || threshold != float64(int(threshold)) {
+			return g, fmt.Errorf("successThreshold must be a positive integer, got %v", value)
+		}
+		g.successThreshold = int(threshold)
+	}
+
+	return g, nil
+}
+
+// check runs the HTTP request or command once and evaluates the condition
+func (g gate) check(ctx context.Context) observation {
+	obs := observation{time: time.Now()}
+
+	var output string
+	if g.url != "" {
+		status, body, err := g.get(ctx)
+		if err != nil {
+			obs.reason = err.Error()
+			return obs
+		}
+		obs.status, output = status, body
+		if !containsInt(g.expectedStatus, status) {
+			obs.output = truncate(output)
+			obs.reason = fmt.Sprintf("status %d (expected %v)", status, g.expectedStatus)
+			return obs
+		}
+	} else {
+		exitCode, stdout, err := g.run(ctx)
+		obs.exitCode, output = exitCode, stdout
+		if err != nil {
+			obs.output = truncate(output)
+			obs.reason = err.Error()
+			return obs
+		}
+	}
+	obs.output = truncate(output)
+
+	if g.contains != "" && !strings.Contains(output, g.contains) {
+		obs.reason = fmt.Sprintf("output does not contain %q", g.contains)
+		return obs
+	}
+
+	if g.field != "" || g.min != nil || g.max != nil {
+		value, err := g.observe(output)
+		if err != nil {
+			obs.reason = err.Error()
+			return obs
+		}
+		obs.value = value
+		if reason := g.compare(value); reason != "" {
+			obs.reason = reason
+			return obs
+		}
+	}
+
+	obs.passed = true
+	return obs
+}
+
+// get sends the GET request and returns the status code and response body
+func (g gate) get(ctx context.Context) (int, string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, g.url, nil)
+	if err != nil {
+		return 0, "", fmt.Errorf("failed to create request: %w", err)
+	}
+	for key, value := range g.headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return 0, "", fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return resp.StatusCode, "", fmt.Errorf("failed to read response body: %w", err)
+	}
+	return resp.StatusCode, string(body), nil
+}
+
+// run executes the command through the system shell and returns its exit code
+// and stdout. A non-zero exit code is an error.
+func (g gate) run(ctx context.Context) (int, string, error) {
+	name, args := "sh", []string{"-c", g.command}
+	if runtime.GOOS == "windows" {
+		name, args = "cmd", []string{"/C", g.command}
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Dir = plugin.WorkingDir(ctx)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	err := cmd.Run()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitCode(), stdout.String(), fmt.Errorf("command exited with code %d: %s", exitErr.ExitCode(), strings.TrimSpace(stderr.String()))
+	}
+	if err != nil {
+		return -1, stdout.String(), fmt.Errorf("failed to run command: %w", err)
+	}
+	return 0, stdout.String(), nil
+}
+
+// observe extracts the observed value from the output: the field of a JSON
+// output if set, otherwise the whole output
+func (g gate) observe(output string) (interface{}, error) {
+	if g.field == "" {
+		return strings.TrimSpace(output), nil
+	}
+
+	var document interface{}
+	if err := json.Unmarshal([]byte(output), &document); err != nil {
+		return nil, fmt.Errorf("output is not JSON: %w", err)
+	}
+
+	value := document
+	for _, key := range strings.Split(g.field, ".") {
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("field %s not found", g.field)
+		}
+		if value, ok = object[key]; !ok {
+			return nil, fmt.Errorf("field %s not found", g.field)
+		}
+	}
+	return value, nil
+}
+
+// compare checks the observed value against the min and max bounds, returning
+// why it does not pass or an empty string
+func (g gate) compare(value interface{}) string {
+	if g.min == nil && g.max == nil {
+		return ""
+	}
+
+	number, ok := toNumber(value)
+	if text, isString := value.(string); isString {
+		parsed, err := strconv.ParseFloat(text, 64)
+		number, ok = parsed, err == nil
+	}
+	if !ok {
+		return fmt.Sprintf("value %v is not a number", value)
+	}
+
+	if g.min != nil && number < *g.min {
+		return fmt.Sprintf("value %v is below the minimum %v", number, *g.min)
+	}
+	if g.max != nil && number > *g.max {
+		return fmt.Sprintf("value %v is above the maximum %v", number, *g.max)
+	}
+	return ""
+}
+
+// resultData returns the job data for the checks made so far
+func resultData(checks, successes int, observations []observation) map[string]interface{} {
+	entries := make([]interface{}, len(observations))
+	for i, obs := range observations {
+		entry := map[string]interface{}{
+			"time":   obs.time.Format(time.RFC3339),
+			"passed": obs.passed,
+		}
+		if obs.status != 0 {
+			entry["status"] = obs.status
+		}
+		if obs.exitCode != 0 {
+			entry["exitCode"] = obs.exitCode
+		}
+		if obs.value != nil {
+			entry["value"] = obs.value
+		}
+		if obs.output != "" {
+			entry["output"] = obs.output
+		}
+		if obs.reason != "" {
+			entry["reason"] = obs.reason
+		}
+		entries[i] = entry
+	}
+
+	data := map[string]interface{}{
+		"checks":       checks,
+		"successes":    successes,
+		"observations": entries,
+	}
+	if last := observations[len(observations)-1]; last.value != nil {
+		data["value"] = last.value
+	}
+	return data
+}
+
+// truncate shortens output kept in the job data
+func truncate(output string) string {
+	if len(output) > maxOutput {
+		return output[:maxOutput] + "..."
+	}
+	return output
+}
+
+// containsInt returns true if value is one of values
+func containsInt(values []int, value int) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// toNumber converts a numeric config value to a float64
+func toNumber(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case float64:
+		return v, true
+	default:
+		return 0, false
+	}
+}
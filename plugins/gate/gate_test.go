@@ -0,0 +1,137 @@
+package gate
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestExecute(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		count := atomic.AddInt32(&requests, 1)
+		switch r.URL.Path {
+		case "/warming":
+			// Unhealthy for the first two checks
+			if count <= 2 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			fmt.Fprint(w, `{"status": "ok"}`)
+		case "/metrics":
+			fmt.Fprintf(w, `{"errors": {"rate": %g}}`, 0.5/float64(count))
+		default:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name            string
+		config          map[string]interface{}
+		expectedSuccess bool
+		expectedChecks  int
+		expectedMessage string
+	}{
+		{
+			name:            "health endpoint becomes ready",
+			config:          map[string]interface{}{"url": server.URL + "/warming", "contains": "ok"},
+			expectedSuccess: true,
+			expectedChecks:  3,
+		},
+		{
+			name: "metric below threshold twice in a row",
+			config: map[string]interface{}{
+				"url":              server.URL + "/metrics",
+				"field":            "errors.rate",
+				"max":              0.2,
+				"successThreshold": 2,
+			},
+			expectedSuccess: true,
+			expectedChecks:  4,
+		},
+		{
+			name:            "never healthy",
+			config:          map[string]interface{}{"url": server.URL + "/down", "timeout": "50ms"},
+			expectedSuccess: false,
+			expectedMessage: "status 503",
+		},
+		{
+			name:            "command",
+			config:          map[string]interface{}{"command": "echo 42", "min": 40},
+			expectedSuccess: true,
+			expectedChecks:  1,
+		},
+		{
+			name:            "failing command",
+			config:          map[string]interface{}{"command": "echo unhealthy >&2; exit 1", "timeout": "30ms"},
+			expectedSuccess: false,
+			expectedMessage: "unhealthy",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			atomic.StoreInt32(&requests, 0)
+			tt.config["interval"] = "5ms"
+
+			plg := &GatePlugin{}
+			if err := plg.Validate(context.Background(), tt.config); err != nil {
+				t.Fatalf("Validate() error = %v", err)
+			}
+
+			result, err := plg.Execute(context.Background(), tt.config)
+			if err != nil {
+				t.Fatalf("Execute() error = %v", err)
+			}
+			if result.Success != tt.expectedSuccess {
+				t.Errorf("Expected success=%v, got %v (%s)", tt.expectedSuccess, result.Success, result.Message)
+			}
+			if tt.expectedChecks != 0 && result.Data["checks"] != tt.expectedChecks {
+				t.Errorf("Expected %d checks, got %v", tt.expectedChecks, result.Data["checks"])
+			}
+			if !strings.Contains(result.Message, tt.expectedMessage) {
+				t.Errorf("Expected message containing %q, got %q", tt.expectedMessage, result.Message)
+			}
+			if observations, _ := result.Data["observations"].([]interface{}); len(observations) == 0 {
+				t.Errorf("Expected observations in the job data, got %v", result.Data)
+			}
+		})
+	}
+}
+
+func TestExecuteCanceled(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	plg := &GatePlugin{}
+	_, err := plg.Execute(ctx, map[string]interface{}{"command": "exit 1", "interval": "5ms"})
+	if err == nil || !strings.Contains(err.Error(), "canceled") {
+		t.Errorf("Expected a cancellation error, got %v", err)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	plg := &GatePlugin{}
+	invalid := []map[string]interface{}{
+		{},
+		{"url": "http://example.com", "command": "true"},
+		{"url": "ftp://example.com"},
+		{"command": "true", "interval": "often"},
+		{"command": "true", "timeout": "0s"},
+		{"command": "true", "successThreshold": 0},
+		{"command": "true", "min": "low"},
+		{"command": "true", "min": 5, "max": 1},
+	}
+
+	for _, config := range invalid {
+		if err := plg.Validate(context.Background(), config); err == nil {
+			t.Errorf("Expected validation error for %v", config)
+		}
+	}
+}
@@ -0,0 +1,322 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cuongtl1992/grp-cli/internal/config"
+	"github.com/cuongtl1992/grp-cli/internal/plugins"
+	"github.com/cuongtl1992/grp-cli/pkg/plugin"
+)
+
+const (
+	// defaultTimeout is used when the job config does not set a timeout
+	defaultTimeout = 30 * time.Second
+	// idempotencyKeyHeader carries the job's idempotency key, so that the server can
+	// recognize a retried request
+	idempotencyKeyHeader = "Idempotency-Key"
+)
+
+// HTTPPlugin implements the Plugin interface for HTTP requests and webhooks
+type HTTPPlugin struct {
+	mutex     sync.Mutex
+	rollbacks map[string][]request
+}
+
+// Plugin is the instance registered as a built-in plugin
+var Plugin HTTPPlugin
+
+func init() {
+	plugins.RegisterBuiltin(&Plugin)
+}
+
+// request describes an HTTP request built from job config
+type request struct {
+	method         string
+	url            string
+	headers        map[string]string
+	body           string
+	expectedStatus []int
+	timeout        time.Duration
+}
+
+// Name returns the plugin name
+func (p *HTTPPlugin) Name() string {
+	return "http"
+}
+
+// Description returns the plugin description
+func (p *HTTPPlugin) Description() string {
+	return "Sends HTTP requests to APIs and webhooks"
+}
+
+// Version returns the plugin version
+func (p *HTTPPlugin) Version() string {
+	return "0.1.0"
+}
+
+// ConfigSchema returns the JSON schema for config validation
+func (p *HTTPPlugin) ConfigSchema() *plugin.JSONSchema {
+	requestProperties := map[string]*plugin.JSONSchema{
+		"method":  {Type: "string"},
+		"url":     {Type: "string"},
+		"headers": {Type: "object"},
+		"body":    {Type: "string"},
+	}
+
+	properties := map[string]*plugin.JSONSchema{
+		"expectedStatus": {Type: "array", Items: &plugin.JSONSchema{Type: "integer"}},
+		"timeout":        {Type: "string"},
+		"rollback":       {Type: "object", Properties: requestProperties, Required: []string{"url"}},
+	}
+	for name, schema := range requestProperties {
+		properties[name] = schema
+	}
+
+	return &plugin.JSONSchema{
+		Type:       "object",
+		Properties: properties,
+		Required:   []string{"url"},
+	}
+}
+
+// Validate checks if the configuration is valid
+func (p *HTTPPlugin) Validate(ctx context.Context, config map[string]interface{}) error {
+	if _, err := parseRequest(config); err != nil {
+		return err
+	}
+
+	if rollback, ok := config["rollback"]; ok {
+		rollbackConfig, ok := rollback.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("rollback must be an object")
+		}
+		if _, err := parseRequest(rollbackConfig); err != nil {
+			return fmt.Errorf("invalid rollback request: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// Execute sends the configured request
+func (p *HTTPPlugin) Execute(ctx context.Context, config map[string]interface{}) (*plugin.Result, error) {
+	executionID := plugin.ExecutionID(ctx)
+
+	config, err := templateConfig(ctx, config)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := parseRequest(config)
+	if err != nil {
+		return nil, err
+	}
+	setIdempotencyKey(req, plugin.IdempotencyKey(ctx))
+
+	status, body, err := send(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	// Remember the compensating request for this execution
+	if rollback, ok := config["rollback"].(map[string]interface{}); ok {
+		if rollbackReq, err := parseRequest(rollback); err == nil {
+			p.mutex.Lock()
+			if p.rollbacks == nil {
+				p.rollbacks = make(map[string][]request)
+			}
+			p.rollbacks[executionID] = append(p.rollbacks[executionID], rollbackReq)
+			p.mutex.Unlock()
+		}
+	}
+
+	success := isExpectedStatus(status, req.expectedStatus)
+	message := fmt.Sprintf("%s %s returned status %d", req.method, req.url, status)
+	if !success {
+		message = fmt.Sprintf("%s (expected %v)", message, req.expectedStatus)
+	}
+
+	return &plugin.Result{
+		Success:     success,
+		Message:     message,
+		ExecutionID: executionID,
+		Data: map[string]interface{}{
+			"status": status,
+			"body":   body,
+		},
+	}, nil
+}
+
+// Capabilities reports that requests can be compensated on rollback
+func (p *HTTPPlugin) Capabilities() plugin.Capabilities {
+	return plugin.Capabilities{Rollback: true}
+}
+
+// CanRollBack reports whether the job has a rollback request to compensate it
+func (p *HTTPPlugin) CanRollBack(config map[string]interface{}) bool {
+	_, ok := config["rollback"]
+	return ok
+}
+
+// Rollback sends the compensating requests recorded for the execution, most recent
+// first. Jobs without a rollback request have nothing to roll back.
+func (p *HTTPPlugin) Rollback(ctx context.Context, executionID string) error {
+	p.mutex.Lock()
+	requests := p.rollbacks[executionID]
+	delete(p.rollbacks, executionID)
+	p.mutex.Unlock()
+	if len(requests) == 0 {
+		return plugin.ErrNothingToRollBack
+	}
+
+	for i := len(requests) - 1; i >= 0; i-- {
+		req := requests[i]
+		status, _, err := send(ctx, req)
+		if err != nil {
+			return fmt.Errorf("rollback request failed: %w", err)
+		}
+		if !isExpectedStatus(status, req.expectedStatus) {
+			return fmt.Errorf("rollback request %s %s returned status %d", req.method, req.url, status)
+		}
+	}
+
+	return nil
+}
+
+// templateConfig resolves ${variables.x} references in the url, body and headers
+// against the variables available in the execution context
+func templateConfig(ctx context.Context, cfg map[string]interface{}) (map[string]interface{}, error) {
+	vars := plugin.Variables(ctx)
+
+	resolved, err := config.NewResolver().ResolveValues(cfg, map[string]interface{}{"variables": vars})
+	if err != nil {
+		return nil, fmt.Errorf("failed to template request: %w", err)
+	}
+	return resolved, nil
+}
+
+// setIdempotencyKey sends the job's idempotency key in an Idempotency-Key header,
+// unless the job sets the header itself
+func setIdempotencyKey(req request, key string) {
+	if key == "" {
+		return
+	}
+	for name := range req.headers {
+		if strings.EqualFold(name, idempotencyKeyHeader) {
+			return
+		}
+	}
+	req.headers[idempotencyKeyHeader] = key
+}
+
+// parseRequest builds a request from job config
+func parseRequest(config map[string]interface{}) (request, error) {
+	req := request{
+		method:         http.MethodGet,
+		headers:        make(map[string]string),
+		expectedStatus: []int{http.StatusOK, http.StatusCreated, http.StatusAccepted, http.StatusNoContent},
+		timeout:        defaultTimeout,
+	}
+
+	url, _ := config["url"].(string)
+	if url == "" {
+		return req, fmt.Errorf("missing required field: url")
+	}
+	if !strings.HasPrefix(url, "http://") && !strings.HasPrefix(url, "https://") {
+		return req, fmt.Errorf("url must start with http:// or https://: %s", url)
+	}
+	req.url = url
+
+	if method, ok := config["method"].(string); ok && method != "" {
+		req.method = strings.ToUpper(method)
+	}
+
+	if body, ok := config["body"].(string); ok {
+		req.body = body
+	}
+
+	if headers, ok := config["headers"].(map[string]interface{}); ok {
+		for key, value := range headers {
+			req.headers[key] = fmt.Sprintf("%v", value)
+		}
+	}
+
+	if statuses, ok := config["expectedStatus"].([]interface{}); ok && len(statuses) > 0 {
+		req.expectedStatus = nil
+		for _, status := range statuses {
+			code, ok := toInt(status)
+			if !ok {
+				return req, fmt.Errorf("expectedStatus must contain integers, got %v", status)
+			}
+			req.expectedStatus = append(req.expectedStatus, code)
+		}
+	}
+
+	if timeout, ok := config["timeout"].(string); ok && timeout != "" {
+		duration, err := time.ParseDuration(timeout)
+		if err != nil {
+			return req, fmt.Errorf("invalid timeout: %w", err)
+		}
+		req.timeout = duration
+	}
+
+	return req, nil
+}
+
+// send performs the request and returns the status code and response body
+func send(ctx context.Context, req request) (int, string, error) {
+	ctx, cancel := context.WithTimeout(ctx, req.timeout)
+	defer cancel()
+
+	httpReq, err := http.NewRequestWithContext(ctx, req.method, req.url, bytes.NewBufferString(req.body))
+	if err != nil {
+		return 0, "", fmt.Errorf("failed to create request: %w", err)
+	}
+	for key, value := range req.headers {
+		httpReq.Header.Set(key, value)
+	}
+
+	resp, err := http.DefaultClient.Do(httpReq)
+	if err != nil {
+		return 0, "", fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return resp.StatusCode, "", fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	return resp.StatusCode, string(body), nil
+}
+
+// isExpectedStatus returns true if status is one of the expected codes
+func isExpectedStatus(status int, expected []int) bool {
+	for _, code := range expected {
+		if status == code {
+			return true
+		}
+	}
+	return false
+}
+
+// toInt converts a numeric config value to an int
+func toInt(value interface{}) (int, bool) {
+	switch v := value.(type) {
+	case int:
+		return v, true
+	case int64:
+		return int(v), true
+	case float64:
+		return int(v), true
+	default:
+		return 0, false
+	}
+}
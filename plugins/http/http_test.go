@@ -0,0 +1,151 @@
+package http
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/cuongtl1992/grp-cli/pkg/plugin"
+)
+
+func TestExecute(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if r.URL.Path == "/fail" {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(r.Method + " " + r.Header.Get("X-Token") + " " + string(body)))
+	}))
+	defer server.Close()
+
+	ctx := plugin.WithVariables(context.Background(), map[string]interface{}{
+		"app": map[string]interface{}{"name": "example"},
+	})
+
+	tests := []struct {
+		name            string
+		config          map[string]interface{}
+		expectedSuccess bool
+		expectedBody    string
+	}{
+		{
+			name: "templated post",
+			config: map[string]interface{}{
+				"method":  "post",
+				"url":     server.URL + "/deploy",
+				"headers": map[string]interface{}{"X-Token": "secret"},
+				"body":    "deploy ${variables.app.name}",
+			},
+			expectedSuccess: true,
+			expectedBody:    "POST secret deploy example",
+		},
+		{
+			name:            "unexpected status",
+			config:          map[string]interface{}{"url": server.URL + "/fail"},
+			expectedSuccess: false,
+		},
+		{
+			name:            "custom expected status",
+			config:          map[string]interface{}{"url": server.URL + "/fail", "expectedStatus": []interface{}{500}},
+			expectedSuccess: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plg := &HTTPPlugin{}
+			if err := plg.Validate(ctx, tt.config); err != nil {
+				t.Fatalf("Validate() error = %v", err)
+			}
+
+			result, err := plg.Execute(ctx, tt.config)
+			if err != nil {
+				t.Fatalf("Execute() error = %v", err)
+			}
+			if result.Success != tt.expectedSuccess {
+				t.Errorf("Expected success=%v, got %v (%s)", tt.expectedSuccess, result.Success, result.Message)
+			}
+			if tt.expectedBody != "" && result.Data["body"] != tt.expectedBody {
+				t.Errorf("Expected body %q, got %q", tt.expectedBody, result.Data["body"])
+			}
+		})
+	}
+}
+
+func TestExecuteIdempotencyKey(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Header.Get("Idempotency-Key")))
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name         string
+		key          string
+		headers      map[string]interface{}
+		expectedBody string
+	}{
+		{name: "job key", key: "job-key", expectedBody: "job-key"},
+		{name: "header set by the job", key: "job-key", headers: map[string]interface{}{"idempotency-key": "custom"}, expectedBody: "custom"},
+		{name: "no key", expectedBody: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.key != "" {
+				ctx = plugin.WithIdempotencyKey(ctx, tt.key)
+			}
+			config := map[string]interface{}{"url": server.URL}
+			if tt.headers != nil {
+				config["headers"] = tt.headers
+			}
+
+			result, err := (&HTTPPlugin{}).Execute(ctx, config)
+			if err != nil {
+				t.Fatalf("Execute() error = %v", err)
+			}
+			if result.Data["body"] != tt.expectedBody {
+				t.Errorf("Expected Idempotency-Key %q, got %q", tt.expectedBody, result.Data["body"])
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	plg := &HTTPPlugin{}
+	invalid := []map[string]interface{}{
+		{},
+		{"url": "ftp://example.com"},
+		{"url": "http://example.com", "timeout": "soon"},
+		{"url": "http://example.com", "rollback": map[string]interface{}{"method": "DELETE"}},
+	}
+
+	for _, config := range invalid {
+		if err := plg.Validate(context.Background(), config); err == nil {
+			t.Errorf("Expected validation error for %v", config)
+		}
+	}
+}
+
+func TestCanRollBack(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   map[string]interface{}
+		expected bool
+	}{
+		{name: "rollback request", config: map[string]interface{}{"url": "https://example.com/deploy", "rollback": map[string]interface{}{"url": "https://example.com/undeploy"}}, expected: true},
+		{name: "no rollback request", config: map[string]interface{}{"url": "https://example.com/deploy"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := (&HTTPPlugin{}).CanRollBack(tt.config); got != tt.expected {
+				t.Errorf("Expected CanRollBack() = %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,282 @@
+package kubernetes
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/cuongtl1992/grp-cli/pkg/plugin"
+)
+
+// blueGreen describes a blue-green release built from config
+type blueGreen struct {
+	namespace string
+	service   string
+	// active selects the pods of the live color, inactive those of the new one
+	active   map[string]string
+	inactive map[string]string
+	manifest string
+	// verifyJobs are the CronJobs run as verification Jobs before switching
+	verifyJobs []string
+	timeout    time.Duration
+}
+
+// blueGreenRevert records how to send a service's traffic back to the previous color
+type blueGreenRevert struct {
+	namespace string
+	service   string
+	selector  map[string]string
+}
+
+// executeBlueGreen deploys the new color alongside the live one, runs the
+// verification jobs against it and then switches the service's selector to it.
+// If the new color does not roll out or fails verification, the resources applied
+// for it are deleted and the service keeps selecting the live color.
+func (p *KubernetesPlugin) executeBlueGreen(ctx context.Context, config map[string]interface{}) (*plugin.Result, error) {
+	executionID := plugin.ExecutionID(ctx)
+
+	bg, err := parseBlueGreen(config)
+	if err != nil {
+		return nil, err
+	}
+
+	current, err := p.serviceSelector(ctx, bg.namespace, bg.service)
+	if err != nil {
+		return nil, err
+	}
+	data := map[string]interface{}{
+		"namespace":        bg.namespace,
+		"resource":         "service/" + bg.service,
+		"action":           actionBlueGreen,
+		"activeSelector":   bg.active,
+		"inactiveSelector": bg.inactive,
+		"switched":         false,
+	}
+	if !reflect.DeepEqual(current, bg.active) {
+		return &plugin.Result{
+			Success:     false,
+			Message:     fmt.Sprintf("service/%s selects %s, not the active selector %s", bg.service, formatSelector(current), formatSelector(bg.active)),
+			ExecutionID: executionID,
+			Data:        data,
+		}, nil
+	}
+
+	// fail deletes what was deployed for the new color and fails the job
+	var deployed []string
+	fail := func(message string) *plugin.Result {
+		if err := p.deleteResources(ctx, bg.namespace, deployed); err != nil {
+			message += fmt.Sprintf(" (revert failed: %v)", err)
+		}
+		return &plugin.Result{Success: false, Message: message, ExecutionID: executionID, Data: data}
+	}
+
+	if bg.manifest != "" {
+		output, err := p.run(ctx, bg.manifest, "apply", "--namespace", bg.namespace, "-f", "-", "-o", "name")
+		deployed = strings.Fields(output)
+		data["deployed"] = deployed
+		if err != nil {
+			return fail(fmt.Sprintf("kubectl apply failed: %v", err)), nil
+		}
+		for _, target := range deployed {
+			if !isDeployment(target) {
+				continue
+			}
+			if _, err := p.waitForRollout(ctx, bg.namespace, target, bg.timeout); err != nil {
+				return fail(err.Error()), nil
+			}
+		}
+	}
+
+	var verification []interface{}
+	for _, cronJob := range bg.verifyJobs {
+		status := p.runVerifyJob(ctx, bg, cronJob, executionID)
+		verification = append(verification, status)
+		data["verification"] = verification
+
+		if status["status"] != "passed" {
+			return fail(fmt.Sprintf("verification %s of service/%s failed: %s", status["job"], bg.service, status["message"])), nil
+		}
+	}
+
+	if err := p.setServiceSelector(ctx, bg.namespace, bg.service, bg.inactive); err != nil {
+		return fail(fmt.Sprintf("switching service/%s failed: %v", bg.service, err)), nil
+	}
+	data["switched"] = true
+
+	// Remember the previous color to send traffic back to if the release is rolled back
+	p.mutex.Lock()
+	if p.blueGreens == nil {
+		p.blueGreens = make(map[string][]blueGreenRevert)
+	}
+	p.blueGreens[executionID] = append(p.blueGreens[executionID], blueGreenRevert{
+		namespace: bg.namespace,
+		service:   bg.service,
+		selector:  bg.active,
+	})
+	p.mutex.Unlock()
+
+	return &plugin.Result{
+		Success:     true,
+		Message:     fmt.Sprintf("Switched service/%s from %s to %s", bg.service, formatSelector(bg.active), formatSelector(bg.inactive)),
+		ExecutionID: executionID,
+		Data:        data,
+	}, nil
+}
+
+// runVerifyJob creates a Job from a verification CronJob and waits for it to
+// complete, and returns the verification's status
+func (p *KubernetesPlugin) runVerifyJob(ctx context.Context, bg blueGreen, cronJob, executionID string) map[string]interface{} {
+	job := verifyJobName(cronJob, executionID)
+	status := map[string]interface{}{"cronJob": cronJob, "job": job}
+
+	if _, err := p.run(ctx, "", "create", "job", job, "--from", "cronjob/"+cronJob, "--namespace", bg.namespace); err != nil {
+		status["status"] = "failed"
+		status["message"] = err.Error()
+		return status
+	}
+	if _, err := p.run(ctx, "", "wait", "--for", "condition=complete", "job/"+job, "--namespace", bg.namespace, "--timeout", bg.timeout.String()); err != nil {
+		status["status"] = "failed"
+		status["message"] = err.Error()
+		return status
+	}
+
+	status["status"] = "passed"
+	return status
+}
+
+// revertBlueGreen sends a service's traffic back to the previous color
+func (p *KubernetesPlugin) revertBlueGreen(ctx context.Context, r blueGreenRevert) error {
+	return p.setServiceSelector(ctx, r.namespace, r.service, r.selector)
+}
+
+// serviceSelector returns the pod selector of a service
+func (p *KubernetesPlugin) serviceSelector(ctx context.Context, namespace, name string) (map[string]string, error) {
+	output, err := p.run(ctx, "", "get", "service", name, "--namespace", namespace, "-o", "json")
+	if err != nil {
+		return nil, err
+	}
+
+	var service struct {
+		Spec struct {
+			Selector map[string]string `json:"selector"`
+		} `json:"spec"`
+	}
+	if err := json.Unmarshal([]byte(output), &service); err != nil {
+		return nil, fmt.Errorf("failed to parse service/%s: %w", name, err)
+	}
+	return service.Spec.Selector, nil
+}
+
+// setServiceSelector replaces the pod selector of a service
+func (p *KubernetesPlugin) setServiceSelector(ctx context.Context, namespace, name string, selector map[string]string) error {
+	patch, err := json.Marshal([]map[string]interface{}{
+		{"op": "replace", "path": "/spec/selector", "value": selector},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to build selector patch: %w", err)
+	}
+	_, err = p.run(ctx, "", "patch", "service", name, "--namespace", namespace, "--type", "json", "-p", string(patch))
+	return err
+}
+
+// deleteResources deletes the kind/name resources, if any
+func (p *KubernetesPlugin) deleteResources(ctx context.Context, namespace string, resources []string) error {
+	if len(resources) == 0 {
+		return nil
+	}
+	args := append([]string{"delete"}, resources...)
+	_, err := p.run(ctx, "", append(args, "--namespace", namespace, "--ignore-not-found")...)
+	return err
+}
+
+// parseBlueGreen builds a blue-green release from config
+func parseBlueGreen(config map[string]interface{}) (blueGreen, error) {
+	var bg blueGreen
+	bg.namespace, _ = config["namespace"].(string)
+
+	resource, _ := config["resource"].(string)
+	kind, name, found := strings.Cut(resource, "/")
+	if !found {
+		kind, name = "service", resource
+	}
+	if kind != "service" && kind != "svc" && kind != "services" || name == "" {
+		return bg, fmt.Errorf("bluegreen action requires a service resource, got %q", resource)
+	}
+	bg.service = name
+
+	var err error
+	if bg.active, err = parseSelector(config, "activeSelector"); err != nil {
+		return bg, err
+	}
+	if bg.inactive, err = parseSelector(config, "inactiveSelector"); err != nil {
+		return bg, err
+	}
+	if reflect.DeepEqual(bg.active, bg.inactive) {
+		return bg, fmt.Errorf("activeSelector and inactiveSelector must differ")
+	}
+
+	bg.manifest, _ = config["manifest"].(string)
+
+	if jobs, ok := config["verifyJobs"].([]interface{}); ok {
+		for _, value := range jobs {
+			job, ok := value.(string)
+			if !ok || job == "" {
+				return bg, fmt.Errorf("verifyJobs must be CronJob names")
+			}
+			bg.verifyJobs = append(bg.verifyJobs, job)
+		}
+	}
+
+	if bg.timeout, err = parseTimeout(config); err != nil {
+		return bg, err
+	}
+	return bg, nil
+}
+
+// parseSelector reads a non-empty label selector from config
+func parseSelector(config map[string]interface{}, field string) (map[string]string, error) {
+	value, _ := config[field].(map[string]interface{})
+	if len(value) == 0 {
+		return nil, fmt.Errorf("%s is required for bluegreen action", field)
+	}
+
+	selector := make(map[string]string, len(value))
+	for key, label := range value {
+		s, ok := label.(string)
+		if !ok {
+			return nil, fmt.Errorf("%s.%s must be a string", field, key)
+		}
+		selector[key] = s
+	}
+	return selector, nil
+}
+
+// formatSelector formats a label selector as kubectl does: key=value pairs by key
+func formatSelector(selector map[string]string) string {
+	pairs := make([]string, 0, len(selector))
+	for key, value := range selector {
+		pairs = append(pairs, key+"="+value)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+// verifyJobName returns the name of the Job run from a verification CronJob for
+// an execution, within the 63 characters allowed for a Job name
+func verifyJobName(cronJob, executionID string) string {
+	suffix := strings.ReplaceAll(executionID, "-", "")
+	if suffix == "" {
+		suffix = fmt.Sprintf("%x", time.Now().Unix())
+	}
+	if len(suffix) > 8 {
+		suffix = suffix[:8]
+	}
+	if limit := 63 - len(suffix) - 1; len(cronJob) > limit {
+		cronJob = strings.TrimRight(cronJob[:limit], "-.")
+	}
+	return strings.ToLower(cronJob + "-" + suffix)
+}
@@ -0,0 +1,205 @@
+package kubernetes
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/cuongtl1992/grp-cli/pkg/plugin"
+)
+
+const blueService = `{"spec": {"selector": {"app": "web", "color": "blue"}}}`
+
+const greenManifest = `{"apiVersion": "apps/v1", "kind": "Deployment", "metadata": {"name": "web-green"}}`
+
+const switchToGreen = `patch service web --namespace prod --type json -p [{"op":"replace","path":"/spec/selector","value":{"app":"web","color":"green"}}]`
+
+func blueGreenConfig(overrides map[string]interface{}) map[string]interface{} {
+	config := map[string]interface{}{
+		"namespace":        "prod",
+		"resource":         "service/web",
+		"action":           "bluegreen",
+		"manifest":         greenManifest,
+		"activeSelector":   map[string]interface{}{"app": "web", "color": "blue"},
+		"inactiveSelector": map[string]interface{}{"app": "web", "color": "green"},
+		"verifyJobs":       []interface{}{"smoke-test"},
+		"timeout":          "30s",
+	}
+	for key, value := range overrides {
+		config[key] = value
+	}
+	return config
+}
+
+func TestExecuteBlueGreen(t *testing.T) {
+	tests := []struct {
+		name          string
+		config        map[string]interface{}
+		service       string
+		fail          string
+		expectedCalls []string
+		wantSuccess   bool
+		wantSwitched  bool
+	}{
+		{
+			name:    "switches after verification",
+			config:  blueGreenConfig(nil),
+			service: blueService,
+			expectedCalls: []string{
+				"get service web --namespace prod -o json",
+				"apply --namespace prod -f - -o name",
+				"get deployment.apps/web-green --namespace prod -o json",
+				"create job smoke-test-exec1 --from cronjob/smoke-test --namespace prod",
+				"wait --for condition=complete job/smoke-test-exec1 --namespace prod --timeout 30s",
+				switchToGreen,
+			},
+			wantSuccess:  true,
+			wantSwitched: true,
+		},
+		{
+			name:    "reverts when verification fails",
+			config:  blueGreenConfig(nil),
+			service: blueService,
+			fail:    "wait",
+			expectedCalls: []string{
+				"get service web --namespace prod -o json",
+				"apply --namespace prod -f - -o name",
+				"get deployment.apps/web-green --namespace prod -o json",
+				"create job smoke-test-exec1 --from cronjob/smoke-test --namespace prod",
+				"wait --for condition=complete job/smoke-test-exec1 --namespace prod --timeout 30s",
+				"delete deployment.apps/web-green --namespace prod --ignore-not-found",
+			},
+		},
+		{
+			name:    "switches without deploying or verifying",
+			config:  blueGreenConfig(map[string]interface{}{"manifest": nil, "verifyJobs": nil}),
+			service: blueService,
+			expectedCalls: []string{
+				"get service web --namespace prod -o json",
+				switchToGreen,
+			},
+			wantSuccess:  true,
+			wantSwitched: true,
+		},
+		{
+			name:    "refuses a service not on the active color",
+			config:  blueGreenConfig(nil),
+			service: `{"spec": {"selector": {"app": "web", "color": "green"}}}`,
+			expectedCalls: []string{
+				"get service web --namespace prod -o json",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kubectl := &fakeKubectl{
+				fail: tt.fail,
+				output: map[string]string{
+					"get service web ":               tt.service,
+					"apply ":                         "deployment.apps/web-green\n",
+					"get deployment.apps/web-green ": rolloutJSON(2, 2, 2),
+				},
+			}
+			p := &KubernetesPlugin{runner: kubectl.run}
+
+			if err := p.Validate(context.Background(), tt.config); err != nil {
+				t.Fatalf("Validate() error = %v", err)
+			}
+
+			ctx := plugin.WithExecutionID(context.Background(), "exec-1")
+			result, err := p.Execute(ctx, tt.config)
+			if err != nil {
+				t.Fatalf("Execute() error = %v", err)
+			}
+			if result.Success != tt.wantSuccess {
+				t.Fatalf("Execute() success = %v, want %v (%s)", result.Success, tt.wantSuccess, result.Message)
+			}
+			if !reflect.DeepEqual(kubectl.calls, tt.expectedCalls) {
+				t.Errorf("kubectl calls = %q, want %q", kubectl.calls, tt.expectedCalls)
+			}
+			if result.Data["switched"] != tt.wantSwitched {
+				t.Errorf("switched = %v, want %v", result.Data["switched"], tt.wantSwitched)
+			}
+		})
+	}
+}
+
+func TestRollbackBlueGreen(t *testing.T) {
+	kubectl := &fakeKubectl{output: map[string]string{"get service web ": blueService}}
+	p := &KubernetesPlugin{runner: kubectl.run}
+
+	ctx := plugin.WithExecutionID(context.Background(), "exec-1")
+	config := blueGreenConfig(map[string]interface{}{"manifest": nil, "verifyJobs": nil})
+	if result, err := p.Execute(ctx, config); err != nil || !result.Success {
+		t.Fatalf("Execute() = %v, %v", result, err)
+	}
+
+	kubectl.calls = nil
+	if err := p.Rollback(context.Background(), "exec-1"); err != nil {
+		t.Fatalf("Rollback() error = %v", err)
+	}
+	expected := []string{
+		`patch service web --namespace prod --type json -p [{"op":"replace","path":"/spec/selector","value":{"app":"web","color":"blue"}}]`,
+	}
+	if !reflect.DeepEqual(kubectl.calls, expected) {
+		t.Errorf("rollback calls = %q, want %q", kubectl.calls, expected)
+	}
+
+	// The revert is only applied once
+	kubectl.calls = nil
+	if err := p.Rollback(context.Background(), "exec-1"); !errors.Is(err, plugin.ErrNothingToRollBack) || len(kubectl.calls) > 0 {
+		t.Errorf("second Rollback() = %v, calls %q", err, kubectl.calls)
+	}
+}
+
+func TestValidateBlueGreen(t *testing.T) {
+	tests := []struct {
+		name      string
+		overrides map[string]interface{}
+		wantErr   string
+	}{
+		{name: "valid", overrides: nil},
+		{name: "plain service name", overrides: map[string]interface{}{"resource": "web"}},
+		{name: "not a service", overrides: map[string]interface{}{"resource": "deployment/web"}, wantErr: "requires a service resource"},
+		{name: "missing active selector", overrides: map[string]interface{}{"activeSelector": nil}, wantErr: "activeSelector is required"},
+		{name: "non-string label", overrides: map[string]interface{}{"inactiveSelector": map[string]interface{}{"color": 2}}, wantErr: "inactiveSelector.color must be a string"},
+		{name: "same selectors", overrides: map[string]interface{}{"inactiveSelector": map[string]interface{}{"app": "web", "color": "blue"}}, wantErr: "must differ"},
+		{name: "empty verify job", overrides: map[string]interface{}{"verifyJobs": []interface{}{""}}, wantErr: "verifyJobs must be CronJob names"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := (&KubernetesPlugin{}).Validate(context.Background(), blueGreenConfig(tt.overrides))
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Validate() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Validate() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestVerifyJobName(t *testing.T) {
+	tests := []struct {
+		cronJob     string
+		executionID string
+		expected    string
+	}{
+		{cronJob: "smoke-test", executionID: "0f8a1c2e-7b3d-4e5f", expected: "smoke-test-0f8a1c2e"},
+		{cronJob: "Smoke", executionID: "exec-1", expected: "smoke-exec1"},
+		{cronJob: strings.Repeat("a", 60), executionID: "12345678", expected: strings.Repeat("a", 54) + "-12345678"},
+	}
+
+	for _, tt := range tests {
+		if got := verifyJobName(tt.cronJob, tt.executionID); got != tt.expected {
+			t.Errorf("verifyJobName(%q, %q) = %q, want %q", tt.cronJob, tt.executionID, got, tt.expected)
+		}
+	}
+}
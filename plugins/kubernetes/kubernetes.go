@@ -1,41 +1,113 @@
-package main
+package kubernetes
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
+	"math"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
+	"github.com/google/uuid"
+
+	"github.com/cuongtl1992/grp-cli/internal/plugins"
 	"github.com/cuongtl1992/grp-cli/pkg/plugin"
 )
 
+// Supported actions
+const (
+	actionApply   = "apply"
+	actionDelete  = "delete"
+	actionRestart = "restart"
+	actionScale   = "scale"
+	actionCanary  = "canary"
+	// actionBlueGreen switches a service to a new color of pods once verified
+	actionBlueGreen = "bluegreen"
+)
+
+const (
+	// canaryTrackLabel marks the pods of a canary deployment
+	canaryTrackLabel = "track"
+	// defaultCanaryPause is the wait between canary steps when no pause is set
+	defaultCanaryPause = 30 * time.Second
+	// defaultTimeout bounds the wait for pods to be ready when no timeout is set
+	defaultTimeout = 5 * time.Minute
+	// defaultPollInterval is the wait between checks of a deployment's rollout status
+	defaultPollInterval = 2 * time.Second
+)
+
+// kubectlRunner runs kubectl with the given stdin and arguments and returns its output
+type kubectlRunner func(ctx context.Context, stdin string, args ...string) (string, error)
+
 // KubernetesPlugin implements the Plugin interface for Kubernetes deployments
-type KubernetesPlugin struct{}
+type KubernetesPlugin struct {
+	mutex sync.Mutex
+	// canaries holds the canary rollouts of each execution, to revert on rollback
+	canaries map[string][]canaryRevert
+	// blueGreens holds the service switches of each execution, to revert on rollback
+	blueGreens map[string][]blueGreenRevert
+	// runner invokes kubectl; nil uses the kubectl binary on PATH
+	runner kubectlRunner
+	// pollInterval is the wait between rollout status checks; 0 uses defaultPollInterval
+	pollInterval time.Duration
+}
 
-// Export the plugin
+// Plugin is the instance registered as a built-in plugin
 var Plugin KubernetesPlugin
 
+func init() {
+	plugins.RegisterBuiltin(&Plugin)
+}
+
+// canary describes a canary rollout built from config
+type canary struct {
+	namespace  string
+	deployment string
+	image      string
+	container  string
+	percentage int
+	steps      []int
+	pause      time.Duration
+	timeout    time.Duration
+}
+
+// canaryRevert records how to restore the stable version of a deployment
+type canaryRevert struct {
+	namespace     string
+	deployment    string
+	container     string
+	previousImage string
+	replicas      int
+	promoted      bool
+}
+
 // Name returns the plugin name
-func (p KubernetesPlugin) Name() string {
+func (p *KubernetesPlugin) Name() string {
 	return "kubernetes"
 }
 
 // Description returns the plugin description
-func (p KubernetesPlugin) Description() string {
+func (p *KubernetesPlugin) Description() string {
 	return "Manages Kubernetes deployments, services, and other resources"
 }
 
 // Version returns the plugin version
-func (p KubernetesPlugin) Version() string {
+func (p *KubernetesPlugin) Version() string {
 	return "0.1.0"
 }
 
 // ConfigSchema returns the JSON schema for config validation
-func (p KubernetesPlugin) ConfigSchema() *plugin.JSONSchema {
+func (p *KubernetesPlugin) ConfigSchema() *plugin.JSONSchema {
 	return &plugin.JSONSchema{
 		Type: "object",
 		Properties: map[string]*plugin.JSONSchema{
 			"namespace": {
-				Type: "string",
+				Type:    "string",
+				Default: "default",
 			},
 			"resource": {
 				Type: "string",
@@ -52,13 +124,42 @@ func (p KubernetesPlugin) ConfigSchema() *plugin.JSONSchema {
 			"timeout": {
 				Type: "string",
 			},
+			"replicas": {
+				Type: "integer",
+			},
+			"image": {
+				Type: "string",
+			},
+			"container": {
+				Type: "string",
+			},
+			"percentage": {
+				Type: "integer",
+			},
+			"steps": {
+				Type:  "array",
+				Items: &plugin.JSONSchema{Type: "integer"},
+			},
+			"pause": {
+				Type: "string",
+			},
+			"activeSelector": {
+				Type: "object",
+			},
+			"inactiveSelector": {
+				Type: "object",
+			},
+			"verifyJobs": {
+				Type:  "array",
+				Items: &plugin.JSONSchema{Type: "string"},
+			},
 		},
-		Required: []string{"namespace", "resource", "action"},
+		Required: []string{"resource", "action"},
 	}
 }
 
 // Validate checks if the configuration is valid
-func (p KubernetesPlugin) Validate(ctx context.Context, config map[string]interface{}) error {
+func (p *KubernetesPlugin) Validate(ctx context.Context, config map[string]interface{}) error {
 	// Check required fields
 	requiredFields := []string{"namespace", "resource", "action"}
 	for _, field := range requiredFields {
@@ -66,58 +167,720 @@ func (p KubernetesPlugin) Validate(ctx context.Context, config map[string]interf
 			return fmt.Errorf("missing required field: %s", field)
 		}
 	}
-	
+
 	// Validate action
 	action, _ := config["action"].(string)
 	validActions := map[string]bool{
-		"apply":   true,
-		"delete":  true,
-		"restart": true,
-		"scale":   true,
+		actionApply:     true,
+		actionDelete:    true,
+		actionRestart:   true,
+		actionScale:     true,
+		actionCanary:    true,
+		actionBlueGreen: true,
 	}
-	
+
 	if !validActions[action] {
 		return fmt.Errorf("invalid action: %s", action)
 	}
-	
+
 	// If action is apply, manifest is required
-	if action == "apply" && config["manifest"] == nil {
+	if action == actionApply && config["manifest"] == nil {
 		return fmt.Errorf("manifest is required for apply action")
 	}
-	
+
+	if action == actionScale {
+		if replicas, ok := toInt(config["replicas"]); !ok || replicas < 0 {
+			return fmt.Errorf("replicas is required for scale action")
+		}
+	}
+
+	if _, err := parseTimeout(config); err != nil {
+		return err
+	}
+
+	if action == actionCanary {
+		if _, err := parseCanary(config); err != nil {
+			return err
+		}
+	}
+
+	if action == actionBlueGreen {
+		if _, err := parseBlueGreen(config); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
-// Execute runs the plugin
-func (p KubernetesPlugin) Execute(ctx context.Context, config map[string]interface{}) (*plugin.Result, error) {
+// Execute runs the configured kubectl action. With wait set, it then polls the
+// deployments it changed until their rollout is complete or the timeout expires.
+func (p *KubernetesPlugin) Execute(ctx context.Context, config map[string]interface{}) (*plugin.Result, error) {
+	// The changes to revert are recorded by execution ID, so a plugin executed
+	// outside of an execution gets its own ID, returned in the result for Rollback
+	executionID := plugin.ExecutionID(ctx)
+	if executionID == "" {
+		executionID = uuid.New().String()
+		ctx = plugin.WithExecutionID(ctx, executionID)
+	}
+
 	// Extract configuration
-	namespace := config["namespace"].(string)
-	resource := config["resource"].(string)
-	action := config["action"].(string)
-	
-	// Simulate execution
-	fmt.Printf("Executing Kubernetes plugin: %s %s in namespace %s\n", action, resource, namespace)
-	time.Sleep(500 * time.Millisecond)
-	
-	// Create result
-	result := &plugin.Result{
+	namespace, _ := config["namespace"].(string)
+	resource, _ := config["resource"].(string)
+	action, _ := config["action"].(string)
+	wait, _ := config["wait"].(bool)
+	timeout, err := parseTimeout(config)
+	if err != nil {
+		return nil, err
+	}
+
+	if action == actionCanary {
+		return p.executeCanary(ctx, config)
+	}
+	if action == actionBlueGreen {
+		return p.executeBlueGreen(ctx, config)
+	}
+
+	// The resources whose rollout to wait for
+	targets := []string{resource}
+
+	switch action {
+	case actionApply:
+		manifest, _ := config["manifest"].(string)
+		output, err := p.run(ctx, manifest, "apply", "--namespace", namespace, "-f", "-", "-o", "name")
+		if err != nil {
+			return failure(executionID, "kubectl apply failed", err), nil
+		}
+		targets = strings.Fields(output)
+
+	case actionDelete:
+		args := []string{"delete", resource, "--namespace", namespace, "--wait=" + strconv.FormatBool(wait)}
+		if wait {
+			args = append(args, "--timeout", timeout.String())
+		}
+		if _, err := p.run(ctx, "", args...); err != nil {
+			return failure(executionID, "kubectl delete failed", err), nil
+		}
+		// kubectl already waited for the deletion
+		targets = nil
+
+	case actionRestart:
+		if _, err := p.run(ctx, "", "rollout", "restart", resource, "--namespace", namespace); err != nil {
+			return failure(executionID, "kubectl rollout restart failed", err), nil
+		}
+
+	case actionScale:
+		replicas, _ := toInt(config["replicas"])
+		if _, err := p.run(ctx, "", "scale", resource, "--namespace", namespace, "--replicas", strconv.Itoa(replicas)); err != nil {
+			return failure(executionID, "kubectl scale failed", err), nil
+		}
+
+	default:
+		return nil, fmt.Errorf("invalid action: %s", action)
+	}
+
+	data := map[string]interface{}{
+		"namespace": namespace,
+		"resource":  resource,
+		"action":    action,
+		"timestamp": time.Now().Format(time.RFC3339),
+	}
+
+	if wait {
+		desired := make(map[string]interface{})
+		ready := make(map[string]interface{})
+		data["desiredReplicas"] = desired
+		data["readyReplicas"] = ready
+
+		for _, target := range targets {
+			if !isDeployment(target) {
+				continue
+			}
+			rollout, err := p.waitForRollout(ctx, namespace, target, timeout)
+			desired[target] = rollout.desired
+			ready[target] = rollout.ready
+			if err != nil {
+				return &plugin.Result{
+					Success:     false,
+					Message:     err.Error(),
+					ExecutionID: executionID,
+					Data:        data,
+				}, nil
+			}
+		}
+	}
+
+	return &plugin.Result{
 		Success:     true,
 		Message:     fmt.Sprintf("Successfully executed %s on %s in namespace %s", action, resource, namespace),
-		ExecutionID: ctx.Value("executionID").(string),
-		Data: map[string]interface{}{
-			"namespace": namespace,
-			"resource":  resource,
-			"action":    action,
-			"timestamp": time.Now().Format(time.RFC3339),
-		},
+		ExecutionID: executionID,
+		Data:        data,
+	}, nil
+}
+
+// Preview shows the changes an apply job would make to the cluster, as reported
+// by kubectl diff. Other actions have no preview.
+func (p *KubernetesPlugin) Preview(ctx context.Context, config map[string]interface{}) (string, error) {
+	action, _ := config["action"].(string)
+	if action != actionApply {
+		return "", nil
+	}
+	namespace, _ := config["namespace"].(string)
+	manifest, _ := config["manifest"].(string)
+
+	// kubectl diff exits with status 1 when there are differences, and prints them
+	output, err := p.run(ctx, manifest, "diff", "--namespace", namespace, "-f", "-")
+	if err != nil && output == "" {
+		return "", err
 	}
-	
-	return result, nil
+	if output == "" {
+		return "no changes", nil
+	}
+	return output, nil
+}
+
+// Capabilities reports that canaries can be reverted on rollback
+func (p *KubernetesPlugin) Capabilities() plugin.Capabilities {
+	return plugin.Capabilities{Rollback: true}
 }
 
-// Rollback reverts changes
-func (p KubernetesPlugin) Rollback(ctx context.Context, executionID string) error {
-	fmt.Printf("Rolling back Kubernetes changes for execution %s\n", executionID)
-	time.Sleep(300 * time.Millisecond)
+// CanRollBack reports whether the job is a canary, the only action reverted on rollback
+func (p *KubernetesPlugin) CanRollBack(config map[string]interface{}) bool {
+	action, _ := config["action"].(string)
+	return action == actionCanary
+}
+
+// Rollback restores the stable version of the deployments canaried by the
+// execution, and the previous color of the services it switched
+func (p *KubernetesPlugin) Rollback(ctx context.Context, executionID string) error {
+	p.mutex.Lock()
+	reverts := p.canaries[executionID]
+	delete(p.canaries, executionID)
+	switches := p.blueGreens[executionID]
+	delete(p.blueGreens, executionID)
+	p.mutex.Unlock()
+	if len(reverts) == 0 && len(switches) == 0 {
+		return plugin.ErrNothingToRollBack
+	}
+
+	var errs []string
+	for i := len(switches) - 1; i >= 0; i-- {
+		if err := p.revertBlueGreen(ctx, switches[i]); err != nil {
+			errs = append(errs, fmt.Sprintf("failed to revert service/%s: %v", switches[i].service, err))
+		}
+	}
+	for i := len(reverts) - 1; i >= 0; i-- {
+		if err := p.revertCanary(ctx, reverts[i]); err != nil {
+			errs = append(errs, fmt.Sprintf("failed to revert canary of deployment/%s: %v", reverts[i].deployment, err))
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("rollback failed: %s", strings.Join(errs, "; "))
+	}
 	return nil
-} 
\ No newline at end of file
+}
+
+// executeCanary shifts traffic to a canary deployment of the new image one step at
+// a time, checking the canary pods are healthy after each step's pause. Traffic is
+// shifted by replica count: the canary pods share the stable pods' labels, so the
+// services selecting the stable pods also route to them. Reaching 100% promotes the
+// image to the stable deployment and removes the canary.
+func (p *KubernetesPlugin) executeCanary(ctx context.Context, config map[string]interface{}) (*plugin.Result, error) {
+	executionID := plugin.ExecutionID(ctx)
+
+	c, err := parseCanary(config)
+	if err != nil {
+		return nil, err
+	}
+
+	stable, err := p.getDeployment(ctx, c.namespace, c.deployment)
+	if err != nil {
+		return nil, err
+	}
+	replicas, container, previousImage, err := stableState(stable, c.container)
+	if err != nil {
+		return nil, fmt.Errorf("deployment/%s: %w", c.deployment, err)
+	}
+	c.container = container
+
+	revert := canaryRevert{
+		namespace:     c.namespace,
+		deployment:    c.deployment,
+		container:     c.container,
+		previousImage: previousImage,
+		replicas:      replicas,
+	}
+	data := map[string]interface{}{
+		"namespace":     c.namespace,
+		"resource":      "deployment/" + c.deployment,
+		"action":        actionCanary,
+		"image":         c.image,
+		"previousImage": previousImage,
+		"percentage":    c.percentage,
+		"promoted":      false,
+	}
+
+	var steps []interface{}
+	for _, percentage := range c.steps {
+		status := p.runCanaryStep(ctx, c, stable, replicas, percentage)
+		steps = append(steps, status)
+		data["steps"] = steps
+
+		if status["status"] != "healthy" {
+			// Send all traffic back to the stable pods
+			message := fmt.Sprintf("canary of deployment/%s failed at %d%%: %s", c.deployment, percentage, status["message"])
+			if err := p.revertCanary(ctx, revert); err != nil {
+				message += fmt.Sprintf(" (revert failed: %v)", err)
+			}
+			return &plugin.Result{Success: false, Message: message, ExecutionID: executionID, Data: data}, nil
+		}
+	}
+
+	if c.percentage == 100 {
+		revert.promoted = true
+		if err := p.promoteCanary(ctx, c, replicas); err != nil {
+			message := fmt.Sprintf("promotion of deployment/%s failed: %v", c.deployment, err)
+			if err := p.revertCanary(ctx, revert); err != nil {
+				message += fmt.Sprintf(" (revert failed: %v)", err)
+			}
+			return &plugin.Result{Success: false, Message: message, ExecutionID: executionID, Data: data}, nil
+		}
+		data["promoted"] = true
+	}
+
+	// Remember the stable version to restore if the release is rolled back
+	p.mutex.Lock()
+	if p.canaries == nil {
+		p.canaries = make(map[string][]canaryRevert)
+	}
+	p.canaries[executionID] = append(p.canaries[executionID], revert)
+	p.mutex.Unlock()
+
+	return &plugin.Result{
+		Success:     true,
+		Message:     fmt.Sprintf("Canary of %s on deployment/%s reached %d%%", c.image, c.deployment, c.percentage),
+		ExecutionID: executionID,
+		Data:        data,
+	}, nil
+}
+
+// runCanaryStep scales the canary and stable deployments so the canary serves
+// percentage of the traffic, then checks the canary pods are healthy, and returns
+// the step's status
+func (p *KubernetesPlugin) runCanaryStep(ctx context.Context, c canary, stable map[string]interface{}, replicas, percentage int) map[string]interface{} {
+	canaryReplicas := int(math.Ceil(float64(replicas) * float64(percentage) / 100))
+	status := map[string]interface{}{
+		"percentage":     percentage,
+		"canaryReplicas": canaryReplicas,
+		"stableReplicas": replicas - canaryReplicas,
+	}
+	fail := func(err error) map[string]interface{} {
+		status["status"] = "failed"
+		status["message"] = err.Error()
+		return status
+	}
+
+	manifest, err := canaryManifest(stable, c, canaryReplicas)
+	if err != nil {
+		return fail(err)
+	}
+	if _, err := p.run(ctx, manifest, "apply", "--namespace", c.namespace, "-f", "-"); err != nil {
+		return fail(err)
+	}
+	if _, err := p.run(ctx, "", "rollout", "status", "deployment/"+canaryName(c.deployment), "--namespace", c.namespace, "--timeout", c.timeout.String()); err != nil {
+		return fail(err)
+	}
+	if _, err := p.run(ctx, "", "scale", "deployment/"+c.deployment, "--namespace", c.namespace, "--replicas", strconv.Itoa(replicas-canaryReplicas)); err != nil {
+		return fail(err)
+	}
+
+	// Let the canary serve traffic before checking its health
+	select {
+	case <-ctx.Done():
+		return fail(ctx.Err())
+	case <-time.After(c.pause):
+	}
+
+	ready, err := p.run(ctx, "", "get", "deployment", canaryName(c.deployment), "--namespace", c.namespace, "-o", "jsonpath={.status.readyReplicas}")
+	if err != nil {
+		return fail(err)
+	}
+	readyReplicas, _ := strconv.Atoi(strings.TrimSpace(ready))
+	status["readyReplicas"] = readyReplicas
+	if readyReplicas < canaryReplicas {
+		status["status"] = "unhealthy"
+		status["message"] = fmt.Sprintf("%d of %d canary replicas ready", readyReplicas, canaryReplicas)
+		return status
+	}
+
+	status["status"] = "healthy"
+	return status
+}
+
+// promoteCanary rolls the new image out to the stable deployment at full scale and
+// removes the canary deployment
+func (p *KubernetesPlugin) promoteCanary(ctx context.Context, c canary, replicas int) error {
+	resource := "deployment/" + c.deployment
+	if _, err := p.run(ctx, "", "set", "image", resource, c.container+"="+c.image, "--namespace", c.namespace); err != nil {
+		return err
+	}
+	if _, err := p.run(ctx, "", "scale", resource, "--namespace", c.namespace, "--replicas", strconv.Itoa(replicas)); err != nil {
+		return err
+	}
+	if _, err := p.run(ctx, "", "rollout", "status", resource, "--namespace", c.namespace, "--timeout", c.timeout.String()); err != nil {
+		return err
+	}
+	_, err := p.run(ctx, "", "delete", "deployment", canaryName(c.deployment), "--namespace", c.namespace, "--ignore-not-found")
+	return err
+}
+
+// revertCanary restores the previous image and replica count of the stable
+// deployment and removes the canary deployment
+func (p *KubernetesPlugin) revertCanary(ctx context.Context, r canaryRevert) error {
+	resource := "deployment/" + r.deployment
+	if r.promoted {
+		if _, err := p.run(ctx, "", "set", "image", resource, r.container+"="+r.previousImage, "--namespace", r.namespace); err != nil {
+			return err
+		}
+	}
+	if _, err := p.run(ctx, "", "scale", resource, "--namespace", r.namespace, "--replicas", strconv.Itoa(r.replicas)); err != nil {
+		return err
+	}
+	_, err := p.run(ctx, "", "delete", "deployment", canaryName(r.deployment), "--namespace", r.namespace, "--ignore-not-found")
+	return err
+}
+
+// rolloutStatus holds the replica counts of a deployment
+type rolloutStatus struct {
+	desired   int
+	ready     int
+	updated   int
+	available int
+	current   int
+	// observed is false until the controller has seen the latest spec
+	observed bool
+}
+
+// complete reports whether every replica runs the latest spec and is available
+func (s rolloutStatus) complete() bool {
+	return s.observed && s.updated >= s.desired && s.current == s.updated && s.available >= s.desired
+}
+
+// waitForRollout polls a deployment until its rollout is complete, and returns
+// the last observed replica counts. It fails once timeout has elapsed.
+func (p *KubernetesPlugin) waitForRollout(ctx context.Context, namespace, deployment string, timeout time.Duration) (rolloutStatus, error) {
+	interval := p.pollInterval
+	if interval <= 0 {
+		interval = defaultPollInterval
+	}
+	deadline := time.After(timeout)
+
+	var status rolloutStatus
+	for {
+		output, err := p.run(ctx, "", "get", deployment, "--namespace", namespace, "-o", "json")
+		if err != nil {
+			return status, fmt.Errorf("failed to get rollout status of %s: %w", deployment, err)
+		}
+		status, err = parseRolloutStatus(output)
+		if err != nil {
+			return status, fmt.Errorf("failed to parse rollout status of %s: %w", deployment, err)
+		}
+		if status.complete() {
+			return status, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return status, fmt.Errorf("stopped waiting for %s: %w", deployment, ctx.Err())
+		case <-deadline:
+			return status, fmt.Errorf("timed out after %s waiting for %s to be ready: %d of %d replicas ready",
+				timeout, deployment, status.ready, status.desired)
+		case <-time.After(interval):
+		}
+	}
+}
+
+// parseRolloutStatus reads the replica counts of a deployment from its JSON
+func parseRolloutStatus(output string) (rolloutStatus, error) {
+	var deployment struct {
+		Metadata struct {
+			Generation int64 `json:"generation"`
+		} `json:"metadata"`
+		Spec struct {
+			Replicas *int `json:"replicas"`
+		} `json:"spec"`
+		Status struct {
+			ObservedGeneration int64 `json:"observedGeneration"`
+			Replicas           int   `json:"replicas"`
+			ReadyReplicas      int   `json:"readyReplicas"`
+			UpdatedReplicas    int   `json:"updatedReplicas"`
+			AvailableReplicas  int   `json:"availableReplicas"`
+		} `json:"status"`
+	}
+	if err := json.Unmarshal([]byte(output), &deployment); err != nil {
+		return rolloutStatus{}, err
+	}
+
+	// Kubernetes defaults an unset replica count to 1
+	desired := 1
+	if deployment.Spec.Replicas != nil {
+		desired = *deployment.Spec.Replicas
+	}
+	return rolloutStatus{
+		desired:   desired,
+		ready:     deployment.Status.ReadyReplicas,
+		updated:   deployment.Status.UpdatedReplicas,
+		available: deployment.Status.AvailableReplicas,
+		current:   deployment.Status.Replicas,
+		observed:  deployment.Status.ObservedGeneration >= deployment.Metadata.Generation,
+	}, nil
+}
+
+// isDeployment reports whether a kind/name resource reference names a deployment
+func isDeployment(resource string) bool {
+	kind, _, found := strings.Cut(resource, "/")
+	if !found {
+		return false
+	}
+	switch strings.ToLower(kind) {
+	case "deployment", "deployments", "deploy", "deployment.apps", "deployments.apps":
+		return true
+	}
+	return false
+}
+
+// getDeployment fetches a deployment as decoded JSON
+func (p *KubernetesPlugin) getDeployment(ctx context.Context, namespace, name string) (map[string]interface{}, error) {
+	output, err := p.run(ctx, "", "get", "deployment", name, "--namespace", namespace, "-o", "json")
+	if err != nil {
+		return nil, err
+	}
+
+	var deployment map[string]interface{}
+	if err := json.Unmarshal([]byte(output), &deployment); err != nil {
+		return nil, fmt.Errorf("failed to parse deployment/%s: %w", name, err)
+	}
+	return deployment, nil
+}
+
+// parseCanary builds a canary rollout from config
+func parseCanary(config map[string]interface{}) (canary, error) {
+	c := canary{percentage: 100, pause: defaultCanaryPause}
+	c.namespace, _ = config["namespace"].(string)
+
+	resource, _ := config["resource"].(string)
+	kind, name, found := strings.Cut(resource, "/")
+	if !found {
+		kind, name = "deployment", resource
+	}
+	if kind != "deployment" && kind != "deploy" && kind != "deployments" || name == "" {
+		return c, fmt.Errorf("canary action requires a deployment resource, got %q", resource)
+	}
+	c.deployment = name
+
+	c.image, _ = config["image"].(string)
+	if c.image == "" {
+		return c, fmt.Errorf("image is required for canary action")
+	}
+	c.container, _ = config["container"].(string)
+
+	if value, ok := config["percentage"]; ok {
+		percentage, ok := toInt(value)
+		if !ok || percentage < 1 || percentage > 100 {
+			return c, fmt.Errorf("percentage must be between 1 and 100")
+		}
+		c.percentage = percentage
+	}
+
+	if steps, ok := config["steps"].([]interface{}); ok {
+		for _, value := range steps {
+			step, ok := toInt(value)
+			if !ok || step < 1 || step > c.percentage {
+				return c, fmt.Errorf("steps must be percentages between 1 and %d", c.percentage)
+			}
+			if len(c.steps) > 0 && step <= c.steps[len(c.steps)-1] {
+				return c, fmt.Errorf("steps must be in increasing order")
+			}
+			c.steps = append(c.steps, step)
+		}
+	}
+	// Always finish at the target percentage
+	if len(c.steps) == 0 || c.steps[len(c.steps)-1] != c.percentage {
+		c.steps = append(c.steps, c.percentage)
+	}
+
+	if pause, ok := config["pause"].(string); ok && pause != "" {
+		duration, err := time.ParseDuration(pause)
+		if err != nil {
+			return c, fmt.Errorf("invalid pause %q: %w", pause, err)
+		}
+		c.pause = duration
+	}
+
+	timeout, err := parseTimeout(config)
+	if err != nil {
+		return c, err
+	}
+	c.timeout = timeout
+
+	return c, nil
+}
+
+// parseTimeout returns the configured timeout, or defaultTimeout if none is set
+func parseTimeout(config map[string]interface{}) (time.Duration, error) {
+	value, ok := config["timeout"].(string)
+	if !ok || value == "" {
+		return defaultTimeout, nil
+	}
+
+	timeout, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid timeout %q: %w", value, err)
+	}
+	if timeout <= 0 {
+		return 0, fmt.Errorf("timeout must be positive, got %q", value)
+	}
+	return timeout, nil
+}
+
+// stableState returns the replica count of a deployment and the name and image of
+// its container, defaulting container to the first one
+func stableState(deployment map[string]interface{}, container string) (int, string, string, error) {
+	spec, _ := deployment["spec"].(map[string]interface{})
+	replicas, ok := toInt(spec["replicas"])
+	if !ok || replicas < 1 {
+		return 0, "", "", fmt.Errorf("deployment has no replicas to shift traffic from")
+	}
+
+	for _, value := range podContainers(deployment) {
+		c, _ := value.(map[string]interface{})
+		name, _ := c["name"].(string)
+		if container == "" || name == container {
+			image, _ := c["image"].(string)
+			return replicas, name, image, nil
+		}
+	}
+	return 0, "", "", fmt.Errorf("container %q not found", container)
+}
+
+// canaryManifest derives the canary deployment from the stable one: same pod
+// template with the new image, and a track label so it owns its own pods
+func canaryManifest(stable map[string]interface{}, c canary, replicas int) (string, error) {
+	spec, _ := stable["spec"].(map[string]interface{})
+	metadata, _ := stable["metadata"].(map[string]interface{})
+	selector, _ := spec["selector"].(map[string]interface{})
+	template, _ := spec["template"].(map[string]interface{})
+	templateMetadata, _ := template["metadata"].(map[string]interface{})
+
+	containers := make([]interface{}, 0)
+	for _, value := range podContainers(stable) {
+		container, _ := value.(map[string]interface{})
+		if container["name"] == c.container {
+			container = withEntry(container, "image", c.image)
+		}
+		containers = append(containers, container)
+	}
+	podSpec, _ := template["spec"].(map[string]interface{})
+
+	manifest := map[string]interface{}{
+		"apiVersion": "apps/v1",
+		"kind":       "Deployment",
+		"metadata": map[string]interface{}{
+			"name":      canaryName(c.deployment),
+			"namespace": c.namespace,
+			"labels":    withEntry(asMap(metadata["labels"]), canaryTrackLabel, "canary"),
+		},
+		"spec": map[string]interface{}{
+			"replicas": replicas,
+			"selector": map[string]interface{}{
+				"matchLabels": withEntry(asMap(selector["matchLabels"]), canaryTrackLabel, "canary"),
+			},
+			"template": map[string]interface{}{
+				"metadata": withEntry(templateMetadata, "labels", withEntry(asMap(templateMetadata["labels"]), canaryTrackLabel, "canary")),
+				"spec":     withEntry(podSpec, "containers", containers),
+			},
+		},
+	}
+
+	data, err := json.Marshal(manifest)
+	if err != nil {
+		return "", fmt.Errorf("failed to build canary manifest: %w", err)
+	}
+	return string(data), nil
+}
+
+// podContainers returns the containers of a deployment's pod template
+func podContainers(deployment map[string]interface{}) []interface{} {
+	spec, _ := deployment["spec"].(map[string]interface{})
+	template, _ := spec["template"].(map[string]interface{})
+	podSpec, _ := template["spec"].(map[string]interface{})
+	containers, _ := podSpec["containers"].([]interface{})
+	return containers
+}
+
+// canaryName returns the name of the canary deployment of a deployment
+func canaryName(deployment string) string {
+	return deployment + "-canary"
+}
+
+// asMap returns value as a map, or nil if it is not one
+func asMap(value interface{}) map[string]interface{} {
+	m, _ := value.(map[string]interface{})
+	return m
+}
+
+// withEntry returns a copy of m with key set to value
+func withEntry(m map[string]interface{}, key string, value interface{}) map[string]interface{} {
+	copied := make(map[string]interface{}, len(m)+1)
+	for k, v := range m {
+		copied[k] = v
+	}
+	copied[key] = value
+	return copied
+}
+
+// toInt converts a YAML or JSON number to an int
+func toInt(value interface{}) (int, bool) {
+	switch v := value.(type) {
+	case int:
+		return v, true
+	case int64:
+		return int(v), true
+	case float64:
+		return int(v), v == math.Trunc(v)
+	}
+	return 0, false
+}
+
+// run invokes kubectl
+func (p *KubernetesPlugin) run(ctx context.Context, stdin string, args ...string) (string, error) {
+	runner := p.runner
+	if runner == nil {
+		runner = runKubectl
+	}
+	return runner(ctx, stdin, args...)
+}
+
+// failure builds an unsuccessful result for a failed kubectl command
+func failure(executionID, message string, err error) *plugin.Result {
+	return &plugin.Result{
+		Success:     false,
+		Message:     fmt.Sprintf("%s: %v", message, err),
+		ExecutionID: executionID,
+	}
+}
+
+// runKubectl runs the kubectl binary and returns its stdout
+func runKubectl(ctx context.Context, stdin string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "kubectl", args...)
+	cmd.Stdin = strings.NewReader(stdin)
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return stdout.String(), fmt.Errorf("kubectl %s: %w: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.String(), nil
+}
@@ -0,0 +1,595 @@
+package kubernetes
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cuongtl1992/grp-cli/pkg/plugin"
+)
+
+const stableDeployment = `{
+  "metadata": {"name": "web", "labels": {"app": "web"}},
+  "spec": {
+    "replicas": 4,
+    "selector": {"matchLabels": {"app": "web"}},
+    "template": {
+      "metadata": {"labels": {"app": "web"}},
+      "spec": {"containers": [{"name": "web", "image": "web:v1"}, {"name": "proxy", "image": "proxy:v1"}]}
+    }
+  },
+  "status": {"readyReplicas": 4}
+}`
+
+// fakeKubectl records kubectl invocations and returns canned output for the
+// invocations starting with a key of output. Invocations starting with a key of
+// sequence return its outputs in turn, repeating the last one.
+type fakeKubectl struct {
+	calls    []string
+	stdin    []string
+	output   map[string]string
+	sequence map[string][]string
+	fail     string
+}
+
+func (f *fakeKubectl) run(ctx context.Context, stdin string, args ...string) (string, error) {
+	call := strings.Join(args, " ")
+	f.calls = append(f.calls, call)
+	f.stdin = append(f.stdin, stdin)
+	if f.fail != "" && strings.HasPrefix(call, f.fail) {
+		return "", fmt.Errorf("kubectl %s: exit status 1", args[0])
+	}
+	for prefix, outputs := range f.sequence {
+		if strings.HasPrefix(call, prefix) {
+			output := outputs[0]
+			if len(outputs) > 1 {
+				f.sequence[prefix] = outputs[1:]
+			}
+			return output, nil
+		}
+	}
+	for prefix, output := range f.output {
+		if strings.HasPrefix(call, prefix) {
+			return output, nil
+		}
+	}
+	return "", nil
+}
+
+func canaryConfig(overrides map[string]interface{}) map[string]interface{} {
+	config := map[string]interface{}{
+		"namespace": "prod",
+		"resource":  "deployment/web",
+		"action":    "canary",
+		"image":     "web:v2",
+		"steps":     []interface{}{25, 50},
+		"pause":     "1ms",
+	}
+	for key, value := range overrides {
+		config[key] = value
+	}
+	return config
+}
+
+// rolloutJSON returns a deployment with the given desired, updated and ready replica counts
+func rolloutJSON(desired, updated, ready int) string {
+	return fmt.Sprintf(`{
+  "metadata": {"generation": 2},
+  "spec": {"replicas": %d},
+  "status": {"observedGeneration": 2, "replicas": %d, "updatedReplicas": %d, "readyReplicas": %d, "availableReplicas": %d}
+}`, desired, updated, updated, ready, ready)
+}
+
+func TestExecute(t *testing.T) {
+	tests := []struct {
+		name          string
+		config        map[string]interface{}
+		rollouts      []string
+		fail          string
+		expectedCalls []string
+		expectedReady map[string]interface{}
+		wantSuccess   bool
+		wantMessage   string
+	}{
+		{
+			name: "apply waits for deployments",
+			config: map[string]interface{}{
+				"namespace": "prod", "resource": "deployment", "action": "apply",
+				"manifest": "kind: Deployment", "wait": true,
+			},
+			rollouts: []string{rolloutJSON(3, 1, 1), rolloutJSON(3, 3, 3)},
+			expectedCalls: []string{
+				"apply --namespace prod -f - -o name",
+				"get deployment.apps/web --namespace prod -o json",
+				"get deployment.apps/web --namespace prod -o json",
+			},
+			expectedReady: map[string]interface{}{"deployment.apps/web": 3},
+			wantSuccess:   true,
+		},
+		{
+			name: "apply without wait",
+			config: map[string]interface{}{
+				"namespace": "prod", "resource": "deployment", "action": "apply", "manifest": "kind: Deployment",
+			},
+			expectedCalls: []string{"apply --namespace prod -f - -o name"},
+			wantSuccess:   true,
+		},
+		{
+			name: "scale times out",
+			config: map[string]interface{}{
+				"namespace": "prod", "resource": "deployment/web", "action": "scale",
+				"replicas": 3, "wait": true, "timeout": "20ms",
+			},
+			rollouts:      []string{rolloutJSON(3, 3, 1)},
+			expectedReady: map[string]interface{}{"deployment/web": 1},
+			wantMessage:   "timed out after 20ms waiting for deployment/web to be ready: 1 of 3 replicas ready",
+		},
+		{
+			name: "restart",
+			config: map[string]interface{}{
+				"namespace": "prod", "resource": "deployment/web", "action": "restart", "wait": true,
+			},
+			rollouts: []string{rolloutJSON(2, 2, 2)},
+			expectedCalls: []string{
+				"rollout restart deployment/web --namespace prod",
+				"get deployment/web --namespace prod -o json",
+			},
+			expectedReady: map[string]interface{}{"deployment/web": 2},
+			wantSuccess:   true,
+		},
+		{
+			name: "delete waits with kubectl",
+			config: map[string]interface{}{
+				"namespace": "prod", "resource": "service/web", "action": "delete", "wait": true, "timeout": "30s",
+			},
+			expectedCalls: []string{"delete service/web --namespace prod --wait=true --timeout 30s"},
+			expectedReady: map[string]interface{}{},
+			wantSuccess:   true,
+		},
+		{
+			name: "kubectl failure",
+			config: map[string]interface{}{
+				"namespace": "prod", "resource": "deployment/web", "action": "restart",
+			},
+			fail:          "rollout restart",
+			expectedCalls: []string{"rollout restart deployment/web --namespace prod"},
+			wantMessage:   "kubectl rollout restart failed: kubectl rollout: exit status 1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kubectl := &fakeKubectl{
+				fail:     tt.fail,
+				output:   map[string]string{"apply ": "deployment.apps/web\nservice/web\n"},
+				sequence: map[string][]string{"get ": tt.rollouts},
+			}
+			p := &KubernetesPlugin{runner: kubectl.run, pollInterval: time.Millisecond}
+
+			if err := p.Validate(context.Background(), tt.config); err != nil {
+				t.Fatalf("Validate() error = %v", err)
+			}
+
+			result, err := p.Execute(context.Background(), tt.config)
+			if err != nil {
+				t.Fatalf("Execute() error = %v", err)
+			}
+			if result.Success != tt.wantSuccess {
+				t.Fatalf("Execute() success = %v, want %v (%s)", result.Success, tt.wantSuccess, result.Message)
+			}
+			if tt.wantMessage != "" && result.Message != tt.wantMessage {
+				t.Errorf("Execute() message = %q, want %q", result.Message, tt.wantMessage)
+			}
+			if tt.expectedCalls != nil && !reflect.DeepEqual(kubectl.calls, tt.expectedCalls) {
+				t.Errorf("kubectl calls = %q, want %q", kubectl.calls, tt.expectedCalls)
+			}
+			if tt.expectedReady != nil && !reflect.DeepEqual(result.Data["readyReplicas"], tt.expectedReady) {
+				t.Errorf("readyReplicas = %v, want %v", result.Data["readyReplicas"], tt.expectedReady)
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  map[string]interface{}
+		wantErr string
+	}{
+		{
+			name:   "valid timeout",
+			config: map[string]interface{}{"namespace": "prod", "resource": "deployment/web", "action": "restart", "timeout": "2m"},
+		},
+		{
+			name:    "invalid timeout",
+			config:  map[string]interface{}{"namespace": "prod", "resource": "deployment/web", "action": "restart", "timeout": "2 minutes"},
+			wantErr: "invalid timeout",
+		},
+		{
+			name:    "negative timeout",
+			config:  map[string]interface{}{"namespace": "prod", "resource": "deployment/web", "action": "restart", "timeout": "-1s"},
+			wantErr: "timeout must be positive",
+		},
+		{
+			name:    "scale without replicas",
+			config:  map[string]interface{}{"namespace": "prod", "resource": "deployment/web", "action": "scale"},
+			wantErr: "replicas is required",
+		},
+		{
+			name:    "apply without manifest",
+			config:  map[string]interface{}{"namespace": "prod", "resource": "deployment/web", "action": "apply"},
+			wantErr: "manifest is required",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := (&KubernetesPlugin{}).Validate(context.Background(), tt.config)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Validate() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Validate() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestExecuteCanary(t *testing.T) {
+	tests := []struct {
+		name          string
+		config        map[string]interface{}
+		ready         string
+		fail          string
+		expectedCalls []string
+		expectedSteps []string
+		wantSuccess   bool
+		wantPromoted  bool
+	}{
+		{
+			name:   "promotes after healthy steps",
+			config: canaryConfig(nil),
+			ready:  "4",
+			expectedCalls: []string{
+				"get deployment web --namespace prod -o json",
+				"apply --namespace prod -f -",
+				"rollout status deployment/web-canary --namespace prod --timeout 5m0s",
+				"scale deployment/web --namespace prod --replicas 3",
+				"get deployment web-canary --namespace prod -o jsonpath={.status.readyReplicas}",
+				"apply --namespace prod -f -",
+				"rollout status deployment/web-canary --namespace prod --timeout 5m0s",
+				"scale deployment/web --namespace prod --replicas 2",
+				"get deployment web-canary --namespace prod -o jsonpath={.status.readyReplicas}",
+				"apply --namespace prod -f -",
+				"rollout status deployment/web-canary --namespace prod --timeout 5m0s",
+				"scale deployment/web --namespace prod --replicas 0",
+				"get deployment web-canary --namespace prod -o jsonpath={.status.readyReplicas}",
+				"set image deployment/web web=web:v2 --namespace prod",
+				"scale deployment/web --namespace prod --replicas 4",
+				"rollout status deployment/web --namespace prod --timeout 5m0s",
+				"delete deployment web-canary --namespace prod --ignore-not-found",
+			},
+			expectedSteps: []string{"healthy", "healthy", "healthy"},
+			wantSuccess:   true,
+			wantPromoted:  true,
+		},
+		{
+			name:   "stops at the target percentage",
+			config: canaryConfig(map[string]interface{}{"percentage": 50, "steps": []interface{}{25}}),
+			ready:  "4",
+			expectedCalls: []string{
+				"get deployment web --namespace prod -o json",
+				"apply --namespace prod -f -",
+				"rollout status deployment/web-canary --namespace prod --timeout 5m0s",
+				"scale deployment/web --namespace prod --replicas 3",
+				"get deployment web-canary --namespace prod -o jsonpath={.status.readyReplicas}",
+				"apply --namespace prod -f -",
+				"rollout status deployment/web-canary --namespace prod --timeout 5m0s",
+				"scale deployment/web --namespace prod --replicas 2",
+				"get deployment web-canary --namespace prod -o jsonpath={.status.readyReplicas}",
+			},
+			expectedSteps: []string{"healthy", "healthy"},
+			wantSuccess:   true,
+		},
+		{
+			name:   "reverts when the canary is unhealthy",
+			config: canaryConfig(nil),
+			ready:  "0",
+			expectedCalls: []string{
+				"get deployment web --namespace prod -o json",
+				"apply --namespace prod -f -",
+				"rollout status deployment/web-canary --namespace prod --timeout 5m0s",
+				"scale deployment/web --namespace prod --replicas 3",
+				"get deployment web-canary --namespace prod -o jsonpath={.status.readyReplicas}",
+				"scale deployment/web --namespace prod --replicas 4",
+				"delete deployment web-canary --namespace prod --ignore-not-found",
+			},
+			expectedSteps: []string{"unhealthy"},
+		},
+		{
+			name:   "reverts when the canary does not roll out",
+			config: canaryConfig(map[string]interface{}{"timeout": "30s"}),
+			fail:   "rollout status",
+			expectedCalls: []string{
+				"get deployment web --namespace prod -o json",
+				"apply --namespace prod -f -",
+				"rollout status deployment/web-canary --namespace prod --timeout 30s",
+				"scale deployment/web --namespace prod --replicas 4",
+				"delete deployment web-canary --namespace prod --ignore-not-found",
+			},
+			expectedSteps: []string{"failed"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kubectl := &fakeKubectl{
+				fail: tt.fail,
+				output: map[string]string{
+					"get deployment web ":        stableDeployment,
+					"get deployment web-canary ": tt.ready,
+				},
+			}
+			p := &KubernetesPlugin{runner: kubectl.run}
+
+			if err := p.Validate(context.Background(), tt.config); err != nil {
+				t.Fatalf("Validate() error = %v", err)
+			}
+
+			ctx := plugin.WithExecutionID(context.Background(), "exec-1")
+			result, err := p.Execute(ctx, tt.config)
+			if err != nil {
+				t.Fatalf("Execute() error = %v", err)
+			}
+			if result.Success != tt.wantSuccess {
+				t.Fatalf("Execute() success = %v, want %v (%s)", result.Success, tt.wantSuccess, result.Message)
+			}
+			if !reflect.DeepEqual(kubectl.calls, tt.expectedCalls) {
+				t.Errorf("kubectl calls = %q, want %q", kubectl.calls, tt.expectedCalls)
+			}
+
+			steps, _ := result.Data["steps"].([]interface{})
+			var statuses []string
+			for _, step := range steps {
+				statuses = append(statuses, step.(map[string]interface{})["status"].(string))
+			}
+			if !reflect.DeepEqual(statuses, tt.expectedSteps) {
+				t.Errorf("step statuses = %v, want %v", statuses, tt.expectedSteps)
+			}
+			if result.Data["promoted"] != tt.wantPromoted {
+				t.Errorf("promoted = %v, want %v", result.Data["promoted"], tt.wantPromoted)
+			}
+		})
+	}
+}
+
+func TestCanaryManifest(t *testing.T) {
+	kubectl := &fakeKubectl{output: map[string]string{
+		"get deployment web ":        stableDeployment,
+		"get deployment web-canary ": "1",
+	}}
+	p := &KubernetesPlugin{runner: kubectl.run}
+
+	config := canaryConfig(map[string]interface{}{"percentage": 25, "steps": nil, "container": "web"})
+	if _, err := p.Execute(context.Background(), config); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+
+	var manifest struct {
+		Metadata struct {
+			Name string `json:"name"`
+		} `json:"metadata"`
+		Spec struct {
+			Replicas int `json:"replicas"`
+			Selector struct {
+				MatchLabels map[string]string `json:"matchLabels"`
+			} `json:"selector"`
+			Template struct {
+				Metadata struct {
+					Labels map[string]string `json:"labels"`
+				} `json:"metadata"`
+				Spec struct {
+					Containers []struct {
+						Name  string `json:"name"`
+						Image string `json:"image"`
+					} `json:"containers"`
+				} `json:"spec"`
+			} `json:"template"`
+		} `json:"spec"`
+	}
+	if err := json.Unmarshal([]byte(kubectl.stdin[1]), &manifest); err != nil {
+		t.Fatalf("invalid canary manifest: %v", err)
+	}
+
+	canaryLabels := map[string]string{"app": "web", "track": "canary"}
+	if manifest.Metadata.Name != "web-canary" || manifest.Spec.Replicas != 1 {
+		t.Errorf("manifest name = %s, replicas = %d", manifest.Metadata.Name, manifest.Spec.Replicas)
+	}
+	if !reflect.DeepEqual(manifest.Spec.Selector.MatchLabels, canaryLabels) || !reflect.DeepEqual(manifest.Spec.Template.Metadata.Labels, canaryLabels) {
+		t.Errorf("manifest labels = %v, %v", manifest.Spec.Selector.MatchLabels, manifest.Spec.Template.Metadata.Labels)
+	}
+	images := []string{}
+	for _, container := range manifest.Spec.Template.Spec.Containers {
+		images = append(images, container.Name+"="+container.Image)
+	}
+	if !reflect.DeepEqual(images, []string{"web=web:v2", "proxy=proxy:v1"}) {
+		t.Errorf("manifest containers = %v", images)
+	}
+}
+
+func TestRollbackCanary(t *testing.T) {
+	kubectl := &fakeKubectl{output: map[string]string{
+		"get deployment web ":        stableDeployment,
+		"get deployment web-canary ": "4",
+	}}
+	p := &KubernetesPlugin{runner: kubectl.run}
+
+	ctx := plugin.WithExecutionID(context.Background(), "exec-1")
+	if result, err := p.Execute(ctx, canaryConfig(nil)); err != nil || !result.Success {
+		t.Fatalf("Execute() = %v, %v", result, err)
+	}
+
+	kubectl.calls = nil
+	if err := p.Rollback(context.Background(), "exec-1"); err != nil {
+		t.Fatalf("Rollback() error = %v", err)
+	}
+	expected := []string{
+		"set image deployment/web web=web:v1 --namespace prod",
+		"scale deployment/web --namespace prod --replicas 4",
+		"delete deployment web-canary --namespace prod --ignore-not-found",
+	}
+	if !reflect.DeepEqual(kubectl.calls, expected) {
+		t.Errorf("rollback calls = %q, want %q", kubectl.calls, expected)
+	}
+
+	// The revert is only applied once
+	kubectl.calls = nil
+	if err := p.Rollback(context.Background(), "exec-1"); !errors.Is(err, plugin.ErrNothingToRollBack) || len(kubectl.calls) > 0 {
+		t.Errorf("second Rollback() = %v, calls %q", err, kubectl.calls)
+	}
+}
+
+func TestExecuteWithoutExecutionID(t *testing.T) {
+	tests := []struct {
+		name   string
+		config map[string]interface{}
+		// expectedRevert is the first kubectl call of the rollback, if any
+		expectedRevert string
+	}{
+		{name: "apply", config: map[string]interface{}{"namespace": "prod", "resource": "deployment", "action": "apply", "manifest": "kind: Deployment"}},
+		{name: "canary", config: canaryConfig(nil), expectedRevert: "set image deployment/web web=web:v1 --namespace prod"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kubectl := &fakeKubectl{output: map[string]string{
+				"apply ":                     "deployment.apps/web\n",
+				"get deployment web ":        stableDeployment,
+				"get deployment web-canary ": "4",
+			}}
+			p := &KubernetesPlugin{runner: kubectl.run}
+
+			// A bare context carries no execution ID
+			result, err := p.Execute(context.Background(), tt.config)
+			if err != nil || !result.Success {
+				t.Fatalf("Execute() = %v, %v", result, err)
+			}
+			if result.ExecutionID == "" {
+				t.Fatal("Expected an execution ID in the result")
+			}
+
+			// The changes are reverted with the execution ID of the result
+			kubectl.calls = nil
+			if err := p.Rollback(context.Background(), result.ExecutionID); err != nil && (tt.expectedRevert != "" || !errors.Is(err, plugin.ErrNothingToRollBack)) {
+				t.Fatalf("Rollback() error = %v", err)
+			}
+			revert := ""
+			if len(kubectl.calls) > 0 {
+				revert = kubectl.calls[0]
+			}
+			if revert != tt.expectedRevert {
+				t.Errorf("Expected rollback to start with %q, got calls %q", tt.expectedRevert, kubectl.calls)
+			}
+		})
+	}
+}
+
+func TestValidateCanary(t *testing.T) {
+	tests := []struct {
+		name      string
+		overrides map[string]interface{}
+		wantErr   string
+	}{
+		{name: "valid", overrides: nil},
+		{name: "plain deployment name", overrides: map[string]interface{}{"resource": "web"}},
+		{name: "not a deployment", overrides: map[string]interface{}{"resource": "service/web"}, wantErr: "requires a deployment resource"},
+		{name: "missing image", overrides: map[string]interface{}{"image": ""}, wantErr: "image is required"},
+		{name: "percentage out of range", overrides: map[string]interface{}{"percentage": 150}, wantErr: "percentage must be between 1 and 100"},
+		{name: "step above percentage", overrides: map[string]interface{}{"percentage": 40}, wantErr: "steps must be percentages between 1 and 40"},
+		{name: "decreasing steps", overrides: map[string]interface{}{"steps": []interface{}{50, 25}}, wantErr: "increasing order"},
+		{name: "invalid pause", overrides: map[string]interface{}{"pause": "soon"}, wantErr: "invalid pause"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := (&KubernetesPlugin{}).Validate(context.Background(), canaryConfig(tt.overrides))
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Validate() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Validate() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPreview(t *testing.T) {
+	tests := []struct {
+		name     string
+		action   string
+		diff     string
+		fail     string
+		expected string
+		calls    int
+		wantErr  bool
+	}{
+		{name: "apply with changes", action: "apply", diff: "-  replicas: 2\n+  replicas: 3\n", expected: "-  replicas: 2\n+  replicas: 3\n", calls: 1},
+		{name: "apply without changes", action: "apply", expected: "no changes", calls: 1},
+		{name: "failed diff", action: "apply", fail: "diff", calls: 1, wantErr: true},
+		{name: "other actions", action: "restart"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kubectl := &fakeKubectl{fail: tt.fail, output: map[string]string{"diff ": tt.diff}}
+			p := &KubernetesPlugin{runner: kubectl.run}
+			config := map[string]interface{}{"namespace": "prod", "resource": "deployment/web", "action": tt.action, "manifest": "kind: Deployment"}
+
+			preview, err := p.Preview(context.Background(), config)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Preview() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if preview != tt.expected {
+				t.Errorf("Preview() = %q, want %q", preview, tt.expected)
+			}
+			if len(kubectl.calls) != tt.calls {
+				t.Fatalf("kubectl calls = %q", kubectl.calls)
+			}
+			if tt.calls > 0 && (kubectl.calls[0] != "diff --namespace prod -f -" || kubectl.stdin[0] != "kind: Deployment") {
+				t.Errorf("kubectl call = %q with %q", kubectl.calls[0], kubectl.stdin[0])
+			}
+		})
+	}
+}
+
+func TestCanRollBack(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   map[string]interface{}
+		expected bool
+	}{
+		{name: "canary", config: map[string]interface{}{"action": "canary"}, expected: true},
+		{name: "apply", config: map[string]interface{}{"action": "apply"}},
+		{name: "scale", config: map[string]interface{}{"action": "scale"}},
+		{name: "blue-green", config: map[string]interface{}{"action": "bluegreen"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := (&KubernetesPlugin{}).CanRollBack(tt.config); got != tt.expected {
+				t.Errorf("Expected CanRollBack() = %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
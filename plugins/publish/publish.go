@@ -0,0 +1,293 @@
+package publish
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/cuongtl1992/grp-cli/internal/plugins"
+	"github.com/cuongtl1992/grp-cli/pkg/plugin"
+)
+
+const (
+	// brokerNATS publishes with the NATS client protocol
+	brokerNATS = "nats"
+	// defaultTimeout bounds connecting, publishing and waiting for the broker's acknowledgement
+	defaultTimeout = 10 * time.Second
+)
+
+// PublishPlugin implements the Plugin interface for jobs that publish a message
+// to a message broker, so that other systems can react to a release
+type PublishPlugin struct{}
+
+// Plugin is the instance registered as a built-in plugin
+var Plugin PublishPlugin
+
+func init() {
+	plugins.RegisterBuiltin(&Plugin)
+}
+
+// message describes the message and broker connection built from job config
+type message struct {
+	broker   string
+	address  string
+	topic    string
+	payload  []byte
+	user     string
+	password string
+	token    string
+	timeout  time.Duration
+}
+
+// Name returns the plugin name
+func (p *PublishPlugin) Name() string {
+	return "publish"
+}
+
+// Description returns the plugin description
+func (p *PublishPlugin) Description() string {
+	return "Publishes a message to a message broker (NATS)"
+}
+
+// Version returns the plugin version
+func (p *PublishPlugin) Version() string {
+	return "0.1.0"
+}
+
+// ConfigSchema returns the JSON schema for config validation
+func (p *PublishPlugin) ConfigSchema() *plugin.JSONSchema {
+	return &plugin.JSONSchema{
+		Type: "object",
+		Properties: map[string]*plugin.JSONSchema{
+			"broker":   {Type: "string", Default: brokerNATS},
+			"address":  {Type: "string"},
+			"topic":    {Type: "string"},
+			"payload":  {},
+			"user":     {Type: "string"},
+			"password": {Type: "string"},
+			"token":    {Type: "string"},
+			"timeout":  {Type: "string"},
+		},
+		Required: []string{"address", "topic"},
+	}
+}
+
+// Validate checks if the configuration is valid
+func (p *PublishPlugin) Validate(ctx context.Context, config map[string]interface{}) error {
+	_, err := parseMessage(config)
+	return err
+}
+
+// Execute connects to the broker, publishes the message and waits for the
+// broker to acknowledge it
+func (p *PublishPlugin) Execute(ctx context.Context, config map[string]interface{}) (*plugin.Result, error) {
+	executionID := plugin.ExecutionID(ctx)
+
+	msg, err := parseMessage(config)
+	if err != nil {
+		return nil, err
+	}
+
+	data := map[string]interface{}{
+		"broker":  msg.broker,
+		"address": msg.address,
+		"topic":   msg.topic,
+		"bytes":   len(msg.payload),
+	}
+	server, err := publishNATS(ctx, msg)
+	if err != nil {
+		return &plugin.Result{
+			Success:     false,
+			Message:     fmt.Sprintf("Failed to publish to %s on %s: %v", msg.topic, msg.address, err),
+			ExecutionID: executionID,
+			Data:        data,
+		}, nil
+	}
+	data["delivered"] = true
+	data["serverId"] = server
+
+	return &plugin.Result{
+		Success:     true,
+		Message:     fmt.Sprintf("Published %d bytes to %s on %s", len(msg.payload), msg.topic, msg.address),
+		ExecutionID: executionID,
+		Data:        data,
+	}, nil
+}
+
+// Capabilities reports that published messages cannot be rolled back
+func (p *PublishPlugin) Capabilities() plugin.Capabilities {
+	return plugin.Capabilities{}
+}
+
+// Rollback does nothing: a published message cannot be taken back
+func (p *PublishPlugin) Rollback(ctx context.Context, executionID string) error {
+	return nil
+}
+
+// parseMessage builds a message from job config. An object or array payload is
+// published as JSON, any other value as text.
+func parseMessage(config map[string]interface{}) (message, error) {
+	msg := message{broker: brokerNATS, timeout: defaultTimeout}
+
+	if broker, ok := config["broker"].(string); ok && broker != "" {
+		msg.broker = broker
+	}
+	if msg.broker != brokerNATS {
+		return msg, fmt.Errorf("unsupported broker %q (supported: %s)", msg.broker, brokerNATS)
+	}
+
+	address, _ := config["address"].(string)
+	if address == "" {
+		return msg, fmt.Errorf("missing required field: address")
+	}
+	if scheme, rest, found := strings.Cut(address, "://"); found {
+		if scheme != "nats" {
+			return msg, fmt.Errorf("address must be host:port or nats://host:port, got %s", address)
+		}
+		address = rest
+	}
+	if _, _, err := net.SplitHostPort(address); err != nil {
+		return msg, fmt.Errorf("invalid address: %w", err)
+	}
+	msg.address = address
+
+	msg.topic, _ = config["topic"].(string)
+	if msg.topic == "" {
+		return msg, fmt.Errorf("missing required field: topic")
+	}
+	if strings.ContainsAny(msg.topic, " \t\r\n") {
+		return msg, fmt.Errorf("topic must not contain whitespace: %q", msg.topic)
+	}
+
+	switch payload := config["payload"].(type) {
+	case nil:
+	case string:
+		msg.payload = []byte(payload)
+	case map[string]interface{}, []interface{}:
+		encoded, err := json.Marshal(payload)
+		if err != nil {
+			return msg, fmt.Errorf("failed to encode payload: %w", err)
+		}
+		msg.payload = encoded
+	default:
+		msg.payload = []byte(fmt.Sprint(payload))
+	}
+
+	msg.user, _ = config["user"].(string)
+	msg.password, _ = config["password"].(string)
+	msg.token, _ = config["token"].(string)
+
+	if timeout, ok := config["timeout"].(string); ok && timeout != "" {
+		duration, err := time.ParseDuration(timeout)
+		if err != nil {
+			return msg, fmt.Errorf("invalid timeout: %w", err)
+		}
+		msg.timeout = duration
+	}
+
+	return msg, nil
+}
+
+// natsInfo holds the fields of the server's INFO message the plugin uses
+type natsInfo struct {
+	ServerID    string `json:"server_id"`
+	TLSRequired bool   `json:"tls_required"`
+	MaxPayload  int    `json:"max_payload"`
+}
+
+// publishNATS publishes the message with the NATS client protocol and returns the
+// ID of the server. The PING sent after the message is only answered once the
+// server has processed the publish, which acknowledges its delivery to the server.
+func publishNATS(ctx context.Context, msg message) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, msg.timeout)
+	defer cancel()
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", msg.address)
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+
+	// Unblock reads and writes once the context is done
+	stop := context.AfterFunc(ctx, func() { conn.SetDeadline(time.Now()) })
+	defer stop()
+
+	reader := bufio.NewReader(conn)
+	line, err := readLine(ctx, reader)
+	if err != nil {
+		return "", err
+	}
+	infoJSON, found := strings.CutPrefix(line, "INFO ")
+	if !found {
+		return "", fmt.Errorf("unexpected greeting from server: %s", line)
+	}
+	var info natsInfo
+	if err := json.Unmarshal([]byte(infoJSON), &info); err != nil {
+		return "", fmt.Errorf("invalid server info: %w", err)
+	}
+	if info.TLSRequired {
+		return "", errors.New("the server requires TLS, which is not supported")
+	}
+	if info.MaxPayload > 0 && len(msg.payload) > info.MaxPayload {
+		return "", fmt.Errorf("payload of %d bytes exceeds the server's maximum of %d", len(msg.payload), info.MaxPayload)
+	}
+
+	options, err := json.Marshal(map[string]interface{}{
+		"verbose":    false,
+		"pedantic":   false,
+		"name":       "grp-cli",
+		"lang":       "go",
+		"protocol":   1,
+		"user":       msg.user,
+		"pass":       msg.password,
+		"auth_token": msg.token,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	request := fmt.Sprintf("CONNECT %s\r\nPUB %s %d\r\n%s\r\nPING\r\n", options, msg.topic, len(msg.payload), msg.payload)
+	if _, err := conn.Write([]byte(request)); err != nil {
+		return "", ctxError(ctx, err)
+	}
+
+	for {
+		line, err := readLine(ctx, reader)
+		if err != nil {
+			return "", err
+		}
+		switch {
+		case line == "PONG":
+			return info.ServerID, nil
+		case line == "PING":
+			if _, err := conn.Write([]byte("PONG\r\n")); err != nil {
+				return "", ctxError(ctx, err)
+			}
+		case strings.HasPrefix(line, "-ERR"):
+			return "", fmt.Errorf("server error: %s", strings.Trim(strings.TrimSpace(strings.TrimPrefix(line, "-ERR")), "'"))
+		}
+	}
+}
+
+// readLine reads a protocol line without its CRLF
+func readLine(ctx context.Context, reader *bufio.Reader) (string, error) {
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		return "", ctxError(ctx, err)
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// ctxError reports the context's error for I/O aborted by its deadline
+func ctxError(ctx context.Context, err error) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return err
+}
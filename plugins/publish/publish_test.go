@@ -0,0 +1,209 @@
+package publish
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/cuongtl1992/grp-cli/pkg/plugin"
+)
+
+// fakeNATS is a NATS server accepting one connection. It greets the client with
+// info, records the published message and answers the PING with reply.
+type fakeNATS struct {
+	address string
+	info    string
+	reply   string
+	// published receives the subject and payload of the PUB, or an error
+	published chan string
+}
+
+func newFakeNATS(t *testing.T, info, reply string) *fakeNATS {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	server := &fakeNATS{address: listener.Addr().String(), info: info, reply: reply, published: make(chan string, 1)}
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		server.published <- server.serve(conn)
+	}()
+	return server
+}
+
+// serve runs the protocol exchange and returns the published message
+func (s *fakeNATS) serve(conn net.Conn) string {
+	fmt.Fprintf(conn, "INFO %s\r\n", s.info)
+	if s.info == "" || strings.Contains(s.info, "tls_required") {
+		return ""
+	}
+
+	reader := bufio.NewReader(conn)
+	var published string
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return published
+		}
+		fields := strings.Fields(line)
+		switch fields[0] {
+		case "PUB":
+			size, _ := strconv.Atoi(fields[2])
+			payload := make([]byte, size+2)
+			if _, err := io.ReadFull(reader, payload); err != nil {
+				return "error: " + err.Error()
+			}
+			published = fields[1] + " " + string(payload[:size])
+		case "PING":
+			fmt.Fprintf(conn, "%s\r\n", s.reply)
+			return published
+		}
+	}
+}
+
+func TestExecute(t *testing.T) {
+	tests := []struct {
+		name          string
+		payload       interface{}
+		info          string
+		reply         string
+		wantSuccess   bool
+		wantPublished string
+		wantMessage   string
+	}{
+		{
+			name:          "object payload as JSON",
+			payload:       map[string]interface{}{"service": "web", "version": "1.2.3"},
+			reply:         "PONG",
+			wantSuccess:   true,
+			wantPublished: `releases.web {"service":"web","version":"1.2.3"}`,
+		},
+		{
+			name:          "text payload",
+			payload:       "web 1.2.3 released",
+			reply:         "PONG",
+			wantSuccess:   true,
+			wantPublished: "releases.web web 1.2.3 released",
+		},
+		{
+			name:        "server error",
+			payload:     "hello",
+			reply:       "-ERR 'Authorization Violation'",
+			wantMessage: "server error: Authorization Violation",
+		},
+		{
+			name:        "TLS required",
+			info:        `{"server_id":"test","tls_required":true}`,
+			wantMessage: "the server requires TLS",
+		},
+		{
+			name:        "payload too large",
+			payload:     strings.Repeat("x", 20),
+			info:        `{"server_id":"test","max_payload":10}`,
+			wantMessage: "payload of 20 bytes exceeds the server's maximum of 10",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info := tt.info
+			if info == "" {
+				info = `{"server_id":"test","max_payload":1048576}`
+			}
+			server := newFakeNATS(t, info, tt.reply)
+
+			config := map[string]interface{}{
+				"address": "nats://" + server.address,
+				"topic":   "releases.web",
+				"payload": tt.payload,
+				"timeout": "5s",
+			}
+			p := &PublishPlugin{}
+			if err := p.Validate(context.Background(), config); err != nil {
+				t.Fatalf("Validate() error = %v", err)
+			}
+
+			ctx := plugin.WithExecutionID(context.Background(), "exec-1")
+			result, err := p.Execute(ctx, config)
+			if err != nil {
+				t.Fatalf("Execute() error = %v", err)
+			}
+			if result.Success != tt.wantSuccess {
+				t.Fatalf("Execute() success = %v, want %v (%s)", result.Success, tt.wantSuccess, result.Message)
+			}
+			if !strings.Contains(result.Message, tt.wantMessage) {
+				t.Errorf("Expected message containing %q, got %q", tt.wantMessage, result.Message)
+			}
+			if tt.wantSuccess {
+				if published := <-server.published; published != tt.wantPublished {
+					t.Errorf("Expected published %q, got %q", tt.wantPublished, published)
+				}
+				if result.Data["delivered"] != true || result.Data["serverId"] != "test" {
+					t.Errorf("Expected the delivery in the data, got %v", result.Data)
+				}
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  map[string]interface{}
+		wantErr string
+	}{
+		{name: "valid", config: map[string]interface{}{"address": "localhost:4222", "topic": "releases"}},
+		{name: "nats url", config: map[string]interface{}{"address": "nats://nats.example.com:4222", "topic": "releases"}},
+		{name: "missing address", config: map[string]interface{}{"topic": "releases"}, wantErr: "missing required field: address"},
+		{name: "missing topic", config: map[string]interface{}{"address": "localhost:4222"}, wantErr: "missing required field: topic"},
+		{name: "missing port", config: map[string]interface{}{"address": "localhost", "topic": "releases"}, wantErr: "invalid address"},
+		{name: "other scheme", config: map[string]interface{}{"address": "tls://localhost:4222", "topic": "releases"}, wantErr: "address must be host:port or nats://host:port"},
+		{name: "topic with spaces", config: map[string]interface{}{"address": "localhost:4222", "topic": "a b"}, wantErr: "must not contain whitespace"},
+		{name: "unsupported broker", config: map[string]interface{}{"broker": "kafka", "address": "localhost:9092", "topic": "releases"}, wantErr: `unsupported broker "kafka"`},
+		{name: "invalid timeout", config: map[string]interface{}{"address": "localhost:4222", "topic": "releases", "timeout": "soon"}, wantErr: "invalid timeout"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := (&PublishPlugin{}).Validate(context.Background(), tt.config)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Validate() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Validate() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestExecuteUnreachable(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	address := listener.Addr().String()
+	listener.Close()
+
+	result, err := (&PublishPlugin{}).Execute(context.Background(), map[string]interface{}{"address": address, "topic": "releases"})
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if result.Success || !strings.Contains(result.Message, "Failed to publish to releases") {
+		t.Errorf("Expected a failed publish, got %+v", result)
+	}
+}
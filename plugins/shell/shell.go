@@ -0,0 +1,255 @@
+package shell
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/cuongtl1992/grp-cli/internal/plugins"
+	"github.com/cuongtl1992/grp-cli/pkg/plugin"
+)
+
+// waitDelay bounds how long we wait for output pipes after the process is killed
+const waitDelay = 5 * time.Second
+
+// ShellPlugin implements the Plugin interface for local commands and scripts
+type ShellPlugin struct {
+	mutex     sync.Mutex
+	rollbacks map[string][]command
+}
+
+// Plugin is the instance registered as a built-in plugin
+var Plugin ShellPlugin
+
+func init() {
+	plugins.RegisterBuiltin(&Plugin)
+}
+
+// command describes a command built from job config
+type command struct {
+	name       string
+	args       []string
+	workingDir string
+	env        []string
+	timeout    time.Duration
+}
+
+// Name returns the plugin name
+func (p *ShellPlugin) Name() string {
+	return "shell"
+}
+
+// Description returns the plugin description
+func (p *ShellPlugin) Description() string {
+	return "Runs local commands and scripts"
+}
+
+// Version returns the plugin version
+func (p *ShellPlugin) Version() string {
+	return "0.1.0"
+}
+
+// ConfigSchema returns the JSON schema for config validation
+func (p *ShellPlugin) ConfigSchema() *plugin.JSONSchema {
+	return &plugin.JSONSchema{
+		Type: "object",
+		Properties: map[string]*plugin.JSONSchema{
+			"command":         {Type: "string"},
+			"args":            {Type: "array", Items: &plugin.JSONSchema{Type: "string"}},
+			"workingDir":      {Type: "string"},
+			"env":             {Type: "object"},
+			"timeout":         {Type: "string"},
+			"rollbackCommand": {Type: "string"},
+		},
+		Required: []string{"command"},
+	}
+}
+
+// Validate checks if the configuration is valid
+func (p *ShellPlugin) Validate(ctx context.Context, config map[string]interface{}) error {
+	_, err := parseCommand(config, "command")
+	return err
+}
+
+// Execute runs the configured command, also writing its output to the job's
+// output if the context has one
+func (p *ShellPlugin) Execute(ctx context.Context, config map[string]interface{}) (*plugin.Result, error) {
+	return p.execute(ctx, config, plugin.Output(ctx))
+}
+
+// ExecuteStreaming runs the configured command, writing its output to output as
+// it is produced
+func (p *ShellPlugin) ExecuteStreaming(ctx context.Context, config map[string]interface{}, output io.Writer) (*plugin.Result, error) {
+	return p.execute(ctx, config, output)
+}
+
+// execute runs the configured command, streaming its output to output if it is set
+func (p *ShellPlugin) execute(ctx context.Context, config map[string]interface{}, output io.Writer) (*plugin.Result, error) {
+	executionID := plugin.ExecutionID(ctx)
+
+	cmd, err := parseCommand(config, "command")
+	if err != nil {
+		return nil, err
+	}
+
+	stdout, stderr, exitCode, runErr := run(ctx, cmd, executionID, output)
+	if runErr != nil && exitCode < 0 {
+		return nil, runErr
+	}
+
+	// Remember the compensating command for this execution
+	if _, ok := config["rollbackCommand"]; ok {
+		if rollbackCmd, err := parseCommand(config, "rollbackCommand"); err == nil {
+			p.mutex.Lock()
+			if p.rollbacks == nil {
+				p.rollbacks = make(map[string][]command)
+			}
+			p.rollbacks[executionID] = append(p.rollbacks[executionID], rollbackCmd)
+			p.mutex.Unlock()
+		}
+	}
+
+	message := fmt.Sprintf("Command %s exited with code %d", cmd.name, exitCode)
+	if line := plugins.LastLine(stderr); runErr != nil && line != "" {
+		message = fmt.Sprintf("%s: %s", message, line)
+	}
+
+	return &plugin.Result{
+		Success:     runErr == nil,
+		Message:     message,
+		ExecutionID: executionID,
+		Data: map[string]interface{}{
+			"stdout":   stdout,
+			"stderr":   stderr,
+			"exitCode": exitCode,
+		},
+	}, nil
+}
+
+// Capabilities reports that commands can be compensated on rollback. Streaming is
+// reported because the plugin implements plugin.StreamingPlugin.
+func (p *ShellPlugin) Capabilities() plugin.Capabilities {
+	return plugin.Capabilities{Rollback: true}
+}
+
+// CanRollBack reports whether the job has a rollbackCommand to compensate it
+func (p *ShellPlugin) CanRollBack(config map[string]interface{}) bool {
+	_, ok := config["rollbackCommand"]
+	return ok
+}
+
+// Rollback runs the rollback commands recorded for the execution, most recent first.
+// Jobs without a rollbackCommand have nothing to roll back.
+func (p *ShellPlugin) Rollback(ctx context.Context, executionID string) error {
+	p.mutex.Lock()
+	commands := p.rollbacks[executionID]
+	delete(p.rollbacks, executionID)
+	p.mutex.Unlock()
+	if len(commands) == 0 {
+		return plugin.ErrNothingToRollBack
+	}
+
+	for i := len(commands) - 1; i >= 0; i-- {
+		_, stderr, exitCode, err := run(ctx, commands[i], executionID, plugin.Output(ctx))
+		if err != nil {
+			err = fmt.Errorf("rollback command exited with code %d", exitCode)
+			if line := plugins.LastLine(stderr); line != "" {
+				err = fmt.Errorf("%w: %s", err, line)
+			}
+			return err
+		}
+	}
+
+	return nil
+}
+
+// parseCommand builds a command from job config, reading the command line from key.
+// Commands without args are run through the system shell.
+func parseCommand(config map[string]interface{}, key string) (command, error) {
+	cmd := command{}
+
+	line, _ := config[key].(string)
+	if line == "" {
+		return cmd, fmt.Errorf("missing required field: %s", key)
+	}
+
+	if args, ok := config["args"].([]interface{}); ok && key == "command" {
+		cmd.name = line
+		for _, arg := range args {
+			cmd.args = append(cmd.args, fmt.Sprintf("%v", arg))
+		}
+	} else if runtime.GOOS == "windows" {
+		cmd.name, cmd.args = "cmd", []string{"/C", line}
+	} else {
+		cmd.name, cmd.args = "sh", []string{"-c", line}
+	}
+
+	if dir, ok := config["workingDir"].(string); ok {
+		cmd.workingDir = dir
+	}
+
+	if env, ok := config["env"].(map[string]interface{}); ok {
+		for name, value := range env {
+			cmd.env = append(cmd.env, fmt.Sprintf("%s=%v", name, value))
+		}
+	}
+
+	if timeout, ok := config["timeout"].(string); ok && timeout != "" {
+		duration, err := time.ParseDuration(timeout)
+		if err != nil {
+			return cmd, fmt.Errorf("invalid timeout: %w", err)
+		}
+		cmd.timeout = duration
+	}
+
+	return cmd, nil
+}
+
+// run executes the command, also writing its output to output if it is set, and
+// returns its output and exit code. The exit code is -1 when the process could not
+// be started or was killed.
+func run(ctx context.Context, cmd command, executionID string, output io.Writer) (string, string, int, error) {
+	if cmd.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, cmd.timeout)
+		defer cancel()
+	}
+
+	// CommandContext kills the process when the context is canceled
+	execCmd := exec.CommandContext(ctx, cmd.name, cmd.args...)
+	execCmd.Dir = plugin.ResolvePath(ctx, cmd.workingDir)
+	execCmd.Env = append(os.Environ(), cmd.env...)
+	execCmd.Env = append(execCmd.Env, "GRP_EXECUTION_ID="+executionID)
+	execCmd.WaitDelay = waitDelay
+
+	var stdout, stderr bytes.Buffer
+	execCmd.Stdout = &stdout
+	execCmd.Stderr = &stderr
+	if output != nil {
+		// Also stream the output to the job log
+		execCmd.Stdout = io.MultiWriter(&stdout, output)
+		execCmd.Stderr = io.MultiWriter(&stderr, output)
+	}
+
+	err := execCmd.Run()
+	if ctx.Err() != nil {
+		return stdout.String(), stderr.String(), -1, fmt.Errorf("command %s canceled: %w", cmd.name, ctx.Err())
+	}
+
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return stdout.String(), stderr.String(), exitErr.ExitCode(), err
+	}
+	if err != nil {
+		return stdout.String(), stderr.String(), -1, fmt.Errorf("failed to run command %s: %w", cmd.name, err)
+	}
+
+	return stdout.String(), stderr.String(), 0, nil
+}
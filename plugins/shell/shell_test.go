@@ -0,0 +1,191 @@
+package shell
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cuongtl1992/grp-cli/pkg/plugin"
+)
+
+func TestExecute(t *testing.T) {
+	tests := []struct {
+		name             string
+		config           map[string]interface{}
+		expectedSuccess  bool
+		expectedStdout   string
+		expectedExitCode int
+		expectedMessage  string
+	}{
+		{
+			name:            "shell command with env",
+			config:          map[string]interface{}{"command": "echo $GREETING", "env": map[string]interface{}{"GREETING": "hello"}},
+			expectedSuccess: true,
+			expectedStdout:  "hello\n",
+		},
+		{
+			name:            "direct exec with args",
+			config:          map[string]interface{}{"command": "echo", "args": []interface{}{"a", "b"}},
+			expectedSuccess: true,
+			expectedStdout:  "a b\n",
+		},
+		{
+			name:             "non-zero exit",
+			config:           map[string]interface{}{"command": "echo oops >&2; exit 3"},
+			expectedSuccess:  false,
+			expectedExitCode: 3,
+			expectedMessage:  "Command sh exited with code 3: oops",
+		},
+		{
+			name:             "non-zero exit without stderr",
+			config:           map[string]interface{}{"command": "exit 1"},
+			expectedSuccess:  false,
+			expectedExitCode: 1,
+			expectedMessage:  "Command sh exited with code 1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plg := &ShellPlugin{}
+			result, err := plg.Execute(context.Background(), tt.config)
+			if err != nil {
+				t.Fatalf("Execute() error = %v", err)
+			}
+			if result.Success != tt.expectedSuccess {
+				t.Errorf("Expected success=%v, got %v (%s)", tt.expectedSuccess, result.Success, result.Message)
+			}
+			if tt.expectedStdout != "" && result.Data["stdout"] != tt.expectedStdout {
+				t.Errorf("Expected stdout %q, got %q", tt.expectedStdout, result.Data["stdout"])
+			}
+			if result.Data["exitCode"] != tt.expectedExitCode {
+				t.Errorf("Expected exit code %d, got %v", tt.expectedExitCode, result.Data["exitCode"])
+			}
+			if tt.expectedMessage != "" && result.Message != tt.expectedMessage {
+				t.Errorf("Expected message %q, got %q", tt.expectedMessage, result.Message)
+			}
+		})
+	}
+}
+
+func TestExecuteStreaming(t *testing.T) {
+	plg := &ShellPlugin{}
+	if !plugin.CapabilitiesOf(plg).Streaming {
+		t.Error("Expected the plugin to report streaming")
+	}
+
+	var output bytes.Buffer
+	result, err := plg.ExecuteStreaming(context.Background(), map[string]interface{}{"command": "echo out; echo err >&2"}, &output)
+	if err != nil || !result.Success {
+		t.Fatalf("ExecuteStreaming() = %+v, %v", result, err)
+	}
+	if got := output.String(); !strings.Contains(got, "out\n") || !strings.Contains(got, "err\n") {
+		t.Errorf("Expected stdout and stderr to be streamed, got %q", got)
+	}
+	if result.Data["stdout"] != "out\n" || result.Data["stderr"] != "err\n" {
+		t.Errorf("Expected the output to be kept in the result, got %v", result.Data)
+	}
+}
+
+func TestExecuteWorkingDir(t *testing.T) {
+	root := t.TempDir()
+	if err := os.Mkdir(filepath.Join(root, "scripts"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	other := t.TempDir()
+
+	tests := []struct {
+		name       string
+		workingDir interface{}
+		expected   string
+	}{
+		{name: "execution working directory", expected: root},
+		{name: "relative to the execution working directory", workingDir: "scripts", expected: filepath.Join(root, "scripts")},
+		{name: "absolute", workingDir: other, expected: other},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := map[string]interface{}{"command": "pwd -P"}
+			if tt.workingDir != nil {
+				config["workingDir"] = tt.workingDir
+			}
+
+			plg := &ShellPlugin{}
+			result, err := plg.Execute(plugin.WithWorkingDir(context.Background(), root), config)
+			if err != nil || !result.Success {
+				t.Fatalf("Execute() = %+v, %v", result, err)
+			}
+			expected, _ := filepath.EvalSymlinks(tt.expected)
+			if stdout := strings.TrimSpace(result.Data["stdout"].(string)); stdout != expected {
+				t.Errorf("Expected the command to run in %s, got %s", expected, stdout)
+			}
+		})
+	}
+}
+
+func TestExecuteCanceledKillsProcess(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := (&ShellPlugin{}).Execute(ctx, map[string]interface{}{"command": "sleep", "args": []interface{}{"10"}})
+	if err == nil || !strings.Contains(err.Error(), "canceled") {
+		t.Errorf("Expected cancellation error, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Expected process to be killed promptly, took %s", elapsed)
+	}
+}
+
+func TestRollback(t *testing.T) {
+	marker := filepath.Join(t.TempDir(), "rolled-back")
+	ctx := plugin.WithExecutionID(context.Background(), "exec-1")
+	plg := &ShellPlugin{}
+
+	_, err := plg.Execute(ctx, map[string]interface{}{
+		"command":         "true",
+		"rollbackCommand": "echo $GRP_EXECUTION_ID > " + marker,
+	})
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+
+	if err := plg.Rollback(ctx, "exec-1"); err != nil {
+		t.Fatalf("Rollback() error = %v", err)
+	}
+
+	data, err := os.ReadFile(marker)
+	if err != nil || strings.TrimSpace(string(data)) != "exec-1" {
+		t.Errorf("Expected rollback command to run with execution ID, got %q (%v)", data, err)
+	}
+
+	// The rollback command only runs once, and jobs without one have nothing to roll back
+	if err := plg.Rollback(ctx, "exec-1"); !errors.Is(err, plugin.ErrNothingToRollBack) {
+		t.Errorf("Expected nothing to roll back, got %v", err)
+	}
+}
+
+func TestCanRollBack(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   map[string]interface{}
+		expected bool
+	}{
+		{name: "rollback command", config: map[string]interface{}{"command": "make deploy", "rollbackCommand": "make undeploy"}, expected: true},
+		{name: "no rollback command", config: map[string]interface{}{"command": "make deploy"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := (&ShellPlugin{}).CanRollBack(tt.config); got != tt.expected {
+				t.Errorf("Expected CanRollBack() = %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,520 @@
+package terraform
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/cuongtl1992/grp-cli/internal/plugins"
+	"github.com/cuongtl1992/grp-cli/pkg/plugin"
+)
+
+// Supported actions
+const (
+	actionInit    = "init"
+	actionPlan    = "plan"
+	actionApply   = "apply"
+	actionDestroy = "destroy"
+)
+
+// summaryRegex matches the resource counts terraform prints after a plan, apply or destroy
+var summaryRegex = regexp.MustCompile(`(?m)^(Plan: \d+ to add, \d+ to change, \d+ to destroy\.|No changes\..*|(?:Apply|Destroy) complete! Resources: .*)$`)
+
+// countsRegex extracts the add/change/destroy counts of a plan summary
+var countsRegex = regexp.MustCompile(`(\d+) to add, (\d+) to change, (\d+) to destroy`)
+
+// commandRunner runs the terraform CLI with the given arguments, streaming
+// output to progress, and returns the combined output
+type commandRunner func(ctx context.Context, progress io.Writer, args ...string) (string, error)
+
+// TerraformPlugin implements the Plugin interface for Terraform infrastructure steps
+type TerraformPlugin struct {
+	mutex     sync.Mutex
+	rollbacks map[string][]job
+	// runner invokes the terraform CLI; nil uses the terraform binary on PATH
+	runner commandRunner
+	// progress receives the terraform CLI output as it runs; nil uses the job
+	// output, or stderr if it is not captured
+	progress io.Writer
+}
+
+// Plugin is the instance registered as a built-in plugin
+var Plugin TerraformPlugin
+
+func init() {
+	plugins.RegisterBuiltin(&Plugin)
+}
+
+// job describes a terraform job built from config
+type job struct {
+	action            string
+	dir               string
+	varFiles          []string
+	variables         map[string]string
+	planFile          string
+	requirePlan       bool
+	autoApprove       bool
+	destroyOnRollback bool
+}
+
+// Name returns the plugin name
+func (p *TerraformPlugin) Name() string {
+	return "terraform"
+}
+
+// Description returns the plugin description
+func (p *TerraformPlugin) Description() string {
+	return "Runs Terraform init, plan, apply and destroy"
+}
+
+// Version returns the plugin version
+func (p *TerraformPlugin) Version() string {
+	return "0.1.0"
+}
+
+// ConfigSchema returns the JSON schema for config validation
+func (p *TerraformPlugin) ConfigSchema() *plugin.JSONSchema {
+	return &plugin.JSONSchema{
+		Type: "object",
+		Properties: map[string]*plugin.JSONSchema{
+			"action":            {Type: "string"},
+			"dir":               {Type: "string"},
+			"varFiles":          {Type: "array", Items: &plugin.JSONSchema{Type: "string"}},
+			"variables":         {Type: "object"},
+			"planFile":          {Type: "string"},
+			"requirePlan":       {Type: "boolean"},
+			"autoApprove":       {Type: "boolean"},
+			"destroyOnRollback": {Type: "boolean"},
+		},
+		Required: []string{"action", "dir"},
+	}
+}
+
+// Validate checks if the configuration is valid
+func (p *TerraformPlugin) Validate(ctx context.Context, config map[string]interface{}) error {
+	_, err := parseJob(config)
+	return err
+}
+
+// Execute runs the configured terraform action
+func (p *TerraformPlugin) Execute(ctx context.Context, config map[string]interface{}) (*plugin.Result, error) {
+	executionID := plugin.ExecutionID(ctx)
+
+	j, err := parseJob(config)
+	if err != nil {
+		return nil, err
+	}
+	j.dir = plugin.ResolvePath(ctx, j.dir)
+
+	data := map[string]interface{}{"dir": j.dir}
+
+	switch j.action {
+	case actionInit:
+		if _, err := p.run(ctx, j.command(actionInit, "-input=false")...); err != nil {
+			return failure(executionID, "terraform init failed", err), nil
+		}
+
+	case actionPlan:
+		args := append([]string{"-input=false"}, j.varArgs()...)
+		if j.planFile != "" {
+			args = append(args, "-out="+j.planFile)
+			data["planFile"] = j.planFile
+		}
+		output, err := p.run(ctx, j.command(actionPlan, args...)...)
+		if err != nil {
+			return failure(executionID, "terraform plan failed", err), nil
+		}
+		addSummary(data, output)
+
+	case actionApply:
+		if j.requirePlan {
+			if _, err := os.Stat(j.planPath()); err != nil {
+				return failure(executionID, "terraform apply requires a plan", err), nil
+			}
+		}
+
+		// Destroying on rollback is only safe if the apply created everything in the state
+		rollback := false
+		if j.destroyOnRollback {
+			existing, err := p.hasResources(ctx, j)
+			if err != nil {
+				return failure(executionID, "terraform show failed", err), nil
+			}
+			rollback = !existing
+			if existing {
+				data["rollback"] = "skipped: the state already had resources before the apply"
+			}
+		}
+
+		args := []string{"-input=false"}
+		if j.planFile != "" {
+			// A saved plan is applied as reviewed, without variables or a prompt
+			args = append(args, j.planFile)
+			data["planFile"] = j.planFile
+		} else {
+			args = append(append(args, "-auto-approve"), j.varArgs()...)
+		}
+		output, err := p.run(ctx, j.command(actionApply, args...)...)
+		if err != nil {
+			return failure(executionID, "terraform apply failed", err), nil
+		}
+		addSummary(data, output)
+
+		outputs, err := p.outputs(ctx, j)
+		if err != nil {
+			return failure(executionID, "terraform output failed", err), nil
+		}
+		data["outputs"] = outputs
+
+		// Remember the workspace to destroy if the release is rolled back
+		if rollback {
+			p.mutex.Lock()
+			if p.rollbacks == nil {
+				p.rollbacks = make(map[string][]job)
+			}
+			p.rollbacks[executionID] = append(p.rollbacks[executionID], j)
+			p.mutex.Unlock()
+		}
+
+	case actionDestroy:
+		args := append([]string{"-input=false", "-auto-approve"}, j.varArgs()...)
+		output, err := p.run(ctx, j.command(actionDestroy, args...)...)
+		if err != nil {
+			return failure(executionID, "terraform destroy failed", err), nil
+		}
+		addSummary(data, output)
+	}
+
+	message := fmt.Sprintf("terraform %s in %s completed", j.action, j.dir)
+	if summary, ok := data["summary"].(string); ok {
+		message += ": " + summary
+	}
+
+	return &plugin.Result{
+		Success:     true,
+		Message:     message,
+		ExecutionID: executionID,
+		Data:        data,
+	}, nil
+}
+
+// Preview shows the changes an apply or destroy job would make: the saved plan
+// an apply uses, or a speculative plan. Other actions have no preview.
+func (p *TerraformPlugin) Preview(ctx context.Context, config map[string]interface{}) (string, error) {
+	j, err := parseJob(config)
+	if err != nil {
+		return "", err
+	}
+	j.dir = plugin.ResolvePath(ctx, j.dir)
+
+	var args []string
+	switch {
+	case j.action == actionApply && j.planFile != "":
+		args = j.command("show", j.planFile)
+	case j.action == actionApply:
+		args = j.command(actionPlan, append([]string{"-input=false"}, j.varArgs()...)...)
+	case j.action == actionDestroy:
+		args = j.command(actionPlan, append([]string{"-input=false", "-destroy"}, j.varArgs()...)...)
+	default:
+		return "", nil
+	}
+
+	return p.runQuiet(ctx, args...)
+}
+
+// Capabilities reports that applies can be destroyed on rollback and that the
+// terraform output is streamed
+func (p *TerraformPlugin) Capabilities() plugin.Capabilities {
+	return plugin.Capabilities{Rollback: true, Streaming: true}
+}
+
+// CanRollBack reports whether the job is an apply destroyed on rollback. The apply
+// is still not destroyed if the state already had resources before it.
+func (p *TerraformPlugin) CanRollBack(config map[string]interface{}) bool {
+	action, _ := config["action"].(string)
+	destroyOnRollback, _ := config["destroyOnRollback"].(bool)
+	return action == actionApply && destroyOnRollback
+}
+
+// Rollback destroys the workspaces applied by the execution, most recent first
+func (p *TerraformPlugin) Rollback(ctx context.Context, executionID string) error {
+	p.mutex.Lock()
+	jobs := p.rollbacks[executionID]
+	delete(p.rollbacks, executionID)
+	p.mutex.Unlock()
+	if len(jobs) == 0 {
+		return plugin.ErrNothingToRollBack
+	}
+
+	var errs []string
+	for i := len(jobs) - 1; i >= 0; i-- {
+		j := jobs[i]
+		args := append([]string{"-input=false", "-auto-approve"}, j.varArgs()...)
+		if _, err := p.run(ctx, j.command(actionDestroy, args...)...); err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", j.dir, err))
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("failed to destroy workspaces: %s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// command builds the arguments of a terraform subcommand run in the job directory
+func (j job) command(subcommand string, args ...string) []string {
+	return append([]string{"-chdir=" + j.dir, subcommand, "-no-color"}, args...)
+}
+
+// varArgs returns the -var-file and -var arguments of the job, variables sorted by name
+func (j job) varArgs() []string {
+	var args []string
+	for _, file := range j.varFiles {
+		args = append(args, "-var-file="+file)
+	}
+
+	names := make([]string, 0, len(j.variables))
+	for name := range j.variables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		args = append(args, "-var", name+"="+j.variables[name])
+	}
+	return args
+}
+
+// planPath returns the path of the plan file, which terraform resolves relative to dir
+func (j job) planPath() string {
+	if filepath.IsAbs(j.planFile) {
+		return j.planFile
+	}
+	return filepath.Join(j.dir, j.planFile)
+}
+
+// parseJob builds a job from config
+func parseJob(config map[string]interface{}) (job, error) {
+	var j job
+
+	j.action, _ = config["action"].(string)
+	switch j.action {
+	case actionInit, actionPlan, actionApply, actionDestroy:
+	case "":
+		return j, fmt.Errorf("missing required field: action")
+	default:
+		return j, fmt.Errorf("unsupported action %q (expected init, plan, apply or destroy)", j.action)
+	}
+
+	j.dir, _ = config["dir"].(string)
+	if j.dir == "" {
+		return j, fmt.Errorf("missing required field: dir")
+	}
+
+	if varFiles, ok := config["varFiles"].([]interface{}); ok {
+		for _, file := range varFiles {
+			j.varFiles = append(j.varFiles, fmt.Sprintf("%v", file))
+		}
+	}
+
+	if variables, ok := config["variables"].(map[string]interface{}); ok {
+		j.variables = make(map[string]string)
+		for name, value := range variables {
+			switch v := value.(type) {
+			case string:
+				j.variables[name] = v
+			case map[string]interface{}, []interface{}:
+				// Terraform accepts lists and maps written as JSON
+				encoded, err := json.Marshal(v)
+				if err != nil {
+					return j, fmt.Errorf("invalid variable %s: %w", name, err)
+				}
+				j.variables[name] = string(encoded)
+			default:
+				j.variables[name] = fmt.Sprintf("%v", v)
+			}
+		}
+	}
+
+	j.planFile, _ = config["planFile"].(string)
+	j.requirePlan, _ = config["requirePlan"].(bool)
+	j.autoApprove, _ = config["autoApprove"].(bool)
+	j.destroyOnRollback, _ = config["destroyOnRollback"].(bool)
+
+	if j.requirePlan && j.planFile == "" {
+		return j, fmt.Errorf("requirePlan needs a planFile")
+	}
+	switch j.action {
+	case actionApply:
+		if !j.autoApprove && j.planFile == "" {
+			return j, fmt.Errorf("apply requires autoApprove or a planFile from an approved plan")
+		}
+	case actionDestroy:
+		if !j.autoApprove {
+			return j, fmt.Errorf("destroy requires autoApprove")
+		}
+	}
+
+	return j, nil
+}
+
+// outputs reads the root module outputs of the workspace, leaving out sensitive ones
+func (p *TerraformPlugin) outputs(ctx context.Context, j job) (map[string]interface{}, error) {
+	output, err := p.runQuiet(ctx, j.command("output", "-json")...)
+	if err != nil {
+		return nil, err
+	}
+
+	var raw map[string]struct {
+		Sensitive bool        `json:"sensitive"`
+		Value     interface{} `json:"value"`
+	}
+	if err := json.Unmarshal([]byte(output), &raw); err != nil {
+		return nil, fmt.Errorf("invalid terraform output: %w", err)
+	}
+
+	outputs := make(map[string]interface{}, len(raw))
+	for name, value := range raw {
+		if !value.Sensitive {
+			outputs[name] = value.Value
+		}
+	}
+	return outputs, nil
+}
+
+// hasResources reports whether the state of the workspace holds any resources
+func (p *TerraformPlugin) hasResources(ctx context.Context, j job) (bool, error) {
+	output, err := p.runQuiet(ctx, j.command("show", "-json")...)
+	if err != nil {
+		return false, err
+	}
+
+	var state struct {
+		Values *struct {
+			RootModule struct {
+				Resources    []json.RawMessage `json:"resources"`
+				ChildModules []json.RawMessage `json:"child_modules"`
+			} `json:"root_module"`
+		} `json:"values"`
+	}
+	if err := json.Unmarshal([]byte(output), &state); err != nil {
+		return false, fmt.Errorf("invalid terraform state: %w", err)
+	}
+	if state.Values == nil {
+		return false, nil
+	}
+	return len(state.Values.RootModule.Resources) > 0 || len(state.Values.RootModule.ChildModules) > 0, nil
+}
+
+// addSummary records the resource counts terraform printed in the job data
+func addSummary(data map[string]interface{}, output string) {
+	match := summaryRegex.FindString(output)
+	if match == "" {
+		return
+	}
+	data["summary"] = strings.TrimSpace(match)
+
+	if counts := countsRegex.FindStringSubmatch(match); counts != nil {
+		data["add"], _ = strconv.Atoi(counts[1])
+		data["change"], _ = strconv.Atoi(counts[2])
+		data["destroy"], _ = strconv.Atoi(counts[3])
+	} else if strings.HasPrefix(match, "No changes.") {
+		data["add"], data["change"], data["destroy"] = 0, 0, 0
+	}
+}
+
+// run invokes the terraform CLI, streaming its output to the progress writer
+func (p *TerraformPlugin) run(ctx context.Context, args ...string) (string, error) {
+	progress := p.progress
+	if progress == nil {
+		progress = plugin.Output(ctx)
+	}
+	if progress == nil {
+		progress = os.Stderr
+	}
+	return p.invoke(ctx, progress, args...)
+}
+
+// runQuiet invokes the terraform CLI without streaming its output, which may hold
+// output values or resource names
+func (p *TerraformPlugin) runQuiet(ctx context.Context, args ...string) (string, error) {
+	return p.invoke(ctx, io.Discard, args...)
+}
+
+// invoke runs terraform with the configured runner
+func (p *TerraformPlugin) invoke(ctx context.Context, progress io.Writer, args ...string) (string, error) {
+	runner := p.runner
+	if runner == nil {
+		runner = runTerraform
+	}
+	return runner(ctx, progress, args...)
+}
+
+// runTerraform runs the terraform binary, copying each output line to progress
+func runTerraform(ctx context.Context, progress io.Writer, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "terraform", args...)
+	cmd.Env = append(os.Environ(), "TF_IN_AUTOMATION=1")
+
+	var stdout bytes.Buffer
+	reader, writer := io.Pipe()
+	cmd.Stdout = io.MultiWriter(&stdout, writer)
+	var stderr bytes.Buffer
+	cmd.Stderr = io.MultiWriter(&stderr, writer)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		scanner := bufio.NewScanner(reader)
+		for scanner.Scan() {
+			fmt.Fprintf(progress, "[terraform] %s\n", scanner.Text())
+		}
+		// Drain the pipe if a line was too long to scan
+		io.Copy(io.Discard, reader)
+	}()
+
+	err := cmd.Run()
+	writer.Close()
+	<-done
+
+	if err != nil {
+		detail := stderr.String()
+		if strings.TrimSpace(detail) == "" {
+			detail = stdout.String()
+		}
+		err = fmt.Errorf("terraform %s: %w", subcommand(args), err)
+		if line := plugins.LastLine(detail); line != "" {
+			err = fmt.Errorf("%w: %s", err, line)
+		}
+		return stdout.String(), err
+	}
+	return stdout.String(), nil
+}
+
+// failure builds an unsuccessful result for a failed terraform command
+func failure(executionID, message string, err error) *plugin.Result {
+	return &plugin.Result{
+		Success:     false,
+		Message:     fmt.Sprintf("%s: %v", message, err),
+		ExecutionID: executionID,
+	}
+}
+
+// subcommand returns the terraform subcommand of the arguments, skipping global options
+func subcommand(args []string) string {
+	for _, arg := range args {
+		if !strings.HasPrefix(arg, "-") {
+			return arg
+		}
+	}
+	return ""
+}
@@ -0,0 +1,290 @@
+package terraform
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/cuongtl1992/grp-cli/pkg/plugin"
+)
+
+// fakeTerraform records terraform invocations and returns canned output per subcommand
+type fakeTerraform struct {
+	calls  []string
+	output map[string]string
+	fail   string
+}
+
+func (f *fakeTerraform) run(ctx context.Context, progress io.Writer, args ...string) (string, error) {
+	f.calls = append(f.calls, strings.Join(args, " "))
+	if subcommand(args) == f.fail {
+		return "", fmt.Errorf("terraform %s: exit status 1", f.fail)
+	}
+	return f.output[subcommand(args)], nil
+}
+
+func TestExecute(t *testing.T) {
+	tests := []struct {
+		name          string
+		config        map[string]interface{}
+		fail          string
+		expectedCalls []string
+		expectedData  map[string]interface{}
+		wantSuccess   bool
+	}{
+		{
+			name:          "init",
+			config:        map[string]interface{}{"action": "init", "dir": "infra"},
+			expectedCalls: []string{"-chdir=infra init -no-color -input=false"},
+			wantSuccess:   true,
+		},
+		{
+			name: "plan",
+			config: map[string]interface{}{
+				"action": "plan", "dir": "infra", "planFile": "release.tfplan",
+				"varFiles": []interface{}{"prod.tfvars"}, "variables": map[string]interface{}{"replicas": 3, "region": "eu-west-1"},
+			},
+			expectedCalls: []string{"-chdir=infra plan -no-color -input=false -var-file=prod.tfvars -var region=eu-west-1 -var replicas=3 -out=release.tfplan"},
+			expectedData:  map[string]interface{}{"summary": "Plan: 2 to add, 1 to change, 0 to destroy.", "add": 2, "change": 1, "destroy": 0},
+			wantSuccess:   true,
+		},
+		{
+			name:   "auto-approved apply",
+			config: map[string]interface{}{"action": "apply", "dir": "infra", "autoApprove": true, "variables": map[string]interface{}{"zones": []interface{}{"a", "b"}}},
+			expectedCalls: []string{
+				`-chdir=infra apply -no-color -input=false -auto-approve -var zones=["a","b"]`,
+				"-chdir=infra output -no-color -json",
+			},
+			expectedData: map[string]interface{}{"summary": "Apply complete! Resources: 2 added, 0 changed, 0 destroyed."},
+			wantSuccess:  true,
+		},
+		{
+			name:          "apply of a saved plan",
+			config:        map[string]interface{}{"action": "apply", "dir": "infra", "planFile": "release.tfplan"},
+			expectedCalls: []string{"-chdir=infra apply -no-color -input=false release.tfplan", "-chdir=infra output -no-color -json"},
+			wantSuccess:   true,
+		},
+		{
+			name:          "apply requiring a missing plan",
+			config:        map[string]interface{}{"action": "apply", "dir": "infra", "planFile": "missing.tfplan", "requirePlan": true},
+			expectedCalls: nil,
+		},
+		{
+			name:          "failed destroy",
+			config:        map[string]interface{}{"action": "destroy", "dir": "infra", "autoApprove": true},
+			fail:          "destroy",
+			expectedCalls: []string{"-chdir=infra destroy -no-color -input=false -auto-approve"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			terraform := &fakeTerraform{
+				fail: tt.fail,
+				output: map[string]string{
+					"plan":   "Terraform will perform the following actions:\n\nPlan: 2 to add, 1 to change, 0 to destroy.\n",
+					"apply":  "Apply complete! Resources: 2 added, 0 changed, 0 destroyed.\n",
+					"output": `{"url": {"sensitive": false, "value": "https://app.example.com"}, "password": {"sensitive": true, "value": "secret"}}`,
+				},
+			}
+			plg := &TerraformPlugin{runner: terraform.run, progress: io.Discard}
+
+			result, err := plg.Execute(context.Background(), tt.config)
+			if err != nil {
+				t.Fatalf("Execute() error = %v", err)
+			}
+			if result.Success != tt.wantSuccess {
+				t.Errorf("Expected success=%v, got %v (%s)", tt.wantSuccess, result.Success, result.Message)
+			}
+			if strings.Join(terraform.calls, "\n") != strings.Join(tt.expectedCalls, "\n") {
+				t.Errorf("Expected terraform calls:\n%s\ngot:\n%s", strings.Join(tt.expectedCalls, "\n"), strings.Join(terraform.calls, "\n"))
+			}
+			for key, value := range tt.expectedData {
+				if result.Data[key] != value {
+					t.Errorf("Expected data %s=%v, got %v", key, value, result.Data[key])
+				}
+			}
+		})
+	}
+}
+
+func TestExecuteReturnsOutputs(t *testing.T) {
+	terraform := &fakeTerraform{output: map[string]string{
+		"output": `{"url": {"sensitive": false, "value": "https://app.example.com"}, "password": {"sensitive": true, "value": "secret"}}`,
+	}}
+	plg := &TerraformPlugin{runner: terraform.run, progress: io.Discard}
+
+	result, err := plg.Execute(context.Background(), map[string]interface{}{"action": "apply", "dir": "infra", "autoApprove": true})
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+
+	outputs, _ := result.Data["outputs"].(map[string]interface{})
+	if outputs["url"] != "https://app.example.com" {
+		t.Errorf("Expected url output, got %v", outputs)
+	}
+	if _, ok := outputs["password"]; ok {
+		t.Errorf("Expected sensitive outputs to be left out, got %v", outputs)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  map[string]interface{}
+		wantErr bool
+	}{
+		{name: "valid plan", config: map[string]interface{}{"action": "plan", "dir": "infra"}},
+		{name: "apply with auto-approve", config: map[string]interface{}{"action": "apply", "dir": "infra", "autoApprove": true}},
+		{name: "apply with plan file", config: map[string]interface{}{"action": "apply", "dir": "infra", "planFile": "release.tfplan"}},
+		{name: "missing action", config: map[string]interface{}{"dir": "infra"}, wantErr: true},
+		{name: "missing dir", config: map[string]interface{}{"action": "init"}, wantErr: true},
+		{name: "unknown action", config: map[string]interface{}{"action": "import", "dir": "infra"}, wantErr: true},
+		{name: "apply without approval", config: map[string]interface{}{"action": "apply", "dir": "infra"}, wantErr: true},
+		{name: "destroy without auto-approve", config: map[string]interface{}{"action": "destroy", "dir": "infra"}, wantErr: true},
+		{name: "required plan without plan file", config: map[string]interface{}{"action": "apply", "dir": "infra", "autoApprove": true, "requirePlan": true}, wantErr: true},
+	}
+
+	plg := &TerraformPlugin{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := plg.Validate(context.Background(), tt.config); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRollbackDestroysNewWorkspaces(t *testing.T) {
+	tests := []struct {
+		name          string
+		state         string
+		expectDestroy bool
+	}{
+		{name: "empty state", state: `{"format_version": "1.0"}`, expectDestroy: true},
+		{name: "existing resources", state: `{"values": {"root_module": {"resources": [{"address": "aws_s3_bucket.logs"}]}}}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			terraform := &fakeTerraform{output: map[string]string{"show": tt.state, "output": "{}"}}
+			plg := &TerraformPlugin{runner: terraform.run, progress: io.Discard}
+			ctx := plugin.WithExecutionID(context.Background(), "exec-1")
+
+			config := map[string]interface{}{"action": "apply", "dir": "infra", "autoApprove": true, "destroyOnRollback": true}
+			if _, err := plg.Execute(ctx, config); err != nil {
+				t.Fatalf("Execute() error = %v", err)
+			}
+
+			terraform.calls = nil
+			if err := plg.Rollback(ctx, "exec-1"); err != nil && (tt.expectDestroy || !errors.Is(err, plugin.ErrNothingToRollBack)) {
+				t.Fatalf("Rollback() error = %v", err)
+			}
+			destroyed := strings.Join(terraform.calls, "\n") == "-chdir=infra destroy -no-color -input=false -auto-approve"
+			if destroyed != tt.expectDestroy {
+				t.Errorf("Expected destroy=%v, got calls %v", tt.expectDestroy, terraform.calls)
+			}
+		})
+	}
+}
+
+func TestExecuteRequiredPlan(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "release.tfplan"), []byte("plan"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	terraform := &fakeTerraform{output: map[string]string{"output": "{}"}}
+	plg := &TerraformPlugin{runner: terraform.run, progress: io.Discard}
+
+	result, err := plg.Execute(context.Background(), map[string]interface{}{"action": "apply", "dir": dir, "planFile": "release.tfplan", "requirePlan": true})
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if !result.Success {
+		t.Errorf("Expected apply of the existing plan to succeed, got %s", result.Message)
+	}
+}
+
+func TestPreview(t *testing.T) {
+	tests := []struct {
+		name         string
+		config       map[string]interface{}
+		fail         string
+		expectedCall string
+		expected     string
+		wantErr      bool
+	}{
+		{
+			name:         "apply",
+			config:       map[string]interface{}{"action": "apply", "dir": "infra", "autoApprove": true, "variables": map[string]interface{}{"env": "prod"}},
+			expectedCall: "-chdir=infra plan -no-color -input=false -var env=prod",
+			expected:     "Plan: 1 to add",
+		},
+		{
+			name:         "apply saved plan",
+			config:       map[string]interface{}{"action": "apply", "dir": "infra", "planFile": "release.tfplan"},
+			expectedCall: "-chdir=infra show -no-color release.tfplan",
+			expected:     "saved plan",
+		},
+		{
+			name:         "destroy",
+			config:       map[string]interface{}{"action": "destroy", "dir": "infra", "autoApprove": true},
+			expectedCall: "-chdir=infra plan -no-color -input=false -destroy",
+			expected:     "Plan: 1 to add",
+		},
+		{
+			name:   "init has no preview",
+			config: map[string]interface{}{"action": "init", "dir": "infra"},
+		},
+		{
+			name:         "failed plan",
+			config:       map[string]interface{}{"action": "apply", "dir": "infra", "autoApprove": true},
+			fail:         "plan",
+			expectedCall: "-chdir=infra plan -no-color -input=false",
+			wantErr:      true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			terraform := &fakeTerraform{fail: tt.fail, output: map[string]string{"plan": "Plan: 1 to add", "show": "saved plan"}}
+			plg := &TerraformPlugin{runner: terraform.run}
+
+			preview, err := plg.Preview(context.Background(), tt.config)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Preview() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if preview != tt.expected {
+				t.Errorf("Preview() = %q, want %q", preview, tt.expected)
+			}
+			if strings.Join(terraform.calls, "\n") != tt.expectedCall {
+				t.Errorf("terraform calls = %q, want %q", terraform.calls, tt.expectedCall)
+			}
+		})
+	}
+}
+
+func TestCanRollBack(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   map[string]interface{}
+		expected bool
+	}{
+		{name: "apply destroyed on rollback", config: map[string]interface{}{"action": "apply", "destroyOnRollback": true}, expected: true},
+		{name: "apply kept", config: map[string]interface{}{"action": "apply"}},
+		{name: "plan", config: map[string]interface{}{"action": "plan", "destroyOnRollback": true}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := (&TerraformPlugin{}).CanRollBack(tt.config); got != tt.expected {
+				t.Errorf("Expected CanRollBack() = %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}